@@ -1,7 +1,9 @@
 package main
 
 import (
+	"bufio"
 	cryptorand "crypto/rand"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
@@ -11,6 +13,7 @@ import (
 	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/codecrafters-io/redis-starter-go/redis"
 )
@@ -21,8 +24,12 @@ const (
 )
 
 var (
-	port      uint64
-	replicaOf *replicaOfFlag
+	port                    uint64
+	replicaOf               *replicaOfFlag
+	encoding                = redis.DefaultEncodingConfig()
+	pubSubOutputBufferLimit = redis.DefaultPubSubOutputBufferLimit()
+	notifyKeyspaceEvents    redis.KeyspaceEvents
+	busyReplyThreshold      = redis.DefaultBusyReplyThreshold
 )
 
 type replicaOfFlag struct {
@@ -72,6 +79,61 @@ func main() {
 			}
 			return nil
 		})
+	flag.IntVar(
+		&encoding.HashMaxListpackEntries,
+		"hash-max-listpack-entries",
+		encoding.HashMaxListpackEntries,
+		"the most fields a hash can have before it's converted from a listpack to a hashtable",
+	)
+	flag.IntVar(
+		&encoding.HashMaxListpackValue,
+		"hash-max-listpack-value",
+		encoding.HashMaxListpackValue,
+		"the longest field or value, in bytes, a hash can have before it's converted from a "+
+			"listpack to a hashtable",
+	)
+	flag.IntVar(
+		&encoding.SetMaxIntsetEntries,
+		"set-max-intset-entries",
+		encoding.SetMaxIntsetEntries,
+		"the most members a set of integers can have before it's converted from an intset to a "+
+			"hashtable",
+	)
+	flag.IntVar(
+		&encoding.HllSparseMaxBytes,
+		"hll-sparse-max-bytes",
+		encoding.HllSparseMaxBytes,
+		"the largest, in bytes, a HyperLogLog can be before it's converted from the sparse to the "+
+			"dense representation",
+	)
+	flag.Func(
+		"client-output-buffer-limit",
+		"the limit on the output waiting to be written to subscribed clients, past which they're "+
+			"disconnected; must be in the format 'pubsub <hard limit> <soft limit> <soft seconds>'",
+		setPubSubOutputBufferLimit,
+	)
+	flag.Func(
+		"notify-keyspace-events",
+		"the classes of keyspace events to publish, like Redis's config parameter, e.g. 'Ex' "+
+			"for expired keys",
+		func(value string) (err error) {
+			notifyKeyspaceEvents, err = redis.ParseKeyspaceEvents(value)
+			return err
+		},
+	)
+	flag.Func(
+		"busy-reply-threshold",
+		"how long, in milliseconds, a script can run before other clients' commands are rejected "+
+			"with a BUSY error; 0 disables it",
+		func(value string) error {
+			milliseconds, err := strconv.ParseUint(value, 10, 32)
+			if err != nil {
+				return err
+			}
+			busyReplyThreshold = time.Duration(milliseconds) * time.Millisecond
+			return nil
+		},
+	)
 	flag.Parse()
 
 	var replicationMasterConfig *redis.ReplicationMasterConfig
@@ -86,14 +148,41 @@ func main() {
 		Replication: redis.ReplicationConfig{
 			Master: replicationMasterConfig,
 		},
+		Encoding:                encoding,
+		PubSubOutputBufferLimit: pubSubOutputBufferLimit,
+		NotifyKeyspaceEvents:    notifyKeyspaceEvents,
+		BusyReplyThreshold:      busyReplyThreshold,
 	}
 	store := redis.NewStore()
 	clock := redis.RealClock{}
 	redisParser := redis.NewParser(config, store, clock)
+	go deleteExpiredKeys(store, clock)
+	go exitOnShutdown(redisParser)
 
 	startTCPServer(redisParser)
 }
 
+// exitOnShutdown exits once a client has sent SHUTDOWN.
+func exitOnShutdown(redisParser redis.Parser) {
+	<-redisParser.ShutdownRequested()
+	fmt.Println("Redis is now ready to exit, bye bye...")
+	os.Exit(0)
+}
+
+// activeExpiryInterval is how often expired keys are looked for, like Redis's default hz of 10.
+const activeExpiryInterval = 100 * time.Millisecond
+
+// deleteExpiredKeys deletes expired keys from store in the background, so that keys that are
+// never accessed again are still deleted, and their expired events notified, soon after they
+// expire.
+func deleteExpiredKeys(store *redis.Store, clock redis.Clock) {
+	ticker := time.NewTicker(activeExpiryInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		store.DeleteExpiredKeys(clock.NowMonotonic())
+	}
+}
+
 func replicaofPortValue() (uint64, error) {
 	replicaofFlagIndex := slices.IndexFunc(os.Args, isReplicaofFlag)
 	if len(os.Args) <= replicaofFlagIndex+2 {
@@ -125,6 +214,62 @@ func isReplicaofFlag(s string) bool {
 	return s == "-"+replicaofFlagName || s == "--"+replicaofFlagName
 }
 
+// setPubSubOutputBufferLimit parses a client-output-buffer-limit of the pubsub class, like
+// Redis's config parameter, where the limits are in bytes, optionally with a unit like mb.
+func setPubSubOutputBufferLimit(value string) error {
+	parts := strings.Fields(value)
+	if len(parts) != 4 || !strings.EqualFold(parts[0], "pubsub") {
+		return errors.New(
+			"client-output-buffer-limit must be in the format " +
+				"'pubsub <hard limit> <soft limit> <soft seconds>'",
+		)
+	}
+	hard, err := parseMemory(parts[1])
+	if err != nil {
+		return err
+	}
+	soft, err := parseMemory(parts[2])
+	if err != nil {
+		return err
+	}
+	seconds, err := strconv.ParseUint(parts[3], 10, 32)
+	if err != nil {
+		return err
+	}
+	pubSubOutputBufferLimit = redis.OutputBufferLimit{
+		HardBytes:    hard,
+		SoftBytes:    soft,
+		SoftDuration: time.Duration(seconds) * time.Second,
+	}
+	return nil
+}
+
+// parseMemory parses an amount of memory in bytes, optionally with a unit like Redis's config
+// file: k, kb, m, mb, g or gb, where k is 1000 and kb 1024.
+func parseMemory(s string) (int, error) {
+	units := []struct {
+		suffix     string
+		multiplier int
+	}{
+		{"kb", 1024}, {"mb", 1024 * 1024}, {"gb", 1024 * 1024 * 1024},
+		{"k", 1000}, {"m", 1000 * 1000}, {"g", 1000 * 1000 * 1000},
+	}
+	lower := strings.ToLower(s)
+	multiplier := 1
+	for _, unit := range units {
+		if strings.HasSuffix(lower, unit.suffix) {
+			lower = strings.TrimSuffix(lower, unit.suffix)
+			multiplier = unit.multiplier
+			break
+		}
+	}
+	n, err := strconv.ParseUint(lower, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount of memory %q: %w", s, err)
+	}
+	return int(n) * multiplier, nil
+}
+
 const alphabet = "0123456789" +
 	"ABCDEFGHIJKLMNOPQRSTUVWXYZ" +
 	"abcdefghijklmnopqrstuvwxyz"
@@ -164,20 +309,110 @@ func startTCPServer(redisParser redis.Parser) {
 func handleConn(conn net.Conn, redisParser redis.Parser) {
 	defer errorHandlingClose(conn)
 
+	client := redisParser.NewClient()
+	defer client.Close()
+
+	requests := make(chan parsedRequest)
+	done := make(chan struct{})
+	defer close(done)
+	// Read requests in the background, so that if the connection closes while a command is
+	// blocked, like BLPOP, the client is closed and the command gives up.
+	go readRequests(bufio.NewReader(conn), redisParser.ForClient(client), client, requests, done)
+
 	for {
-		command, err := redisParser.Parse(conn)
-		if err != nil {
-			if err == io.EOF {
+		select {
+		case request, ok := <-requests:
+			if !ok {
 				return
 			}
-			printErr(err)
+			err := serveRequest(conn, request)
+			close(request.served)
+			if err != nil {
+				printErr(err)
+				return
+			}
+		case <-client.Pushed():
+			// Messages published to channels the client is subscribed to are written between
+			// replies, as they arrive.
+			if _, err := io.WriteString(conn, client.TakePushes()); err != nil {
+				printErr(err)
+				return
+			}
+		case <-client.Closed():
 			return
 		}
+	}
+}
 
-		response := command.Run()
-		_, err = io.WriteString(conn, response)
-		if err != nil {
-			printErr(err)
+// serveRequest runs the command of request and writes its response to conn, or writes the
+// request's error if it's a redis.CommandError. It returns any other error.
+func serveRequest(conn net.Conn, request parsedRequest) error {
+	if request.err != nil {
+		var commandErr redis.CommandError
+		if !errors.As(request.err, &commandErr) {
+			return request.err
+		}
+		_, err := io.WriteString(conn, commandErr.Response())
+		return err
+	}
+	_, err := io.WriteString(conn, request.command.Run())
+	return err
+}
+
+type parsedRequest struct {
+	command redis.Command
+	err     error
+	// served is closed once the request has been served.
+	served chan struct{}
+}
+
+// readRequests parses requests from reader and sends them to requests until reading fails or
+// done is closed. If reading fails, it closes client.
+//
+// Each request is only parsed once the one before it has been served, since whether a command
+// is allowed can depend on the commands before it, like SUBSCRIBE. It still waits for the next
+// request to start arriving in the meantime, so that it notices if the connection closes.
+func readRequests(
+	reader *bufio.Reader,
+	redisParser redis.Parser,
+	client *redis.Client,
+	requests chan<- parsedRequest,
+	done <-chan struct{},
+) {
+	defer close(requests)
+
+	fail := func(err error) {
+		client.Close()
+		if err != io.EOF {
+			select {
+			case requests <- parsedRequest{err: err, served: make(chan struct{})}:
+			case <-done:
+			}
+		}
+	}
+
+	for {
+		command, err := redisParser.Parse(reader)
+		var commandErr redis.CommandError
+		if err != nil && !errors.As(err, &commandErr) {
+			fail(err)
+			return
+		}
+
+		request := parsedRequest{command: command, err: err, served: make(chan struct{})}
+		select {
+		case requests <- request:
+		case <-done:
+			return
+		}
+
+		if _, err := reader.Peek(1); err != nil {
+			fail(err)
+			return
+		}
+		select {
+		case <-request.served:
+		case <-done:
 			return
 		}
 	}
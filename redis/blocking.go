@@ -0,0 +1,223 @@
+package redis
+
+import (
+	"math"
+	"strconv"
+	"time"
+)
+
+// serveFunc tries to serve a blocked command from key. It returns the command's response and
+// true if it was served, or false if key isn't ready for it yet. err is only returned if key
+// holds a value of the wrong type for the command.
+type serveFunc func(tx *storeTx, key string) (response string, ok bool, err error)
+
+// blockedClient is a command waiting for one of its keys to become ready.
+type blockedClient struct {
+	keys  []string
+	serve serveFunc
+	// response receives the command's response when it's served.
+	response chan string
+	served   bool
+}
+
+// blockForKeys serves the first of keys that serve can serve right away. If none can, it blocks
+// until one of them can be served after another command writes to it, the timeout passes, or
+// client is unblocked or closed. A timeout of zero blocks forever. In a transaction, where it
+// can't block, it replies with noBlockResponse right away instead, which is the command's null
+// reply.
+//
+// Clients blocked on the same key are served in the order that they blocked.
+func blockForKeys(
+	store *Store,
+	clock Clock,
+	client *Client,
+	keys []string,
+	timeout time.Duration,
+	noBlockResponse string,
+	serve serveFunc,
+) string {
+	serveAny := func(tx *storeTx) (string, bool, error) {
+		for _, key := range keys {
+			response, ok, err := serve(tx, key)
+			if err != nil || ok {
+				return response, ok, err
+			}
+		}
+		return "", false, nil
+	}
+	return blockUnlessServed(store, clock, client, keys, timeout, noBlockResponse, serveAny, serve)
+}
+
+// blockUnlessServed is like blockForKeys, but tries serveNow rather than serve to serve the
+// command right away, for commands like XREAD that serve several keys at once when they don't
+// block. serveNow runs in the same write transaction that blocks, if it can't serve.
+func blockUnlessServed(
+	store *Store,
+	clock Clock,
+	client *Client,
+	keys []string,
+	timeout time.Duration,
+	noBlockResponse string,
+	serveNow func(tx *storeTx) (response string, ok bool, err error),
+	serve serveFunc,
+) string {
+	var response string
+	var blocked *blockedClient
+	store.write(clock.NowMonotonic(), func(tx *storeTx) {
+		r, ok, err := serveNow(tx)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		if ok {
+			response = r
+			return
+		}
+		if store.exclusive {
+			// Like Redis, a blocking command in a transaction replies right away, since nothing
+			// else can write to its keys until the transaction is over.
+			response = noBlockResponse
+			return
+		}
+		blocked = tx.block(keys, serve)
+		client.setBlocked(true)
+	})
+	if blocked == nil {
+		return response
+	}
+	defer client.setBlocked(false)
+
+	var timedOut <-chan time.Time
+	if timeout > 0 {
+		timedOut = clock.After(timeout)
+	}
+	response = nullArray
+	select {
+	case response := <-blocked.response:
+		return response
+	case <-timedOut:
+	case err := <-client.unblocked:
+		if err != nil {
+			response = errorResponse(err)
+		}
+	case <-client.closed:
+	}
+	if !store.unblock(blocked) {
+		// The command was served before it could give up.
+		return <-blocked.response
+	}
+	return response
+}
+
+// block adds a blockedClient that's waiting on keys. The caller must have already found that
+// none of keys can be served.
+func (tx *storeTx) block(keys []string, serve serveFunc) *blockedClient {
+	tx.checkWritable()
+	blocked := &blockedClient{
+		keys:     keys,
+		serve:    serve,
+		response: make(chan string, 1),
+	}
+	for _, key := range keys {
+		tx.store.blocked[key] = append(tx.store.blocked[key], blocked)
+	}
+	return blocked
+}
+
+// unblock removes blocked, returning false if it has already been served.
+func (s *Store) unblock(blocked *blockedClient) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if blocked.served {
+		return false
+	}
+	s.removeBlockedClient(blocked)
+	return true
+}
+
+func (s *Store) removeBlockedClient(blocked *blockedClient) {
+	for _, key := range blocked.keys {
+		queue := s.blocked[key]
+		for i, b := range queue {
+			if b == blocked {
+				queue = append(queue[:i], queue[i+1:]...)
+				break
+			}
+		}
+		if len(queue) == 0 {
+			delete(s.blocked, key)
+		} else {
+			s.blocked[key] = queue
+		}
+	}
+}
+
+// signalKeyAsReady records that key may now be able to serve a blocked client, such as when
+// elements are pushed onto a list. Blocked clients are served once the write transaction's
+// function returns.
+func (tx *storeTx) signalKeyAsReady(key string) {
+	tx.checkWritable()
+	if len(tx.store.blocked[key]) == 0 {
+		return
+	}
+	for _, readyKey := range tx.readyKeys {
+		if readyKey == key {
+			return
+		}
+	}
+	tx.readyKeys = append(tx.readyKeys, key)
+}
+
+// serveBlockedClients serves the clients blocked on each ready key in the order that they
+// blocked, for as long as the key can serve them. Serving a client may make more keys ready,
+// like BLMOVE pushing onto its destination, so this carries on until no keys are ready.
+func (tx *storeTx) serveBlockedClients() {
+	for len(tx.readyKeys) > 0 {
+		key := tx.readyKeys[0]
+		tx.readyKeys = tx.readyKeys[1:]
+		for len(tx.store.blocked[key]) > 0 {
+			blocked := tx.store.blocked[key][0]
+			response, ok, _ := blocked.serve(tx, key)
+			if !ok {
+				break
+			}
+			blocked.served = true
+			tx.store.removeBlockedClient(blocked)
+			blocked.response <- response
+		}
+	}
+}
+
+// parseTimeout parses the timeout of a blocking command, given in seconds with an optional
+// fractional part. Like Redis, it's rounded up to the nearest millisecond.
+func parseTimeout(s string) (time.Duration, error) {
+	seconds, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(seconds) || math.IsInf(seconds, 0) {
+		return 0, CommandError("ERR timeout is not a float or out of range")
+	}
+	if seconds < 0 {
+		return 0, CommandError("ERR timeout is negative")
+	}
+	milliseconds := math.Ceil(seconds * 1000)
+	if milliseconds > float64(math.MaxInt64/int64(time.Millisecond)) {
+		return 0, CommandError("ERR timeout is out of range")
+	}
+	return time.Duration(milliseconds) * time.Millisecond, nil
+}
+
+// parseMillisecondsTimeout parses the timeout of a blocking command that's given as a whole
+// number of milliseconds, like XREAD's BLOCK.
+func parseMillisecondsTimeout(s string) (time.Duration, error) {
+	milliseconds, err := parseInteger(s)
+	if err != nil {
+		return 0, CommandError("ERR timeout is not an integer or out of range")
+	}
+	if milliseconds < 0 {
+		return 0, CommandError("ERR timeout is negative")
+	}
+	if milliseconds > math.MaxInt64/int(time.Millisecond) {
+		return 0, CommandError("ERR timeout is out of range")
+	}
+	return time.Duration(milliseconds) * time.Millisecond, nil
+}
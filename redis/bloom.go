@@ -0,0 +1,359 @@
+package redis
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"math"
+	"math/rand"
+)
+
+// itemHashes returns two independent 64-bit hashes of item, from which probabilistic structures
+// derive as many hash functions as they need by double hashing: a + i*b.
+func itemHashes(item string) (a, b uint64) {
+	h := fnv.New128a()
+	_, _ = h.Write([]byte(item))
+	sum := h.Sum(nil)
+	return mix64(binary.BigEndian.Uint64(sum[:8])), mix64(binary.BigEndian.Uint64(sum[8:]))
+}
+
+// mix64 is MurmurHash3's finalizer, which spreads FNV's poorly mixed high bits over every bit.
+func mix64(h uint64) uint64 {
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h
+}
+
+const (
+	defaultBloomErrorRate = 0.01
+	defaultBloomCapacity  = 100
+	defaultBloomExpansion = 2
+	// bloomErrorTighteningRatio is how much lower each layer's error rate is than the last, so
+	// that the error rate of the whole filter converges however many layers it has.
+	bloomErrorTighteningRatio = 0.5
+)
+
+const (
+	errBloomExists  CommandError = "ERR item exists"
+	errBloomMissing CommandError = "ERR not found"
+	errBloomFull    CommandError = "ERR non scaling filter is full"
+)
+
+// bloomFilter is a scalable Bloom filter, like RedisBloom's: a stack of Bloom filters where a
+// new, larger layer is added whenever the last one reaches its capacity.
+type bloomFilter struct {
+	// expansion is how many times larger each layer's capacity is than the last's, or 0 if the
+	// filter doesn't scale.
+	expansion int
+	layers    []*bloomLayer
+}
+
+type bloomLayer struct {
+	bits      []byte
+	size      uint64
+	hashes    int
+	capacity  int
+	count     int
+	errorRate float64
+}
+
+func newBloomFilter(errorRate float64, capacity, expansion int) *bloomFilter {
+	return &bloomFilter{
+		expansion: expansion,
+		layers:    []*bloomLayer{newBloomLayer(capacity, errorRate)},
+	}
+}
+
+// newBloomLayer returns a layer with the optimal number of bits and hash functions to hold
+// capacity items at errorRate.
+func newBloomLayer(capacity int, errorRate float64) *bloomLayer {
+	bitsPerItem := -math.Log(errorRate) / (math.Ln2 * math.Ln2)
+	bytes := (uint64(math.Ceil(float64(capacity)*bitsPerItem)) + 7) / 8
+	return &bloomLayer{
+		bits:      make([]byte, bytes),
+		size:      bytes * 8,
+		hashes:    int(math.Ceil(math.Ln2 * bitsPerItem)),
+		capacity:  capacity,
+		errorRate: errorRate,
+	}
+}
+
+func (l *bloomLayer) contains(a, b uint64) bool {
+	for i := 0; i < l.hashes; i++ {
+		bit := (a + uint64(i)*b) % l.size
+		if l.bits[bit/8]&(1<<(bit%8)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (l *bloomLayer) add(a, b uint64) {
+	for i := 0; i < l.hashes; i++ {
+		bit := (a + uint64(i)*b) % l.size
+		l.bits[bit/8] |= 1 << (bit % 8)
+	}
+	l.count++
+}
+
+func (f *bloomFilter) contains(item string) bool {
+	a, b := itemHashes(item)
+	for _, layer := range f.layers {
+		if layer.contains(a, b) {
+			return true
+		}
+	}
+	return false
+}
+
+// add adds item, returning false if it may have been added already.
+func (f *bloomFilter) add(item string) (bool, error) {
+	a, b := itemHashes(item)
+	for _, layer := range f.layers {
+		if layer.contains(a, b) {
+			return false, nil
+		}
+	}
+	last := f.layers[len(f.layers)-1]
+	if last.count >= last.capacity {
+		if f.expansion == 0 {
+			return false, errBloomFull
+		}
+		last = newBloomLayer(
+			last.capacity*f.expansion, last.errorRate*bloomErrorTighteningRatio,
+		)
+		f.layers = append(f.layers, last)
+	}
+	last.add(a, b)
+	return true, nil
+}
+
+func (f *bloomFilter) capacity() int {
+	result := 0
+	for _, layer := range f.layers {
+		result += layer.capacity
+	}
+	return result
+}
+
+func (f *bloomFilter) count() int {
+	result := 0
+	for _, layer := range f.layers {
+		result += layer.count
+	}
+	return result
+}
+
+// size returns the number of bytes the filter's bits take up.
+func (f *bloomFilter) size() int {
+	result := 0
+	for _, layer := range f.layers {
+		result += len(layer.bits)
+	}
+	return result
+}
+
+const (
+	defaultCuckooCapacity      = 1024
+	defaultCuckooBucketSize    = 2
+	defaultCuckooMaxIterations = 20
+	defaultCuckooExpansion     = 1
+)
+
+const (
+	errCuckooFull    CommandError = "ERR Filter is full"
+	errCuckooMissing CommandError = "ERR Not found"
+)
+
+// cuckooFilter is a Cuckoo filter, like RedisBloom's, which unlike a Bloom filter supports
+// deleting items and counting how many times they were added. Each item has an 8-bit
+// fingerprint that's stored in one of two buckets; when both are full, a random fingerprint is
+// kicked out of one to its other bucket, and so on for up to maxIterations moves. If that
+// fails, a new, larger layer is added.
+type cuckooFilter struct {
+	bucketSize    int
+	maxIterations int
+	// expansion is how many times more buckets each layer has than the last, or 0 if the
+	// filter doesn't scale.
+	expansion int
+	layers    []*cuckooLayer
+	inserted  int
+	deleted   int
+}
+
+// cuckooLayer is a power of two number of buckets, whose slots hold fingerprints, or 0 for an
+// empty slot.
+type cuckooLayer struct {
+	buckets uint64
+	slots   []byte
+}
+
+func newCuckooFilter(capacity, bucketSize, maxIterations, expansion int) *cuckooFilter {
+	buckets := nextPowerOfTwo(uint64((capacity + bucketSize - 1) / bucketSize))
+	return &cuckooFilter{
+		bucketSize:    bucketSize,
+		maxIterations: maxIterations,
+		expansion:     expansion,
+		layers:        []*cuckooLayer{newCuckooLayer(buckets, bucketSize)},
+	}
+}
+
+func newCuckooLayer(buckets uint64, bucketSize int) *cuckooLayer {
+	return &cuckooLayer{buckets: buckets, slots: make([]byte, buckets*uint64(bucketSize))}
+}
+
+func nextPowerOfTwo(n uint64) uint64 {
+	result := uint64(1)
+	for result < n {
+		result <<= 1
+	}
+	return result
+}
+
+// cuckooHash returns the fingerprint of item and a hash that its first bucket in each layer is
+// derived from.
+func cuckooHash(item string) (fingerprint byte, h uint64) {
+	a, b := itemHashes(item)
+	return byte(a%255) + 1, b
+}
+
+// indices returns the two buckets that fingerprint may be in.
+func (l *cuckooLayer) indices(fingerprint byte, h uint64) (uint64, uint64) {
+	i := h & (l.buckets - 1)
+	return i, l.altIndex(i, fingerprint)
+}
+
+// altIndex returns the other bucket that fingerprint may be in. Since the number of buckets is
+// a power of two, it's its own inverse.
+func (l *cuckooLayer) altIndex(i uint64, fingerprint byte) uint64 {
+	return (i ^ uint64(fingerprint)*0x5bd1e995) & (l.buckets - 1)
+}
+
+func (l *cuckooLayer) bucket(i uint64, bucketSize int) []byte {
+	return l.slots[i*uint64(bucketSize) : (i+1)*uint64(bucketSize)]
+}
+
+// insertInto stores fingerprint in an empty slot of bucket i, returning false if it's full.
+func (l *cuckooLayer) insertInto(i uint64, bucketSize int, fingerprint byte) bool {
+	bucket := l.bucket(i, bucketSize)
+	for j, slot := range bucket {
+		if slot == 0 {
+			bucket[j] = fingerprint
+			return true
+		}
+	}
+	return false
+}
+
+// count returns how many times fingerprint appears in bucket i.
+func (l *cuckooLayer) count(i uint64, bucketSize int, fingerprint byte) int {
+	result := 0
+	for _, slot := range l.bucket(i, bucketSize) {
+		if slot == fingerprint {
+			result++
+		}
+	}
+	return result
+}
+
+// kickOut stores fingerprint in bucket i by moving other fingerprints to their other buckets.
+// If that takes more than maxIterations moves, they're undone and it returns false.
+func (l *cuckooLayer) kickOut(i uint64, bucketSize, maxIterations int, fingerprint byte) bool {
+	var moves []uint64
+	for n := 0; n < maxIterations; n++ {
+		slot := i*uint64(bucketSize) + uint64(rand.Intn(bucketSize))
+		fingerprint, l.slots[slot] = l.slots[slot], fingerprint
+		moves = append(moves, slot)
+		i = l.altIndex(i, fingerprint)
+		if l.insertInto(i, bucketSize, fingerprint) {
+			return true
+		}
+	}
+	for n := len(moves) - 1; n >= 0; n-- {
+		fingerprint, l.slots[moves[n]] = l.slots[moves[n]], fingerprint
+	}
+	return false
+}
+
+func (f *cuckooFilter) add(item string) error {
+	fingerprint, h := cuckooHash(item)
+	for _, layer := range f.layers {
+		i1, i2 := layer.indices(fingerprint, h)
+		if layer.insertInto(i1, f.bucketSize, fingerprint) ||
+			layer.insertInto(i2, f.bucketSize, fingerprint) {
+			f.inserted++
+			return nil
+		}
+	}
+	last := f.layers[len(f.layers)-1]
+	i, _ := last.indices(fingerprint, h)
+	if !last.kickOut(i, f.bucketSize, f.maxIterations, fingerprint) {
+		if f.expansion == 0 {
+			return errCuckooFull
+		}
+		last = newCuckooLayer(last.buckets*nextPowerOfTwo(uint64(f.expansion)), f.bucketSize)
+		f.layers = append(f.layers, last)
+		i, _ = last.indices(fingerprint, h)
+		last.insertInto(i, f.bucketSize, fingerprint)
+	}
+	f.inserted++
+	return nil
+}
+
+// count returns how many times item may have been added and not deleted.
+func (f *cuckooFilter) count(item string) int {
+	fingerprint, h := cuckooHash(item)
+	result := 0
+	for _, layer := range f.layers {
+		i1, i2 := layer.indices(fingerprint, h)
+		result += layer.count(i1, f.bucketSize, fingerprint)
+		if i2 != i1 {
+			result += layer.count(i2, f.bucketSize, fingerprint)
+		}
+	}
+	return result
+}
+
+func (f *cuckooFilter) contains(item string) bool {
+	return f.count(item) > 0
+}
+
+// delete deletes one copy of item, newest layer first, returning false if it wasn't found.
+func (f *cuckooFilter) delete(item string) bool {
+	fingerprint, h := cuckooHash(item)
+	for n := len(f.layers) - 1; n >= 0; n-- {
+		layer := f.layers[n]
+		i1, i2 := layer.indices(fingerprint, h)
+		for _, i := range []uint64{i1, i2} {
+			bucket := layer.bucket(i, f.bucketSize)
+			for j, slot := range bucket {
+				if slot == fingerprint {
+					bucket[j] = 0
+					f.inserted--
+					f.deleted++
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func (f *cuckooFilter) buckets() uint64 {
+	result := uint64(0)
+	for _, layer := range f.layers {
+		result += layer.buckets
+	}
+	return result
+}
+
+// size returns the number of bytes the filter's slots take up.
+func (f *cuckooFilter) size() int {
+	result := 0
+	for _, layer := range f.layers {
+		result += len(layer.slots)
+	}
+	return result
+}
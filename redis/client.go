@@ -0,0 +1,361 @@
+package redis
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+func NewClients() *Clients {
+	return &Clients{
+		clients: make(map[int]*Client),
+	}
+}
+
+// Clients keeps track of every connected client, so that commands like CLIENT UNBLOCK can find
+// a client by its ID.
+type Clients struct {
+	mu      sync.Mutex
+	nextID  int
+	clients map[int]*Client
+}
+
+// NewClient registers a new client with the next unused ID. The client must be closed when its
+// connection is.
+func (c *Clients) NewClient() *Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.nextID++
+	client := &Client{
+		id:        c.nextID,
+		clients:   c,
+		proto:     2,
+		unblocked: make(chan error, 1),
+		pushed:    make(chan struct{}, 1),
+		closed:    make(chan struct{}),
+	}
+	c.clients[client.id] = client
+	return client
+}
+
+func (c *Clients) get(id int) (*Client, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	client, ok := c.clients[id]
+	return client, ok
+}
+
+// Client is the state of a single client connection that outlives any one command.
+type Client struct {
+	id      int
+	clients *Clients
+
+	mu      sync.Mutex
+	blocked bool
+	// unblocked receives a value when CLIENT UNBLOCK unblocks the client: nil to make the blocked
+	// command time out, or an error to make it fail with.
+	unblocked chan error
+	// pubSub is the broker that the client has subscribed to channels with, if any, which it
+	// unsubscribes from when it's closed.
+	pubSub *PubSub
+	// proto is the version of RESP that the client speaks, set with HELLO: 2 or 3.
+	proto int
+	// tracking is the tracking table that the client turned CLIENT TRACKING on with, if any,
+	// which it turns it off with when it's closed.
+	tracking *tracking
+	// caching is true if the client's last command was CLIENT CACHING, which affects whether
+	// the keys of its next command are tracked.
+	caching bool
+	// transaction is the transaction that the client started with MULTI, if any.
+	transaction *transaction
+	// watches is the table of watched keys that the client watched keys with, if any, which it
+	// stops watching them with when it's closed.
+	watches *watches
+
+	pushMu sync.Mutex
+	// pushes are the messages pushed to the client, like those of channels it's subscribed to,
+	// that are waiting to be written to its connection.
+	pushes        []string
+	pushedBytes   int
+	overSoftSince time.Time
+	// pushed receives a value when pushes stops being empty.
+	pushed chan struct{}
+
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+func (c *Client) ID() int {
+	return c.id
+}
+
+// Close unregisters the client, unsubscribes it from every channel and makes any command blocked
+// on its behalf give up. It's safe to call more than once.
+func (c *Client) Close() {
+	c.closeOnce.Do(func() {
+		c.clients.mu.Lock()
+		delete(c.clients.clients, c.id)
+		c.clients.mu.Unlock()
+
+		c.mu.Lock()
+		pubSub := c.pubSub
+		c.mu.Unlock()
+		if pubSub != nil {
+			pubSub.unsubscribeAll(c)
+		}
+		c.disableTracking()
+		c.unwatch()
+		close(c.closed)
+	})
+}
+
+// Closed returns a channel that's closed when the client is, for example because it sent QUIT or
+// fell too far behind on the messages pushed to it.
+func (c *Client) Closed() <-chan struct{} {
+	return c.closed
+}
+
+// isClosed returns true if the client has been closed.
+func (c *Client) isClosed() bool {
+	select {
+	case <-c.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+// protocol returns the version of RESP that the client speaks, 2 unless it switched to 3 with
+// HELLO. A nil client speaks RESP2.
+func (c *Client) protocol() int {
+	if c == nil {
+		return 2
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.proto
+}
+
+func (c *Client) setProtocol(protocol int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.proto = protocol
+}
+
+// pushMessage returns a message of elements, each of which must already be RESP-encoded, to be
+// sent to the client out of band, like a message of a channel it's subscribed to: a RESP3 push
+// if the client speaks RESP3, or else an array.
+func (c *Client) pushMessage(elements ...string) string {
+	if c.protocol() == 3 {
+		return pushArray(elements...)
+	}
+	return array(elements...)
+}
+
+// subscribed returns true if the client is subscribed to anything.
+func (c *Client) subscribed() bool {
+	c.mu.Lock()
+	pubSub := c.pubSub
+	c.mu.Unlock()
+	return pubSub != nil && pubSub.subscribed(c)
+}
+
+// setTracking records that the client turned CLIENT TRACKING on with tracking.
+func (c *Client) setTracking(tracking *tracking) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.tracking = tracking
+}
+
+// tracked returns true if the client has CLIENT TRACKING on.
+func (c *Client) tracked() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.tracking != nil
+}
+
+// disableTracking turns CLIENT TRACKING off for the client, if it's on.
+func (c *Client) disableTracking() {
+	c.mu.Lock()
+	tracking := c.tracking
+	c.tracking = nil
+	c.mu.Unlock()
+	if tracking != nil {
+		tracking.disable(c)
+	}
+}
+
+// setCaching records that the client sent CLIENT CACHING, so that it applies to its next
+// command.
+func (c *Client) setCaching() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.caching = true
+}
+
+// takeCaching returns true if the client's last command was CLIENT CACHING, and forgets it, so
+// that it only applies to the command after it.
+func (c *Client) takeCaching() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	result := c.caching
+	c.caching = false
+	return result
+}
+
+// startTransaction starts a transaction for the client, returning false if it's already in one.
+func (c *Client) startTransaction() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.transaction != nil {
+		return false
+	}
+	c.transaction = &transaction{}
+	return true
+}
+
+// inTransaction returns true if the client is in a transaction, so its commands are queued.
+func (c *Client) inTransaction() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.transaction != nil
+}
+
+// queue adds command to the client's transaction.
+func (c *Client) queue(command Command) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.transaction != nil {
+		c.transaction.commands = append(c.transaction.commands, command)
+	}
+}
+
+// failTransaction records that a command couldn't be queued in the client's transaction.
+func (c *Client) failTransaction() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.transaction != nil {
+		c.transaction.failed = true
+	}
+}
+
+// endTransaction ends the client's transaction, returning it, or nil if it wasn't in one.
+func (c *Client) endTransaction() *transaction {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	result := c.transaction
+	c.transaction = nil
+	return result
+}
+
+// setWatches records that the client watched keys with watches.
+func (c *Client) setWatches(watches *watches) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.watches = watches
+}
+
+// unwatch stops the client watching any keys. It returns the keys it watched, with whether each
+// had already expired when it was watched, and whether any of them changed since.
+func (c *Client) unwatch() (expired map[string]bool, changed bool) {
+	c.mu.Lock()
+	watches := c.watches
+	c.watches = nil
+	c.mu.Unlock()
+	if watches == nil {
+		return nil, false
+	}
+	return watches.unwatch(c)
+}
+
+// Pushed returns a channel that receives a value when messages have been pushed to the client.
+// Use TakePushes to get them.
+func (c *Client) Pushed() <-chan struct{} {
+	return c.pushed
+}
+
+// TakePushes returns the messages that have been pushed to the client since the last call, to
+// be written to its connection.
+func (c *Client) TakePushes() string {
+	c.pushMu.Lock()
+	defer c.pushMu.Unlock()
+
+	result := strings.Join(c.pushes, "")
+	c.pushes = nil
+	c.pushedBytes = 0
+	c.overSoftSince = time.Time{}
+	return result
+}
+
+// push queues message to be written to the client's connection. It returns false, dropping the
+// message, if the messages waiting to be written exceed limit at time now, in which case the
+// client must be closed.
+func (c *Client) push(message string, limit OutputBufferLimit, now time.Time) bool {
+	c.pushMu.Lock()
+	defer c.pushMu.Unlock()
+
+	if limit.exceeded(c.pushedBytes+len(message), &c.overSoftSince, now) {
+		return false
+	}
+	c.pushes = append(c.pushes, message)
+	c.pushedBytes += len(message)
+	select {
+	case c.pushed <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+const errUnblocked CommandError = "UNBLOCKED client unblocked via CLIENT UNBLOCK"
+
+// Blocked returns true if a command like BLPOP is blocked on the client's behalf.
+func (c *Client) Blocked() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.blocked
+}
+
+// setBlocked records whether a command is blocked on the client's behalf, so that only blocked
+// clients can be unblocked.
+func (c *Client) setBlocked(blocked bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.blocked = blocked
+	if !blocked {
+		// Drop any unblock that arrived after the command stopped waiting for one.
+		select {
+		case <-c.unblocked:
+		default:
+		}
+	}
+}
+
+// unblock unblocks the client if it's blocked, making its command fail with err, or time out if
+// err is nil. It returns false if the client wasn't blocked.
+func (c *Client) unblock(err error) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.blocked {
+		return false
+	}
+	c.blocked = false
+	c.unblocked <- err
+	return true
+}
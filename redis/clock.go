@@ -6,6 +6,14 @@ type Clock interface {
 	// NowMonotonic returns the current time with a "monotonic time" component. This makes it
 	// appropriate for measuring time with Time.After, Time.Before, Time.Compare and Time.Sub.
 	NowMonotonic() time.Time
+
+	// Now returns the current wall clock time, without a monotonic time component. This makes it
+	// appropriate for timestamps that are shown to clients, like the IDs of stream entries.
+	Now() time.Time
+
+	// After waits for the duration d to elapse and then sends the current time on the returned
+	// channel, like [time.After].
+	After(d time.Duration) <-chan time.Time
 }
 
 type RealClock struct{}
@@ -13,3 +21,11 @@ type RealClock struct{}
 func (r RealClock) NowMonotonic() time.Time {
 	return time.Now()
 }
+
+func (r RealClock) Now() time.Time {
+	return time.Now().Round(0)
+}
+
+func (r RealClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
@@ -31,3 +31,31 @@ func isNotMonotonicTime(t time.Time) bool {
 	// stripped off.
 	return t == t.Round(0)
 }
+
+func TestRealClock_Now(t *testing.T) {
+	t.Parallel()
+
+	r := redis.RealClock{}
+
+	got := r.Now()
+
+	if !isNotMonotonicTime(got) {
+		t.Errorf("Now() = %v, want wall clock time without a monotonic component", got)
+	}
+	if time.Since(got) > time.Second {
+		t.Errorf("Now() = %v, want real time within one second", got)
+	}
+}
+
+func TestRealClock_After(t *testing.T) {
+	t.Parallel()
+
+	r := redis.RealClock{}
+	start := time.Now()
+
+	<-r.After(10 * time.Millisecond)
+
+	if elapsed := time.Since(start); elapsed < 10*time.Millisecond {
+		t.Errorf("After(10ms) fired after %v, want at least 10ms", elapsed)
+	}
+}
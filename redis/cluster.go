@@ -0,0 +1,37 @@
+package redis
+
+import "strings"
+
+// clusterSlots is the number of hash slots that keys are assigned to in a Redis Cluster.
+const clusterSlots = 16384
+
+const errCrossSlot CommandError = "CROSSSLOT Keys in request don't hash to the same slot"
+
+// keyHashSlot returns the cluster hash slot of key, like Redis Cluster: the CRC16 of key modulo
+// the number of slots. If key has a hash tag, the non-empty part between its first "{" and the
+// "}" after it, only the hash tag is hashed, so that keys that share one share a slot.
+func keyHashSlot(key string) int {
+	if start := strings.IndexByte(key, '{'); start >= 0 {
+		if end := strings.IndexByte(key[start+1:], '}'); end > 0 {
+			key = key[start+1 : start+1+end]
+		}
+	}
+	return int(crc16(key)) % clusterSlots
+}
+
+// crc16 returns the CRC16 of s with the XMODEM parameters, which Redis Cluster uses: polynomial
+// 0x1021 and initial value 0.
+func crc16(s string) uint16 {
+	var crc uint16
+	for i := 0; i < len(s); i++ {
+		crc ^= uint16(s[i]) << 8
+		for bit := 0; bit < 8; bit++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
@@ -1,9 +1,11 @@
 package redis
 
 import (
+	"errors"
 	"fmt"
 	"reflect"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -32,17 +34,18 @@ type GetCommand struct {
 }
 
 func (g GetCommand) Run() string {
-	result, ok := g.store.Get(g.key)
-	if !ok {
-		return nullBulkString
-	}
-
-	expiryTime := result.ExpiryTime()
-	if expiryTime != nil && g.clock.NowMonotonic().After(*expiryTime) {
-		return nullBulkString
-	}
-
-	return bulkString(result.Data())
+	response := nullBulkString
+	g.store.read(g.clock.NowMonotonic(), func(tx *storeTx) {
+		result, ok, err := tx.getTyped(g.key, ValueTypeString)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		if ok {
+			response = bulkString(result.Data())
+		}
+	})
+	return response
 }
 
 type InfoKind string
@@ -80,6 +83,45 @@ func (i *InfoCommand) Run() string {
 	return bulkString(strings.Join(entries, "\n"))
 }
 
+func NewShutdownCommand(parser Parser, noSave bool) *ShutdownCommand {
+	return &ShutdownCommand{
+		parser: parser,
+		noSave: noSave,
+	}
+}
+
+// ShutdownCommand is SHUTDOWN [NOSAVE|SAVE], which asks the server to exit, without a reply.
+// Since keys are only kept in memory, nothing is saved either way, but like Redis, only SHUTDOWN
+// NOSAVE is allowed while a script is busy, and it kills the script even if it has written to
+// the keyspace.
+type ShutdownCommand struct {
+	parser Parser
+	noSave bool
+}
+
+func (s *ShutdownCommand) Run() string {
+	p := s.parser
+	err := p.scripts.busy(p.clock.NowMonotonic(), p.config.BusyReplyThreshold)
+	if err != nil && !s.noSave {
+		return errorResponse(err)
+	}
+	p.scripts.stop()
+	p.shutdown.close()
+	return ""
+}
+
+// shutdown is closed once the server has been asked to exit.
+type shutdown struct {
+	once sync.Once
+	done chan struct{}
+}
+
+func (s *shutdown) close() {
+	s.once.Do(func() {
+		close(s.done)
+	})
+}
+
 type PingCommand struct{}
 
 func (p PingCommand) Run() string {
@@ -144,7 +186,36 @@ func ExpiryTime(t time.Time) func(*SetCommand) {
 	}
 }
 
-const nullBulkString = "$-1\r\n"
+func NewObjectEncodingCommand(store *Store, clock Clock, key string) *ObjectEncodingCommand {
+	return &ObjectEncodingCommand{
+		store: store,
+		clock: clock,
+		key:   key,
+	}
+}
+
+// ObjectEncodingCommand is OBJECT ENCODING, which returns the name of the internal encoding of
+// the value at key.
+type ObjectEncodingCommand struct {
+	store *Store
+	clock Clock
+	key   string
+}
+
+func (o *ObjectEncodingCommand) Run() string {
+	response := nullBulkString
+	o.store.read(o.clock.NowMonotonic(), func(tx *storeTx) {
+		if value, ok := tx.get(o.key); ok {
+			response = bulkString(value.encoding())
+		}
+	})
+	return response
+}
+
+const (
+	nullBulkString = "$-1\r\n"
+	nullArray      = "*-1\r\n"
+)
 
 func bulkString(s string) string {
 	return fmt.Sprintf("$%d\r\n%s\r\n", len(s), s)
@@ -153,3 +224,41 @@ func bulkString(s string) string {
 func simpleString(s string) string {
 	return fmt.Sprintf("+%s\r\n", s)
 }
+
+func simpleError(s string) string {
+	return fmt.Sprintf("-%s\r\n", s)
+}
+
+// errorResponse returns the Redis error reply for err.
+func errorResponse(err error) string {
+	var commandErr CommandError
+	if errors.As(err, &commandErr) {
+		return commandErr.Response()
+	}
+	return simpleError("ERR " + err.Error())
+}
+
+func integer(i int) string {
+	return fmt.Sprintf(":%d\r\n", i)
+}
+
+// array returns a RESP array of elements, each of which must already be RESP-encoded.
+func array(elements ...string) string {
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("*%d\r\n", len(elements)))
+	for _, element := range elements {
+		builder.WriteString(element)
+	}
+	return builder.String()
+}
+
+// pushArray returns a RESP3 push of elements, like array, which RESP3 clients tell apart from
+// replies since it's sent out of band.
+func pushArray(elements ...string) string {
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf(">%d\r\n", len(elements)))
+	for _, element := range elements {
+		builder.WriteString(element)
+	}
+	return builder.String()
+}
@@ -0,0 +1,564 @@
+package redis
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+)
+
+func NewSetBitCommand(store *Store, clock Clock, key string, offset int, bit byte) *SetBitCommand {
+	return &SetBitCommand{
+		store:  store,
+		clock:  clock,
+		key:    key,
+		offset: offset,
+		bit:    bit,
+	}
+}
+
+type SetBitCommand struct {
+	store  *Store
+	clock  Clock
+	key    string
+	offset int
+	bit    byte
+}
+
+func (s *SetBitCommand) Run() string {
+	var response string
+	s.store.write(s.clock.NowMonotonic(), func(tx *storeTx) {
+		value, _, err := tx.getTyped(s.key, ValueTypeString)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		data := growToFit(value.bytes(), s.offset/8+1)
+		response = integer(int(getBit(data, s.offset)))
+		setBit(data, s.offset, s.bit)
+		tx.set(s.key, value.withData(data))
+		tx.notify(notifyString, "setbit", s.key)
+	})
+	return response
+}
+
+func NewGetBitCommand(store *Store, clock Clock, key string, offset int) *GetBitCommand {
+	return &GetBitCommand{
+		store:  store,
+		clock:  clock,
+		key:    key,
+		offset: offset,
+	}
+}
+
+type GetBitCommand struct {
+	store  *Store
+	clock  Clock
+	key    string
+	offset int
+}
+
+func (g *GetBitCommand) Run() string {
+	var response string
+	g.store.read(g.clock.NowMonotonic(), func(tx *storeTx) {
+		value, _, err := tx.getTyped(g.key, ValueTypeString)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		response = integer(int(getBit(value.bytes(), g.offset)))
+	})
+	return response
+}
+
+// BitRange is the range of a string that BITCOUNT and BITPOS look at. Start and End are
+// inclusive, and negative values count back from the end of the string. They're byte indexes
+// unless Bits is true, in which case they're bit indexes.
+type BitRange struct {
+	Start int
+	End   int
+	Bits  bool
+}
+
+// bitIndexes returns the inclusive range of bit indexes of a string with length bytes that r
+// refers to. The range is empty if start > end.
+func (r BitRange) bitIndexes(length int) (start, end int) {
+	total := length
+	if r.Bits {
+		total = length * 8
+	}
+	start, end = r.Start, r.End
+	if start < 0 {
+		start += total
+	}
+	if end < 0 {
+		end += total
+	}
+	if start < 0 {
+		start = 0
+	}
+	if end < 0 {
+		end = 0
+	}
+	if end >= total {
+		end = total - 1
+	}
+	if r.Bits {
+		return start, end
+	}
+	return start * 8, end*8 + 7
+}
+
+func NewBitCountCommand(store *Store, clock Clock, key string, bitRange *BitRange) *BitCountCommand {
+	return &BitCountCommand{
+		store:    store,
+		clock:    clock,
+		key:      key,
+		bitRange: bitRange,
+	}
+}
+
+type BitCountCommand struct {
+	store    *Store
+	clock    Clock
+	key      string
+	bitRange *BitRange
+}
+
+func (b *BitCountCommand) Run() string {
+	var response string
+	b.store.read(b.clock.NowMonotonic(), func(tx *storeTx) {
+		value, _, err := tx.getTyped(b.key, ValueTypeString)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		data := value.bytes()
+		start, end := 0, len(data)*8-1
+		if b.bitRange != nil {
+			start, end = b.bitRange.bitIndexes(len(data))
+		}
+		response = integer(countBits(data, start, end))
+	})
+	return response
+}
+
+// countBits returns the number of set bits between bit indexes start and end, inclusive.
+func countBits(data []byte, start, end int) int {
+	count := 0
+	i := start
+	for ; i <= end && i%8 != 0; i++ {
+		count += int(getBit(data, i))
+	}
+	// Count whole bytes at a time where possible.
+	for ; i+7 <= end; i += 8 {
+		count += bits.OnesCount8(data[i/8])
+	}
+	for ; i <= end; i++ {
+		count += int(getBit(data, i))
+	}
+	return count
+}
+
+func NewBitPosCommand(
+	store *Store,
+	clock Clock,
+	key string,
+	bit byte,
+	start *int,
+	bitRange *BitRange,
+) *BitPosCommand {
+	return &BitPosCommand{
+		store:    store,
+		clock:    clock,
+		key:      key,
+		bit:      bit,
+		start:    start,
+		bitRange: bitRange,
+	}
+}
+
+// BitPosCommand finds the first bit set to bit. It looks at the whole string by default, from
+// start to the end of the string if start is set, or in bitRange if that is set.
+type BitPosCommand struct {
+	store    *Store
+	clock    Clock
+	key      string
+	bit      byte
+	start    *int
+	bitRange *BitRange
+}
+
+func (b *BitPosCommand) Run() string {
+	var ok bool
+	var err error
+	result := -1
+	b.store.read(b.clock.NowMonotonic(), func(tx *storeTx) {
+		var value StoreValue
+		value, ok, err = tx.getTyped(b.key, ValueTypeString)
+		data := value.bytes()
+
+		bitRange := BitRange{Start: 0, End: -1}
+		switch {
+		case b.bitRange != nil:
+			bitRange = *b.bitRange
+		case b.start != nil:
+			bitRange.Start = *b.start
+		}
+		start, end := bitRange.bitIndexes(len(data))
+		for i := start; i <= end; i++ {
+			if getBit(data, i) == b.bit {
+				result = i
+				return
+			}
+		}
+		// When looking for a clear bit without an explicit end, the string is treated as if
+		// it's padded with zero bytes on the right, so the first clear bit is just past it.
+		if b.bit == 0 && b.bitRange == nil && start <= end {
+			result = end + 1
+		}
+	})
+	if err != nil {
+		return errorResponse(err)
+	}
+	if !ok {
+		if b.bit == 0 {
+			return integer(0)
+		}
+		return integer(-1)
+	}
+	return integer(result)
+}
+
+type BitOp int
+
+const (
+	BitOpAnd BitOp = iota
+	BitOpOr
+	BitOpXor
+	BitOpNot
+)
+
+func NewBitOpCommand(
+	store *Store,
+	clock Clock,
+	op BitOp,
+	destination string,
+	keys []string,
+) *BitOpCommand {
+	return &BitOpCommand{
+		store:       store,
+		clock:       clock,
+		op:          op,
+		destination: destination,
+		keys:        keys,
+	}
+}
+
+type BitOpCommand struct {
+	store       *Store
+	clock       Clock
+	op          BitOp
+	destination string
+	keys        []string
+}
+
+func (b *BitOpCommand) Run() string {
+	var response string
+	b.store.write(b.clock.NowMonotonic(), func(tx *storeTx) {
+		length := 0
+		sources := make([][]byte, len(b.keys))
+		for i, key := range b.keys {
+			value, _, err := tx.getTyped(key, ValueTypeString)
+			if err != nil {
+				response = errorResponse(err)
+				return
+			}
+			sources[i] = value.bytes()
+			if len(sources[i]) > length {
+				length = len(sources[i])
+			}
+		}
+		response = integer(length)
+
+		result := make([]byte, length)
+		for i := range result {
+			result[i] = b.apply(sources, i)
+		}
+
+		if length == 0 {
+			if tx.delete(b.destination) {
+				tx.notify(notifyGeneric, "del", b.destination)
+			}
+			return
+		}
+		tx.set(b.destination, StoreValue{data: result})
+		tx.notify(notifyString, "set", b.destination)
+	})
+	return response
+}
+
+// apply returns the result of b's operation on the byte at index i of each source, treating
+// sources that are too short as if they're padded with zero bytes.
+func (b *BitOpCommand) apply(sources [][]byte, i int) byte {
+	byteAt := func(source []byte) byte {
+		if i < len(source) {
+			return source[i]
+		}
+		return 0
+	}
+
+	result := byteAt(sources[0])
+	if b.op == BitOpNot {
+		return ^result
+	}
+	for _, source := range sources[1:] {
+		switch b.op {
+		case BitOpAnd:
+			result &= byteAt(source)
+		case BitOpOr:
+			result |= byteAt(source)
+		case BitOpXor:
+			result ^= byteAt(source)
+		}
+	}
+	return result
+}
+
+// BitfieldOverflow is how BITFIELD handles SET and INCRBY results that don't fit in their field.
+type BitfieldOverflow int
+
+const (
+	// BitfieldOverflowWrap wraps around, like integer overflow in Go.
+	BitfieldOverflowWrap BitfieldOverflow = iota
+	// BitfieldOverflowSat saturates to the field's minimum or maximum value.
+	BitfieldOverflowSat
+	// BitfieldOverflowFail leaves the field unchanged and returns a null reply.
+	BitfieldOverflowFail
+)
+
+type BitfieldOpKind int
+
+const (
+	BitfieldGet BitfieldOpKind = iota
+	BitfieldSet
+	BitfieldIncrBy
+)
+
+// BitfieldOp is one GET, SET or INCRBY subcommand of BITFIELD.
+type BitfieldOp struct {
+	Kind BitfieldOpKind
+	// Signed and Bits are the field's type, like i8 or u16.
+	Signed bool
+	Bits   int
+	// Offset is the index of the field's first bit.
+	Offset int
+	// Value is the value to set for BitfieldSet or the increment for BitfieldIncrBy.
+	Value    int64
+	Overflow BitfieldOverflow
+}
+
+func NewBitfieldCommand(store *Store, clock Clock, key string, ops []BitfieldOp) *BitfieldCommand {
+	return &BitfieldCommand{
+		store: store,
+		clock: clock,
+		key:   key,
+		ops:   ops,
+	}
+}
+
+// BitfieldCommand is BITFIELD, or BITFIELD_RO when all of its ops are BitfieldGet.
+type BitfieldCommand struct {
+	store *Store
+	clock Clock
+	key   string
+	ops   []BitfieldOp
+}
+
+func (b *BitfieldCommand) Run() string {
+	var response string
+	run := func(tx *storeTx) {
+		value, _, err := tx.getTyped(b.key, ValueTypeString)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		data := value.bytes()
+		writeEnd := 0
+		for _, op := range b.ops {
+			if op.Kind != BitfieldGet && op.Offset+op.Bits > writeEnd {
+				writeEnd = op.Offset + op.Bits
+			}
+		}
+		if writeEnd > 0 {
+			// Like Redis, grow the string for every write up front, even if it later fails.
+			data = growToFit(data, (writeEnd+7)/8)
+		}
+
+		responses := make([]string, len(b.ops))
+		for i, op := range b.ops {
+			responses[i] = op.run(data)
+		}
+		response = array(responses...)
+
+		if writeEnd > 0 {
+			tx.set(b.key, value.withData(data))
+			tx.notify(notifyString, "setbit", b.key)
+		}
+	}
+
+	if b.readOnly() {
+		b.store.read(b.clock.NowMonotonic(), run)
+	} else {
+		b.store.write(b.clock.NowMonotonic(), run)
+	}
+	return response
+}
+
+func (b *BitfieldCommand) readOnly() bool {
+	for _, op := range b.ops {
+		if op.Kind != BitfieldGet {
+			return false
+		}
+	}
+	return true
+}
+
+// run runs o against data, which must be long enough to hold o's field if o writes to it, and
+// returns o's response.
+func (o BitfieldOp) run(data []byte) string {
+	current := o.get(data)
+	switch o.Kind {
+	case BitfieldGet:
+		return integer(int(current))
+	case BitfieldSet:
+		newValue, ok := o.limit(o.Value, 0)
+		if !ok {
+			return nullBulkString
+		}
+		o.set(data, newValue)
+		return integer(int(current))
+	case BitfieldIncrBy:
+		newValue, ok := o.limit(current, o.Value)
+		if !ok {
+			return nullBulkString
+		}
+		o.set(data, newValue)
+		return integer(int(newValue))
+	}
+	panic(fmt.Sprintf("unknown redis.BitfieldOpKind: %d", o.Kind))
+}
+
+// get returns o's field in data, sign-extended if o is signed. Bits beyond the end of data are
+// treated as zero.
+func (o BitfieldOp) get(data []byte) int64 {
+	var result uint64
+	for i := 0; i < o.Bits; i++ {
+		result = result<<1 | uint64(getBit(data, o.Offset+i))
+	}
+	if o.Signed && o.Bits < 64 && result&(1<<(o.Bits-1)) != 0 {
+		result |= math.MaxUint64 << o.Bits
+	}
+	return int64(result)
+}
+
+func (o BitfieldOp) set(data []byte, value int64) {
+	for i := 0; i < o.Bits; i++ {
+		bit := byte(uint64(value) >> (o.Bits - 1 - i) & 1)
+		setBit(data, o.Offset+i, bit)
+	}
+}
+
+// limit returns value+increment handled according to o's overflow behaviour if it doesn't fit
+// in o's field, or false if it doesn't fit and o's overflow behaviour is BitfieldOverflowFail.
+// This follows Redis's checkSignedBitfieldOverflow and checkUnsignedBitfieldOverflow.
+func (o BitfieldOp) limit(value, increment int64) (int64, bool) {
+	if o.Signed {
+		return o.limitSigned(value, increment)
+	}
+	return o.limitUnsigned(uint64(value), increment)
+}
+
+func (o BitfieldOp) limitSigned(value, increment int64) (int64, bool) {
+	max := int64(math.MaxInt64)
+	if o.Bits != 64 {
+		max = 1<<(o.Bits-1) - 1
+	}
+	min := -max - 1
+	maxIncrement := max - value
+	minIncrement := min - value
+
+	overflow := value > max ||
+		(o.Bits != 64 && increment > maxIncrement) ||
+		(value >= 0 && increment > 0 && increment > maxIncrement)
+	underflow := value < min ||
+		(o.Bits != 64 && increment < minIncrement) ||
+		(value < 0 && increment < 0 && increment < minIncrement)
+	switch {
+	case !overflow && !underflow:
+		return value + increment, true
+	case o.Overflow == BitfieldOverflowFail:
+		return 0, false
+	case o.Overflow == BitfieldOverflowSat && overflow:
+		return max, true
+	case o.Overflow == BitfieldOverflowSat:
+		return min, true
+	}
+
+	result := uint64(value) + uint64(increment)
+	if o.Bits < 64 {
+		mask := uint64(math.MaxUint64) << o.Bits
+		if result&(1<<(o.Bits-1)) != 0 {
+			result |= mask
+		} else {
+			result &^= mask
+		}
+	}
+	return int64(result), true
+}
+
+func (o BitfieldOp) limitUnsigned(value uint64, increment int64) (int64, bool) {
+	max := uint64(1)<<o.Bits - 1
+	maxIncrement := int64(max - value)
+	minIncrement := -int64(value)
+
+	overflow := value > max || increment > maxIncrement
+	underflow := !overflow && increment < 0 && increment < minIncrement
+	switch {
+	case !overflow && !underflow:
+		return int64(value + uint64(increment)), true
+	case o.Overflow == BitfieldOverflowFail:
+		return 0, false
+	case o.Overflow == BitfieldOverflowSat && overflow:
+		return int64(max), true
+	case o.Overflow == BitfieldOverflowSat:
+		return 0, true
+	}
+	return int64((value + uint64(increment)) & max), true
+}
+
+// getBit returns the bit at offset in data, where offset 0 is the most significant bit of the
+// first byte. Bits beyond the end of data are treated as zero.
+func getBit(data []byte, offset int) byte {
+	if offset/8 >= len(data) {
+		return 0
+	}
+	return data[offset/8] >> (7 - offset%8) & 1
+}
+
+// setBit sets the bit at offset in data, which must be long enough to hold it.
+func setBit(data []byte, offset int, bit byte) {
+	mask := byte(1) << (7 - offset%8)
+	if bit == 0 {
+		data[offset/8] &^= mask
+	} else {
+		data[offset/8] |= mask
+	}
+}
+
+// growToFit returns data padded with zero bytes so that it's at least length bytes long.
+func growToFit(data []byte, length int) []byte {
+	if len(data) >= length {
+		return data
+	}
+	return append(data, make([]byte, length-len(data))...)
+}
@@ -0,0 +1,296 @@
+package redis_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/redis"
+)
+
+func TestSetBitCommand(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	store.SetWithExpiryTime("mykey", "", time.UnixMilli(100))
+	clock := FakeClock{CurrentTime: time.UnixMilli(0)}
+
+	if response := redis.NewSetBitCommand(store, clock, "mykey", 7, 1).Run(); response != ":0\r\n" {
+		t.Errorf(`command expected to return ":0\r\n" but was %#v`, response)
+	}
+	if response := redis.NewSetBitCommand(store, clock, "mykey", 7, 0).Run(); response != ":1\r\n" {
+		t.Errorf(`command expected to return ":1\r\n" but was %#v`, response)
+	}
+	redis.NewSetBitCommand(store, clock, "mykey", 17, 1).Run()
+
+	value, _ := store.Get("mykey")
+	if value.Data() != "\x00\x00\x40" {
+		t.Errorf(`value.Data() expected to be "\x00\x00\x40" but was %#v`, value.Data())
+	}
+	if value.ExpiryTime() == nil {
+		t.Errorf(`value.ExpiryTime() expected to be kept but was nil`)
+	}
+}
+
+func TestGetBitCommand(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	store.Set("mykey", "\x40")
+	clock := FakeClock{}
+
+	tests := []struct {
+		offset   int
+		response string
+	}{
+		{offset: 0, response: ":0\r\n"},
+		{offset: 1, response: ":1\r\n"},
+		{offset: 100, response: ":0\r\n"},
+	}
+
+	for _, tt := range tests {
+		response := redis.NewGetBitCommand(store, clock, "mykey", tt.offset).Run()
+		if response != tt.response {
+			t.Errorf(`GETBIT mykey %d expected to return %#v but was %#v`, tt.offset, tt.response, response)
+		}
+	}
+}
+
+func TestBitCountCommand(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		bitRange *redis.BitRange
+		response string
+	}{
+		{name: "whole string", bitRange: nil, response: ":26\r\n"},
+		{name: "0 0", bitRange: &redis.BitRange{Start: 0, End: 0}, response: ":4\r\n"},
+		{name: "1 1", bitRange: &redis.BitRange{Start: 1, End: 1}, response: ":6\r\n"},
+		{name: "1 1 BYTE", bitRange: &redis.BitRange{Start: 1, End: 1}, response: ":6\r\n"},
+		{name: "5 30 BIT", bitRange: &redis.BitRange{Start: 5, End: 30, Bits: true}, response: ":17\r\n"},
+		{name: "-2 -1", bitRange: &redis.BitRange{Start: -2, End: -1}, response: ":7\r\n"},
+		{name: "2 1", bitRange: &redis.BitRange{Start: 2, End: 1}, response: ":0\r\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := redis.NewStore()
+			store.Set("mykey", "foobar")
+			clock := FakeClock{}
+
+			response := redis.NewBitCountCommand(store, clock, "mykey", tt.bitRange).Run()
+
+			if response != tt.response {
+				t.Errorf(`command expected to return %#v but was %#v`, tt.response, response)
+			}
+		})
+	}
+}
+
+func TestBitPosCommand(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		data     string
+		bit      byte
+		start    *int
+		bitRange *redis.BitRange
+		response string
+	}{
+		{name: "first clear bit", data: "\xff\xf0\x00", bit: 0, response: ":12\r\n"},
+		{name: "first set bit", data: "\x00\xff\xf0", bit: 1, response: ":8\r\n"},
+		{name: "first set bit from 2", data: "\x00\xff\xf0", bit: 1, start: ptr(2), response: ":16\r\n"},
+		{
+			name:     "first set bit in bit range",
+			data:     "\x00\xff\xf0",
+			bit:      1,
+			bitRange: &redis.BitRange{Start: 7, End: 15, Bits: true},
+			response: ":8\r\n",
+		},
+		{name: "no clear bits", data: "\xff\xff", bit: 0, response: ":16\r\n"},
+		{
+			name:     "no clear bits in explicit range",
+			data:     "\xff\xff",
+			bit:      0,
+			bitRange: &redis.BitRange{Start: 0, End: -1},
+			response: ":-1\r\n",
+		},
+		{name: "no set bits", data: "\x00\x00", bit: 1, response: ":-1\r\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := redis.NewStore()
+			store.Set("mykey", tt.data)
+			clock := FakeClock{}
+
+			response := redis.NewBitPosCommand(store, clock, "mykey", tt.bit, tt.start, tt.bitRange).Run()
+
+			if response != tt.response {
+				t.Errorf(`command expected to return %#v but was %#v`, tt.response, response)
+			}
+		})
+	}
+
+	t.Run("absent key", func(t *testing.T) {
+		store := redis.NewStore()
+		clock := FakeClock{}
+
+		if response := redis.NewBitPosCommand(store, clock, "k", 0, nil, nil).Run(); response != ":0\r\n" {
+			t.Errorf(`command expected to return ":0\r\n" but was %#v`, response)
+		}
+		if response := redis.NewBitPosCommand(store, clock, "k", 1, nil, nil).Run(); response != ":-1\r\n" {
+			t.Errorf(`command expected to return ":-1\r\n" but was %#v`, response)
+		}
+	})
+}
+
+func TestBitOpCommand(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		op       redis.BitOp
+		keys     []string
+		response string
+		data     string
+	}{
+		{name: "AND", op: redis.BitOpAnd, keys: []string{"a", "b"}, response: ":2\r\n", data: "\x0f\x00"},
+		{name: "OR", op: redis.BitOpOr, keys: []string{"a", "b"}, response: ":2\r\n", data: "\xff\xf0"},
+		{name: "XOR", op: redis.BitOpXor, keys: []string{"a", "b"}, response: ":2\r\n", data: "\xf0\xf0"},
+		{name: "NOT", op: redis.BitOpNot, keys: []string{"b"}, response: ":1\r\n", data: "\xf0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := redis.NewStore()
+			store.Set("a", "\xff\xf0")
+			store.Set("b", "\x0f")
+			clock := FakeClock{}
+
+			response := redis.NewBitOpCommand(store, clock, tt.op, "dest", tt.keys).Run()
+
+			if response != tt.response {
+				t.Errorf(`command expected to return %#v but was %#v`, tt.response, response)
+			}
+			if value, _ := store.Get("dest"); value.Data() != tt.data {
+				t.Errorf(`value.Data() expected to be %#v but was %#v`, tt.data, value.Data())
+			}
+		})
+	}
+}
+
+func TestBitfieldCommand(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		data     string
+		ops      []redis.BitfieldOp
+		response string
+		newData  string
+	}{
+		{
+			name: "INCRBY i5 100 1 GET u4 0",
+			data: "",
+			ops: []redis.BitfieldOp{
+				{Kind: redis.BitfieldIncrBy, Signed: true, Bits: 5, Offset: 100, Value: 1},
+				{Kind: redis.BitfieldGet, Bits: 4, Offset: 0},
+			},
+			response: "*2\r\n:1\r\n:0\r\n",
+			newData:  "\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x80",
+		},
+		{
+			name: "SET u8 0 255 GET i8 0",
+			data: "",
+			ops: []redis.BitfieldOp{
+				{Kind: redis.BitfieldSet, Bits: 8, Offset: 0, Value: 255},
+				{Kind: redis.BitfieldGet, Signed: true, Bits: 8, Offset: 0},
+			},
+			response: "*2\r\n:0\r\n:-1\r\n",
+			newData:  "\xff",
+		},
+		{
+			name: "INCRBY u2 102 1 with overflow WRAP",
+			data: "\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x03",
+			ops: []redis.BitfieldOp{
+				{Kind: redis.BitfieldIncrBy, Bits: 2, Offset: 102, Value: 1},
+			},
+			response: "*1\r\n:0\r\n",
+			newData:  "\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00",
+		},
+		{
+			name: "INCRBY u2 102 1 with overflow SAT",
+			data: "\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x03",
+			ops: []redis.BitfieldOp{
+				{
+					Kind:     redis.BitfieldIncrBy,
+					Bits:     2,
+					Offset:   102,
+					Value:    1,
+					Overflow: redis.BitfieldOverflowSat,
+				},
+			},
+			response: "*1\r\n:3\r\n",
+			newData:  "\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x03",
+		},
+		{
+			name: "INCRBY i8 0 -200 with overflow SAT",
+			data: "\x00",
+			ops: []redis.BitfieldOp{
+				{
+					Kind:     redis.BitfieldIncrBy,
+					Signed:   true,
+					Bits:     8,
+					Offset:   0,
+					Value:    -200,
+					Overflow: redis.BitfieldOverflowSat,
+				},
+			},
+			response: "*1\r\n:-128\r\n",
+			newData:  "\x80",
+		},
+		{
+			name: "INCRBY i8 0 100 with overflow WRAP",
+			data: "\x64",
+			ops: []redis.BitfieldOp{
+				{Kind: redis.BitfieldIncrBy, Signed: true, Bits: 8, Offset: 0, Value: 100},
+			},
+			response: "*1\r\n:-56\r\n",
+			newData:  "\xc8",
+		},
+		{
+			name: "INCRBY u2 102 1 with overflow FAIL",
+			data: "\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x03",
+			ops: []redis.BitfieldOp{
+				{
+					Kind:     redis.BitfieldIncrBy,
+					Bits:     2,
+					Offset:   102,
+					Value:    1,
+					Overflow: redis.BitfieldOverflowFail,
+				},
+			},
+			response: "*1\r\n$-1\r\n",
+			newData:  "\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x03",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := redis.NewStore()
+			store.Set("mykey", tt.data)
+			clock := FakeClock{}
+
+			response := redis.NewBitfieldCommand(store, clock, "mykey", tt.ops).Run()
+
+			if response != tt.response {
+				t.Errorf(`command expected to return %#v but was %#v`, tt.response, response)
+			}
+			if value, _ := store.Get("mykey"); value.Data() != tt.newData {
+				t.Errorf(`value.Data() expected to be %#v but was %#v`, tt.newData, value.Data())
+			}
+		})
+	}
+}
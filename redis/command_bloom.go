@@ -0,0 +1,525 @@
+package redis
+
+// getBloomFilter returns the Bloom filter at key, or false if there isn't one.
+func getBloomFilter(tx *storeTx, key string) (*bloomFilter, bool, error) {
+	value, ok, err := tx.getTyped(key, ValueTypeBloom)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	return value.bloom(), true, nil
+}
+
+func NewBFReserveCommand(
+	store *Store,
+	clock Clock,
+	key string,
+	errorRate float64,
+	capacity int,
+	expansion int,
+) *BFReserveCommand {
+	return &BFReserveCommand{
+		store:     store,
+		clock:     clock,
+		key:       key,
+		errorRate: errorRate,
+		capacity:  capacity,
+		expansion: expansion,
+	}
+}
+
+// BFReserveCommand is BF.RESERVE, which creates an empty Bloom filter. An expansion of 0 is
+// NONSCALING.
+type BFReserveCommand struct {
+	store     *Store
+	clock     Clock
+	key       string
+	errorRate float64
+	capacity  int
+	expansion int
+}
+
+func (b *BFReserveCommand) Run() string {
+	var response string
+	b.store.write(b.clock.NowMonotonic(), func(tx *storeTx) {
+		if _, ok := tx.get(b.key); ok {
+			response = errorResponse(errBloomExists)
+			return
+		}
+		tx.set(b.key, StoreValue{data: newBloomFilter(b.errorRate, b.capacity, b.expansion)})
+		tx.notify(notifyModule, "bf.reserve", b.key)
+		response = simpleString("OK")
+	})
+	return response
+}
+
+func NewBFAddCommand(store *Store, clock Clock, key string, items []string) *BFAddCommand {
+	return &BFAddCommand{
+		store: store,
+		clock: clock,
+		key:   key,
+		items: items,
+	}
+}
+
+// BFAddCommand is BF.ADD, which adds an item to a Bloom filter and replies with whether it was
+// added, or BF.MADD, which replies with an array of whether each of its items was. A filter
+// with the default error rate and capacity is created if there isn't one.
+type BFAddCommand struct {
+	store *Store
+	clock Clock
+	key   string
+	items []string
+	multi bool
+}
+
+func NewBFMAddCommand(store *Store, clock Clock, key string, items []string) *BFAddCommand {
+	result := NewBFAddCommand(store, clock, key, items)
+	result.multi = true
+	return result
+}
+
+func (b *BFAddCommand) Run() string {
+	var response string
+	b.store.write(b.clock.NowMonotonic(), func(tx *storeTx) {
+		filter, ok, err := getBloomFilter(tx, b.key)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		if !ok {
+			filter = newBloomFilter(
+				defaultBloomErrorRate, defaultBloomCapacity, defaultBloomExpansion,
+			)
+			tx.set(b.key, StoreValue{data: filter})
+		}
+		changed := !ok
+		elements := make([]string, len(b.items))
+		for i, item := range b.items {
+			added, err := filter.add(item)
+			if err != nil {
+				elements[i] = errorResponse(err)
+			} else if added {
+				elements[i] = integer(1)
+				changed = true
+			} else {
+				elements[i] = integer(0)
+			}
+		}
+		if b.multi {
+			response = array(elements...)
+		} else {
+			response = elements[0]
+		}
+		if changed && b.multi {
+			tx.notify(notifyModule, "bf.madd", b.key)
+		} else if changed {
+			tx.notify(notifyModule, "bf.add", b.key)
+		}
+	})
+	return response
+}
+
+func NewBFExistsCommand(store *Store, clock Clock, key string, items []string) *BFExistsCommand {
+	return &BFExistsCommand{
+		store: store,
+		clock: clock,
+		key:   key,
+		items: items,
+	}
+}
+
+// BFExistsCommand is BF.EXISTS, which replies with whether an item may have been added to a
+// Bloom filter, or BF.MEXISTS, which replies with an array of whether each of its items may
+// have been.
+type BFExistsCommand struct {
+	store *Store
+	clock Clock
+	key   string
+	items []string
+	multi bool
+}
+
+func NewBFMExistsCommand(store *Store, clock Clock, key string, items []string) *BFExistsCommand {
+	result := NewBFExistsCommand(store, clock, key, items)
+	result.multi = true
+	return result
+}
+
+func (b *BFExistsCommand) Run() string {
+	var response string
+	b.store.read(b.clock.NowMonotonic(), func(tx *storeTx) {
+		filter, ok, err := getBloomFilter(tx, b.key)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		elements := make([]string, len(b.items))
+		for i, item := range b.items {
+			if ok && filter.contains(item) {
+				elements[i] = integer(1)
+			} else {
+				elements[i] = integer(0)
+			}
+		}
+		if b.multi {
+			response = array(elements...)
+		} else {
+			response = elements[0]
+		}
+	})
+	return response
+}
+
+// BFInfoField is the field of BF.INFO to reply with, or BFInfoAll for all of them.
+type BFInfoField int
+
+const (
+	BFInfoAll BFInfoField = iota
+	BFInfoCapacity
+	BFInfoSize
+	BFInfoFilters
+	BFInfoItems
+	BFInfoExpansion
+)
+
+func NewBFInfoCommand(store *Store, clock Clock, key string, field BFInfoField) *BFInfoCommand {
+	return &BFInfoCommand{
+		store: store,
+		clock: clock,
+		key:   key,
+		field: field,
+	}
+}
+
+// BFInfoCommand is BF.INFO, which replies with information about a Bloom filter.
+type BFInfoCommand struct {
+	store *Store
+	clock Clock
+	key   string
+	field BFInfoField
+}
+
+func (b *BFInfoCommand) Run() string {
+	var response string
+	b.store.read(b.clock.NowMonotonic(), func(tx *storeTx) {
+		filter, ok, err := getBloomFilter(tx, b.key)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		if !ok {
+			response = errorResponse(errBloomMissing)
+			return
+		}
+		expansion := nullBulkString
+		if filter.expansion > 0 {
+			expansion = integer(filter.expansion)
+		}
+		values := []string{
+			BFInfoCapacity:  integer(filter.capacity()),
+			BFInfoSize:      integer(filter.size()),
+			BFInfoFilters:   integer(len(filter.layers)),
+			BFInfoItems:     integer(filter.count()),
+			BFInfoExpansion: expansion,
+		}
+		if b.field != BFInfoAll {
+			response = array(values[b.field])
+			return
+		}
+		response = array(
+			simpleString("Capacity"), values[BFInfoCapacity],
+			simpleString("Size"), values[BFInfoSize],
+			simpleString("Number of filters"), values[BFInfoFilters],
+			simpleString("Number of items inserted"), values[BFInfoItems],
+			simpleString("Expansion rate"), values[BFInfoExpansion],
+		)
+	})
+	return response
+}
+
+// getCuckooFilter returns the Cuckoo filter at key, or false if there isn't one.
+func getCuckooFilter(tx *storeTx, key string) (*cuckooFilter, bool, error) {
+	value, ok, err := tx.getTyped(key, ValueTypeCuckoo)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	return value.cuckoo(), true, nil
+}
+
+func NewCFReserveCommand(
+	store *Store,
+	clock Clock,
+	key string,
+	capacity int,
+	bucketSize int,
+	maxIterations int,
+	expansion int,
+) *CFReserveCommand {
+	return &CFReserveCommand{
+		store:         store,
+		clock:         clock,
+		key:           key,
+		capacity:      capacity,
+		bucketSize:    bucketSize,
+		maxIterations: maxIterations,
+		expansion:     expansion,
+	}
+}
+
+// CFReserveCommand is CF.RESERVE, which creates an empty Cuckoo filter.
+type CFReserveCommand struct {
+	store         *Store
+	clock         Clock
+	key           string
+	capacity      int
+	bucketSize    int
+	maxIterations int
+	expansion     int
+}
+
+func (c *CFReserveCommand) Run() string {
+	var response string
+	c.store.write(c.clock.NowMonotonic(), func(tx *storeTx) {
+		if _, ok := tx.get(c.key); ok {
+			response = errorResponse(errBloomExists)
+			return
+		}
+		filter := newCuckooFilter(c.capacity, c.bucketSize, c.maxIterations, c.expansion)
+		tx.set(c.key, StoreValue{data: filter})
+		tx.notify(notifyModule, "cf.reserve", c.key)
+		response = simpleString("OK")
+	})
+	return response
+}
+
+func NewCFAddCommand(store *Store, clock Clock, key, item string) *CFAddCommand {
+	return &CFAddCommand{
+		store: store,
+		clock: clock,
+		key:   key,
+		item:  item,
+	}
+}
+
+// CFAddCommand is CF.ADD, which adds an item to a Cuckoo filter even if it's already there, or
+// CF.ADDNX, which only adds it if it isn't, replying with whether it was added. A filter with
+// the default capacity is created if there isn't one.
+type CFAddCommand struct {
+	store        *Store
+	clock        Clock
+	key          string
+	item         string
+	onlyIfAbsent bool
+}
+
+func NewCFAddNXCommand(store *Store, clock Clock, key, item string) *CFAddCommand {
+	result := NewCFAddCommand(store, clock, key, item)
+	result.onlyIfAbsent = true
+	return result
+}
+
+func (c *CFAddCommand) Run() string {
+	var response string
+	c.store.write(c.clock.NowMonotonic(), func(tx *storeTx) {
+		filter, ok, err := getCuckooFilter(tx, c.key)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		if !ok {
+			filter = newCuckooFilter(
+				defaultCuckooCapacity,
+				defaultCuckooBucketSize,
+				defaultCuckooMaxIterations,
+				defaultCuckooExpansion,
+			)
+			tx.set(c.key, StoreValue{data: filter})
+		}
+		event := "cf.add"
+		if c.onlyIfAbsent {
+			event = "cf.addnx"
+		}
+		if c.onlyIfAbsent && filter.contains(c.item) {
+			if !ok {
+				tx.notify(notifyModule, event, c.key)
+			}
+			response = integer(0)
+			return
+		}
+		if err := filter.add(c.item); err != nil {
+			if !ok {
+				tx.notify(notifyModule, event, c.key)
+			}
+			response = errorResponse(err)
+			return
+		}
+		tx.notify(notifyModule, event, c.key)
+		response = integer(1)
+	})
+	return response
+}
+
+func NewCFExistsCommand(store *Store, clock Clock, key string, items []string) *CFExistsCommand {
+	return &CFExistsCommand{
+		store: store,
+		clock: clock,
+		key:   key,
+		items: items,
+	}
+}
+
+// CFExistsCommand is CF.EXISTS, which replies with whether an item may be in a Cuckoo filter,
+// or CF.MEXISTS, which replies with an array of whether each of its items may be.
+type CFExistsCommand struct {
+	store *Store
+	clock Clock
+	key   string
+	items []string
+	multi bool
+}
+
+func NewCFMExistsCommand(store *Store, clock Clock, key string, items []string) *CFExistsCommand {
+	result := NewCFExistsCommand(store, clock, key, items)
+	result.multi = true
+	return result
+}
+
+func (c *CFExistsCommand) Run() string {
+	var response string
+	c.store.read(c.clock.NowMonotonic(), func(tx *storeTx) {
+		filter, ok, err := getCuckooFilter(tx, c.key)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		elements := make([]string, len(c.items))
+		for i, item := range c.items {
+			if ok && filter.contains(item) {
+				elements[i] = integer(1)
+			} else {
+				elements[i] = integer(0)
+			}
+		}
+		if c.multi {
+			response = array(elements...)
+		} else {
+			response = elements[0]
+		}
+	})
+	return response
+}
+
+func NewCFDelCommand(store *Store, clock Clock, key, item string) *CFDelCommand {
+	return &CFDelCommand{
+		store: store,
+		clock: clock,
+		key:   key,
+		item:  item,
+	}
+}
+
+// CFDelCommand is CF.DEL, which deletes one copy of an item from a Cuckoo filter.
+type CFDelCommand struct {
+	store *Store
+	clock Clock
+	key   string
+	item  string
+}
+
+func (c *CFDelCommand) Run() string {
+	var response string
+	c.store.write(c.clock.NowMonotonic(), func(tx *storeTx) {
+		filter, ok, err := getCuckooFilter(tx, c.key)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		if !ok {
+			response = errorResponse(errCuckooMissing)
+			return
+		}
+		if filter.delete(c.item) {
+			tx.notify(notifyModule, "cf.del", c.key)
+			response = integer(1)
+		} else {
+			response = integer(0)
+		}
+	})
+	return response
+}
+
+func NewCFCountCommand(store *Store, clock Clock, key, item string) *CFCountCommand {
+	return &CFCountCommand{
+		store: store,
+		clock: clock,
+		key:   key,
+		item:  item,
+	}
+}
+
+// CFCountCommand is CF.COUNT, which replies with how many times an item may have been added to
+// a Cuckoo filter and not deleted.
+type CFCountCommand struct {
+	store *Store
+	clock Clock
+	key   string
+	item  string
+}
+
+func (c *CFCountCommand) Run() string {
+	var response string
+	c.store.read(c.clock.NowMonotonic(), func(tx *storeTx) {
+		filter, ok, err := getCuckooFilter(tx, c.key)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		if !ok {
+			response = integer(0)
+			return
+		}
+		response = integer(filter.count(c.item))
+	})
+	return response
+}
+
+func NewCFInfoCommand(store *Store, clock Clock, key string) *CFInfoCommand {
+	return &CFInfoCommand{
+		store: store,
+		clock: clock,
+		key:   key,
+	}
+}
+
+// CFInfoCommand is CF.INFO, which replies with information about a Cuckoo filter.
+type CFInfoCommand struct {
+	store *Store
+	clock Clock
+	key   string
+}
+
+func (c *CFInfoCommand) Run() string {
+	var response string
+	c.store.read(c.clock.NowMonotonic(), func(tx *storeTx) {
+		filter, ok, err := getCuckooFilter(tx, c.key)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		if !ok {
+			response = errorResponse(errBloomMissing)
+			return
+		}
+		response = array(
+			simpleString("Size"), integer(filter.size()),
+			simpleString("Number of buckets"), integer(int(filter.buckets())),
+			simpleString("Number of filters"), integer(len(filter.layers)),
+			simpleString("Number of items inserted"), integer(filter.inserted),
+			simpleString("Number of items deleted"), integer(filter.deleted),
+			simpleString("Bucket size"), integer(filter.bucketSize),
+			simpleString("Expansion rate"), integer(filter.expansion),
+			simpleString("Max iterations"), integer(filter.maxIterations),
+		)
+	})
+	return response
+}
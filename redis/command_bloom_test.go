@@ -0,0 +1,378 @@
+package redis_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/codecrafters-io/redis-starter-go/redis"
+)
+
+func TestBFReserveCommand(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	store.Set("string", "zelda")
+	clock := FakeClock{}
+
+	tests := []struct {
+		name     string
+		key      string
+		response string
+	}{
+		{
+			name:     "new key",
+			key:      "bf",
+			response: "+OK\r\n",
+		},
+		{
+			name:     "existing filter",
+			key:      "bf",
+			response: "-ERR item exists\r\n",
+		},
+		{
+			name:     "existing key of another type",
+			key:      "string",
+			response: "-ERR item exists\r\n",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			command := redis.NewBFReserveCommand(store, clock, test.key, 0.01, 100, 2)
+			if response := command.Run(); response != test.response {
+				t.Errorf("command expected to return %#v but was %#v", test.response, response)
+			}
+		})
+	}
+}
+
+func TestBFAddCommand(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	store.Set("string", "zelda")
+	clock := FakeClock{}
+	redis.NewBFReserveCommand(store, clock, "small", 0.001, 2, 0).Run()
+
+	tests := []struct {
+		name     string
+		command  redis.Command
+		response string
+	}{
+		{
+			name:     "new key",
+			command:  redis.NewBFAddCommand(store, clock, "bf", []string{"a"}),
+			response: ":1\r\n",
+		},
+		{
+			name:     "existing item",
+			command:  redis.NewBFAddCommand(store, clock, "bf", []string{"a"}),
+			response: ":0\r\n",
+		},
+		{
+			name:     "several items",
+			command:  redis.NewBFMAddCommand(store, clock, "bf", []string{"a", "b", "c", "b"}),
+			response: "*4\r\n:0\r\n:1\r\n:1\r\n:0\r\n",
+		},
+		{
+			name:     "exists",
+			command:  redis.NewBFExistsCommand(store, clock, "bf", []string{"b"}),
+			response: ":1\r\n",
+		},
+		{
+			name:     "several exist",
+			command:  redis.NewBFMExistsCommand(store, clock, "bf", []string{"a", "d", "c"}),
+			response: "*3\r\n:1\r\n:0\r\n:1\r\n",
+		},
+		{
+			name:     "exists in missing key",
+			command:  redis.NewBFExistsCommand(store, clock, "missing", []string{"a"}),
+			response: ":0\r\n",
+		},
+		{
+			name:     "full non-scaling filter",
+			command:  redis.NewBFMAddCommand(store, clock, "small", []string{"a", "b", "c"}),
+			response: "*3\r\n:1\r\n:1\r\n-ERR non scaling filter is full\r\n",
+		},
+		{
+			name:     "wrong type",
+			command:  redis.NewBFAddCommand(store, clock, "string", []string{"a"}),
+			response: "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n",
+		},
+		{
+			name:     "exists in wrong type",
+			command:  redis.NewBFMExistsCommand(store, clock, "string", []string{"a"}),
+			response: "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			if response := test.command.Run(); response != test.response {
+				t.Errorf("command expected to return %#v but was %#v", test.response, response)
+			}
+		})
+	}
+}
+
+func TestBFAddCommand_FalsePositiveRate(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{}
+	redis.NewBFReserveCommand(store, clock, "bf", 0.01, 1000, 2).Run()
+	items := make([]string, 1000)
+	for i := range items {
+		items[i] = "item:" + strconv.Itoa(i)
+	}
+	redis.NewBFMAddCommand(store, clock, "bf", items).Run()
+
+	for _, item := range items {
+		response := redis.NewBFExistsCommand(store, clock, "bf", []string{item}).Run()
+		if response != ":1\r\n" {
+			t.Fatalf("expected %#v to exist but BF.EXISTS returned %#v", item, response)
+		}
+	}
+	falsePositives := 0
+	for i := 0; i < 10000; i++ {
+		item := "other:" + strconv.Itoa(i)
+		if redis.NewBFExistsCommand(store, clock, "bf", []string{item}).Run() == ":1\r\n" {
+			falsePositives++
+		}
+	}
+	if falsePositives > 200 {
+		t.Errorf("expected a false positive rate of about 1%% but was %.2f%%",
+			float64(falsePositives)/100)
+	}
+}
+
+func TestBFInfoCommand(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	store.Set("string", "zelda")
+	clock := FakeClock{}
+	redis.NewBFAddCommand(store, clock, "bf", []string{"a"}).Run()
+	redis.NewBFReserveCommand(store, clock, "scaled", 0.01, 10, 2).Run()
+	items := make([]string, 100)
+	for i := range items {
+		items[i] = strconv.Itoa(i)
+	}
+	redis.NewBFMAddCommand(store, clock, "scaled", items).Run()
+	redis.NewBFReserveCommand(store, clock, "nonscaling", 0.01, 10, 0).Run()
+
+	tests := []struct {
+		name     string
+		key      string
+		field    redis.BFInfoField
+		response string
+	}{
+		{
+			name:  "default filter",
+			key:   "bf",
+			field: redis.BFInfoAll,
+			response: "*10\r\n+Capacity\r\n:100\r\n+Size\r\n:120\r\n+Number of filters\r\n:1\r\n" +
+				"+Number of items inserted\r\n:1\r\n+Expansion rate\r\n:2\r\n",
+		},
+		{
+			name:     "capacity of a scaled filter",
+			key:      "scaled",
+			field:    redis.BFInfoCapacity,
+			response: "*1\r\n:150\r\n",
+		},
+		{
+			name:     "filters of a scaled filter",
+			key:      "scaled",
+			field:    redis.BFInfoFilters,
+			response: "*1\r\n:4\r\n",
+		},
+		{
+			name:     "expansion of a non-scaling filter",
+			key:      "nonscaling",
+			field:    redis.BFInfoExpansion,
+			response: "*1\r\n$-1\r\n",
+		},
+		{
+			name:     "missing key",
+			key:      "missing",
+			field:    redis.BFInfoAll,
+			response: "-ERR not found\r\n",
+		},
+		{
+			name:     "wrong type",
+			key:      "string",
+			field:    redis.BFInfoAll,
+			response: "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			command := redis.NewBFInfoCommand(store, clock, test.key, test.field)
+			if response := command.Run(); response != test.response {
+				t.Errorf("command expected to return %#v but was %#v", test.response, response)
+			}
+		})
+	}
+}
+
+func TestCFAddCommand(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	store.Set("string", "zelda")
+	clock := FakeClock{}
+
+	tests := []struct {
+		name     string
+		command  redis.Command
+		response string
+	}{
+		{
+			name:     "new key",
+			command:  redis.NewCFAddCommand(store, clock, "cf", "a"),
+			response: ":1\r\n",
+		},
+		{
+			name:     "existing item",
+			command:  redis.NewCFAddCommand(store, clock, "cf", "a"),
+			response: ":1\r\n",
+		},
+		{
+			name:     "existing item with NX",
+			command:  redis.NewCFAddNXCommand(store, clock, "cf", "a"),
+			response: ":0\r\n",
+		},
+		{
+			name:     "new item with NX",
+			command:  redis.NewCFAddNXCommand(store, clock, "cf", "b"),
+			response: ":1\r\n",
+		},
+		{
+			name:     "count",
+			command:  redis.NewCFCountCommand(store, clock, "cf", "a"),
+			response: ":2\r\n",
+		},
+		{
+			name:     "exists",
+			command:  redis.NewCFExistsCommand(store, clock, "cf", []string{"b"}),
+			response: ":1\r\n",
+		},
+		{
+			name:     "several exist",
+			command:  redis.NewCFMExistsCommand(store, clock, "cf", []string{"a", "c", "b"}),
+			response: "*3\r\n:1\r\n:0\r\n:1\r\n",
+		},
+		{
+			name:     "delete",
+			command:  redis.NewCFDelCommand(store, clock, "cf", "a"),
+			response: ":1\r\n",
+		},
+		{
+			name:     "count after delete",
+			command:  redis.NewCFCountCommand(store, clock, "cf", "a"),
+			response: ":1\r\n",
+		},
+		{
+			name:     "delete last copy",
+			command:  redis.NewCFDelCommand(store, clock, "cf", "a"),
+			response: ":1\r\n",
+		},
+		{
+			name:     "exists after delete",
+			command:  redis.NewCFExistsCommand(store, clock, "cf", []string{"a"}),
+			response: ":0\r\n",
+		},
+		{
+			name:     "delete missing item",
+			command:  redis.NewCFDelCommand(store, clock, "cf", "a"),
+			response: ":0\r\n",
+		},
+		{
+			name:    "info",
+			command: redis.NewCFInfoCommand(store, clock, "cf"),
+			response: "*16\r\n+Size\r\n:1024\r\n+Number of buckets\r\n:512\r\n" +
+				"+Number of filters\r\n:1\r\n+Number of items inserted\r\n:1\r\n" +
+				"+Number of items deleted\r\n:2\r\n+Bucket size\r\n:2\r\n" +
+				"+Expansion rate\r\n:1\r\n+Max iterations\r\n:20\r\n",
+		},
+		{
+			name:     "delete from missing key",
+			command:  redis.NewCFDelCommand(store, clock, "missing", "a"),
+			response: "-ERR Not found\r\n",
+		},
+		{
+			name:     "count in missing key",
+			command:  redis.NewCFCountCommand(store, clock, "missing", "a"),
+			response: ":0\r\n",
+		},
+		{
+			name:     "info of missing key",
+			command:  redis.NewCFInfoCommand(store, clock, "missing"),
+			response: "-ERR not found\r\n",
+		},
+		{
+			name:     "wrong type",
+			command:  redis.NewCFAddCommand(store, clock, "string", "a"),
+			response: "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			if response := test.command.Run(); response != test.response {
+				t.Errorf("command expected to return %#v but was %#v", test.response, response)
+			}
+		})
+	}
+}
+
+func TestCFAddCommand_Full(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{}
+	redis.NewCFReserveCommand(store, clock, "nonscaling", 8, 2, 20, 0).Run()
+	redis.NewCFReserveCommand(store, clock, "scaling", 8, 2, 20, 2).Run()
+
+	added := 0
+	for i := 0; i < 100; i++ {
+		response := redis.NewCFAddCommand(store, clock, "nonscaling", strconv.Itoa(i)).Run()
+		if response == "-ERR Filter is full\r\n" {
+			break
+		}
+		if response != ":1\r\n" {
+			t.Fatalf("CF.ADD expected to return 1 but was %#v", response)
+		}
+		added++
+	}
+	if added == 100 || added > 8 {
+		t.Errorf("expected a filter with 8 slots to fill up but %d items were added", added)
+	}
+	// No item is lost when an insertion fails.
+	for i := 0; i < added; i++ {
+		command := redis.NewCFExistsCommand(store, clock, "nonscaling", []string{strconv.Itoa(i)})
+		if command.Run() != ":1\r\n" {
+			t.Errorf("expected %d to exist in the full filter", i)
+		}
+	}
+
+	for i := 0; i < 100; i++ {
+		response := redis.NewCFAddCommand(store, clock, "scaling", strconv.Itoa(i)).Run()
+		if response != ":1\r\n" {
+			t.Fatalf("CF.ADD expected to return 1 but was %#v", response)
+		}
+	}
+	for i := 0; i < 100; i++ {
+		command := redis.NewCFExistsCommand(store, clock, "scaling", []string{strconv.Itoa(i)})
+		if command.Run() != ":1\r\n" {
+			t.Errorf("expected %d to exist in the scaled filter", i)
+		}
+	}
+}
@@ -0,0 +1,302 @@
+package redis
+
+import (
+	"fmt"
+	"strings"
+)
+
+func NewClientIDCommand(client *Client) *ClientIDCommand {
+	return &ClientIDCommand{
+		client: client,
+	}
+}
+
+// ClientIDCommand is CLIENT ID, which returns the ID of the client that sent it.
+type ClientIDCommand struct {
+	client *Client
+}
+
+func (c *ClientIDCommand) Run() string {
+	return integer(c.client.ID())
+}
+
+func NewClientUnblockCommand(clients *Clients, id int, withError bool) *ClientUnblockCommand {
+	return &ClientUnblockCommand{
+		clients:   clients,
+		id:        id,
+		withError: withError,
+	}
+}
+
+// ClientUnblockCommand is CLIENT UNBLOCK, which unblocks the client with the given ID if it's
+// blocked by a command like BLPOP. The blocked command times out, or fails if withError is set.
+type ClientUnblockCommand struct {
+	clients   *Clients
+	id        int
+	withError bool
+}
+
+func (c *ClientUnblockCommand) Run() string {
+	client, ok := c.clients.get(c.id)
+	if !ok {
+		return integer(0)
+	}
+	var err error
+	if c.withError {
+		err = errUnblocked
+	}
+	if client.unblock(err) {
+		return integer(1)
+	}
+	return integer(0)
+}
+
+const errTrackingRedirect CommandError = "ERR The client ID you want redirect to does not exist"
+
+func NewClientTrackingCommand(
+	store *Store,
+	clients *Clients,
+	client *Client,
+	on bool,
+	options ...func(*ClientTrackingCommand),
+) *ClientTrackingCommand {
+	result := &ClientTrackingCommand{
+		store:   store,
+		clients: clients,
+		client:  client,
+		on:      on,
+	}
+	for _, option := range options {
+		option(result)
+	}
+	return result
+}
+
+// ClientTrackingCommand is CLIENT TRACKING, which turns server-assisted client-side caching on
+// or off for the client that sent it. While it's on, the client is sent an invalidation message
+// when a key it read changes, or in broadcasting mode, when any key with one of its prefixes
+// does.
+type ClientTrackingCommand struct {
+	store   *Store
+	clients *Clients
+	client  *Client
+	on      bool
+	// redirect is the ID of the client to send invalidation messages to instead, or 0.
+	redirect int
+	options  trackingOptions
+}
+
+// ClientTrackingRedirect is REDIRECT, which sends invalidation messages to the client with ID id
+// instead, which RESP2 clients need since they can't be sent them alongside replies.
+func ClientTrackingRedirect(id int) func(*ClientTrackingCommand) {
+	return func(c *ClientTrackingCommand) {
+		c.redirect = id
+	}
+}
+
+// ClientTrackingBroadcast is BCAST, which sends invalidation messages for every key that starts
+// with one of prefixes, or for every key if there are none, rather than for the keys read.
+func ClientTrackingBroadcast(prefixes []string) func(*ClientTrackingCommand) {
+	return func(c *ClientTrackingCommand) {
+		c.options.broadcast = true
+		c.options.prefixes = prefixes
+	}
+}
+
+// ClientTrackingOptIn is OPTIN, which only tracks the keys read by commands sent right after
+// CLIENT CACHING YES.
+func ClientTrackingOptIn() func(*ClientTrackingCommand) {
+	return func(c *ClientTrackingCommand) {
+		c.options.optIn = true
+	}
+}
+
+// ClientTrackingOptOut is OPTOUT, which doesn't track the keys read by commands sent right after
+// CLIENT CACHING NO.
+func ClientTrackingOptOut() func(*ClientTrackingCommand) {
+	return func(c *ClientTrackingCommand) {
+		c.options.optOut = true
+	}
+}
+
+// ClientTrackingNoLoop is NOLOOP, which doesn't send invalidation messages for keys that the
+// client changed itself.
+func ClientTrackingNoLoop() func(*ClientTrackingCommand) {
+	return func(c *ClientTrackingCommand) {
+		c.options.noLoop = true
+	}
+}
+
+func (c *ClientTrackingCommand) Run() string {
+	if !c.on {
+		c.client.disableTracking()
+		return simpleString("OK")
+	}
+	options := c.options
+	if c.redirect != 0 {
+		target, ok := c.clients.get(c.redirect)
+		if !ok {
+			return errorResponse(errTrackingRedirect)
+		}
+		options.redirect = target
+	}
+	if err := c.store.tracking.enable(c.client, options); err != nil {
+		return errorResponse(err)
+	}
+	return simpleString("OK")
+}
+
+const (
+	errCachingNotTracking CommandError = "ERR CLIENT CACHING can be called only when the client " +
+		"is in tracking mode with OPTIN or OPTOUT mode enabled"
+	errCachingYes CommandError = "ERR CLIENT CACHING YES is only valid when tracking is enabled " +
+		"in OPTIN mode."
+	errCachingNo CommandError = "ERR CLIENT CACHING NO is only valid when tracking is enabled " +
+		"in OPTOUT mode."
+)
+
+func NewClientCachingCommand(store *Store, client *Client, yes bool) *ClientCachingCommand {
+	return &ClientCachingCommand{
+		store:  store,
+		client: client,
+		yes:    yes,
+	}
+}
+
+// ClientCachingCommand is CLIENT CACHING, which in OPTIN mode tracks the keys read by the
+// client's next command, with YES, or in OPTOUT mode doesn't, with NO.
+type ClientCachingCommand struct {
+	store  *Store
+	client *Client
+	yes    bool
+}
+
+func (c *ClientCachingCommand) Run() string {
+	options, ok := c.store.tracking.options(c.client)
+	switch {
+	case !ok:
+		return errorResponse(errCachingNotTracking)
+	case c.yes && !options.optIn:
+		return errorResponse(errCachingYes)
+	case !c.yes && !options.optOut:
+		return errorResponse(errCachingNo)
+	}
+	c.client.setCaching()
+	return simpleString("OK")
+}
+
+func NewClientGetRedirCommand(store *Store, client *Client) *ClientGetRedirCommand {
+	return &ClientGetRedirCommand{
+		store:  store,
+		client: client,
+	}
+}
+
+// ClientGetRedirCommand is CLIENT GETREDIR, which replies with the ID of the client that the
+// client that sent it redirects invalidation messages to, 0 if it doesn't, or -1 if it doesn't
+// have tracking on.
+type ClientGetRedirCommand struct {
+	store  *Store
+	client *Client
+}
+
+func (c *ClientGetRedirCommand) Run() string {
+	options, ok := c.store.tracking.options(c.client)
+	switch {
+	case !ok:
+		return integer(-1)
+	case options.redirect == nil:
+		return integer(0)
+	}
+	return integer(options.redirect.ID())
+}
+
+// redisVersion is the version of Redis that HELLO replies that the server is.
+const redisVersion = "7.4.0"
+
+const errNoProto CommandError = "NOPROTO unsupported protocol version"
+
+func NewHelloCommand(config *Config, client *Client, protocol int) *HelloCommand {
+	return &HelloCommand{
+		config:   config,
+		client:   client,
+		protocol: protocol,
+	}
+}
+
+// HelloCommand is HELLO, which switches the connection of the client that sent it to the version
+// of RESP protocol, if it isn't 0, and replies with a map of information about the server. Only
+// RESP3's pushes are supported: other replies stay the same, since RESP3 clients read RESP2's
+// types too.
+type HelloCommand struct {
+	config   *Config
+	client   *Client
+	protocol int
+}
+
+func (h *HelloCommand) Run() string {
+	if h.protocol != 0 {
+		if h.protocol != 2 && h.protocol != 3 {
+			return errorResponse(errNoProto)
+		}
+		h.client.setProtocol(h.protocol)
+	}
+	elements := []string{
+		bulkString("server"), bulkString("redis"),
+		bulkString("version"), bulkString(redisVersion),
+		bulkString("proto"), integer(h.client.protocol()),
+		bulkString("id"), integer(h.client.ID()),
+		bulkString("mode"), bulkString("standalone"),
+		bulkString("role"), bulkString(h.config.Replication.Role().String()),
+		bulkString("modules"), array(),
+	}
+	if h.client.protocol() == 3 {
+		return fmt.Sprintf("%%%d\r\n", len(elements)/2) + strings.Join(elements, "")
+	}
+	return array(elements...)
+}
+
+func NewQuitCommand(client *Client) *QuitCommand {
+	return &QuitCommand{
+		client: client,
+	}
+}
+
+// QuitCommand is QUIT, which closes the connection of the client that sent it after replying.
+type QuitCommand struct {
+	client *Client
+}
+
+func (q *QuitCommand) Run() string {
+	if q.client != nil {
+		q.client.Close()
+	}
+	return simpleString("OK")
+}
+
+func NewResetCommand(pubSub *PubSub, client *Client) *ResetCommand {
+	return &ResetCommand{
+		pubSub: pubSub,
+		client: client,
+	}
+}
+
+// ResetCommand is RESET, which resets the state of the connection of the client that sent it:
+// it's unsubscribed from everything, any transaction is discarded and it stops watching keys,
+// CLIENT TRACKING is turned off and it speaks RESP2 again.
+type ResetCommand struct {
+	pubSub *PubSub
+	client *Client
+}
+
+func (r *ResetCommand) Run() string {
+	if r.client != nil {
+		r.pubSub.unsubscribeAll(r.client)
+		r.client.disableTracking()
+		r.client.takeCaching()
+		r.client.setProtocol(2)
+		r.client.endTransaction()
+		r.client.unwatch()
+	}
+	return simpleString("RESET")
+}
@@ -0,0 +1,390 @@
+package redis_test
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/redis"
+)
+
+func TestClientIDCommand(t *testing.T) {
+	t.Parallel()
+
+	clients := redis.NewClients()
+	first := clients.NewClient()
+	second := clients.NewClient()
+
+	if response := redis.NewClientIDCommand(first).Run(); response != ":1\r\n" {
+		t.Errorf(`command expected to return ":1\r\n" but was %#v`, response)
+	}
+	if response := redis.NewClientIDCommand(second).Run(); response != ":2\r\n" {
+		t.Errorf(`command expected to return ":2\r\n" but was %#v`, response)
+	}
+}
+
+func TestClientUnblockCommand_NotBlocked(t *testing.T) {
+	t.Parallel()
+
+	clients := redis.NewClients()
+	client := clients.NewClient()
+	closedClient := clients.NewClient()
+	closedClient.Close()
+
+	tests := []struct {
+		name string
+		id   int
+	}{
+		{name: "client not blocked", id: client.ID()},
+		{name: "client closed", id: closedClient.ID()},
+		{name: "no such client", id: 42},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if response := redis.NewClientUnblockCommand(clients, tt.id, false).Run(); response != ":0\r\n" {
+				t.Errorf(`command expected to return ":0\r\n" but was %#v`, response)
+			}
+		})
+	}
+}
+
+func TestClientUnblockCommand_Blocked(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{}
+	clients := redis.NewClients()
+	client := clients.NewClient()
+	response := make(chan string, 1)
+	go func() {
+		response <- redis.NewBlockingPopCommand(
+			store, clock, client, []string{"list"}, redis.ListEndLeft, 0,
+		).Run()
+	}()
+	waitUntilBlocked(t, client)
+
+	if got := redis.NewClientUnblockCommand(clients, client.ID(), false).Run(); got != ":1\r\n" {
+		t.Errorf(`command expected to return ":1\r\n" but was %#v`, got)
+	}
+	// The client is no longer blocked, so unblocking it again does nothing.
+	if got := redis.NewClientUnblockCommand(clients, client.ID(), true).Run(); got != ":0\r\n" {
+		t.Errorf(`command expected to return ":0\r\n" but was %#v`, got)
+	}
+	if got := <-response; got != "*-1\r\n" {
+		t.Errorf(`BLPOP expected to return "*-1\r\n" but was %#v`, got)
+	}
+}
+
+// requestRunner returns a function that parses a request of args on behalf of client and runs
+// its command, returning its response, or the error response if it can't be parsed.
+func requestRunner(
+	t *testing.T,
+	parser redis.Parser,
+	client *redis.Client,
+) func(args ...string) string {
+	t.Helper()
+
+	parser = parser.ForClient(client)
+	return func(args ...string) string {
+		t.Helper()
+
+		request := fmt.Sprintf("*%d\r\n", len(args))
+		for _, arg := range args {
+			request += bulkString(arg)
+		}
+		command, err := parser.Parse(strings.NewReader(request))
+		if err != nil {
+			var commandErr redis.CommandError
+			if !errors.As(err, &commandErr) {
+				t.Fatalf("err: expected: nil; got: %v", err)
+			}
+			return commandErr.Response()
+		}
+		return command.Run()
+	}
+}
+
+// invalidate returns the message that a RESP2 client subscribed to __redis__:invalidate is pushed
+// for keys.
+func invalidate(keys ...string) string {
+	return "*3\r\n" + bulkString("message") + bulkString("__redis__:invalidate") +
+		bulkStringArray(keys...)
+}
+
+func TestClientTrackingCommand(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		options []string
+		// requests are sent by the tracking client, and writes by another client afterwards.
+		requests [][]string
+		writes   [][]string
+		want     string
+	}{
+		{
+			name:     "keys read",
+			requests: [][]string{{"GET", "a"}, {"GET", "b"}},
+			writes:   [][]string{{"SET", "a", "1"}, {"SET", "a", "2"}, {"SET", "c", "3"}},
+			want:     invalidate("a"),
+		},
+		{
+			name:     "keys written by the tracking client",
+			requests: [][]string{{"GET", "a"}, {"SET", "a", "1"}},
+			want:     invalidate("a"),
+		},
+		{
+			name:     "NOLOOP",
+			options:  []string{"NOLOOP"},
+			requests: [][]string{{"GET", "a"}, {"SET", "a", "1"}},
+			want:     "",
+		},
+		{
+			name:     "OPTIN",
+			options:  []string{"OPTIN"},
+			requests: [][]string{{"GET", "a"}, {"CLIENT", "CACHING", "YES"}, {"GET", "b"}},
+			writes:   [][]string{{"SET", "a", "1"}, {"SET", "b", "2"}},
+			want:     invalidate("b"),
+		},
+		{
+			name:     "OPTOUT",
+			options:  []string{"OPTOUT"},
+			requests: [][]string{{"GET", "a"}, {"CLIENT", "CACHING", "NO"}, {"GET", "b"}},
+			writes:   [][]string{{"SET", "a", "1"}, {"SET", "b", "2"}},
+			want:     invalidate("a"),
+		},
+		{
+			name:    "BCAST",
+			options: []string{"BCAST"},
+			writes:  [][]string{{"MSET", "a", "1", "b", "2"}, {"SET", "a", "3"}},
+			want:    invalidate("a", "b") + invalidate("a"),
+		},
+		{
+			name:    "BCAST PREFIX",
+			options: []string{"BCAST", "PREFIX", "user:", "PREFIX", "post:"},
+			writes:  [][]string{{"MSET", "user:1", "a", "other", "b", "post:1", "c"}},
+			want:    invalidate("user:1", "post:1"),
+		},
+		{
+			name:     "module types",
+			requests: [][]string{{"BF.EXISTS", "filter", "a"}},
+			writes:   [][]string{{"BF.ADD", "filter", "a"}},
+			want:     invalidate("filter"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser := redis.NewParser(
+				defaultEncodingRedisConfig, redis.NewStore(), &FakeClock{CurrentTime: time.UnixMilli(0)},
+			)
+			client := parser.NewClient()
+			redirect := parser.NewClient()
+			writer := parser.NewClient()
+			run := requestRunner(t, parser, client)
+			requestRunner(t, parser, redirect)("SUBSCRIBE", "__redis__:invalidate")
+			redirect.TakePushes()
+
+			args := append(
+				[]string{"CLIENT", "TRACKING", "ON", "REDIRECT", strconv.Itoa(redirect.ID())},
+				tt.options...,
+			)
+			if response := run(args...); response != "+OK\r\n" {
+				t.Fatalf(`command expected to return "+OK\r\n" but was %#v`, response)
+			}
+			for _, request := range tt.requests {
+				run(request...)
+			}
+			for _, write := range tt.writes {
+				requestRunner(t, parser, writer)(write...)
+			}
+
+			if pushes := redirect.TakePushes(); pushes != tt.want {
+				t.Errorf("expected to be pushed %#v but was %#v", tt.want, pushes)
+			}
+		})
+	}
+}
+
+func TestClientTrackingCommand_RESP3(t *testing.T) {
+	t.Parallel()
+
+	parser := redis.NewParser(zeroValueRedisConfig, redis.NewStore(), FakeClock{})
+	client := parser.NewClient()
+	run := requestRunner(t, parser, client)
+	run("HELLO", "3")
+	run("CLIENT", "TRACKING", "ON")
+	run("GET", "a")
+	run("SET", "a", "1")
+
+	want := ">2\r\n" + bulkString("invalidate") + bulkStringArray("a")
+	if pushes := client.TakePushes(); pushes != want {
+		t.Errorf("expected to be pushed %#v but was %#v", want, pushes)
+	}
+
+	// Turning tracking off stops invalidation messages.
+	run("GET", "a")
+	run("CLIENT", "TRACKING", "OFF")
+	run("SET", "a", "2")
+	if pushes := client.TakePushes(); pushes != "" {
+		t.Errorf(`expected to be pushed "" but was %#v`, pushes)
+	}
+}
+
+func TestClientTrackingCommand_Errors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		requests [][]string
+		want     string
+	}{
+		{
+			name:     "REDIRECT to a client that doesn't exist",
+			requests: [][]string{{"CLIENT", "TRACKING", "ON", "REDIRECT", "42"}},
+			want:     "-ERR The client ID you want redirect to does not exist\r\n",
+		},
+		{
+			name: "switching BCAST",
+			requests: [][]string{
+				{"CLIENT", "TRACKING", "ON"},
+				{"CLIENT", "TRACKING", "ON", "BCAST"},
+			},
+			want: "-ERR You can't switch BCAST mode on/off before disabling tracking for this " +
+				"client, and then re-enabling it with a different mode.\r\n",
+		},
+		{
+			name: "switching OPTIN to OPTOUT",
+			requests: [][]string{
+				{"CLIENT", "TRACKING", "ON", "OPTIN"},
+				{"CLIENT", "TRACKING", "ON", "OPTOUT"},
+			},
+			want: "-ERR You can't switch OPTIN/OPTOUT mode before disabling tracking for this " +
+				"client, and then re-enabling it with a different mode.\r\n",
+		},
+		{
+			name: "overlapping prefixes",
+			requests: [][]string{
+				{"CLIENT", "TRACKING", "ON", "BCAST", "PREFIX", "user:", "PREFIX", "user:1"},
+			},
+			want: "-ERR Prefix 'user:' overlaps with another provided prefix 'user:1'. " +
+				"Prefixes for a single client must not overlap.\r\n",
+		},
+		{
+			name: "prefix overlapping an existing one",
+			requests: [][]string{
+				{"CLIENT", "TRACKING", "ON", "BCAST", "PREFIX", "user:"},
+				{"CLIENT", "TRACKING", "ON", "BCAST", "PREFIX", "us"},
+			},
+			want: "-ERR Prefix 'us' overlaps with an existing prefix 'user:'. " +
+				"Prefixes for a single client must not overlap.\r\n",
+		},
+		{
+			name:     "CLIENT CACHING without tracking",
+			requests: [][]string{{"CLIENT", "CACHING", "YES"}},
+			want: "-ERR CLIENT CACHING can be called only when the client is in tracking mode " +
+				"with OPTIN or OPTOUT mode enabled\r\n",
+		},
+		{
+			name: "CLIENT CACHING YES in OPTOUT mode",
+			requests: [][]string{
+				{"CLIENT", "TRACKING", "ON", "OPTOUT"},
+				{"CLIENT", "CACHING", "YES"},
+			},
+			want: "-ERR CLIENT CACHING YES is only valid when tracking is enabled in OPTIN mode.\r\n",
+		},
+		{
+			name:     "HELLO 4",
+			requests: [][]string{{"HELLO", "4"}},
+			want:     "-NOPROTO unsupported protocol version\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser := redis.NewParser(zeroValueRedisConfig, redis.NewStore(), FakeClock{})
+			run := requestRunner(t, parser, parser.NewClient())
+
+			var response string
+			for _, request := range tt.requests {
+				response = run(request...)
+			}
+
+			if response != tt.want {
+				t.Errorf("command expected to return %#v but was %#v", tt.want, response)
+			}
+		})
+	}
+}
+
+func TestClientGetRedirCommand(t *testing.T) {
+	t.Parallel()
+
+	parser := redis.NewParser(zeroValueRedisConfig, redis.NewStore(), FakeClock{})
+	client := parser.NewClient()
+	redirect := parser.NewClient()
+	run := requestRunner(t, parser, client)
+
+	if response := run("CLIENT", "GETREDIR"); response != ":-1\r\n" {
+		t.Errorf(`command expected to return ":-1\r\n" but was %#v`, response)
+	}
+	run("CLIENT", "TRACKING", "ON")
+	if response := run("CLIENT", "GETREDIR"); response != ":0\r\n" {
+		t.Errorf(`command expected to return ":0\r\n" but was %#v`, response)
+	}
+	run("CLIENT", "TRACKING", "ON", "REDIRECT", strconv.Itoa(redirect.ID()))
+	want := fmt.Sprintf(":%d\r\n", redirect.ID())
+	if response := run("CLIENT", "GETREDIR"); response != want {
+		t.Errorf("command expected to return %#v but was %#v", want, response)
+	}
+	// RESET turns tracking off.
+	run("RESET")
+	if response := run("CLIENT", "GETREDIR"); response != ":-1\r\n" {
+		t.Errorf(`command expected to return ":-1\r\n" but was %#v`, response)
+	}
+}
+
+func TestHelloCommand(t *testing.T) {
+	t.Parallel()
+
+	parser := redis.NewParser(zeroValueRedisConfig, redis.NewStore(), FakeClock{})
+	client := parser.NewClient()
+	run := requestRunner(t, parser, client)
+	fields := func(protocol int) string {
+		return bulkString("server") + bulkString("redis") +
+			bulkString("version") + bulkString("7.4.0") +
+			bulkString("proto") + fmt.Sprintf(":%d\r\n", protocol) +
+			bulkString("id") + fmt.Sprintf(":%d\r\n", client.ID()) +
+			bulkString("mode") + bulkString("standalone") +
+			bulkString("role") + bulkString("slave") +
+			bulkString("modules") + "*0\r\n"
+	}
+
+	if want, response := "*14\r\n"+fields(2), run("HELLO"); response != want {
+		t.Errorf("command expected to return %#v but was %#v", want, response)
+	}
+	if want, response := "%7\r\n"+fields(3), run("HELLO", "3"); response != want {
+		t.Errorf("command expected to return %#v but was %#v", want, response)
+	}
+
+	// RESP3 clients can send any command while subscribed, and are pushed messages.
+	want := ">3\r\n" + bulkString("subscribe") + bulkString("news") + ":1\r\n"
+	if response := run("SUBSCRIBE", "news"); response != want {
+		t.Errorf("command expected to return %#v but was %#v", want, response)
+	}
+	if response := run("GET", "key"); response != "$-1\r\n" {
+		t.Errorf(`command expected to return "$-1\r\n" but was %#v`, response)
+	}
+	if response := run("PING"); response != "+PONG\r\n" {
+		t.Errorf(`command expected to return "+PONG\r\n" but was %#v`, response)
+	}
+	requestRunner(t, parser, parser.NewClient())("PUBLISH", "news", "hello")
+	want = ">3\r\n" + bulkString("message") + bulkString("news") + bulkString("hello")
+	if pushes := client.TakePushes(); pushes != want {
+		t.Errorf("expected to be pushed %#v but was %#v", want, pushes)
+	}
+}
@@ -0,0 +1,248 @@
+package redis
+
+import "sort"
+
+const (
+	errFunctionNotFound    CommandError = "ERR Function not found"
+	errLibraryNotFound     CommandError = "ERR Library not found"
+	errFunctionNotReadOnly CommandError = "ERR Can not execute a script with write flag using " +
+		"*_ro command."
+)
+
+func NewFCallCommand(
+	parser Parser, function string, keys, args []string, readOnly bool,
+) *FCallCommand {
+	return &FCallCommand{
+		parser:   parser,
+		function: function,
+		keys:     keys,
+		args:     args,
+		readOnly: readOnly,
+	}
+}
+
+// FCallCommand is FCALL, which calls a function that a library registered with the names of the
+// keys that it accesses and its other arguments, which it gets as two tables, and replies with
+// what it returns. Like EVAL, it runs atomically. FCALL_RO, for which readOnly is true, can only
+// call functions with the no-writes flag.
+type FCallCommand struct {
+	parser   Parser
+	function string
+	keys     []string
+	args     []string
+	readOnly bool
+}
+
+func (f *FCallCommand) Run() string {
+	scripts := f.parser.scripts
+	function, ok := scripts.function(f.function)
+	switch {
+	case !ok:
+		return errorResponse(errFunctionNotFound)
+	case f.readOnly && !function.flags["no-writes"]:
+		return errorResponse(errFunctionNotReadOnly)
+	}
+	var response string
+	f.parser.store.exclusively(f.parser.clock.NowMonotonic(), func(*storeTx) {
+		response = scripts.runFunction(f.parser, function, f.keys, f.args)
+	})
+	return response
+}
+
+func NewFunctionLoadCommand(
+	scripts *Scripts, clock Clock, code string, replace bool,
+) *FunctionLoadCommand {
+	return &FunctionLoadCommand{
+		scripts: scripts,
+		clock:   clock,
+		code:    code,
+		replace: replace,
+	}
+}
+
+// FunctionLoadCommand is FUNCTION LOAD, which loads a library from its code, whose first line
+// gives its name, like "#!lua name=mylib", replying with its name. Running the code registers the
+// library's functions. It fails if the library is already loaded, unless replace is true.
+type FunctionLoadCommand struct {
+	scripts *Scripts
+	clock   Clock
+	code    string
+	replace bool
+}
+
+func (f *FunctionLoadCommand) Run() string {
+	library, err := loadLibrary(f.code, f.clock)
+	if err != nil {
+		return errorResponse(err)
+	}
+	policy := FunctionRestoreAppend
+	if f.replace {
+		policy = FunctionRestoreReplace
+	}
+	if err := f.scripts.addLibraries([]*luaLibrary{library}, policy); err != nil {
+		return errorResponse(err)
+	}
+	return bulkString(library.name)
+}
+
+func NewFunctionDeleteCommand(scripts *Scripts, library string) *FunctionDeleteCommand {
+	return &FunctionDeleteCommand{
+		scripts: scripts,
+		library: library,
+	}
+}
+
+// FunctionDeleteCommand is FUNCTION DELETE, which deletes a library and its functions.
+type FunctionDeleteCommand struct {
+	scripts *Scripts
+	library string
+}
+
+func (f *FunctionDeleteCommand) Run() string {
+	if !f.scripts.deleteLibrary(f.library) {
+		return errorResponse(errLibraryNotFound)
+	}
+	return simpleString("OK")
+}
+
+func NewFunctionFlushCommand(scripts *Scripts) *FunctionFlushCommand {
+	return &FunctionFlushCommand{scripts: scripts}
+}
+
+// FunctionFlushCommand is FUNCTION FLUSH, which deletes every library.
+type FunctionFlushCommand struct {
+	scripts *Scripts
+}
+
+func (f *FunctionFlushCommand) Run() string {
+	f.scripts.flushLibraries()
+	return simpleString("OK")
+}
+
+func NewFunctionListCommand(scripts *Scripts, pattern string, withCode bool) *FunctionListCommand {
+	return &FunctionListCommand{
+		scripts:  scripts,
+		pattern:  pattern,
+		withCode: withCode,
+	}
+}
+
+// FunctionListCommand is FUNCTION LIST, which replies with the libraries whose names match the
+// glob-style pattern, in order of their names, and their functions' names, descriptions and
+// flags. With WITHCODE, for which withCode is true, it replies with their code too.
+type FunctionListCommand struct {
+	scripts  *Scripts
+	pattern  string
+	withCode bool
+}
+
+func (f *FunctionListCommand) Run() string {
+	var libraries []string
+	for _, library := range f.scripts.sortedLibraries() {
+		if !globMatch(f.pattern, library.name) {
+			continue
+		}
+		functions := make([]*luaFunction, 0, len(library.functions))
+		for _, function := range library.functions {
+			functions = append(functions, function)
+		}
+		sort.Slice(functions, func(i, j int) bool { return functions[i].name < functions[j].name })
+		var elements []string
+		for _, function := range functions {
+			description := nullBulkString
+			if function.description != "" {
+				description = bulkString(function.description)
+			}
+			var flags []string
+			for _, flag := range luaFunctionFlags {
+				if function.flags[flag] {
+					flags = append(flags, bulkString(flag))
+				}
+			}
+			elements = append(elements, array(
+				bulkString("name"), bulkString(function.name),
+				bulkString("description"), description,
+				bulkString("flags"), array(flags...),
+			))
+		}
+		fields := []string{
+			bulkString("library_name"), bulkString(library.name),
+			bulkString("engine"), bulkString("LUA"),
+			bulkString("functions"), array(elements...),
+		}
+		if f.withCode {
+			fields = append(fields, bulkString("library_code"), bulkString(library.code))
+		}
+		libraries = append(libraries, array(fields...))
+	}
+	return array(libraries...)
+}
+
+func NewFunctionDumpCommand(scripts *Scripts) *FunctionDumpCommand {
+	return &FunctionDumpCommand{scripts: scripts}
+}
+
+// FunctionDumpCommand is FUNCTION DUMP, which replies with a payload of every library, in the
+// same format as Redis, for FUNCTION RESTORE to load.
+type FunctionDumpCommand struct {
+	scripts *Scripts
+}
+
+func (f *FunctionDumpCommand) Run() string {
+	return bulkString(dumpLibraries(f.scripts.sortedLibraries()))
+}
+
+func NewFunctionRestoreCommand(
+	scripts *Scripts, clock Clock, payload string, policy FunctionRestorePolicy,
+) *FunctionRestoreCommand {
+	return &FunctionRestoreCommand{
+		scripts: scripts,
+		clock:   clock,
+		payload: payload,
+		policy:  policy,
+	}
+}
+
+// FunctionRestoreCommand is FUNCTION RESTORE, which loads the libraries in a payload from
+// FUNCTION DUMP, following policy for the libraries that are already loaded. If any library
+// fails to load, none are.
+type FunctionRestoreCommand struct {
+	scripts *Scripts
+	clock   Clock
+	payload string
+	policy  FunctionRestorePolicy
+}
+
+func (f *FunctionRestoreCommand) Run() string {
+	codes, err := restoreLibraries(f.payload)
+	if err != nil {
+		return errorResponse(err)
+	}
+	libraries := make([]*luaLibrary, len(codes))
+	for i, code := range codes {
+		if libraries[i], err = loadLibrary(code, f.clock); err != nil {
+			return errorResponse(err)
+		}
+	}
+	if err := f.scripts.addLibraries(libraries, f.policy); err != nil {
+		return errorResponse(err)
+	}
+	return simpleString("OK")
+}
+
+func NewFunctionKillCommand(scripts *Scripts) *FunctionKillCommand {
+	return &FunctionKillCommand{scripts: scripts}
+}
+
+// FunctionKillCommand is FUNCTION KILL, which kills the running function, like SCRIPT KILL kills
+// the running script.
+type FunctionKillCommand struct {
+	scripts *Scripts
+}
+
+func (f *FunctionKillCommand) Run() string {
+	if err := f.scripts.kill(true); err != nil {
+		return errorResponse(err)
+	}
+	return simpleString("OK")
+}
@@ -0,0 +1,418 @@
+package redis_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/redis"
+)
+
+const (
+	testLibrary = "#!lua name=mylib\n" +
+		"redis.register_function('myset', function(keys, args)\n" +
+		"  return redis.call('SET', keys[1], args[1])\n" +
+		"end)\n" +
+		"redis.register_function{function_name = 'myget', description = 'gets a key',\n" +
+		"  callback = function(keys) return redis.call('GET', keys[1]) end,\n" +
+		"  flags = {'no-writes', 'allow-stale'}}\n"
+	testLibraryList = "*1\r\n*6\r\n" + "$12\r\nlibrary_name\r\n$5\r\nmylib\r\n" +
+		"$6\r\nengine\r\n$3\r\nLUA\r\n" +
+		"$9\r\nfunctions\r\n*2\r\n" +
+		"*6\r\n$4\r\nname\r\n$5\r\nmyget\r\n$11\r\ndescription\r\n$10\r\ngets a key\r\n" +
+		"$5\r\nflags\r\n*2\r\n$9\r\nno-writes\r\n$11\r\nallow-stale\r\n" +
+		"*6\r\n$4\r\nname\r\n$5\r\nmyset\r\n$11\r\ndescription\r\n$-1\r\n$5\r\nflags\r\n*0\r\n"
+)
+
+func TestFCallCommand(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		// requests are sent in order, and want is the response to the last one.
+		requests [][]string
+		want     string
+	}{
+		{
+			name:     "FUNCTION LOAD",
+			requests: [][]string{{"FUNCTION", "LOAD", testLibrary}},
+			want:     bulkString("mylib"),
+		},
+		{
+			name: "FCALL",
+			requests: [][]string{
+				{"FUNCTION", "LOAD", testLibrary},
+				{"FCALL", "myset", "1", "key", "value"},
+				{"FCALL", "myget", "1", "key"},
+			},
+			want: bulkString("value"),
+		},
+		{
+			name: "FCALL_RO",
+			requests: [][]string{
+				{"SET", "key", "value"},
+				{"FUNCTION", "LOAD", testLibrary},
+				{"FCALL_RO", "myget", "1", "key"},
+			},
+			want: bulkString("value"),
+		},
+		{
+			name: "FCALL_RO of a function without no-writes",
+			requests: [][]string{
+				{"FUNCTION", "LOAD", testLibrary},
+				{"FCALL_RO", "myset", "1", "key", "value"},
+			},
+			want: "-ERR Can not execute a script with write flag using *_ro command.\r\n",
+		},
+		{
+			name: "no-writes functions can't write",
+			requests: [][]string{{
+				"FUNCTION", "LOAD", "#!lua name=lib\n" +
+					"redis.register_function{function_name = 'f', flags = {'no-writes'},\n" +
+					"  callback = function(keys) return redis.call('SET', keys[1], 'v') end}",
+			}, {"FCALL", "f", "1", "key"}, {"GET", "key"}},
+			want: "$-1\r\n",
+		},
+		{
+			name: "no-writes functions get an error when they write",
+			requests: [][]string{{
+				"FUNCTION", "LOAD", "#!lua name=lib\n" +
+					"redis.register_function{function_name = 'f', flags = {'no-writes'},\n" +
+					"  callback = function(keys) return redis.pcall('SET', keys[1], 'v') end}",
+			}, {"FCALL", "f", "1", "key"}},
+			want: "-ERR Write commands are not allowed from read-only scripts.\r\n",
+		},
+		{
+			name: "functions share the library's local variables",
+			requests: [][]string{
+				{"FUNCTION", "LOAD", "#!lua name=lib\nlocal n = 0\n" +
+					"redis.register_function('incr', function() n = n + 1 return n end)"},
+				{"FCALL", "incr", "0"},
+				{"FCALL", "incr", "0"},
+			},
+			want: ":2\r\n",
+		},
+		{
+			name: "errors give the function's name",
+			requests: [][]string{
+				{"FUNCTION", "LOAD", "#!lua name=lib\n" +
+					"redis.register_function('f', function()\n  return redis.call('NOPE')\nend)"},
+				{"FCALL", "f", "0"},
+			},
+			want: "-ERR Unknown Redis command called from script script: f, on @user_function:3.\r\n",
+		},
+		{
+			name:     "function not found",
+			requests: [][]string{{"FCALL", "nope", "0"}},
+			want:     "-ERR Function not found\r\n",
+		},
+		{
+			name:     "missing metadata",
+			requests: [][]string{{"FUNCTION", "LOAD", "redis.register_function('f', function() end)"}},
+			want:     "-ERR Missing library metadata\r\n",
+		},
+		{
+			name:     "unknown engine",
+			requests: [][]string{{"FUNCTION", "LOAD", "#!js name=lib\n"}},
+			want:     "-ERR Engine 'js' not found\r\n",
+		},
+		{
+			name:     "no library name",
+			requests: [][]string{{"FUNCTION", "LOAD", "#!lua\n"}},
+			want:     "-ERR Library name was not given\r\n",
+		},
+		{
+			name:     "invalid metadata",
+			requests: [][]string{{"FUNCTION", "LOAD", "#!lua name=lib version=2\n"}},
+			want:     "-ERR Invalid metadata value given: version=2\r\n",
+		},
+		{
+			name:     "no functions",
+			requests: [][]string{{"FUNCTION", "LOAD", "#!lua name=lib\nlocal x = 1"}},
+			want:     "-ERR No functions registered\r\n",
+		},
+		{
+			name:     "compile error",
+			requests: [][]string{{"FUNCTION", "LOAD", "#!lua name=lib\nlocal = 1"}},
+			want:     "-ERR Error compiling function: user_function:2: syntax error near '='\r\n",
+		},
+		{
+			name:     "commands can't be called while loading",
+			requests: [][]string{{"FUNCTION", "LOAD", "#!lua name=lib\nredis.call('PING')"}},
+			want: "-ERR Error registering functions: user_function:2: attempt to call a " +
+				"non-function object\r\n",
+		},
+		{
+			name: "unknown flag",
+			requests: [][]string{{
+				"FUNCTION", "LOAD", "#!lua name=lib\n" +
+					"redis.register_function{function_name = 'f', callback = function() end, " +
+					"flags = {'fast'}}",
+			}},
+			want: "-ERR Error registering functions: user_function:2: unknown flag given\r\n",
+		},
+		{
+			name: "library already exists",
+			requests: [][]string{
+				{"FUNCTION", "LOAD", testLibrary},
+				{"FUNCTION", "LOAD", testLibrary},
+			},
+			want: "-ERR Library 'mylib' already exists\r\n",
+		},
+		{
+			name: "REPLACE",
+			requests: [][]string{
+				{"FUNCTION", "LOAD", testLibrary},
+				{"FUNCTION", "LOAD", "REPLACE",
+					"#!lua name=mylib\nredis.register_function('f', function() return 2 end)"},
+				{"FCALL", "myget", "1", "key"},
+			},
+			want: "-ERR Function not found\r\n",
+		},
+		{
+			name: "function of another library already exists",
+			requests: [][]string{
+				{"FUNCTION", "LOAD", testLibrary},
+				{"FUNCTION", "LOAD",
+					"#!lua name=other\nredis.register_function('myget', function() end)"},
+			},
+			want: "-ERR Function myget already exists\r\n",
+		},
+		{
+			name:     "FUNCTION LIST",
+			requests: [][]string{{"FUNCTION", "LOAD", testLibrary}, {"FUNCTION", "LIST"}},
+			want:     testLibraryList,
+		},
+		{
+			name: "FUNCTION LIST WITHCODE LIBRARYNAME",
+			requests: [][]string{
+				{"FUNCTION", "LOAD", testLibrary},
+				{"FUNCTION", "LOAD", "#!lua name=other\nredis.register_function('f', function() end)"},
+				{"FUNCTION", "LIST", "WITHCODE", "LIBRARYNAME", "my*"},
+			},
+			want: strings.Replace(testLibraryList, "*6", "*8", 1) +
+				bulkString("library_code") + bulkString(testLibrary),
+		},
+		{
+			name: "FUNCTION DELETE",
+			requests: [][]string{
+				{"FUNCTION", "LOAD", testLibrary},
+				{"FUNCTION", "DELETE", "mylib"},
+				{"FCALL", "myget", "1", "key"},
+			},
+			want: "-ERR Function not found\r\n",
+		},
+		{
+			name:     "FUNCTION DELETE of a missing library",
+			requests: [][]string{{"FUNCTION", "DELETE", "mylib"}},
+			want:     "-ERR Library not found\r\n",
+		},
+		{
+			name: "FUNCTION FLUSH",
+			requests: [][]string{
+				{"FUNCTION", "LOAD", testLibrary},
+				{"FUNCTION", "FLUSH"},
+				{"FUNCTION", "LIST"},
+			},
+			want: "*0\r\n",
+		},
+		{
+			name: "FUNCTION DUMP",
+			requests: [][]string{
+				{"FUNCTION", "LOAD", "#!lua name=a\nredis.register_function('f', function() end)"},
+				{"FUNCTION", "DUMP"},
+			},
+			want: bulkString(
+				"\xf5\x39#!lua name=a\nredis.register_function('f', function() end)" +
+					"\x0b\x00\x0f\xc0\xe3\x9a\xb6\xe4\x19\x92",
+			),
+		},
+		{
+			name: "FUNCTION RESTORE of a corrupt payload",
+			requests: [][]string{{
+				"FUNCTION", "RESTORE", "\xf5\x01a\x0b\x00\x00\x00\x00\x00\x00\x00\x00\x00",
+			}},
+			want: "-ERR payload version or checksum are wrong\r\n",
+		},
+		{
+			name:     "FUNCTION KILL with no function running",
+			requests: [][]string{{"FUNCTION", "KILL"}},
+			want:     "-NOTBUSY No scripts in execution right now.\r\n",
+		},
+		{
+			name: "functions can't be called from scripts",
+			requests: [][]string{
+				{"FUNCTION", "LOAD", testLibrary},
+				{"EVAL", "return redis.pcall('FCALL', 'myget', 1, 'key')", "0"},
+			},
+			want: "-ERR This Redis command is not allowed from script\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser := redis.NewParser(
+				zeroValueRedisConfig, redis.NewStore(), &FakeClock{CurrentTime: time.UnixMilli(0)},
+			)
+			run := requestRunner(t, parser, parser.NewClient())
+
+			var response string
+			for _, request := range tt.requests {
+				response = run(request...)
+			}
+
+			if response != tt.want {
+				t.Errorf("command expected to return %#v but was %#v", tt.want, response)
+			}
+		})
+	}
+}
+
+func TestFunctionRestoreCommand(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		policy []string
+		want   string
+		// wantList is the reply to FUNCTION LIST after restoring.
+		wantList string
+	}{
+		{
+			name:     "APPEND",
+			policy:   nil,
+			want:     "-ERR Library 'mylib' already exists\r\n",
+			wantList: "*2\r\n",
+		},
+		{
+			name:     "REPLACE",
+			policy:   []string{"REPLACE"},
+			want:     "+OK\r\n",
+			wantList: "*2\r\n",
+		},
+		{
+			name:     "FLUSH",
+			policy:   []string{"FLUSH"},
+			want:     "+OK\r\n",
+			wantList: "*1\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser := redis.NewParser(
+				zeroValueRedisConfig, redis.NewStore(), &FakeClock{CurrentTime: time.UnixMilli(0)},
+			)
+			run := requestRunner(t, parser, parser.NewClient())
+			run("FUNCTION", "LOAD", testLibrary)
+			dump := bulkStrings(t, "*1\r\n"+run("FUNCTION", "DUMP"))[0]
+			run("FUNCTION", "LOAD", "#!lua name=other\nredis.register_function('f', function() end)")
+
+			response := run(append([]string{"FUNCTION", "RESTORE", dump}, tt.policy...)...)
+			list := run("FUNCTION", "LIST")
+
+			if response != tt.want {
+				t.Errorf("command expected to return %#v but was %#v", tt.want, response)
+			}
+			if !strings.HasPrefix(list, tt.wantList) {
+				t.Errorf("command expected to return %#v but was %#v", tt.wantList, list)
+			}
+			if got := run("FCALL", "myset", "1", "key", "value"); got != "+OK\r\n" {
+				t.Errorf("command expected to return %#v but was %#v", "+OK\r\n", got)
+			}
+		})
+	}
+}
+
+func TestFunctionKillCommand(t *testing.T) {
+	t.Parallel()
+
+	config := &redis.Config{BusyReplyThreshold: 10 * time.Millisecond}
+	parser := redis.NewParser(config, redis.NewStore(), redis.RealClock{})
+	caller := requestRunner(t, parser, parser.NewClient())
+	other := requestRunner(t, parser, parser.NewClient())
+	caller("FUNCTION", "LOAD", "#!lua name=lib\nredis.register_function('loop', function()\n"+
+		"  while true do end\nend)")
+
+	responses := make(chan string)
+	go func() {
+		responses <- caller("FCALL", "loop", "0")
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.HasPrefix(other("PING"), "-BUSY") {
+		if time.Now().After(deadline) {
+			t.Fatal("function expected to make the server busy")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	busy := other("GET", "key")
+	notScript := other("SCRIPT", "KILL")
+	killed := other("FUNCTION", "KILL")
+	response := <-responses
+
+	wantBusy := "-BUSY Redis is busy running a script. You can only call FUNCTION KILL or " +
+		"SHUTDOWN NOSAVE.\r\n"
+	if busy != wantBusy {
+		t.Errorf("command expected to return %#v but was %#v", wantBusy, busy)
+	}
+	if want := "-NOTBUSY No scripts in execution right now.\r\n"; notScript != want {
+		t.Errorf("command expected to return %#v but was %#v", want, notScript)
+	}
+	if want := "+OK\r\n"; killed != want {
+		t.Errorf("command expected to return %#v but was %#v", want, killed)
+	}
+	want := "-ERR Script killed by user with FUNCTION KILL... script: loop, on @user_function:"
+	if !strings.HasPrefix(response, want) {
+		t.Errorf("command expected to return %#v but was %#v", want, response)
+	}
+}
+
+func TestShutdownCommand_BusyFunction(t *testing.T) {
+	t.Parallel()
+
+	config := &redis.Config{BusyReplyThreshold: 10 * time.Millisecond}
+	parser := redis.NewParser(config, redis.NewStore(), redis.RealClock{})
+	caller := requestRunner(t, parser, parser.NewClient())
+	other := requestRunner(t, parser, parser.NewClient())
+	caller("FUNCTION", "LOAD", "#!lua name=lib\nredis.register_function('loop', function()\n"+
+		"  redis.call('SET', 'key', 'value')\n  while true do end\nend)")
+
+	responses := make(chan string)
+	go func() {
+		responses <- caller("FCALL", "loop", "0")
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.HasPrefix(other("PING"), "-BUSY") {
+		if time.Now().After(deadline) {
+			t.Fatal("function expected to make the server busy")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	unkillable := other("FUNCTION", "KILL")
+	busy := other("SHUTDOWN", "SAVE")
+	busyExit := shutdownRequested(parser)
+	shutdown := other("SHUTDOWN", "NOSAVE")
+	response := <-responses
+
+	if want := "-UNKILLABLE"; !strings.HasPrefix(unkillable, want) {
+		t.Errorf("command expected to return %#v but was %#v", want, unkillable)
+	}
+	wantBusy := "-BUSY Redis is busy running a script. You can only call FUNCTION KILL or " +
+		"SHUTDOWN NOSAVE.\r\n"
+	if busy != wantBusy {
+		t.Errorf("command expected to return %#v but was %#v", wantBusy, busy)
+	}
+	if busyExit {
+		t.Error("shutdown expected not to be requested by SHUTDOWN SAVE while busy")
+	}
+	if shutdown != "" {
+		t.Errorf("command expected to return %#v but was %#v", "", shutdown)
+	}
+	if !shutdownRequested(parser) {
+		t.Error("shutdown expected to be requested by SHUTDOWN NOSAVE")
+	}
+	want := "-ERR Script killed by user with FUNCTION KILL... script: loop, on @user_function:"
+	if !strings.HasPrefix(response, want) {
+		t.Errorf("command expected to return %#v but was %#v", want, response)
+	}
+}
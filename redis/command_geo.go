@@ -0,0 +1,421 @@
+package redis
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// GeoMember is a member of a geospatial index and its position.
+type GeoMember struct {
+	Longitude, Latitude float64
+	Member              string
+}
+
+// NewGeoAddCommand returns GEOADD, which is ZADD with scores that are the geohashes of the
+// positions. The positions must be valid, and the only options that apply are ZAddNX, ZAddXX
+// and ZAddCH.
+func NewGeoAddCommand(
+	store *Store,
+	clock Clock,
+	key string,
+	members []GeoMember,
+	options ...func(*ZAddCommand),
+) *ZAddCommand {
+	scoreMembers := make([]ScoreMember, len(members))
+	for i, member := range members {
+		scoreMembers[i] = ScoreMember{
+			Score:  geoEncodeScore(member.Longitude, member.Latitude),
+			Member: member.Member,
+		}
+	}
+	return NewZAddCommand(store, clock, key, scoreMembers, options...)
+}
+
+// formatGeoDistance formats a distance to 4 decimal places, like Redis.
+func formatGeoDistance(distance float64) string {
+	return strconv.FormatFloat(distance, 'f', 4, 64)
+}
+
+// geoPositionResponse returns a position as an array of its longitude and latitude, formatted
+// to 17 decimal places without trailing zeros like Redis.
+func geoPositionResponse(longitude, latitude float64) string {
+	format := func(f float64) string {
+		s := strings.TrimRight(strconv.FormatFloat(f, 'f', 17, 64), "0")
+		return strings.TrimSuffix(s, ".")
+	}
+	return array(bulkString(format(longitude)), bulkString(format(latitude)))
+}
+
+func NewGeoDistCommand(
+	store *Store,
+	clock Clock,
+	key string,
+	member1 string,
+	member2 string,
+	unit float64,
+) *GeoDistCommand {
+	return &GeoDistCommand{
+		store:   store,
+		clock:   clock,
+		key:     key,
+		member1: member1,
+		member2: member2,
+		unit:    unit,
+	}
+}
+
+// GeoDistCommand is GEODIST, which replies with the distance between two members in units of
+// unit meters.
+type GeoDistCommand struct {
+	store   *Store
+	clock   Clock
+	key     string
+	member1 string
+	member2 string
+	unit    float64
+}
+
+func (g *GeoDistCommand) Run() string {
+	response := nullBulkString
+	g.store.read(g.clock.NowMonotonic(), func(tx *storeTx) {
+		value, ok, err := tx.getTyped(g.key, ValueTypeZSet)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		if !ok {
+			return
+		}
+		score1, ok1 := value.zset().score(g.member1)
+		score2, ok2 := value.zset().score(g.member2)
+		if !ok1 || !ok2 {
+			return
+		}
+		lon1, lat1 := geoDecodeScore(score1)
+		lon2, lat2 := geoDecodeScore(score2)
+		response = bulkString(formatGeoDistance(geoDistance(lon1, lat1, lon2, lat2) / g.unit))
+	})
+	return response
+}
+
+func NewGeoPosCommand(store *Store, clock Clock, key string, members []string) *GeoPosCommand {
+	return &GeoPosCommand{
+		store:   store,
+		clock:   clock,
+		key:     key,
+		members: members,
+	}
+}
+
+// GeoPosCommand is GEOPOS, which replies with the position of each member, or null for those
+// that aren't in the index.
+type GeoPosCommand struct {
+	store   *Store
+	clock   Clock
+	key     string
+	members []string
+}
+
+func (g *GeoPosCommand) Run() string {
+	var response string
+	g.store.read(g.clock.NowMonotonic(), func(tx *storeTx) {
+		value, ok, err := tx.getTyped(g.key, ValueTypeZSet)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		elements := make([]string, len(g.members))
+		for i, member := range g.members {
+			elements[i] = nullArray
+			if !ok {
+				continue
+			}
+			if score, exists := value.zset().score(member); exists {
+				elements[i] = geoPositionResponse(geoDecodeScore(score))
+			}
+		}
+		response = array(elements...)
+	})
+	return response
+}
+
+func NewGeoHashCommand(store *Store, clock Clock, key string, members []string) *GeoHashCommand {
+	return &GeoHashCommand{
+		store:   store,
+		clock:   clock,
+		key:     key,
+		members: members,
+	}
+}
+
+// GeoHashCommand is GEOHASH, which replies with the standard 11 character geohash of each
+// member, or null for those that aren't in the index.
+type GeoHashCommand struct {
+	store   *Store
+	clock   Clock
+	key     string
+	members []string
+}
+
+const geoHashAlphabet = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+func (g *GeoHashCommand) Run() string {
+	var response string
+	g.store.read(g.clock.NowMonotonic(), func(tx *storeTx) {
+		value, ok, err := tx.getTyped(g.key, ValueTypeZSet)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		elements := make([]string, len(g.members))
+		for i, member := range g.members {
+			elements[i] = nullBulkString
+			if !ok {
+				continue
+			}
+			if score, exists := value.zset().score(member); exists {
+				elements[i] = bulkString(geoHashString(score))
+			}
+		}
+		response = array(elements...)
+	})
+	return response
+}
+
+// geoHashString returns the standard geohash of the position a sorted set score encodes, which
+// unlike the score covers latitudes from -90 to 90.
+func geoHashString(score float64) string {
+	longitude, latitude := geoDecodeScore(score)
+	bits := geoEncode(longitude, latitude, geoStepMax, geoRange{-90, 90}).bits
+	var result [11]byte
+	for i := range result {
+		// There are only 52 bits, so the last character is always 0.
+		var index uint64
+		if i < len(result)-1 {
+			index = bits >> (52 - (i+1)*5) & 0x1f
+		}
+		result[i] = geoHashAlphabet[index]
+	}
+	return string(result[:])
+}
+
+// GeoOrder is the order of GEOSEARCH results.
+type GeoOrder int
+
+const (
+	// GeoOrderNone leaves the results in the order they're found in.
+	GeoOrderNone GeoOrder = iota
+	// GeoOrderAsc sorts the results nearest first.
+	GeoOrderAsc
+	// GeoOrderDesc sorts the results furthest first.
+	GeoOrderDesc
+)
+
+// GeoSearchQuery is the area that GEOSEARCH searches and how it orders and limits the results.
+type GeoSearchQuery struct {
+	// FromMember centres the search on the position of Member rather than Longitude and Latitude.
+	FromMember          bool
+	Member              string
+	Longitude, Latitude float64
+	Shape               GeoShape
+	Order               GeoOrder
+	// Count limits the results to that many if it's positive. They're the nearest ones unless
+	// Any is true, which stops the search as soon as enough are found.
+	Count int
+	Any   bool
+}
+
+func NewGeoSearchCommand(
+	store *Store,
+	clock Clock,
+	key string,
+	query GeoSearchQuery,
+	options ...func(*GeoSearchCommand),
+) *GeoSearchCommand {
+	result := &GeoSearchCommand{
+		store: store,
+		clock: clock,
+		key:   key,
+		query: query,
+	}
+	for _, option := range options {
+		option(result)
+	}
+	return result
+}
+
+// GeoSearchCommand is GEOSEARCH, or GEOSEARCHSTORE if it has a destination.
+type GeoSearchCommand struct {
+	store       *Store
+	clock       Clock
+	key         string
+	query       GeoSearchQuery
+	withCoord   bool
+	withDist    bool
+	withHash    bool
+	destination *string
+	storeDist   bool
+}
+
+func GeoSearchWithCoord() func(*GeoSearchCommand) {
+	return func(command *GeoSearchCommand) {
+		command.withCoord = true
+	}
+}
+
+func GeoSearchWithDist() func(*GeoSearchCommand) {
+	return func(command *GeoSearchCommand) {
+		command.withDist = true
+	}
+}
+
+func GeoSearchWithHash() func(*GeoSearchCommand) {
+	return func(command *GeoSearchCommand) {
+		command.withHash = true
+	}
+}
+
+// GeoSearchStore makes a GeoSearchCommand store the results at destination as a geospatial
+// index, replacing whatever is there, and reply with the number of results.
+func GeoSearchStore(destination string) func(*GeoSearchCommand) {
+	return func(command *GeoSearchCommand) {
+		command.destination = &destination
+	}
+}
+
+// GeoSearchStoreDist makes a GeoSearchCommand that stores its results use their distances
+// from the centre of the search as their scores, rather than their geohashes.
+func GeoSearchStoreDist() func(*GeoSearchCommand) {
+	return func(command *GeoSearchCommand) {
+		command.storeDist = true
+	}
+}
+
+// geoSearchResult is a member found by a GEOSEARCH and its distance in meters from the centre.
+type geoSearchResult struct {
+	ScoreMember
+	distance float64
+}
+
+func (g *GeoSearchCommand) Run() string {
+	var response string
+	run := g.store.read
+	if g.destination != nil {
+		run = g.store.write
+	}
+	run(g.clock.NowMonotonic(), func(tx *storeTx) {
+		value, ok, err := tx.getTyped(g.key, ValueTypeZSet)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		var results []geoSearchResult
+		if ok {
+			if results, err = g.search(value.zset()); err != nil {
+				response = errorResponse(err)
+				return
+			}
+		}
+		if g.destination == nil {
+			response = g.response(results)
+			return
+		}
+
+		response = integer(len(results))
+		if len(results) == 0 {
+			if tx.delete(*g.destination) {
+				tx.notify(notifyGeneric, "del", *g.destination)
+			}
+			return
+		}
+		zset := newZSet()
+		for _, result := range results {
+			score := result.Score
+			if g.storeDist {
+				score = result.distance / g.query.Shape.Unit
+			}
+			zset.add(result.Member, score)
+		}
+		tx.set(*g.destination, StoreValue{data: zset})
+		tx.notify(notifyZSet, "geosearchstore", *g.destination)
+		tx.signalKeyAsReady(*g.destination)
+	})
+	return response
+}
+
+// search returns the members of z within the area of g's query, ordered and limited by it.
+func (g *GeoSearchCommand) search(z *zset) ([]geoSearchResult, error) {
+	q := g.query
+	longitude, latitude := q.Longitude, q.Latitude
+	if q.FromMember {
+		score, ok := z.score(q.Member)
+		if !ok {
+			return nil, CommandError("ERR could not decode requested zset member")
+		}
+		longitude, latitude = geoDecodeScore(score)
+	}
+
+	limit := 0
+	if q.Any {
+		limit = q.Count
+	}
+	var results []geoSearchResult
+	for _, area := range geoSearchAreas(q.Shape, longitude, latitude) {
+		min, max := area.scoreRange()
+		scores := ScoreRange{Min: min, Max: max, MaxExclusive: true}
+		for _, entry := range z.query(ZRangeQuery{By: ZRangeByScore, Scores: scores, Count: -1}) {
+			if limit > 0 && len(results) >= limit {
+				break
+			}
+			entryLon, entryLat := geoDecodeScore(entry.Score)
+			if distance, ok := q.Shape.contains(longitude, latitude, entryLon, entryLat); ok {
+				results = append(results, geoSearchResult{ScoreMember: entry, distance: distance})
+			}
+		}
+	}
+
+	// The nearest results are the ones to keep, unless any will do.
+	order := q.Order
+	if q.Count > 0 && order == GeoOrderNone && !q.Any {
+		order = GeoOrderAsc
+	}
+	switch order {
+	case GeoOrderAsc:
+		sort.SliceStable(results, func(i, j int) bool {
+			return results[i].distance < results[j].distance
+		})
+	case GeoOrderDesc:
+		sort.SliceStable(results, func(i, j int) bool {
+			return results[i].distance > results[j].distance
+		})
+	}
+	if q.Count > 0 && len(results) > q.Count {
+		results = results[:q.Count]
+	}
+	return results, nil
+}
+
+// response returns results as an array of their members, or of arrays of their members followed
+// by whichever of their distance, geohash and position g asks for.
+func (g *GeoSearchCommand) response(results []geoSearchResult) string {
+	elements := make([]string, len(results))
+	for i, result := range results {
+		if !g.withDist && !g.withHash && !g.withCoord {
+			elements[i] = bulkString(result.Member)
+			continue
+		}
+		fields := []string{bulkString(result.Member)}
+		if g.withDist {
+			fields = append(fields, bulkString(formatGeoDistance(result.distance/g.query.Shape.Unit)))
+		}
+		if g.withHash {
+			fields = append(fields, integer(int(result.Score)))
+		}
+		if g.withCoord {
+			fields = append(fields, geoPositionResponse(geoDecodeScore(result.Score)))
+		}
+		elements[i] = array(fields...)
+	}
+	return array(elements...)
+}
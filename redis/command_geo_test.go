@@ -0,0 +1,610 @@
+package redis_test
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/codecrafters-io/redis-starter-go/redis"
+)
+
+const (
+	palermo = "*2\r\n$20\r\n13.36138933897018433\r\n$20\r\n38.11555639549629859\r\n"
+	catania = "*2\r\n$20\r\n15.08726745843887329\r\n$20\r\n37.50266842333162032\r\n"
+)
+
+// sicily is the request that adds Palermo and Catania to the geospatial index at "Sicily", as in
+// the Redis docs.
+var sicily = []string{
+	"GEOADD", "Sicily", "13.361389", "38.115556", "Palermo", "15.087269", "37.502669", "Catania",
+}
+
+func TestGeoAddCommand(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	store.Set("string", "zelda")
+	clock := FakeClock{}
+	palermo := redis.GeoMember{Longitude: 13.361389, Latitude: 38.115556, Member: "Palermo"}
+	catania := redis.GeoMember{Longitude: 15.087269, Latitude: 37.502669, Member: "Catania"}
+	moved := redis.GeoMember{Longitude: 15, Latitude: 37, Member: "Catania"}
+
+	tests := []struct {
+		name     string
+		key      string
+		members  []redis.GeoMember
+		options  []func(*redis.ZAddCommand)
+		response string
+		score    string
+	}{
+		{
+			name:     "new key",
+			key:      "Sicily",
+			members:  []redis.GeoMember{palermo, catania},
+			response: ":2\r\n",
+			score:    "$16\r\n3479447370796909\r\n",
+		},
+		{
+			name:     "existing members",
+			key:      "Sicily",
+			members:  []redis.GeoMember{palermo, catania},
+			response: ":0\r\n",
+			score:    "$16\r\n3479447370796909\r\n",
+		},
+		{
+			name:     "NX",
+			key:      "Sicily",
+			members:  []redis.GeoMember{moved},
+			options:  []func(*redis.ZAddCommand){redis.ZAddNX()},
+			response: ":0\r\n",
+			score:    "$16\r\n3479447370796909\r\n",
+		},
+		{
+			name:     "XX with CH",
+			key:      "Sicily",
+			members:  []redis.GeoMember{moved},
+			options:  []func(*redis.ZAddCommand){redis.ZAddXX(), redis.ZAddCH()},
+			response: ":1\r\n",
+			score:    "$16\r\n3476504601741708\r\n",
+		},
+		{
+			name:     "wrong type",
+			key:      "string",
+			members:  []redis.GeoMember{palermo},
+			response: "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			command := redis.NewGeoAddCommand(store, clock, test.key, test.members, test.options...)
+			if response := command.Run(); response != test.response {
+				t.Errorf("command expected to return %#v but was %#v", test.response, response)
+			}
+			if test.score == "" {
+				return
+			}
+			score := redis.NewZScoreCommand(store, clock, test.key, []string{"Catania"}).Run()
+			if score != test.score {
+				t.Errorf("expected score to be %#v but was %#v", test.score, score)
+			}
+		})
+	}
+}
+
+func TestGeoDistCommand(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	store.Set("string", "zelda")
+	clock := FakeClock{}
+	parser := redis.NewParser(defaultEncodingRedisConfig, store, clock)
+	keysWith(t, parser, sicily)
+
+	tests := []struct {
+		name     string
+		key      string
+		member1  string
+		member2  string
+		unit     float64
+		response string
+	}{
+		{
+			name:     "meters",
+			key:      "Sicily",
+			member1:  "Palermo",
+			member2:  "Catania",
+			unit:     1,
+			response: "$11\r\n166274.1516\r\n",
+		},
+		{
+			name:     "kilometers",
+			key:      "Sicily",
+			member1:  "Palermo",
+			member2:  "Catania",
+			unit:     1000,
+			response: "$8\r\n166.2742\r\n",
+		},
+		{
+			name:     "miles",
+			key:      "Sicily",
+			member1:  "Palermo",
+			member2:  "Catania",
+			unit:     1609.34,
+			response: "$8\r\n103.3182\r\n",
+		},
+		{
+			name:     "same member",
+			key:      "Sicily",
+			member1:  "Palermo",
+			member2:  "Palermo",
+			unit:     1,
+			response: "$6\r\n0.0000\r\n",
+		},
+		{
+			name:     "missing member",
+			key:      "Sicily",
+			member1:  "Palermo",
+			member2:  "Agrigento",
+			unit:     1,
+			response: "$-1\r\n",
+		},
+		{
+			name:     "missing key",
+			key:      "missing",
+			member1:  "Palermo",
+			member2:  "Catania",
+			unit:     1,
+			response: "$-1\r\n",
+		},
+		{
+			name:     "wrong type",
+			key:      "string",
+			member1:  "Palermo",
+			member2:  "Catania",
+			unit:     1,
+			response: "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			command := redis.NewGeoDistCommand(
+				store, clock, test.key, test.member1, test.member2, test.unit,
+			)
+			if response := command.Run(); response != test.response {
+				t.Errorf("command expected to return %#v but was %#v", test.response, response)
+			}
+		})
+	}
+}
+
+func TestGeoPosCommand(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	store.Set("string", "zelda")
+	clock := FakeClock{}
+	parser := redis.NewParser(defaultEncodingRedisConfig, store, clock)
+	keysWith(t, parser, sicily)
+
+	tests := []struct {
+		name     string
+		key      string
+		members  []string
+		response string
+	}{
+		{
+			name:     "members",
+			key:      "Sicily",
+			members:  []string{"Palermo", "Catania", "Agrigento"},
+			response: "*3\r\n" + palermo + catania + "*-1\r\n",
+		},
+		{
+			name:     "no members",
+			key:      "Sicily",
+			response: "*0\r\n",
+		},
+		{
+			name:     "missing key",
+			key:      "missing",
+			members:  []string{"Palermo"},
+			response: "*1\r\n*-1\r\n",
+		},
+		{
+			name:     "wrong type",
+			key:      "string",
+			members:  []string{"Palermo"},
+			response: "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			command := redis.NewGeoPosCommand(store, clock, test.key, test.members)
+			if response := command.Run(); response != test.response {
+				t.Errorf("command expected to return %#v but was %#v", test.response, response)
+			}
+		})
+	}
+}
+
+func TestGeoHashCommand(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	store.Set("string", "zelda")
+	clock := FakeClock{}
+	parser := redis.NewParser(defaultEncodingRedisConfig, store, clock)
+	keysWith(t, parser, sicily)
+
+	tests := []struct {
+		name     string
+		key      string
+		members  []string
+		response string
+	}{
+		{
+			name:     "members",
+			key:      "Sicily",
+			members:  []string{"Palermo", "Catania", "Agrigento"},
+			response: "*3\r\n$11\r\nsqc8b49rny0\r\n$11\r\nsqdtr74hyu0\r\n$-1\r\n",
+		},
+		{
+			name:     "missing key",
+			key:      "missing",
+			members:  []string{"Palermo"},
+			response: "*1\r\n$-1\r\n",
+		},
+		{
+			name:     "wrong type",
+			key:      "string",
+			members:  []string{"Palermo"},
+			response: "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			command := redis.NewGeoHashCommand(store, clock, test.key, test.members)
+			if response := command.Run(); response != test.response {
+				t.Errorf("command expected to return %#v but was %#v", test.response, response)
+			}
+		})
+	}
+}
+
+func TestGeoSearchCommand(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	store.Set("string", "zelda")
+	clock := FakeClock{}
+	parser := redis.NewParser(defaultEncodingRedisConfig, store, clock)
+	keysWith(t, parser, sicily)
+	redis.NewGeoAddCommand(store, clock, "Sicily", []redis.GeoMember{
+		{Longitude: 12.758489, Latitude: 38.788135, Member: "edge1"},
+		{Longitude: 17.241510, Latitude: 38.788135, Member: "edge2"},
+	}).Run()
+
+	radius := redis.GeoShape{Radius: 200, Unit: 1000}
+	box := redis.GeoShape{Box: true, Width: 400, Height: 400, Unit: 1000}
+	withAll := []func(*redis.GeoSearchCommand){
+		redis.GeoSearchWithDist(), redis.GeoSearchWithHash(), redis.GeoSearchWithCoord(),
+	}
+
+	tests := []struct {
+		name     string
+		key      string
+		query    redis.GeoSearchQuery
+		options  []func(*redis.GeoSearchCommand)
+		response string
+	}{
+		{
+			name: "radius",
+			key:  "Sicily",
+			query: redis.GeoSearchQuery{
+				Longitude: 15, Latitude: 37, Shape: radius, Order: redis.GeoOrderAsc,
+			},
+			response: "*2\r\n$7\r\nCatania\r\n$7\r\nPalermo\r\n",
+		},
+		{
+			name: "radius with coordinates and distances",
+			key:  "Sicily",
+			query: redis.GeoSearchQuery{
+				Longitude: 15, Latitude: 37, Shape: radius, Order: redis.GeoOrderAsc,
+			},
+			options: []func(*redis.GeoSearchCommand){
+				redis.GeoSearchWithCoord(), redis.GeoSearchWithDist(),
+			},
+			response: "*2\r\n" +
+				"*3\r\n$7\r\nCatania\r\n$7\r\n56.4413\r\n" + catania +
+				"*3\r\n$7\r\nPalermo\r\n$8\r\n190.4424\r\n" + palermo,
+		},
+		{
+			name: "box with everything",
+			key:  "Sicily",
+			query: redis.GeoSearchQuery{
+				Longitude: 15, Latitude: 37, Shape: box, Order: redis.GeoOrderAsc,
+			},
+			options: withAll,
+			response: "*4\r\n" +
+				"*4\r\n$7\r\nCatania\r\n$7\r\n56.4413\r\n:3479447370796909\r\n" + catania +
+				"*4\r\n$7\r\nPalermo\r\n$8\r\n190.4424\r\n:3479099956230698\r\n" + palermo +
+				"*4\r\n$5\r\nedge2\r\n$8\r\n279.7403\r\n:3481342659049484\r\n" +
+				"*2\r\n$20\r\n17.24151045083999634\r\n$20\r\n38.78813451624225195\r\n" +
+				"*4\r\n$5\r\nedge1\r\n$8\r\n279.7405\r\n:3479273021651468\r\n" +
+				"*2\r\n$19\r\n12.7584877610206604\r\n$20\r\n38.78813451624225195\r\n",
+		},
+		{
+			name: "descending",
+			key:  "Sicily",
+			query: redis.GeoSearchQuery{
+				Longitude: 15, Latitude: 37, Shape: box, Order: redis.GeoOrderDesc,
+			},
+			response: "*4\r\n$5\r\nedge1\r\n$5\r\nedge2\r\n$7\r\nPalermo\r\n$7\r\nCatania\r\n",
+		},
+		{
+			name: "count sorts nearest first",
+			key:  "Sicily",
+			query: redis.GeoSearchQuery{
+				Longitude: 15, Latitude: 37, Shape: box, Count: 2,
+			},
+			response: "*2\r\n$7\r\nCatania\r\n$7\r\nPalermo\r\n",
+		},
+		{
+			name: "count descending",
+			key:  "Sicily",
+			query: redis.GeoSearchQuery{
+				Longitude: 15, Latitude: 37, Shape: box, Order: redis.GeoOrderDesc, Count: 1,
+			},
+			response: "*1\r\n$5\r\nedge1\r\n",
+		},
+		{
+			name: "from member",
+			key:  "Sicily",
+			query: redis.GeoSearchQuery{
+				FromMember: true,
+				Member:     "Palermo",
+				Shape:      redis.GeoShape{Radius: 200, Unit: 1000},
+				Order:      redis.GeoOrderAsc,
+			},
+			options: []func(*redis.GeoSearchCommand){redis.GeoSearchWithDist()},
+			response: "*3\r\n" +
+				"*2\r\n$7\r\nPalermo\r\n$6\r\n0.0000\r\n" +
+				"*2\r\n$5\r\nedge1\r\n$7\r\n91.4007\r\n" +
+				"*2\r\n$7\r\nCatania\r\n$8\r\n166.2742\r\n",
+		},
+		{
+			name: "nothing found",
+			key:  "Sicily",
+			query: redis.GeoSearchQuery{
+				Longitude: 0, Latitude: 0, Shape: radius,
+			},
+			response: "*0\r\n",
+		},
+		{
+			name: "missing member",
+			key:  "Sicily",
+			query: redis.GeoSearchQuery{
+				FromMember: true, Member: "Agrigento", Shape: radius,
+			},
+			response: "-ERR could not decode requested zset member\r\n",
+		},
+		{
+			name: "missing key",
+			key:  "missing",
+			query: redis.GeoSearchQuery{
+				FromMember: true, Member: "Palermo", Shape: radius,
+			},
+			response: "*0\r\n",
+		},
+		{
+			name: "wrong type",
+			key:  "string",
+			query: redis.GeoSearchQuery{
+				Longitude: 15, Latitude: 37, Shape: radius,
+			},
+			response: "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			command := redis.NewGeoSearchCommand(store, clock, test.key, test.query, test.options...)
+			if response := command.Run(); response != test.response {
+				t.Errorf("command expected to return %#v but was %#v", test.response, response)
+			}
+		})
+	}
+}
+
+func TestGeoSearchCommand_Any(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{}
+	parser := redis.NewParser(defaultEncodingRedisConfig, store, clock)
+	keysWith(t, parser, sicily)
+
+	query := redis.GeoSearchQuery{
+		Longitude: 15,
+		Latitude:  37,
+		Shape:     redis.GeoShape{Radius: 200, Unit: 1000},
+		Count:     1,
+		Any:       true,
+	}
+	response := redis.NewGeoSearchCommand(store, clock, "Sicily", query).Run()
+	if members := bulkStrings(t, response); len(members) != 1 {
+		t.Errorf("command expected to return 1 member but was %#v", response)
+	}
+}
+
+// TestGeoSearchCommand_Radius checks searches against the distances of every member, which
+// catches results missed by searching too few geohash areas.
+func TestGeoSearchCommand_Radius(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{}
+	random := rand.New(rand.NewSource(1))
+	members := make([]redis.GeoMember, 2000)
+	for i := range members {
+		members[i] = redis.GeoMember{
+			Longitude: -0.2 + random.Float64()*0.2,
+			Latitude:  51.45 + random.Float64()*0.1,
+			Member:    fmt.Sprint("driver", i),
+		}
+	}
+	redis.NewGeoAddCommand(store, clock, "drivers", members).Run()
+
+	for _, radius := range []float64{0.5, 1, 3, 10} {
+		centre := members[random.Intn(len(members))]
+		var want []string
+		edge := make(map[string]bool)
+		for _, member := range members {
+			// Searches use the positions decoded from the scores, which are slightly off, so
+			// leave out members that are too close to the edge to tell.
+			distance := haversine(centre, member)
+			if math.Abs(distance-radius*1000) < 1 {
+				edge[member.Member] = true
+			} else if distance <= radius*1000 {
+				want = append(want, member.Member)
+			}
+		}
+
+		query := redis.GeoSearchQuery{
+			FromMember: true,
+			Member:     centre.Member,
+			Shape:      redis.GeoShape{Radius: radius, Unit: 1000},
+		}
+		response := redis.NewGeoSearchCommand(store, clock, "drivers", query).Run()
+		var got []string
+		for _, member := range bulkStrings(t, response) {
+			if !edge[member] {
+				got = append(got, member)
+			}
+		}
+		if !equalSorted(got, want) {
+			t.Errorf("search within %gkm expected to find %d members but found %d",
+				radius, len(want), len(got))
+		}
+	}
+}
+
+// haversine returns the distance in meters between two members, like Redis.
+func haversine(a, b redis.GeoMember) float64 {
+	radians := func(degrees float64) float64 { return degrees * math.Pi / 180 }
+	u := math.Sin((radians(b.Latitude) - radians(a.Latitude)) / 2)
+	v := math.Sin((radians(b.Longitude) - radians(a.Longitude)) / 2)
+	h := u*u + math.Cos(radians(a.Latitude))*math.Cos(radians(b.Latitude))*v*v
+	return 2 * 6372797.560856 * math.Asin(math.Sqrt(h))
+}
+
+func TestGeoSearchCommand_Store(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	store.Set("string", "zelda")
+	clock := FakeClock{}
+	parser := redis.NewParser(defaultEncodingRedisConfig, store, clock)
+	keysWith(t, parser, sicily)
+	radius := redis.GeoShape{Radius: 200, Unit: 1000}
+
+	tests := []struct {
+		name     string
+		key      string
+		query    redis.GeoSearchQuery
+		options  []func(*redis.GeoSearchCommand)
+		response string
+		stored   string
+	}{
+		{
+			name: "geohashes",
+			key:  "Sicily",
+			query: redis.GeoSearchQuery{
+				Longitude: 15, Latitude: 37, Shape: radius,
+			},
+			response: ":2\r\n",
+			stored: "*4\r\n$7\r\nPalermo\r\n$16\r\n3479099956230698\r\n" +
+				"$7\r\nCatania\r\n$16\r\n3479447370796909\r\n",
+		},
+		{
+			name: "distances",
+			key:  "Sicily",
+			query: redis.GeoSearchQuery{
+				Longitude: 15, Latitude: 37, Shape: radius,
+			},
+			options:  []func(*redis.GeoSearchCommand){redis.GeoSearchStoreDist()},
+			response: ":2\r\n",
+			stored: "*4\r\n$7\r\nCatania\r\n$16\r\n56.4412578701582\r\n" +
+				"$7\r\nPalermo\r\n$18\r\n190.44242984775795\r\n",
+		},
+		{
+			name: "nothing found",
+			key:  "Sicily",
+			query: redis.GeoSearchQuery{
+				Longitude: 0, Latitude: 0, Shape: radius,
+			},
+			response: ":0\r\n",
+			stored:   "*0\r\n",
+		},
+		{
+			name: "missing key",
+			key:  "missing",
+			query: redis.GeoSearchQuery{
+				Longitude: 15, Latitude: 37, Shape: radius,
+			},
+			response: ":0\r\n",
+			stored:   "*0\r\n",
+		},
+		{
+			name: "wrong type",
+			key:  "string",
+			query: redis.GeoSearchQuery{
+				Longitude: 15, Latitude: 37, Shape: radius,
+			},
+			response: "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n",
+			stored:   "*0\r\n",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			destination := "dest-" + test.name
+			if test.response[0] != '-' {
+				// The destination is replaced whatever its type.
+				store.Set(destination, "zelda")
+			}
+			options := append([]func(*redis.GeoSearchCommand){
+				redis.GeoSearchStore(destination),
+			}, test.options...)
+			command := redis.NewGeoSearchCommand(store, clock, test.key, test.query, options...)
+			if response := command.Run(); response != test.response {
+				t.Errorf("command expected to return %#v but was %#v", test.response, response)
+			}
+			query := redis.NewZRangeQuery(0, -1)
+			stored := redis.NewZRangeCommand(store, clock, destination, query, true).Run()
+			if stored != test.stored {
+				t.Errorf("expected destination to be %#v but was %#v", test.stored, stored)
+			}
+		})
+	}
+}
@@ -0,0 +1,1038 @@
+package redis
+
+import (
+	"math"
+	"math/rand"
+	"strconv"
+	"time"
+)
+
+// FieldValue is a hash field and the value to set it to.
+type FieldValue struct {
+	Field string
+	Value string
+}
+
+// hashForWrite returns the hash at key with its expired fields removed, notifying the hexpired
+// event, first deleting key if that leaves the hash empty. ok is false if there's no hash at key.
+func hashForWrite(tx *storeTx, key string) (h *hash, ok bool, err error) {
+	value, ok, err := tx.getTyped(key, ValueTypeHash)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	h = value.hash()
+	if h.removeExpired(tx.now) {
+		tx.notify(notifyHash, "hexpired", key)
+	}
+	if h.len(tx.now) == 0 {
+		tx.deleteEmpty(key)
+		return nil, false, nil
+	}
+	return h, true, nil
+}
+
+// deleteExpiredHashFields removes the expired fields of the hash at key for DeleteExpiredKeys,
+// like hashForWrite, returning true if any had expired. It stops sampling key once there's no
+// hash there with fields that have expiry times.
+func deleteExpiredHashFields(tx *storeTx, key string) bool {
+	value, ok, err := tx.getTyped(key, ValueTypeHash)
+	if err != nil || !ok || value.hash().expiring == 0 {
+		delete(tx.store.hashExpires, key)
+		return false
+	}
+	expiring := value.hash().expiring
+	h, ok, _ := hashForWrite(tx, key)
+	if !ok {
+		return true
+	}
+	if h.expiring == 0 {
+		delete(tx.store.hashExpires, key)
+	}
+	return h.expiring < expiring
+}
+
+// createHashForWrite is like hashForWrite, but creates an empty hash at key if there isn't one.
+func createHashForWrite(tx *storeTx, key string) (*hash, error) {
+	h, ok, err := hashForWrite(tx, key)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		h = createHash(tx, key)
+	}
+	return h, nil
+}
+
+func createHash(tx *storeTx, key string) *hash {
+	h := newHash()
+	tx.set(key, StoreValue{data: h})
+	return h
+}
+
+// hashGet returns the value of field in hash, if ok is true and it has one.
+func hashGet(hash *hash, ok bool, field string, now time.Time) (string, bool) {
+	if !ok {
+		return "", false
+	}
+	return hash.get(field, now)
+}
+
+// deleteHashIfEmpty deletes the hash at key if it no longer has any fields.
+func deleteHashIfEmpty(tx *storeTx, key string, h *hash) {
+	if h.len(tx.now) == 0 {
+		tx.deleteEmpty(key)
+	}
+}
+
+func NewHSetCommand(
+	store *Store,
+	clock Clock,
+	config *Config,
+	key string,
+	fieldValues []FieldValue,
+	options ...func(*HSetCommand),
+) *HSetCommand {
+	result := &HSetCommand{
+		store:       store,
+		clock:       clock,
+		config:      config,
+		key:         key,
+		fieldValues: fieldValues,
+	}
+	for _, option := range options {
+		option(result)
+	}
+	return result
+}
+
+// HSetCommand is HSET, or HMSET if it replies OK.
+type HSetCommand struct {
+	store       *Store
+	clock       Clock
+	config      *Config
+	key         string
+	fieldValues []FieldValue
+	replyOK     bool
+}
+
+// HSetReplyOK makes an HSetCommand reply OK rather than with the number of fields it added, like
+// HMSET.
+func HSetReplyOK() func(*HSetCommand) {
+	return func(command *HSetCommand) {
+		command.replyOK = true
+	}
+}
+
+func (h *HSetCommand) Run() string {
+	var response string
+	h.store.write(h.clock.NowMonotonic(), func(tx *storeTx) {
+		hash, err := createHashForWrite(tx, h.key)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		added := 0
+		for _, fieldValue := range h.fieldValues {
+			if hash.set(fieldValue.Field, fieldValue.Value, false) {
+				added++
+			}
+		}
+		hash.convertIfNeeded(h.config.Encoding)
+		tx.notify(notifyHash, "hset", h.key)
+		if h.replyOK {
+			response = simpleString("OK")
+		} else {
+			response = integer(added)
+		}
+	})
+	return response
+}
+
+func NewHSetNXCommand(
+	store *Store,
+	clock Clock,
+	config *Config,
+	key,
+	field,
+	value string,
+) *HSetNXCommand {
+	return &HSetNXCommand{
+		store:  store,
+		clock:  clock,
+		config: config,
+		key:    key,
+		field:  field,
+		value:  value,
+	}
+}
+
+type HSetNXCommand struct {
+	store  *Store
+	clock  Clock
+	config *Config
+	key    string
+	field  string
+	value  string
+}
+
+func (h *HSetNXCommand) Run() string {
+	var response string
+	h.store.write(h.clock.NowMonotonic(), func(tx *storeTx) {
+		hash, err := createHashForWrite(tx, h.key)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		if _, ok := hash.get(h.field, tx.now); ok {
+			response = integer(0)
+			return
+		}
+		hash.set(h.field, h.value, false)
+		hash.convertIfNeeded(h.config.Encoding)
+		tx.notify(notifyHash, "hset", h.key)
+		response = integer(1)
+	})
+	return response
+}
+
+func NewHGetCommand(store *Store, clock Clock, key, field string) *HGetCommand {
+	return &HGetCommand{
+		store: store,
+		clock: clock,
+		key:   key,
+		field: field,
+	}
+}
+
+type HGetCommand struct {
+	store *Store
+	clock Clock
+	key   string
+	field string
+}
+
+func (h *HGetCommand) Run() string {
+	response := nullBulkString
+	h.store.read(h.clock.NowMonotonic(), func(tx *storeTx) {
+		value, ok, err := tx.getTyped(h.key, ValueTypeHash)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		if !ok {
+			return
+		}
+		if fieldValue, ok := value.hash().get(h.field, tx.now); ok {
+			response = bulkString(fieldValue)
+		}
+	})
+	return response
+}
+
+func NewHMGetCommand(store *Store, clock Clock, key string, fields []string) *HMGetCommand {
+	return &HMGetCommand{
+		store:  store,
+		clock:  clock,
+		key:    key,
+		fields: fields,
+	}
+}
+
+type HMGetCommand struct {
+	store  *Store
+	clock  Clock
+	key    string
+	fields []string
+}
+
+func (h *HMGetCommand) Run() string {
+	var response string
+	h.store.read(h.clock.NowMonotonic(), func(tx *storeTx) {
+		value, ok, err := tx.getTyped(h.key, ValueTypeHash)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		elements := make([]string, len(h.fields))
+		for i, field := range h.fields {
+			elements[i] = nullBulkString
+			if !ok {
+				continue
+			}
+			if fieldValue, ok := value.hash().get(field, tx.now); ok {
+				elements[i] = bulkString(fieldValue)
+			}
+		}
+		response = array(elements...)
+	})
+	return response
+}
+
+func NewHDelCommand(store *Store, clock Clock, key string, fields []string) *HDelCommand {
+	return &HDelCommand{
+		store:  store,
+		clock:  clock,
+		key:    key,
+		fields: fields,
+	}
+}
+
+type HDelCommand struct {
+	store  *Store
+	clock  Clock
+	key    string
+	fields []string
+}
+
+func (h *HDelCommand) Run() string {
+	var response string
+	h.store.write(h.clock.NowMonotonic(), func(tx *storeTx) {
+		hash, ok, err := hashForWrite(tx, h.key)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		if !ok {
+			response = integer(0)
+			return
+		}
+		deleted := 0
+		for _, field := range h.fields {
+			if hash.delete(field) {
+				deleted++
+			}
+		}
+		if deleted > 0 {
+			tx.notify(notifyHash, "hdel", h.key)
+		}
+		deleteHashIfEmpty(tx, h.key, hash)
+		response = integer(deleted)
+	})
+	return response
+}
+
+func NewHLenCommand(store *Store, clock Clock, key string) *HLenCommand {
+	return &HLenCommand{
+		store: store,
+		clock: clock,
+		key:   key,
+	}
+}
+
+type HLenCommand struct {
+	store *Store
+	clock Clock
+	key   string
+}
+
+func (h *HLenCommand) Run() string {
+	response := integer(0)
+	h.store.read(h.clock.NowMonotonic(), func(tx *storeTx) {
+		value, ok, err := tx.getTyped(h.key, ValueTypeHash)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		if ok {
+			response = integer(value.hash().len(tx.now))
+		}
+	})
+	return response
+}
+
+func NewHStrLenCommand(store *Store, clock Clock, key, field string) *HStrLenCommand {
+	return &HStrLenCommand{
+		store: store,
+		clock: clock,
+		key:   key,
+		field: field,
+	}
+}
+
+type HStrLenCommand struct {
+	store *Store
+	clock Clock
+	key   string
+	field string
+}
+
+func (h *HStrLenCommand) Run() string {
+	response := integer(0)
+	h.store.read(h.clock.NowMonotonic(), func(tx *storeTx) {
+		value, ok, err := tx.getTyped(h.key, ValueTypeHash)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		if !ok {
+			return
+		}
+		if fieldValue, ok := value.hash().get(h.field, tx.now); ok {
+			response = integer(len(fieldValue))
+		}
+	})
+	return response
+}
+
+func NewHExistsCommand(store *Store, clock Clock, key, field string) *HExistsCommand {
+	return &HExistsCommand{
+		store: store,
+		clock: clock,
+		key:   key,
+		field: field,
+	}
+}
+
+type HExistsCommand struct {
+	store *Store
+	clock Clock
+	key   string
+	field string
+}
+
+func (h *HExistsCommand) Run() string {
+	response := integer(0)
+	h.store.read(h.clock.NowMonotonic(), func(tx *storeTx) {
+		value, ok, err := tx.getTyped(h.key, ValueTypeHash)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		if !ok {
+			return
+		}
+		if _, ok := value.hash().get(h.field, tx.now); ok {
+			response = integer(1)
+		}
+	})
+	return response
+}
+
+func NewHKeysCommand(store *Store, clock Clock, key string) *HKeysCommand {
+	return &HKeysCommand{
+		store: store,
+		clock: clock,
+		key:   key,
+	}
+}
+
+type HKeysCommand struct {
+	store *Store
+	clock Clock
+	key   string
+}
+
+func (h *HKeysCommand) Run() string {
+	return hashContents(h.store, h.clock, h.key, true, false)
+}
+
+func NewHValsCommand(store *Store, clock Clock, key string) *HValsCommand {
+	return &HValsCommand{
+		store: store,
+		clock: clock,
+		key:   key,
+	}
+}
+
+type HValsCommand struct {
+	store *Store
+	clock Clock
+	key   string
+}
+
+func (h *HValsCommand) Run() string {
+	return hashContents(h.store, h.clock, h.key, false, true)
+}
+
+func NewHGetAllCommand(store *Store, clock Clock, key string) *HGetAllCommand {
+	return &HGetAllCommand{
+		store: store,
+		clock: clock,
+		key:   key,
+	}
+}
+
+type HGetAllCommand struct {
+	store *Store
+	clock Clock
+	key   string
+}
+
+func (h *HGetAllCommand) Run() string {
+	return hashContents(h.store, h.clock, h.key, true, true)
+}
+
+// hashContents returns the response to HKEYS, HVALS or HGETALL: an array of the fields, the
+// values, or both interleaved, of the hash at key.
+func hashContents(store *Store, clock Clock, key string, fields, values bool) string {
+	response := array()
+	store.read(clock.NowMonotonic(), func(tx *storeTx) {
+		value, ok, err := tx.getTyped(key, ValueTypeHash)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		if !ok {
+			return
+		}
+		var elements []string
+		value.hash().each(tx.now, func(field, value string) {
+			if fields {
+				elements = append(elements, bulkString(field))
+			}
+			if values {
+				elements = append(elements, bulkString(value))
+			}
+		})
+		response = array(elements...)
+	})
+	return response
+}
+
+func NewHIncrByCommand(
+	store *Store,
+	clock Clock,
+	config *Config,
+	key,
+	field string,
+	increment int,
+) *HIncrByCommand {
+	return &HIncrByCommand{
+		store:     store,
+		clock:     clock,
+		config:    config,
+		key:       key,
+		field:     field,
+		increment: increment,
+	}
+}
+
+type HIncrByCommand struct {
+	store     *Store
+	clock     Clock
+	config    *Config
+	key       string
+	field     string
+	increment int
+}
+
+func (h *HIncrByCommand) Run() string {
+	var response string
+	h.store.write(h.clock.NowMonotonic(), func(tx *storeTx) {
+		hash, ok, err := hashForWrite(tx, h.key)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		current := 0
+		if value, exists := hashGet(hash, ok, h.field, tx.now); exists {
+			current, err = strconv.Atoi(value)
+			if err != nil {
+				response = errorResponse(CommandError("ERR hash value is not an integer"))
+				return
+			}
+		}
+		if (h.increment > 0 && current > math.MaxInt-h.increment) ||
+			(h.increment < 0 && current < math.MinInt-h.increment) {
+			response = errorResponse(CommandError("ERR increment or decrement would overflow"))
+			return
+		}
+		result := current + h.increment
+		if !ok {
+			hash = createHash(tx, h.key)
+		}
+		hash.set(h.field, strconv.Itoa(result), true)
+		hash.convertIfNeeded(h.config.Encoding)
+		tx.notify(notifyHash, "hincrby", h.key)
+		response = integer(result)
+	})
+	return response
+}
+
+func NewHIncrByFloatCommand(
+	store *Store,
+	clock Clock,
+	config *Config,
+	key,
+	field string,
+	increment float64,
+) *HIncrByFloatCommand {
+	return &HIncrByFloatCommand{
+		store:     store,
+		clock:     clock,
+		config:    config,
+		key:       key,
+		field:     field,
+		increment: increment,
+	}
+}
+
+type HIncrByFloatCommand struct {
+	store     *Store
+	clock     Clock
+	config    *Config
+	key       string
+	field     string
+	increment float64
+}
+
+func (h *HIncrByFloatCommand) Run() string {
+	var response string
+	h.store.write(h.clock.NowMonotonic(), func(tx *storeTx) {
+		hash, ok, err := hashForWrite(tx, h.key)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		current := 0.0
+		if value, exists := hashGet(hash, ok, h.field, tx.now); exists {
+			current, err = parseFloat(value)
+			if err != nil {
+				response = errorResponse(CommandError("ERR hash value is not a float"))
+				return
+			}
+		}
+		result := current + h.increment
+		if math.IsNaN(result) || math.IsInf(result, 0) {
+			response = errorResponse(CommandError("ERR increment would produce NaN or Infinity"))
+			return
+		}
+		formatted := formatFloat(result)
+		if !ok {
+			hash = createHash(tx, h.key)
+		}
+		hash.set(h.field, formatted, true)
+		hash.convertIfNeeded(h.config.Encoding)
+		tx.notify(notifyHash, "hincrbyfloat", h.key)
+		response = bulkString(formatted)
+	})
+	return response
+}
+
+// parseFloat parses s like Redis parses floating point values, which don't include NaN.
+func parseFloat(s string) (float64, error) {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(f) {
+		return 0, CommandError("ERR value is not a valid float")
+	}
+	return f, nil
+}
+
+// formatFloat formats f like Redis's INCRBYFLOAT: in decimal, never in exponent form, with no
+// trailing zeros.
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func NewHRandFieldCommand(
+	store *Store,
+	clock Clock,
+	key string,
+	options ...func(*HRandFieldCommand),
+) *HRandFieldCommand {
+	result := &HRandFieldCommand{
+		store: store,
+		clock: clock,
+		key:   key,
+	}
+	for _, option := range options {
+		option(result)
+	}
+	return result
+}
+
+// HRandFieldCommand is HRANDFIELD. Without a count it returns one random field. With a positive
+// count it returns up to that many distinct fields, and with a negative count it returns exactly
+// that many fields, which may repeat.
+type HRandFieldCommand struct {
+	store      *Store
+	clock      Clock
+	key        string
+	count      *int
+	withValues bool
+}
+
+func HRandFieldCount(count int) func(*HRandFieldCommand) {
+	return func(command *HRandFieldCommand) {
+		command.count = &count
+	}
+}
+
+func HRandFieldWithValues() func(*HRandFieldCommand) {
+	return func(command *HRandFieldCommand) {
+		command.withValues = true
+	}
+}
+
+func (h *HRandFieldCommand) Run() string {
+	response := nullBulkString
+	if h.count != nil {
+		response = array()
+	}
+	h.store.read(h.clock.NowMonotonic(), func(tx *storeTx) {
+		value, ok, err := tx.getTyped(h.key, ValueTypeHash)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		if !ok {
+			return
+		}
+		var fields []FieldValue
+		value.hash().each(tx.now, func(field, value string) {
+			fields = append(fields, FieldValue{Field: field, Value: value})
+		})
+		if len(fields) == 0 {
+			return
+		}
+		if h.count == nil {
+			response = bulkString(fields[rand.Intn(len(fields))].Field)
+			return
+		}
+
+		var chosen []FieldValue
+		if *h.count >= 0 {
+			rand.Shuffle(len(fields), func(i, j int) {
+				fields[i], fields[j] = fields[j], fields[i]
+			})
+			if *h.count < len(fields) {
+				fields = fields[:*h.count]
+			}
+			chosen = fields
+		} else {
+			chosen = make([]FieldValue, -*h.count)
+			for i := range chosen {
+				chosen[i] = fields[rand.Intn(len(fields))]
+			}
+		}
+		var elements []string
+		for _, fieldValue := range chosen {
+			elements = append(elements, bulkString(fieldValue.Field))
+			if h.withValues {
+				elements = append(elements, bulkString(fieldValue.Value))
+			}
+		}
+		response = array(elements...)
+	})
+	return response
+}
+
+func NewHScanCommand(
+	store *Store,
+	clock Clock,
+	key string,
+	cursor uint64,
+	options ...func(*HScanCommand),
+) *HScanCommand {
+	result := &HScanCommand{
+		store:  store,
+		clock:  clock,
+		key:    key,
+		cursor: cursor,
+		count:  defaultScanCount,
+	}
+	for _, option := range options {
+		option(result)
+	}
+	return result
+}
+
+// HScanCommand is HSCAN. Like Redis, it returns the whole of a listpack hash at once, whatever
+// the cursor and count.
+type HScanCommand struct {
+	store    *Store
+	clock    Clock
+	key      string
+	cursor   uint64
+	pattern  *string
+	count    int
+	noValues bool
+}
+
+func HScanMatch(pattern string) func(*HScanCommand) {
+	return func(command *HScanCommand) {
+		command.pattern = &pattern
+	}
+}
+
+func HScanCount(count int) func(*HScanCommand) {
+	return func(command *HScanCommand) {
+		command.count = count
+	}
+}
+
+func HScanNoValues() func(*HScanCommand) {
+	return func(command *HScanCommand) {
+		command.noValues = true
+	}
+}
+
+func (h *HScanCommand) Run() string {
+	response := array(bulkString("0"), array())
+	h.store.read(h.clock.NowMonotonic(), func(tx *storeTx) {
+		value, ok, err := tx.getTyped(h.key, ValueTypeHash)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		if !ok {
+			return
+		}
+		hash := value.hash()
+		var fields []string
+		hash.each(tx.now, func(field, _ string) {
+			fields = append(fields, field)
+		})
+		var next uint64
+		if hash.table != nil {
+			fields, next = scanPage(fields, h.cursor, h.count)
+		}
+
+		var elements []string
+		for _, field := range fields {
+			if h.pattern != nil && !globMatch(*h.pattern, field) {
+				continue
+			}
+			elements = append(elements, bulkString(field))
+			if !h.noValues {
+				fieldValue, _ := hash.get(field, tx.now)
+				elements = append(elements, bulkString(fieldValue))
+			}
+		}
+		response = array(bulkString(strconv.FormatUint(next, 10)), array(elements...))
+	})
+	return response
+}
+
+// ExpireCondition is the condition under which a command like HEXPIRE sets an expiry time.
+type ExpireCondition int
+
+const (
+	// ExpireAlways always sets the expiry time.
+	ExpireAlways ExpireCondition = iota
+	// ExpireNX only sets the expiry time if there isn't one.
+	ExpireNX
+	// ExpireXX only sets the expiry time if there already is one.
+	ExpireXX
+	// ExpireGT only sets the expiry time if it's later than the current one. No expiry time
+	// counts as an infinite one.
+	ExpireGT
+	// ExpireLT only sets the expiry time if it's earlier than the current one.
+	ExpireLT
+)
+
+// allows returns true if the condition allows current, which may be nil, to be replaced by
+// expiryTime.
+func (e ExpireCondition) allows(current *time.Time, expiryTime time.Time) bool {
+	switch e {
+	case ExpireNX:
+		return current == nil
+	case ExpireXX:
+		return current != nil
+	case ExpireGT:
+		return current != nil && expiryTime.After(*current)
+	case ExpireLT:
+		return current == nil || expiryTime.Before(*current)
+	}
+	return true
+}
+
+// The per-field results of HEXPIRE, HTTL and HPERSIST.
+const (
+	hashFieldMissing      = -2
+	hashFieldNoExpiryTime = -1
+	hashFieldNotChanged   = 0
+	hashFieldChanged      = 1
+	hashFieldDeleted      = 2
+)
+
+func NewHExpireCommand(
+	store *Store,
+	clock Clock,
+	key string,
+	expiryTime time.Time,
+	condition ExpireCondition,
+	fields []string,
+) *HExpireCommand {
+	return &HExpireCommand{
+		store:      store,
+		clock:      clock,
+		key:        key,
+		expiryTime: expiryTime,
+		condition:  condition,
+		fields:     fields,
+	}
+}
+
+// HExpireCommand is HEXPIRE, HPEXPIRE, HEXPIREAT or HPEXPIREAT. Fields whose expiry time is
+// already in the past are deleted.
+type HExpireCommand struct {
+	store      *Store
+	clock      Clock
+	key        string
+	expiryTime time.Time
+	condition  ExpireCondition
+	fields     []string
+}
+
+func (h *HExpireCommand) Run() string {
+	var response string
+	h.store.write(h.clock.NowMonotonic(), func(tx *storeTx) {
+		hash, ok, err := hashForWrite(tx, h.key)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		results := make([]string, len(h.fields))
+		changed, deleted := false, false
+		for i, field := range h.fields {
+			result := h.expireField(tx, hash, ok, field)
+			changed = changed || result == hashFieldChanged
+			deleted = deleted || result == hashFieldDeleted
+			results[i] = integer(result)
+		}
+		if changed {
+			tx.store.hashExpires[h.key] = struct{}{}
+			tx.notify(notifyHash, "hexpire", h.key)
+		}
+		if deleted {
+			tx.notify(notifyHash, "hdel", h.key)
+		}
+		if ok {
+			deleteHashIfEmpty(tx, h.key, hash)
+		}
+		response = array(results...)
+	})
+	return response
+}
+
+func (h *HExpireCommand) expireField(tx *storeTx, hash *hash, ok bool, field string) int {
+	if !ok {
+		return hashFieldMissing
+	}
+	f, ok := hash.lookup(field)
+	if !ok {
+		return hashFieldMissing
+	}
+	if !h.condition.allows(f.expiryTime, h.expiryTime) {
+		return hashFieldNotChanged
+	}
+	if !h.expiryTime.After(tx.now) {
+		hash.delete(field)
+		return hashFieldDeleted
+	}
+	hash.setExpiryTime(field, &h.expiryTime)
+	return hashFieldChanged
+}
+
+func NewHTTLCommand(
+	store *Store,
+	clock Clock,
+	key string,
+	fields []string,
+	unit time.Duration,
+	absolute bool,
+) *HTTLCommand {
+	return &HTTLCommand{
+		store:    store,
+		clock:    clock,
+		key:      key,
+		fields:   fields,
+		unit:     unit,
+		absolute: absolute,
+	}
+}
+
+// HTTLCommand is HTTL or HPTTL, which return how long fields have left to live in seconds or
+// milliseconds, depending on unit, or HEXPIRETIME or HPEXPIRETIME, which return the Unix times
+// at which fields expire if absolute is true.
+type HTTLCommand struct {
+	store    *Store
+	clock    Clock
+	key      string
+	fields   []string
+	unit     time.Duration
+	absolute bool
+}
+
+func (h *HTTLCommand) Run() string {
+	var response string
+	h.store.read(h.clock.NowMonotonic(), func(tx *storeTx) {
+		value, ok, err := tx.getTyped(h.key, ValueTypeHash)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		results := make([]string, len(h.fields))
+		for i, field := range h.fields {
+			results[i] = integer(hashFieldMissing)
+			if !ok {
+				continue
+			}
+			f, ok := value.hash().lookup(field)
+			switch {
+			case !ok || value.hash().expired(f, tx.now):
+			case f.expiryTime == nil:
+				results[i] = integer(hashFieldNoExpiryTime)
+			case h.absolute:
+				results[i] = integer(int(f.expiryTime.UnixMilli() / h.unit.Milliseconds()))
+			default:
+				// Round up, so that a field with any time left never has a TTL of 0.
+				remaining := f.expiryTime.Sub(tx.now)
+				results[i] = integer(int((remaining + h.unit - 1) / h.unit))
+			}
+		}
+		response = array(results...)
+	})
+	return response
+}
+
+func NewHPersistCommand(store *Store, clock Clock, key string, fields []string) *HPersistCommand {
+	return &HPersistCommand{
+		store:  store,
+		clock:  clock,
+		key:    key,
+		fields: fields,
+	}
+}
+
+type HPersistCommand struct {
+	store  *Store
+	clock  Clock
+	key    string
+	fields []string
+}
+
+func (h *HPersistCommand) Run() string {
+	var response string
+	h.store.write(h.clock.NowMonotonic(), func(tx *storeTx) {
+		hash, ok, err := hashForWrite(tx, h.key)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		results := make([]string, len(h.fields))
+		persisted := false
+		for i, field := range h.fields {
+			results[i] = integer(hashFieldMissing)
+			if !ok {
+				continue
+			}
+			f, exists := hash.lookup(field)
+			switch {
+			case !exists:
+			case f.expiryTime == nil:
+				results[i] = integer(hashFieldNoExpiryTime)
+			default:
+				hash.setExpiryTime(field, nil)
+				results[i] = integer(hashFieldChanged)
+				persisted = true
+			}
+		}
+		if persisted {
+			tx.notify(notifyHash, "hpersist", h.key)
+		}
+		response = array(results...)
+	})
+	return response
+}
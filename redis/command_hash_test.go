@@ -0,0 +1,615 @@
+package redis_test
+
+import (
+	"math"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/redis"
+)
+
+func TestHashCommands(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{}
+
+	response := redis.NewHSetCommand(store, clock, defaultEncodingRedisConfig, "hash", []redis.FieldValue{
+		{Field: "name", Value: "link"},
+		{Field: "game", Value: "zelda"},
+	}).Run()
+	if response != ":2\r\n" {
+		t.Errorf(`HSET expected to return ":2\r\n" but was %#v`, response)
+	}
+	response = redis.NewHSetCommand(store, clock, defaultEncodingRedisConfig, "hash", []redis.FieldValue{
+		{Field: "game", Value: "botw"},
+		{Field: "age", Value: "17"},
+	}, redis.HSetReplyOK()).Run()
+	if response != "+OK\r\n" {
+		t.Errorf(`HMSET expected to return "+OK\r\n" but was %#v`, response)
+	}
+
+	tests := []struct {
+		name     string
+		command  redis.Command
+		response string
+	}{
+		{name: "HGET", command: redis.NewHGetCommand(store, clock, "hash", "game"), response: "$4\r\nbotw\r\n"},
+		{name: "HGET absent field", command: redis.NewHGetCommand(store, clock, "hash", "nope"), response: redisNullBulkString},
+		{name: "HGET absent key", command: redis.NewHGetCommand(store, clock, "nope", "game"), response: redisNullBulkString},
+		{
+			name:     "HMGET",
+			command:  redis.NewHMGetCommand(store, clock, "hash", []string{"name", "nope", "age"}),
+			response: "*3\r\n$4\r\nlink\r\n$-1\r\n$2\r\n17\r\n",
+		},
+		{name: "HLEN", command: redis.NewHLenCommand(store, clock, "hash"), response: ":3\r\n"},
+		{name: "HSTRLEN", command: redis.NewHStrLenCommand(store, clock, "hash", "name"), response: ":4\r\n"},
+		{name: "HEXISTS", command: redis.NewHExistsCommand(store, clock, "hash", "age"), response: ":1\r\n"},
+		{name: "HEXISTS absent", command: redis.NewHExistsCommand(store, clock, "hash", "nope"), response: ":0\r\n"},
+		{
+			name:     "HKEYS",
+			command:  redis.NewHKeysCommand(store, clock, "hash"),
+			response: "*3\r\n$4\r\nname\r\n$4\r\ngame\r\n$3\r\nage\r\n",
+		},
+		{
+			name:     "HVALS",
+			command:  redis.NewHValsCommand(store, clock, "hash"),
+			response: "*3\r\n$4\r\nlink\r\n$4\r\nbotw\r\n$2\r\n17\r\n",
+		},
+		{
+			name:     "HGETALL",
+			command:  redis.NewHGetAllCommand(store, clock, "hash"),
+			response: "*6\r\n$4\r\nname\r\n$4\r\nlink\r\n$4\r\ngame\r\n$4\r\nbotw\r\n$3\r\nage\r\n$2\r\n17\r\n",
+		},
+		{name: "HGETALL absent", command: redis.NewHGetAllCommand(store, clock, "nope"), response: "*0\r\n"},
+		{
+			name:     "HSETNX existing",
+			command:  redis.NewHSetNXCommand(store, clock, defaultEncodingRedisConfig, "hash", "name", "zelda"),
+			response: ":0\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if response := tt.command.Run(); response != tt.response {
+				t.Errorf(`command expected to return %#v but was %#v`, tt.response, response)
+			}
+		})
+	}
+}
+
+func TestHDelCommand(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{}
+	parser := redis.NewParser(defaultEncodingRedisConfig, store, clock)
+	keysWith(t, parser, []string{"HSET", "hash", "a", "1", "b", "2"})
+
+	if response := redis.NewHDelCommand(store, clock, "hash", []string{"a", "c"}).Run(); response != ":1\r\n" {
+		t.Errorf(`command expected to return ":1\r\n" but was %#v`, response)
+	}
+	if response := redis.NewHDelCommand(store, clock, "hash", []string{"b"}).Run(); response != ":1\r\n" {
+		t.Errorf(`command expected to return ":1\r\n" but was %#v`, response)
+	}
+	if _, ok := store.Get("hash"); ok {
+		t.Errorf(`store expected not to contain "hash" once its last field was deleted`)
+	}
+}
+
+func TestHashCommands_WrongType(t *testing.T) {
+	t.Parallel()
+
+	const wrongType = "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n"
+	store := redis.NewStore()
+	store.Set("string", "zelda")
+	clock := FakeClock{}
+	parser := redis.NewParser(defaultEncodingRedisConfig, store, clock)
+	keysWith(t, parser, []string{"HSET", "hash", "a", "1"})
+
+	tests := []struct {
+		name    string
+		command redis.Command
+	}{
+		{
+			name: "HSET string",
+			command: redis.NewHSetCommand(
+				store, clock, defaultEncodingRedisConfig, "string", []redis.FieldValue{{Field: "a", Value: "1"}},
+			),
+		},
+		{name: "HGET string", command: redis.NewHGetCommand(store, clock, "string", "a")},
+		{name: "HGETALL string", command: redis.NewHGetAllCommand(store, clock, "string")},
+		{name: "HINCRBY string", command: redis.NewHIncrByCommand(store, clock, defaultEncodingRedisConfig, "string", "a", 1)},
+		{name: "HSCAN string", command: redis.NewHScanCommand(store, clock, "string", 0)},
+		{name: "GET hash", command: redis.NewGetCommand(store, clock, "hash")},
+		{name: "LPUSH hash", command: redis.NewPushCommand(store, clock, "hash", redis.ListEndLeft, []string{"a"})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if response := tt.command.Run(); response != wrongType {
+				t.Errorf(`command expected to return %#v but was %#v`, wrongType, response)
+			}
+		})
+	}
+}
+
+func TestHSetCommand_Encoding(t *testing.T) {
+	t.Parallel()
+
+	config := &redis.Config{
+		Encoding: redis.EncodingConfig{HashMaxListpackEntries: 2, HashMaxListpackValue: 5},
+	}
+	tests := []struct {
+		name        string
+		fieldValues []redis.FieldValue
+		encoding    string
+	}{
+		{
+			name:        "small",
+			fieldValues: []redis.FieldValue{{Field: "a", Value: "1"}, {Field: "b", Value: "2"}},
+			encoding:    "listpack",
+		},
+		{
+			name: "too many fields",
+			fieldValues: []redis.FieldValue{
+				{Field: "a", Value: "1"}, {Field: "b", Value: "2"}, {Field: "c", Value: "3"},
+			},
+			encoding: "hashtable",
+		},
+		{
+			name:        "value too long",
+			fieldValues: []redis.FieldValue{{Field: "a", Value: "zelda!"}},
+			encoding:    "hashtable",
+		},
+		{
+			name:        "field too long",
+			fieldValues: []redis.FieldValue{{Field: "link's", Value: "1"}},
+			encoding:    "hashtable",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := redis.NewStore()
+			clock := FakeClock{}
+
+			redis.NewHSetCommand(store, clock, config, "hash", tt.fieldValues).Run()
+
+			response := redis.NewObjectEncodingCommand(store, clock, "hash").Run()
+			if want := "$" + strconv.Itoa(len(tt.encoding)) + "\r\n" + tt.encoding + "\r\n"; response != want {
+				t.Errorf(`OBJECT ENCODING expected to return %#v but was %#v`, want, response)
+			}
+			response = redis.NewHGetCommand(store, clock, "hash", tt.fieldValues[0].Field).Run()
+			if want := "$" + strconv.Itoa(len(tt.fieldValues[0].Value)) + "\r\n" + tt.fieldValues[0].Value + "\r\n"; response != want {
+				t.Errorf(`HGET expected to return %#v but was %#v`, want, response)
+			}
+		})
+	}
+
+	t.Run("never converts back", func(t *testing.T) {
+		store := redis.NewStore()
+		clock := FakeClock{}
+		redis.NewHSetCommand(store, clock, config, "hash", []redis.FieldValue{
+			{Field: "a", Value: "1"}, {Field: "b", Value: "2"}, {Field: "c", Value: "3"},
+		}).Run()
+
+		redis.NewHDelCommand(store, clock, "hash", []string{"a", "b"}).Run()
+
+		if response := redis.NewObjectEncodingCommand(store, clock, "hash").Run(); response != "$9\r\nhashtable\r\n" {
+			t.Errorf(`OBJECT ENCODING expected to return "$9\r\nhashtable\r\n" but was %#v`, response)
+		}
+	})
+}
+
+func TestHIncrByCommand(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{}
+	parser := redis.NewParser(defaultEncodingRedisConfig, store, clock)
+	keysWith(t, parser,
+		[]string{"HSET", "hash", "count", "5", "name", "link", "max", "9223372036854775807"})
+
+	tests := []struct {
+		name      string
+		field     string
+		increment int
+		response  string
+	}{
+		{name: "existing", field: "count", increment: 10, response: ":15\r\n"},
+		{name: "negative", field: "count", increment: -20, response: ":-5\r\n"},
+		{name: "new field", field: "new", increment: 3, response: ":3\r\n"},
+		{name: "not integer", field: "name", increment: 1, response: "-ERR hash value is not an integer\r\n"},
+		{name: "overflow", field: "max", increment: 1, response: "-ERR increment or decrement would overflow\r\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := redis.NewHIncrByCommand(
+				store, clock, defaultEncodingRedisConfig, "hash", tt.field, tt.increment,
+			).Run()
+			if response != tt.response {
+				t.Errorf(`command expected to return %#v but was %#v`, tt.response, response)
+			}
+		})
+	}
+}
+
+func TestHIncrByFloatCommand(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{}
+	parser := redis.NewParser(defaultEncodingRedisConfig, store, clock)
+	keysWith(t, parser, []string{"HSET", "hash", "price", "10.50", "name", "link", "exp", "5.0e3"})
+
+	tests := []struct {
+		name      string
+		key       string
+		field     string
+		increment float64
+		response  string
+	}{
+		{name: "existing", key: "hash", field: "price", increment: 0.1, response: "$4\r\n10.6\r\n"},
+		{name: "exponent", key: "hash", field: "exp", increment: 200, response: "$4\r\n5200\r\n"},
+		{name: "new field", key: "hash", field: "new", increment: -1.5, response: "$4\r\n-1.5\r\n"},
+		{name: "not float", key: "hash", field: "name", increment: 1, response: "-ERR hash value is not a float\r\n"},
+		{
+			name:      "infinity",
+			key:       "other",
+			field:     "a",
+			increment: math.Inf(1),
+			response:  "-ERR increment would produce NaN or Infinity\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := redis.NewHIncrByFloatCommand(
+				store, clock, defaultEncodingRedisConfig, tt.key, tt.field, tt.increment,
+			).Run()
+			if response != tt.response {
+				t.Errorf(`command expected to return %#v but was %#v`, tt.response, response)
+			}
+		})
+	}
+
+	if _, ok := store.Get("other"); ok {
+		t.Errorf(`store expected not to contain "other" after a failed HINCRBYFLOAT`)
+	}
+}
+
+func TestHRandFieldCommand(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{}
+	parser := redis.NewParser(defaultEncodingRedisConfig, store, clock)
+	keysWith(t, parser, []string{"HSET", "hash", "a", "1", "b", "2", "c", "3"})
+
+	response := redis.NewHRandFieldCommand(store, clock, "hash").Run()
+	if response != "$1\r\na\r\n" && response != "$1\r\nb\r\n" && response != "$1\r\nc\r\n" {
+		t.Errorf(`command expected to return a field but was %#v`, response)
+	}
+
+	response = redis.NewHRandFieldCommand(store, clock, "hash", redis.HRandFieldCount(5)).Run()
+	if fields := bulkStrings(t, response); !equalSorted(fields, []string{"a", "b", "c"}) {
+		t.Errorf(`command expected to return every field once but was %#v`, response)
+	}
+
+	response = redis.NewHRandFieldCommand(store, clock, "hash", redis.HRandFieldCount(-5)).Run()
+	if fields := bulkStrings(t, response); len(fields) != 5 {
+		t.Errorf(`command expected to return 5 fields but was %#v`, response)
+	}
+
+	response = redis.NewHRandFieldCommand(
+		store, clock, "hash", redis.HRandFieldCount(1), redis.HRandFieldWithValues(),
+	).Run()
+	if response != "*2\r\n$1\r\na\r\n$1\r\n1\r\n" &&
+		response != "*2\r\n$1\r\nb\r\n$1\r\n2\r\n" &&
+		response != "*2\r\n$1\r\nc\r\n$1\r\n3\r\n" {
+		t.Errorf(`command expected to return a field and its value but was %#v`, response)
+	}
+
+	if response := redis.NewHRandFieldCommand(store, clock, "nope").Run(); response != redisNullBulkString {
+		t.Errorf(`command expected to return %#v but was %#v`, redisNullBulkString, response)
+	}
+	if response := redis.NewHRandFieldCommand(store, clock, "nope", redis.HRandFieldCount(2)).Run(); response != "*0\r\n" {
+		t.Errorf(`command expected to return "*0\r\n" but was %#v`, response)
+	}
+}
+
+func TestHScanCommand_Listpack(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{}
+	parser := redis.NewParser(defaultEncodingRedisConfig, store, clock)
+	keysWith(t, parser, []string{"HSET", "hash", "name", "link", "game", "zelda", "age", "17"})
+
+	tests := []struct {
+		name     string
+		options  []func(*redis.HScanCommand)
+		response string
+	}{
+		{
+			name:     "no options",
+			options:  []func(*redis.HScanCommand){redis.HScanCount(1)},
+			response: "*2\r\n$1\r\n0\r\n*6\r\n$4\r\nname\r\n$4\r\nlink\r\n$4\r\ngame\r\n$5\r\nzelda\r\n$3\r\nage\r\n$2\r\n17\r\n",
+		},
+		{
+			name:     "MATCH",
+			options:  []func(*redis.HScanCommand){redis.HScanMatch("*am*")},
+			response: "*2\r\n$1\r\n0\r\n*4\r\n$4\r\nname\r\n$4\r\nlink\r\n$4\r\ngame\r\n$5\r\nzelda\r\n",
+		},
+		{
+			name:     "NOVALUES",
+			options:  []func(*redis.HScanCommand){redis.HScanNoValues()},
+			response: "*2\r\n$1\r\n0\r\n*3\r\n$4\r\nname\r\n$4\r\ngame\r\n$3\r\nage\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if response := redis.NewHScanCommand(store, clock, "hash", 0, tt.options...).Run(); response != tt.response {
+				t.Errorf(`command expected to return %#v but was %#v`, tt.response, response)
+			}
+		})
+	}
+}
+
+func TestHScanCommand_Hashtable(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{}
+	parser := redis.NewParser(defaultEncodingRedisConfig, store, clock)
+	var fieldValues []string
+	for i := 0; i < 200; i++ {
+		fieldValues = append(fieldValues, "field:"+strconv.Itoa(i), strconv.Itoa(i))
+	}
+	keysWith(t, parser, append([]string{"HSET", "hash"}, fieldValues...))
+
+	seen := make(map[string]int)
+	var cursor uint64
+	for calls := 0; ; calls++ {
+		if calls > 200 {
+			t.Fatalf("HSCAN expected to finish")
+		}
+		response := redis.NewHScanCommand(
+			store, clock, "hash", cursor, redis.HScanCount(7), redis.HScanNoValues(),
+		).Run()
+		elements := bulkStrings(t, response)
+		for _, field := range elements[1:] {
+			seen[field]++
+		}
+
+		next := elements[0]
+		if next == "0" {
+			break
+		}
+		var err error
+		cursor, err = strconv.ParseUint(next, 10, 64)
+		if err != nil {
+			t.Fatalf("HSCAN returned invalid cursor %#v", next)
+		}
+		// Fields that come and go during the scan mustn't upset it.
+		redis.NewHDelCommand(store, clock, "hash", []string{"field:" + strconv.Itoa(calls+1000)}).Run()
+		keysWith(t, parser, []string{"HSET", "hash", "field:" + strconv.Itoa(calls+1000), "new"})
+	}
+
+	for i := 0; i < 200; i++ {
+		if field := "field:" + strconv.Itoa(i); seen[field] != 1 {
+			t.Errorf("HSCAN expected to return %#v once but returned it %d times", field, seen[field])
+		}
+	}
+}
+
+func TestHScanCommand_Match(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		pattern string
+		field   string
+		want    bool
+	}{
+		{pattern: "*", field: "", want: true},
+		{pattern: "h?llo", field: "hello", want: true},
+		{pattern: "h?llo", field: "hllo", want: false},
+		{pattern: "h*llo", field: "heeeello", want: true},
+		{pattern: "h[ae]llo", field: "hallo", want: true},
+		{pattern: "h[ae]llo", field: "hillo", want: false},
+		{pattern: "h[^e]llo", field: "hallo", want: true},
+		{pattern: "h[^e]llo", field: "hello", want: false},
+		{pattern: "h[a-b]llo", field: "hbllo", want: true},
+		{pattern: "h[b-a]llo", field: "hallo", want: true},
+		{pattern: "h[a-b]llo", field: "hcllo", want: false},
+		{pattern: `h\*llo`, field: "h*llo", want: true},
+		{pattern: `h\*llo`, field: "hello", want: false},
+		{pattern: "h[a", field: "ha", want: true},
+		{pattern: "**o", field: "hello", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.field, func(t *testing.T) {
+			store := redis.NewStore()
+			clock := FakeClock{}
+			parser := redis.NewParser(defaultEncodingRedisConfig, store, clock)
+			keysWith(t, parser, []string{"HSET", "hash", tt.field, "1"})
+
+			response := redis.NewHScanCommand(
+				store, clock, "hash", 0, redis.HScanMatch(tt.pattern), redis.HScanNoValues(),
+			).Run()
+
+			if got := response != "*2\r\n$1\r\n0\r\n*0\r\n"; got != tt.want {
+				t.Errorf(`MATCH %#v expected to match %#v: %v, but response was %#v`, tt.pattern, tt.field, tt.want, response)
+			}
+		})
+	}
+}
+
+func TestHashFieldExpiry(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{CurrentTime: time.UnixMilli(1_000_000)}
+	parser := redis.NewParser(defaultEncodingRedisConfig, store, clock)
+	keysWith(t, parser, []string{"HSET", "hash", "a", "1", "b", "2", "c", "3"})
+
+	response := redis.NewHExpireCommand(
+		store, clock, "hash", clock.CurrentTime.Add(10*time.Second), redis.ExpireAlways, []string{"a", "nope"},
+	).Run()
+	if response != "*2\r\n:1\r\n:-2\r\n" {
+		t.Errorf(`HEXPIRE expected to return "*2\r\n:1\r\n:-2\r\n" but was %#v`, response)
+	}
+	if response := redis.NewObjectEncodingCommand(store, clock, "hash").Run(); response != "$10\r\nlistpackex\r\n" {
+		t.Errorf(`OBJECT ENCODING expected to return "$10\r\nlistpackex\r\n" but was %#v`, response)
+	}
+
+	tests := []struct {
+		name     string
+		command  redis.Command
+		response string
+	}{
+		{
+			name:     "HTTL",
+			command:  redis.NewHTTLCommand(store, clock, "hash", []string{"a", "b", "nope"}, time.Second, false),
+			response: "*3\r\n:10\r\n:-1\r\n:-2\r\n",
+		},
+		{
+			name:     "HPTTL",
+			command:  redis.NewHTTLCommand(store, clock, "hash", []string{"a"}, time.Millisecond, false),
+			response: "*1\r\n:10000\r\n",
+		},
+		{
+			name:     "HEXPIRETIME",
+			command:  redis.NewHTTLCommand(store, clock, "hash", []string{"a"}, time.Second, true),
+			response: "*1\r\n:1010\r\n",
+		},
+		{
+			name:     "HPEXPIRETIME",
+			command:  redis.NewHTTLCommand(store, clock, "hash", []string{"a"}, time.Millisecond, true),
+			response: "*1\r\n:1010000\r\n",
+		},
+		{
+			name:     "HTTL absent key",
+			command:  redis.NewHTTLCommand(store, clock, "nope", []string{"a"}, time.Second, false),
+			response: "*1\r\n:-2\r\n",
+		},
+		{
+			name: "HEXPIRE NX",
+			command: redis.NewHExpireCommand(
+				store, clock, "hash", clock.CurrentTime.Add(time.Second), redis.ExpireNX, []string{"a", "b"},
+			),
+			response: "*2\r\n:0\r\n:1\r\n",
+		},
+		{
+			name: "HEXPIRE XX",
+			command: redis.NewHExpireCommand(
+				store, clock, "hash", clock.CurrentTime.Add(time.Minute), redis.ExpireXX, []string{"a", "c"},
+			),
+			response: "*2\r\n:1\r\n:0\r\n",
+		},
+		{
+			name: "HEXPIRE GT",
+			command: redis.NewHExpireCommand(
+				store, clock, "hash", clock.CurrentTime.Add(time.Second), redis.ExpireGT, []string{"a", "c"},
+			),
+			response: "*2\r\n:0\r\n:0\r\n",
+		},
+		{
+			name: "HEXPIRE LT",
+			command: redis.NewHExpireCommand(
+				store, clock, "hash", clock.CurrentTime.Add(time.Second), redis.ExpireLT, []string{"a", "c"},
+			),
+			response: "*2\r\n:1\r\n:1\r\n",
+		},
+		{
+			name:     "HPERSIST",
+			command:  redis.NewHPersistCommand(store, clock, "hash", []string{"c", "c", "nope"}),
+			response: "*3\r\n:1\r\n:-1\r\n:-2\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if response := tt.command.Run(); response != tt.response {
+				t.Errorf(`command expected to return %#v but was %#v`, tt.response, response)
+			}
+		})
+	}
+}
+
+func TestHashFieldExpiry_FieldsExpire(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	now := time.UnixMilli(1_000_000)
+	clock := FakeClock{CurrentTime: now}
+	parser := redis.NewParser(defaultEncodingRedisConfig, store, clock)
+	later := FakeClock{CurrentTime: now.Add(2 * time.Second)}
+	keysWith(t, parser, []string{"HSET", "hash", "a", "1", "b", "2", "c", "3"})
+	redis.NewHExpireCommand(store, clock, "hash", now.Add(time.Second), redis.ExpireAlways, []string{"a", "b"}).Run()
+	// HSET removes the expiry time of the fields it sets.
+	keysWith(t, parser, []string{"HSET", "hash", "b", "two"})
+
+	if response := redis.NewHGetCommand(store, later, "hash", "a").Run(); response != redisNullBulkString {
+		t.Errorf(`HGET expected to return %#v but was %#v`, redisNullBulkString, response)
+	}
+	if response := redis.NewHLenCommand(store, later, "hash").Run(); response != ":2\r\n" {
+		t.Errorf(`HLEN expected to return ":2\r\n" but was %#v`, response)
+	}
+	if response := redis.NewHKeysCommand(store, later, "hash").Run(); response != "*2\r\n$1\r\nb\r\n$1\r\nc\r\n" {
+		t.Errorf(`HKEYS expected to return "*2\r\n$1\r\nb\r\n$1\r\nc\r\n" but was %#v`, response)
+	}
+
+	redis.NewHExpireCommand(store, later, "hash", later.CurrentTime.Add(time.Second), redis.ExpireAlways, []string{"b", "c"}).Run()
+	muchLater := FakeClock{CurrentTime: now.Add(time.Minute)}
+	if response := redis.NewHDelCommand(store, muchLater, "hash", []string{"a"}).Run(); response != ":0\r\n" {
+		t.Errorf(`HDEL expected to return ":0\r\n" but was %#v`, response)
+	}
+	if _, ok := store.Get("hash"); ok {
+		t.Errorf(`store expected not to contain "hash" once all its fields expired`)
+	}
+}
+
+func TestHashFieldExpiry_IncrementKeepsExpiryTime(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{CurrentTime: time.UnixMilli(1_000_000)}
+	parser := redis.NewParser(defaultEncodingRedisConfig, store, clock)
+	keysWith(t, parser, []string{"HSET", "hash", "count", "1"})
+	redis.NewHExpireCommand(
+		store, clock, "hash", clock.CurrentTime.Add(time.Minute), redis.ExpireAlways, []string{"count"},
+	).Run()
+
+	redis.NewHIncrByCommand(store, clock, defaultEncodingRedisConfig, "hash", "count", 1).Run()
+
+	response := redis.NewHTTLCommand(store, clock, "hash", []string{"count"}, time.Second, false).Run()
+	if response != "*1\r\n:60\r\n" {
+		t.Errorf(`HTTL expected to return "*1\r\n:60\r\n" but was %#v`, response)
+	}
+}
+
+func TestHExpireCommand_PastTimeDeletes(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{CurrentTime: time.UnixMilli(1_000_000)}
+	parser := redis.NewParser(defaultEncodingRedisConfig, store, clock)
+	keysWith(t, parser, []string{"HSET", "hash", "a", "1"})
+
+	response := redis.NewHExpireCommand(
+		store, clock, "hash", clock.CurrentTime, redis.ExpireAlways, []string{"a"},
+	).Run()
+
+	if response != "*1\r\n:2\r\n" {
+		t.Errorf(`command expected to return "*1\r\n:2\r\n" but was %#v`, response)
+	}
+	if _, ok := store.Get("hash"); ok {
+		t.Errorf(`store expected not to contain "hash"`)
+	}
+}
@@ -0,0 +1,436 @@
+package redis
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+)
+
+// getHyperLogLog returns the HyperLogLog at key, or false if there isn't one.
+func getHyperLogLog(tx *storeTx, key string) (hyperLogLog, StoreValue, bool, error) {
+	value, ok, err := tx.getTyped(key, ValueTypeString)
+	if err != nil || !ok {
+		return nil, StoreValue{}, false, err
+	}
+	h, err := parseHyperLogLog(value.bytes())
+	if err != nil {
+		return nil, StoreValue{}, false, err
+	}
+	return h, value, true, nil
+}
+
+func NewPFAddCommand(
+	store *Store,
+	clock Clock,
+	config *Config,
+	key string,
+	elements []string,
+) *PFAddCommand {
+	return &PFAddCommand{
+		store:    store,
+		clock:    clock,
+		config:   config,
+		key:      key,
+		elements: elements,
+	}
+}
+
+type PFAddCommand struct {
+	store    *Store
+	clock    Clock
+	config   *Config
+	key      string
+	elements []string
+}
+
+func (p *PFAddCommand) Run() string {
+	var response string
+	p.store.write(p.clock.NowMonotonic(), func(tx *storeTx) {
+		h, value, ok, err := getHyperLogLog(tx, p.key)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		changed := !ok
+		if !ok {
+			h = newHyperLogLog()
+		}
+		for _, element := range p.elements {
+			added, err := h.add([]byte(element), p.config.Encoding.HllSparseMaxBytes)
+			if err != nil {
+				response = errorResponse(err)
+				return
+			}
+			changed = changed || added
+		}
+		if !changed {
+			response = integer(0)
+			return
+		}
+		if ok {
+			tx.set(p.key, value.withData(h))
+		} else {
+			tx.set(p.key, StoreValue{data: []byte(h)})
+		}
+		tx.notify(notifyString, "pfadd", p.key)
+		response = integer(1)
+	})
+	return response
+}
+
+func NewPFCountCommand(store *Store, clock Clock, keys []string) *PFCountCommand {
+	return &PFCountCommand{
+		store: store,
+		clock: clock,
+		keys:  keys,
+	}
+}
+
+// PFCountCommand is PFCOUNT, which estimates the cardinality of the union of HyperLogLogs by
+// merging them on the fly. With a single key, the cardinality is cached in its header.
+type PFCountCommand struct {
+	store *Store
+	clock Clock
+	keys  []string
+}
+
+func (p *PFCountCommand) Run() string {
+	var response string
+	if len(p.keys) == 1 {
+		cached := true
+		p.store.read(p.clock.NowMonotonic(), func(tx *storeTx) {
+			h, _, ok, err := getHyperLogLog(tx, p.keys[0])
+			if err != nil {
+				response = errorResponse(err)
+				return
+			}
+			if !ok {
+				response = integer(0)
+				return
+			}
+			var count uint64
+			count, cached, err = h.estimate()
+			if err != nil {
+				response = errorResponse(err)
+				return
+			}
+			response = integer(int(count))
+		})
+		if !cached && !p.store.noWrites {
+			// Like Redis, caching the cardinality changes the key, since GET returns the header
+			// it's cached in, though there's no keyspace event. The HyperLogLog may have changed
+			// since it was read, so it's only cached if it's still stale. Read-only scripts
+			// leave it stale for the next PFCOUNT.
+			p.store.write(p.clock.NowMonotonic(), func(tx *storeTx) {
+				h, _, ok, err := getHyperLogLog(tx, p.keys[0])
+				if err != nil || !ok {
+					return
+				}
+				if count, cached, err := h.estimate(); err == nil && !cached {
+					h.cache(count)
+					tx.modified(p.keys[0])
+				}
+			})
+		}
+		return response
+	}
+
+	p.store.read(p.clock.NowMonotonic(), func(tx *storeTx) {
+		var registers [hllRegisters]uint8
+		for _, key := range p.keys {
+			h, _, ok, err := getHyperLogLog(tx, key)
+			if err != nil {
+				response = errorResponse(err)
+				return
+			}
+			if !ok {
+				continue
+			}
+			if err := h.mergeInto(&registers); err != nil {
+				response = errorResponse(err)
+				return
+			}
+		}
+		response = integer(int(hllCount(&registers)))
+	})
+	return response
+}
+
+func NewPFMergeCommand(
+	store *Store,
+	clock Clock,
+	config *Config,
+	destination string,
+	sources []string,
+) *PFMergeCommand {
+	return &PFMergeCommand{
+		store:       store,
+		clock:       clock,
+		config:      config,
+		destination: destination,
+		sources:     sources,
+	}
+}
+
+type PFMergeCommand struct {
+	store       *Store
+	clock       Clock
+	config      *Config
+	destination string
+	sources     []string
+}
+
+func (p *PFMergeCommand) Run() string {
+	response := simpleString("OK")
+	p.store.write(p.clock.NowMonotonic(), func(tx *storeTx) {
+		// The destination is merged too, if it exists.
+		var registers [hllRegisters]uint8
+		dense := false
+		for _, key := range append([]string{p.destination}, p.sources...) {
+			h, _, ok, err := getHyperLogLog(tx, key)
+			if err != nil {
+				response = errorResponse(err)
+				return
+			}
+			if !ok {
+				continue
+			}
+			dense = dense || h.encoding() == hllEncodingDense
+			if err := h.mergeInto(&registers); err != nil {
+				response = errorResponse(err)
+				return
+			}
+		}
+
+		h, value, ok, _ := getHyperLogLog(tx, p.destination)
+		if !ok {
+			h = newHyperLogLog()
+		}
+		// Start with the dense representation straight away if any of the HyperLogLogs is dense.
+		if dense {
+			if err := h.toDense(); err != nil {
+				response = errorResponse(err)
+				return
+			}
+		}
+		for i, count := range registers {
+			if count == 0 {
+				continue
+			}
+			if _, err := h.set(i, int(count), p.config.Encoding.HllSparseMaxBytes); err != nil {
+				response = errorResponse(err)
+				return
+			}
+		}
+		h.invalidateCache()
+		if ok {
+			tx.set(p.destination, value.withData(h))
+		} else {
+			tx.set(p.destination, StoreValue{data: []byte(h)})
+		}
+		tx.notify(notifyString, "pfadd", p.destination)
+	})
+	return response
+}
+
+// PFDebugSubcommand is a subcommand of PFDEBUG, which inspects the representation of a
+// HyperLogLog.
+type PFDebugSubcommand int
+
+const (
+	// PFDebugGetReg returns the registers, converting the HyperLogLog to dense first.
+	PFDebugGetReg PFDebugSubcommand = iota + 1
+	// PFDebugDecode returns the opcodes of a sparse HyperLogLog in a human readable form.
+	PFDebugDecode
+	// PFDebugEncoding returns "sparse" or "dense".
+	PFDebugEncoding
+	// PFDebugToDense converts the HyperLogLog to dense, returning whether it was sparse.
+	PFDebugToDense
+)
+
+func NewPFDebugCommand(
+	store *Store,
+	clock Clock,
+	subcommand PFDebugSubcommand,
+	key string,
+) *PFDebugCommand {
+	return &PFDebugCommand{
+		store:      store,
+		clock:      clock,
+		subcommand: subcommand,
+		key:        key,
+	}
+}
+
+type PFDebugCommand struct {
+	store      *Store
+	clock      Clock
+	subcommand PFDebugSubcommand
+	key        string
+}
+
+func (p *PFDebugCommand) Run() string {
+	var response string
+	p.store.write(p.clock.NowMonotonic(), func(tx *storeTx) {
+		h, value, ok, err := getHyperLogLog(tx, p.key)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		if !ok {
+			response = errorResponse(CommandError("ERR The specified key does not exist"))
+			return
+		}
+
+		switch p.subcommand {
+		case PFDebugGetReg, PFDebugToDense:
+			wasSparse := h.encoding() == hllEncodingSparse
+			if err := h.toDense(); err != nil {
+				response = errorResponse(err)
+				return
+			}
+			tx.set(p.key, value.withData(h))
+			if p.subcommand == PFDebugToDense {
+				response = integer(0)
+				if wasSparse {
+					response = integer(1)
+				}
+				return
+			}
+			elements := make([]string, hllRegisters)
+			for i := range elements {
+				elements[i] = integer(hllDenseGet(h[hllHeaderSize:], i))
+			}
+			response = array(elements...)
+		case PFDebugDecode:
+			if h.encoding() != hllEncodingSparse {
+				response = errorResponse(CommandError("ERR HLL encoding is not sparse"))
+				return
+			}
+			response = bulkString(h.decode())
+		case PFDebugEncoding:
+			if h.encoding() == hllEncodingDense {
+				response = simpleString("dense")
+			} else {
+				response = simpleString("sparse")
+			}
+		default:
+			panic(fmt.Sprintf("unknown redis.PFDebugSubcommand: %d", p.subcommand))
+		}
+	})
+	return response
+}
+
+// decode returns the opcodes of a sparse HyperLogLog like "Z:16000 v:3,1 z:5", without checking
+// that they cover all the registers.
+func (h hyperLogLog) decode() string {
+	var ops []string
+	for p := hllHeaderSize; p < len(h); p++ {
+		switch b := h[p]; {
+		case hllSparseIsZero(b):
+			ops = append(ops, fmt.Sprintf("z:%d", hllSparseZeroLen(b)))
+		case hllSparseIsXZero(b) && p+1 < len(h):
+			ops = append(ops, fmt.Sprintf("Z:%d", hllSparseXZeroLen(b, h[p+1])))
+			p++
+		case hllSparseIsXZero(b):
+			return strings.Join(ops, " ")
+		default:
+			ops = append(ops, fmt.Sprintf("v:%d,%d", hllSparseValValue(b), hllSparseValLen(b)))
+		}
+	}
+	return strings.Join(ops, " ")
+}
+
+func NewPFSelfTestCommand(config *Config) *PFSelfTestCommand {
+	return &PFSelfTestCommand{config: config}
+}
+
+// PFSelfTestCommand is PFSELFTEST, which checks that registers are packed correctly and that
+// the sparse and dense representations agree on estimates that are within the expected error.
+type PFSelfTestCommand struct {
+	config *Config
+}
+
+func (p *PFSelfTestCommand) Run() string {
+	if err := p.testRegisters(); err != nil {
+		return errorResponse(err)
+	}
+	if err := p.testApproximation(); err != nil {
+		return errorResponse(err)
+	}
+	return simpleString("OK")
+}
+
+func (p *PFSelfTestCommand) testRegisters() error {
+	registers := make([]byte, hllDenseSize-hllHeaderSize)
+	var want [hllRegisters]int
+	for round := 0; round < 100; round++ {
+		for i := range want {
+			want[i] = rand.Intn(hllRegisterMax + 1)
+			hllDenseSet(registers, i, want[i])
+		}
+		for i := range want {
+			if got := hllDenseGet(registers, i); got != want[i] {
+				return CommandError(fmt.Sprintf(
+					"TESTFAILED Register error, counter %d should be %d, got %d", i, want[i], got,
+				))
+			}
+		}
+	}
+	return nil
+}
+
+func (p *PFSelfTestCommand) testApproximation() error {
+	sparseMaxBytes := p.config.Encoding.HllSparseMaxBytes
+	sparse := newHyperLogLog()
+	dense := newHyperLogLog()
+	if err := dense.toDense(); err != nil {
+		return err
+	}
+
+	relativeError := 1.04 / math.Sqrt(hllRegisters)
+	seed := rand.Uint64()
+	element := make([]byte, 8)
+	for i, checkpoint := 1, 1; i <= 1000000; i++ {
+		binary.LittleEndian.PutUint64(element, uint64(i)^seed)
+		if _, err := dense.add(element, sparseMaxBytes); err != nil {
+			return err
+		}
+		if _, err := sparse.add(element, sparseMaxBytes); err != nil {
+			return err
+		}
+		if i != checkpoint {
+			continue
+		}
+		checkpoint *= 10
+
+		// Small cardinalities must use the sparse representation.
+		if i < sparseMaxBytes/2 && sparse.encoding() != hllEncodingSparse {
+			return CommandError("TESTFAILED sparse encoding not used")
+		}
+		denseCount, err := dense.count()
+		if err != nil {
+			return err
+		}
+		sparseCount, err := sparse.count()
+		if err != nil {
+			return err
+		}
+		if denseCount != sparseCount {
+			return CommandError("TESTFAILED dense/sparse disagree")
+		}
+		maxError := int(math.Ceil(relativeError * 6 * float64(i)))
+		if i == 10 {
+			// Collisions make much bigger errors likely enough at this cardinality.
+			maxError = 1
+		}
+		if absError := i - int(denseCount); absError > maxError || -absError > maxError {
+			return CommandError(fmt.Sprintf(
+				"TESTFAILED Too big error. card:%d abserr:%d", i, absError,
+			))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,386 @@
+package redis_test
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/codecrafters-io/redis-starter-go/redis"
+)
+
+// pfadd returns the request that adds n elements to the HyperLogLog at key, which are the numbers
+// from start.
+func pfadd(key string, start, n int) []string {
+	request := []string{"PFADD", key}
+	for i := 0; i < n; i++ {
+		request = append(request, strconv.Itoa(start+i))
+	}
+	return request
+}
+
+// pfCount returns the cardinality that PFCOUNT estimates for keys.
+func pfCount(t *testing.T, store *redis.Store, clock redis.Clock, keys ...string) int {
+	t.Helper()
+
+	response := redis.NewPFCountCommand(store, clock, keys).Run()
+	count, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(response, ":"), "\r\n"))
+	if err != nil {
+		t.Fatalf("PFCOUNT expected to return an integer but was %#v", response)
+	}
+	return count
+}
+
+// withinError returns whether estimate is within 5% of count.
+func withinError(estimate, count int) bool {
+	return math.Abs(float64(estimate-count)) <= 0.05*float64(count)
+}
+
+func TestPFAddCommand(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	store.Set("string", "zelda")
+	clock := FakeClock{}
+	redis.NewPushCommand(store, clock, "list", redis.ListEndRight, []string{"a"}).Run()
+
+	tests := []struct {
+		name     string
+		key      string
+		elements []string
+		response string
+	}{
+		{
+			name:     "new key",
+			key:      "hll",
+			elements: []string{"a", "b"},
+			response: ":1\r\n",
+		},
+		{
+			name:     "existing elements",
+			key:      "hll",
+			elements: []string{"b", "a"},
+			response: ":0\r\n",
+		},
+		{
+			name:     "new element",
+			key:      "hll",
+			elements: []string{"a", "c"},
+			response: ":1\r\n",
+		},
+		{
+			name:     "new key without elements",
+			key:      "empty",
+			response: ":1\r\n",
+		},
+		{
+			name:     "existing key without elements",
+			key:      "empty",
+			response: ":0\r\n",
+		},
+		{
+			name:     "string that isn't a HyperLogLog",
+			key:      "string",
+			elements: []string{"a"},
+			response: "-WRONGTYPE Key is not a valid HyperLogLog string value.\r\n",
+		},
+		{
+			name:     "wrong type",
+			key:      "list",
+			elements: []string{"a"},
+			response: "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := redis.NewPFAddCommand(store, clock, defaultEncodingRedisConfig, tt.key, tt.elements).Run()
+			if response != tt.response {
+				t.Errorf(`command expected to return %#v but was %#v`, tt.response, response)
+			}
+		})
+	}
+}
+
+func TestPFAddCommand_Representation(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{}
+	redis.NewPFAddCommand(store, clock, defaultEncodingRedisConfig, "hll", nil).Run()
+
+	// An empty HyperLogLog is sparse with a single XZERO opcode covering all 16384 registers, and
+	// a cached cardinality of 0.
+	want := "HYLL\x01\x00\x00\x00" + "\x00\x00\x00\x00\x00\x00\x00\x00" + "\x7f\xff"
+	if value, _ := store.Get("hll"); value.Data() != want {
+		t.Errorf("HyperLogLog expected to be %q but was %q", want, value.Data())
+	}
+}
+
+func TestPFCountCommand(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	store.Set("string", "zelda")
+	clock := FakeClock{}
+	parser := redis.NewParser(defaultEncodingRedisConfig, store, clock)
+	keysWith(t, parser,
+		pfadd("a", 0, 1000),
+		pfadd("b", 500, 1000),
+	)
+
+	tests := []struct {
+		name  string
+		keys  []string
+		count int
+	}{
+		{name: "one key", keys: []string{"a"}, count: 1000},
+		{name: "union", keys: []string{"a", "b"}, count: 1500},
+		{name: "missing keys", keys: []string{"a", "missing"}, count: 1000},
+		{name: "missing key", keys: []string{"missing"}, count: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if count := pfCount(t, store, clock, tt.keys...); !withinError(count, tt.count) {
+				t.Errorf("PFCOUNT expected to return about %d but was %d", tt.count, count)
+			}
+		})
+	}
+
+	t.Run("string that isn't a HyperLogLog", func(t *testing.T) {
+		want := "-WRONGTYPE Key is not a valid HyperLogLog string value.\r\n"
+		if response := redis.NewPFCountCommand(store, clock, []string{"a", "string"}).Run(); response != want {
+			t.Errorf(`command expected to return %#v but was %#v`, want, response)
+		}
+	})
+}
+
+func TestPFCountCommand_CachesCardinality(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{}
+	parser := redis.NewParser(defaultEncodingRedisConfig, store, clock)
+	keysWith(t, parser, pfadd("hll", 0, 100))
+
+	value, _ := store.Get("hll")
+	if value.Data()[15]&0x80 == 0 {
+		t.Errorf("cached cardinality expected to be invalid after PFADD")
+	}
+	count := pfCount(t, store, clock, "hll")
+	value, _ = store.Get("hll")
+	if cached := binary.LittleEndian.Uint64([]byte(value.Data()[8:16])); cached != uint64(count) {
+		t.Errorf("cached cardinality expected to be %d but was %d", count, cached)
+	}
+}
+
+func TestPFCountCommand_CachingIsAWrite(t *testing.T) {
+	t.Parallel()
+
+	parser := redis.NewParser(zeroValueRedisConfig, redis.NewStore(), FakeClock{})
+	run := requestRunner(t, parser, parser.NewClient())
+	watcher := requestRunner(t, parser, parser.NewClient())
+	tracker := parser.NewClient()
+	runTracked := requestRunner(t, parser, tracker)
+	run("PFADD", "hll", "a", "b", "c")
+	runTracked("HELLO", "3")
+	runTracked("CLIENT", "TRACKING", "ON")
+
+	// Caching the cardinality changes the header that GET returns, like Redis.
+	watcher("WATCH", "hll")
+	runTracked("GET", "hll")
+	if want, response := ":3\r\n", run("PFCOUNT", "hll"); response != want {
+		t.Errorf("command expected to return %#v but was %#v", want, response)
+	}
+	watcher("MULTI")
+	watcher("PING")
+	if want, response := "*-1\r\n", watcher("EXEC"); response != want {
+		t.Errorf("command expected to return %#v but was %#v", want, response)
+	}
+	want := ">2\r\n" + bulkString("invalidate") + bulkStringArray("hll")
+	if pushes := tracker.TakePushes(); pushes != want {
+		t.Errorf("expected to be pushed %#v but was %#v", want, pushes)
+	}
+
+	// Once it's cached, counting doesn't change anything.
+	watcher("WATCH", "hll")
+	runTracked("GET", "hll")
+	run("PFCOUNT", "hll")
+	watcher("MULTI")
+	watcher("PING")
+	if want, response := "*1\r\n+PONG\r\n", watcher("EXEC"); response != want {
+		t.Errorf("command expected to return %#v but was %#v", want, response)
+	}
+	if pushes := tracker.TakePushes(); pushes != "" {
+		t.Errorf(`expected to be pushed "" but was %#v`, pushes)
+	}
+}
+
+func TestPFCountCommand_ReadOnlyScript(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	parser := redis.NewParser(zeroValueRedisConfig, store, FakeClock{})
+	run := requestRunner(t, parser, parser.NewClient())
+	run("PFADD", "hll", "a", "b", "c")
+	run("FUNCTION", "LOAD", "#!lua name=lib\nredis.register_function{function_name = 'count', "+
+		"callback = function(keys) return redis.call('PFCOUNT', keys[1]) end, "+
+		"flags = {'no-writes'}}")
+
+	// A function that can't write still counts, but leaves the cardinality for the next PFCOUNT
+	// to cache.
+	if want, response := ":3\r\n", run("FCALL_RO", "count", "1", "hll"); response != want {
+		t.Errorf("command expected to return %#v but was %#v", want, response)
+	}
+	if value, _ := store.Get("hll"); value.Data()[15]&0x80 == 0 {
+		t.Errorf("cached cardinality expected to be stale after FCALL_RO")
+	}
+}
+
+func TestPFCountCommand_SparseAndDenseAgree(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{}
+	parser := redis.NewParser(defaultEncodingRedisConfig, store, clock)
+	denseConfig := &redis.Config{Encoding: redis.EncodingConfig{HllSparseMaxBytes: 0}}
+	denseParser := redis.NewParser(denseConfig, store, clock)
+
+	for _, n := range []int{1, 10, 100, 1000, 10000} {
+		t.Run(fmt.Sprint(n), func(t *testing.T) {
+			sparse, dense := fmt.Sprint("sparse", n), fmt.Sprint("dense", n)
+			keysWith(t, parser, pfadd(sparse, 0, n))
+			keysWith(t, denseParser, pfadd(dense, 0, n))
+
+			sparseCount, denseCount := pfCount(t, store, clock, sparse), pfCount(t, store, clock, dense)
+			if sparseCount != denseCount {
+				t.Errorf("sparse count %d expected to equal dense count %d", sparseCount, denseCount)
+			}
+			if !withinError(denseCount, n) {
+				t.Errorf("PFCOUNT expected to return about %d but was %d", n, denseCount)
+			}
+		})
+	}
+
+	wantEncoding := map[string]string{"sparse100": "+sparse\r\n", "sparse10000": "+dense\r\n", "dense1": "+dense\r\n"}
+	for key, want := range wantEncoding {
+		response := redis.NewPFDebugCommand(store, clock, redis.PFDebugEncoding, key).Run()
+		if response != want {
+			t.Errorf(`PFDEBUG ENCODING %s expected to return %#v but was %#v`, key, want, response)
+		}
+	}
+}
+
+func TestPFMergeCommand(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		sourceConfig *redis.Config
+		encoding     string
+	}{
+		{name: "sparse sources", sourceConfig: defaultEncodingRedisConfig, encoding: "+sparse\r\n"},
+		{
+			name:         "dense source",
+			sourceConfig: &redis.Config{Encoding: redis.EncodingConfig{HllSparseMaxBytes: 0}},
+			encoding:     "+dense\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := redis.NewStore()
+			clock := FakeClock{}
+			parser := redis.NewParser(defaultEncodingRedisConfig, store, clock)
+			sourceParser := redis.NewParser(tt.sourceConfig, store, clock)
+			keysWith(t, parser, pfadd("dest", 0, 50))
+			keysWith(t, sourceParser, pfadd("a", 0, 100))
+			keysWith(t, parser, pfadd("b", 50, 100))
+			union := pfCount(t, store, clock, "dest", "a", "b")
+
+			response := redis.NewPFMergeCommand(store, clock, defaultEncodingRedisConfig, "dest", []string{"a", "b", "missing"}).Run()
+			if response != "+OK\r\n" {
+				t.Errorf(`command expected to return "+OK\r\n" but was %#v`, response)
+			}
+			if count := pfCount(t, store, clock, "dest"); count != union {
+				t.Errorf("PFCOUNT dest expected to return %d but was %d", union, count)
+			}
+			encoding := redis.NewPFDebugCommand(store, clock, redis.PFDebugEncoding, "dest").Run()
+			if encoding != tt.encoding {
+				t.Errorf(`PFDEBUG ENCODING dest expected to return %#v but was %#v`, tt.encoding, encoding)
+			}
+		})
+	}
+}
+
+func TestPFDebugCommand(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{}
+	redis.NewPFAddCommand(store, clock, defaultEncodingRedisConfig, "hll", []string{"a"}).Run()
+
+	// The one element sets a single register, splitting the XZERO opcode in two around it.
+	decoded := redis.NewPFDebugCommand(store, clock, redis.PFDebugDecode, "hll").Run()
+	var before, value, after int
+	_, err := fmt.Sscanf(bulkStrings(t, "*1\r\n"+decoded)[0], "Z:%d v:%d,1 Z:%d", &before, &value, &after)
+	if err != nil || before+1+after != 16384 {
+		t.Fatalf("PFDEBUG DECODE expected to return a single register but was %#v", decoded)
+	}
+
+	steps := []struct {
+		name     string
+		command  redis.Command
+		response string
+	}{
+		{
+			name:     "TODENSE",
+			command:  redis.NewPFDebugCommand(store, clock, redis.PFDebugToDense, "hll"),
+			response: ":1\r\n",
+		},
+		{
+			name:     "TODENSE when dense",
+			command:  redis.NewPFDebugCommand(store, clock, redis.PFDebugToDense, "hll"),
+			response: ":0\r\n",
+		},
+		{
+			name:     "ENCODING",
+			command:  redis.NewPFDebugCommand(store, clock, redis.PFDebugEncoding, "hll"),
+			response: "+dense\r\n",
+		},
+		{
+			name:     "DECODE when dense",
+			command:  redis.NewPFDebugCommand(store, clock, redis.PFDebugDecode, "hll"),
+			response: "-ERR HLL encoding is not sparse\r\n",
+		},
+		{
+			name:     "missing key",
+			command:  redis.NewPFDebugCommand(store, clock, redis.PFDebugEncoding, "missing"),
+			response: "-ERR The specified key does not exist\r\n",
+		},
+	}
+
+	for _, step := range steps {
+		if response := step.command.Run(); response != step.response {
+			t.Errorf(`%s: command expected to return %#v but was %#v`, step.name, step.response, response)
+		}
+	}
+
+	registers := redis.NewPFDebugCommand(store, clock, redis.PFDebugGetReg, "hll").Run()
+	want := "*16384\r\n" + strings.Repeat(":0\r\n", before) + ":" + strconv.Itoa(value) + "\r\n" +
+		strings.Repeat(":0\r\n", after)
+	if registers != want {
+		t.Errorf("PFDEBUG GETREG expected register %d to be %d and the rest 0", before, value)
+	}
+}
+
+func TestPFSelfTestCommand(t *testing.T) {
+	t.Parallel()
+
+	if response := redis.NewPFSelfTestCommand(defaultEncodingRedisConfig).Run(); response != "+OK\r\n" {
+		t.Errorf(`command expected to return "+OK\r\n" but was %#v`, response)
+	}
+}
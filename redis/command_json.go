@@ -0,0 +1,768 @@
+package redis
+
+import (
+	"fmt"
+	"math"
+)
+
+const (
+	errJSONNotAtRoot  CommandError = "ERR new objects must be created at the root"
+	errJSONKeyMissing CommandError = "ERR could not perform this operation on a key that doesn't exist"
+)
+
+func errJSONPathMissing(path *JSONPath) error {
+	return CommandError(fmt.Sprintf("ERR Path '%s' does not exist", path.text))
+}
+
+// errJSONWrongType returns the error for a value that isn't of the type a command expects, like
+// "an array".
+func errJSONWrongType(expected string, value any) error {
+	return CommandError(fmt.Sprintf(
+		"WRONGTYPE wrong type of path value - expected %s but found %s",
+		expected, jsonTypeName(value),
+	))
+}
+
+// getJSON returns the JSON document at key, or false if there isn't one.
+func getJSON(tx *storeTx, key string) (*jsonDocument, bool, error) {
+	value, ok, err := tx.getTyped(key, ValueTypeJSON)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	return value.json(), true, nil
+}
+
+// nodes returns the nodes that p selects in doc: every one for a JSONPath, or only the first for
+// a legacy path.
+func (p *JSONPath) nodes(doc *jsonDocument) []jsonNode {
+	nodes := p.evaluate(doc)
+	if p.legacy && len(nodes) > 1 {
+		return nodes[:1]
+	}
+	return nodes
+}
+
+// jsonPathResponse applies fn to the nodes that path selects in doc. For a JSONPath it returns
+// an array of fn's responses, with null for those that fn returns an error for. For a legacy
+// path it returns fn's response, or the error for an error or if path selects nothing.
+func jsonPathResponse(doc *jsonDocument, path *JSONPath, fn func(jsonNode) (string, error)) string {
+	nodes := path.nodes(doc)
+	if path.legacy {
+		if len(nodes) == 0 {
+			return errorResponse(errJSONPathMissing(path))
+		}
+		response, err := fn(nodes[0])
+		if err != nil {
+			return errorResponse(err)
+		}
+		return response
+	}
+	elements := make([]string, len(nodes))
+	for i, node := range nodes {
+		response, err := fn(node)
+		if err != nil {
+			response = nullBulkString
+		}
+		elements[i] = response
+	}
+	return array(elements...)
+}
+
+// parseJSONValues parses each of values as JSON.
+func parseJSONValues(values []string) ([]any, error) {
+	result := make([]any, len(values))
+	for i, value := range values {
+		var err error
+		if result[i], err = parseJSON(value); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func NewJSONSetCommand(
+	store *Store,
+	clock Clock,
+	key string,
+	path *JSONPath,
+	value string,
+	options ...func(*JSONSetCommand),
+) *JSONSetCommand {
+	result := &JSONSetCommand{
+		store: store,
+		clock: clock,
+		key:   key,
+		path:  path,
+		value: value,
+	}
+	for _, option := range options {
+		option(result)
+	}
+	return result
+}
+
+// JSONSetCommand is JSON.SET, which replaces the values that path selects with value. If path
+// selects nothing but would select a member of an object, the member is added.
+type JSONSetCommand struct {
+	store *Store
+	clock Clock
+	key   string
+	path  *JSONPath
+	value string
+	// onlyIfAbsent and onlyIfExists are NX and XX, which only set values that path doesn't
+	// select and does.
+	onlyIfAbsent bool
+	onlyIfExists bool
+}
+
+func JSONSetNX() func(*JSONSetCommand) {
+	return func(command *JSONSetCommand) {
+		command.onlyIfAbsent = true
+	}
+}
+
+func JSONSetXX() func(*JSONSetCommand) {
+	return func(command *JSONSetCommand) {
+		command.onlyIfExists = true
+	}
+}
+
+func (j *JSONSetCommand) Run() string {
+	value, err := parseJSON(j.value)
+	if err != nil {
+		return errorResponse(err)
+	}
+	response := nullBulkString
+	j.store.write(j.clock.NowMonotonic(), func(tx *storeTx) {
+		doc, ok, err := getJSON(tx, j.key)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		if !ok {
+			if len(j.path.steps) > 0 {
+				response = errorResponse(errJSONNotAtRoot)
+				return
+			}
+			if !j.onlyIfExists {
+				tx.set(j.key, StoreValue{data: &jsonDocument{root: value}})
+				tx.notify(notifyModule, "json.set", j.key)
+				response = simpleString("OK")
+			}
+			return
+		}
+
+		if nodes := j.path.nodes(doc); len(nodes) > 0 {
+			if j.onlyIfAbsent {
+				return
+			}
+			for i, node := range nodes {
+				if i > 0 {
+					value = cloneJSON(value)
+				}
+				node.set(value)
+			}
+			tx.notify(notifyModule, "json.set", j.key)
+			response = simpleString("OK")
+			return
+		}
+		if j.onlyIfExists {
+			return
+		}
+
+		// Add a member to the objects selected by the rest of the path, if the last step selects
+		// a single member.
+		last := j.path.steps[len(j.path.steps)-1]
+		if len(last.names) != 1 || last.recursive {
+			return
+		}
+		parent := &JSONPath{legacy: j.path.legacy, steps: j.path.steps[:len(j.path.steps)-1]}
+		added := false
+		for _, node := range parent.nodes(doc) {
+			if object, ok := node.value.(*jsonObject); ok {
+				if added {
+					value = cloneJSON(value)
+				}
+				object.set(last.names[0], value)
+				added = true
+			}
+		}
+		if added {
+			tx.notify(notifyModule, "json.set", j.key)
+			response = simpleString("OK")
+		}
+	})
+	return response
+}
+
+func NewJSONGetCommand(
+	store *Store,
+	clock Clock,
+	key string,
+	paths []*JSONPath,
+	format JSONFormat,
+) *JSONGetCommand {
+	return &JSONGetCommand{
+		store:  store,
+		clock:  clock,
+		key:    key,
+		paths:  paths,
+		format: format,
+	}
+}
+
+// JSONGetCommand is JSON.GET, which replies with the values that paths select, serialized in
+// format. With several paths it replies with an object of each path's values.
+type JSONGetCommand struct {
+	store  *Store
+	clock  Clock
+	key    string
+	paths  []*JSONPath
+	format JSONFormat
+}
+
+func (j *JSONGetCommand) Run() string {
+	response := nullBulkString
+	j.store.read(j.clock.NowMonotonic(), func(tx *storeTx) {
+		doc, ok, err := getJSON(tx, j.key)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		if !ok {
+			return
+		}
+
+		// Legacy paths are only treated as such if they all are.
+		legacy := true
+		for _, path := range j.paths {
+			legacy = legacy && path.legacy
+		}
+		values := make([]any, len(j.paths))
+		for i, path := range j.paths {
+			nodes := path.evaluate(doc)
+			if legacy {
+				if len(nodes) == 0 {
+					response = errorResponse(errJSONPathMissing(path))
+					return
+				}
+				values[i] = nodes[0].value
+				continue
+			}
+			array := &jsonArray{elements: make([]any, len(nodes))}
+			for k, node := range nodes {
+				array.elements[k] = node.value
+			}
+			values[i] = array
+		}
+
+		if len(j.paths) == 1 {
+			response = bulkString(j.format.format(values[0]))
+			return
+		}
+		object := newJSONObject()
+		for i, path := range j.paths {
+			object.set(path.text, values[i])
+		}
+		response = bulkString(j.format.format(object))
+	})
+	return response
+}
+
+func NewJSONMGetCommand(store *Store, clock Clock, keys []string, path *JSONPath) *JSONMGetCommand {
+	return &JSONMGetCommand{
+		store: store,
+		clock: clock,
+		keys:  keys,
+		path:  path,
+	}
+}
+
+// JSONMGetCommand is JSON.MGET, which replies with the values that path selects in each of keys,
+// or null for keys that don't hold JSON.
+type JSONMGetCommand struct {
+	store *Store
+	clock Clock
+	keys  []string
+	path  *JSONPath
+}
+
+func (j *JSONMGetCommand) Run() string {
+	var response string
+	j.store.read(j.clock.NowMonotonic(), func(tx *storeTx) {
+		elements := make([]string, len(j.keys))
+		for i, key := range j.keys {
+			elements[i] = nullBulkString
+			doc, ok, _ := getJSON(tx, key)
+			if !ok {
+				continue
+			}
+			nodes := j.path.nodes(doc)
+			if !j.path.legacy {
+				array := &jsonArray{elements: make([]any, len(nodes))}
+				for k, node := range nodes {
+					array.elements[k] = node.value
+				}
+				elements[i] = bulkString(formatJSON(array))
+			} else if len(nodes) > 0 {
+				elements[i] = bulkString(formatJSON(nodes[0].value))
+			}
+		}
+		response = array(elements...)
+	})
+	return response
+}
+
+func NewJSONDelCommand(store *Store, clock Clock, key string, path *JSONPath) *JSONDelCommand {
+	return &JSONDelCommand{
+		store: store,
+		clock: clock,
+		key:   key,
+		path:  path,
+	}
+}
+
+// JSONDelCommand is JSON.DEL, which deletes the values that path selects, or the key if path is
+// the root, and replies with the number deleted.
+type JSONDelCommand struct {
+	store *Store
+	clock Clock
+	key   string
+	path  *JSONPath
+}
+
+func (j *JSONDelCommand) Run() string {
+	response := integer(0)
+	j.store.write(j.clock.NowMonotonic(), func(tx *storeTx) {
+		doc, ok, err := getJSON(tx, j.key)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		if !ok {
+			return
+		}
+		if len(j.path.steps) == 0 {
+			tx.delete(j.key)
+			tx.notify(notifyModule, "json.del", j.key)
+			response = integer(1)
+			return
+		}
+
+		// Elements are removed from arrays all at once so that their indices stay valid.
+		deleted := 0
+		removed := make(map[*jsonArray]map[int]bool)
+		for _, node := range j.path.nodes(doc) {
+			switch parent := node.parent.(type) {
+			case *jsonObject:
+				if parent.delete(node.key) {
+					deleted++
+				}
+			case *jsonArray:
+				if removed[parent] == nil {
+					removed[parent] = make(map[int]bool)
+				}
+				if !removed[parent][node.index] {
+					removed[parent][node.index] = true
+					deleted++
+				}
+			}
+		}
+		for array, indices := range removed {
+			elements := array.elements[:0]
+			for i, element := range array.elements {
+				if !indices[i] {
+					elements = append(elements, element)
+				}
+			}
+			array.elements = elements
+		}
+		if deleted > 0 {
+			tx.notify(notifyModule, "json.del", j.key)
+		}
+		response = integer(deleted)
+	})
+	return response
+}
+
+func NewJSONTypeCommand(store *Store, clock Clock, key string, path *JSONPath) *JSONTypeCommand {
+	return &JSONTypeCommand{
+		store: store,
+		clock: clock,
+		key:   key,
+		path:  path,
+	}
+}
+
+// JSONTypeCommand is JSON.TYPE, which replies with the type of each value that path selects.
+type JSONTypeCommand struct {
+	store *Store
+	clock Clock
+	key   string
+	path  *JSONPath
+}
+
+func (j *JSONTypeCommand) Run() string {
+	response := nullBulkString
+	j.store.read(j.clock.NowMonotonic(), func(tx *storeTx) {
+		doc, ok, err := getJSON(tx, j.key)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		if !ok {
+			return
+		}
+		nodes := j.path.nodes(doc)
+		if j.path.legacy {
+			if len(nodes) > 0 {
+				response = simpleString(jsonTypeName(nodes[0].value))
+			}
+			return
+		}
+		elements := make([]string, len(nodes))
+		for i, node := range nodes {
+			elements[i] = bulkString(jsonTypeName(node.value))
+		}
+		response = array(elements...)
+	})
+	return response
+}
+
+func NewJSONNumIncrByCommand(
+	store *Store,
+	clock Clock,
+	key string,
+	path *JSONPath,
+	increment string,
+) *JSONNumIncrByCommand {
+	return &JSONNumIncrByCommand{
+		store:     store,
+		clock:     clock,
+		key:       key,
+		path:      path,
+		increment: increment,
+	}
+}
+
+// JSONNumIncrByCommand is JSON.NUMINCRBY, which adds increment to the numbers that path selects
+// and replies with the results serialized as JSON. Integers stay integers if increment is one
+// and the result is in range.
+type JSONNumIncrByCommand struct {
+	store     *Store
+	clock     Clock
+	key       string
+	path      *JSONPath
+	increment string
+}
+
+func (j *JSONNumIncrByCommand) Run() string {
+	increment, err := parseJSON(j.increment)
+	if err != nil {
+		return errorResponse(err)
+	}
+	if _, ok := jsonNumber(increment); !ok {
+		return errorResponse(errJSONWrongType("a number", increment))
+	}
+
+	var response string
+	j.store.write(j.clock.NowMonotonic(), func(tx *storeTx) {
+		doc, ok, err := getJSON(tx, j.key)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		if !ok {
+			response = errorResponse(errJSONKeyMissing)
+			return
+		}
+
+		nodes := j.path.nodes(doc)
+		if j.path.legacy && len(nodes) == 0 {
+			response = errorResponse(errJSONPathMissing(j.path))
+			return
+		}
+		results := &jsonArray{elements: make([]any, len(nodes))}
+		changed := false
+		for i, node := range nodes {
+			result, err := addJSONNumbers(node.value, increment)
+			if err != nil && j.path.legacy {
+				response = errorResponse(err)
+				return
+			}
+			if err == nil {
+				node.set(result)
+				results.elements[i] = result
+				changed = true
+			}
+		}
+		if changed {
+			tx.notify(notifyModule, "json.numincrby", j.key)
+		}
+		if j.path.legacy {
+			response = bulkString(formatJSON(results.elements[0]))
+		} else {
+			response = bulkString(formatJSON(results))
+		}
+	})
+	return response
+}
+
+// addJSONNumbers returns x + y, which is an integer if they both are and it doesn't overflow.
+func addJSONNumbers(x, y any) (any, error) {
+	a, ok := jsonNumber(x)
+	if !ok {
+		return nil, errJSONWrongType("a number", x)
+	}
+	b, _ := jsonNumber(y)
+	i, xInteger := x.(int64)
+	k, yInteger := y.(int64)
+	if sum := i + k; xInteger && yInteger && (sum > i) == (k > 0) {
+		return sum, nil
+	}
+	sum := a + b
+	if math.IsInf(sum, 0) || math.IsNaN(sum) {
+		return nil, CommandError("ERR result is not a finite number")
+	}
+	return sum, nil
+}
+
+func NewJSONArrAppendCommand(
+	store *Store,
+	clock Clock,
+	key string,
+	path *JSONPath,
+	values []string,
+) *JSONArrAppendCommand {
+	return &JSONArrAppendCommand{
+		store:  store,
+		clock:  clock,
+		key:    key,
+		path:   path,
+		values: values,
+	}
+}
+
+// JSONArrAppendCommand is JSON.ARRAPPEND, which appends values to the arrays that path selects
+// and replies with their new lengths.
+type JSONArrAppendCommand struct {
+	store  *Store
+	clock  Clock
+	key    string
+	path   *JSONPath
+	values []string
+}
+
+func (j *JSONArrAppendCommand) Run() string {
+	values, err := parseJSONValues(j.values)
+	if err != nil {
+		return errorResponse(err)
+	}
+	var response string
+	j.store.write(j.clock.NowMonotonic(), func(tx *storeTx) {
+		doc, ok, err := getJSON(tx, j.key)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		if !ok {
+			response = errorResponse(errJSONKeyMissing)
+			return
+		}
+		changed := false
+		response = jsonPathResponse(doc, j.path, func(node jsonNode) (string, error) {
+			array, ok := node.value.(*jsonArray)
+			if !ok {
+				return "", errJSONWrongType("an array", node.value)
+			}
+			for _, value := range values {
+				array.elements = append(array.elements, cloneJSON(value))
+			}
+			changed = true
+			return integer(len(array.elements)), nil
+		})
+		if changed {
+			tx.notify(notifyModule, "json.arrappend", j.key)
+		}
+	})
+	return response
+}
+
+func NewJSONArrPopCommand(
+	store *Store,
+	clock Clock,
+	key string,
+	path *JSONPath,
+	index int,
+) *JSONArrPopCommand {
+	return &JSONArrPopCommand{
+		store: store,
+		clock: clock,
+		key:   key,
+		path:  path,
+		index: index,
+	}
+}
+
+// JSONArrPopCommand is JSON.ARRPOP, which removes the element at index from the arrays that path
+// selects and replies with them serialized as JSON. Negative indices count back from the end,
+// and indices out of range are clamped to it.
+type JSONArrPopCommand struct {
+	store *Store
+	clock Clock
+	key   string
+	path  *JSONPath
+	index int
+}
+
+func (j *JSONArrPopCommand) Run() string {
+	response := nullBulkString
+	j.store.write(j.clock.NowMonotonic(), func(tx *storeTx) {
+		doc, ok, err := getJSON(tx, j.key)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		if !ok {
+			return
+		}
+		changed := false
+		response = jsonPathResponse(doc, j.path, func(node jsonNode) (string, error) {
+			array, ok := node.value.(*jsonArray)
+			if !ok {
+				return "", errJSONWrongType("an array", node.value)
+			}
+			n := len(array.elements)
+			if n == 0 {
+				return nullBulkString, nil
+			}
+			i := j.index
+			if i < 0 {
+				i += n
+			}
+			if i < 0 {
+				i = 0
+			}
+			if i >= n {
+				i = n - 1
+			}
+			element := array.elements[i]
+			array.elements = append(array.elements[:i], array.elements[i+1:]...)
+			changed = true
+			return bulkString(formatJSON(element)), nil
+		})
+		if changed {
+			tx.notify(notifyModule, "json.arrpop", j.key)
+		}
+	})
+	return response
+}
+
+func NewJSONObjKeysCommand(
+	store *Store,
+	clock Clock,
+	key string,
+	path *JSONPath,
+) *JSONObjKeysCommand {
+	return &JSONObjKeysCommand{
+		store: store,
+		clock: clock,
+		key:   key,
+		path:  path,
+	}
+}
+
+// JSONObjKeysCommand is JSON.OBJKEYS, which replies with the keys of the objects that path
+// selects.
+type JSONObjKeysCommand struct {
+	store *Store
+	clock Clock
+	key   string
+	path  *JSONPath
+}
+
+func (j *JSONObjKeysCommand) Run() string {
+	response := nullBulkString
+	j.store.read(j.clock.NowMonotonic(), func(tx *storeTx) {
+		doc, ok, err := getJSON(tx, j.key)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		if !ok {
+			return
+		}
+		response = jsonPathResponse(doc, j.path, func(node jsonNode) (string, error) {
+			object, ok := node.value.(*jsonObject)
+			if !ok {
+				return "", errJSONWrongType("an object", node.value)
+			}
+			return bulkStringArray(object.keys), nil
+		})
+	})
+	return response
+}
+
+func NewJSONStrAppendCommand(
+	store *Store,
+	clock Clock,
+	key string,
+	path *JSONPath,
+	value string,
+) *JSONStrAppendCommand {
+	return &JSONStrAppendCommand{
+		store: store,
+		clock: clock,
+		key:   key,
+		path:  path,
+		value: value,
+	}
+}
+
+// JSONStrAppendCommand is JSON.STRAPPEND, which appends value, which must be a JSON string, to
+// the strings that path selects and replies with their new lengths.
+type JSONStrAppendCommand struct {
+	store *Store
+	clock Clock
+	key   string
+	path  *JSONPath
+	value string
+}
+
+func (j *JSONStrAppendCommand) Run() string {
+	value, err := parseJSON(j.value)
+	if err != nil {
+		return errorResponse(err)
+	}
+	suffix, ok := value.(string)
+	if !ok {
+		return errorResponse(errJSONWrongType("a string", value))
+	}
+
+	var response string
+	j.store.write(j.clock.NowMonotonic(), func(tx *storeTx) {
+		doc, ok, err := getJSON(tx, j.key)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		if !ok {
+			response = errorResponse(errJSONKeyMissing)
+			return
+		}
+		changed := false
+		response = jsonPathResponse(doc, j.path, func(node jsonNode) (string, error) {
+			s, ok := node.value.(string)
+			if !ok {
+				return "", errJSONWrongType("a string", node.value)
+			}
+			node.set(s + suffix)
+			changed = true
+			return integer(len(s) + len(suffix)), nil
+		})
+		if changed {
+			tx.notify(notifyModule, "json.strappend", j.key)
+		}
+	})
+	return response
+}
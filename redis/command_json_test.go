@@ -0,0 +1,993 @@
+package redis_test
+
+import (
+	"testing"
+
+	"github.com/codecrafters-io/redis-starter-go/redis"
+)
+
+const profile = `{"name":"Ada","age":36,"score":1.5,"tags":["a","b"],` +
+	`"address":{"city":"London","zip":"N1"},` +
+	`"friends":[{"name":"Bob","age":30},{"name":"Cy","age":40}]}`
+
+// jsonPath compiles a JSONPath, failing the test if it's invalid.
+func jsonPath(t *testing.T, path string) *redis.JSONPath {
+	t.Helper()
+
+	result, err := redis.ParseJSONPath(path)
+	if err != nil {
+		t.Fatalf("failed to parse JSONPath %#v: %v", path, err)
+	}
+	return result
+}
+
+// jsonGet returns the document at key.
+func jsonGet(t *testing.T, store *redis.Store, clock redis.Clock, key string) string {
+	t.Helper()
+
+	paths := []*redis.JSONPath{jsonPath(t, ".")}
+	return redis.NewJSONGetCommand(store, clock, key, paths, redis.JSONFormat{}).Run()
+}
+
+func TestJSONSetCommand(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	store.Set("string", "zelda")
+	clock := FakeClock{}
+
+	tests := []struct {
+		name     string
+		key      string
+		path     string
+		value    string
+		options  []func(*redis.JSONSetCommand)
+		response string
+		doc      string
+	}{
+		{
+			name:     "new key",
+			key:      "user",
+			path:     "$",
+			value:    `{"name":"Ada","friends":[{"name":"Bob"},{"name":"Cy"}]}`,
+			response: "+OK\r\n",
+			doc:      `{"name":"Ada","friends":[{"name":"Bob"},{"name":"Cy"}]}`,
+		},
+		{
+			name:     "new key with XX",
+			key:      "other",
+			path:     "$",
+			value:    `1`,
+			options:  []func(*redis.JSONSetCommand){redis.JSONSetXX()},
+			response: "$-1\r\n",
+		},
+		{
+			name:     "new key not at the root",
+			key:      "other",
+			path:     "$.name",
+			value:    `"Ada"`,
+			response: "-ERR new objects must be created at the root\r\n",
+		},
+		{
+			name:     "existing member",
+			key:      "user",
+			path:     "$.name",
+			value:    `"Ada Lovelace"`,
+			response: "+OK\r\n",
+			doc:      `{"name":"Ada Lovelace","friends":[{"name":"Bob"},{"name":"Cy"}]}`,
+		},
+		{
+			name:     "legacy path",
+			key:      "user",
+			path:     "name",
+			value:    `"Ada"`,
+			response: "+OK\r\n",
+			doc:      `{"name":"Ada","friends":[{"name":"Bob"},{"name":"Cy"}]}`,
+		},
+		{
+			name:     "existing member with NX",
+			key:      "user",
+			path:     "$.name",
+			value:    `"Bob"`,
+			options:  []func(*redis.JSONSetCommand){redis.JSONSetNX()},
+			response: "$-1\r\n",
+			doc:      `{"name":"Ada","friends":[{"name":"Bob"},{"name":"Cy"}]}`,
+		},
+		{
+			name:     "new member with XX",
+			key:      "user",
+			path:     "$.age",
+			value:    `36`,
+			options:  []func(*redis.JSONSetCommand){redis.JSONSetXX()},
+			response: "$-1\r\n",
+			doc:      `{"name":"Ada","friends":[{"name":"Bob"},{"name":"Cy"}]}`,
+		},
+		{
+			name:     "new member",
+			key:      "user",
+			path:     "$.age",
+			value:    `36`,
+			options:  []func(*redis.JSONSetCommand){redis.JSONSetNX()},
+			response: "+OK\r\n",
+			doc:      `{"name":"Ada","friends":[{"name":"Bob"},{"name":"Cy"}],"age":36}`,
+		},
+		{
+			name:     "new member of several objects",
+			key:      "user",
+			path:     "$.friends[*].tags",
+			value:    `[]`,
+			response: "+OK\r\n",
+			doc: `{"name":"Ada","friends":[{"name":"Bob","tags":[]},{"name":"Cy","tags":[]}],` +
+				`"age":36}`,
+		},
+		{
+			name:     "several members",
+			key:      "user",
+			path:     "$..tags",
+			value:    `["x"]`,
+			response: "+OK\r\n",
+			doc: `{"name":"Ada","friends":[{"name":"Bob","tags":["x"]},` +
+				`{"name":"Cy","tags":["x"]}],"age":36}`,
+		},
+		{
+			name:     "missing parent",
+			key:      "user",
+			path:     "$.address.city",
+			value:    `"London"`,
+			response: "$-1\r\n",
+			doc: `{"name":"Ada","friends":[{"name":"Bob","tags":["x"]},` +
+				`{"name":"Cy","tags":["x"]}],"age":36}`,
+		},
+		{
+			name:     "root",
+			key:      "user",
+			path:     "$",
+			value:    `[1, 2.50, -0.0, 1e21, 1E-7, "é\n"]`,
+			response: "+OK\r\n",
+			doc:      `[1,2.5,-0.0,1e21,1e-7,"é\n"]`,
+		},
+		{
+			name:     "invalid JSON",
+			key:      "user",
+			path:     "$",
+			value:    `{"name":}`,
+			response: "-ERR missing value after object key\r\n",
+			doc:      `[1,2.5,-0.0,1e21,1e-7,"é\n"]`,
+		},
+		{
+			name:     "trailing characters",
+			key:      "user",
+			path:     "$",
+			value:    `1 2`,
+			response: "-ERR trailing characters\r\n",
+			doc:      `[1,2.5,-0.0,1e21,1e-7,"é\n"]`,
+		},
+		{
+			name:     "wrong type",
+			key:      "string",
+			path:     "$",
+			value:    `1`,
+			response: "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			path := jsonPath(t, test.path)
+			command := redis.NewJSONSetCommand(
+				store, clock, test.key, path, test.value, test.options...,
+			)
+			if response := command.Run(); response != test.response {
+				t.Errorf("command expected to return %#v but was %#v", test.response, response)
+			}
+			if test.doc == "" {
+				return
+			}
+			if doc := jsonGet(t, store, clock, test.key); doc != bulkString(test.doc) {
+				t.Errorf("expected document to be %#v but was %#v", bulkString(test.doc), doc)
+			}
+		})
+	}
+}
+
+func TestJSONGetCommand(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	store.Set("string", "zelda")
+	clock := FakeClock{}
+	parser := redis.NewParser(defaultEncodingRedisConfig, store, clock)
+	keysWith(t, parser, []string{"JSON.SET", "user", "$", profile})
+
+	tests := []struct {
+		name     string
+		key      string
+		paths    []string
+		format   redis.JSONFormat
+		response string
+	}{
+		{
+			name:     "root",
+			key:      "user",
+			paths:    []string{"."},
+			response: bulkString(profile),
+		},
+		{
+			name:     "member",
+			key:      "user",
+			paths:    []string{"$.name"},
+			response: bulkString(`["Ada"]`),
+		},
+		{
+			name:     "legacy member",
+			key:      "user",
+			paths:    []string{".address.city"},
+			response: bulkString(`"London"`),
+		},
+		{
+			name:     "legacy member without a dot",
+			key:      "user",
+			paths:    []string{"friends[0].name"},
+			response: bulkString(`"Bob"`),
+		},
+		{
+			name:     "bracketed member",
+			key:      "user",
+			paths:    []string{`$['address']["zip","city"]`},
+			response: bulkString(`["N1","London"]`),
+		},
+		{
+			name:     "recursive",
+			key:      "user",
+			paths:    []string{"$..name"},
+			response: bulkString(`["Ada","Bob","Cy"]`),
+		},
+		{
+			name:     "legacy recursive",
+			key:      "user",
+			paths:    []string{"..name"},
+			response: bulkString(`"Ada"`),
+		},
+		{
+			name:     "wildcard",
+			key:      "user",
+			paths:    []string{"$.friends[*].age"},
+			response: bulkString(`[30,40]`),
+		},
+		{
+			name:     "dotted wildcard",
+			key:      "user",
+			paths:    []string{"$.address.*"},
+			response: bulkString(`["London","N1"]`),
+		},
+		{
+			name:     "negative index",
+			key:      "user",
+			paths:    []string{"$.friends[-1].name"},
+			response: bulkString(`["Cy"]`),
+		},
+		{
+			name:     "indices",
+			key:      "user",
+			paths:    []string{"$.tags[1,0,5]"},
+			response: bulkString(`["b","a"]`),
+		},
+		{
+			name:     "slice",
+			key:      "user",
+			paths:    []string{"$.tags[:1]"},
+			response: bulkString(`["a"]`),
+		},
+		{
+			name:     "filter",
+			key:      "user",
+			paths:    []string{"$.friends[?(@.age > 35)].name"},
+			response: bulkString(`["Cy"]`),
+		},
+		{
+			name:     "filter with or",
+			key:      "user",
+			paths:    []string{`$.friends[?(@.name == "Bob" || @.age >= 40)].age`},
+			response: bulkString(`[30,40]`),
+		},
+		{
+			name:     "filter with and",
+			key:      "user",
+			paths:    []string{`$.friends[?(@.name != 'Bob' && !(@.age < 40))].name`},
+			response: bulkString(`["Cy"]`),
+		},
+		{
+			name:     "filter with regular expression",
+			key:      "user",
+			paths:    []string{`$.friends[?(@.name =~ "^C")]`},
+			response: bulkString(`[{"name":"Cy","age":40}]`),
+		},
+		{
+			name:     "filter with absolute path",
+			key:      "user",
+			paths:    []string{`$.friends[?(@.age < $.age)].name`},
+			response: bulkString(`["Bob"]`),
+		},
+		{
+			name:     "filter on existence",
+			key:      "user",
+			paths:    []string{`$..[?(@.zip)].city`},
+			response: bulkString(`["London"]`),
+		},
+		{
+			name:     "nothing selected",
+			key:      "user",
+			paths:    []string{"$.missing"},
+			response: bulkString(`[]`),
+		},
+		{
+			name:     "legacy path selects nothing",
+			key:      "user",
+			paths:    []string{".missing"},
+			response: "-ERR Path '.missing' does not exist\r\n",
+		},
+		{
+			name:     "several paths",
+			key:      "user",
+			paths:    []string{"$.name", "$.age", ".tags[0]"},
+			response: bulkString(`{"$.name":["Ada"],"$.age":[36],".tags[0]":["a"]}`),
+		},
+		{
+			name:     "several legacy paths",
+			key:      "user",
+			paths:    []string{".name", "age"},
+			response: bulkString(`{".name":"Ada","age":36}`),
+		},
+		{
+			name:     "formatted",
+			key:      "user",
+			paths:    []string{"$.address"},
+			format:   redis.JSONFormat{Indent: "  ", Newline: "\n", Space: " "},
+			response: bulkString("[\n  {\n    \"city\": \"London\",\n    \"zip\": \"N1\"\n  }\n]"),
+		},
+		{
+			name:     "missing key",
+			key:      "missing",
+			paths:    []string{"."},
+			response: "$-1\r\n",
+		},
+		{
+			name:     "wrong type",
+			key:      "string",
+			paths:    []string{"."},
+			response: "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			paths := make([]*redis.JSONPath, len(test.paths))
+			for i, path := range test.paths {
+				paths[i] = jsonPath(t, path)
+			}
+			command := redis.NewJSONGetCommand(store, clock, test.key, paths, test.format)
+			if response := command.Run(); response != test.response {
+				t.Errorf("command expected to return %#v but was %#v", test.response, response)
+			}
+		})
+	}
+}
+
+func TestJSONMGetCommand(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	store.Set("string", "zelda")
+	clock := FakeClock{}
+	parser := redis.NewParser(defaultEncodingRedisConfig, store, clock)
+	keysWith(t, parser,
+		[]string{"JSON.SET", "ada", "$", `{"name":"Ada"}`},
+		[]string{"JSON.SET", "bob", "$", `{"name":"Bob","age":30}`},
+	)
+
+	tests := []struct {
+		name     string
+		keys     []string
+		path     string
+		response string
+	}{
+		{
+			name: "JSONPath",
+			keys: []string{"ada", "bob", "missing", "string"},
+			path: "$.age",
+			response: "*4\r\n" + bulkString("[]") + bulkString("[30]") +
+				"$-1\r\n$-1\r\n",
+		},
+		{
+			name:     "legacy path",
+			keys:     []string{"ada", "bob"},
+			path:     ".name",
+			response: "*2\r\n" + bulkString(`"Ada"`) + bulkString(`"Bob"`),
+		},
+		{
+			name:     "legacy path selects nothing",
+			keys:     []string{"ada", "bob"},
+			path:     ".age",
+			response: "*2\r\n$-1\r\n" + bulkString("30"),
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			command := redis.NewJSONMGetCommand(store, clock, test.keys, jsonPath(t, test.path))
+			if response := command.Run(); response != test.response {
+				t.Errorf("command expected to return %#v but was %#v", test.response, response)
+			}
+		})
+	}
+}
+
+func TestJSONDelCommand(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	store.Set("string", "zelda")
+	clock := FakeClock{}
+	parser := redis.NewParser(defaultEncodingRedisConfig, store, clock)
+	keysWith(t, parser, []string{"JSON.SET", "user", "$", profile})
+
+	tests := []struct {
+		name     string
+		key      string
+		path     string
+		response string
+		doc      string
+	}{
+		{
+			name:     "elements",
+			key:      "user",
+			path:     "$.tags[0,1]",
+			response: ":2\r\n",
+			doc: `{"name":"Ada","age":36,"score":1.5,"tags":[],` +
+				`"address":{"city":"London","zip":"N1"},` +
+				`"friends":[{"name":"Bob","age":30},{"name":"Cy","age":40}]}`,
+		},
+		{
+			name:     "members",
+			key:      "user",
+			path:     "$..age",
+			response: ":3\r\n",
+			doc: `{"name":"Ada","score":1.5,"tags":[],` +
+				`"address":{"city":"London","zip":"N1"},` +
+				`"friends":[{"name":"Bob"},{"name":"Cy"}]}`,
+		},
+		{
+			name:     "legacy path",
+			key:      "user",
+			path:     "..name",
+			response: ":1\r\n",
+			doc: `{"score":1.5,"tags":[],"address":{"city":"London","zip":"N1"},` +
+				`"friends":[{"name":"Bob"},{"name":"Cy"}]}`,
+		},
+		{
+			name:     "filter",
+			key:      "user",
+			path:     `$.friends[?(@.name == "Bob")]`,
+			response: ":1\r\n",
+			doc: `{"score":1.5,"tags":[],"address":{"city":"London","zip":"N1"},` +
+				`"friends":[{"name":"Cy"}]}`,
+		},
+		{
+			name:     "nothing selected",
+			key:      "user",
+			path:     "$.missing",
+			response: ":0\r\n",
+			doc: `{"score":1.5,"tags":[],"address":{"city":"London","zip":"N1"},` +
+				`"friends":[{"name":"Cy"}]}`,
+		},
+		{
+			name:     "root",
+			key:      "user",
+			path:     "$",
+			response: ":1\r\n",
+		},
+		{
+			name:     "missing key",
+			key:      "user",
+			path:     "$",
+			response: ":0\r\n",
+		},
+		{
+			name:     "wrong type",
+			key:      "string",
+			path:     "$",
+			response: "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			command := redis.NewJSONDelCommand(store, clock, test.key, jsonPath(t, test.path))
+			if response := command.Run(); response != test.response {
+				t.Errorf("command expected to return %#v but was %#v", test.response, response)
+			}
+			doc := "$-1\r\n"
+			if test.doc != "" {
+				doc = bulkString(test.doc)
+			}
+			if got := jsonGet(t, store, clock, "user"); got != doc && test.key == "user" {
+				t.Errorf("expected document to be %#v but was %#v", doc, got)
+			}
+		})
+	}
+}
+
+func TestJSONTypeCommand(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	store.Set("string", "zelda")
+	clock := FakeClock{}
+	parser := redis.NewParser(defaultEncodingRedisConfig, store, clock)
+	keysWith(t, parser, []string{"JSON.SET", "doc", "$", `{"a":[1,2.5,"x",true,null,{}]}`})
+
+	tests := []struct {
+		name     string
+		key      string
+		path     string
+		response string
+	}{
+		{
+			name: "JSONPath",
+			key:  "doc",
+			path: "$.a[*]",
+			response: "*6\r\n$7\r\ninteger\r\n$6\r\nnumber\r\n$6\r\nstring\r\n" +
+				"$7\r\nboolean\r\n$4\r\nnull\r\n$6\r\nobject\r\n",
+		},
+		{
+			name:     "legacy path",
+			key:      "doc",
+			path:     ".a",
+			response: "+array\r\n",
+		},
+		{
+			name:     "nothing selected",
+			key:      "doc",
+			path:     "$.b",
+			response: "*0\r\n",
+		},
+		{
+			name:     "legacy path selects nothing",
+			key:      "doc",
+			path:     ".b",
+			response: "$-1\r\n",
+		},
+		{
+			name:     "missing key",
+			key:      "missing",
+			path:     ".",
+			response: "$-1\r\n",
+		},
+		{
+			name:     "wrong type",
+			key:      "string",
+			path:     ".",
+			response: "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			command := redis.NewJSONTypeCommand(store, clock, test.key, jsonPath(t, test.path))
+			if response := command.Run(); response != test.response {
+				t.Errorf("command expected to return %#v but was %#v", test.response, response)
+			}
+		})
+	}
+}
+
+func TestJSONNumIncrByCommand(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	store.Set("string", "zelda")
+	clock := FakeClock{}
+	parser := redis.NewParser(defaultEncodingRedisConfig, store, clock)
+	keysWith(t, parser,
+		[]string{"JSON.SET", "doc", "$", `{"a":1,"b":1.5,"c":"x","d":{"a":9223372036854775807}}`})
+
+	tests := []struct {
+		name      string
+		key       string
+		path      string
+		increment string
+		response  string
+		doc       string
+	}{
+		{
+			name:      "integers",
+			key:       "doc",
+			path:      "$..a",
+			increment: "2",
+			response:  bulkString(`[3,9.223372036854776e18]`),
+			doc:       `{"a":3,"b":1.5,"c":"x","d":{"a":9.223372036854776e18}}`,
+		},
+		{
+			name:      "floats",
+			key:       "doc",
+			path:      "$.*",
+			increment: "1.5",
+			response:  bulkString(`[4.5,3.0,null,null]`),
+			doc:       `{"a":4.5,"b":3.0,"c":"x","d":{"a":9.223372036854776e18}}`,
+		},
+		{
+			name:      "legacy path",
+			key:       "doc",
+			path:      ".b",
+			increment: "-1",
+			response:  bulkString(`2.0`),
+			doc:       `{"a":4.5,"b":2.0,"c":"x","d":{"a":9.223372036854776e18}}`,
+		},
+		{
+			name:      "legacy path to a string",
+			key:       "doc",
+			path:      ".c",
+			increment: "1",
+			response: "-WRONGTYPE wrong type of path value - expected a number but found " +
+				"string\r\n",
+		},
+		{
+			name:      "legacy path selects nothing",
+			key:       "doc",
+			path:      ".e",
+			increment: "1",
+			response:  "-ERR Path '.e' does not exist\r\n",
+		},
+		{
+			name:      "increment not a number",
+			key:       "doc",
+			path:      "$.a",
+			increment: `"1"`,
+			response: "-WRONGTYPE wrong type of path value - expected a number but found " +
+				"string\r\n",
+		},
+		{
+			name:      "missing key",
+			key:       "missing",
+			path:      "$",
+			increment: "1",
+			response:  "-ERR could not perform this operation on a key that doesn't exist\r\n",
+		},
+		{
+			name:      "wrong type",
+			key:       "string",
+			path:      "$",
+			increment: "1",
+			response:  "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			command := redis.NewJSONNumIncrByCommand(
+				store, clock, test.key, jsonPath(t, test.path), test.increment,
+			)
+			if response := command.Run(); response != test.response {
+				t.Errorf("command expected to return %#v but was %#v", test.response, response)
+			}
+			if test.doc == "" {
+				return
+			}
+			if doc := jsonGet(t, store, clock, test.key); doc != bulkString(test.doc) {
+				t.Errorf("expected document to be %#v but was %#v", bulkString(test.doc), doc)
+			}
+		})
+	}
+}
+
+func TestJSONArrAppendCommand(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{}
+	parser := redis.NewParser(defaultEncodingRedisConfig, store, clock)
+	keysWith(t, parser, []string{"JSON.SET", "doc", "$", `{"a":[],"b":{"a":[1]},"c":1}`})
+
+	tests := []struct {
+		name     string
+		key      string
+		path     string
+		values   []string
+		response string
+		doc      string
+	}{
+		{
+			name:     "JSONPath",
+			key:      "doc",
+			path:     "$..a",
+			values:   []string{`"x"`, `{"y":1}`},
+			response: "*2\r\n:2\r\n:3\r\n",
+			doc:      `{"a":["x",{"y":1}],"b":{"a":[1,"x",{"y":1}]},"c":1}`,
+		},
+		{
+			name:     "not an array",
+			key:      "doc",
+			path:     "$.c",
+			values:   []string{`1`},
+			response: "*1\r\n$-1\r\n",
+			doc:      `{"a":["x",{"y":1}],"b":{"a":[1,"x",{"y":1}]},"c":1}`,
+		},
+		{
+			name:     "legacy path",
+			key:      "doc",
+			path:     ".a",
+			values:   []string{`null`},
+			response: ":3\r\n",
+			doc:      `{"a":["x",{"y":1},null],"b":{"a":[1,"x",{"y":1}]},"c":1}`,
+		},
+		{
+			name:     "legacy path not to an array",
+			key:      "doc",
+			path:     ".b",
+			values:   []string{`1`},
+			response: "-WRONGTYPE wrong type of path value - expected an array but found object\r\n",
+		},
+		{
+			name:     "invalid JSON",
+			key:      "doc",
+			path:     ".a",
+			values:   []string{`[`},
+			response: "-ERR unexpected end of JSON input\r\n",
+		},
+		{
+			name:     "missing key",
+			key:      "missing",
+			path:     ".a",
+			values:   []string{`1`},
+			response: "-ERR could not perform this operation on a key that doesn't exist\r\n",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			command := redis.NewJSONArrAppendCommand(
+				store, clock, test.key, jsonPath(t, test.path), test.values,
+			)
+			if response := command.Run(); response != test.response {
+				t.Errorf("command expected to return %#v but was %#v", test.response, response)
+			}
+			if test.doc == "" {
+				return
+			}
+			if doc := jsonGet(t, store, clock, test.key); doc != bulkString(test.doc) {
+				t.Errorf("expected document to be %#v but was %#v", bulkString(test.doc), doc)
+			}
+		})
+	}
+}
+
+func TestJSONArrPopCommand(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{}
+	parser := redis.NewParser(defaultEncodingRedisConfig, store, clock)
+	keysWith(t, parser, []string{"JSON.SET", "doc", "$", `{"a":[1,2,3,4],"b":{"a":[]},"c":"x"}`})
+
+	tests := []struct {
+		name     string
+		key      string
+		path     string
+		index    int
+		response string
+		doc      string
+	}{
+		{
+			name:     "last",
+			key:      "doc",
+			path:     "$..a",
+			index:    -1,
+			response: "*2\r\n" + bulkString("4") + "$-1\r\n",
+			doc:      `{"a":[1,2,3],"b":{"a":[]},"c":"x"}`,
+		},
+		{
+			name:     "first",
+			key:      "doc",
+			path:     ".a",
+			index:    0,
+			response: bulkString("1"),
+			doc:      `{"a":[2,3],"b":{"a":[]},"c":"x"}`,
+		},
+		{
+			name:     "out of range",
+			key:      "doc",
+			path:     ".a",
+			index:    10,
+			response: bulkString("3"),
+			doc:      `{"a":[2],"b":{"a":[]},"c":"x"}`,
+		},
+		{
+			name:     "empty",
+			key:      "doc",
+			path:     ".b.a",
+			index:    -1,
+			response: "$-1\r\n",
+			doc:      `{"a":[2],"b":{"a":[]},"c":"x"}`,
+		},
+		{
+			name:     "not an array",
+			key:      "doc",
+			path:     ".c",
+			index:    -1,
+			response: "-WRONGTYPE wrong type of path value - expected an array but found string\r\n",
+		},
+		{
+			name:     "missing key",
+			key:      "missing",
+			path:     ".",
+			index:    -1,
+			response: "$-1\r\n",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			command := redis.NewJSONArrPopCommand(
+				store, clock, test.key, jsonPath(t, test.path), test.index,
+			)
+			if response := command.Run(); response != test.response {
+				t.Errorf("command expected to return %#v but was %#v", test.response, response)
+			}
+			if test.doc == "" {
+				return
+			}
+			if doc := jsonGet(t, store, clock, test.key); doc != bulkString(test.doc) {
+				t.Errorf("expected document to be %#v but was %#v", bulkString(test.doc), doc)
+			}
+		})
+	}
+}
+
+func TestJSONObjKeysCommand(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{}
+	parser := redis.NewParser(defaultEncodingRedisConfig, store, clock)
+	keysWith(t, parser, []string{"JSON.SET", "user", "$", profile})
+
+	tests := []struct {
+		name     string
+		key      string
+		path     string
+		response string
+	}{
+		{
+			name:     "root",
+			key:      "user",
+			path:     ".",
+			response: bulkStringArray("name", "age", "score", "tags", "address", "friends"),
+		},
+		{
+			name: "JSONPath",
+			key:  "user",
+			path: "$.friends[*]",
+			response: "*2\r\n" + bulkStringArray("name", "age") +
+				bulkStringArray("name", "age"),
+		},
+		{
+			name:     "not an object",
+			key:      "user",
+			path:     "$.tags",
+			response: "*1\r\n$-1\r\n",
+		},
+		{
+			name:     "legacy path not to an object",
+			key:      "user",
+			path:     ".tags",
+			response: "-WRONGTYPE wrong type of path value - expected an object but found array\r\n",
+		},
+		{
+			name:     "missing key",
+			key:      "missing",
+			path:     ".",
+			response: "$-1\r\n",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			command := redis.NewJSONObjKeysCommand(store, clock, test.key, jsonPath(t, test.path))
+			if response := command.Run(); response != test.response {
+				t.Errorf("command expected to return %#v but was %#v", test.response, response)
+			}
+		})
+	}
+}
+
+func TestJSONStrAppendCommand(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{}
+	parser := redis.NewParser(defaultEncodingRedisConfig, store, clock)
+	keysWith(t, parser, []string{"JSON.SET", "doc", "$", `{"a":"foo","b":{"a":"x"},"c":1}`})
+
+	tests := []struct {
+		name     string
+		key      string
+		path     string
+		value    string
+		response string
+		doc      string
+	}{
+		{
+			name:     "JSONPath",
+			key:      "doc",
+			path:     "$..a",
+			value:    `"bar"`,
+			response: "*2\r\n:6\r\n:4\r\n",
+			doc:      `{"a":"foobar","b":{"a":"xbar"},"c":1}`,
+		},
+		{
+			name:     "not a string",
+			key:      "doc",
+			path:     "$.c",
+			value:    `"bar"`,
+			response: "*1\r\n$-1\r\n",
+			doc:      `{"a":"foobar","b":{"a":"xbar"},"c":1}`,
+		},
+		{
+			name:     "legacy path",
+			key:      "doc",
+			path:     ".a",
+			value:    `"!"`,
+			response: ":7\r\n",
+			doc:      `{"a":"foobar!","b":{"a":"xbar"},"c":1}`,
+		},
+		{
+			name:     "value not a string",
+			key:      "doc",
+			path:     ".a",
+			value:    `1`,
+			response: "-WRONGTYPE wrong type of path value - expected a string but found integer\r\n",
+		},
+		{
+			name:     "missing key",
+			key:      "missing",
+			path:     ".",
+			value:    `"bar"`,
+			response: "-ERR could not perform this operation on a key that doesn't exist\r\n",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			command := redis.NewJSONStrAppendCommand(
+				store, clock, test.key, jsonPath(t, test.path), test.value,
+			)
+			if response := command.Run(); response != test.response {
+				t.Errorf("command expected to return %#v but was %#v", test.response, response)
+			}
+			if test.doc == "" {
+				return
+			}
+			if doc := jsonGet(t, store, clock, test.key); doc != bulkString(test.doc) {
+				t.Errorf("expected document to be %#v but was %#v", bulkString(test.doc), doc)
+			}
+		})
+	}
+}
@@ -0,0 +1,917 @@
+package redis
+
+import (
+	"fmt"
+	"time"
+)
+
+// ListEnd is one of the two ends of a list: the left end, or head, and the right end, or tail.
+type ListEnd int
+
+const (
+	ListEndLeft ListEnd = iota
+	ListEndRight
+)
+
+func (l ListEnd) String() string {
+	switch l {
+	case ListEndLeft:
+		return "LEFT"
+	case ListEndRight:
+		return "RIGHT"
+	}
+	panic(fmt.Sprintf("unknown redis.ListEnd: %d", l))
+}
+
+func (l ListEnd) push(list *quicklist, element string) {
+	if l == ListEndLeft {
+		list.pushFront(element)
+	} else {
+		list.pushBack(element)
+	}
+}
+
+func (l ListEnd) pop(list *quicklist) string {
+	if l == ListEndLeft {
+		return list.popFront()
+	}
+	return list.popBack()
+}
+
+// pushEvent returns the keyspace event of pushing onto l: lpush or rpush.
+func (l ListEnd) pushEvent() string {
+	if l == ListEndLeft {
+		return "lpush"
+	}
+	return "rpush"
+}
+
+// popEvent returns the keyspace event of popping from l: lpop or rpop.
+func (l ListEnd) popEvent() string {
+	if l == ListEndLeft {
+		return "lpop"
+	}
+	return "rpop"
+}
+
+// normalizeRange converts start and stop, which are inclusive indexes that count back from the
+// end of a sequence of length elements if they're negative, into non-negative indexes. ok is
+// false if the range is empty.
+func normalizeRange(start, stop, length int) (int, int, bool) {
+	if start < 0 {
+		start += length
+	}
+	if stop < 0 {
+		stop += length
+	}
+	if start < 0 {
+		start = 0
+	}
+	if start > stop || start >= length {
+		return 0, 0, false
+	}
+	if stop >= length {
+		stop = length - 1
+	}
+	return start, stop, true
+}
+
+func NewPushCommand(
+	store *Store,
+	clock Clock,
+	key string,
+	end ListEnd,
+	elements []string,
+	options ...func(*PushCommand),
+) *PushCommand {
+	result := &PushCommand{
+		store:    store,
+		clock:    clock,
+		key:      key,
+		end:      end,
+		elements: elements,
+	}
+	for _, option := range options {
+		option(result)
+	}
+	return result
+}
+
+// PushCommand is LPUSH, RPUSH, LPUSHX or RPUSHX, depending on its end and whether it only
+// pushes if the list exists.
+type PushCommand struct {
+	store        *Store
+	clock        Clock
+	key          string
+	end          ListEnd
+	elements     []string
+	onlyIfExists bool
+}
+
+// PushOnlyIfExists makes a PushCommand do nothing if its list doesn't exist, like LPUSHX and
+// RPUSHX.
+func PushOnlyIfExists() func(*PushCommand) {
+	return func(command *PushCommand) {
+		command.onlyIfExists = true
+	}
+}
+
+func (p *PushCommand) Run() string {
+	var response string
+	p.store.write(p.clock.NowMonotonic(), func(tx *storeTx) {
+		value, ok, err := tx.getTyped(p.key, ValueTypeList)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		if !ok {
+			if p.onlyIfExists {
+				response = integer(0)
+				return
+			}
+			value = StoreValue{data: newQuicklist()}
+			tx.set(p.key, value)
+		}
+
+		list := value.list()
+		for _, element := range p.elements {
+			p.end.push(list, element)
+		}
+		tx.notify(notifyList, p.end.pushEvent(), p.key)
+		tx.signalKeyAsReady(p.key)
+		response = integer(list.len())
+	})
+	return response
+}
+
+func NewPopCommand(
+	store *Store,
+	clock Clock,
+	key string,
+	end ListEnd,
+	count *int,
+) *PopCommand {
+	return &PopCommand{
+		store: store,
+		clock: clock,
+		key:   key,
+		end:   end,
+		count: count,
+	}
+}
+
+// PopCommand is LPOP or RPOP, depending on its end. If count is set it pops up to that many
+// elements and returns them as an array, otherwise it pops one.
+type PopCommand struct {
+	store *Store
+	clock Clock
+	key   string
+	end   ListEnd
+	count *int
+}
+
+func (p *PopCommand) Run() string {
+	var response string
+	p.store.write(p.clock.NowMonotonic(), func(tx *storeTx) {
+		value, ok, err := tx.getTyped(p.key, ValueTypeList)
+		switch {
+		case err != nil:
+			response = errorResponse(err)
+			return
+		case !ok && p.count == nil:
+			response = nullBulkString
+			return
+		case !ok:
+			response = nullArray
+			return
+		case p.count == nil:
+			response = bulkString(popElements(tx, p.key, value.list(), p.end, 1)[0])
+			return
+		}
+
+		elements := popElements(tx, p.key, value.list(), p.end, *p.count)
+		response = bulkStringArray(elements)
+	})
+	return response
+}
+
+// popElements pops up to count elements from end of list, which is stored at key, deleting key
+// if the list is left empty, and notifies the keyspace events.
+func popElements(tx *storeTx, key string, list *quicklist, end ListEnd, count int) []string {
+	if count > list.len() {
+		count = list.len()
+	}
+	elements := make([]string, count)
+	for i := range elements {
+		elements[i] = end.pop(list)
+	}
+	tx.notify(notifyList, end.popEvent(), key)
+	if list.len() == 0 {
+		tx.deleteEmpty(key)
+	}
+	return elements
+}
+
+func NewLLenCommand(store *Store, clock Clock, key string) *LLenCommand {
+	return &LLenCommand{
+		store: store,
+		clock: clock,
+		key:   key,
+	}
+}
+
+type LLenCommand struct {
+	store *Store
+	clock Clock
+	key   string
+}
+
+func (l *LLenCommand) Run() string {
+	var response string
+	l.store.read(l.clock.NowMonotonic(), func(tx *storeTx) {
+		value, ok, err := tx.getTyped(l.key, ValueTypeList)
+		switch {
+		case err != nil:
+			response = errorResponse(err)
+		case !ok:
+			response = integer(0)
+		default:
+			response = integer(value.list().len())
+		}
+	})
+	return response
+}
+
+func NewLRangeCommand(store *Store, clock Clock, key string, start, stop int) *LRangeCommand {
+	return &LRangeCommand{
+		store: store,
+		clock: clock,
+		key:   key,
+		start: start,
+		stop:  stop,
+	}
+}
+
+type LRangeCommand struct {
+	store *Store
+	clock Clock
+	key   string
+	start int
+	stop  int
+}
+
+func (l *LRangeCommand) Run() string {
+	var response string
+	l.store.read(l.clock.NowMonotonic(), func(tx *storeTx) {
+		value, ok, err := tx.getTyped(l.key, ValueTypeList)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		if !ok {
+			response = array()
+			return
+		}
+		list := value.list()
+		start, stop, ok := normalizeRange(l.start, l.stop, list.len())
+		if !ok {
+			response = array()
+			return
+		}
+		response = bulkStringArray(list.slice(start, stop))
+	})
+	return response
+}
+
+func NewLIndexCommand(store *Store, clock Clock, key string, index int) *LIndexCommand {
+	return &LIndexCommand{
+		store: store,
+		clock: clock,
+		key:   key,
+		index: index,
+	}
+}
+
+type LIndexCommand struct {
+	store *Store
+	clock Clock
+	key   string
+	index int
+}
+
+func (l *LIndexCommand) Run() string {
+	response := nullBulkString
+	l.store.read(l.clock.NowMonotonic(), func(tx *storeTx) {
+		value, ok, err := tx.getTyped(l.key, ValueTypeList)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		if !ok {
+			return
+		}
+		list := value.list()
+		index := l.index
+		if index < 0 {
+			index += list.len()
+		}
+		if index < 0 || index >= list.len() {
+			return
+		}
+		response = bulkString(list.index(index))
+	})
+	return response
+}
+
+func NewLSetCommand(store *Store, clock Clock, key string, index int, element string) *LSetCommand {
+	return &LSetCommand{
+		store:   store,
+		clock:   clock,
+		key:     key,
+		index:   index,
+		element: element,
+	}
+}
+
+type LSetCommand struct {
+	store   *Store
+	clock   Clock
+	key     string
+	index   int
+	element string
+}
+
+func (l *LSetCommand) Run() string {
+	var response string
+	l.store.write(l.clock.NowMonotonic(), func(tx *storeTx) {
+		value, ok, err := tx.getTyped(l.key, ValueTypeList)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		if !ok {
+			response = simpleError("ERR no such key")
+			return
+		}
+		list := value.list()
+		index := l.index
+		if index < 0 {
+			index += list.len()
+		}
+		if index < 0 || index >= list.len() {
+			response = simpleError("ERR index out of range")
+			return
+		}
+		list.set(index, l.element)
+		tx.notify(notifyList, "lset", l.key)
+		response = simpleString("OK")
+	})
+	return response
+}
+
+func NewLRemCommand(store *Store, clock Clock, key string, count int, element string) *LRemCommand {
+	return &LRemCommand{
+		store:   store,
+		clock:   clock,
+		key:     key,
+		count:   count,
+		element: element,
+	}
+}
+
+type LRemCommand struct {
+	store   *Store
+	clock   Clock
+	key     string
+	count   int
+	element string
+}
+
+func (l *LRemCommand) Run() string {
+	var response string
+	l.store.write(l.clock.NowMonotonic(), func(tx *storeTx) {
+		value, ok, err := tx.getTyped(l.key, ValueTypeList)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		if !ok {
+			response = integer(0)
+			return
+		}
+		list := value.list()
+		removed := list.remove(l.element, l.count)
+		response = integer(removed)
+		if removed > 0 {
+			tx.notify(notifyList, "lrem", l.key)
+		}
+		if list.len() == 0 {
+			tx.deleteEmpty(l.key)
+		}
+	})
+	return response
+}
+
+func NewLTrimCommand(store *Store, clock Clock, key string, start, stop int) *LTrimCommand {
+	return &LTrimCommand{
+		store: store,
+		clock: clock,
+		key:   key,
+		start: start,
+		stop:  stop,
+	}
+}
+
+type LTrimCommand struct {
+	store *Store
+	clock Clock
+	key   string
+	start int
+	stop  int
+}
+
+func (l *LTrimCommand) Run() string {
+	var response string
+	l.store.write(l.clock.NowMonotonic(), func(tx *storeTx) {
+		value, ok, err := tx.getTyped(l.key, ValueTypeList)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		response = simpleString("OK")
+		if !ok {
+			return
+		}
+		list := value.list()
+		start, stop, ok := normalizeRange(l.start, l.stop, list.len())
+		tx.notify(notifyList, "ltrim", l.key)
+		if !ok {
+			tx.deleteEmpty(l.key)
+			return
+		}
+		list.trim(start, stop)
+	})
+	return response
+}
+
+func NewLInsertCommand(
+	store *Store,
+	clock Clock,
+	key string,
+	before bool,
+	pivot,
+	element string,
+) *LInsertCommand {
+	return &LInsertCommand{
+		store:   store,
+		clock:   clock,
+		key:     key,
+		before:  before,
+		pivot:   pivot,
+		element: element,
+	}
+}
+
+// LInsertCommand inserts element before pivot if before is true, otherwise after it.
+type LInsertCommand struct {
+	store   *Store
+	clock   Clock
+	key     string
+	before  bool
+	pivot   string
+	element string
+}
+
+func (l *LInsertCommand) Run() string {
+	var response string
+	l.store.write(l.clock.NowMonotonic(), func(tx *storeTx) {
+		value, ok, err := tx.getTyped(l.key, ValueTypeList)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		if !ok {
+			response = integer(0)
+			return
+		}
+		list := value.list()
+		pivotIndex := -1
+		list.each(func(i int, element string) bool {
+			if element == l.pivot {
+				pivotIndex = i
+				return false
+			}
+			return true
+		})
+		if pivotIndex == -1 {
+			response = integer(-1)
+			return
+		}
+		if l.before {
+			list.insert(pivotIndex, l.element)
+		} else {
+			list.insert(pivotIndex+1, l.element)
+		}
+		tx.notify(notifyList, "linsert", l.key)
+		response = integer(list.len())
+	})
+	return response
+}
+
+func NewLPosCommand(
+	store *Store,
+	clock Clock,
+	key,
+	element string,
+	options ...func(*LPosCommand),
+) *LPosCommand {
+	result := &LPosCommand{
+		store:   store,
+		clock:   clock,
+		key:     key,
+		element: element,
+		rank:    1,
+	}
+	for _, option := range options {
+		option(result)
+	}
+	return result
+}
+
+type LPosCommand struct {
+	store   *Store
+	clock   Clock
+	key     string
+	element string
+	rank    int
+	count   *int
+	maxLen  int
+}
+
+// LPosRank makes an LPosCommand skip the first rank-1 matches, or search from the end of the
+// list if rank is negative, like LPOS's RANK option. rank must not be 0.
+func LPosRank(rank int) func(*LPosCommand) {
+	return func(command *LPosCommand) {
+		command.rank = rank
+	}
+}
+
+// LPosCount makes an LPosCommand return an array of up to count matches, or every match if
+// count is 0, like LPOS's COUNT option.
+func LPosCount(count int) func(*LPosCommand) {
+	return func(command *LPosCommand) {
+		command.count = &count
+	}
+}
+
+// LPosMaxLen makes an LPosCommand compare at most maxLen elements, or every element if maxLen is
+// 0, like LPOS's MAXLEN option.
+func LPosMaxLen(maxLen int) func(*LPosCommand) {
+	return func(command *LPosCommand) {
+		command.maxLen = maxLen
+	}
+}
+
+func (l *LPosCommand) Run() string {
+	var response string
+	l.store.read(l.clock.NowMonotonic(), func(tx *storeTx) {
+		value, ok, err := tx.getTyped(l.key, ValueTypeList)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		if !ok {
+			if l.count != nil {
+				response = array()
+			} else {
+				response = nullBulkString
+			}
+			return
+		}
+
+		want := 1
+		if l.count != nil {
+			want = *l.count
+		}
+		skip := l.rank - 1
+		if l.rank < 0 {
+			skip = -l.rank - 1
+		}
+		var matches []string
+		compared := 0
+		search := func(i int, element string) bool {
+			if l.maxLen != 0 && compared >= l.maxLen {
+				return false
+			}
+			compared++
+			if element != l.element {
+				return true
+			}
+			if skip > 0 {
+				skip--
+				return true
+			}
+			matches = append(matches, integer(i))
+			return want == 0 || len(matches) < want
+		}
+		if l.rank > 0 {
+			value.list().each(search)
+		} else {
+			value.list().eachReverse(search)
+		}
+
+		switch {
+		case l.count != nil:
+			response = array(matches...)
+		case len(matches) == 0:
+			response = nullBulkString
+		default:
+			response = matches[0]
+		}
+	})
+	return response
+}
+
+func NewLMoveCommand(
+	store *Store,
+	clock Clock,
+	source,
+	destination string,
+	from,
+	to ListEnd,
+) *LMoveCommand {
+	return &LMoveCommand{
+		store:       store,
+		clock:       clock,
+		source:      source,
+		destination: destination,
+		from:        from,
+		to:          to,
+	}
+}
+
+// LMoveCommand is LMOVE, or RPOPLPUSH when from is ListEndRight and to is ListEndLeft.
+type LMoveCommand struct {
+	store       *Store
+	clock       Clock
+	source      string
+	destination string
+	from        ListEnd
+	to          ListEnd
+}
+
+func (l *LMoveCommand) Run() string {
+	response := nullBulkString
+	l.store.write(l.clock.NowMonotonic(), func(tx *storeTx) {
+		element, ok, err := moveElement(tx, l.source, l.destination, l.from, l.to)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		if ok {
+			response = bulkString(element)
+		}
+	})
+	return response
+}
+
+// moveElement pops an element from the from end of the list at source and pushes it onto the to
+// end of the list at destination, creating it if needed. ok is false if source doesn't exist.
+func moveElement(
+	tx *storeTx,
+	source,
+	destination string,
+	from,
+	to ListEnd,
+) (element string, ok bool, err error) {
+	sourceValue, ok, err := tx.getTyped(source, ValueTypeList)
+	if err != nil || !ok {
+		return "", false, err
+	}
+	destinationValue, destinationExists, err := tx.getTyped(destination, ValueTypeList)
+	if err != nil {
+		return "", false, err
+	}
+
+	sourceList := sourceValue.list()
+	element = from.pop(sourceList)
+	if !destinationExists {
+		destinationValue = StoreValue{data: newQuicklist()}
+		tx.set(destination, destinationValue)
+	}
+	to.push(destinationValue.list(), element)
+	tx.notify(notifyList, from.popEvent(), source)
+	tx.notify(notifyList, to.pushEvent(), destination)
+	tx.signalKeyAsReady(destination)
+	// Only delete the source list now, since it may be the destination list too.
+	if sourceList.len() == 0 {
+		tx.deleteEmpty(source)
+	}
+	return element, true, nil
+}
+
+func bulkStringArray(elements []string) string {
+	result := make([]string, len(elements))
+	for i, element := range elements {
+		result[i] = bulkString(element)
+	}
+	return array(result...)
+}
+
+func NewLMPopCommand(store *Store, clock Clock, keys []string, end ListEnd, count int) *LMPopCommand {
+	return &LMPopCommand{
+		store: store,
+		clock: clock,
+		keys:  keys,
+		end:   end,
+		count: count,
+	}
+}
+
+// LMPopCommand is LMPOP, which pops up to count elements from end of the first non-empty list
+// of keys.
+type LMPopCommand struct {
+	store *Store
+	clock Clock
+	keys  []string
+	end   ListEnd
+	count int
+}
+
+func (l *LMPopCommand) Run() string {
+	response := nullArray
+	l.store.write(l.clock.NowMonotonic(), func(tx *storeTx) {
+		serve := lmpopServeFunc(l.end, l.count)
+		for _, key := range l.keys {
+			r, ok, err := serve(tx, key)
+			if err != nil {
+				response = errorResponse(err)
+				return
+			}
+			if ok {
+				response = r
+				return
+			}
+		}
+	})
+	return response
+}
+
+// lmpopServeFunc returns a serveFunc that pops up to count elements from end of a list, and
+// responds with the list's key and the popped elements.
+func lmpopServeFunc(end ListEnd, count int) serveFunc {
+	return func(tx *storeTx, key string) (string, bool, error) {
+		value, ok, err := tx.getTyped(key, ValueTypeList)
+		if err != nil || !ok {
+			return "", false, err
+		}
+		elements := popElements(tx, key, value.list(), end, count)
+		return array(bulkString(key), bulkStringArray(elements)), true, nil
+	}
+}
+
+func NewBlockingPopCommand(
+	store *Store,
+	clock Clock,
+	client *Client,
+	keys []string,
+	end ListEnd,
+	timeout time.Duration,
+) *BlockingPopCommand {
+	return &BlockingPopCommand{
+		store:   store,
+		clock:   clock,
+		client:  client,
+		keys:    keys,
+		end:     end,
+		timeout: timeout,
+	}
+}
+
+// BlockingPopCommand is BLPOP or BRPOP, depending on its end. It pops an element from the first
+// non-empty list of keys, blocking until one of them has an element if they're all empty.
+type BlockingPopCommand struct {
+	store   *Store
+	clock   Clock
+	client  *Client
+	keys    []string
+	end     ListEnd
+	timeout time.Duration
+}
+
+func (b *BlockingPopCommand) Run() string {
+	return blockForKeys(
+		b.store,
+		b.clock,
+		b.client,
+		b.keys,
+		b.timeout,
+		nullArray,
+		func(tx *storeTx, key string) (string, bool, error) {
+			value, ok, err := tx.getTyped(key, ValueTypeList)
+			if err != nil || !ok {
+				return "", false, err
+			}
+			element := popElements(tx, key, value.list(), b.end, 1)[0]
+			return array(bulkString(key), bulkString(element)), true, nil
+		},
+	)
+}
+
+func NewBLMPopCommand(
+	store *Store,
+	clock Clock,
+	client *Client,
+	keys []string,
+	end ListEnd,
+	count int,
+	timeout time.Duration,
+) *BLMPopCommand {
+	return &BLMPopCommand{
+		store:   store,
+		clock:   clock,
+		client:  client,
+		keys:    keys,
+		end:     end,
+		count:   count,
+		timeout: timeout,
+	}
+}
+
+// BLMPopCommand is BLMPOP, the blocking version of LMPOP.
+type BLMPopCommand struct {
+	store   *Store
+	clock   Clock
+	client  *Client
+	keys    []string
+	end     ListEnd
+	count   int
+	timeout time.Duration
+}
+
+func (b *BLMPopCommand) Run() string {
+	return blockForKeys(
+		b.store, b.clock, b.client, b.keys, b.timeout, nullArray, lmpopServeFunc(b.end, b.count),
+	)
+}
+
+func NewBLMoveCommand(
+	store *Store,
+	clock Clock,
+	client *Client,
+	source,
+	destination string,
+	from,
+	to ListEnd,
+	timeout time.Duration,
+) *BLMoveCommand {
+	return &BLMoveCommand{
+		store:       store,
+		clock:       clock,
+		client:      client,
+		source:      source,
+		destination: destination,
+		from:        from,
+		to:          to,
+		timeout:     timeout,
+	}
+}
+
+// BLMoveCommand is BLMOVE, or BRPOPLPUSH when from is ListEndRight and to is ListEndLeft. It's
+// the blocking version of LMOVE.
+type BLMoveCommand struct {
+	store       *Store
+	clock       Clock
+	client      *Client
+	source      string
+	destination string
+	from        ListEnd
+	to          ListEnd
+	timeout     time.Duration
+}
+
+func (b *BLMoveCommand) Run() string {
+	return blockForKeys(
+		b.store,
+		b.clock,
+		b.client,
+		[]string{b.source},
+		b.timeout,
+		nullBulkString,
+		func(tx *storeTx, key string) (string, bool, error) {
+			if _, _, err := tx.getTyped(b.source, ValueTypeList); err != nil {
+				return "", false, err
+			}
+			element, ok, err := moveElement(tx, b.source, b.destination, b.from, b.to)
+			if err != nil {
+				// The destination holds the wrong type, which is reported to the client even if
+				// it has to block first.
+				return errorResponse(err), true, nil
+			}
+			if !ok {
+				return "", false, nil
+			}
+			return bulkString(element), true, nil
+		},
+	)
+}
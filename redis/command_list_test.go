@@ -0,0 +1,616 @@
+package redis_test
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/redis"
+)
+
+func TestPushCommand(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{}
+
+	response := redis.NewPushCommand(store, clock, "list", redis.ListEndLeft, []string{"b", "a"}).Run()
+	if response != ":2\r\n" {
+		t.Errorf(`LPUSH expected to return ":2\r\n" but was %#v`, response)
+	}
+	response = redis.NewPushCommand(store, clock, "list", redis.ListEndRight, []string{"c"}).Run()
+	if response != ":3\r\n" {
+		t.Errorf(`RPUSH expected to return ":3\r\n" but was %#v`, response)
+	}
+
+	response = redis.NewLRangeCommand(store, clock, "list", 0, -1).Run()
+	if want := "*3\r\n$1\r\na\r\n$1\r\nb\r\n$1\r\nc\r\n"; response != want {
+		t.Errorf(`LRANGE expected to return %#v but was %#v`, want, response)
+	}
+}
+
+func TestPushCommand_OnlyIfExists(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{}
+
+	response := redis.NewPushCommand(
+		store, clock, "list", redis.ListEndLeft, []string{"a"}, redis.PushOnlyIfExists(),
+	).Run()
+
+	if response != ":0\r\n" {
+		t.Errorf(`LPUSHX expected to return ":0\r\n" but was %#v`, response)
+	}
+	if _, ok := store.Get("list"); ok {
+		t.Errorf(`store expected not to contain "list"`)
+	}
+}
+
+func TestListCommands_WrongType(t *testing.T) {
+	t.Parallel()
+
+	const wrongType = "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n"
+	store := redis.NewStore()
+	store.Set("string", "zelda")
+	clock := FakeClock{}
+	redis.NewPushCommand(store, clock, "list", redis.ListEndLeft, []string{"a"}).Run()
+
+	tests := []struct {
+		name    string
+		command redis.Command
+	}{
+		{name: "LPUSH string", command: redis.NewPushCommand(store, clock, "string", redis.ListEndLeft, []string{"a"})},
+		{name: "LPOP string", command: redis.NewPopCommand(store, clock, "string", redis.ListEndLeft, nil)},
+		{name: "LLEN string", command: redis.NewLLenCommand(store, clock, "string")},
+		{name: "LRANGE string", command: redis.NewLRangeCommand(store, clock, "string", 0, -1)},
+		{name: "LMOVE list string", command: redis.NewLMoveCommand(store, clock, "list", "string", redis.ListEndLeft, redis.ListEndLeft)},
+		{name: "GET list", command: redis.NewGetCommand(store, clock, "list")},
+		{name: "APPEND list", command: redis.NewAppendCommand(store, clock, "list", "a")},
+		{name: "SETBIT list", command: redis.NewSetBitCommand(store, clock, "list", 0, 1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if response := tt.command.Run(); response != wrongType {
+				t.Errorf(`command expected to return %#v but was %#v`, wrongType, response)
+			}
+		})
+	}
+
+	if response := redis.NewLLenCommand(store, clock, "list").Run(); response != ":1\r\n" {
+		t.Errorf(`LLEN list expected to return ":1\r\n" after failed LMOVE but was %#v`, response)
+	}
+}
+
+func TestPopCommand(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		end      redis.ListEnd
+		count    *int
+		response string
+	}{
+		{name: "LPOP", end: redis.ListEndLeft, response: "$1\r\na\r\n"},
+		{name: "RPOP", end: redis.ListEndRight, response: "$1\r\nc\r\n"},
+		{name: "LPOP 2", end: redis.ListEndLeft, count: ptr(2), response: "*2\r\n$1\r\na\r\n$1\r\nb\r\n"},
+		{name: "RPOP 0", end: redis.ListEndRight, count: ptr(0), response: "*0\r\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := redis.NewStore()
+			clock := FakeClock{}
+			redis.NewPushCommand(store, clock, "list", redis.ListEndRight, []string{"a", "b", "c"}).Run()
+
+			response := redis.NewPopCommand(store, clock, "list", tt.end, tt.count).Run()
+
+			if response != tt.response {
+				t.Errorf(`command expected to return %#v but was %#v`, tt.response, response)
+			}
+		})
+	}
+
+	t.Run("absent key", func(t *testing.T) {
+		store := redis.NewStore()
+		clock := FakeClock{}
+
+		if response := redis.NewPopCommand(store, clock, "list", redis.ListEndLeft, nil).Run(); response != redisNullBulkString {
+			t.Errorf(`command expected to return %#v but was %#v`, redisNullBulkString, response)
+		}
+		if response := redis.NewPopCommand(store, clock, "list", redis.ListEndLeft, ptr(1)).Run(); response != "*-1\r\n" {
+			t.Errorf(`command expected to return "*-1\r\n" but was %#v`, response)
+		}
+	})
+
+	t.Run("deletes empty list", func(t *testing.T) {
+		store := redis.NewStore()
+		clock := FakeClock{}
+		redis.NewPushCommand(store, clock, "list", redis.ListEndRight, []string{"a"}).Run()
+
+		redis.NewPopCommand(store, clock, "list", redis.ListEndLeft, nil).Run()
+
+		if _, ok := store.Get("list"); ok {
+			t.Errorf(`store expected not to contain "list"`)
+		}
+	})
+}
+
+func TestListCommands_ManyElements(t *testing.T) {
+	t.Parallel()
+
+	// Use enough elements that the list is split across several quicklist nodes.
+	const n = 1000
+	store := redis.NewStore()
+	clock := FakeClock{}
+	for i := n/2 - 1; i >= 0; i-- {
+		redis.NewPushCommand(store, clock, "list", redis.ListEndLeft, []string{strconv.Itoa(i)}).Run()
+	}
+	for i := n / 2; i < n; i++ {
+		redis.NewPushCommand(store, clock, "list", redis.ListEndRight, []string{strconv.Itoa(i)}).Run()
+	}
+
+	for _, i := range []int{0, 1, 127, 128, 499, 500, 871, 999} {
+		response := redis.NewLIndexCommand(store, clock, "list", i).Run()
+		if want := "$" + strconv.Itoa(len(strconv.Itoa(i))) + "\r\n" + strconv.Itoa(i) + "\r\n"; response != want {
+			t.Errorf(`LINDEX list %d expected to return %#v but was %#v`, i, want, response)
+		}
+	}
+
+	response := redis.NewLRangeCommand(store, clock, "list", 126, 130).Run()
+	if want := "*5\r\n$3\r\n126\r\n$3\r\n127\r\n$3\r\n128\r\n$3\r\n129\r\n$3\r\n130\r\n"; response != want {
+		t.Errorf(`LRANGE list 126 130 expected to return %#v but was %#v`, want, response)
+	}
+
+	redis.NewLInsertCommand(store, clock, "list", true, "300", "x").Run()
+	response = redis.NewLPosCommand(store, clock, "list", "x").Run()
+	if response != ":300\r\n" {
+		t.Errorf(`LPOS list x expected to return ":300\r\n" but was %#v`, response)
+	}
+	response = redis.NewLIndexCommand(store, clock, "list", 301).Run()
+	if response != "$3\r\n300\r\n" {
+		t.Errorf(`LINDEX list 301 expected to return "$3\r\n300\r\n" but was %#v`, response)
+	}
+
+	redis.NewLTrimCommand(store, clock, "list", 100, -101).Run()
+	response = redis.NewLLenCommand(store, clock, "list").Run()
+	if response != ":801\r\n" {
+		t.Errorf(`LLEN list expected to return ":801\r\n" but was %#v`, response)
+	}
+	response = redis.NewLRangeCommand(store, clock, "list", 0, 0).Run()
+	if response != "*1\r\n$3\r\n100\r\n" {
+		t.Errorf(`LRANGE list 0 0 expected to return "*1\r\n$3\r\n100\r\n" but was %#v`, response)
+	}
+}
+
+func TestLRangeCommand(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		start    int
+		stop     int
+		response string
+	}{
+		{start: 0, stop: 0, response: "*1\r\n$3\r\none\r\n"},
+		{start: -3, stop: 2, response: "*3\r\n$3\r\none\r\n$3\r\ntwo\r\n$5\r\nthree\r\n"},
+		{start: -100, stop: 100, response: "*3\r\n$3\r\none\r\n$3\r\ntwo\r\n$5\r\nthree\r\n"},
+		{start: 5, stop: 10, response: "*0\r\n"},
+		{start: 2, stop: 1, response: "*0\r\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(tt.start)+" "+strconv.Itoa(tt.stop), func(t *testing.T) {
+			store := redis.NewStore()
+			clock := FakeClock{}
+			redis.NewPushCommand(store, clock, "list", redis.ListEndRight, []string{"one", "two", "three"}).Run()
+
+			response := redis.NewLRangeCommand(store, clock, "list", tt.start, tt.stop).Run()
+
+			if response != tt.response {
+				t.Errorf(`command expected to return %#v but was %#v`, tt.response, response)
+			}
+		})
+	}
+}
+
+func TestLSetCommand(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{}
+	redis.NewPushCommand(store, clock, "list", redis.ListEndRight, []string{"one", "two", "three"}).Run()
+
+	if response := redis.NewLSetCommand(store, clock, "list", -2, "four").Run(); response != "+OK\r\n" {
+		t.Errorf(`command expected to return "+OK\r\n" but was %#v`, response)
+	}
+	if response := redis.NewLSetCommand(store, clock, "list", 3, "five").Run(); response != "-ERR index out of range\r\n" {
+		t.Errorf(`command expected to return "-ERR index out of range\r\n" but was %#v`, response)
+	}
+	if response := redis.NewLSetCommand(store, clock, "absent", 0, "five").Run(); response != "-ERR no such key\r\n" {
+		t.Errorf(`command expected to return "-ERR no such key\r\n" but was %#v`, response)
+	}
+	response := redis.NewLRangeCommand(store, clock, "list", 0, -1).Run()
+	if want := "*3\r\n$3\r\none\r\n$4\r\nfour\r\n$5\r\nthree\r\n"; response != want {
+		t.Errorf(`LRANGE expected to return %#v but was %#v`, want, response)
+	}
+}
+
+func TestLRemCommand(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		count    int
+		response string
+		list     string
+	}{
+		{count: 2, response: ":2\r\n", list: "*3\r\n$1\r\nb\r\n$1\r\nc\r\n$1\r\na\r\n"},
+		{count: -2, response: ":2\r\n", list: "*3\r\n$1\r\na\r\n$1\r\nb\r\n$1\r\nc\r\n"},
+		{count: 0, response: ":3\r\n", list: "*2\r\n$1\r\nb\r\n$1\r\nc\r\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(tt.count), func(t *testing.T) {
+			store := redis.NewStore()
+			clock := FakeClock{}
+			redis.NewPushCommand(store, clock, "list", redis.ListEndRight, []string{"a", "b", "a", "c", "a"}).Run()
+
+			response := redis.NewLRemCommand(store, clock, "list", tt.count, "a").Run()
+
+			if response != tt.response {
+				t.Errorf(`command expected to return %#v but was %#v`, tt.response, response)
+			}
+			if list := redis.NewLRangeCommand(store, clock, "list", 0, -1).Run(); list != tt.list {
+				t.Errorf(`LRANGE expected to return %#v but was %#v`, tt.list, list)
+			}
+		})
+	}
+}
+
+func TestLInsertCommand(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{}
+	redis.NewPushCommand(store, clock, "list", redis.ListEndRight, []string{"Hello", "World"}).Run()
+
+	if response := redis.NewLInsertCommand(store, clock, "list", true, "World", "There").Run(); response != ":3\r\n" {
+		t.Errorf(`command expected to return ":3\r\n" but was %#v`, response)
+	}
+	if response := redis.NewLInsertCommand(store, clock, "list", false, "World", "!").Run(); response != ":4\r\n" {
+		t.Errorf(`command expected to return ":4\r\n" but was %#v`, response)
+	}
+	if response := redis.NewLInsertCommand(store, clock, "list", false, "Nope", "!").Run(); response != ":-1\r\n" {
+		t.Errorf(`command expected to return ":-1\r\n" but was %#v`, response)
+	}
+	response := redis.NewLRangeCommand(store, clock, "list", 0, -1).Run()
+	if want := "*4\r\n$5\r\nHello\r\n$5\r\nThere\r\n$5\r\nWorld\r\n$1\r\n!\r\n"; response != want {
+		t.Errorf(`LRANGE expected to return %#v but was %#v`, want, response)
+	}
+}
+
+func TestLPosCommand(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		options  []func(*redis.LPosCommand)
+		response string
+	}{
+		{name: "no options", response: ":2\r\n"},
+		{name: "RANK 2", options: []func(*redis.LPosCommand){redis.LPosRank(2)}, response: ":6\r\n"},
+		{name: "RANK -1", options: []func(*redis.LPosCommand){redis.LPosRank(-1)}, response: ":7\r\n"},
+		{name: "COUNT 2", options: []func(*redis.LPosCommand){redis.LPosCount(2)}, response: "*2\r\n:2\r\n:6\r\n"},
+		{
+			name:     "RANK -1 COUNT 2",
+			options:  []func(*redis.LPosCommand){redis.LPosRank(-1), redis.LPosCount(2)},
+			response: "*2\r\n:7\r\n:6\r\n",
+		},
+		{name: "COUNT 0", options: []func(*redis.LPosCommand){redis.LPosCount(0)}, response: "*3\r\n:2\r\n:6\r\n:7\r\n"},
+		{name: "MAXLEN 2", options: []func(*redis.LPosCommand){redis.LPosMaxLen(2)}, response: redisNullBulkString},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := redis.NewStore()
+			clock := FakeClock{}
+			redis.NewPushCommand(
+				store, clock, "list", redis.ListEndRight, []string{"a", "b", "c", "1", "2", "3", "c", "c"},
+			).Run()
+
+			response := redis.NewLPosCommand(store, clock, "list", "c", tt.options...).Run()
+
+			if response != tt.response {
+				t.Errorf(`command expected to return %#v but was %#v`, tt.response, response)
+			}
+		})
+	}
+}
+
+func TestLMoveCommand(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{}
+	redis.NewPushCommand(store, clock, "list", redis.ListEndRight, []string{"one", "two", "three"}).Run()
+
+	response := redis.NewLMoveCommand(store, clock, "list", "other", redis.ListEndRight, redis.ListEndLeft).Run()
+	if response != "$5\r\nthree\r\n" {
+		t.Errorf(`command expected to return "$5\r\nthree\r\n" but was %#v`, response)
+	}
+	response = redis.NewLMoveCommand(store, clock, "list", "other", redis.ListEndLeft, redis.ListEndRight).Run()
+	if response != "$3\r\none\r\n" {
+		t.Errorf(`command expected to return "$3\r\none\r\n" but was %#v`, response)
+	}
+	if list := redis.NewLRangeCommand(store, clock, "list", 0, -1).Run(); list != "*1\r\n$3\r\ntwo\r\n" {
+		t.Errorf(`LRANGE list expected to return "*1\r\n$3\r\ntwo\r\n" but was %#v`, list)
+	}
+	if list := redis.NewLRangeCommand(store, clock, "other", 0, -1).Run(); list != "*2\r\n$5\r\nthree\r\n$3\r\none\r\n" {
+		t.Errorf(`LRANGE other expected to return "*2\r\n$5\r\nthree\r\n$3\r\none\r\n" but was %#v`, list)
+	}
+
+	// Rotating a single element list onto itself must not lose the element.
+	response = redis.NewLMoveCommand(store, clock, "list", "list", redis.ListEndLeft, redis.ListEndRight).Run()
+	if response != "$3\r\ntwo\r\n" {
+		t.Errorf(`command expected to return "$3\r\ntwo\r\n" but was %#v`, response)
+	}
+	if list := redis.NewLRangeCommand(store, clock, "list", 0, -1).Run(); list != "*1\r\n$3\r\ntwo\r\n" {
+		t.Errorf(`LRANGE list expected to return "*1\r\n$3\r\ntwo\r\n" but was %#v`, list)
+	}
+
+	response = redis.NewLMoveCommand(store, clock, "absent", "list", redis.ListEndLeft, redis.ListEndRight).Run()
+	if response != redisNullBulkString {
+		t.Errorf(`command expected to return %#v but was %#v`, redisNullBulkString, response)
+	}
+}
+
+func TestLMPopCommand(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{}
+	redis.NewPushCommand(store, clock, "list", redis.ListEndRight, []string{"a", "b", "c"}).Run()
+
+	response := redis.NewLMPopCommand(store, clock, []string{"absent", "list"}, redis.ListEndRight, 2).Run()
+	if want := "*2\r\n$4\r\nlist\r\n*2\r\n$1\r\nc\r\n$1\r\nb\r\n"; response != want {
+		t.Errorf(`command expected to return %#v but was %#v`, want, response)
+	}
+	response = redis.NewLMPopCommand(store, clock, []string{"list"}, redis.ListEndLeft, 5).Run()
+	if want := "*2\r\n$4\r\nlist\r\n*1\r\n$1\r\na\r\n"; response != want {
+		t.Errorf(`command expected to return %#v but was %#v`, want, response)
+	}
+	response = redis.NewLMPopCommand(store, clock, []string{"list"}, redis.ListEndLeft, 1).Run()
+	if response != "*-1\r\n" {
+		t.Errorf(`command expected to return "*-1\r\n" but was %#v`, response)
+	}
+}
+
+func TestBlockingPopCommand_ServesImmediately(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	store.Set("string", "zelda")
+	clock := FakeClock{}
+	client := redis.NewClients().NewClient()
+	redis.NewPushCommand(store, clock, "list", redis.ListEndRight, []string{"a", "b"}).Run()
+
+	response := redis.NewBlockingPopCommand(
+		store, clock, client, []string{"absent", "list"}, redis.ListEndRight, 0,
+	).Run()
+	if want := "*2\r\n$4\r\nlist\r\n$1\r\nb\r\n"; response != want {
+		t.Errorf(`command expected to return %#v but was %#v`, want, response)
+	}
+
+	response = redis.NewBlockingPopCommand(
+		store, clock, client, []string{"string", "list"}, redis.ListEndRight, 0,
+	).Run()
+	if want := "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n"; response != want {
+		t.Errorf(`command expected to return %#v but was %#v`, want, response)
+	}
+	if client.Blocked() {
+		t.Errorf("client expected not to be blocked")
+	}
+}
+
+func TestBlockingPopCommand_ServesBlockedClientsInOrder(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{}
+	clients := redis.NewClients()
+
+	var responses []chan string
+	for i := 0; i < 3; i++ {
+		client := clients.NewClient()
+		response := make(chan string, 1)
+		go func() {
+			response <- redis.NewBlockingPopCommand(
+				store, clock, client, []string{"other", "list"}, redis.ListEndLeft, 0,
+			).Run()
+		}()
+		waitUntilBlocked(t, client)
+		responses = append(responses, response)
+	}
+
+	redis.NewPushCommand(store, clock, "list", redis.ListEndRight, []string{"a", "b"}).Run()
+
+	for i, want := range []string{"a", "b"} {
+		if response := <-responses[i]; response != "*2\r\n$4\r\nlist\r\n$1\r\n"+want+"\r\n" {
+			t.Errorf(`client %d expected to pop %#v but response was %#v`, i, want, response)
+		}
+	}
+	select {
+	case response := <-responses[2]:
+		t.Errorf(`client 2 expected to still be blocked but response was %#v`, response)
+	default:
+	}
+	if _, ok := store.Get("list"); ok {
+		t.Errorf(`store expected not to contain "list"`)
+	}
+
+	redis.NewPushCommand(store, clock, "other", redis.ListEndRight, []string{"c"}).Run()
+
+	if response := <-responses[2]; response != "*2\r\n$5\r\nother\r\n$1\r\nc\r\n" {
+		t.Errorf(`client 2 expected to pop "c" but response was %#v`, response)
+	}
+}
+
+func TestBlockingPopCommand_GivesUp(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		giveUp   func(clients *redis.Clients, client *redis.Client, clock FakeClock)
+		response string
+	}{
+		{
+			name: "timeout",
+			giveUp: func(_ *redis.Clients, _ *redis.Client, clock FakeClock) {
+				close(clock.Timeout)
+			},
+			response: "*-1\r\n",
+		},
+		{
+			name: "CLIENT UNBLOCK TIMEOUT",
+			giveUp: func(clients *redis.Clients, client *redis.Client, _ FakeClock) {
+				redis.NewClientUnblockCommand(clients, client.ID(), false).Run()
+			},
+			response: "*-1\r\n",
+		},
+		{
+			name: "CLIENT UNBLOCK ERROR",
+			giveUp: func(clients *redis.Clients, client *redis.Client, _ FakeClock) {
+				redis.NewClientUnblockCommand(clients, client.ID(), true).Run()
+			},
+			response: "-UNBLOCKED client unblocked via CLIENT UNBLOCK\r\n",
+		},
+		{
+			name: "client closed",
+			giveUp: func(_ *redis.Clients, client *redis.Client, _ FakeClock) {
+				client.Close()
+			},
+			response: "*-1\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := redis.NewStore()
+			clock := FakeClock{Timeout: make(chan time.Time)}
+			clients := redis.NewClients()
+			client := clients.NewClient()
+			response := make(chan string, 1)
+			go func() {
+				response <- redis.NewBlockingPopCommand(
+					store, clock, client, []string{"list"}, redis.ListEndLeft, time.Second,
+				).Run()
+			}()
+			waitUntilBlocked(t, client)
+
+			tt.giveUp(clients, client, clock)
+
+			if got := <-response; got != tt.response {
+				t.Errorf(`command expected to return %#v but was %#v`, tt.response, got)
+			}
+			// The client gave up, so it mustn't take the element.
+			redis.NewPushCommand(store, clock, "list", redis.ListEndRight, []string{"a"}).Run()
+			if got := redis.NewLLenCommand(store, clock, "list").Run(); got != ":1\r\n" {
+				t.Errorf(`LLEN expected to return ":1\r\n" but was %#v`, got)
+			}
+		})
+	}
+}
+
+func TestBLMPopCommand(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{}
+	client := redis.NewClients().NewClient()
+	response := make(chan string, 1)
+	go func() {
+		response <- redis.NewBLMPopCommand(
+			store, clock, client, []string{"list"}, redis.ListEndLeft, 2, 0,
+		).Run()
+	}()
+	waitUntilBlocked(t, client)
+
+	redis.NewPushCommand(store, clock, "list", redis.ListEndRight, []string{"a", "b", "c"}).Run()
+
+	if got, want := <-response, "*2\r\n$4\r\nlist\r\n*2\r\n$1\r\na\r\n$1\r\nb\r\n"; got != want {
+		t.Errorf(`command expected to return %#v but was %#v`, want, got)
+	}
+}
+
+func TestBLMoveCommand(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{}
+	clients := redis.NewClients()
+	moveClient := clients.NewClient()
+	popClient := clients.NewClient()
+
+	moveResponse := make(chan string, 1)
+	go func() {
+		moveResponse <- redis.NewBLMoveCommand(
+			store, clock, moveClient, "source", "destination", redis.ListEndLeft, redis.ListEndRight, 0,
+		).Run()
+	}()
+	waitUntilBlocked(t, moveClient)
+	popResponse := make(chan string, 1)
+	go func() {
+		popResponse <- redis.NewBlockingPopCommand(
+			store, clock, popClient, []string{"destination"}, redis.ListEndLeft, 0,
+		).Run()
+	}()
+	waitUntilBlocked(t, popClient)
+
+	// Serving BLMOVE pushes onto its destination, which serves the client blocked on that.
+	redis.NewPushCommand(store, clock, "source", redis.ListEndRight, []string{"a"}).Run()
+
+	if got := <-moveResponse; got != "$1\r\na\r\n" {
+		t.Errorf(`BLMOVE expected to return "$1\r\na\r\n" but was %#v`, got)
+	}
+	if got, want := <-popResponse, "*2\r\n$11\r\ndestination\r\n$1\r\na\r\n"; got != want {
+		t.Errorf(`BLPOP expected to return %#v but was %#v`, want, got)
+	}
+}
+
+func TestBLMoveCommand_DestinationWrongType(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	store.Set("destination", "zelda")
+	clock := FakeClock{}
+	client := redis.NewClients().NewClient()
+	response := make(chan string, 1)
+	go func() {
+		response <- redis.NewBLMoveCommand(
+			store, clock, client, "source", "destination", redis.ListEndLeft, redis.ListEndRight, 0,
+		).Run()
+	}()
+	waitUntilBlocked(t, client)
+
+	redis.NewPushCommand(store, clock, "source", redis.ListEndRight, []string{"a"}).Run()
+
+	if got, want := <-response, "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n"; got != want {
+		t.Errorf(`command expected to return %#v but was %#v`, want, got)
+	}
+	if got := redis.NewLLenCommand(store, clock, "source").Run(); got != ":1\r\n" {
+		t.Errorf(`LLEN source expected to return ":1\r\n" but was %#v`, got)
+	}
+}
+
+// waitUntilBlocked waits for a command like BLPOP to block on client's behalf.
+func waitUntilBlocked(t *testing.T, client *redis.Client) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !client.Blocked() {
+		if time.Now().After(deadline) {
+			t.Fatalf("client %d expected to block", client.ID())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,223 @@
+package redis
+
+// subscriptionResponse returns the reply to (UN)SUBSCRIBE for one channel: kind, the channel,
+// and client's number of subscriptions after it. It's a push to RESP3 clients.
+func subscriptionResponse(client *Client, kind string, channel string, count int) string {
+	return client.pushMessage(bulkString(kind), bulkString(channel), integer(count))
+}
+
+func NewSubscribeCommand(pubSub *PubSub, client *Client, channels []string) *SubscribeCommand {
+	return &SubscribeCommand{
+		pubSub:   pubSub,
+		client:   client,
+		channels: channels,
+		kind:     channelSubscription,
+	}
+}
+
+// SubscribeCommand is SUBSCRIBE, which subscribes the client that sent it to channels, putting
+// it in subscribed mode, PSUBSCRIBE, which subscribes it to the channels that match glob-style
+// patterns, or SSUBSCRIBE, which subscribes it to shard channels. It replies with a subscribe
+// message for each channel or pattern.
+type SubscribeCommand struct {
+	pubSub   *PubSub
+	client   *Client
+	channels []string
+	kind     subscriptionKind
+}
+
+func NewPSubscribeCommand(pubSub *PubSub, client *Client, patterns []string) *SubscribeCommand {
+	result := NewSubscribeCommand(pubSub, client, patterns)
+	result.kind = patternSubscription
+	return result
+}
+
+func NewSSubscribeCommand(pubSub *PubSub, client *Client, channels []string) *SubscribeCommand {
+	result := NewSubscribeCommand(pubSub, client, channels)
+	result.kind = shardChannelSubscription
+	return result
+}
+
+func (s *SubscribeCommand) Run() string {
+	kind, _, _ := s.kind.names()
+	var response string
+	for _, channel := range s.channels {
+		count := s.pubSub.subscribe(s.client, s.kind, channel)
+		response += subscriptionResponse(s.client, kind, channel, count)
+	}
+	return response
+}
+
+func NewUnsubscribeCommand(
+	pubSub *PubSub,
+	client *Client,
+	channels []string,
+) *UnsubscribeCommand {
+	return &UnsubscribeCommand{
+		pubSub:   pubSub,
+		client:   client,
+		channels: channels,
+		kind:     channelSubscription,
+	}
+}
+
+// UnsubscribeCommand is UNSUBSCRIBE, which unsubscribes the client that sent it from channels,
+// or from every channel if there are none, or PUNSUBSCRIBE or SUNSUBSCRIBE, which do the same
+// for patterns and shard channels. It replies with an unsubscribe message for each channel or
+// pattern, or one with a null channel if there's nothing to unsubscribe from.
+type UnsubscribeCommand struct {
+	pubSub   *PubSub
+	client   *Client
+	channels []string
+	kind     subscriptionKind
+}
+
+func NewPUnsubscribeCommand(
+	pubSub *PubSub,
+	client *Client,
+	patterns []string,
+) *UnsubscribeCommand {
+	result := NewUnsubscribeCommand(pubSub, client, patterns)
+	result.kind = patternSubscription
+	return result
+}
+
+func NewSUnsubscribeCommand(
+	pubSub *PubSub,
+	client *Client,
+	channels []string,
+) *UnsubscribeCommand {
+	result := NewUnsubscribeCommand(pubSub, client, channels)
+	result.kind = shardChannelSubscription
+	return result
+}
+
+func (u *UnsubscribeCommand) Run() string {
+	_, kind, _ := u.kind.names()
+	channels := u.channels
+	if len(channels) == 0 {
+		channels = u.pubSub.subscribedTo(u.client, u.kind)
+		if len(channels) == 0 {
+			count := u.pubSub.subscriptionCount(u.client, u.kind)
+			return u.client.pushMessage(bulkString(kind), nullBulkString, integer(count))
+		}
+	}
+	var response string
+	for _, channel := range channels {
+		count := u.pubSub.unsubscribe(u.client, u.kind, channel)
+		response += subscriptionResponse(u.client, kind, channel, count)
+	}
+	return response
+}
+
+func NewPublishCommand(pubSub *PubSub, channel string, message string) *PublishCommand {
+	return &PublishCommand{
+		pubSub:  pubSub,
+		channel: channel,
+		message: message,
+	}
+}
+
+// PublishCommand is PUBLISH, which pushes a message to the clients subscribed to a channel and
+// replies with how many there are, or SPUBLISH, which does the same for a shard channel.
+type PublishCommand struct {
+	pubSub  *PubSub
+	channel string
+	message string
+	shard   bool
+}
+
+func NewSPublishCommand(pubSub *PubSub, channel string, message string) *PublishCommand {
+	result := NewPublishCommand(pubSub, channel, message)
+	result.shard = true
+	return result
+}
+
+func (p *PublishCommand) Run() string {
+	if p.shard {
+		return integer(p.pubSub.publishShard(p.channel, p.message))
+	}
+	return integer(p.pubSub.publish(p.channel, p.message))
+}
+
+func NewPubSubChannelsCommand(pubSub *PubSub, pattern string) *PubSubChannelsCommand {
+	return &PubSubChannelsCommand{
+		pubSub:  pubSub,
+		pattern: pattern,
+		kind:    channelSubscription,
+	}
+}
+
+// PubSubChannelsCommand is PUBSUB CHANNELS, which replies with the channels that have
+// subscribers and match a glob-style pattern, or PUBSUB SHARDCHANNELS, which does the same for
+// shard channels.
+type PubSubChannelsCommand struct {
+	pubSub  *PubSub
+	pattern string
+	kind    subscriptionKind
+}
+
+func NewPubSubShardChannelsCommand(pubSub *PubSub, pattern string) *PubSubChannelsCommand {
+	result := NewPubSubChannelsCommand(pubSub, pattern)
+	result.kind = shardChannelSubscription
+	return result
+}
+
+func (p *PubSubChannelsCommand) Run() string {
+	return bulkStringArray(p.pubSub.active(p.kind, p.pattern))
+}
+
+func NewPubSubNumPatCommand(pubSub *PubSub) *PubSubNumPatCommand {
+	return &PubSubNumPatCommand{
+		pubSub: pubSub,
+	}
+}
+
+// PubSubNumPatCommand is PUBSUB NUMPAT, which replies with the number of patterns that clients
+// are subscribed to.
+type PubSubNumPatCommand struct {
+	pubSub *PubSub
+}
+
+func (p *PubSubNumPatCommand) Run() string {
+	return integer(p.pubSub.patternCount())
+}
+
+func NewPubSubNumSubCommand(pubSub *PubSub, channels []string) *PubSubNumSubCommand {
+	return &PubSubNumSubCommand{
+		pubSub:   pubSub,
+		channels: channels,
+		kind:     channelSubscription,
+	}
+}
+
+// PubSubNumSubCommand is PUBSUB NUMSUB, which replies with each channel followed by its number
+// of subscribers, or PUBSUB SHARDNUMSUB, which does the same for shard channels.
+type PubSubNumSubCommand struct {
+	pubSub   *PubSub
+	channels []string
+	kind     subscriptionKind
+}
+
+func NewPubSubShardNumSubCommand(pubSub *PubSub, channels []string) *PubSubNumSubCommand {
+	result := NewPubSubNumSubCommand(pubSub, channels)
+	result.kind = shardChannelSubscription
+	return result
+}
+
+func (p *PubSubNumSubCommand) Run() string {
+	elements := make([]string, 0, len(p.channels)*2)
+	for _, channel := range p.channels {
+		count := p.pubSub.subscriberCount(p.kind, channel)
+		elements = append(elements, bulkString(channel), integer(count))
+	}
+	return array(elements...)
+}
+
+// SubscribedPingCommand is PING in subscribed mode, which replies with a pong message rather
+// than PONG, with the message given to PING, if any.
+type SubscribedPingCommand string
+
+func (s SubscribedPingCommand) Run() string {
+	return array(bulkString("pong"), bulkString(string(s)))
+}
@@ -0,0 +1,511 @@
+package redis_test
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/redis"
+)
+
+// message returns the message that a subscriber is pushed when message is published to channel.
+func message(channel, message string) string {
+	return "*3\r\n$7\r\nmessage\r\n" + bulkString(channel) + bulkString(message)
+}
+
+func TestPublishCommand(t *testing.T) {
+	t.Parallel()
+
+	pubSub := redis.NewPubSub(FakeClock{}, redis.OutputBufferLimit{})
+	clients := redis.NewClients()
+	alice := clients.NewClient()
+	bob := clients.NewClient()
+
+	tests := []struct {
+		name     string
+		command  redis.Command
+		response string
+	}{
+		{
+			name:     "publish without subscribers",
+			command:  redis.NewPublishCommand(pubSub, "news", "hello"),
+			response: ":0\r\n",
+		},
+		{
+			name:    "subscribe",
+			command: redis.NewSubscribeCommand(pubSub, alice, []string{"news", "sport"}),
+			response: "*3\r\n$9\r\nsubscribe\r\n$4\r\nnews\r\n:1\r\n" +
+				"*3\r\n$9\r\nsubscribe\r\n$5\r\nsport\r\n:2\r\n",
+		},
+		{
+			name:     "subscribe again",
+			command:  redis.NewSubscribeCommand(pubSub, alice, []string{"news"}),
+			response: "*3\r\n$9\r\nsubscribe\r\n$4\r\nnews\r\n:2\r\n",
+		},
+		{
+			name:     "subscribe another client",
+			command:  redis.NewSubscribeCommand(pubSub, bob, []string{"news"}),
+			response: "*3\r\n$9\r\nsubscribe\r\n$4\r\nnews\r\n:1\r\n",
+		},
+		{
+			name:     "publish",
+			command:  redis.NewPublishCommand(pubSub, "news", "hello"),
+			response: ":2\r\n",
+		},
+		{
+			name:     "publish to one subscriber",
+			command:  redis.NewPublishCommand(pubSub, "sport", "goal"),
+			response: ":1\r\n",
+		},
+		{
+			name:     "channels",
+			command:  redis.NewPubSubChannelsCommand(pubSub, "*"),
+			response: "*2\r\n$4\r\nnews\r\n$5\r\nsport\r\n",
+		},
+		{
+			name:     "channels matching a pattern",
+			command:  redis.NewPubSubChannelsCommand(pubSub, "s*"),
+			response: "*1\r\n$5\r\nsport\r\n",
+		},
+		{
+			name:    "number of subscribers",
+			command: redis.NewPubSubNumSubCommand(pubSub, []string{"news", "sport", "weather"}),
+			response: "*6\r\n$4\r\nnews\r\n:2\r\n$5\r\nsport\r\n:1\r\n" +
+				"$7\r\nweather\r\n:0\r\n",
+		},
+		{
+			name:     "unsubscribe",
+			command:  redis.NewUnsubscribeCommand(pubSub, alice, []string{"news"}),
+			response: "*3\r\n$11\r\nunsubscribe\r\n$4\r\nnews\r\n:1\r\n",
+		},
+		{
+			name:     "publish after unsubscribing",
+			command:  redis.NewPublishCommand(pubSub, "news", "bye"),
+			response: ":1\r\n",
+		},
+		{
+			name:     "unsubscribe from everything",
+			command:  redis.NewUnsubscribeCommand(pubSub, bob, nil),
+			response: "*3\r\n$11\r\nunsubscribe\r\n$4\r\nnews\r\n:0\r\n",
+		},
+		{
+			name:     "unsubscribe without subscriptions",
+			command:  redis.NewUnsubscribeCommand(pubSub, bob, nil),
+			response: "*3\r\n$11\r\nunsubscribe\r\n$-1\r\n:0\r\n",
+		},
+		{
+			name:     "channels without subscribers",
+			command:  redis.NewPubSubChannelsCommand(pubSub, "news"),
+			response: "*0\r\n",
+		},
+		{
+			name:     "reset",
+			command:  redis.NewResetCommand(pubSub, alice),
+			response: "+RESET\r\n",
+		},
+		{
+			name:     "publish after reset",
+			command:  redis.NewPublishCommand(pubSub, "sport", "miss"),
+			response: ":0\r\n",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			if response := test.command.Run(); response != test.response {
+				t.Errorf("command expected to return %#v but was %#v", test.response, response)
+			}
+		})
+	}
+
+	want := message("news", "hello") + message("sport", "goal")
+	if pushes := alice.TakePushes(); pushes != want {
+		t.Errorf("expected alice to be pushed %#v but was %#v", want, pushes)
+	}
+	want = message("news", "hello") + message("news", "bye")
+	if pushes := bob.TakePushes(); pushes != want {
+		t.Errorf("expected bob to be pushed %#v but was %#v", want, pushes)
+	}
+}
+
+// pmessage returns the message that a subscriber to pattern is pushed when message is published
+// to channel.
+func pmessage(pattern, channel, message string) string {
+	return "*4\r\n$8\r\npmessage\r\n" + bulkString(pattern) + bulkString(channel) +
+		bulkString(message)
+}
+
+func TestPSubscribeCommand(t *testing.T) {
+	t.Parallel()
+
+	pubSub := redis.NewPubSub(FakeClock{}, redis.OutputBufferLimit{})
+	clients := redis.NewClients()
+	alice := clients.NewClient()
+	bob := clients.NewClient()
+
+	tests := []struct {
+		name     string
+		command  redis.Command
+		response string
+	}{
+		{
+			name:    "subscribe to patterns",
+			command: redis.NewPSubscribeCommand(pubSub, alice, []string{"news.*", "h?llo"}),
+			response: "*3\r\n$10\r\npsubscribe\r\n$6\r\nnews.*\r\n:1\r\n" +
+				"*3\r\n$10\r\npsubscribe\r\n$5\r\nh?llo\r\n:2\r\n",
+		},
+		{
+			name:     "subscribe to a channel too",
+			command:  redis.NewSubscribeCommand(pubSub, alice, []string{"news.tech"}),
+			response: "*3\r\n$9\r\nsubscribe\r\n$9\r\nnews.tech\r\n:3\r\n",
+		},
+		{
+			name:     "subscribe another client to a pattern",
+			command:  redis.NewPSubscribeCommand(pubSub, bob, []string{"news.*"}),
+			response: "*3\r\n$10\r\npsubscribe\r\n$6\r\nnews.*\r\n:1\r\n",
+		},
+		{
+			name:     "number of patterns",
+			command:  redis.NewPubSubNumPatCommand(pubSub),
+			response: ":2\r\n",
+		},
+		{
+			name:     "publish to a channel and a pattern",
+			command:  redis.NewPublishCommand(pubSub, "news.tech", "go"),
+			response: ":3\r\n",
+		},
+		{
+			name:     "publish to a pattern",
+			command:  redis.NewPublishCommand(pubSub, "hello", "world"),
+			response: ":1\r\n",
+		},
+		{
+			name:     "publish to nothing that matches",
+			command:  redis.NewPublishCommand(pubSub, "news", "none"),
+			response: ":0\r\n",
+		},
+		{
+			name:     "pattern subscriptions aren't channels",
+			command:  redis.NewPubSubChannelsCommand(pubSub, "*"),
+			response: "*1\r\n$9\r\nnews.tech\r\n",
+		},
+		{
+			name:     "unsubscribe from a pattern",
+			command:  redis.NewPUnsubscribeCommand(pubSub, alice, []string{"h?llo"}),
+			response: "*3\r\n$12\r\npunsubscribe\r\n$5\r\nh?llo\r\n:2\r\n",
+		},
+		{
+			name:     "publish to an unsubscribed pattern",
+			command:  redis.NewPublishCommand(pubSub, "hallo", "world"),
+			response: ":0\r\n",
+		},
+		{
+			name:     "unsubscribe from every pattern",
+			command:  redis.NewPUnsubscribeCommand(pubSub, alice, nil),
+			response: "*3\r\n$12\r\npunsubscribe\r\n$6\r\nnews.*\r\n:1\r\n",
+		},
+		{
+			name:     "unsubscribe from every pattern without patterns",
+			command:  redis.NewPUnsubscribeCommand(pubSub, alice, nil),
+			response: "*3\r\n$12\r\npunsubscribe\r\n$-1\r\n:1\r\n",
+		},
+		{
+			name:     "number of patterns after unsubscribing",
+			command:  redis.NewPubSubNumPatCommand(pubSub),
+			response: ":1\r\n",
+		},
+		{
+			name:     "reset",
+			command:  redis.NewResetCommand(pubSub, bob),
+			response: "+RESET\r\n",
+		},
+		{
+			name:     "number of patterns after reset",
+			command:  redis.NewPubSubNumPatCommand(pubSub),
+			response: ":0\r\n",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			if response := test.command.Run(); response != test.response {
+				t.Errorf("command expected to return %#v but was %#v", test.response, response)
+			}
+		})
+	}
+
+	want := message("news.tech", "go") + pmessage("news.*", "news.tech", "go") +
+		pmessage("h?llo", "hello", "world")
+	if pushes := alice.TakePushes(); pushes != want {
+		t.Errorf("expected alice to be pushed %#v but was %#v", want, pushes)
+	}
+	want = pmessage("news.*", "news.tech", "go")
+	if pushes := bob.TakePushes(); pushes != want {
+		t.Errorf("expected bob to be pushed %#v but was %#v", want, pushes)
+	}
+}
+
+// smessage returns the message that a subscriber to a shard channel is pushed when message is
+// published to it.
+func smessage(channel, message string) string {
+	return "*3\r\n$8\r\nsmessage\r\n" + bulkString(channel) + bulkString(message)
+}
+
+func TestSSubscribeCommand(t *testing.T) {
+	t.Parallel()
+
+	pubSub := redis.NewPubSub(FakeClock{}, redis.OutputBufferLimit{})
+	clients := redis.NewClients()
+	alice := clients.NewClient()
+	bob := clients.NewClient()
+
+	tests := []struct {
+		name     string
+		command  redis.Command
+		response string
+	}{
+		{
+			name:    "subscribe to shard channels",
+			command: redis.NewSSubscribeCommand(pubSub, alice, []string{"{user}a", "{user}b"}),
+			response: "*3\r\n$10\r\nssubscribe\r\n$7\r\n{user}a\r\n:1\r\n" +
+				"*3\r\n$10\r\nssubscribe\r\n$7\r\n{user}b\r\n:2\r\n",
+		},
+		{
+			name:     "shard channels are counted apart from channels",
+			command:  redis.NewSubscribeCommand(pubSub, alice, []string{"{user}a"}),
+			response: "*3\r\n$9\r\nsubscribe\r\n$7\r\n{user}a\r\n:1\r\n",
+		},
+		{
+			name:     "subscribe another client to a pattern",
+			command:  redis.NewPSubscribeCommand(pubSub, bob, []string{"*"}),
+			response: "*3\r\n$10\r\npsubscribe\r\n$1\r\n*\r\n:1\r\n",
+		},
+		{
+			name:     "publish to a shard channel",
+			command:  redis.NewSPublishCommand(pubSub, "{user}a", "hello"),
+			response: ":1\r\n",
+		},
+		{
+			name:     "publish to a channel with the same name",
+			command:  redis.NewPublishCommand(pubSub, "{user}a", "hi"),
+			response: ":2\r\n",
+		},
+		{
+			name:     "shard channels",
+			command:  redis.NewPubSubShardChannelsCommand(pubSub, "*"),
+			response: "*2\r\n$7\r\n{user}a\r\n$7\r\n{user}b\r\n",
+		},
+		{
+			name:     "shard channels matching a pattern",
+			command:  redis.NewPubSubShardChannelsCommand(pubSub, "*b"),
+			response: "*1\r\n$7\r\n{user}b\r\n",
+		},
+		{
+			name:     "number of subscribers of shard channels",
+			command:  redis.NewPubSubShardNumSubCommand(pubSub, []string{"{user}a", "{user}c"}),
+			response: "*4\r\n$7\r\n{user}a\r\n:1\r\n$7\r\n{user}c\r\n:0\r\n",
+		},
+		{
+			name:     "unsubscribe from a shard channel",
+			command:  redis.NewSUnsubscribeCommand(pubSub, alice, []string{"{user}a"}),
+			response: "*3\r\n$12\r\nsunsubscribe\r\n$7\r\n{user}a\r\n:1\r\n",
+		},
+		{
+			name:     "unsubscribe from every shard channel",
+			command:  redis.NewSUnsubscribeCommand(pubSub, alice, nil),
+			response: "*3\r\n$12\r\nsunsubscribe\r\n$7\r\n{user}b\r\n:0\r\n",
+		},
+		{
+			name:     "unsubscribe from every shard channel without shard channels",
+			command:  redis.NewSUnsubscribeCommand(pubSub, alice, nil),
+			response: "*3\r\n$12\r\nsunsubscribe\r\n$-1\r\n:0\r\n",
+		},
+		{
+			name:     "still subscribed to the channel",
+			command:  redis.NewPublishCommand(pubSub, "{user}a", "bye"),
+			response: ":2\r\n",
+		},
+		{
+			name:     "publish to a shard channel without subscribers",
+			command:  redis.NewSPublishCommand(pubSub, "{user}a", "bye"),
+			response: ":0\r\n",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			if response := test.command.Run(); response != test.response {
+				t.Errorf("command expected to return %#v but was %#v", test.response, response)
+			}
+		})
+	}
+
+	want := smessage("{user}a", "hello") + message("{user}a", "hi") + message("{user}a", "bye")
+	if pushes := alice.TakePushes(); pushes != want {
+		t.Errorf("expected alice to be pushed %#v but was %#v", want, pushes)
+	}
+	want = pmessage("*", "{user}a", "hi") + pmessage("*", "{user}a", "bye")
+	if pushes := bob.TakePushes(); pushes != want {
+		t.Errorf("expected bob to be pushed %#v but was %#v", want, pushes)
+	}
+}
+
+func TestPublishCommand_ManyPatterns(t *testing.T) {
+	t.Parallel()
+
+	pubSub := redis.NewPubSub(FakeClock{}, redis.OutputBufferLimit{})
+	client := redis.NewClients().NewClient()
+	var patterns []string
+	for i := 0; i < 5000; i++ {
+		patterns = append(patterns, "user:"+strconv.Itoa(i)+":*")
+	}
+	patterns = append(patterns, "*", "user:4?:*", "user:[1-3]:*", `user:\*`)
+	redis.NewPSubscribeCommand(pubSub, client, patterns).Run()
+
+	tests := []struct {
+		channel   string
+		receivers int
+	}{
+		{channel: "user:1:name", receivers: 3},
+		{channel: "user:42:name", receivers: 3},
+		{channel: "user:4999:name", receivers: 2},
+		{channel: "user:5000:name", receivers: 1},
+		{channel: "user:*", receivers: 2},
+		{channel: "user:", receivers: 1},
+		{channel: "", receivers: 1},
+	}
+
+	for _, tt := range tests {
+		command := redis.NewPublishCommand(pubSub, tt.channel, "hi")
+		want := ":" + strconv.Itoa(tt.receivers) + "\r\n"
+		if response := command.Run(); response != want {
+			t.Errorf("expected a message to %#v to have %d receivers but PUBLISH returned %#v",
+				tt.channel, tt.receivers, response)
+		}
+	}
+}
+
+func TestPublishCommand_Pushed(t *testing.T) {
+	t.Parallel()
+
+	pubSub := redis.NewPubSub(FakeClock{}, redis.OutputBufferLimit{})
+	client := redis.NewClients().NewClient()
+	redis.NewSubscribeCommand(pubSub, client, []string{"news"}).Run()
+
+	select {
+	case <-client.Pushed():
+		t.Fatal("expected nothing to be pushed before publishing")
+	default:
+	}
+	redis.NewPublishCommand(pubSub, "news", "a").Run()
+	redis.NewPublishCommand(pubSub, "news", "b").Run()
+	select {
+	case <-client.Pushed():
+	default:
+		t.Fatal("expected Pushed to receive a value after publishing")
+	}
+	if want, pushes := message("news", "a")+message("news", "b"), client.TakePushes(); pushes != want {
+		t.Errorf("expected the client to be pushed %#v but was %#v", want, pushes)
+	}
+	if pushes := client.TakePushes(); pushes != "" {
+		t.Errorf("expected no more pushes but was %#v", pushes)
+	}
+}
+
+func TestPublishCommand_OutputBufferLimit(t *testing.T) {
+	t.Parallel()
+
+	size := len(message("news", "hello"))
+	clock := &FakeClock{CurrentTime: time.Unix(0, 0)}
+	pubSub := redis.NewPubSub(clock, redis.OutputBufferLimit{
+		HardBytes:    size * 4,
+		SoftBytes:    size * 2,
+		SoftDuration: time.Minute,
+	})
+	clients := redis.NewClients()
+	slow := clients.NewClient()
+	soft := clients.NewClient()
+	fast := clients.NewClient()
+	for _, client := range []*redis.Client{slow, soft, fast} {
+		redis.NewSubscribeCommand(pubSub, client, []string{"news"}).Run()
+	}
+	publish := func() {
+		// The publisher never waits for a slow subscriber.
+		redis.NewPublishCommand(pubSub, "news", "hello").Run()
+		fast.TakePushes()
+	}
+
+	publish()
+	publish()
+	soft.TakePushes()
+	publish()
+	publish()
+	// soft has reached the soft limit, but not for long enough to be disconnected.
+	clock.CurrentTime = clock.CurrentTime.Add(time.Minute)
+	publish()
+	assertOpen(t, soft)
+	assertClosed(t, slow)
+	assertOpen(t, fast)
+
+	clock.CurrentTime = clock.CurrentTime.Add(time.Second)
+	publish()
+	assertClosed(t, soft)
+	assertOpen(t, fast)
+
+	// Closed clients are unsubscribed, so the publisher has one receiver left.
+	if response := redis.NewPublishCommand(pubSub, "news", "hello").Run(); response != ":1\r\n" {
+		t.Errorf(`command expected to return ":1\r\n" but was %#v`, response)
+	}
+}
+
+func assertOpen(t *testing.T, client *redis.Client) {
+	t.Helper()
+
+	select {
+	case <-client.Closed():
+		t.Errorf("expected client %d to be open", client.ID())
+	default:
+	}
+}
+
+func assertClosed(t *testing.T, client *redis.Client) {
+	t.Helper()
+
+	select {
+	case <-client.Closed():
+	default:
+		t.Errorf("expected client %d to be closed", client.ID())
+	}
+}
+
+func TestQuitCommand(t *testing.T) {
+	t.Parallel()
+
+	parser := redis.NewParser(zeroValueRedisConfig, redis.NewStore(), FakeClock{})
+	client := parser.NewClient()
+	subscribe, err := parser.ForClient(client).Parse(
+		strings.NewReader("*2\r\n$9\r\nSUBSCRIBE\r\n$4\r\nnews\r\n"),
+	)
+	if err != nil {
+		t.Fatalf("err: expected: nil; got: %v", err)
+	}
+	subscribe.Run()
+
+	if response := redis.NewQuitCommand(client).Run(); response != "+OK\r\n" {
+		t.Errorf(`command expected to return "+OK\r\n" but was %#v`, response)
+	}
+	assertClosed(t, client)
+
+	// Closing the client unsubscribed it.
+	channels, err := parser.Parse(strings.NewReader("*2\r\n$6\r\nPUBSUB\r\n$8\r\nCHANNELS\r\n"))
+	if err != nil {
+		t.Fatalf("err: expected: nil; got: %v", err)
+	}
+	if response := channels.Run(); response != "*0\r\n" {
+		t.Errorf(`PUBSUB CHANNELS expected to return "*0\r\n" but was %#v`, response)
+	}
+}
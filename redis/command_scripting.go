@@ -0,0 +1,157 @@
+package redis
+
+import "github.com/codecrafters-io/redis-starter-go/internal/lua"
+
+const (
+	errNoScript   CommandError = "NOSCRIPT No matching script. Please use EVAL."
+	errNotBusy    CommandError = "NOTBUSY No scripts in execution right now."
+	errUnkillable CommandError = "UNKILLABLE Sorry the script already executed write commands " +
+		"against the dataset. You can either wait the script termination or kill the server in a " +
+		"hard way using the SHUTDOWN NOSAVE command."
+	errBusy CommandError = "BUSY Redis is busy running a script. You can only call SCRIPT KILL " +
+		"or SHUTDOWN NOSAVE."
+	errBusyFunction CommandError = "BUSY Redis is busy running a script. You can only call " +
+		"FUNCTION KILL or SHUTDOWN NOSAVE."
+	errNumKeysTooLarge         CommandError = "ERR Number of keys can't be greater than number of args"
+	errNumKeysNegative         CommandError = "ERR Number of keys can't be negative"
+	errWriteFromReadOnlyScript CommandError = "ERR Write commands are not allowed from read-only " +
+		"scripts."
+)
+
+func NewEvalCommand(parser Parser, script string, keys, args []string) *EvalCommand {
+	return &EvalCommand{
+		parser: parser,
+		script: script,
+		keys:   keys,
+		args:   args,
+	}
+}
+
+// NewEvalShaCommand returns the EvalCommand for EVALSHA, which runs the script in the cache
+// with the SHA1 digest sha.
+func NewEvalShaCommand(parser Parser, sha string, keys, args []string) *EvalCommand {
+	return &EvalCommand{
+		parser: parser,
+		sha:    sha,
+		keys:   keys,
+		args:   args,
+	}
+}
+
+// EvalCommand is EVAL, which runs a Lua script with the names of the keys that it accesses in
+// the table KEYS and its other arguments in ARGV, and replies with what it returns. The script
+// calls commands with redis.call and redis.pcall, and runs atomically, without any other
+// client's commands in between. EVAL adds the script to the cache, for EVALSHA to run again by
+// its SHA1 digest.
+type EvalCommand struct {
+	parser Parser
+	script string
+	// sha is the SHA1 digest of the script to run for EVALSHA.
+	sha  string
+	keys []string
+	args []string
+}
+
+func (e *EvalCommand) Run() string {
+	scripts := e.parser.scripts
+	sha := e.sha
+	var proto *lua.FunctionProto
+	if sha == "" {
+		var err error
+		sha, proto, err = scripts.load(e.script)
+		if err != nil {
+			return errorResponse(err)
+		}
+	} else {
+		var ok bool
+		proto, ok = scripts.get(sha)
+		if !ok {
+			return errorResponse(errNoScript)
+		}
+	}
+	var response string
+	e.parser.store.exclusively(e.parser.clock.NowMonotonic(), func(*storeTx) {
+		response = scripts.runScript(e.parser, sha, proto, e.keys, e.args)
+	})
+	return response
+}
+
+func NewScriptLoadCommand(scripts *Scripts, script string) *ScriptLoadCommand {
+	return &ScriptLoadCommand{
+		scripts: scripts,
+		script:  script,
+	}
+}
+
+// ScriptLoadCommand is SCRIPT LOAD, which compiles a script and adds it to the cache without
+// running it, replying with its SHA1 digest.
+type ScriptLoadCommand struct {
+	scripts *Scripts
+	script  string
+}
+
+func (s *ScriptLoadCommand) Run() string {
+	sha, _, err := s.scripts.load(s.script)
+	if err != nil {
+		return errorResponse(err)
+	}
+	return bulkString(sha)
+}
+
+func NewScriptExistsCommand(scripts *Scripts, shas []string) *ScriptExistsCommand {
+	return &ScriptExistsCommand{
+		scripts: scripts,
+		shas:    shas,
+	}
+}
+
+// ScriptExistsCommand is SCRIPT EXISTS, which replies with 1 for each SHA1 digest of a script in
+// the cache, and 0 for the others.
+type ScriptExistsCommand struct {
+	scripts *Scripts
+	shas    []string
+}
+
+func (s *ScriptExistsCommand) Run() string {
+	elements := make([]string, len(s.shas))
+	for i, sha := range s.shas {
+		if _, ok := s.scripts.get(sha); ok {
+			elements[i] = integer(1)
+		} else {
+			elements[i] = integer(0)
+		}
+	}
+	return array(elements...)
+}
+
+func NewScriptFlushCommand(scripts *Scripts) *ScriptFlushCommand {
+	return &ScriptFlushCommand{scripts: scripts}
+}
+
+// ScriptFlushCommand is SCRIPT FLUSH, which empties the cache.
+type ScriptFlushCommand struct {
+	scripts *Scripts
+}
+
+func (s *ScriptFlushCommand) Run() string {
+	s.scripts.flush()
+	return simpleString("OK")
+}
+
+func NewScriptKillCommand(scripts *Scripts) *ScriptKillCommand {
+	return &ScriptKillCommand{scripts: scripts}
+}
+
+// ScriptKillCommand is SCRIPT KILL, which kills the running script, making it fail, as long as
+// it hasn't written to the keyspace yet. It's the one command that clients can send while a
+// script has been running for too long.
+type ScriptKillCommand struct {
+	scripts *Scripts
+}
+
+func (s *ScriptKillCommand) Run() string {
+	if err := s.scripts.kill(false); err != nil {
+		return errorResponse(err)
+	}
+	return simpleString("OK")
+}
@@ -0,0 +1,471 @@
+package redis_test
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/redis"
+)
+
+// scriptSHA returns the SHA1 digest of script, which EVALSHA runs it by.
+func scriptSHA(script string) string {
+	sum := sha1.Sum([]byte(script))
+	return hex.EncodeToString(sum[:])
+}
+
+// scriptError returns the error reply for message, raised on line of script and not caught.
+func scriptError(script string, line int, message string) string {
+	return fmt.Sprintf("-%s script: %s, on @user_script:%d.\r\n", message, scriptSHA(script), line)
+}
+
+func TestEvalCommand(t *testing.T) {
+	t.Parallel()
+
+	const wrongType = "WRONGTYPE Operation against a key holding the wrong kind of value"
+
+	tests := []struct {
+		name string
+		// requests are sent in order, and want is the response to the last one.
+		requests [][]string
+		want     string
+	}{
+		{
+			name:     "number",
+			requests: [][]string{{"EVAL", "return 1 + 2 * 3", "0"}},
+			want:     ":7\r\n",
+		},
+		{
+			name:     "KEYS and ARGV",
+			requests: [][]string{{"EVAL", "return {KEYS[1], KEYS[2], ARGV[1]}", "2", "a", "b", "c"}},
+			want:     bulkStringArray("a", "b", "c"),
+		},
+		{
+			name: "Lua values are converted to RESP",
+			requests: [][]string{{
+				"EVAL", "return {1, 'two', true, false, 3.99, {ok = 'fine'}, nil, 'after nil'}", "0",
+			}},
+			want: "*6\r\n:1\r\n" + bulkString("two") + ":1\r\n$-1\r\n:3\r\n+fine\r\n",
+		},
+		{
+			name:     "nil",
+			requests: [][]string{{"EVAL", "return nil", "0"}},
+			want:     "$-1\r\n",
+		},
+		{
+			name:     "status reply",
+			requests: [][]string{{"EVAL", "return redis.status_reply('PONG')", "0"}},
+			want:     "+PONG\r\n",
+		},
+		{
+			name:     "error reply",
+			requests: [][]string{{"EVAL", "return redis.error_reply('MY_ERR oops')", "0"}},
+			want:     "-MY_ERR oops\r\n",
+		},
+		{
+			name: "redis.call",
+			requests: [][]string{{
+				"EVAL",
+				"redis.call('SET', KEYS[1], ARGV[1]) return redis.call('GET', KEYS[1])",
+				"1", "key", "value",
+			}},
+			want: bulkString("value"),
+		},
+		{
+			name: "replies are converted to Lua",
+			requests: [][]string{{
+				"EVAL",
+				"local set = redis.call('SET', 'key', 'value') " +
+					"local missing = redis.call('GET', 'missing') " +
+					"local n = redis.call('RPUSH', 'list', 'a', 'b') " +
+					"local list = redis.call('LRANGE', 'list', 0, -1) " +
+					"return {set.ok, tostring(missing), n + 1, list[2], #list}",
+				"0",
+			}},
+			want: "*5\r\n" + bulkString("OK") + bulkString("false") + ":3\r\n" + bulkString("b") +
+				":2\r\n",
+		},
+		{
+			name: "numbers are passed to commands as integers if they are",
+			requests: [][]string{{
+				"EVAL", "redis.call('RPUSH', 'list', 42, 2.5) return redis.call('LRANGE', 'list', 0, -1)",
+				"0",
+			}},
+			want: bulkStringArray("42", "2.5"),
+		},
+		{
+			name: "redis.call raises errors",
+			requests: [][]string{
+				{"SET", "key", "value"},
+				{"EVAL", "return redis.call('LLEN', KEYS[1])", "1", "key"},
+			},
+			want: scriptError("return redis.call('LLEN', KEYS[1])", 1, wrongType),
+		},
+		{
+			name: "redis.pcall returns errors",
+			requests: [][]string{
+				{"SET", "key", "value"},
+				{"EVAL", "local reply = redis.pcall('LLEN', KEYS[1]) return reply.err", "1", "key"},
+			},
+			want: bulkString(wrongType),
+		},
+		{
+			name: "pcall catches redis.call's errors",
+			requests: [][]string{
+				{"SET", "key", "value"},
+				{"EVAL", "local ok, err = pcall(redis.call, 'LLEN', KEYS[1]) return err", "1", "key"},
+			},
+			want: "-" + wrongType + "\r\n",
+		},
+		{
+			name:     "runtime error",
+			requests: [][]string{{"EVAL", "local t = {}\nreturn t.a.b", "0"}},
+			want: scriptError(
+				"local t = {}\nreturn t.a.b", 2,
+				"ERR user_script:2: attempt to index a non-table object(nil) with key 'b'",
+			),
+		},
+		{
+			name:     "error",
+			requests: [][]string{{"EVAL", "error('boom')", "0"}},
+			want:     scriptError("error('boom')", 1, "ERR user_script:1: boom"),
+		},
+		{
+			name:     "compile error",
+			requests: [][]string{{"EVAL", "return +", "0"}},
+			want: "-ERR Error compiling script (new function): user_script:1: " +
+				"syntax error near '+'\r\n",
+		},
+		{
+			name:     "nonexistent global",
+			requests: [][]string{{"EVAL", "return foo", "0"}},
+			want: scriptError(
+				"return foo", 1,
+				"ERR user_script:1: Script attempted to access nonexistent global variable 'foo'",
+			),
+		},
+		{
+			name:     "globals can't be set",
+			requests: [][]string{{"EVAL", "foo = 1", "0"}},
+			want: scriptError(
+				"foo = 1", 1, "ERR user_script:1: Attempt to modify a readonly table",
+			),
+		},
+		{
+			name:     "command that isn't allowed",
+			requests: [][]string{{"EVAL", "return redis.call('MULTI')", "0"}},
+			want: scriptError(
+				"return redis.call('MULTI')", 1, "ERR This Redis command is not allowed from script",
+			),
+		},
+		{
+			name:     "unknown command",
+			requests: [][]string{{"EVAL", "return redis.call('NOPE')", "0"}},
+			want: scriptError(
+				"return redis.call('NOPE')", 1, "ERR Unknown Redis command called from script",
+			),
+		},
+		{
+			name:     "argument that isn't a string or number",
+			requests: [][]string{{"EVAL", "return redis.call('GET', {})", "0"}},
+			want: scriptError(
+				"return redis.call('GET', {})", 1,
+				"ERR Lua redis lib command arguments must be strings or integers",
+			),
+		},
+		{
+			name: "libraries",
+			requests: [][]string{{
+				"EVAL",
+				"local words = {} " +
+					"for word in ARGV[1]:gmatch('%a+') do table.insert(words, word:upper()) end " +
+					"return string.format('%s=%d', table.concat(words, '-'), math.max(1, 5, 3))",
+				"0", "hello, scripting world",
+			}},
+			want: bulkString("HELLO-SCRIPTING-WORLD=5"),
+		},
+		{
+			name: "cjson",
+			requests: [][]string{{
+				"EVAL",
+				"local t = cjson.decode(ARGV[1]) " +
+					"return {cjson.encode({t.a[2], t.b}), tostring(t.c == cjson.null)}",
+				"0", `{"a": [1, "two"], "b": {"c": true}, "c": null}`,
+			}},
+			want: bulkStringArray(`["two",{"c":true}]`, "true"),
+		},
+		{
+			name: "cmsgpack",
+			requests: [][]string{{
+				"EVAL",
+				"local packed = cmsgpack.pack({1, 'two', {three = 3}}) " +
+					"local t = cmsgpack.unpack(packed) " +
+					"return {#packed, t[1], t[2], t[3].three}",
+				"0",
+			}},
+			want: "*4\r\n:14\r\n:1\r\n" + bulkString("two") + ":3\r\n",
+		},
+		{
+			name: "bit",
+			requests: [][]string{{
+				"EVAL",
+				"return {bit.band(0xff, 0x0f), bit.lshift(1, 31), bit.tohex(255), bit.tohex(-1, -4)}",
+				"0",
+			}},
+			want: "*4\r\n:15\r\n:-2147483648\r\n" + bulkString("000000ff") + bulkString("FFFF"),
+		},
+		{
+			name: "struct",
+			requests: [][]string{{
+				"EVAL",
+				"local packed = struct.pack('>i2s', 258, 'hi') " +
+					"return {packed, struct.unpack('>i2s', packed)}",
+				"0",
+			}},
+			want: "*4\r\n" + bulkString("\x01\x02hi\x00") + ":258\r\n" + bulkString("hi") + ":6\r\n",
+		},
+		{
+			name:     "loadstring",
+			requests: [][]string{{"EVAL", "return loadstring('return 1 + 1')()", "0"}},
+			want:     ":2\r\n",
+		},
+		{
+			name:     "string methods",
+			requests: [][]string{{"EVAL", "return tostring(getmetatable('').__index == string)", "0"}},
+			want:     bulkString("true"),
+		},
+		{
+			name: "redis.setresp",
+			requests: [][]string{{
+				"EVAL",
+				"local before = redis.call('GET', 'missing') " +
+					"redis.setresp(3) " +
+					"return {tostring(before), tostring(redis.call('GET', 'missing'))}",
+				"0",
+			}},
+			want: bulkStringArray("false", "nil"),
+		},
+		{
+			name:     "redis.setresp with a version that isn't 2 or 3",
+			requests: [][]string{{"EVAL", "redis.setresp(4)", "0"}},
+			want: scriptError(
+				"redis.setresp(4)", 1, "ERR user_script:1: RESP version must be 2 or 3.",
+			),
+		},
+		{
+			name:     "REDIS_VERSION",
+			requests: [][]string{{"EVAL", "return {redis.REDIS_VERSION, redis.REDIS_VERSION_NUM}", "0"}},
+			want:     "*2\r\n" + bulkString("7.4.0") + ":459776\r\n",
+		},
+		{
+			name:     "blocking commands don't block",
+			requests: [][]string{{"EVAL", "return redis.call('BLPOP', 'list', 0)", "0"}},
+			want:     "$-1\r\n",
+		},
+		{
+			name: "EVAL in a transaction",
+			requests: [][]string{
+				{"MULTI"},
+				{"EVAL", "return redis.call('SET', KEYS[1], 'value')", "1", "key"},
+				{"GET", "key"},
+				{"EXEC"},
+			},
+			want: "*2\r\n+OK\r\n" + bulkString("value"),
+		},
+		{
+			name: "EVALSHA",
+			requests: [][]string{
+				{"EVAL", "return 'cached'", "0"},
+				{"EVALSHA", scriptSHA("return 'cached'"), "0"},
+			},
+			want: bulkString("cached"),
+		},
+		{
+			name: "EVALSHA in upper case",
+			requests: [][]string{
+				{"EVAL", "return 'cached'", "0"},
+				{"EVALSHA", strings.ToUpper(scriptSHA("return 'cached'")), "0"},
+			},
+			want: bulkString("cached"),
+		},
+		{
+			name:     "EVALSHA of a script that isn't cached",
+			requests: [][]string{{"EVALSHA", scriptSHA("return 1"), "0"}},
+			want:     "-NOSCRIPT No matching script. Please use EVAL.\r\n",
+		},
+		{
+			name: "SCRIPT LOAD",
+			requests: [][]string{
+				{"SCRIPT", "LOAD", "return ARGV[1]"},
+				{"EVALSHA", scriptSHA("return ARGV[1]"), "0", "loaded"},
+			},
+			want: bulkString("loaded"),
+		},
+		{
+			name:     "SCRIPT LOAD replies with the digest",
+			requests: [][]string{{"SCRIPT", "LOAD", "return 1"}},
+			want:     bulkString("e0e1f9fabfc9d4800c877a703b823ac0578ff8db"),
+		},
+		{
+			name:     "SCRIPT LOAD of a script that doesn't compile",
+			requests: [][]string{{"SCRIPT", "LOAD", "return ("}},
+			want: "-ERR Error compiling script (new function): user_script:1: " +
+				"syntax error near '<eof>'\r\n",
+		},
+		{
+			name: "SCRIPT EXISTS",
+			requests: [][]string{
+				{"SCRIPT", "LOAD", "return 1"},
+				{"SCRIPT", "EXISTS", scriptSHA("return 1"), scriptSHA("return 2")},
+			},
+			want: "*2\r\n:1\r\n:0\r\n",
+		},
+		{
+			name: "SCRIPT FLUSH",
+			requests: [][]string{
+				{"SCRIPT", "LOAD", "return 1"},
+				{"SCRIPT", "FLUSH"},
+				{"EVALSHA", scriptSHA("return 1"), "0"},
+			},
+			want: "-NOSCRIPT No matching script. Please use EVAL.\r\n",
+		},
+		{
+			name:     "SCRIPT KILL with no script running",
+			requests: [][]string{{"SCRIPT", "KILL"}},
+			want:     "-NOTBUSY No scripts in execution right now.\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser := redis.NewParser(
+				zeroValueRedisConfig, redis.NewStore(), &FakeClock{CurrentTime: time.UnixMilli(0)},
+			)
+			run := requestRunner(t, parser, parser.NewClient())
+
+			var response string
+			for _, request := range tt.requests {
+				response = run(request...)
+			}
+
+			if response != tt.want {
+				t.Errorf("command expected to return %#v but was %#v", tt.want, response)
+			}
+		})
+	}
+}
+
+func TestEvalCommand_WatchedKeys(t *testing.T) {
+	t.Parallel()
+
+	parser := redis.NewParser(
+		zeroValueRedisConfig, redis.NewStore(), &FakeClock{CurrentTime: time.UnixMilli(0)},
+	)
+	watcher := requestRunner(t, parser, parser.NewClient())
+	scripter := requestRunner(t, parser, parser.NewClient())
+
+	watcher("WATCH", "key")
+	scripter("EVAL", "return redis.call('SET', KEYS[1], 'value')", "1", "key")
+	watcher("MULTI")
+	watcher("SET", "other", "value")
+	response := watcher("EXEC")
+
+	if want := "*-1\r\n"; response != want {
+		t.Errorf("command expected to return %#v but was %#v", want, response)
+	}
+}
+
+func TestScriptKillCommand(t *testing.T) {
+	t.Parallel()
+
+	config := &redis.Config{BusyReplyThreshold: 10 * time.Millisecond}
+	parser := redis.NewParser(config, redis.NewStore(), redis.RealClock{})
+	scripter := requestRunner(t, parser, parser.NewClient())
+	other := requestRunner(t, parser, parser.NewClient())
+
+	responses := make(chan string)
+	go func() {
+		responses <- scripter("EVAL", "while true do end", "0")
+	}()
+	// Commands are rejected once the script has been running for longer than the threshold.
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.HasPrefix(other("PING"), "-BUSY") {
+		if time.Now().After(deadline) {
+			t.Fatal("script expected to make the server busy")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	busy := other("GET", "key")
+	killed := other("SCRIPT", "KILL")
+	response := <-responses
+
+	wantBusy := "-BUSY Redis is busy running a script. You can only call SCRIPT KILL or " +
+		"SHUTDOWN NOSAVE.\r\n"
+	if busy != wantBusy {
+		t.Errorf("command expected to return %#v but was %#v", wantBusy, busy)
+	}
+	if want := "+OK\r\n"; killed != want {
+		t.Errorf("command expected to return %#v but was %#v", want, killed)
+	}
+	if want := "-ERR Script killed by user with SCRIPT KILL..."; !strings.HasPrefix(response, want) {
+		t.Errorf("command expected to return %#v but was %#v", want, response)
+	}
+	if pong, want := other("PING"), "+PONG\r\n"; pong != want {
+		t.Errorf("command expected to return %#v but was %#v", want, pong)
+	}
+}
+
+func TestShutdownCommand_BusyScript(t *testing.T) {
+	t.Parallel()
+
+	config := &redis.Config{BusyReplyThreshold: 10 * time.Millisecond}
+	parser := redis.NewParser(config, redis.NewStore(), redis.RealClock{})
+	scripter := requestRunner(t, parser, parser.NewClient())
+	other := requestRunner(t, parser, parser.NewClient())
+
+	responses := make(chan string)
+	go func() {
+		responses <- scripter("EVAL", "redis.call('SET', 'key', 'value') while true do end", "0")
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.HasPrefix(other("PING"), "-BUSY") {
+		if time.Now().After(deadline) {
+			t.Fatal("script expected to make the server busy")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	// A script that has written can't be killed, and the server can only be shut down without
+	// saving.
+	unkillable := other("SCRIPT", "KILL")
+	busy := other("SHUTDOWN")
+	busyExit := shutdownRequested(parser)
+	shutdown := other("SHUTDOWN", "NOSAVE")
+	response := <-responses
+
+	wantUnkillable := "-UNKILLABLE Sorry the script already executed write commands against " +
+		"the dataset. You can either wait the script termination or kill the server in a hard " +
+		"way using the SHUTDOWN NOSAVE command.\r\n"
+	if unkillable != wantUnkillable {
+		t.Errorf("command expected to return %#v but was %#v", wantUnkillable, unkillable)
+	}
+	wantBusy := "-BUSY Redis is busy running a script. You can only call SCRIPT KILL or " +
+		"SHUTDOWN NOSAVE.\r\n"
+	if busy != wantBusy {
+		t.Errorf("command expected to return %#v but was %#v", wantBusy, busy)
+	}
+	if busyExit {
+		t.Error("shutdown expected not to be requested by SHUTDOWN while busy")
+	}
+	if shutdown != "" {
+		t.Errorf("command expected to return %#v but was %#v", "", shutdown)
+	}
+	if !shutdownRequested(parser) {
+		t.Error("shutdown expected to be requested by SHUTDOWN NOSAVE")
+	}
+	if want := "-ERR Script killed by user with SCRIPT KILL..."; !strings.HasPrefix(response, want) {
+		t.Errorf("command expected to return %#v but was %#v", want, response)
+	}
+}
@@ -0,0 +1,716 @@
+package redis
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+)
+
+// createSetForWrite returns the set at key, first creating an empty one if there isn't one.
+func createSetForWrite(tx *storeTx, key string) (*set, error) {
+	value, ok, err := tx.getTyped(key, ValueTypeSet)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		return value.set(), nil
+	}
+	s := newSet()
+	tx.set(key, StoreValue{data: s})
+	return s, nil
+}
+
+// deleteSetIfEmpty deletes the set at key if it no longer has any members.
+func deleteSetIfEmpty(tx *storeTx, key string, s *set) {
+	if s.len() == 0 {
+		tx.deleteEmpty(key)
+	}
+}
+
+func NewSAddCommand(store *Store, clock Clock, config *Config, key string, members []string) *SAddCommand {
+	return &SAddCommand{
+		store:   store,
+		clock:   clock,
+		config:  config,
+		key:     key,
+		members: members,
+	}
+}
+
+type SAddCommand struct {
+	store   *Store
+	clock   Clock
+	config  *Config
+	key     string
+	members []string
+}
+
+func (s *SAddCommand) Run() string {
+	var response string
+	s.store.write(s.clock.NowMonotonic(), func(tx *storeTx) {
+		set, err := createSetForWrite(tx, s.key)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		added := 0
+		for _, member := range s.members {
+			if set.add(member, s.config.Encoding) {
+				added++
+			}
+		}
+		if added > 0 {
+			tx.notify(notifySet, "sadd", s.key)
+		}
+		response = integer(added)
+	})
+	return response
+}
+
+func NewSRemCommand(store *Store, clock Clock, key string, members []string) *SRemCommand {
+	return &SRemCommand{
+		store:   store,
+		clock:   clock,
+		key:     key,
+		members: members,
+	}
+}
+
+type SRemCommand struct {
+	store   *Store
+	clock   Clock
+	key     string
+	members []string
+}
+
+func (s *SRemCommand) Run() string {
+	response := integer(0)
+	s.store.write(s.clock.NowMonotonic(), func(tx *storeTx) {
+		value, ok, err := tx.getTyped(s.key, ValueTypeSet)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		if !ok {
+			return
+		}
+		set := value.set()
+		removed := 0
+		for _, member := range s.members {
+			if set.remove(member) {
+				removed++
+			}
+		}
+		if removed > 0 {
+			tx.notify(notifySet, "srem", s.key)
+		}
+		deleteSetIfEmpty(tx, s.key, set)
+		response = integer(removed)
+	})
+	return response
+}
+
+func NewSMembersCommand(store *Store, clock Clock, key string) *SMembersCommand {
+	return &SMembersCommand{
+		store: store,
+		clock: clock,
+		key:   key,
+	}
+}
+
+type SMembersCommand struct {
+	store *Store
+	clock Clock
+	key   string
+}
+
+func (s *SMembersCommand) Run() string {
+	response := array()
+	s.store.read(s.clock.NowMonotonic(), func(tx *storeTx) {
+		value, ok, err := tx.getTyped(s.key, ValueTypeSet)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		if ok {
+			response = bulkStringArray(value.set().members())
+		}
+	})
+	return response
+}
+
+func NewSIsMemberCommand(store *Store, clock Clock, key, member string) *SIsMemberCommand {
+	return &SIsMemberCommand{
+		store:  store,
+		clock:  clock,
+		key:    key,
+		member: member,
+	}
+}
+
+type SIsMemberCommand struct {
+	store  *Store
+	clock  Clock
+	key    string
+	member string
+}
+
+func (s *SIsMemberCommand) Run() string {
+	response := integer(0)
+	s.store.read(s.clock.NowMonotonic(), func(tx *storeTx) {
+		value, ok, err := tx.getTyped(s.key, ValueTypeSet)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		if ok && value.set().contains(s.member) {
+			response = integer(1)
+		}
+	})
+	return response
+}
+
+func NewSMIsMemberCommand(store *Store, clock Clock, key string, members []string) *SMIsMemberCommand {
+	return &SMIsMemberCommand{
+		store:   store,
+		clock:   clock,
+		key:     key,
+		members: members,
+	}
+}
+
+type SMIsMemberCommand struct {
+	store   *Store
+	clock   Clock
+	key     string
+	members []string
+}
+
+func (s *SMIsMemberCommand) Run() string {
+	var response string
+	s.store.read(s.clock.NowMonotonic(), func(tx *storeTx) {
+		value, ok, err := tx.getTyped(s.key, ValueTypeSet)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		elements := make([]string, len(s.members))
+		for i, member := range s.members {
+			if ok && value.set().contains(member) {
+				elements[i] = integer(1)
+			} else {
+				elements[i] = integer(0)
+			}
+		}
+		response = array(elements...)
+	})
+	return response
+}
+
+func NewSCardCommand(store *Store, clock Clock, key string) *SCardCommand {
+	return &SCardCommand{
+		store: store,
+		clock: clock,
+		key:   key,
+	}
+}
+
+type SCardCommand struct {
+	store *Store
+	clock Clock
+	key   string
+}
+
+func (s *SCardCommand) Run() string {
+	response := integer(0)
+	s.store.read(s.clock.NowMonotonic(), func(tx *storeTx) {
+		value, ok, err := tx.getTyped(s.key, ValueTypeSet)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		if ok {
+			response = integer(value.set().len())
+		}
+	})
+	return response
+}
+
+func NewSPopCommand(store *Store, clock Clock, key string, count *int) *SPopCommand {
+	return &SPopCommand{
+		store: store,
+		clock: clock,
+		key:   key,
+		count: count,
+	}
+}
+
+// SPopCommand is SPOP. If count is set it pops up to that many random members and returns them
+// as an array, otherwise it pops one.
+type SPopCommand struct {
+	store *Store
+	clock Clock
+	key   string
+	count *int
+}
+
+func (s *SPopCommand) Run() string {
+	response := nullBulkString
+	if s.count != nil {
+		response = array()
+	}
+	s.store.write(s.clock.NowMonotonic(), func(tx *storeTx) {
+		value, ok, err := tx.getTyped(s.key, ValueTypeSet)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		if !ok {
+			return
+		}
+		set := value.set()
+		if s.count == nil {
+			member := set.random()
+			set.remove(member)
+			tx.notify(notifySet, "spop", s.key)
+			deleteSetIfEmpty(tx, s.key, set)
+			response = bulkString(member)
+			return
+		}
+
+		popped := set.members()
+		if len(popped) > 0 && *s.count > 0 {
+			tx.notify(notifySet, "spop", s.key)
+		}
+		if *s.count >= len(popped) {
+			tx.deleteEmpty(s.key)
+		} else {
+			rand.Shuffle(len(popped), func(i, j int) {
+				popped[i], popped[j] = popped[j], popped[i]
+			})
+			popped = popped[:*s.count]
+			for _, member := range popped {
+				set.remove(member)
+			}
+		}
+		response = bulkStringArray(popped)
+	})
+	return response
+}
+
+func NewSRandMemberCommand(store *Store, clock Clock, key string, count *int) *SRandMemberCommand {
+	return &SRandMemberCommand{
+		store: store,
+		clock: clock,
+		key:   key,
+		count: count,
+	}
+}
+
+// SRandMemberCommand is SRANDMEMBER. Without a count it returns one random member. With a
+// positive count it returns up to that many distinct members, and with a negative count it
+// returns exactly that many members, which may repeat.
+type SRandMemberCommand struct {
+	store *Store
+	clock Clock
+	key   string
+	count *int
+}
+
+func (s *SRandMemberCommand) Run() string {
+	response := nullBulkString
+	if s.count != nil {
+		response = array()
+	}
+	s.store.read(s.clock.NowMonotonic(), func(tx *storeTx) {
+		value, ok, err := tx.getTyped(s.key, ValueTypeSet)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		if !ok {
+			return
+		}
+		set := value.set()
+		if s.count == nil {
+			response = bulkString(set.random())
+			return
+		}
+
+		members := set.members()
+		var chosen []string
+		if *s.count >= 0 {
+			rand.Shuffle(len(members), func(i, j int) {
+				members[i], members[j] = members[j], members[i]
+			})
+			if *s.count < len(members) {
+				members = members[:*s.count]
+			}
+			chosen = members
+		} else {
+			chosen = make([]string, -*s.count)
+			for i := range chosen {
+				chosen[i] = members[rand.Intn(len(members))]
+			}
+		}
+		response = bulkStringArray(chosen)
+	})
+	return response
+}
+
+func NewSMoveCommand(
+	store *Store,
+	clock Clock,
+	config *Config,
+	source, destination, member string,
+) *SMoveCommand {
+	return &SMoveCommand{
+		store:       store,
+		clock:       clock,
+		config:      config,
+		source:      source,
+		destination: destination,
+		member:      member,
+	}
+}
+
+type SMoveCommand struct {
+	store       *Store
+	clock       Clock
+	config      *Config
+	source      string
+	destination string
+	member      string
+}
+
+func (s *SMoveCommand) Run() string {
+	response := integer(0)
+	s.store.write(s.clock.NowMonotonic(), func(tx *storeTx) {
+		value, ok, err := tx.getTyped(s.source, ValueTypeSet)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		if _, _, err := tx.getTyped(s.destination, ValueTypeSet); err != nil {
+			response = errorResponse(err)
+			return
+		}
+		if !ok || !value.set().contains(s.member) {
+			return
+		}
+		response = integer(1)
+		if s.source == s.destination {
+			return
+		}
+
+		source := value.set()
+		source.remove(s.member)
+		tx.notify(notifySet, "srem", s.source)
+		deleteSetIfEmpty(tx, s.source, source)
+		destination, _ := createSetForWrite(tx, s.destination)
+		if destination.add(s.member, s.config.Encoding) {
+			tx.notify(notifySet, "sadd", s.destination)
+		}
+	})
+	return response
+}
+
+// SetOp is an operation that combines sets.
+type SetOp int
+
+const (
+	SetOpUnion SetOp = iota
+	SetOpInter
+	SetOpDiff
+)
+
+// storeEvent returns the keyspace event of storing the result of o, after the "s" or "z" of its
+// type: unionstore, interstore or diffstore.
+func (o SetOp) storeEvent() string {
+	switch o {
+	case SetOpInter:
+		return "interstore"
+	case SetOpDiff:
+		return "diffstore"
+	}
+	return "unionstore"
+}
+
+// setOpSources returns the sets at keys, with nil for keys that don't exist.
+func setOpSources(tx *storeTx, keys []string) ([]*set, error) {
+	sources := make([]*set, len(keys))
+	for i, key := range keys {
+		value, _, err := tx.getTyped(key, ValueTypeSet)
+		if err != nil {
+			return nil, err
+		}
+		sources[i] = value.set()
+	}
+	return sources, nil
+}
+
+// applySetOp returns the members of the result of op on sources, where nil sources are empty.
+func applySetOp(op SetOp, sources []*set) []string {
+	switch op {
+	case SetOpUnion:
+		var result []string
+		seen := make(map[string]bool)
+		for _, source := range sources {
+			if source == nil {
+				continue
+			}
+			for _, member := range source.members() {
+				if !seen[member] {
+					seen[member] = true
+					result = append(result, member)
+				}
+			}
+		}
+		return result
+	case SetOpInter:
+		return setIntersection(sources, 0)
+	case SetOpDiff:
+		if sources[0] == nil {
+			return nil
+		}
+		var result []string
+		for _, member := range sources[0].members() {
+			if !containedInAny(sources[1:], member) {
+				result = append(result, member)
+			}
+		}
+		return result
+	}
+	panic(fmt.Sprintf("unknown redis.SetOp: %d", op))
+}
+
+// setIntersection returns the members common to every one of sources, stopping once it's found
+// limit of them if limit isn't 0. It's empty if any source is nil.
+//
+// It checks the members of the smallest source against the others, smallest first, so that the
+// cost depends on the smallest set rather than the largest.
+func setIntersection(sources []*set, limit int) []string {
+	sorted := make([]*set, len(sources))
+	copy(sorted, sources)
+	for _, source := range sorted {
+		if source == nil {
+			return nil
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].len() < sorted[j].len()
+	})
+
+	var result []string
+	for _, member := range sorted[0].members() {
+		inAll := true
+		for _, other := range sorted[1:] {
+			if !other.contains(member) {
+				inAll = false
+				break
+			}
+		}
+		if !inAll {
+			continue
+		}
+		result = append(result, member)
+		if limit != 0 && len(result) == limit {
+			break
+		}
+	}
+	return result
+}
+
+func containedInAny(sets []*set, member string) bool {
+	for _, s := range sets {
+		if s != nil && s.contains(member) {
+			return true
+		}
+	}
+	return false
+}
+
+func NewSetOpCommand(store *Store, clock Clock, op SetOp, keys []string) *SetOpCommand {
+	return &SetOpCommand{
+		store: store,
+		clock: clock,
+		op:    op,
+		keys:  keys,
+	}
+}
+
+// SetOpCommand is SUNION, SINTER or SDIFF, depending on its op.
+type SetOpCommand struct {
+	store *Store
+	clock Clock
+	op    SetOp
+	keys  []string
+}
+
+func (s *SetOpCommand) Run() string {
+	var response string
+	s.store.read(s.clock.NowMonotonic(), func(tx *storeTx) {
+		sources, err := setOpSources(tx, s.keys)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		response = bulkStringArray(applySetOp(s.op, sources))
+	})
+	return response
+}
+
+func NewSetOpStoreCommand(
+	store *Store,
+	clock Clock,
+	config *Config,
+	op SetOp,
+	destination string,
+	keys []string,
+) *SetOpStoreCommand {
+	return &SetOpStoreCommand{
+		store:       store,
+		clock:       clock,
+		config:      config,
+		op:          op,
+		destination: destination,
+		keys:        keys,
+	}
+}
+
+// SetOpStoreCommand is SUNIONSTORE, SINTERSTORE or SDIFFSTORE, depending on its op. It replaces
+// destination with the result, whatever its type, or deletes it if the result is empty.
+type SetOpStoreCommand struct {
+	store       *Store
+	clock       Clock
+	config      *Config
+	op          SetOp
+	destination string
+	keys        []string
+}
+
+func (s *SetOpStoreCommand) Run() string {
+	var response string
+	s.store.write(s.clock.NowMonotonic(), func(tx *storeTx) {
+		sources, err := setOpSources(tx, s.keys)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		members := applySetOp(s.op, sources)
+		response = integer(len(members))
+		if len(members) == 0 {
+			if tx.delete(s.destination) {
+				tx.notify(notifyGeneric, "del", s.destination)
+			}
+			return
+		}
+		tx.set(s.destination, StoreValue{data: newSetWithMembers(members, s.config.Encoding)})
+		tx.notify(notifySet, "s"+s.op.storeEvent(), s.destination)
+	})
+	return response
+}
+
+func NewSInterCardCommand(store *Store, clock Clock, keys []string, limit int) *SInterCardCommand {
+	return &SInterCardCommand{
+		store: store,
+		clock: clock,
+		keys:  keys,
+		limit: limit,
+	}
+}
+
+// SInterCardCommand is SINTERCARD. It counts up to limit members of the intersection, or all of
+// them if limit is 0.
+type SInterCardCommand struct {
+	store *Store
+	clock Clock
+	keys  []string
+	limit int
+}
+
+func (s *SInterCardCommand) Run() string {
+	var response string
+	s.store.read(s.clock.NowMonotonic(), func(tx *storeTx) {
+		sources, err := setOpSources(tx, s.keys)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		response = integer(len(setIntersection(sources, s.limit)))
+	})
+	return response
+}
+
+func NewSScanCommand(
+	store *Store,
+	clock Clock,
+	key string,
+	cursor uint64,
+	options ...func(*SScanCommand),
+) *SScanCommand {
+	result := &SScanCommand{
+		store:  store,
+		clock:  clock,
+		key:    key,
+		cursor: cursor,
+		count:  defaultScanCount,
+	}
+	for _, option := range options {
+		option(result)
+	}
+	return result
+}
+
+// SScanCommand is SSCAN. Like Redis, it returns the whole of an intset at once, whatever the
+// cursor and count.
+type SScanCommand struct {
+	store   *Store
+	clock   Clock
+	key     string
+	cursor  uint64
+	pattern *string
+	count   int
+}
+
+func SScanMatch(pattern string) func(*SScanCommand) {
+	return func(command *SScanCommand) {
+		command.pattern = &pattern
+	}
+}
+
+func SScanCount(count int) func(*SScanCommand) {
+	return func(command *SScanCommand) {
+		command.count = count
+	}
+}
+
+func (s *SScanCommand) Run() string {
+	response := array(bulkString("0"), array())
+	s.store.read(s.clock.NowMonotonic(), func(tx *storeTx) {
+		value, ok, err := tx.getTyped(s.key, ValueTypeSet)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		if !ok {
+			return
+		}
+		set := value.set()
+		members := set.members()
+		var next uint64
+		if set.table != nil {
+			members, next = scanPage(members, s.cursor, s.count)
+		}
+
+		var elements []string
+		for _, member := range members {
+			if s.pattern == nil || globMatch(*s.pattern, member) {
+				elements = append(elements, bulkString(member))
+			}
+		}
+		response = array(bulkString(strconv.FormatUint(next, 10)), array(elements...))
+	})
+	return response
+}
@@ -0,0 +1,478 @@
+package redis_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/codecrafters-io/redis-starter-go/redis"
+)
+
+func TestSetCommands(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{}
+
+	response := redis.NewSAddCommand(store, clock, defaultEncodingRedisConfig, "set", []string{"3", "1", "2", "1"}).Run()
+	if response != ":3\r\n" {
+		t.Errorf(`SADD expected to return ":3\r\n" but was %#v`, response)
+	}
+
+	tests := []struct {
+		name     string
+		command  redis.Command
+		response string
+	}{
+		{
+			name:     "SMEMBERS intset is sorted",
+			command:  redis.NewSMembersCommand(store, clock, "set"),
+			response: "*3\r\n$1\r\n1\r\n$1\r\n2\r\n$1\r\n3\r\n",
+		},
+		{name: "SMEMBERS absent", command: redis.NewSMembersCommand(store, clock, "nope"), response: "*0\r\n"},
+		{name: "SISMEMBER", command: redis.NewSIsMemberCommand(store, clock, "set", "2"), response: ":1\r\n"},
+		{name: "SISMEMBER absent member", command: redis.NewSIsMemberCommand(store, clock, "set", "4"), response: ":0\r\n"},
+		{name: "SISMEMBER non-integer", command: redis.NewSIsMemberCommand(store, clock, "set", "02"), response: ":0\r\n"},
+		{name: "SISMEMBER absent key", command: redis.NewSIsMemberCommand(store, clock, "nope", "2"), response: ":0\r\n"},
+		{
+			name:     "SMISMEMBER",
+			command:  redis.NewSMIsMemberCommand(store, clock, "set", []string{"1", "a", "3"}),
+			response: "*3\r\n:1\r\n:0\r\n:1\r\n",
+		},
+		{
+			name:     "SMISMEMBER absent key",
+			command:  redis.NewSMIsMemberCommand(store, clock, "nope", []string{"1"}),
+			response: "*1\r\n:0\r\n",
+		},
+		{name: "SCARD", command: redis.NewSCardCommand(store, clock, "set"), response: ":3\r\n"},
+		{name: "SCARD absent", command: redis.NewSCardCommand(store, clock, "nope"), response: ":0\r\n"},
+		{name: "SPOP absent", command: redis.NewSPopCommand(store, clock, "nope", nil), response: redisNullBulkString},
+		{name: "SPOP count absent", command: redis.NewSPopCommand(store, clock, "nope", ptr(2)), response: "*0\r\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if response := tt.command.Run(); response != tt.response {
+				t.Errorf(`command expected to return %#v but was %#v`, tt.response, response)
+			}
+		})
+	}
+}
+
+func TestSRemCommand(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{}
+	parser := redis.NewParser(defaultEncodingRedisConfig, store, clock)
+	keysWith(t, parser, []string{"SADD", "set", "a", "b"})
+
+	if response := redis.NewSRemCommand(store, clock, "set", []string{"a", "c"}).Run(); response != ":1\r\n" {
+		t.Errorf(`command expected to return ":1\r\n" but was %#v`, response)
+	}
+	if response := redis.NewSRemCommand(store, clock, "set", []string{"b"}).Run(); response != ":1\r\n" {
+		t.Errorf(`command expected to return ":1\r\n" but was %#v`, response)
+	}
+	if _, ok := store.Get("set"); ok {
+		t.Errorf(`store expected not to contain "set" once its last member was removed`)
+	}
+}
+
+func TestSetCommands_WrongType(t *testing.T) {
+	t.Parallel()
+
+	const wrongType = "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n"
+	store := redis.NewStore()
+	store.Set("string", "zelda")
+	clock := FakeClock{}
+	parser := redis.NewParser(defaultEncodingRedisConfig, store, clock)
+	keysWith(t, parser, []string{"SADD", "set", "a"})
+
+	tests := []struct {
+		name    string
+		command redis.Command
+	}{
+		{name: "SADD", command: redis.NewSAddCommand(store, clock, defaultEncodingRedisConfig, "string", []string{"a"})},
+		{name: "SREM", command: redis.NewSRemCommand(store, clock, "string", []string{"a"})},
+		{name: "SMEMBERS", command: redis.NewSMembersCommand(store, clock, "string")},
+		{name: "SISMEMBER", command: redis.NewSIsMemberCommand(store, clock, "string", "a")},
+		{name: "SCARD", command: redis.NewSCardCommand(store, clock, "string")},
+		{name: "SPOP", command: redis.NewSPopCommand(store, clock, "string", nil)},
+		{name: "SRANDMEMBER", command: redis.NewSRandMemberCommand(store, clock, "string", nil)},
+		{
+			name:    "SMOVE destination",
+			command: redis.NewSMoveCommand(store, clock, defaultEncodingRedisConfig, "set", "string", "a"),
+		},
+		{
+			name:    "SINTER after a missing key",
+			command: redis.NewSetOpCommand(store, clock, redis.SetOpInter, []string{"nope", "string"}),
+		},
+		{
+			name: "SUNIONSTORE",
+			command: redis.NewSetOpStoreCommand(
+				store, clock, defaultEncodingRedisConfig, redis.SetOpUnion, "dest", []string{"set", "string"},
+			),
+		},
+		{name: "SINTERCARD", command: redis.NewSInterCardCommand(store, clock, []string{"set", "string"}, 0)},
+		{name: "SSCAN", command: redis.NewSScanCommand(store, clock, "string", 0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if response := tt.command.Run(); response != wrongType {
+				t.Errorf(`command expected to return %#v but was %#v`, wrongType, response)
+			}
+		})
+	}
+
+	if response := redis.NewSMembersCommand(store, clock, "set").Run(); response != "*1\r\n$1\r\na\r\n" {
+		t.Errorf(`SMOVE expected to leave "set" unchanged but it was %#v`, response)
+	}
+}
+
+func TestSAddCommand_Encoding(t *testing.T) {
+	t.Parallel()
+
+	config := &redis.Config{Encoding: redis.EncodingConfig{SetMaxIntsetEntries: 2}}
+	tests := []struct {
+		name     string
+		members  []string
+		encoding string
+	}{
+		{name: "integers", members: []string{"1", "-2"}, encoding: "intset"},
+		{name: "too many integers", members: []string{"1", "2", "3"}, encoding: "hashtable"},
+		{name: "not an integer", members: []string{"1", "a"}, encoding: "hashtable"},
+		{name: "not a canonical integer", members: []string{"01"}, encoding: "hashtable"},
+		{name: "too large for an integer", members: []string{"9223372036854775808"}, encoding: "hashtable"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := redis.NewStore()
+			clock := FakeClock{}
+
+			redis.NewSAddCommand(store, clock, config, "set", tt.members).Run()
+
+			response := redis.NewObjectEncodingCommand(store, clock, "set").Run()
+			if want := "$" + strconv.Itoa(len(tt.encoding)) + "\r\n" + tt.encoding + "\r\n"; response != want {
+				t.Errorf(`OBJECT ENCODING expected to return %#v but was %#v`, want, response)
+			}
+			response = redis.NewSMembersCommand(store, clock, "set").Run()
+			if members := bulkStrings(t, response); !equalSorted(members, tt.members) {
+				t.Errorf(`SMEMBERS expected to return %#v but was %#v`, tt.members, response)
+			}
+		})
+	}
+
+	t.Run("never converts back", func(t *testing.T) {
+		store := redis.NewStore()
+		clock := FakeClock{}
+		redis.NewSAddCommand(store, clock, config, "set", []string{"1", "a"}).Run()
+
+		redis.NewSRemCommand(store, clock, "set", []string{"a"}).Run()
+
+		if response := redis.NewObjectEncodingCommand(store, clock, "set").Run(); response != "$9\r\nhashtable\r\n" {
+			t.Errorf(`OBJECT ENCODING expected to return "$9\r\nhashtable\r\n" but was %#v`, response)
+		}
+		if response := redis.NewSIsMemberCommand(store, clock, "set", "1").Run(); response != ":1\r\n" {
+			t.Errorf(`SISMEMBER expected to return ":1\r\n" but was %#v`, response)
+		}
+	})
+}
+
+func TestSPopCommand(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{}
+	parser := redis.NewParser(defaultEncodingRedisConfig, store, clock)
+	keysWith(t, parser, []string{"SADD", "set", "a", "b", "c", "d"})
+
+	response := redis.NewSPopCommand(store, clock, "set", nil).Run()
+	popped := bulkStrings(t, "*1\r\n"+response)
+	response = redis.NewSPopCommand(store, clock, "set", ptr(2)).Run()
+	popped = append(popped, bulkStrings(t, response)...)
+	if len(popped) != 3 {
+		t.Fatalf(`SPOP expected to pop 3 members but popped %#v`, popped)
+	}
+
+	response = redis.NewSPopCommand(store, clock, "set", ptr(5)).Run()
+	popped = append(popped, bulkStrings(t, response)...)
+	if !equalSorted(popped, []string{"a", "b", "c", "d"}) {
+		t.Errorf(`SPOP expected to pop every member once but popped %#v`, popped)
+	}
+	if _, ok := store.Get("set"); ok {
+		t.Errorf(`store expected not to contain "set" once its last member was popped`)
+	}
+}
+
+func TestSRandMemberCommand(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{}
+	parser := redis.NewParser(defaultEncodingRedisConfig, store, clock)
+	keysWith(t, parser, []string{"SADD", "set", "a", "b", "c"})
+
+	response := redis.NewSRandMemberCommand(store, clock, "set", nil).Run()
+	if response != "$1\r\na\r\n" && response != "$1\r\nb\r\n" && response != "$1\r\nc\r\n" {
+		t.Errorf(`command expected to return a member but was %#v`, response)
+	}
+
+	response = redis.NewSRandMemberCommand(store, clock, "set", ptr(5)).Run()
+	if members := bulkStrings(t, response); !equalSorted(members, []string{"a", "b", "c"}) {
+		t.Errorf(`command expected to return every member once but was %#v`, response)
+	}
+
+	response = redis.NewSRandMemberCommand(store, clock, "set", ptr(-5)).Run()
+	if members := bulkStrings(t, response); len(members) != 5 {
+		t.Errorf(`command expected to return 5 members but was %#v`, response)
+	}
+
+	if response := redis.NewSCardCommand(store, clock, "set").Run(); response != ":3\r\n" {
+		t.Errorf(`SRANDMEMBER expected to leave the set unchanged but SCARD returned %#v`, response)
+	}
+	if response := redis.NewSRandMemberCommand(store, clock, "nope", nil).Run(); response != redisNullBulkString {
+		t.Errorf(`command expected to return %#v but was %#v`, redisNullBulkString, response)
+	}
+	if response := redis.NewSRandMemberCommand(store, clock, "nope", ptr(2)).Run(); response != "*0\r\n" {
+		t.Errorf(`command expected to return "*0\r\n" but was %#v`, response)
+	}
+}
+
+func TestSMoveCommand(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{}
+	parser := redis.NewParser(defaultEncodingRedisConfig, store, clock)
+	keysWith(t, parser, []string{"SADD", "source", "a", "b"})
+
+	tests := []struct {
+		name        string
+		source      string
+		destination string
+		member      string
+		response    string
+		want        map[string]string
+	}{
+		{
+			name:        "to a new set",
+			source:      "source",
+			destination: "destination",
+			member:      "a",
+			response:    ":1\r\n",
+			want:        map[string]string{"source": "*1\r\n$1\r\nb\r\n", "destination": "*1\r\n$1\r\na\r\n"},
+		},
+		{
+			name:        "absent member",
+			source:      "source",
+			destination: "destination",
+			member:      "c",
+			response:    ":0\r\n",
+			want:        map[string]string{"source": "*1\r\n$1\r\nb\r\n", "destination": "*1\r\n$1\r\na\r\n"},
+		},
+		{
+			name:        "to the same set",
+			source:      "source",
+			destination: "source",
+			member:      "b",
+			response:    ":1\r\n",
+			want:        map[string]string{"source": "*1\r\n$1\r\nb\r\n"},
+		},
+		{
+			name:        "last member",
+			source:      "source",
+			destination: "destination",
+			member:      "b",
+			response:    ":1\r\n",
+			want:        map[string]string{"source": "*0\r\n"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := redis.NewSMoveCommand(
+				store, clock, defaultEncodingRedisConfig, tt.source, tt.destination, tt.member,
+			).Run()
+
+			if response != tt.response {
+				t.Errorf(`command expected to return %#v but was %#v`, tt.response, response)
+			}
+			for key, want := range tt.want {
+				if response := redis.NewSMembersCommand(store, clock, key).Run(); response != want {
+					t.Errorf(`SMEMBERS %s expected to return %#v but was %#v`, key, want, response)
+				}
+			}
+		})
+	}
+
+	if response := redis.NewSCardCommand(store, clock, "destination").Run(); response != ":2\r\n" {
+		t.Errorf(`SCARD expected to return ":2\r\n" but was %#v`, response)
+	}
+}
+
+func TestSetOpCommand(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{}
+	parser := redis.NewParser(defaultEncodingRedisConfig, store, clock)
+	keysWith(t, parser,
+		[]string{"SADD", "a", "1", "2", "3", "x"},
+		[]string{"SADD", "b", "2", "3", "4", "x"},
+		[]string{"SADD", "c", "3", "x", "y"},
+	)
+
+	tests := []struct {
+		name string
+		op   redis.SetOp
+		keys []string
+		want []string
+	}{
+		{name: "SUNION", op: redis.SetOpUnion, keys: []string{"a", "b", "c"}, want: []string{"1", "2", "3", "4", "x", "y"}},
+		{name: "SUNION absent key", op: redis.SetOpUnion, keys: []string{"nope", "c"}, want: []string{"3", "x", "y"}},
+		{name: "SINTER", op: redis.SetOpInter, keys: []string{"a", "b", "c"}, want: []string{"3", "x"}},
+		{name: "SINTER absent key", op: redis.SetOpInter, keys: []string{"a", "nope"}, want: nil},
+		{name: "SDIFF", op: redis.SetOpDiff, keys: []string{"a", "b"}, want: []string{"1"}},
+		{name: "SDIFF absent key", op: redis.SetOpDiff, keys: []string{"a", "nope", "c"}, want: []string{"1", "2"}},
+		{name: "SDIFF absent first key", op: redis.SetOpDiff, keys: []string{"nope", "a"}, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := redis.NewSetOpCommand(store, clock, tt.op, tt.keys).Run()
+			if members := bulkStrings(t, response); !equalSorted(members, tt.want) {
+				t.Errorf(`command expected to return %#v but was %#v`, tt.want, response)
+			}
+
+			destination := "store:" + tt.name
+			response = redis.NewSetOpStoreCommand(
+				store, clock, defaultEncodingRedisConfig, tt.op, destination, tt.keys,
+			).Run()
+			if want := ":" + strconv.Itoa(len(tt.want)) + "\r\n"; response != want {
+				t.Errorf(`STORE command expected to return %#v but was %#v`, want, response)
+			}
+			response = redis.NewSMembersCommand(store, clock, destination).Run()
+			if members := bulkStrings(t, response); !equalSorted(members, tt.want) {
+				t.Errorf(`SMEMBERS expected to return %#v but was %#v`, tt.want, response)
+			}
+		})
+	}
+}
+
+func TestSetOpStoreCommand(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{}
+	parser := redis.NewParser(defaultEncodingRedisConfig, store, clock)
+	store.Set("string", "zelda")
+	keysWith(t, parser,
+		[]string{"SADD", "a", "1", "2"},
+		[]string{"SADD", "b", "3"},
+	)
+
+	response := redis.NewSetOpStoreCommand(
+		store, clock, defaultEncodingRedisConfig, redis.SetOpUnion, "string", []string{"a", "b"},
+	).Run()
+	if response != ":3\r\n" {
+		t.Errorf(`command expected to return ":3\r\n" but was %#v`, response)
+	}
+	if response := redis.NewObjectEncodingCommand(store, clock, "string").Run(); response != "$6\r\nintset\r\n" {
+		t.Errorf(`command expected to replace "string" with an intset but OBJECT ENCODING returned %#v`, response)
+	}
+
+	response = redis.NewSetOpStoreCommand(
+		store, clock, defaultEncodingRedisConfig, redis.SetOpInter, "string", []string{"a", "b"},
+	).Run()
+	if response != ":0\r\n" {
+		t.Errorf(`command expected to return ":0\r\n" but was %#v`, response)
+	}
+	if _, ok := store.Get("string"); ok {
+		t.Errorf(`store expected not to contain "string" once an empty result was stored there`)
+	}
+}
+
+func TestSInterCardCommand(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{}
+	parser := redis.NewParser(defaultEncodingRedisConfig, store, clock)
+	keysWith(t, parser,
+		[]string{"SADD", "a", "1", "2", "3", "4"},
+		[]string{"SADD", "b", "2", "3", "4", "5"},
+	)
+
+	tests := []struct {
+		name     string
+		keys     []string
+		limit    int
+		response string
+	}{
+		{name: "no limit", keys: []string{"a", "b"}, limit: 0, response: ":3\r\n"},
+		{name: "limit", keys: []string{"a", "b"}, limit: 2, response: ":2\r\n"},
+		{name: "limit above cardinality", keys: []string{"a", "b"}, limit: 10, response: ":3\r\n"},
+		{name: "absent key", keys: []string{"a", "nope"}, limit: 0, response: ":0\r\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if response := redis.NewSInterCardCommand(store, clock, tt.keys, tt.limit).Run(); response != tt.response {
+				t.Errorf(`command expected to return %#v but was %#v`, tt.response, response)
+			}
+		})
+	}
+}
+
+func TestSScanCommand(t *testing.T) {
+	t.Parallel()
+
+	t.Run("intset", func(t *testing.T) {
+		store := redis.NewStore()
+		clock := FakeClock{}
+		parser := redis.NewParser(defaultEncodingRedisConfig, store, clock)
+		keysWith(t, parser, []string{"SADD", "set", "30", "10", "20"})
+
+		response := redis.NewSScanCommand(store, clock, "set", 0, redis.SScanCount(1)).Run()
+		if want := "*2\r\n$1\r\n0\r\n*3\r\n$2\r\n10\r\n$2\r\n20\r\n$2\r\n30\r\n"; response != want {
+			t.Errorf(`command expected to return %#v but was %#v`, want, response)
+		}
+		response = redis.NewSScanCommand(store, clock, "set", 0, redis.SScanMatch("2*")).Run()
+		if want := "*2\r\n$1\r\n0\r\n*1\r\n$2\r\n20\r\n"; response != want {
+			t.Errorf(`command expected to return %#v but was %#v`, want, response)
+		}
+	})
+
+	t.Run("hashtable", func(t *testing.T) {
+		store := redis.NewStore()
+		clock := FakeClock{}
+		parser := redis.NewParser(defaultEncodingRedisConfig, store, clock)
+		var members []string
+		for i := 0; i < 100; i++ {
+			members = append(members, "member:"+strconv.Itoa(i))
+		}
+		keysWith(t, parser, append([]string{"SADD", "set"}, members...))
+
+		var seen []string
+		var cursor uint64
+		for calls := 0; ; calls++ {
+			if calls > 100 {
+				t.Fatalf("SSCAN expected to finish")
+			}
+			elements := bulkStrings(t, redis.NewSScanCommand(store, clock, "set", cursor, redis.SScanCount(7)).Run())
+			seen = append(seen, elements[1:]...)
+			if elements[0] == "0" {
+				break
+			}
+			var err error
+			cursor, err = strconv.ParseUint(elements[0], 10, 64)
+			if err != nil {
+				t.Fatalf("SSCAN returned invalid cursor %#v", elements[0])
+			}
+		}
+		if !equalSorted(seen, members) {
+			t.Errorf("SSCAN expected to return every member once but returned %#v", seen)
+		}
+	})
+}
@@ -0,0 +1,400 @@
+package redis
+
+// getCountMinSketch returns the Count-Min sketch at key, or errCMSMissing if there isn't one.
+func getCountMinSketch(tx *storeTx, key string) (*countMinSketch, error) {
+	value, ok, err := tx.getTyped(key, ValueTypeCMS)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errCMSMissing
+	}
+	return value.cms(), nil
+}
+
+func NewCMSInitCommand(store *Store, clock Clock, key string, width, depth int) *CMSInitCommand {
+	return &CMSInitCommand{
+		store: store,
+		clock: clock,
+		key:   key,
+		width: width,
+		depth: depth,
+	}
+}
+
+// CMSInitCommand is CMS.INITBYDIM or CMS.INITBYPROB, which create an empty Count-Min sketch.
+type CMSInitCommand struct {
+	store *Store
+	clock Clock
+	key   string
+	width int
+	depth int
+}
+
+func (c *CMSInitCommand) Run() string {
+	var response string
+	c.store.write(c.clock.NowMonotonic(), func(tx *storeTx) {
+		if _, ok := tx.get(c.key); ok {
+			response = errorResponse(errCMSExists)
+			return
+		}
+		tx.set(c.key, StoreValue{data: newCountMinSketch(c.width, c.depth)})
+		tx.notify(notifyModule, "cms.init", c.key)
+		response = simpleString("OK")
+	})
+	return response
+}
+
+// CMSIncrement is an item of CMS.INCRBY and how much to increment its count by.
+type CMSIncrement struct {
+	Item      string
+	Increment int64
+}
+
+func NewCMSIncrByCommand(
+	store *Store,
+	clock Clock,
+	key string,
+	increments []CMSIncrement,
+) *CMSIncrByCommand {
+	return &CMSIncrByCommand{
+		store:      store,
+		clock:      clock,
+		key:        key,
+		increments: increments,
+	}
+}
+
+// CMSIncrByCommand is CMS.INCRBY, which increments the counts of items in a Count-Min sketch
+// and replies with their new counts.
+type CMSIncrByCommand struct {
+	store      *Store
+	clock      Clock
+	key        string
+	increments []CMSIncrement
+}
+
+func (c *CMSIncrByCommand) Run() string {
+	var response string
+	c.store.write(c.clock.NowMonotonic(), func(tx *storeTx) {
+		sketch, err := getCountMinSketch(tx, c.key)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		elements := make([]string, len(c.increments))
+		for i, increment := range c.increments {
+			elements[i] = integer(int(sketch.incrBy(increment.Item, increment.Increment)))
+		}
+		tx.notify(notifyModule, "cms.incrby", c.key)
+		response = array(elements...)
+	})
+	return response
+}
+
+func NewCMSQueryCommand(store *Store, clock Clock, key string, items []string) *CMSQueryCommand {
+	return &CMSQueryCommand{
+		store: store,
+		clock: clock,
+		key:   key,
+		items: items,
+	}
+}
+
+// CMSQueryCommand is CMS.QUERY, which replies with the counts of items in a Count-Min sketch.
+type CMSQueryCommand struct {
+	store *Store
+	clock Clock
+	key   string
+	items []string
+}
+
+func (c *CMSQueryCommand) Run() string {
+	var response string
+	c.store.read(c.clock.NowMonotonic(), func(tx *storeTx) {
+		sketch, err := getCountMinSketch(tx, c.key)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		elements := make([]string, len(c.items))
+		for i, item := range c.items {
+			elements[i] = integer(int(sketch.query(item)))
+		}
+		response = array(elements...)
+	})
+	return response
+}
+
+func NewCMSMergeCommand(
+	store *Store,
+	clock Clock,
+	destination string,
+	sources []string,
+	weights []int64,
+) *CMSMergeCommand {
+	return &CMSMergeCommand{
+		store:       store,
+		clock:       clock,
+		destination: destination,
+		sources:     sources,
+		weights:     weights,
+	}
+}
+
+// CMSMergeCommand is CMS.MERGE, which replaces the counts of a Count-Min sketch with the sum of
+// the counts of others, each multiplied by its weight. All the sketches must already exist and
+// have the same dimensions.
+type CMSMergeCommand struct {
+	store       *Store
+	clock       Clock
+	destination string
+	sources     []string
+	weights     []int64
+}
+
+func (c *CMSMergeCommand) Run() string {
+	var response string
+	c.store.write(c.clock.NowMonotonic(), func(tx *storeTx) {
+		destination, err := getCountMinSketch(tx, c.destination)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		sources := make([]*countMinSketch, len(c.sources))
+		for i, key := range c.sources {
+			if sources[i], err = getCountMinSketch(tx, key); err != nil {
+				response = errorResponse(err)
+				return
+			}
+			if sources[i].width != destination.width || sources[i].depth != destination.depth {
+				response = errorResponse(errCMSDimensions)
+				return
+			}
+		}
+		destination.merge(sources, c.weights)
+		tx.notify(notifyModule, "cms.merge", c.destination)
+		response = simpleString("OK")
+	})
+	return response
+}
+
+func NewCMSInfoCommand(store *Store, clock Clock, key string) *CMSInfoCommand {
+	return &CMSInfoCommand{
+		store: store,
+		clock: clock,
+		key:   key,
+	}
+}
+
+// CMSInfoCommand is CMS.INFO, which replies with the dimensions and total count of a Count-Min
+// sketch.
+type CMSInfoCommand struct {
+	store *Store
+	clock Clock
+	key   string
+}
+
+func (c *CMSInfoCommand) Run() string {
+	var response string
+	c.store.read(c.clock.NowMonotonic(), func(tx *storeTx) {
+		sketch, err := getCountMinSketch(tx, c.key)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		response = array(
+			simpleString("width"), integer(sketch.width),
+			simpleString("depth"), integer(sketch.depth),
+			simpleString("count"), integer(int(sketch.count)),
+		)
+	})
+	return response
+}
+
+// getTopK returns the Top-K at key, or errTopKMissing if there isn't one.
+func getTopK(tx *storeTx, key string) (*topK, error) {
+	value, ok, err := tx.getTyped(key, ValueTypeTopK)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errTopKMissing
+	}
+	return value.topK(), nil
+}
+
+func NewTopKReserveCommand(
+	store *Store,
+	clock Clock,
+	key string,
+	k int,
+	width int,
+	depth int,
+	decay float64,
+) *TopKReserveCommand {
+	return &TopKReserveCommand{
+		store: store,
+		clock: clock,
+		key:   key,
+		k:     k,
+		width: width,
+		depth: depth,
+		decay: decay,
+	}
+}
+
+// TopKReserveCommand is TOPK.RESERVE, which creates an empty Top-K.
+type TopKReserveCommand struct {
+	store *Store
+	clock Clock
+	key   string
+	k     int
+	width int
+	depth int
+	decay float64
+}
+
+func (t *TopKReserveCommand) Run() string {
+	var response string
+	t.store.write(t.clock.NowMonotonic(), func(tx *storeTx) {
+		if _, ok := tx.get(t.key); ok {
+			response = errorResponse(errTopKExists)
+			return
+		}
+		tx.set(t.key, StoreValue{data: newTopK(t.k, t.width, t.depth, t.decay)})
+		tx.notify(notifyModule, "topk.reserve", t.key)
+		response = simpleString("OK")
+	})
+	return response
+}
+
+func NewTopKAddCommand(store *Store, clock Clock, key string, items []string) *TopKAddCommand {
+	return &TopKAddCommand{
+		store: store,
+		clock: clock,
+		key:   key,
+		items: items,
+	}
+}
+
+// TopKAddCommand is TOPK.ADD, which adds items to a Top-K and replies with an array of the item
+// that each one expelled from the top k, or null if it didn't expel one.
+type TopKAddCommand struct {
+	store *Store
+	clock Clock
+	key   string
+	items []string
+}
+
+func (t *TopKAddCommand) Run() string {
+	var response string
+	t.store.write(t.clock.NowMonotonic(), func(tx *storeTx) {
+		topK, err := getTopK(tx, t.key)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		elements := make([]string, len(t.items))
+		for i, item := range t.items {
+			if expelled, ok := topK.add(item, 1); ok {
+				elements[i] = bulkString(expelled)
+			} else {
+				elements[i] = nullBulkString
+			}
+		}
+		tx.notify(notifyModule, "topk.add", t.key)
+		response = array(elements...)
+	})
+	return response
+}
+
+func NewTopKListCommand(
+	store *Store,
+	clock Clock,
+	key string,
+	options ...func(*TopKListCommand),
+) *TopKListCommand {
+	result := &TopKListCommand{
+		store: store,
+		clock: clock,
+		key:   key,
+	}
+	for _, option := range options {
+		option(result)
+	}
+	return result
+}
+
+// TopKListCommand is TOPK.LIST, which replies with the top k items, highest count first.
+type TopKListCommand struct {
+	store     *Store
+	clock     Clock
+	key       string
+	withCount bool
+}
+
+func TopKListWithCount() func(*TopKListCommand) {
+	return func(command *TopKListCommand) {
+		command.withCount = true
+	}
+}
+
+func (t *TopKListCommand) Run() string {
+	var response string
+	t.store.read(t.clock.NowMonotonic(), func(tx *storeTx) {
+		topK, err := getTopK(tx, t.key)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		var elements []string
+		for _, item := range topK.list() {
+			elements = append(elements, bulkString(item.item))
+			if t.withCount {
+				elements = append(elements, integer(int(item.count)))
+			}
+		}
+		response = array(elements...)
+	})
+	return response
+}
+
+func NewTopKQueryCommand(store *Store, clock Clock, key string, items []string) *TopKQueryCommand {
+	return &TopKQueryCommand{
+		store: store,
+		clock: clock,
+		key:   key,
+		items: items,
+	}
+}
+
+// TopKQueryCommand is TOPK.QUERY, which replies with an array of whether each item is in the
+// top k.
+type TopKQueryCommand struct {
+	store *Store
+	clock Clock
+	key   string
+	items []string
+}
+
+func (t *TopKQueryCommand) Run() string {
+	var response string
+	t.store.read(t.clock.NowMonotonic(), func(tx *storeTx) {
+		topK, err := getTopK(tx, t.key)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		elements := make([]string, len(t.items))
+		for i, item := range t.items {
+			if topK.contains(item) {
+				elements[i] = integer(1)
+			} else {
+				elements[i] = integer(0)
+			}
+		}
+		response = array(elements...)
+	})
+	return response
+}
@@ -0,0 +1,237 @@
+package redis_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/codecrafters-io/redis-starter-go/redis"
+)
+
+func TestCMSIncrByCommand(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	store.Set("string", "zelda")
+	clock := FakeClock{}
+	redis.NewCMSInitCommand(store, clock, "cms", 2000, 5).Run()
+	redis.NewCMSInitCommand(store, clock, "other", 2000, 5).Run()
+	redis.NewCMSInitCommand(store, clock, "merged", 2000, 5).Run()
+	redis.NewCMSInitCommand(store, clock, "narrow", 10, 5).Run()
+
+	tests := []struct {
+		name     string
+		command  redis.Command
+		response string
+	}{
+		{
+			name:     "init existing key",
+			command:  redis.NewCMSInitCommand(store, clock, "cms", 10, 10),
+			response: "-ERR CMS: key already exists\r\n",
+		},
+		{
+			name: "increment",
+			command: redis.NewCMSIncrByCommand(store, clock, "cms", []redis.CMSIncrement{
+				{Item: "a", Increment: 5},
+				{Item: "b", Increment: 3},
+				{Item: "a", Increment: 2},
+			}),
+			response: "*3\r\n:5\r\n:3\r\n:7\r\n",
+		},
+		{
+			name:     "query",
+			command:  redis.NewCMSQueryCommand(store, clock, "cms", []string{"a", "b", "c"}),
+			response: "*3\r\n:7\r\n:3\r\n:0\r\n",
+		},
+		{
+			name: "increment other",
+			command: redis.NewCMSIncrByCommand(store, clock, "other", []redis.CMSIncrement{
+				{Item: "a", Increment: 1},
+				{Item: "c", Increment: 4},
+			}),
+			response: "*2\r\n:1\r\n:4\r\n",
+		},
+		{
+			name: "merge",
+			command: redis.NewCMSMergeCommand(
+				store, clock, "merged", []string{"cms", "other"}, []int64{1, 2},
+			),
+			response: "+OK\r\n",
+		},
+		{
+			name:     "query merged",
+			command:  redis.NewCMSQueryCommand(store, clock, "merged", []string{"a", "b", "c"}),
+			response: "*3\r\n:9\r\n:3\r\n:8\r\n",
+		},
+		{
+			name:     "info",
+			command:  redis.NewCMSInfoCommand(store, clock, "merged"),
+			response: "*6\r\n+width\r\n:2000\r\n+depth\r\n:5\r\n+count\r\n:20\r\n",
+		},
+		{
+			name: "merge into itself",
+			command: redis.NewCMSMergeCommand(
+				store, clock, "merged", []string{"merged", "merged"}, []int64{1, 1},
+			),
+			response: "+OK\r\n",
+		},
+		{
+			name:     "query merged into itself",
+			command:  redis.NewCMSQueryCommand(store, clock, "merged", []string{"a"}),
+			response: "*1\r\n:18\r\n",
+		},
+		{
+			name: "merge different dimensions",
+			command: redis.NewCMSMergeCommand(
+				store, clock, "narrow", []string{"cms"}, []int64{1},
+			),
+			response: "-ERR CMS: width/depth is not equal\r\n",
+		},
+		{
+			name: "merge missing source",
+			command: redis.NewCMSMergeCommand(
+				store, clock, "merged", []string{"missing"}, []int64{1},
+			),
+			response: "-ERR CMS: key does not exist\r\n",
+		},
+		{
+			name:     "query missing key",
+			command:  redis.NewCMSQueryCommand(store, clock, "missing", []string{"a"}),
+			response: "-ERR CMS: key does not exist\r\n",
+		},
+		{
+			name: "increment missing key",
+			command: redis.NewCMSIncrByCommand(store, clock, "missing", []redis.CMSIncrement{
+				{Item: "a", Increment: 1},
+			}),
+			response: "-ERR CMS: key does not exist\r\n",
+		},
+		{
+			name:     "wrong type",
+			command:  redis.NewCMSQueryCommand(store, clock, "string", []string{"a"}),
+			response: "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			if response := test.command.Run(); response != test.response {
+				t.Errorf("command expected to return %#v but was %#v", test.response, response)
+			}
+		})
+	}
+}
+
+func TestCMSQueryCommand_Overestimates(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{}
+	redis.NewCMSInitCommand(store, clock, "cms", 100, 4).Run()
+	var increments []redis.CMSIncrement
+	for i := 0; i < 1000; i++ {
+		increments = append(increments, redis.CMSIncrement{Item: strconv.Itoa(i), Increment: 1})
+	}
+	redis.NewCMSIncrByCommand(store, clock, "cms", increments).Run()
+
+	for i := 0; i < 1000; i++ {
+		response := redis.NewCMSQueryCommand(store, clock, "cms", []string{strconv.Itoa(i)}).Run()
+		count, err := strconv.Atoi(response[len("*1\r\n:") : len(response)-2])
+		if err != nil || count < 1 {
+			t.Fatalf("expected a count of at least 1 for %d but CMS.QUERY returned %#v", i, response)
+		}
+	}
+}
+
+func TestTopKAddCommand(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	store.Set("string", "zelda")
+	clock := FakeClock{}
+	redis.NewTopKReserveCommand(store, clock, "topk", 2, 50, 5, 0.9).Run()
+
+	tests := []struct {
+		name     string
+		command  redis.Command
+		response string
+	}{
+		{
+			name:     "reserve existing key",
+			command:  redis.NewTopKReserveCommand(store, clock, "topk", 2, 8, 7, 0.9),
+			response: "-ERR TopK: key already exists\r\n",
+		},
+		{
+			name:     "add",
+			command:  redis.NewTopKAddCommand(store, clock, "topk", []string{"a", "b", "a"}),
+			response: "*3\r\n$-1\r\n$-1\r\n$-1\r\n",
+		},
+		{
+			name:     "add beyond k",
+			command:  redis.NewTopKAddCommand(store, clock, "topk", []string{"c", "c", "c"}),
+			response: "*3\r\n$-1\r\n$1\r\nb\r\n$-1\r\n",
+		},
+		{
+			name:     "list",
+			command:  redis.NewTopKListCommand(store, clock, "topk"),
+			response: "*2\r\n$1\r\nc\r\n$1\r\na\r\n",
+		},
+		{
+			name:     "list with counts",
+			command:  redis.NewTopKListCommand(store, clock, "topk", redis.TopKListWithCount()),
+			response: "*4\r\n$1\r\nc\r\n:3\r\n$1\r\na\r\n:2\r\n",
+		},
+		{
+			name:     "query",
+			command:  redis.NewTopKQueryCommand(store, clock, "topk", []string{"a", "b", "c"}),
+			response: "*3\r\n:1\r\n:0\r\n:1\r\n",
+		},
+		{
+			name:     "list of missing key",
+			command:  redis.NewTopKListCommand(store, clock, "empty"),
+			response: "-ERR TopK: key does not exist\r\n",
+		},
+		{
+			name:     "add to missing key",
+			command:  redis.NewTopKAddCommand(store, clock, "missing", []string{"a"}),
+			response: "-ERR TopK: key does not exist\r\n",
+		},
+		{
+			name:     "wrong type",
+			command:  redis.NewTopKQueryCommand(store, clock, "string", []string{"a"}),
+			response: "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			if response := test.command.Run(); response != test.response {
+				t.Errorf("command expected to return %#v but was %#v", test.response, response)
+			}
+		})
+	}
+}
+
+func TestTopKAddCommand_HeavyHitters(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{}
+	redis.NewTopKReserveCommand(store, clock, "topk", 3, 8, 7, 0.9).Run()
+
+	// Three heavy hitters among a long tail of items seen once.
+	var items []string
+	for i := 0; i < 1000; i++ {
+		items = append(items, "tail:"+strconv.Itoa(i))
+		if i%10 == 0 {
+			items = append(items, "x", "y", "z")
+		}
+	}
+	redis.NewTopKAddCommand(store, clock, "topk", items).Run()
+
+	response := redis.NewTopKListCommand(store, clock, "topk").Run()
+	if got := bulkStrings(t, response); !equalSorted(got, []string{"x", "y", "z"}) {
+		t.Errorf("expected the top 3 to be x, y and z but was %#v", got)
+	}
+}
@@ -0,0 +1,460 @@
+package redis
+
+import "time"
+
+const (
+	errStreamIDNotGreater CommandError = "ERR The ID specified in XADD is equal or smaller than the " +
+		"target stream top item"
+
+	errStreamExhausted CommandError = "ERR The stream has exhausted the last possible ID, unable to " +
+		"add more items"
+)
+
+// streamEntriesResponse returns entries as an array of their IDs, each with an array of its
+// field names and values.
+func streamEntriesResponse(entries []streamEntry) string {
+	elements := make([]string, len(entries))
+	for i, entry := range entries {
+		elements[i] = streamEntryResponse(entry)
+	}
+	return array(elements...)
+}
+
+// streamEntryResponse returns entry as an array of its ID and an array of its field names and
+// values.
+func streamEntryResponse(entry streamEntry) string {
+	return array(bulkString(entry.id.String()), bulkStringArray(entry.fields))
+}
+
+// XAddID is the ID argument of XADD: either an ID for the new entry, or "*" or "<ms>-*" to have
+// the stream generate all or part of one.
+type XAddID struct {
+	ID StreamID
+	// Auto generates the whole ID from the current time, like "*".
+	Auto bool
+	// AutoSeq generates the sequence number of an ID with the milliseconds of ID, like "<ms>-*".
+	AutoSeq bool
+}
+
+// generate returns the ID for a new entry in s when the Unix time in milliseconds is nowMs, or
+// an error if it wouldn't be greater than the stream's last ID.
+func (x XAddID) generate(s *stream, nowMs uint64) (StreamID, error) {
+	switch {
+	case x.Auto:
+		if nowMs > s.lastID.Ms {
+			return StreamID{Ms: nowMs}, nil
+		}
+		id, ok := s.lastID.next()
+		if !ok {
+			return StreamID{}, errStreamExhausted
+		}
+		return id, nil
+	case x.AutoSeq:
+		switch {
+		case x.ID.Ms > s.lastID.Ms:
+			return StreamID{Ms: x.ID.Ms}, nil
+		case x.ID.Ms == s.lastID.Ms && s.lastID.Seq < maxStreamID.Seq:
+			return StreamID{Ms: x.ID.Ms, Seq: s.lastID.Seq + 1}, nil
+		}
+		return StreamID{}, errStreamIDNotGreater
+	}
+	if !s.lastID.less(x.ID) {
+		return StreamID{}, errStreamIDNotGreater
+	}
+	return x.ID, nil
+}
+
+func NewXAddCommand(
+	store *Store,
+	clock Clock,
+	key string,
+	id XAddID,
+	fields []string,
+	options ...func(*XAddCommand),
+) *XAddCommand {
+	result := &XAddCommand{
+		store:  store,
+		clock:  clock,
+		key:    key,
+		id:     id,
+		fields: fields,
+	}
+	for _, option := range options {
+		option(result)
+	}
+	return result
+}
+
+type XAddCommand struct {
+	store  *Store
+	clock  Clock
+	key    string
+	id     XAddID
+	fields []string
+	// noMkStream is NOMKSTREAM, which doesn't create the stream if it doesn't exist.
+	noMkStream bool
+	trim       *StreamTrim
+}
+
+func XAddNoMkStream() func(*XAddCommand) {
+	return func(command *XAddCommand) {
+		command.noMkStream = true
+	}
+}
+
+// XAddTrim trims the stream after adding the entry, like the MAXLEN and MINID arguments.
+func XAddTrim(trim StreamTrim) func(*XAddCommand) {
+	return func(command *XAddCommand) {
+		command.trim = &trim
+	}
+}
+
+func (x *XAddCommand) Run() string {
+	var response string
+	x.store.write(x.clock.NowMonotonic(), func(tx *storeTx) {
+		value, ok, err := tx.getTyped(x.key, ValueTypeStream)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		if !ok && x.noMkStream {
+			response = nullBulkString
+			return
+		}
+
+		stream := value.stream()
+		if !ok {
+			stream = newStream()
+		}
+		id, err := x.id.generate(stream, uint64(x.clock.Now().UnixMilli()))
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		if !ok {
+			tx.set(x.key, StoreValue{data: stream})
+		}
+		stream.add(id, x.fields)
+		tx.notify(notifyStream, "xadd", x.key)
+		if x.trim != nil && stream.trim(*x.trim) > 0 {
+			tx.notify(notifyStream, "xtrim", x.key)
+		}
+		tx.signalKeyAsReady(x.key)
+		response = bulkString(id.String())
+	})
+	return response
+}
+
+func NewXTrimCommand(store *Store, clock Clock, key string, trim StreamTrim) *XTrimCommand {
+	return &XTrimCommand{
+		store: store,
+		clock: clock,
+		key:   key,
+		trim:  trim,
+	}
+}
+
+type XTrimCommand struct {
+	store *Store
+	clock Clock
+	key   string
+	trim  StreamTrim
+}
+
+func (x *XTrimCommand) Run() string {
+	response := integer(0)
+	x.store.write(x.clock.NowMonotonic(), func(tx *storeTx) {
+		value, ok, err := tx.getTyped(x.key, ValueTypeStream)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		if !ok {
+			return
+		}
+		trimmed := value.stream().trim(x.trim)
+		if trimmed > 0 {
+			tx.notify(notifyStream, "xtrim", x.key)
+		}
+		response = integer(trimmed)
+	})
+	return response
+}
+
+func NewXRangeCommand(
+	store *Store,
+	clock Clock,
+	key string,
+	start, end StreamID,
+	options ...func(*XRangeCommand),
+) *XRangeCommand {
+	result := &XRangeCommand{
+		store: store,
+		clock: clock,
+		key:   key,
+		start: start,
+		end:   end,
+		count: -1,
+	}
+	for _, option := range options {
+		option(result)
+	}
+	return result
+}
+
+// XRangeCommand is XRANGE, or XREVRANGE if it's reversed. Its start and end are inclusive, with
+// start being the lesser ID even when it's reversed.
+type XRangeCommand struct {
+	store   *Store
+	clock   Clock
+	key     string
+	start   StreamID
+	end     StreamID
+	reverse bool
+	// count is the most entries to return, or unlimited if it's negative.
+	count int
+}
+
+func XRangeReverse() func(*XRangeCommand) {
+	return func(command *XRangeCommand) {
+		command.reverse = true
+	}
+}
+
+func XRangeCount(count int) func(*XRangeCommand) {
+	return func(command *XRangeCommand) {
+		command.count = count
+	}
+}
+
+func (x *XRangeCommand) Run() string {
+	if x.count == 0 {
+		return nullArray
+	}
+	response := array()
+	x.store.read(x.clock.NowMonotonic(), func(tx *storeTx) {
+		value, ok, err := tx.getTyped(x.key, ValueTypeStream)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		if ok {
+			entries := value.stream().entries(x.start, x.end, x.count, x.reverse)
+			response = streamEntriesResponse(entries)
+		}
+	})
+	return response
+}
+
+func NewXLenCommand(store *Store, clock Clock, key string) *XLenCommand {
+	return &XLenCommand{
+		store: store,
+		clock: clock,
+		key:   key,
+	}
+}
+
+type XLenCommand struct {
+	store *Store
+	clock Clock
+	key   string
+}
+
+func (x *XLenCommand) Run() string {
+	response := integer(0)
+	x.store.read(x.clock.NowMonotonic(), func(tx *storeTx) {
+		value, ok, err := tx.getTyped(x.key, ValueTypeStream)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		if ok {
+			response = integer(value.stream().len())
+		}
+	})
+	return response
+}
+
+func NewXDelCommand(store *Store, clock Clock, key string, ids []StreamID) *XDelCommand {
+	return &XDelCommand{
+		store: store,
+		clock: clock,
+		key:   key,
+		ids:   ids,
+	}
+}
+
+type XDelCommand struct {
+	store *Store
+	clock Clock
+	key   string
+	ids   []StreamID
+}
+
+func (x *XDelCommand) Run() string {
+	response := integer(0)
+	x.store.write(x.clock.NowMonotonic(), func(tx *storeTx) {
+		value, ok, err := tx.getTyped(x.key, ValueTypeStream)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		if !ok {
+			return
+		}
+		deleted := 0
+		for _, id := range x.ids {
+			if value.stream().remove(id) {
+				deleted++
+			}
+		}
+		if deleted > 0 {
+			tx.notify(notifyStream, "xdel", x.key)
+		}
+		response = integer(deleted)
+	})
+	return response
+}
+
+// XReadID is where XREAD reads a stream from, which is after ID unless New or Last is true.
+type XReadID struct {
+	ID StreamID
+	// New reads only entries added after XREAD starts, like "$".
+	New bool
+	// Last reads from the stream's last entry, like "+".
+	Last bool
+}
+
+func NewXReadCommand(
+	store *Store,
+	clock Clock,
+	client *Client,
+	keys []string,
+	ids []XReadID,
+	options ...func(*XReadCommand),
+) *XReadCommand {
+	result := &XReadCommand{
+		store:  store,
+		clock:  clock,
+		client: client,
+		keys:   keys,
+		ids:    ids,
+	}
+	for _, option := range options {
+		option(result)
+	}
+	return result
+}
+
+type XReadCommand struct {
+	store  *Store
+	clock  Clock
+	client *Client
+	keys   []string
+	ids    []XReadID
+	// count is the most entries to read from each stream, or unlimited if it's not positive.
+	count int
+	// block is how long to wait for entries if there aren't any, forever if it's zero, or nil
+	// to not wait at all.
+	block *time.Duration
+}
+
+func XReadCount(count int) func(*XReadCommand) {
+	return func(command *XReadCommand) {
+		command.count = count
+	}
+}
+
+func XReadBlock(timeout time.Duration) func(*XReadCommand) {
+	return func(command *XReadCommand) {
+		command.block = &timeout
+	}
+}
+
+func (x *XReadCommand) Run() string {
+	// after holds the ID to read each stream after, once the IDs that depend on the streams,
+	// like "$", are resolved.
+	var after []StreamID
+	readAll := func(tx *storeTx) (string, bool, error) {
+		var err error
+		if after, err = x.resolveIDs(tx); err != nil {
+			return "", false, err
+		}
+		var elements []string
+		for i, key := range x.keys {
+			response, ok, err := x.read(tx, key, after[i])
+			if err != nil {
+				return "", false, err
+			}
+			if ok {
+				elements = append(elements, response)
+			}
+		}
+		return array(elements...), len(elements) > 0, nil
+	}
+
+	if x.block == nil {
+		response := nullArray
+		x.store.read(x.clock.NowMonotonic(), func(tx *storeTx) {
+			r, ok, err := readAll(tx)
+			if err != nil {
+				response = errorResponse(err)
+			} else if ok {
+				response = r
+			}
+		})
+		return response
+	}
+	serve := func(tx *storeTx, key string) (string, bool, error) {
+		for i := range x.keys {
+			if x.keys[i] == key {
+				response, ok, err := x.read(tx, key, after[i])
+				return array(response), ok, err
+			}
+		}
+		return "", false, nil
+	}
+	return blockUnlessServed(
+		x.store, x.clock, x.client, x.keys, *x.block, nullArray, readAll, serve,
+	)
+}
+
+// resolveIDs returns the ID to read each stream after.
+func (x *XReadCommand) resolveIDs(tx *storeTx) ([]StreamID, error) {
+	after := make([]StreamID, len(x.ids))
+	for i, key := range x.keys {
+		value, ok, err := tx.getTyped(key, ValueTypeStream)
+		if err != nil {
+			return nil, err
+		}
+		after[i] = x.ids[i].ID
+		switch {
+		case !ok:
+		case x.ids[i].New:
+			after[i] = value.stream().lastID
+		case x.ids[i].Last:
+			if last, ok := value.stream().last(); ok {
+				after[i], _ = last.id.prev()
+			}
+		}
+	}
+	return after, nil
+}
+
+// read returns the key and entries of the stream at key with IDs greater than after, or false
+// if there aren't any.
+func (x *XReadCommand) read(tx *storeTx, key string, after StreamID) (string, bool, error) {
+	value, ok, err := tx.getTyped(key, ValueTypeStream)
+	if err != nil || !ok {
+		return "", false, err
+	}
+	start, ok := after.next()
+	if !ok {
+		return "", false, nil
+	}
+	entries := value.stream().entries(start, maxStreamID, x.count, false)
+	if len(entries) == 0 {
+		return "", false, nil
+	}
+	return array(bulkString(key), streamEntriesResponse(entries)), true, nil
+}
@@ -0,0 +1,1167 @@
+package redis
+
+import (
+	"fmt"
+	"time"
+)
+
+const errXGroupKeyMissing CommandError = "ERR The XGROUP subcommand requires the key to exist. " +
+	"Note that for CREATE you may want to use the MKSTREAM option to create an empty stream " +
+	"automatically."
+
+// noGroupError is the error for a group that doesn't exist, or whose stream doesn't.
+func noGroupError(key, group string) error {
+	return CommandError(fmt.Sprintf("NOGROUP No such key '%s' or consumer group '%s'", key, group))
+}
+
+// noGroupForKeyError is the error that XGROUP and XINFO give for a group that doesn't exist.
+func noGroupForKeyError(key, group string) error {
+	return CommandError(
+		fmt.Sprintf("NOGROUP No such consumer group '%s' for key name '%s'", group, key),
+	)
+}
+
+// getGroup returns the stream at key and its group named group, or false if either doesn't
+// exist.
+func getGroup(tx *storeTx, key, group string) (*stream, *streamGroup, bool, error) {
+	value, ok, err := tx.getTyped(key, ValueTypeStream)
+	if err != nil || !ok {
+		return nil, nil, false, err
+	}
+	g, ok := value.stream().groups[group]
+	return value.stream(), g, ok, nil
+}
+
+// idleMilliseconds returns how long it's been since t at now in milliseconds.
+func idleMilliseconds(now, t time.Time) int {
+	return int(now.Sub(t).Milliseconds())
+}
+
+// XGroupID is where a consumer group starts reading a stream from, which is after ID unless
+// New is true.
+type XGroupID struct {
+	ID StreamID
+	// New starts after the stream's last entry, like "$".
+	New bool
+}
+
+// resolve returns the ID that id stands for in s, which may be nil.
+func (id XGroupID) resolve(s *stream) StreamID {
+	if !id.New {
+		return id.ID
+	}
+	if s == nil {
+		return StreamID{}
+	}
+	return s.lastID
+}
+
+func NewXGroupCreateCommand(
+	store *Store,
+	clock Clock,
+	key, group string,
+	id XGroupID,
+	options ...func(*XGroupCreateCommand),
+) *XGroupCreateCommand {
+	result := &XGroupCreateCommand{
+		store:       store,
+		clock:       clock,
+		key:         key,
+		group:       group,
+		id:          id,
+		entriesRead: -1,
+	}
+	for _, option := range options {
+		option(result)
+	}
+	return result
+}
+
+type XGroupCreateCommand struct {
+	store *Store
+	clock Clock
+	key   string
+	group string
+	id    XGroupID
+	// mkStream is MKSTREAM, which creates an empty stream if it doesn't exist.
+	mkStream bool
+	// entriesRead is how many of the stream's entries the group has read, or -1 if unknown.
+	entriesRead int
+}
+
+func XGroupCreateMkStream() func(*XGroupCreateCommand) {
+	return func(command *XGroupCreateCommand) {
+		command.mkStream = true
+	}
+}
+
+func XGroupCreateEntriesRead(entriesRead int) func(*XGroupCreateCommand) {
+	return func(command *XGroupCreateCommand) {
+		command.entriesRead = entriesRead
+	}
+}
+
+func (x *XGroupCreateCommand) Run() string {
+	response := simpleString("OK")
+	x.store.write(x.clock.NowMonotonic(), func(tx *storeTx) {
+		value, ok, err := tx.getTyped(x.key, ValueTypeStream)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		if !ok && !x.mkStream {
+			response = errorResponse(errXGroupKeyMissing)
+			return
+		}
+		s := value.stream()
+		if !ok {
+			s = newStream()
+			tx.set(x.key, StoreValue{data: s})
+		}
+		if !s.createGroup(x.group, x.id.resolve(s), x.entriesRead) {
+			response = errorResponse(CommandError("BUSYGROUP Consumer Group name already exists"))
+			return
+		}
+		tx.notify(notifyStream, "xgroup-create", x.key)
+	})
+	return response
+}
+
+func NewXGroupSetIDCommand(
+	store *Store,
+	clock Clock,
+	key, group string,
+	id XGroupID,
+	entriesRead int,
+) *XGroupSetIDCommand {
+	return &XGroupSetIDCommand{
+		store:       store,
+		clock:       clock,
+		key:         key,
+		group:       group,
+		id:          id,
+		entriesRead: entriesRead,
+	}
+}
+
+type XGroupSetIDCommand struct {
+	store *Store
+	clock Clock
+	key   string
+	group string
+	id    XGroupID
+	// entriesRead is how many of the stream's entries the group has read, or -1 if unknown.
+	entriesRead int
+}
+
+func (x *XGroupSetIDCommand) Run() string {
+	response := simpleString("OK")
+	x.store.write(x.clock.NowMonotonic(), func(tx *storeTx) {
+		response = runXGroupCommand(tx, x.key, x.group, func(s *stream, group *streamGroup) string {
+			group.lastID = x.id.resolve(s)
+			group.entriesRead = x.entriesRead
+			tx.notify(notifyStream, "xgroup-setid", x.key)
+			return simpleString("OK")
+		})
+	})
+	return response
+}
+
+// groupConsumer returns group's consumer named name, like streamGroup.consumer, notifying the
+// xgroup-createconsumer event of the stream at key if it's created.
+func groupConsumer(
+	tx *storeTx,
+	key string,
+	group *streamGroup,
+	name string,
+	now time.Time,
+) *streamConsumer {
+	if _, ok := group.consumers[name]; !ok {
+		tx.notify(notifyStream, "xgroup-createconsumer", key)
+	}
+	return group.consumer(name, now)
+}
+
+// runXGroupCommand runs an XGROUP subcommand other than CREATE on the group named group of the
+// stream at key, returning fn's response or an error if either doesn't exist.
+func runXGroupCommand(
+	tx *storeTx,
+	key, group string,
+	fn func(s *stream, group *streamGroup) string,
+) string {
+	value, ok, err := tx.getTyped(key, ValueTypeStream)
+	if err != nil {
+		return errorResponse(err)
+	}
+	if !ok {
+		return errorResponse(errXGroupKeyMissing)
+	}
+	g, ok := value.stream().groups[group]
+	if !ok {
+		return errorResponse(noGroupForKeyError(key, group))
+	}
+	return fn(value.stream(), g)
+}
+
+func NewXGroupDestroyCommand(store *Store, clock Clock, key, group string) *XGroupDestroyCommand {
+	return &XGroupDestroyCommand{
+		store: store,
+		clock: clock,
+		key:   key,
+		group: group,
+	}
+}
+
+type XGroupDestroyCommand struct {
+	store *Store
+	clock Clock
+	key   string
+	group string
+}
+
+func (x *XGroupDestroyCommand) Run() string {
+	var response string
+	x.store.write(x.clock.NowMonotonic(), func(tx *storeTx) {
+		value, ok, err := tx.getTyped(x.key, ValueTypeStream)
+		switch {
+		case err != nil:
+			response = errorResponse(err)
+		case !ok:
+			response = errorResponse(errXGroupKeyMissing)
+		default:
+			s := value.stream()
+			if _, ok := s.groups[x.group]; !ok {
+				response = integer(0)
+				return
+			}
+			delete(s.groups, x.group)
+			tx.notify(notifyStream, "xgroup-destroy", x.key)
+			// Let the clients blocked reading from the group know that it's gone.
+			tx.signalKeyAsReady(x.key)
+			response = integer(1)
+		}
+	})
+	return response
+}
+
+func NewXGroupCreateConsumerCommand(
+	store *Store,
+	clock Clock,
+	key, group, consumer string,
+) *XGroupCreateConsumerCommand {
+	return &XGroupCreateConsumerCommand{
+		store:    store,
+		clock:    clock,
+		key:      key,
+		group:    group,
+		consumer: consumer,
+	}
+}
+
+type XGroupCreateConsumerCommand struct {
+	store    *Store
+	clock    Clock
+	key      string
+	group    string
+	consumer string
+}
+
+func (x *XGroupCreateConsumerCommand) Run() string {
+	var response string
+	x.store.write(x.clock.NowMonotonic(), func(tx *storeTx) {
+		response = runXGroupCommand(tx, x.key, x.group, func(_ *stream, group *streamGroup) string {
+			if _, created := group.createConsumer(x.consumer, x.clock.Now()); created {
+				tx.notify(notifyStream, "xgroup-createconsumer", x.key)
+				return integer(1)
+			}
+			return integer(0)
+		})
+	})
+	return response
+}
+
+func NewXGroupDelConsumerCommand(
+	store *Store,
+	clock Clock,
+	key, group, consumer string,
+) *XGroupDelConsumerCommand {
+	return &XGroupDelConsumerCommand{
+		store:    store,
+		clock:    clock,
+		key:      key,
+		group:    group,
+		consumer: consumer,
+	}
+}
+
+type XGroupDelConsumerCommand struct {
+	store    *Store
+	clock    Clock
+	key      string
+	group    string
+	consumer string
+}
+
+func (x *XGroupDelConsumerCommand) Run() string {
+	var response string
+	x.store.write(x.clock.NowMonotonic(), func(tx *storeTx) {
+		response = runXGroupCommand(tx, x.key, x.group, func(_ *stream, group *streamGroup) string {
+			if _, ok := group.consumers[x.consumer]; !ok {
+				return integer(0)
+			}
+			tx.notify(notifyStream, "xgroup-delconsumer", x.key)
+			return integer(group.deleteConsumer(x.consumer))
+		})
+	})
+	return response
+}
+
+// XReadGroupID is where XREADGROUP reads a stream from. Undelivered reads the entries that
+// haven't been delivered to any consumer of the group, like ">". Otherwise it reads the
+// consumer's pending entries with IDs greater than ID.
+type XReadGroupID struct {
+	ID          StreamID
+	Undelivered bool
+}
+
+func NewXReadGroupCommand(
+	store *Store,
+	clock Clock,
+	client *Client,
+	group, consumer string,
+	keys []string,
+	ids []XReadGroupID,
+	options ...func(*XReadGroupCommand),
+) *XReadGroupCommand {
+	result := &XReadGroupCommand{
+		store:    store,
+		clock:    clock,
+		client:   client,
+		group:    group,
+		consumer: consumer,
+		keys:     keys,
+		ids:      ids,
+	}
+	for _, option := range options {
+		option(result)
+	}
+	return result
+}
+
+type XReadGroupCommand struct {
+	store    *Store
+	clock    Clock
+	client   *Client
+	group    string
+	consumer string
+	keys     []string
+	ids      []XReadGroupID
+	// count is the most entries to read from each stream, or unlimited if it's not positive.
+	count int
+	// block is how long to wait for entries if there aren't any, forever if it's zero, or nil
+	// to not wait at all. It's ignored unless all the IDs are Undelivered.
+	block *time.Duration
+	// noAck delivers entries without adding them to the pending entries lists, as if they were
+	// acknowledged straight away.
+	noAck bool
+}
+
+func XReadGroupCount(count int) func(*XReadGroupCommand) {
+	return func(command *XReadGroupCommand) {
+		command.count = count
+	}
+}
+
+func XReadGroupBlock(timeout time.Duration) func(*XReadGroupCommand) {
+	return func(command *XReadGroupCommand) {
+		command.block = &timeout
+	}
+}
+
+func XReadGroupNoAck() func(*XReadGroupCommand) {
+	return func(command *XReadGroupCommand) {
+		command.noAck = true
+	}
+}
+
+func (x *XReadGroupCommand) Run() string {
+	readAll := func(tx *storeTx) (string, bool, error) {
+		for _, key := range x.keys {
+			if _, _, ok, err := getGroup(tx, key, x.group); err != nil {
+				return "", false, err
+			} else if !ok {
+				return "", false, CommandError(fmt.Sprintf("NOGROUP No such key '%s' or consumer "+
+					"group '%s' in XREADGROUP with GROUP option", key, x.group))
+			}
+		}
+		var elements []string
+		for i, key := range x.keys {
+			if response, ok := x.read(tx, key, x.ids[i]); ok {
+				elements = append(elements, response)
+			}
+		}
+		return array(elements...), len(elements) > 0, nil
+	}
+
+	if x.block == nil || !x.allUndelivered() {
+		response := nullArray
+		x.store.write(x.clock.NowMonotonic(), func(tx *storeTx) {
+			r, ok, err := readAll(tx)
+			if err != nil {
+				response = errorResponse(err)
+			} else if ok {
+				response = r
+			}
+		})
+		return response
+	}
+	serve := func(tx *storeTx, key string) (string, bool, error) {
+		s, _, ok, _ := getGroup(tx, key, x.group)
+		if s == nil {
+			return "", false, nil
+		}
+		if !ok {
+			return errorResponse(CommandError("NOGROUP the consumer group this client was " +
+				"blocked on no longer exists")), true, nil
+		}
+		response, ok := x.read(tx, key, XReadGroupID{Undelivered: true})
+		return array(response), ok, nil
+	}
+	return blockUnlessServed(
+		x.store, x.clock, x.client, x.keys, *x.block, nullArray, readAll, serve,
+	)
+}
+
+func (x *XReadGroupCommand) allUndelivered() bool {
+	for _, id := range x.ids {
+		if !id.Undelivered {
+			return false
+		}
+	}
+	return true
+}
+
+// read returns the key and entries that the consumer reads from the stream at key, whose group
+// must exist, or false if there aren't any new entries to read.
+func (x *XReadGroupCommand) read(tx *storeTx, key string, id XReadGroupID) (string, bool) {
+	s, group, _, _ := getGroup(tx, key, x.group)
+	now := x.clock.Now()
+	consumer := groupConsumer(tx, key, group, x.consumer, now)
+
+	if id.Undelivered {
+		start, ok := group.lastID.next()
+		if !ok {
+			return "", false
+		}
+		entries := s.entries(start, maxStreamID, x.count, false)
+		if len(entries) == 0 {
+			return "", false
+		}
+		for _, entry := range entries {
+			group.advance(s, entry.id)
+			if !x.noAck {
+				group.deliver(entry.id, consumer, now)
+			}
+		}
+		consumer.activeTime = now
+		return array(bulkString(key), streamEntriesResponse(entries)), true
+	}
+
+	// Read the consumer's history from its pending entries, which is always replied with even if
+	// it's empty.
+	var elements []string
+	if start, ok := id.ID.next(); ok {
+		pending := consumer.pending[consumer.pending.search(start):]
+		if x.count > 0 && len(pending) > x.count {
+			pending = pending[:x.count]
+		}
+		for _, p := range pending {
+			entry, ok := s.entry(p.id)
+			if !ok {
+				elements = append(elements, array(bulkString(p.id.String()), nullArray))
+				continue
+			}
+			p.deliveryTime = now
+			p.deliveryCount++
+			consumer.activeTime = now
+			elements = append(elements, streamEntryResponse(entry))
+		}
+	}
+	return array(bulkString(key), array(elements...)), true
+}
+
+func NewXAckCommand(store *Store, clock Clock, key, group string, ids []StreamID) *XAckCommand {
+	return &XAckCommand{
+		store: store,
+		clock: clock,
+		key:   key,
+		group: group,
+		ids:   ids,
+	}
+}
+
+type XAckCommand struct {
+	store *Store
+	clock Clock
+	key   string
+	group string
+	ids   []StreamID
+}
+
+func (x *XAckCommand) Run() string {
+	response := integer(0)
+	x.store.write(x.clock.NowMonotonic(), func(tx *storeTx) {
+		_, group, ok, err := getGroup(tx, x.key, x.group)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		if !ok {
+			return
+		}
+		acked := 0
+		for _, id := range x.ids {
+			if group.ack(id) {
+				acked++
+			}
+		}
+		response = integer(acked)
+	})
+	return response
+}
+
+func NewXPendingCommand(
+	store *Store,
+	clock Clock,
+	key, group string,
+	options ...func(*XPendingCommand),
+) *XPendingCommand {
+	result := &XPendingCommand{
+		store: store,
+		clock: clock,
+		key:   key,
+		group: group,
+	}
+	for _, option := range options {
+		option(result)
+	}
+	return result
+}
+
+// XPendingCommand is XPENDING, which summarizes a group's pending entries unless it's given a
+// range to list them from.
+type XPendingCommand struct {
+	store *Store
+	clock Clock
+	key   string
+	group string
+	// extended lists the pending entries from start to end inclusive, up to count of them.
+	extended bool
+	start    StreamID
+	end      StreamID
+	count    int
+	// minIdle only lists the entries that were last delivered at least this long ago.
+	minIdle time.Duration
+	// consumer only lists the entries pending for this consumer, unless it's empty.
+	consumer string
+}
+
+// XPendingRange lists the pending entries from start to end inclusive, up to count of them.
+func XPendingRange(start, end StreamID, count int) func(*XPendingCommand) {
+	return func(command *XPendingCommand) {
+		command.extended = true
+		command.start = start
+		command.end = end
+		command.count = count
+	}
+}
+
+func XPendingMinIdle(minIdle time.Duration) func(*XPendingCommand) {
+	return func(command *XPendingCommand) {
+		command.minIdle = minIdle
+	}
+}
+
+func XPendingConsumer(consumer string) func(*XPendingCommand) {
+	return func(command *XPendingCommand) {
+		command.consumer = consumer
+	}
+}
+
+func (x *XPendingCommand) Run() string {
+	var response string
+	x.store.read(x.clock.NowMonotonic(), func(tx *storeTx) {
+		_, group, ok, err := getGroup(tx, x.key, x.group)
+		switch {
+		case err != nil:
+			response = errorResponse(err)
+		case !ok:
+			response = errorResponse(noGroupError(x.key, x.group))
+		case x.extended:
+			response = x.list(group)
+		default:
+			response = x.summary(group)
+		}
+	})
+	return response
+}
+
+func (x *XPendingCommand) summary(group *streamGroup) string {
+	if len(group.pending) == 0 {
+		return array(integer(0), nullBulkString, nullBulkString, nullArray)
+	}
+	var consumers []string
+	for _, consumer := range group.sortedConsumers() {
+		if len(consumer.pending) > 0 {
+			consumers = append(consumers, array(
+				bulkString(consumer.name),
+				bulkString(fmt.Sprint(len(consumer.pending))),
+			))
+		}
+	}
+	return array(
+		integer(len(group.pending)),
+		bulkString(group.pending[0].id.String()),
+		bulkString(group.pending[len(group.pending)-1].id.String()),
+		array(consumers...),
+	)
+}
+
+func (x *XPendingCommand) list(group *streamGroup) string {
+	pending := group.pending
+	if x.consumer != "" {
+		consumer, ok := group.consumers[x.consumer]
+		if !ok {
+			return array()
+		}
+		pending = consumer.pending
+	}
+	now := x.clock.Now()
+	var elements []string
+	for _, entry := range pending[pending.search(x.start):] {
+		if len(elements) >= x.count || x.end.less(entry.id) {
+			break
+		}
+		idle := now.Sub(entry.deliveryTime)
+		if idle < x.minIdle {
+			continue
+		}
+		elements = append(elements, array(
+			bulkString(entry.id.String()),
+			bulkString(entry.consumer.name),
+			integer(int(idle.Milliseconds())),
+			integer(entry.deliveryCount),
+		))
+	}
+	return array(elements...)
+}
+
+func NewXClaimCommand(
+	store *Store,
+	clock Clock,
+	key, group, consumer string,
+	minIdle time.Duration,
+	ids []StreamID,
+	options ...func(*XClaimCommand),
+) *XClaimCommand {
+	result := &XClaimCommand{
+		store:      store,
+		clock:      clock,
+		key:        key,
+		group:      group,
+		consumer:   consumer,
+		minIdle:    minIdle,
+		ids:        ids,
+		retryCount: -1,
+	}
+	for _, option := range options {
+		option(result)
+	}
+	return result
+}
+
+type XClaimCommand struct {
+	store    *Store
+	clock    Clock
+	key      string
+	group    string
+	consumer string
+	// minIdle only claims the entries that were last delivered at least this long ago.
+	minIdle time.Duration
+	ids     []StreamID
+	// idle or deliveryTime sets when the claimed entries were last delivered to this long ago
+	// or this time, rather than now.
+	idle         *time.Duration
+	deliveryTime *time.Time
+	// retryCount sets the delivery count of the claimed entries, unless it's negative.
+	retryCount int
+	// force claims entries that aren't pending as long as they're in the stream.
+	force  bool
+	justID bool
+	// lastID moves the group's last ID on to it if it's greater.
+	lastID *StreamID
+}
+
+func XClaimIdle(idle time.Duration) func(*XClaimCommand) {
+	return func(command *XClaimCommand) {
+		command.idle = &idle
+	}
+}
+
+func XClaimTime(deliveryTime time.Time) func(*XClaimCommand) {
+	return func(command *XClaimCommand) {
+		command.deliveryTime = &deliveryTime
+	}
+}
+
+func XClaimRetryCount(retryCount int) func(*XClaimCommand) {
+	return func(command *XClaimCommand) {
+		command.retryCount = retryCount
+	}
+}
+
+func XClaimForce() func(*XClaimCommand) {
+	return func(command *XClaimCommand) {
+		command.force = true
+	}
+}
+
+// XClaimJustID returns only the IDs of the claimed entries, without incrementing their delivery
+// counts.
+func XClaimJustID() func(*XClaimCommand) {
+	return func(command *XClaimCommand) {
+		command.justID = true
+	}
+}
+
+func XClaimLastID(id StreamID) func(*XClaimCommand) {
+	return func(command *XClaimCommand) {
+		command.lastID = &id
+	}
+}
+
+func (x *XClaimCommand) Run() string {
+	var response string
+	x.store.write(x.clock.NowMonotonic(), func(tx *storeTx) {
+		s, group, ok, err := getGroup(tx, x.key, x.group)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		if !ok {
+			response = errorResponse(noGroupError(x.key, x.group))
+			return
+		}
+		now := x.clock.Now()
+		deliveryTime := now
+		if x.idle != nil {
+			deliveryTime = now.Add(-*x.idle)
+		} else if x.deliveryTime != nil {
+			deliveryTime = *x.deliveryTime
+		}
+		if deliveryTime.After(now) {
+			deliveryTime = now
+		}
+		if x.lastID != nil && group.lastID.less(*x.lastID) {
+			group.lastID = *x.lastID
+		}
+		consumer := groupConsumer(tx, x.key, group, x.consumer, now)
+
+		var elements []string
+		for _, id := range x.ids {
+			pending, isPending := group.pending.find(id)
+			entry, exists := s.entry(id)
+			switch {
+			case isPending && !exists:
+				group.ack(id)
+				continue
+			case !isPending && exists && x.force:
+				pending = &pendingEntry{id: id, deliveryCount: 1}
+				group.pending.insert(pending)
+			case !isPending:
+				continue
+			}
+			if pending.consumer != nil && now.Sub(pending.deliveryTime) < x.minIdle {
+				continue
+			}
+			group.transfer(pending, consumer)
+			pending.deliveryTime = deliveryTime
+			if x.retryCount >= 0 {
+				pending.deliveryCount = x.retryCount
+			} else if !x.justID {
+				pending.deliveryCount++
+			}
+			consumer.activeTime = now
+			if x.justID {
+				elements = append(elements, bulkString(id.String()))
+			} else {
+				elements = append(elements, streamEntryResponse(entry))
+			}
+		}
+		response = array(elements...)
+	})
+	return response
+}
+
+func NewXAutoClaimCommand(
+	store *Store,
+	clock Clock,
+	key, group, consumer string,
+	minIdle time.Duration,
+	start StreamID,
+	options ...func(*XAutoClaimCommand),
+) *XAutoClaimCommand {
+	result := &XAutoClaimCommand{
+		store:    store,
+		clock:    clock,
+		key:      key,
+		group:    group,
+		consumer: consumer,
+		minIdle:  minIdle,
+		start:    start,
+		count:    100,
+	}
+	for _, option := range options {
+		option(result)
+	}
+	return result
+}
+
+// XAutoClaimCommand is XAUTOCLAIM, which claims the pending entries from start that were last
+// delivered at least minIdle ago, like XCLAIM, and returns a cursor to carry on from.
+type XAutoClaimCommand struct {
+	store    *Store
+	clock    Clock
+	key      string
+	group    string
+	consumer string
+	minIdle  time.Duration
+	start    StreamID
+	// count is the most entries to claim, which must be positive. Up to ten times as many
+	// pending entries are checked.
+	count  int
+	justID bool
+}
+
+func XAutoClaimCount(count int) func(*XAutoClaimCommand) {
+	return func(command *XAutoClaimCommand) {
+		command.count = count
+	}
+}
+
+func XAutoClaimJustID() func(*XAutoClaimCommand) {
+	return func(command *XAutoClaimCommand) {
+		command.justID = true
+	}
+}
+
+func (x *XAutoClaimCommand) Run() string {
+	var response string
+	x.store.write(x.clock.NowMonotonic(), func(tx *storeTx) {
+		s, group, ok, err := getGroup(tx, x.key, x.group)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		if !ok {
+			response = errorResponse(noGroupError(x.key, x.group))
+			return
+		}
+		now := x.clock.Now()
+		consumer := groupConsumer(tx, x.key, group, x.consumer, now)
+
+		var claimed, deleted []string
+		count, attempts := x.count, x.count*10
+		i := group.pending.search(x.start)
+		for ; attempts > 0 && count > 0 && i < len(group.pending); attempts-- {
+			pending := group.pending[i]
+			entry, exists := s.entry(pending.id)
+			if !exists {
+				// The entry was deleted, so it can't be claimed. Its ID is returned so that the
+				// consumer knows.
+				group.ack(pending.id)
+				deleted = append(deleted, pending.id.String())
+				count--
+				continue
+			}
+			i++
+			if now.Sub(pending.deliveryTime) < x.minIdle {
+				continue
+			}
+			group.transfer(pending, consumer)
+			pending.deliveryTime = now
+			if !x.justID {
+				pending.deliveryCount++
+			}
+			consumer.activeTime = now
+			count--
+			if x.justID {
+				claimed = append(claimed, bulkString(pending.id.String()))
+			} else {
+				claimed = append(claimed, streamEntryResponse(entry))
+			}
+		}
+
+		cursor := StreamID{}
+		if i < len(group.pending) {
+			cursor = group.pending[i].id
+		}
+		response = array(bulkString(cursor.String()), array(claimed...), bulkStringArray(deleted))
+	})
+	return response
+}
+
+func NewXInfoStreamCommand(
+	store *Store,
+	clock Clock,
+	key string,
+	options ...func(*XInfoStreamCommand),
+) *XInfoStreamCommand {
+	result := &XInfoStreamCommand{
+		store: store,
+		clock: clock,
+		key:   key,
+	}
+	for _, option := range options {
+		option(result)
+	}
+	return result
+}
+
+type XInfoStreamCommand struct {
+	store *Store
+	clock Clock
+	key   string
+	// full returns the stream's entries and the details of its groups rather than a summary,
+	// with up to count entries, pending entries of each group and pending entries of each
+	// consumer, or all of them if count is 0.
+	full  bool
+	count int
+}
+
+func XInfoStreamFull(count int) func(*XInfoStreamCommand) {
+	return func(command *XInfoStreamCommand) {
+		command.full = true
+		command.count = count
+	}
+}
+
+func (x *XInfoStreamCommand) Run() string {
+	var response string
+	x.store.read(x.clock.NowMonotonic(), func(tx *storeTx) {
+		value, ok, err := tx.getTyped(x.key, ValueTypeStream)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		if !ok {
+			response = errorResponse(CommandError("ERR no such key"))
+			return
+		}
+		s := value.stream()
+		elements := []string{
+			bulkString("length"), integer(s.len()),
+			// There's no radix tree, but each node of entries would be a key of one.
+			bulkString("radix-tree-keys"), integer(len(s.nodes)),
+			bulkString("radix-tree-nodes"), integer(len(s.nodes)),
+			bulkString("last-generated-id"), bulkString(s.lastID.String()),
+			bulkString("max-deleted-entry-id"), bulkString(s.maxDeletedID.String()),
+			bulkString("entries-added"), integer(s.entriesAdded),
+			bulkString("recorded-first-entry-id"), bulkString(s.firstID().String()),
+		}
+		if x.full {
+			entries := s.entries(StreamID{}, maxStreamID, x.count, false)
+			elements = append(elements,
+				bulkString("entries"), streamEntriesResponse(entries),
+				bulkString("groups"), x.fullGroups(s),
+			)
+		} else {
+			elements = append(elements, bulkString("groups"), integer(len(s.groups)))
+			first := s.entries(StreamID{}, maxStreamID, 1, false)
+			last := s.entries(StreamID{}, maxStreamID, 1, true)
+			elements = append(elements,
+				bulkString("first-entry"), streamEntryOrNil(first),
+				bulkString("last-entry"), streamEntryOrNil(last),
+			)
+		}
+		response = array(elements...)
+	})
+	return response
+}
+
+// streamEntryOrNil returns the only one of entries, or nil if there isn't one.
+func streamEntryOrNil(entries []streamEntry) string {
+	if len(entries) == 0 {
+		return nullBulkString
+	}
+	return streamEntryResponse(entries[0])
+}
+
+// limitPending returns up to count of pending, or all of them if count is 0.
+func limitPending(pending pendingEntries, count int) pendingEntries {
+	if count > 0 && len(pending) > count {
+		return pending[:count]
+	}
+	return pending
+}
+
+func (x *XInfoStreamCommand) fullGroups(s *stream) string {
+	var groups []string
+	for _, group := range s.sortedGroups() {
+		var pending []string
+		for _, entry := range limitPending(group.pending, x.count) {
+			pending = append(pending, array(
+				bulkString(entry.id.String()),
+				bulkString(entry.consumer.name),
+				integer(int(entry.deliveryTime.UnixMilli())),
+				integer(entry.deliveryCount),
+			))
+		}
+		var consumers []string
+		for _, consumer := range group.sortedConsumers() {
+			var consumerPending []string
+			for _, entry := range limitPending(consumer.pending, x.count) {
+				consumerPending = append(consumerPending, array(
+					bulkString(entry.id.String()),
+					integer(int(entry.deliveryTime.UnixMilli())),
+					integer(entry.deliveryCount),
+				))
+			}
+			activeTime := -1
+			if !consumer.activeTime.IsZero() {
+				activeTime = int(consumer.activeTime.UnixMilli())
+			}
+			consumers = append(consumers, array(
+				bulkString("name"), bulkString(consumer.name),
+				bulkString("seen-time"), integer(int(consumer.seenTime.UnixMilli())),
+				bulkString("active-time"), integer(activeTime),
+				bulkString("pel-count"), integer(len(consumer.pending)),
+				bulkString("pending"), array(consumerPending...),
+			))
+		}
+		groups = append(groups, array(
+			bulkString("name"), bulkString(group.name),
+			bulkString("last-delivered-id"), bulkString(group.lastID.String()),
+			bulkString("entries-read"), entriesReadResponse(group),
+			bulkString("lag"), lagResponse(s, group),
+			bulkString("pel-count"), integer(len(group.pending)),
+			bulkString("pending"), array(pending...),
+			bulkString("consumers"), array(consumers...),
+		))
+	}
+	return array(groups...)
+}
+
+func entriesReadResponse(group *streamGroup) string {
+	if group.entriesRead < 0 {
+		return nullBulkString
+	}
+	return integer(group.entriesRead)
+}
+
+func lagResponse(s *stream, group *streamGroup) string {
+	lag, ok := group.lag(s)
+	if !ok {
+		return nullBulkString
+	}
+	return integer(lag)
+}
+
+func NewXInfoGroupsCommand(store *Store, clock Clock, key string) *XInfoGroupsCommand {
+	return &XInfoGroupsCommand{
+		store: store,
+		clock: clock,
+		key:   key,
+	}
+}
+
+type XInfoGroupsCommand struct {
+	store *Store
+	clock Clock
+	key   string
+}
+
+func (x *XInfoGroupsCommand) Run() string {
+	var response string
+	x.store.read(x.clock.NowMonotonic(), func(tx *storeTx) {
+		value, ok, err := tx.getTyped(x.key, ValueTypeStream)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		if !ok {
+			response = errorResponse(CommandError("ERR no such key"))
+			return
+		}
+		s := value.stream()
+		var groups []string
+		for _, group := range s.sortedGroups() {
+			groups = append(groups, array(
+				bulkString("name"), bulkString(group.name),
+				bulkString("consumers"), integer(len(group.consumers)),
+				bulkString("pending"), integer(len(group.pending)),
+				bulkString("last-delivered-id"), bulkString(group.lastID.String()),
+				bulkString("entries-read"), entriesReadResponse(group),
+				bulkString("lag"), lagResponse(s, group),
+			))
+		}
+		response = array(groups...)
+	})
+	return response
+}
+
+func NewXInfoConsumersCommand(
+	store *Store,
+	clock Clock,
+	key, group string,
+) *XInfoConsumersCommand {
+	return &XInfoConsumersCommand{
+		store: store,
+		clock: clock,
+		key:   key,
+		group: group,
+	}
+}
+
+type XInfoConsumersCommand struct {
+	store *Store
+	clock Clock
+	key   string
+	group string
+}
+
+func (x *XInfoConsumersCommand) Run() string {
+	var response string
+	x.store.read(x.clock.NowMonotonic(), func(tx *storeTx) {
+		value, ok, err := tx.getTyped(x.key, ValueTypeStream)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		if !ok {
+			response = errorResponse(CommandError("ERR no such key"))
+			return
+		}
+		group, ok := value.stream().groups[x.group]
+		if !ok {
+			response = errorResponse(noGroupForKeyError(x.key, x.group))
+			return
+		}
+		now := x.clock.Now()
+		var consumers []string
+		for _, consumer := range group.sortedConsumers() {
+			inactive := -1
+			if !consumer.activeTime.IsZero() {
+				inactive = idleMilliseconds(now, consumer.activeTime)
+			}
+			consumers = append(consumers, array(
+				bulkString("name"), bulkString(consumer.name),
+				bulkString("pending"), integer(len(consumer.pending)),
+				bulkString("idle"), integer(idleMilliseconds(now, consumer.seenTime)),
+				bulkString("inactive"), integer(inactive),
+			))
+		}
+		response = array(consumers...)
+	})
+	return response
+}
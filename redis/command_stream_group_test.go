@@ -0,0 +1,853 @@
+package redis_test
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/redis"
+)
+
+// readGroup has consumer read up to count new entries from the stream at key with group.
+func readGroup(store *redis.Store, clock redis.Clock, key, group, consumer string, count int) string {
+	return redis.NewXReadGroupCommand(
+		store, clock, redis.NewClients().NewClient(), group, consumer, []string{key},
+		[]redis.XReadGroupID{{Undelivered: true}}, redis.XReadGroupCount(count),
+	).Run()
+}
+
+// entryResponse returns the response for the entry with ID n-0 added by streamWith.
+func entryResponse(n int) string {
+	s := strconv.Itoa(n)
+	return "*2\r\n$3\r\n" + s + "-0\r\n*2\r\n$1\r\nn\r\n$1\r\n" + s + "\r\n"
+}
+
+// pendingResponse returns an entry of the response of XPENDING with a range.
+func pendingResponse(id, consumer string, idle, deliveries int) string {
+	return "*4\r\n$" + strconv.Itoa(len(id)) + "\r\n" + id + "\r\n" +
+		"$" + strconv.Itoa(len(consumer)) + "\r\n" + consumer + "\r\n" +
+		":" + strconv.Itoa(idle) + "\r\n:" + strconv.Itoa(deliveries) + "\r\n"
+}
+
+// allPending returns XPENDING for up to 10 of the group's pending entries.
+func allPending(store *redis.Store, clock redis.Clock, key, group string) *redis.XPendingCommand {
+	return redis.NewXPendingCommand(
+		store, clock, key, group, redis.XPendingRange(redis.StreamID{}, maxStreamID, 10),
+	)
+}
+
+func TestXGroupCreateCommand(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	store.Set("string", "zelda")
+	clock := FakeClock{}
+	parser := redis.NewParser(defaultEncodingRedisConfig, store, clock)
+	keysWith(t, parser,
+		append(xadds("stream", 2), []string{"XGROUP", "CREATE", "stream", "existing", "0"})...)
+
+	tests := []struct {
+		name     string
+		key      string
+		group    string
+		options  []func(*redis.XGroupCreateCommand)
+		response string
+	}{
+		{
+			name:     "new group",
+			key:      "stream",
+			group:    "new",
+			response: "+OK\r\n",
+		},
+		{
+			name:     "existing group",
+			key:      "stream",
+			group:    "existing",
+			response: "-BUSYGROUP Consumer Group name already exists\r\n",
+		},
+		{
+			name:  "missing key",
+			key:   "missing",
+			group: "new",
+			response: "-ERR The XGROUP subcommand requires the key to exist. Note that for CREATE you " +
+				"may want to use the MKSTREAM option to create an empty stream automatically.\r\n",
+		},
+		{
+			name:     "MKSTREAM",
+			key:      "created",
+			group:    "new",
+			options:  []func(*redis.XGroupCreateCommand){redis.XGroupCreateMkStream()},
+			response: "+OK\r\n",
+		},
+		{
+			name:     "wrong type",
+			key:      "string",
+			group:    "new",
+			options:  []func(*redis.XGroupCreateCommand){redis.XGroupCreateMkStream()},
+			response: "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := redis.NewXGroupCreateCommand(
+				store, clock, tt.key, tt.group, redis.XGroupID{}, tt.options...,
+			).Run()
+			if response != tt.response {
+				t.Errorf(`command expected to return %#v but was %#v`, tt.response, response)
+			}
+		})
+	}
+
+	if got := redis.NewXLenCommand(store, clock, "created").Run(); got != ":0\r\n" {
+		t.Errorf(`XLEN created expected to return ":0\r\n" but was %#v`, got)
+	}
+}
+
+func TestXGroupCommands(t *testing.T) {
+	t.Parallel()
+
+	const noGroup = "-NOGROUP No such consumer group 'missing' for key name 'stream'\r\n"
+	store := redis.NewStore()
+	clock := FakeClock{}
+	parser := redis.NewParser(defaultEncodingRedisConfig, store, clock)
+	keysWith(t, parser,
+		append(xadds("stream", 3), []string{"XGROUP", "CREATE", "stream", "group", "0"})...)
+	readGroup(store, clock, "stream", "group", "alice", 2)
+
+	tests := []struct {
+		name     string
+		command  redis.Command
+		response string
+	}{
+		{
+			name:     "SETID",
+			command:  redis.NewXGroupSetIDCommand(store, clock, "stream", "group", redis.XGroupID{New: true}, 3),
+			response: "+OK\r\n",
+		},
+		{
+			name:     "SETID of missing group",
+			command:  redis.NewXGroupSetIDCommand(store, clock, "stream", "missing", redis.XGroupID{}, -1),
+			response: noGroup,
+		},
+		{
+			name:     "CREATECONSUMER",
+			command:  redis.NewXGroupCreateConsumerCommand(store, clock, "stream", "group", "bob"),
+			response: ":1\r\n",
+		},
+		{
+			name:     "CREATECONSUMER of existing consumer",
+			command:  redis.NewXGroupCreateConsumerCommand(store, clock, "stream", "group", "alice"),
+			response: ":0\r\n",
+		},
+		{
+			name:     "CREATECONSUMER in missing group",
+			command:  redis.NewXGroupCreateConsumerCommand(store, clock, "stream", "missing", "bob"),
+			response: noGroup,
+		},
+		{
+			name:     "DELCONSUMER returns pending entries",
+			command:  redis.NewXGroupDelConsumerCommand(store, clock, "stream", "group", "alice"),
+			response: ":2\r\n",
+		},
+		{
+			name:     "DELCONSUMER of missing consumer",
+			command:  redis.NewXGroupDelConsumerCommand(store, clock, "stream", "group", "alice"),
+			response: ":0\r\n",
+		},
+		{
+			name:     "pending entries are gone with their consumer",
+			command:  redis.NewXPendingCommand(store, clock, "stream", "group"),
+			response: "*4\r\n:0\r\n$-1\r\n$-1\r\n*-1\r\n",
+		},
+		{
+			name:     "DESTROY",
+			command:  redis.NewXGroupDestroyCommand(store, clock, "stream", "group"),
+			response: ":1\r\n",
+		},
+		{
+			name:     "DESTROY of missing group",
+			command:  redis.NewXGroupDestroyCommand(store, clock, "stream", "group"),
+			response: ":0\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if response := tt.command.Run(); response != tt.response {
+				t.Errorf(`command expected to return %#v but was %#v`, tt.response, response)
+			}
+		})
+	}
+}
+
+func TestXReadGroupCommand(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{CurrentTime: time.UnixMilli(10000)}
+	parser := redis.NewParser(defaultEncodingRedisConfig, store, clock)
+	client := redis.NewClients().NewClient()
+	keysWith(t, parser,
+		append(xadds("stream", 3), []string{"XGROUP", "CREATE", "stream", "group", "0"})...)
+	read := func(consumer string, id redis.XReadGroupID, options ...func(*redis.XReadGroupCommand)) redis.Command {
+		return redis.NewXReadGroupCommand(
+			store, clock, client, "group", consumer, []string{"stream"}, []redis.XReadGroupID{id}, options...,
+		)
+	}
+	undelivered := redis.XReadGroupID{Undelivered: true}
+
+	// Each step runs in turn on the same stream.
+	steps := []struct {
+		name     string
+		command  redis.Command
+		response string
+	}{
+		{
+			name:     "new entries",
+			command:  read("alice", undelivered, redis.XReadGroupCount(2)),
+			response: "*1\r\n*2\r\n$6\r\nstream\r\n*2\r\n" + entryResponse(1) + entryResponse(2),
+		},
+		{
+			name:     "entries not delivered to other consumers",
+			command:  read("bob", undelivered),
+			response: "*1\r\n*2\r\n$6\r\nstream\r\n*1\r\n" + entryResponse(3),
+		},
+		{
+			name:     "no new entries",
+			command:  read("bob", undelivered),
+			response: "*-1\r\n",
+		},
+		{
+			name:     "history",
+			command:  read("alice", redis.XReadGroupID{}),
+			response: "*1\r\n*2\r\n$6\r\nstream\r\n*2\r\n" + entryResponse(1) + entryResponse(2),
+		},
+		{
+			name:    "pending entries",
+			command: redis.NewXPendingCommand(store, clock, "stream", "group"),
+			response: "*4\r\n:3\r\n$3\r\n1-0\r\n$3\r\n3-0\r\n*2\r\n" +
+				"*2\r\n$5\r\nalice\r\n$1\r\n2\r\n" +
+				"*2\r\n$3\r\nbob\r\n$1\r\n1\r\n",
+		},
+		{
+			name:     "XACK",
+			command:  redis.NewXAckCommand(store, clock, "stream", "group", []redis.StreamID{{Ms: 1}, {Ms: 9}}),
+			response: ":1\r\n",
+		},
+		{
+			name:     "XDEL of pending entry",
+			command:  redis.NewXDelCommand(store, clock, "stream", []redis.StreamID{{Ms: 2}}),
+			response: ":1\r\n",
+		},
+		{
+			name:     "history with deleted entry",
+			command:  read("alice", redis.XReadGroupID{}),
+			response: "*1\r\n*2\r\n$6\r\nstream\r\n*1\r\n*2\r\n$3\r\n2-0\r\n*-1\r\n",
+		},
+		{
+			name:     "history is counted",
+			command:  allPending(store, clock, "stream", "group"),
+			response: "*2\r\n" + pendingResponse("2-0", "alice", 0, 2) + pendingResponse("3-0", "bob", 0, 1),
+		},
+		{
+			name:     "empty history",
+			command:  read("alice", redis.XReadGroupID{ID: redis.StreamID{Ms: 2}}),
+			response: "*1\r\n*2\r\n$6\r\nstream\r\n*0\r\n",
+		},
+		{
+			name:     "history doesn't block",
+			command:  read("alice", redis.XReadGroupID{ID: redis.StreamID{Ms: 2}}, redis.XReadGroupBlock(0)),
+			response: "*1\r\n*2\r\n$6\r\nstream\r\n*0\r\n",
+		},
+	}
+
+	for _, step := range steps {
+		if response := step.command.Run(); response != step.response {
+			t.Errorf(`%s: command expected to return %#v but was %#v`, step.name, step.response, response)
+		}
+	}
+}
+
+func TestXReadGroupCommand_NoAck(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{}
+	parser := redis.NewParser(defaultEncodingRedisConfig, store, clock)
+	keysWith(t, parser,
+		append(xadds("stream", 1), []string{"XGROUP", "CREATE", "stream", "group", "0"})...)
+
+	response := redis.NewXReadGroupCommand(
+		store, clock, redis.NewClients().NewClient(), "group", "alice", []string{"stream"},
+		[]redis.XReadGroupID{{Undelivered: true}}, redis.XReadGroupNoAck(),
+	).Run()
+	if want := "*1\r\n*2\r\n$6\r\nstream\r\n*1\r\n" + entryResponse(1); response != want {
+		t.Errorf(`command expected to return %#v but was %#v`, want, response)
+	}
+
+	want := "*4\r\n:0\r\n$-1\r\n$-1\r\n*-1\r\n"
+	if got := redis.NewXPendingCommand(store, clock, "stream", "group").Run(); got != want {
+		t.Errorf(`XPENDING expected to return %#v but was %#v`, want, got)
+	}
+}
+
+func TestXReadGroupCommand_Errors(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	store.Set("string", "zelda")
+	clock := FakeClock{}
+	parser := redis.NewParser(defaultEncodingRedisConfig, store, clock)
+	keysWith(t, parser,
+		append(xadds("stream", 1), []string{"XGROUP", "CREATE", "stream", "group", "0"})...)
+
+	tests := []struct {
+		name     string
+		keys     []string
+		response string
+	}{
+		{
+			name: "missing group",
+			keys: []string{"stream", "other"},
+			response: "-NOGROUP No such key 'other' or consumer group 'group' in XREADGROUP with GROUP " +
+				"option\r\n",
+		},
+		{
+			name:     "wrong type",
+			keys:     []string{"string"},
+			response: "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ids := make([]redis.XReadGroupID, len(tt.keys))
+			response := redis.NewXReadGroupCommand(
+				store, clock, redis.NewClients().NewClient(), "group", "alice", tt.keys, ids,
+			).Run()
+			if response != tt.response {
+				t.Errorf(`command expected to return %#v but was %#v`, tt.response, response)
+			}
+		})
+	}
+
+	// Nothing is delivered when any group is missing.
+	want := "*4\r\n:0\r\n$-1\r\n$-1\r\n*-1\r\n"
+	if got := redis.NewXPendingCommand(store, clock, "stream", "group").Run(); got != want {
+		t.Errorf(`XPENDING expected to return %#v but was %#v`, want, got)
+	}
+}
+
+func TestXReadGroupCommand_Block(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		unblock  func(store *redis.Store, clock redis.Clock)
+		response string
+	}{
+		{
+			name: "new entry",
+			unblock: func(store *redis.Store, clock redis.Clock) {
+				redis.NewXAddCommand(store, clock, "stream", redis.XAddID{ID: redis.StreamID{Ms: 2}}, []string{"n", "2"}).Run()
+			},
+			response: "*1\r\n*2\r\n$6\r\nstream\r\n*1\r\n" + entryResponse(2),
+		},
+		{
+			name: "group destroyed",
+			unblock: func(store *redis.Store, clock redis.Clock) {
+				redis.NewXGroupDestroyCommand(store, clock, "stream", "group").Run()
+			},
+			response: "-NOGROUP the consumer group this client was blocked on no longer exists\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := redis.NewStore()
+			clock := FakeClock{}
+			parser := redis.NewParser(defaultEncodingRedisConfig, store, clock)
+			keysWith(t, parser, xadds("stream", 1)...)
+			redis.NewXGroupCreateCommand(store, clock, "stream", "group", redis.XGroupID{New: true}).Run()
+			client := redis.NewClients().NewClient()
+			response := make(chan string, 1)
+			go func() {
+				response <- redis.NewXReadGroupCommand(
+					store, clock, client, "group", "alice", []string{"stream"},
+					[]redis.XReadGroupID{{Undelivered: true}}, redis.XReadGroupBlock(0),
+				).Run()
+			}()
+			waitUntilBlocked(t, client)
+
+			tt.unblock(store, clock)
+
+			if got := <-response; got != tt.response {
+				t.Errorf(`command expected to return %#v but was %#v`, tt.response, got)
+			}
+		})
+	}
+}
+
+func TestXPendingCommand(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	parser := redis.NewParser(defaultEncodingRedisConfig, store, FakeClock{})
+	keysWith(t, parser,
+		append(xadds("stream", 3), []string{"XGROUP", "CREATE", "stream", "group", "0"})...)
+	readGroup(store, FakeClock{CurrentTime: time.UnixMilli(10000)}, "stream", "group", "alice", 2)
+	readGroup(store, FakeClock{CurrentTime: time.UnixMilli(12000)}, "stream", "group", "bob", 1)
+	clock := FakeClock{CurrentTime: time.UnixMilli(15000)}
+	all := []func(*redis.XPendingCommand){redis.XPendingRange(redis.StreamID{}, maxStreamID, 10)}
+
+	tests := []struct {
+		name     string
+		group    string
+		options  []func(*redis.XPendingCommand)
+		response string
+	}{
+		{
+			name:    "all",
+			options: all,
+			response: "*3\r\n" +
+				pendingResponse("1-0", "alice", 5000, 1) +
+				pendingResponse("2-0", "alice", 5000, 1) +
+				pendingResponse("3-0", "bob", 3000, 1),
+		},
+		{
+			name:     "range",
+			options:  []func(*redis.XPendingCommand){redis.XPendingRange(redis.StreamID{Ms: 2}, maxStreamID, 10)},
+			response: "*2\r\n" + pendingResponse("2-0", "alice", 5000, 1) + pendingResponse("3-0", "bob", 3000, 1),
+		},
+		{
+			name:     "count",
+			options:  []func(*redis.XPendingCommand){redis.XPendingRange(redis.StreamID{}, maxStreamID, 1)},
+			response: "*1\r\n" + pendingResponse("1-0", "alice", 5000, 1),
+		},
+		{
+			name:     "IDLE",
+			options:  append([]func(*redis.XPendingCommand){redis.XPendingMinIdle(4 * time.Second)}, all...),
+			response: "*2\r\n" + pendingResponse("1-0", "alice", 5000, 1) + pendingResponse("2-0", "alice", 5000, 1),
+		},
+		{
+			name:     "consumer",
+			options:  append([]func(*redis.XPendingCommand){redis.XPendingConsumer("bob")}, all...),
+			response: "*1\r\n" + pendingResponse("3-0", "bob", 3000, 1),
+		},
+		{
+			name:     "missing consumer",
+			options:  append([]func(*redis.XPendingCommand){redis.XPendingConsumer("carol")}, all...),
+			response: "*0\r\n",
+		},
+		{
+			name:     "missing group",
+			group:    "missing",
+			response: "-NOGROUP No such key 'stream' or consumer group 'missing'\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			group := tt.group
+			if group == "" {
+				group = "group"
+			}
+			response := redis.NewXPendingCommand(store, clock, "stream", group, tt.options...).Run()
+			if response != tt.response {
+				t.Errorf(`command expected to return %#v but was %#v`, tt.response, response)
+			}
+		})
+	}
+}
+
+func TestXClaimCommand(t *testing.T) {
+	t.Parallel()
+
+	// alice reads entries 1-0 to 3-0 at 10s, and 3-0 is then deleted. bob claims at 15s.
+	clock := FakeClock{CurrentTime: time.UnixMilli(15000)}
+	ids := []redis.StreamID{{Ms: 1}, {Ms: 3}}
+
+	tests := []struct {
+		name     string
+		minIdle  time.Duration
+		ids      []redis.StreamID
+		options  []func(*redis.XClaimCommand)
+		response string
+		pending  string
+	}{
+		{
+			name:     "idle entries",
+			minIdle:  time.Second,
+			ids:      ids,
+			response: "*1\r\n" + entryResponse(1),
+			pending:  "*2\r\n" + pendingResponse("1-0", "bob", 0, 2) + pendingResponse("2-0", "alice", 5000, 1),
+		},
+		{
+			name:     "entries not idle for long enough",
+			minIdle:  6 * time.Second,
+			ids:      ids,
+			response: "*0\r\n",
+			pending:  "*2\r\n" + pendingResponse("1-0", "alice", 5000, 1) + pendingResponse("2-0", "alice", 5000, 1),
+		},
+		{
+			name:     "JUSTID",
+			ids:      ids,
+			options:  []func(*redis.XClaimCommand){redis.XClaimJustID()},
+			response: "*1\r\n$3\r\n1-0\r\n",
+			pending:  "*2\r\n" + pendingResponse("1-0", "bob", 0, 1) + pendingResponse("2-0", "alice", 5000, 1),
+		},
+		{
+			name: "IDLE and RETRYCOUNT",
+			ids:  ids,
+			options: []func(*redis.XClaimCommand){
+				redis.XClaimIdle(2 * time.Second), redis.XClaimRetryCount(5),
+			},
+			response: "*1\r\n" + entryResponse(1),
+			pending:  "*2\r\n" + pendingResponse("1-0", "bob", 2000, 5) + pendingResponse("2-0", "alice", 5000, 1),
+		},
+		{
+			name:     "TIME",
+			ids:      ids,
+			options:  []func(*redis.XClaimCommand){redis.XClaimTime(time.UnixMilli(14000))},
+			response: "*1\r\n" + entryResponse(1),
+			pending:  "*2\r\n" + pendingResponse("1-0", "bob", 1000, 2) + pendingResponse("2-0", "alice", 5000, 1),
+		},
+		{
+			name:     "FORCE",
+			ids:      []redis.StreamID{{Ms: 4}, {Ms: 5}},
+			options:  []func(*redis.XClaimCommand){redis.XClaimForce()},
+			response: "*1\r\n" + entryResponse(4),
+			pending: "*4\r\n" + pendingResponse("1-0", "alice", 5000, 1) +
+				pendingResponse("2-0", "alice", 5000, 1) + pendingResponse("3-0", "alice", 5000, 1) +
+				pendingResponse("4-0", "bob", 0, 2),
+		},
+		{
+			name:     "entries not pending",
+			ids:      []redis.StreamID{{Ms: 4}},
+			response: "*0\r\n",
+			pending: "*3\r\n" + pendingResponse("1-0", "alice", 5000, 1) +
+				pendingResponse("2-0", "alice", 5000, 1) + pendingResponse("3-0", "alice", 5000, 1),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := redis.NewStore()
+			parser := redis.NewParser(defaultEncodingRedisConfig, store, clock)
+			keysWith(t, parser,
+				append(xadds("stream", 4), []string{"XGROUP", "CREATE", "stream", "group", "0"})...)
+			readGroup(store, FakeClock{CurrentTime: time.UnixMilli(10000)}, "stream", "group", "alice", 3)
+			redis.NewXDelCommand(store, clock, "stream", []redis.StreamID{{Ms: 3}}).Run()
+
+			response := redis.NewXClaimCommand(
+				store, clock, "stream", "group", "bob", tt.minIdle, tt.ids, tt.options...,
+			).Run()
+			if response != tt.response {
+				t.Errorf(`command expected to return %#v but was %#v`, tt.response, response)
+			}
+			if got := allPending(store, clock, "stream", "group").Run(); got != tt.pending {
+				t.Errorf(`XPENDING expected to return %#v but was %#v`, tt.pending, got)
+			}
+		})
+	}
+}
+
+func TestXClaimCommand_MissingGroup(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{}
+	want := "-NOGROUP No such key 'stream' or consumer group 'group'\r\n"
+	response := redis.NewXClaimCommand(
+		store, clock, "stream", "group", "bob", 0, []redis.StreamID{{Ms: 1}},
+	).Run()
+	if response != want {
+		t.Errorf(`XCLAIM expected to return %#v but was %#v`, want, response)
+	}
+	response = redis.NewXAutoClaimCommand(store, clock, "stream", "group", "bob", 0, redis.StreamID{}).Run()
+	if response != want {
+		t.Errorf(`XAUTOCLAIM expected to return %#v but was %#v`, want, response)
+	}
+}
+
+func TestXAutoClaimCommand(t *testing.T) {
+	t.Parallel()
+
+	// alice reads entries 1-0 to 5-0 at 10s, and 2-0 is then deleted. bob claims at 15s.
+	clock := FakeClock{CurrentTime: time.UnixMilli(15000)}
+	deleted := "*1\r\n$3\r\n2-0\r\n"
+
+	tests := []struct {
+		name     string
+		minIdle  time.Duration
+		start    redis.StreamID
+		options  []func(*redis.XAutoClaimCommand)
+		response string
+	}{
+		{
+			name:    "all idle entries",
+			minIdle: time.Second,
+			response: "*3\r\n$3\r\n0-0\r\n*4\r\n" +
+				entryResponse(1) + entryResponse(3) + entryResponse(4) + entryResponse(5) +
+				deleted,
+		},
+		{
+			name:     "COUNT includes deleted entries",
+			options:  []func(*redis.XAutoClaimCommand){redis.XAutoClaimCount(2)},
+			response: "*3\r\n$3\r\n3-0\r\n*1\r\n" + entryResponse(1) + deleted,
+		},
+		{
+			name: "JUSTID",
+			options: []func(*redis.XAutoClaimCommand){
+				redis.XAutoClaimCount(3), redis.XAutoClaimJustID(),
+			},
+			response: "*3\r\n$3\r\n4-0\r\n*2\r\n$3\r\n1-0\r\n$3\r\n3-0\r\n" + deleted,
+		},
+		{
+			name:     "entries not idle for long enough",
+			minIdle:  6 * time.Second,
+			response: "*3\r\n$3\r\n0-0\r\n*0\r\n" + deleted,
+		},
+		{
+			name:     "start",
+			start:    redis.StreamID{Ms: 4},
+			response: "*3\r\n$3\r\n0-0\r\n*2\r\n" + entryResponse(4) + entryResponse(5) + "*0\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := redis.NewStore()
+			parser := redis.NewParser(defaultEncodingRedisConfig, store, clock)
+			keysWith(t, parser,
+				append(xadds("stream", 5), []string{"XGROUP", "CREATE", "stream", "group", "0"})...)
+			readGroup(store, FakeClock{CurrentTime: time.UnixMilli(10000)}, "stream", "group", "alice", 0)
+			redis.NewXDelCommand(store, clock, "stream", []redis.StreamID{{Ms: 2}}).Run()
+
+			response := redis.NewXAutoClaimCommand(
+				store, clock, "stream", "group", "bob", tt.minIdle, tt.start, tt.options...,
+			).Run()
+			if response != tt.response {
+				t.Errorf(`command expected to return %#v but was %#v`, tt.response, response)
+			}
+		})
+	}
+}
+
+func TestXInfoStreamCommand(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{CurrentTime: time.UnixMilli(10000)}
+	parser := redis.NewParser(defaultEncodingRedisConfig, store, clock)
+	keysWith(t, parser,
+		append(xadds("stream", 3), []string{"XGROUP", "CREATE", "stream", "group", "0"})...)
+	readGroup(store, clock, "stream", "group", "alice", 1)
+	redis.NewXDelCommand(store, clock, "stream", []redis.StreamID{{Ms: 2}}).Run()
+	redis.NewXAddCommand(store, clock, "empty", redis.XAddID{ID: redis.StreamID{Ms: 1}}, []string{"n", "1"}).Run()
+	redis.NewXDelCommand(store, clock, "empty", []redis.StreamID{{Ms: 1}}).Run()
+
+	header := func(length, nodes int, lastID, maxDeletedID string, added int, firstID string) string {
+		return "$6\r\nlength\r\n:" + strconv.Itoa(length) + "\r\n" +
+			"$15\r\nradix-tree-keys\r\n:" + strconv.Itoa(nodes) + "\r\n" +
+			"$16\r\nradix-tree-nodes\r\n:" + strconv.Itoa(nodes) + "\r\n" +
+			"$17\r\nlast-generated-id\r\n$3\r\n" + lastID + "\r\n" +
+			"$20\r\nmax-deleted-entry-id\r\n$3\r\n" + maxDeletedID + "\r\n" +
+			"$13\r\nentries-added\r\n:" + strconv.Itoa(added) + "\r\n" +
+			"$23\r\nrecorded-first-entry-id\r\n$3\r\n" + firstID + "\r\n"
+	}
+
+	tests := []struct {
+		name     string
+		key      string
+		options  []func(*redis.XInfoStreamCommand)
+		response string
+	}{
+		{
+			name: "summary",
+			key:  "stream",
+			response: "*20\r\n" + header(2, 1, "3-0", "2-0", 3, "1-0") +
+				"$6\r\ngroups\r\n:1\r\n" +
+				"$11\r\nfirst-entry\r\n" + entryResponse(1) +
+				"$10\r\nlast-entry\r\n" + entryResponse(3),
+		},
+		{
+			name: "summary of empty stream",
+			key:  "empty",
+			response: "*20\r\n" + header(0, 0, "1-0", "1-0", 1, "0-0") +
+				"$6\r\ngroups\r\n:0\r\n" +
+				"$11\r\nfirst-entry\r\n$-1\r\n" +
+				"$10\r\nlast-entry\r\n$-1\r\n",
+		},
+		{
+			name:    "FULL",
+			key:     "stream",
+			options: []func(*redis.XInfoStreamCommand){redis.XInfoStreamFull(1)},
+			response: "*18\r\n" + header(2, 1, "3-0", "2-0", 3, "1-0") +
+				"$7\r\nentries\r\n*1\r\n" + entryResponse(1) +
+				"$6\r\ngroups\r\n*1\r\n*14\r\n" +
+				"$4\r\nname\r\n$5\r\ngroup\r\n" +
+				"$17\r\nlast-delivered-id\r\n$3\r\n1-0\r\n" +
+				"$12\r\nentries-read\r\n:1\r\n" +
+				"$3\r\nlag\r\n$-1\r\n" +
+				"$9\r\npel-count\r\n:1\r\n" +
+				"$7\r\npending\r\n*1\r\n*4\r\n$3\r\n1-0\r\n$5\r\nalice\r\n:10000\r\n:1\r\n" +
+				"$9\r\nconsumers\r\n*1\r\n*10\r\n" +
+				"$4\r\nname\r\n$5\r\nalice\r\n" +
+				"$9\r\nseen-time\r\n:10000\r\n" +
+				"$11\r\nactive-time\r\n:10000\r\n" +
+				"$9\r\npel-count\r\n:1\r\n" +
+				"$7\r\npending\r\n*1\r\n*3\r\n$3\r\n1-0\r\n:10000\r\n:1\r\n",
+		},
+		{
+			name:     "missing key",
+			key:      "missing",
+			response: "-ERR no such key\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := redis.NewXInfoStreamCommand(store, clock, tt.key, tt.options...).Run()
+			if response != tt.response {
+				t.Errorf(`command expected to return %#v but was %#v`, tt.response, response)
+			}
+		})
+	}
+}
+
+func TestXInfoGroupsCommand(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		setUp       func(store *redis.Store, clock redis.Clock)
+		consumers   int
+		pending     int
+		lastID      string
+		entriesRead string
+		lag         string
+	}{
+		{
+			name:        "unknown entries read",
+			setUp:       func(*redis.Store, redis.Clock) {},
+			lastID:      "0-0",
+			entriesRead: "$-1\r\n",
+			lag:         ":3\r\n",
+		},
+		{
+			name: "after reading",
+			setUp: func(store *redis.Store, clock redis.Clock) {
+				readGroup(store, clock, "stream", "group", "alice", 2)
+			},
+			consumers:   1,
+			pending:     2,
+			lastID:      "2-0",
+			entriesRead: ":2\r\n",
+			lag:         ":1\r\n",
+		},
+		{
+			name: "after trimming",
+			setUp: func(store *redis.Store, clock redis.Clock) {
+				readGroup(store, clock, "stream", "group", "alice", 2)
+				trim := redis.StreamTrim{Strategy: redis.StreamTrimMaxLen, MaxLen: 1}
+				redis.NewXTrimCommand(store, clock, "stream", trim).Run()
+			},
+			consumers:   1,
+			pending:     2,
+			lastID:      "2-0",
+			entriesRead: ":2\r\n",
+			lag:         ":1\r\n",
+		},
+		{
+			name: "after deleting an unread entry",
+			setUp: func(store *redis.Store, clock redis.Clock) {
+				readGroup(store, clock, "stream", "group", "alice", 1)
+				redis.NewXDelCommand(store, clock, "stream", []redis.StreamID{{Ms: 2}}).Run()
+			},
+			consumers:   1,
+			pending:     1,
+			lastID:      "1-0",
+			entriesRead: ":1\r\n",
+			lag:         "$-1\r\n",
+		},
+		{
+			name: "after reading past a deleted entry",
+			setUp: func(store *redis.Store, clock redis.Clock) {
+				redis.NewXDelCommand(store, clock, "stream", []redis.StreamID{{Ms: 2}}).Run()
+				readGroup(store, clock, "stream", "group", "alice", 0)
+			},
+			consumers:   1,
+			pending:     2,
+			lastID:      "3-0",
+			entriesRead: ":3\r\n",
+			lag:         ":0\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := redis.NewStore()
+			clock := FakeClock{}
+			parser := redis.NewParser(defaultEncodingRedisConfig, store, clock)
+			keysWith(t, parser,
+				append(xadds("stream", 3), []string{"XGROUP", "CREATE", "stream", "group", "0"})...)
+			tt.setUp(store, clock)
+
+			want := "*1\r\n*12\r\n" +
+				"$4\r\nname\r\n$5\r\ngroup\r\n" +
+				"$9\r\nconsumers\r\n:" + strconv.Itoa(tt.consumers) + "\r\n" +
+				"$7\r\npending\r\n:" + strconv.Itoa(tt.pending) + "\r\n" +
+				"$17\r\nlast-delivered-id\r\n$3\r\n" + tt.lastID + "\r\n" +
+				"$12\r\nentries-read\r\n" + tt.entriesRead +
+				"$3\r\nlag\r\n" + tt.lag
+			if response := redis.NewXInfoGroupsCommand(store, clock, "stream").Run(); response != want {
+				t.Errorf(`command expected to return %#v but was %#v`, want, response)
+			}
+		})
+	}
+}
+
+func TestXInfoConsumersCommand(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{CurrentTime: time.UnixMilli(10000)}
+	parser := redis.NewParser(defaultEncodingRedisConfig, store, clock)
+	keysWith(t, parser,
+		append(xadds("stream", 3), []string{"XGROUP", "CREATE", "stream", "group", "0"})...)
+	readGroup(store, clock, "stream", "group", "alice", 2)
+	redis.NewXGroupCreateConsumerCommand(store, clock, "stream", "group", "bob").Run()
+	later := FakeClock{CurrentTime: time.UnixMilli(15000)}
+
+	tests := []struct {
+		name     string
+		group    string
+		response string
+	}{
+		{
+			name:  "consumers",
+			group: "group",
+			response: "*2\r\n" +
+				"*8\r\n$4\r\nname\r\n$5\r\nalice\r\n$7\r\npending\r\n:2\r\n" +
+				"$4\r\nidle\r\n:5000\r\n$8\r\ninactive\r\n:5000\r\n" +
+				"*8\r\n$4\r\nname\r\n$3\r\nbob\r\n$7\r\npending\r\n:0\r\n" +
+				"$4\r\nidle\r\n:5000\r\n$8\r\ninactive\r\n:-1\r\n",
+		},
+		{
+			name:     "missing group",
+			group:    "missing",
+			response: "-NOGROUP No such consumer group 'missing' for key name 'stream'\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := redis.NewXInfoConsumersCommand(store, later, "stream", tt.group).Run()
+			if response != tt.response {
+				t.Errorf(`command expected to return %#v but was %#v`, tt.response, response)
+			}
+		})
+	}
+}
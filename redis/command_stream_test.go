@@ -0,0 +1,659 @@
+package redis_test
+
+import (
+	"math"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/redis"
+)
+
+// xadds returns the requests that add entries with IDs 1-0 up to n-0 to the stream at key, each
+// with a field "n" holding its number.
+func xadds(key string, n int) [][]string {
+	requests := make([][]string, n)
+	for i := range requests {
+		number := strconv.Itoa(i + 1)
+		requests[i] = []string{"XADD", key, number + "-0", "n", number}
+	}
+	return requests
+}
+
+// streamIDs returns the IDs of the entries in an XRANGE response whose entries each have a
+// single field.
+func streamIDs(t *testing.T, response string) []string {
+	t.Helper()
+
+	elements := bulkStrings(t, response)
+	var ids []string
+	for i := 0; i < len(elements); i += 3 {
+		ids = append(ids, elements[i])
+	}
+	return ids
+}
+
+// idRange returns the IDs "<from>-0" to "<to>-0", counting down if to is less than from.
+func idRange(from, to int) []string {
+	var ids []string
+	step := 1
+	if to < from {
+		step = -1
+	}
+	for i := from; i != to+step; i += step {
+		ids = append(ids, strconv.Itoa(i)+"-0")
+	}
+	return ids
+}
+
+const redisStreamIDNotGreater = "-ERR The ID specified in XADD is equal or smaller than the target " +
+	"stream top item\r\n"
+
+func TestXAddCommand(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		lastID    *redis.StreamID
+		id        redis.XAddID
+		response  string
+		wantAdded bool
+	}{
+		{
+			name:      "auto ID from clock",
+			id:        redis.XAddID{Auto: true},
+			response:  "$6\r\n1000-0\r\n",
+			wantAdded: true,
+		},
+		{
+			name:      "auto ID in same millisecond",
+			lastID:    &redis.StreamID{Ms: 1000, Seq: 4},
+			id:        redis.XAddID{Auto: true},
+			response:  "$6\r\n1000-5\r\n",
+			wantAdded: true,
+		},
+		{
+			name:      "auto ID when clock is behind",
+			lastID:    &redis.StreamID{Ms: 2000, Seq: 0},
+			id:        redis.XAddID{Auto: true},
+			response:  "$6\r\n2000-1\r\n",
+			wantAdded: true,
+		},
+		{
+			name:      "auto ID when sequence is exhausted",
+			lastID:    &redis.StreamID{Ms: 2000, Seq: math.MaxUint64},
+			id:        redis.XAddID{Auto: true},
+			response:  "$6\r\n2001-0\r\n",
+			wantAdded: true,
+		},
+		{
+			name:     "auto ID when stream is exhausted",
+			lastID:   &redis.StreamID{Ms: math.MaxUint64, Seq: math.MaxUint64},
+			id:       redis.XAddID{Auto: true},
+			response: "-ERR The stream has exhausted the last possible ID, unable to add more items\r\n",
+		},
+		{
+			name:      "auto sequence in empty stream",
+			id:        redis.XAddID{ID: redis.StreamID{Ms: 0}, AutoSeq: true},
+			response:  "$3\r\n0-1\r\n",
+			wantAdded: true,
+		},
+		{
+			name:      "auto sequence in new millisecond",
+			lastID:    &redis.StreamID{Ms: 5, Seq: 3},
+			id:        redis.XAddID{ID: redis.StreamID{Ms: 6}, AutoSeq: true},
+			response:  "$3\r\n6-0\r\n",
+			wantAdded: true,
+		},
+		{
+			name:      "auto sequence in same millisecond",
+			lastID:    &redis.StreamID{Ms: 5, Seq: 3},
+			id:        redis.XAddID{ID: redis.StreamID{Ms: 5}, AutoSeq: true},
+			response:  "$3\r\n5-4\r\n",
+			wantAdded: true,
+		},
+		{
+			name:     "auto sequence in earlier millisecond",
+			lastID:   &redis.StreamID{Ms: 5, Seq: 3},
+			id:       redis.XAddID{ID: redis.StreamID{Ms: 4}, AutoSeq: true},
+			response: redisStreamIDNotGreater,
+		},
+		{
+			name:      "explicit ID",
+			lastID:    &redis.StreamID{Ms: 5, Seq: 3},
+			id:        redis.XAddID{ID: redis.StreamID{Ms: 5, Seq: 4}},
+			response:  "$3\r\n5-4\r\n",
+			wantAdded: true,
+		},
+		{
+			name:     "explicit ID equal to last ID",
+			lastID:   &redis.StreamID{Ms: 5, Seq: 3},
+			id:       redis.XAddID{ID: redis.StreamID{Ms: 5, Seq: 3}},
+			response: redisStreamIDNotGreater,
+		},
+		{
+			name:     "explicit ID less than last ID",
+			lastID:   &redis.StreamID{Ms: 5, Seq: 3},
+			id:       redis.XAddID{ID: redis.StreamID{Ms: 4, Seq: 9}},
+			response: redisStreamIDNotGreater,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := redis.NewStore()
+			clock := FakeClock{CurrentTime: time.UnixMilli(1000)}
+			wantLen := 0
+			if tt.lastID != nil {
+				id := redis.XAddID{ID: *tt.lastID}
+				redis.NewXAddCommand(store, clock, "stream", id, []string{"a", "1"}).Run()
+				wantLen++
+			}
+			if tt.wantAdded {
+				wantLen++
+			}
+
+			response := redis.NewXAddCommand(store, clock, "stream", tt.id, []string{"a", "2"}).Run()
+
+			if response != tt.response {
+				t.Errorf(`command expected to return %#v but was %#v`, tt.response, response)
+			}
+			want := ":" + strconv.Itoa(wantLen) + "\r\n"
+			if response := redis.NewXLenCommand(store, clock, "stream").Run(); response != want {
+				t.Errorf(`XLEN expected to return %#v but was %#v`, want, response)
+			}
+		})
+	}
+}
+
+func TestXAddCommand_LastIDSurvivesDeletion(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{}
+	parser := redis.NewParser(defaultEncodingRedisConfig, store, clock)
+	keysWith(t, parser, xadds("stream", 3)...)
+	redis.NewXDelCommand(store, clock, "stream", []redis.StreamID{{Ms: 3}}).Run()
+
+	response := redis.NewXAddCommand(
+		store, clock, "stream", redis.XAddID{ID: redis.StreamID{Ms: 3}}, []string{"a", "1"},
+	).Run()
+
+	if response != redisStreamIDNotGreater {
+		t.Errorf(`command expected to return %#v but was %#v`, redisStreamIDNotGreater, response)
+	}
+}
+
+func TestXAddCommand_NoMkStream(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{}
+
+	response := redis.NewXAddCommand(
+		store, clock, "stream", redis.XAddID{Auto: true}, []string{"a", "1"}, redis.XAddNoMkStream(),
+	).Run()
+
+	if response != redisNullBulkString {
+		t.Errorf(`command expected to return %#v but was %#v`, redisNullBulkString, response)
+	}
+	if _, ok := store.Get("stream"); ok {
+		t.Errorf(`store expected not to contain "stream"`)
+	}
+}
+
+func TestXAddCommand_Trim(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{}
+	parser := redis.NewParser(defaultEncodingRedisConfig, store, clock)
+	keysWith(t, parser, xadds("stream", 5)...)
+
+	redis.NewXAddCommand(
+		store, clock, "stream", redis.XAddID{ID: redis.StreamID{Ms: 6}}, []string{"n", "6"},
+		redis.XAddTrim(redis.StreamTrim{Strategy: redis.StreamTrimMaxLen, MaxLen: 2}),
+	).Run()
+
+	response := redis.NewXRangeCommand(store, clock, "stream", redis.StreamID{}, maxStreamID).Run()
+	if got, want := streamIDs(t, response), idRange(5, 6); !equalStrings(got, want) {
+		t.Errorf(`XRANGE expected to return %#v but was %#v`, want, got)
+	}
+}
+
+var maxStreamID = redis.StreamID{Ms: math.MaxUint64, Seq: math.MaxUint64}
+
+func TestXRangeCommand(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{}
+	parser := redis.NewParser(defaultEncodingRedisConfig, store, clock)
+	// Enough entries to span several nodes.
+	keysWith(t, parser, xadds("stream", 250)...)
+	redis.NewXDelCommand(store, clock, "stream", []redis.StreamID{{Ms: 100}, {Ms: 101}}).Run()
+
+	tests := []struct {
+		name    string
+		start   redis.StreamID
+		end     redis.StreamID
+		options []func(*redis.XRangeCommand)
+		want    []string
+	}{
+		{name: "single entry", start: redis.StreamID{Ms: 7}, end: redis.StreamID{Ms: 7}, want: idRange(7, 7)},
+		{
+			name:  "inclusive",
+			start: redis.StreamID{Ms: 3},
+			end:   redis.StreamID{Ms: 5},
+			want:  idRange(3, 5),
+		},
+		{
+			name:  "between entries",
+			start: redis.StreamID{Ms: 3, Seq: 1},
+			end:   redis.StreamID{Ms: 5, Seq: 1},
+			want:  idRange(4, 5),
+		},
+		{
+			name:  "across nodes and deleted entries",
+			start: redis.StreamID{Ms: 98},
+			end:   redis.StreamID{Ms: 103},
+			want:  []string{"98-0", "99-0", "102-0", "103-0"},
+		},
+		{
+			name:    "COUNT",
+			start:   redis.StreamID{Ms: 240},
+			end:     maxStreamID,
+			options: []func(*redis.XRangeCommand){redis.XRangeCount(3)},
+			want:    idRange(240, 242),
+		},
+		{
+			name:    "reverse",
+			start:   redis.StreamID{Ms: 98},
+			end:     redis.StreamID{Ms: 103},
+			options: []func(*redis.XRangeCommand){redis.XRangeReverse()},
+			want:    []string{"103-0", "102-0", "99-0", "98-0"},
+		},
+		{
+			name:    "reverse between entries",
+			start:   redis.StreamID{},
+			end:     redis.StreamID{Ms: 200, Seq: 1},
+			options: []func(*redis.XRangeCommand){redis.XRangeReverse(), redis.XRangeCount(3)},
+			want:    idRange(200, 198),
+		},
+		{
+			name:    "reverse from the end",
+			start:   redis.StreamID{},
+			end:     maxStreamID,
+			options: []func(*redis.XRangeCommand){redis.XRangeReverse(), redis.XRangeCount(2)},
+			want:    idRange(250, 249),
+		},
+		{
+			name:    "reverse from before a node",
+			start:   redis.StreamID{},
+			end:     redis.StreamID{Ms: 201},
+			options: []func(*redis.XRangeCommand){redis.XRangeReverse(), redis.XRangeCount(3)},
+			want:    idRange(201, 199),
+		},
+		{name: "empty", start: redis.StreamID{Ms: 300}, end: maxStreamID, want: nil},
+		{name: "start after end", start: redis.StreamID{Ms: 5}, end: redis.StreamID{Ms: 3}, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := redis.NewXRangeCommand(store, clock, "stream", tt.start, tt.end, tt.options...).Run()
+			if got := streamIDs(t, response); !equalStrings(got, tt.want) {
+				t.Errorf(`command expected to return %#v but was %#v`, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestXRangeCommand_Response(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{}
+	redis.NewXAddCommand(
+		store, clock, "stream", redis.XAddID{ID: redis.StreamID{Ms: 1, Seq: 2}}, []string{"a", "1", "b", "2"},
+	).Run()
+
+	tests := []struct {
+		name     string
+		key      string
+		options  []func(*redis.XRangeCommand)
+		response string
+	}{
+		{
+			name:     "entries",
+			key:      "stream",
+			response: "*1\r\n*2\r\n$3\r\n1-2\r\n*4\r\n$1\r\na\r\n$1\r\n1\r\n$1\r\nb\r\n$1\r\n2\r\n",
+		},
+		{name: "COUNT 0", key: "stream", options: []func(*redis.XRangeCommand){redis.XRangeCount(0)}, response: "*-1\r\n"},
+		{name: "absent key", key: "nope", response: "*0\r\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := redis.NewXRangeCommand(store, clock, tt.key, redis.StreamID{}, maxStreamID, tt.options...).Run()
+			if response != tt.response {
+				t.Errorf(`command expected to return %#v but was %#v`, tt.response, response)
+			}
+		})
+	}
+}
+
+func TestXTrimCommand(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		trim     redis.StreamTrim
+		response string
+		want     []string
+	}{
+		{
+			name:     "MAXLEN",
+			trim:     redis.StreamTrim{Strategy: redis.StreamTrimMaxLen, MaxLen: 10},
+			response: ":240\r\n",
+			want:     idRange(241, 250),
+		},
+		{
+			name:     "MAXLEN 0",
+			trim:     redis.StreamTrim{Strategy: redis.StreamTrimMaxLen},
+			response: ":250\r\n",
+			want:     nil,
+		},
+		{
+			name:     "MAXLEN longer than stream",
+			trim:     redis.StreamTrim{Strategy: redis.StreamTrimMaxLen, MaxLen: 300},
+			response: ":0\r\n",
+			want:     idRange(1, 250),
+		},
+		{
+			name:     "MAXLEN ~ only removes whole nodes",
+			trim:     redis.StreamTrim{Strategy: redis.StreamTrimMaxLen, MaxLen: 10, Approximate: true},
+			response: ":200\r\n",
+			want:     idRange(201, 250),
+		},
+		{
+			name:     "MAXLEN ~ LIMIT",
+			trim:     redis.StreamTrim{Strategy: redis.StreamTrimMaxLen, MaxLen: 10, Approximate: true, Limit: 150},
+			response: ":100\r\n",
+			want:     idRange(101, 250),
+		},
+		{
+			name:     "MINID",
+			trim:     redis.StreamTrim{Strategy: redis.StreamTrimMinID, MinID: redis.StreamID{Ms: 150}},
+			response: ":149\r\n",
+			want:     idRange(150, 250),
+		},
+		{
+			name:     "MINID ~",
+			trim:     redis.StreamTrim{Strategy: redis.StreamTrimMinID, MinID: redis.StreamID{Ms: 150}, Approximate: true},
+			response: ":100\r\n",
+			want:     idRange(101, 250),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := redis.NewStore()
+			clock := FakeClock{}
+			parser := redis.NewParser(defaultEncodingRedisConfig, store, clock)
+			keysWith(t, parser, xadds("stream", 250)...)
+
+			if response := redis.NewXTrimCommand(store, clock, "stream", tt.trim).Run(); response != tt.response {
+				t.Errorf(`command expected to return %#v but was %#v`, tt.response, response)
+			}
+			response := redis.NewXRangeCommand(store, clock, "stream", redis.StreamID{}, maxStreamID).Run()
+			if got := streamIDs(t, response); !equalStrings(got, tt.want) {
+				t.Errorf(`XRANGE expected to return %#v but was %#v`, tt.want, got)
+			}
+			want := ":" + strconv.Itoa(len(tt.want)) + "\r\n"
+			if response := redis.NewXLenCommand(store, clock, "stream").Run(); response != want {
+				t.Errorf(`XLEN expected to return %#v but was %#v`, want, response)
+			}
+		})
+	}
+}
+
+func TestXDelCommand(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{}
+	parser := redis.NewParser(defaultEncodingRedisConfig, store, clock)
+	keysWith(t, parser, xadds("stream", 3)...)
+
+	response := redis.NewXDelCommand(store, clock, "stream", []redis.StreamID{{Ms: 1}, {Ms: 3}, {Ms: 4}}).Run()
+
+	if response != ":2\r\n" {
+		t.Errorf(`command expected to return ":2\r\n" but was %#v`, response)
+	}
+	response = redis.NewXRangeCommand(store, clock, "stream", redis.StreamID{}, maxStreamID).Run()
+	if got := streamIDs(t, response); !equalStrings(got, []string{"2-0"}) {
+		t.Errorf(`XRANGE expected to return ["2-0"] but was %#v`, got)
+	}
+
+	redis.NewXDelCommand(store, clock, "stream", []redis.StreamID{{Ms: 2}}).Run()
+	if response := redis.NewXLenCommand(store, clock, "stream").Run(); response != ":0\r\n" {
+		t.Errorf(`XLEN expected to return ":0\r\n" but was %#v`, response)
+	}
+	if _, ok := store.Get("stream"); !ok {
+		t.Errorf(`store expected to still contain the empty "stream"`)
+	}
+}
+
+func TestStreamCommands_WrongType(t *testing.T) {
+	t.Parallel()
+
+	const wrongType = "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n"
+	store := redis.NewStore()
+	store.Set("string", "zelda")
+	clock := FakeClock{}
+
+	tests := []struct {
+		name    string
+		command redis.Command
+	}{
+		{name: "XADD", command: redis.NewXAddCommand(store, clock, "string", redis.XAddID{Auto: true}, []string{"a", "1"})},
+		{name: "XRANGE", command: redis.NewXRangeCommand(store, clock, "string", redis.StreamID{}, maxStreamID)},
+		{name: "XLEN", command: redis.NewXLenCommand(store, clock, "string")},
+		{name: "XDEL", command: redis.NewXDelCommand(store, clock, "string", []redis.StreamID{{Ms: 1}})},
+		{
+			name:    "XTRIM",
+			command: redis.NewXTrimCommand(store, clock, "string", redis.StreamTrim{Strategy: redis.StreamTrimMaxLen}),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if response := tt.command.Run(); response != wrongType {
+				t.Errorf(`command expected to return %#v but was %#v`, wrongType, response)
+			}
+		})
+	}
+}
+
+func TestXReadCommand(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	store.Set("string", "zelda")
+	clock := FakeClock{}
+	parser := redis.NewParser(defaultEncodingRedisConfig, store, clock)
+	client := redis.NewClients().NewClient()
+	keysWith(t, parser, xadds("a", 3)...)
+	keysWith(t, parser, xadds("b", 1)...)
+
+	tests := []struct {
+		name     string
+		keys     []string
+		ids      []redis.XReadID
+		options  []func(*redis.XReadCommand)
+		response string
+	}{
+		{
+			name: "several streams",
+			keys: []string{"a", "b"},
+			ids:  []redis.XReadID{{ID: redis.StreamID{Ms: 1}}, {}},
+			response: "*2\r\n" +
+				"*2\r\n$1\r\na\r\n*2\r\n" +
+				"*2\r\n$3\r\n2-0\r\n*2\r\n$1\r\nn\r\n$1\r\n2\r\n" +
+				"*2\r\n$3\r\n3-0\r\n*2\r\n$1\r\nn\r\n$1\r\n3\r\n" +
+				"*2\r\n$1\r\nb\r\n*1\r\n" +
+				"*2\r\n$3\r\n1-0\r\n*2\r\n$1\r\nn\r\n$1\r\n1\r\n",
+		},
+		{
+			name:    "COUNT",
+			keys:    []string{"a"},
+			ids:     []redis.XReadID{{}},
+			options: []func(*redis.XReadCommand){redis.XReadCount(1)},
+			response: "*1\r\n" +
+				"*2\r\n$1\r\na\r\n*1\r\n" +
+				"*2\r\n$3\r\n1-0\r\n*2\r\n$1\r\nn\r\n$1\r\n1\r\n",
+		},
+		{
+			name: "only streams with new entries",
+			keys: []string{"a", "b", "absent"},
+			ids:  []redis.XReadID{{ID: redis.StreamID{Ms: 2, Seq: 5}}, {ID: redis.StreamID{Ms: 1}}, {}},
+			response: "*1\r\n" +
+				"*2\r\n$1\r\na\r\n*1\r\n" +
+				"*2\r\n$3\r\n3-0\r\n*2\r\n$1\r\nn\r\n$1\r\n3\r\n",
+		},
+		{
+			name: "+",
+			keys: []string{"a"},
+			ids:  []redis.XReadID{{Last: true}},
+			response: "*1\r\n" +
+				"*2\r\n$1\r\na\r\n*1\r\n" +
+				"*2\r\n$3\r\n3-0\r\n*2\r\n$1\r\nn\r\n$1\r\n3\r\n",
+		},
+		{
+			name:     "$",
+			keys:     []string{"a"},
+			ids:      []redis.XReadID{{New: true}},
+			response: "*-1\r\n",
+		},
+		{
+			name:     "no new entries",
+			keys:     []string{"a", "absent"},
+			ids:      []redis.XReadID{{ID: redis.StreamID{Ms: 3}}, {}},
+			response: "*-1\r\n",
+		},
+		{
+			name:     "wrong type",
+			keys:     []string{"a", "string"},
+			ids:      []redis.XReadID{{}, {}},
+			response: "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n",
+		},
+		{
+			name:     "wrong type when blocking",
+			keys:     []string{"string"},
+			ids:      []redis.XReadID{{}},
+			options:  []func(*redis.XReadCommand){redis.XReadBlock(0)},
+			response: "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n",
+		},
+		{
+			name:    "BLOCK with entries",
+			keys:    []string{"absent", "b"},
+			ids:     []redis.XReadID{{}, {}},
+			options: []func(*redis.XReadCommand){redis.XReadBlock(0)},
+			response: "*1\r\n" +
+				"*2\r\n$1\r\nb\r\n*1\r\n" +
+				"*2\r\n$3\r\n1-0\r\n*2\r\n$1\r\nn\r\n$1\r\n1\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := redis.NewXReadCommand(store, clock, client, tt.keys, tt.ids, tt.options...).Run()
+			if response != tt.response {
+				t.Errorf(`command expected to return %#v but was %#v`, tt.response, response)
+			}
+		})
+	}
+}
+
+func TestXReadCommand_Block(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{}
+	parser := redis.NewParser(defaultEncodingRedisConfig, store, clock)
+	clients := redis.NewClients()
+	keysWith(t, parser, xadds("a", 1)...)
+
+	var responses []chan string
+	for i := 0; i < 2; i++ {
+		client := clients.NewClient()
+		response := make(chan string, 1)
+		go func() {
+			response <- redis.NewXReadCommand(
+				store, clock, client, []string{"a", "b"}, []redis.XReadID{{New: true}, {New: true}},
+				redis.XReadBlock(0),
+			).Run()
+		}()
+		waitUntilBlocked(t, client)
+		responses = append(responses, response)
+	}
+
+	redis.NewXAddCommand(store, clock, "b", redis.XAddID{ID: redis.StreamID{Ms: 5}}, []string{"x", "y"}).Run()
+
+	// Every client blocked on the stream reads the new entry, unlike with BLPOP.
+	want := "*1\r\n*2\r\n$1\r\nb\r\n*1\r\n*2\r\n$3\r\n5-0\r\n*2\r\n$1\r\nx\r\n$1\r\ny\r\n"
+	for i, response := range responses {
+		if got := <-response; got != want {
+			t.Errorf(`client %d expected to read %#v but was %#v`, i, want, got)
+		}
+	}
+}
+
+func TestXReadCommand_BlockGivesUp(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		timeout time.Duration
+		giveUp  func(client *redis.Client, clock FakeClock)
+	}{
+		{
+			name:    "timeout",
+			timeout: time.Second,
+			giveUp: func(_ *redis.Client, clock FakeClock) {
+				close(clock.Timeout)
+			},
+		},
+		{
+			name: "client closed while blocked forever",
+			giveUp: func(client *redis.Client, _ FakeClock) {
+				client.Close()
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := redis.NewStore()
+			clock := FakeClock{Timeout: make(chan time.Time)}
+			client := redis.NewClients().NewClient()
+			response := make(chan string, 1)
+			go func() {
+				response <- redis.NewXReadCommand(
+					store, clock, client, []string{"stream"}, []redis.XReadID{{New: true}},
+					redis.XReadBlock(tt.timeout),
+				).Run()
+			}()
+			waitUntilBlocked(t, client)
+
+			tt.giveUp(client, clock)
+
+			if got := <-response; got != "*-1\r\n" {
+				t.Errorf(`command expected to return "*-1\r\n" but was %#v`, got)
+			}
+			if client.Blocked() {
+				t.Errorf("client expected not to be blocked")
+			}
+		})
+	}
+}
@@ -0,0 +1,550 @@
+package redis
+
+import "time"
+
+// maxStringLength is the largest string that can be stored, which matches Redis's default
+// proto-max-bulk-len of 512MB.
+const maxStringLength = 512 * 1024 * 1024
+
+const errStringTooLong CommandError = "ERR string exceeds maximum allowed size (proto-max-bulk-len)"
+
+func NewAppendCommand(store *Store, clock Clock, key, value string) *AppendCommand {
+	return &AppendCommand{
+		store: store,
+		clock: clock,
+		key:   key,
+		value: value,
+	}
+}
+
+type AppendCommand struct {
+	store *Store
+	clock Clock
+	key   string
+	value string
+}
+
+func (a *AppendCommand) Run() string {
+	var response string
+	a.store.write(a.clock.NowMonotonic(), func(tx *storeTx) {
+		current, ok, err := tx.getTyped(a.key, ValueTypeString)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		if !ok {
+			tx.set(a.key, NewStoreValue(a.value))
+			tx.notify(notifyString, "append", a.key)
+			response = integer(len(a.value))
+			return
+		}
+		data := current.bytes()
+		if len(data)+len(a.value) > maxStringLength {
+			response = errStringTooLong.Response()
+			return
+		}
+		tx.set(a.key, current.withData(append(data, a.value...)))
+		tx.notify(notifyString, "append", a.key)
+		response = integer(len(data) + len(a.value))
+	})
+	return response
+}
+
+func NewStrLenCommand(store *Store, clock Clock, key string) *StrLenCommand {
+	return &StrLenCommand{
+		store: store,
+		clock: clock,
+		key:   key,
+	}
+}
+
+type StrLenCommand struct {
+	store *Store
+	clock Clock
+	key   string
+}
+
+func (s *StrLenCommand) Run() string {
+	var response string
+	s.store.read(s.clock.NowMonotonic(), func(tx *storeTx) {
+		value, _, err := tx.getTyped(s.key, ValueTypeString)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		response = integer(len(value.bytes()))
+	})
+	return response
+}
+
+func NewGetRangeCommand(store *Store, clock Clock, key string, start, end int) *GetRangeCommand {
+	return &GetRangeCommand{
+		store: store,
+		clock: clock,
+		key:   key,
+		start: start,
+		end:   end,
+	}
+}
+
+type GetRangeCommand struct {
+	store *Store
+	clock Clock
+	key   string
+	start int
+	end   int
+}
+
+func (g *GetRangeCommand) Run() string {
+	var data string
+	var err error
+	g.store.read(g.clock.NowMonotonic(), func(tx *storeTx) {
+		var value StoreValue
+		value, _, err = tx.getTyped(g.key, ValueTypeString)
+		data = string(value.bytes())
+	})
+	if err != nil {
+		return errorResponse(err)
+	}
+
+	start, end := g.start, g.end
+	if start < 0 && end < 0 && start > end {
+		return bulkString("")
+	}
+	if start < 0 {
+		start += len(data)
+	}
+	if end < 0 {
+		end += len(data)
+	}
+	if start < 0 {
+		start = 0
+	}
+	if end < 0 {
+		end = 0
+	}
+	if end >= len(data) {
+		end = len(data) - 1
+	}
+	if start > end || len(data) == 0 {
+		return bulkString("")
+	}
+	return bulkString(data[start : end+1])
+}
+
+func NewSetRangeCommand(store *Store, clock Clock, key string, offset int, value string) *SetRangeCommand {
+	return &SetRangeCommand{
+		store:  store,
+		clock:  clock,
+		key:    key,
+		offset: offset,
+		value:  value,
+	}
+}
+
+type SetRangeCommand struct {
+	store  *Store
+	clock  Clock
+	key    string
+	offset int
+	value  string
+}
+
+func (s *SetRangeCommand) Run() string {
+	var response string
+	s.store.write(s.clock.NowMonotonic(), func(tx *storeTx) {
+		current, ok, err := tx.getTyped(s.key, ValueTypeString)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		data := current.bytes()
+		if len(s.value) == 0 {
+			// Like Redis, don't create the key or pad it with zero bytes if there's nothing to
+			// write.
+			response = integer(len(data))
+			return
+		}
+		if s.offset+len(s.value) > maxStringLength {
+			response = errStringTooLong.Response()
+			return
+		}
+
+		if end := s.offset + len(s.value); len(data) < end {
+			// Pad with zero bytes up to the offset, like Redis does.
+			data = append(data, make([]byte, end-len(data))...)
+		}
+		copy(data[s.offset:], s.value)
+
+		if ok {
+			tx.set(s.key, current.withData(data))
+		} else {
+			tx.set(s.key, StoreValue{data: data})
+		}
+		tx.notify(notifyString, "setrange", s.key)
+		response = integer(len(data))
+	})
+	return response
+}
+
+func NewGetDelCommand(store *Store, clock Clock, key string) *GetDelCommand {
+	return &GetDelCommand{
+		store: store,
+		clock: clock,
+		key:   key,
+	}
+}
+
+type GetDelCommand struct {
+	store *Store
+	clock Clock
+	key   string
+}
+
+func (g *GetDelCommand) Run() string {
+	response := nullBulkString
+	g.store.write(g.clock.NowMonotonic(), func(tx *storeTx) {
+		value, ok, err := tx.getTyped(g.key, ValueTypeString)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		if !ok {
+			return
+		}
+		tx.delete(g.key)
+		tx.notify(notifyGeneric, "del", g.key)
+		response = bulkString(string(value.bytes()))
+	})
+	return response
+}
+
+func NewGetExCommand(
+	store *Store,
+	clock Clock,
+	key string,
+	options ...func(*GetExCommand),
+) *GetExCommand {
+	result := &GetExCommand{
+		store: store,
+		clock: clock,
+		key:   key,
+	}
+	for _, option := range options {
+		option(result)
+	}
+	return result
+}
+
+type GetExCommand struct {
+	store      *Store
+	clock      Clock
+	key        string
+	expiryTime *time.Time
+	persist    bool
+}
+
+func (g *GetExCommand) Run() string {
+	response := nullBulkString
+	g.store.write(g.clock.NowMonotonic(), func(tx *storeTx) {
+		value, ok, err := tx.getTyped(g.key, ValueTypeString)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		if !ok {
+			return
+		}
+		response = bulkString(string(value.bytes()))
+
+		switch {
+		case g.persist && value.expiryTime != nil:
+			value.expiryTime = nil
+			tx.set(g.key, value)
+			tx.notify(notifyGeneric, "persist", g.key)
+		case g.expiryTime != nil:
+			expiryTime := *g.expiryTime
+			value.expiryTime = &expiryTime
+			tx.set(g.key, value)
+			tx.notify(notifyGeneric, "expire", g.key)
+		}
+	})
+	return response
+}
+
+// GetExExpiryTime makes a GetExCommand set the key's expiry time, like GETEX's EX, PX, EXAT and
+// PXAT options.
+func GetExExpiryTime(t time.Time) func(*GetExCommand) {
+	return func(command *GetExCommand) {
+		command.expiryTime = &t
+	}
+}
+
+// GetExPersist makes a GetExCommand remove the key's expiry time, like GETEX's PERSIST option.
+func GetExPersist() func(*GetExCommand) {
+	return func(command *GetExCommand) {
+		command.persist = true
+	}
+}
+
+func NewGetSetCommand(store *Store, clock Clock, key, value string) *GetSetCommand {
+	return &GetSetCommand{
+		store: store,
+		clock: clock,
+		key:   key,
+		value: value,
+	}
+}
+
+type GetSetCommand struct {
+	store *Store
+	clock Clock
+	key   string
+	value string
+}
+
+func (g *GetSetCommand) Run() string {
+	response := nullBulkString
+	g.store.write(g.clock.NowMonotonic(), func(tx *storeTx) {
+		value, ok, err := tx.getTyped(g.key, ValueTypeString)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		if ok {
+			response = bulkString(string(value.bytes()))
+		}
+		tx.set(g.key, NewStoreValue(g.value))
+		tx.notify(notifyString, "set", g.key)
+	})
+	return response
+}
+
+func NewLCSCommand(
+	store *Store,
+	clock Clock,
+	key1,
+	key2 string,
+	options ...func(*LCSCommand),
+) *LCSCommand {
+	result := &LCSCommand{
+		store: store,
+		clock: clock,
+		key1:  key1,
+		key2:  key2,
+	}
+	for _, option := range options {
+		option(result)
+	}
+	return result
+}
+
+type LCSCommand struct {
+	store        *Store
+	clock        Clock
+	key1         string
+	key2         string
+	length       bool
+	indexes      bool
+	minMatchLen  int
+	withMatchLen bool
+}
+
+// LCSLen makes an LCSCommand return the length of the match, like LCS's LEN option.
+func LCSLen() func(*LCSCommand) {
+	return func(command *LCSCommand) {
+		command.length = true
+	}
+}
+
+// LCSIdx makes an LCSCommand return the positions of each match, like LCS's IDX option.
+func LCSIdx() func(*LCSCommand) {
+	return func(command *LCSCommand) {
+		command.indexes = true
+	}
+}
+
+// LCSMinMatchLen makes an LCSCommand leave out matches shorter than n when returning the
+// positions of each match, like LCS's MINMATCHLEN option.
+func LCSMinMatchLen(n int) func(*LCSCommand) {
+	return func(command *LCSCommand) {
+		command.minMatchLen = n
+	}
+}
+
+// LCSWithMatchLen makes an LCSCommand return the length of each match alongside its position,
+// like LCS's WITHMATCHLEN option.
+func LCSWithMatchLen() func(*LCSCommand) {
+	return func(command *LCSCommand) {
+		command.withMatchLen = true
+	}
+}
+
+func (l *LCSCommand) Run() string {
+	var a, b string
+	var err error
+	l.store.read(l.clock.NowMonotonic(), func(tx *storeTx) {
+		var value1, value2 StoreValue
+		if value1, _, err = tx.getTyped(l.key1, ValueTypeString); err != nil {
+			return
+		}
+		if value2, _, err = tx.getTyped(l.key2, ValueTypeString); err != nil {
+			return
+		}
+		a, b = string(value1.bytes()), string(value2.bytes())
+	})
+	if err != nil {
+		return errorResponse(err)
+	}
+
+	// lengths[i][j] is the length of the longest common subsequence of a[:i] and b[:j].
+	lengths := make([][]int, len(a)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(b)+1)
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			if a[i-1] == b[j-1] {
+				lengths[i][j] = lengths[i-1][j-1] + 1
+			} else if lengths[i-1][j] > lengths[i][j-1] {
+				lengths[i][j] = lengths[i-1][j]
+			} else {
+				lengths[i][j] = lengths[i][j-1]
+			}
+		}
+	}
+	length := lengths[len(a)][len(b)]
+
+	if l.length && !l.indexes {
+		return integer(length)
+	}
+
+	// Walk back from the end of both strings to recover the subsequence, along with the
+	// contiguous ranges of each string that make it up. This follows Redis's implementation so
+	// that ranges are returned in the same order.
+	result := make([]byte, length)
+	var matches []string
+	const noRange = -1
+	aStart, aEnd, bStart, bEnd := noRange, 0, 0, 0
+	for i, j, k := len(a), len(b), length; i > 0 && j > 0; {
+		emitRange := false
+		if a[i-1] == b[j-1] {
+			result[k-1] = a[i-1]
+			if aStart == noRange {
+				aStart, aEnd, bStart, bEnd = i-1, i-1, j-1, j-1
+			} else if aStart == i && bStart == j {
+				aStart--
+				bStart--
+			} else {
+				emitRange = true
+			}
+			if aStart == 0 || bStart == 0 {
+				emitRange = true
+			}
+			i--
+			j--
+			k--
+		} else {
+			if lengths[i-1][j] > lengths[i][j-1] {
+				i--
+			} else {
+				j--
+			}
+			if aStart != noRange {
+				emitRange = true
+			}
+		}
+
+		if emitRange {
+			matchLen := aEnd - aStart + 1
+			if l.minMatchLen == 0 || matchLen >= l.minMatchLen {
+				match := []string{
+					array(integer(aStart), integer(aEnd)),
+					array(integer(bStart), integer(bEnd)),
+				}
+				if l.withMatchLen {
+					match = append(match, integer(matchLen))
+				}
+				matches = append(matches, array(match...))
+			}
+			aStart = noRange
+		}
+	}
+
+	if !l.indexes {
+		return bulkString(string(result))
+	}
+	return array(
+		bulkString("matches"),
+		array(matches...),
+		bulkString("len"),
+		integer(length),
+	)
+}
+
+func NewMSetCommand(store *Store, keyValues []KeyValue) *MSetCommand {
+	return &MSetCommand{
+		store:     store,
+		keyValues: keyValues,
+	}
+}
+
+type MSetCommand struct {
+	store     *Store
+	keyValues []KeyValue
+}
+
+func (m *MSetCommand) Run() string {
+	m.store.SetMultiple(m.keyValues)
+	return simpleString("OK")
+}
+
+func NewMSetNXCommand(store *Store, clock Clock, keyValues []KeyValue) *MSetNXCommand {
+	return &MSetNXCommand{
+		store:     store,
+		clock:     clock,
+		keyValues: keyValues,
+	}
+}
+
+type MSetNXCommand struct {
+	store     *Store
+	clock     Clock
+	keyValues []KeyValue
+}
+
+func (m *MSetNXCommand) Run() string {
+	if m.store.SetMultipleIfAllAbsent(m.clock.NowMonotonic(), m.keyValues) {
+		return integer(1)
+	}
+	return integer(0)
+}
+
+func NewMGetCommand(store *Store, clock Clock, keys []string) *MGetCommand {
+	return &MGetCommand{
+		store: store,
+		clock: clock,
+		keys:  keys,
+	}
+}
+
+type MGetCommand struct {
+	store *Store
+	clock Clock
+	keys  []string
+}
+
+func (m *MGetCommand) Run() string {
+	values := make([]string, len(m.keys))
+	m.store.read(m.clock.NowMonotonic(), func(tx *storeTx) {
+		for i, key := range m.keys {
+			value, ok := tx.get(key)
+			if !ok || value.Type() != ValueTypeString {
+				values[i] = nullBulkString
+				continue
+			}
+			values[i] = bulkString(string(value.bytes()))
+		}
+	})
+	return array(values...)
+}
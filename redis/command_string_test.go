@@ -0,0 +1,424 @@
+package redis_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/redis"
+)
+
+func TestAppendCommand(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		store    func() *redis.Store
+		value    string
+		response string
+		data     string
+	}{
+		{
+			name:     "absent key",
+			store:    redis.NewStore,
+			value:    "zelda",
+			response: ":5\r\n",
+			data:     "zelda",
+		},
+		{
+			name:     "present key",
+			store:    storeWith("link", "zel"),
+			value:    "da",
+			response: ":5\r\n",
+			data:     "zelda",
+		},
+		{
+			name:     "expired key",
+			store:    storeWithExpiryTime("link", "ganon", time.Unix(0, 0)),
+			value:    "zelda",
+			response: ":5\r\n",
+			data:     "zelda",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := tt.store()
+			clock := FakeClock{CurrentTime: time.Unix(0, 1)}
+
+			response := redis.NewAppendCommand(store, clock, "link", tt.value).Run()
+
+			if response != tt.response {
+				t.Errorf(`command expected to return %#v but was %#v`, tt.response, response)
+			}
+			if value, _ := store.Get("link"); value.Data() != tt.data {
+				t.Errorf(`value.Data() expected to be %#v but was %#v`, tt.data, value.Data())
+			}
+		})
+	}
+}
+
+func TestAppendCommand_KeepsExpiryTime(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	store.SetWithExpiryTime("link", "zel", time.UnixMilli(100))
+	clock := FakeClock{CurrentTime: time.UnixMilli(0)}
+
+	redis.NewAppendCommand(store, clock, "link", "da").Run()
+
+	value, _ := store.Get("link")
+	if value.ExpiryTime() == nil || !value.ExpiryTime().Equal(time.UnixMilli(100)) {
+		t.Errorf(
+			`value.ExpiryTime() expected to be &time.UnixMilli(100) but was %#v`,
+			value.ExpiryTime(),
+		)
+	}
+}
+
+func TestStrLenCommand(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	store.Set("link", "zelda")
+	clock := FakeClock{}
+
+	if response := redis.NewStrLenCommand(store, clock, "link").Run(); response != ":5\r\n" {
+		t.Errorf(`command expected to return ":5\r\n" but was %#v`, response)
+	}
+	if response := redis.NewStrLenCommand(store, clock, "absent").Run(); response != ":0\r\n" {
+		t.Errorf(`command expected to return ":0\r\n" but was %#v`, response)
+	}
+}
+
+func TestGetRangeCommand(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		start    int
+		end      int
+		response string
+	}{
+		{start: 0, end: 3, response: "$4\r\nThis\r\n"},
+		{start: -3, end: -1, response: "$3\r\ning\r\n"},
+		{start: 0, end: -1, response: "$16\r\nThis is a string\r\n"},
+		{start: 10, end: 100, response: "$6\r\nstring\r\n"},
+		{start: 5, end: 3, response: "$0\r\n\r\n"},
+		{start: -1, end: -5, response: "$0\r\n\r\n"},
+		{start: -100, end: 3, response: "$4\r\nThis\r\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.response, func(t *testing.T) {
+			store := redis.NewStore()
+			store.Set("mykey", "This is a string")
+			clock := FakeClock{}
+
+			response := redis.NewGetRangeCommand(store, clock, "mykey", tt.start, tt.end).Run()
+
+			if response != tt.response {
+				t.Errorf(`command expected to return %#v but was %#v`, tt.response, response)
+			}
+		})
+	}
+}
+
+func TestSetRangeCommand(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		store    func() *redis.Store
+		offset   int
+		value    string
+		response string
+		data     string
+		present  bool
+	}{
+		{
+			name:     "overwrite",
+			store:    storeWith("key1", "Hello World"),
+			offset:   6,
+			value:    "Redis",
+			response: ":11\r\n",
+			data:     "Hello Redis",
+			present:  true,
+		},
+		{
+			name:     "zero padding",
+			store:    redis.NewStore,
+			offset:   6,
+			value:    "Redis",
+			response: ":11\r\n",
+			data:     "\x00\x00\x00\x00\x00\x00Redis",
+			present:  true,
+		},
+		{
+			name:     "extend",
+			store:    storeWith("key1", "Hello"),
+			offset:   3,
+			value:    "p me!",
+			response: ":8\r\n",
+			data:     "Help me!",
+			present:  true,
+		},
+		{
+			name:     "empty value on absent key",
+			store:    redis.NewStore,
+			offset:   10,
+			value:    "",
+			response: ":0\r\n",
+			present:  false,
+		},
+		{
+			name:     "too long",
+			store:    redis.NewStore,
+			offset:   512 * 1024 * 1024,
+			value:    "a",
+			response: "-ERR string exceeds maximum allowed size (proto-max-bulk-len)\r\n",
+			present:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := tt.store()
+			clock := FakeClock{}
+
+			response := redis.NewSetRangeCommand(store, clock, "key1", tt.offset, tt.value).Run()
+
+			if response != tt.response {
+				t.Errorf(`command expected to return %#v but was %#v`, tt.response, response)
+			}
+			value, ok := store.Get("key1")
+			if ok != tt.present {
+				t.Errorf(`ok expected to be %v but was %v`, tt.present, ok)
+			}
+			if value.Data() != tt.data {
+				t.Errorf(`value.Data() expected to be %#v but was %#v`, tt.data, value.Data())
+			}
+		})
+	}
+}
+
+func TestGetDelCommand(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	store.Set("link", "zelda")
+	clock := FakeClock{}
+
+	response := redis.NewGetDelCommand(store, clock, "link").Run()
+
+	if response != "$5\r\nzelda\r\n" {
+		t.Errorf(`command expected to return "$5\r\nzelda\r\n" but was %#v`, response)
+	}
+	if _, ok := store.Get("link"); ok {
+		t.Errorf(`ok expected to be false but was true`)
+	}
+	response = redis.NewGetDelCommand(store, clock, "link").Run()
+	if response != redisNullBulkString {
+		t.Errorf(`command expected to return %#v but was %#v`, redisNullBulkString, response)
+	}
+}
+
+func TestGetExCommand(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		options    []func(*redis.GetExCommand)
+		expiryTime *time.Time
+	}{
+		{
+			name:       "no options",
+			options:    nil,
+			expiryTime: ptr(time.UnixMilli(100)),
+		},
+		{
+			name:       "expiry time",
+			options:    []func(*redis.GetExCommand){redis.GetExExpiryTime(time.UnixMilli(200))},
+			expiryTime: ptr(time.UnixMilli(200)),
+		},
+		{
+			name:       "persist",
+			options:    []func(*redis.GetExCommand){redis.GetExPersist()},
+			expiryTime: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := redis.NewStore()
+			store.SetWithExpiryTime("link", "zelda", time.UnixMilli(100))
+			clock := FakeClock{CurrentTime: time.UnixMilli(0)}
+
+			response := redis.NewGetExCommand(store, clock, "link", tt.options...).Run()
+
+			if response != "$5\r\nzelda\r\n" {
+				t.Errorf(`command expected to return "$5\r\nzelda\r\n" but was %#v`, response)
+			}
+			value, _ := store.Get("link")
+			if !expiryTimesEqual(value.ExpiryTime(), tt.expiryTime) {
+				t.Errorf(
+					`value.ExpiryTime() expected to be %v but was %v`,
+					tt.expiryTime, value.ExpiryTime(),
+				)
+			}
+		})
+	}
+}
+
+func TestGetSetCommand(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	store.SetWithExpiryTime("link", "zelda", time.UnixMilli(100))
+	clock := FakeClock{CurrentTime: time.UnixMilli(0)}
+
+	response := redis.NewGetSetCommand(store, clock, "link", "ganon").Run()
+
+	if response != "$5\r\nzelda\r\n" {
+		t.Errorf(`command expected to return "$5\r\nzelda\r\n" but was %#v`, response)
+	}
+	value, _ := store.Get("link")
+	if value.Data() != "ganon" {
+		t.Errorf(`value.Data() expected to be "ganon" but was %#v`, value.Data())
+	}
+	if value.ExpiryTime() != nil {
+		t.Errorf(`value.ExpiryTime() expected to be nil but was %v`, value.ExpiryTime())
+	}
+}
+
+func TestLCSCommand(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		options  []func(*redis.LCSCommand)
+		response string
+	}{
+		{
+			name:     "no options",
+			response: "$6\r\nmytext\r\n",
+		},
+		{
+			name:     "LEN",
+			options:  []func(*redis.LCSCommand){redis.LCSLen()},
+			response: ":6\r\n",
+		},
+		{
+			name:    "IDX",
+			options: []func(*redis.LCSCommand){redis.LCSIdx()},
+			response: "*4\r\n$7\r\nmatches\r\n*2\r\n" +
+				"*2\r\n*2\r\n:4\r\n:7\r\n*2\r\n:5\r\n:8\r\n" +
+				"*2\r\n*2\r\n:2\r\n:3\r\n*2\r\n:0\r\n:1\r\n" +
+				"$3\r\nlen\r\n:6\r\n",
+		},
+		{
+			name: "IDX MINMATCHLEN 4 WITHMATCHLEN",
+			options: []func(*redis.LCSCommand){
+				redis.LCSIdx(),
+				redis.LCSMinMatchLen(4),
+				redis.LCSWithMatchLen(),
+			},
+			response: "*4\r\n$7\r\nmatches\r\n*1\r\n" +
+				"*3\r\n*2\r\n:4\r\n:7\r\n*2\r\n:5\r\n:8\r\n:4\r\n" +
+				"$3\r\nlen\r\n:6\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := redis.NewStore()
+			store.Set("key1", "ohmytext")
+			store.Set("key2", "mynewtext")
+			clock := FakeClock{}
+
+			response := redis.NewLCSCommand(store, clock, "key1", "key2", tt.options...).Run()
+
+			if response != tt.response {
+				t.Errorf(`command expected to return %#v but was %#v`, tt.response, response)
+			}
+		})
+	}
+}
+
+func storeWith(key, value string) func() *redis.Store {
+	return func() *redis.Store {
+		store := redis.NewStore()
+		store.Set(key, value)
+		return store
+	}
+}
+
+func storeWithExpiryTime(key, value string, expiryTime time.Time) func() *redis.Store {
+	return func() *redis.Store {
+		store := redis.NewStore()
+		store.SetWithExpiryTime(key, value, expiryTime)
+		return store
+	}
+}
+
+func expiryTimesEqual(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+func TestMSetCommand(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+
+	response := redis.NewMSetCommand(store, []redis.KeyValue{
+		{Key: "link", Value: "zelda"},
+		{Key: "grape", Value: "banana"},
+	}).Run()
+
+	if response != "+OK\r\n" {
+		t.Errorf(`command expected to return "+OK\r\n" but was %#v`, response)
+	}
+	if value, _ := store.Get("grape"); value.Data() != "banana" {
+		t.Errorf(`value.Data() expected to be "banana" but was %#v`, value.Data())
+	}
+}
+
+func TestMSetNXCommand(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	store.Set("link", "zelda")
+	clock := FakeClock{}
+
+	response := redis.NewMSetNXCommand(store, clock, []redis.KeyValue{
+		{Key: "grape", Value: "banana"},
+	}).Run()
+	if response != ":1\r\n" {
+		t.Errorf(`command expected to return ":1\r\n" but was %#v`, response)
+	}
+
+	response = redis.NewMSetNXCommand(store, clock, []redis.KeyValue{
+		{Key: "apple", Value: "pear"},
+		{Key: "link", Value: "ganon"},
+	}).Run()
+	if response != ":0\r\n" {
+		t.Errorf(`command expected to return ":0\r\n" but was %#v`, response)
+	}
+}
+
+func TestMGetCommand(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	store.Set("link", "zelda")
+	store.SetWithExpiryTime("grape", "banana", time.Unix(0, 0))
+	clock := FakeClock{CurrentTime: time.Unix(0, 1)}
+
+	response := redis.NewMGetCommand(store, clock, []string{"link", "grape", "absent"}).Run()
+
+	want := "*3\r\n$5\r\nzelda\r\n$-1\r\n$-1\r\n"
+	if response != want {
+		t.Errorf(`command expected to return %#v but was %#v`, want, response)
+	}
+}
@@ -147,6 +147,74 @@ func TestPingCommand(t *testing.T) {
 	}
 }
 
+func TestShutdownCommand(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		args     []string
+		want     string
+		wantExit bool
+	}{
+		{
+			name:     "SHUTDOWN",
+			args:     []string{"SHUTDOWN"},
+			want:     "",
+			wantExit: true,
+		},
+		{
+			name:     "SHUTDOWN NOSAVE",
+			args:     []string{"SHUTDOWN", "nosave"},
+			want:     "",
+			wantExit: true,
+		},
+		{
+			name:     "SHUTDOWN SAVE",
+			args:     []string{"SHUTDOWN", "SAVE"},
+			want:     "",
+			wantExit: true,
+		},
+		{
+			name: "SHUTDOWN NOSAVE SAVE",
+			args: []string{"SHUTDOWN", "NOSAVE", "SAVE"},
+			want: "-ERR syntax error\r\n",
+		},
+		{
+			name: "unknown option",
+			args: []string{"SHUTDOWN", "LATER"},
+			want: "-ERR syntax error\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser := redis.NewParser(
+				zeroValueRedisConfig, redis.NewStore(), &FakeClock{CurrentTime: time.UnixMilli(0)},
+			)
+			run := requestRunner(t, parser, parser.NewClient())
+
+			response := run(tt.args...)
+
+			if response != tt.want {
+				t.Errorf("command expected to return %#v but was %#v", tt.want, response)
+			}
+			if exit := shutdownRequested(parser); exit != tt.wantExit {
+				t.Errorf("shutdown expected to be requested: %v; was: %v", tt.wantExit, exit)
+			}
+		})
+	}
+}
+
+// shutdownRequested returns true if a client of parser has asked the server to exit.
+func shutdownRequested(parser redis.Parser) bool {
+	select {
+	case <-parser.ShutdownRequested():
+		return true
+	default:
+		return false
+	}
+}
+
 func TestSetCommand(t *testing.T) {
 	tests := []struct {
 		name  string
@@ -174,7 +242,7 @@ func TestSetCommand(t *testing.T) {
 			if response != "+OK\r\n" {
 				t.Errorf(`command expected to return "+OK\r\n" but was %#v`, response)
 			}
-			if result, ok := store.Get(tt.key); !ok || result != tt.value {
+			if result, ok := store.Get(tt.key); !ok || !result.Equal(tt.value) {
 				t.Errorf(
 					`command expected to contain key-value pair (%s: %v) but was %#v`,
 					tt.key, tt.value, store,
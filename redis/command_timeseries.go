@@ -0,0 +1,613 @@
+package redis
+
+import (
+	"math"
+	"sort"
+)
+
+// getTimeSeries returns the time series at key, or errTSMissing if there isn't one.
+func getTimeSeries(tx *storeTx, key string) (*timeSeries, error) {
+	value, ok, err := tx.getTyped(key, ValueTypeTimeSeries)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errTSMissing
+	}
+	return value.timeSeries(), nil
+}
+
+// getOrCreateTimeSeries returns the time series at key, creating it with options if there isn't
+// one.
+func getOrCreateTimeSeries(tx *storeTx, key string, options TSCreateOptions) (*timeSeries, error) {
+	series, err := getTimeSeries(tx, key)
+	if err == errTSMissing {
+		series = newTimeSeries(options)
+		tx.set(key, StoreValue{data: series})
+		tx.notify(notifyModule, "ts.create", key)
+		return series, nil
+	}
+	return series, err
+}
+
+// nowMilliseconds returns the current time of clock as a timestamp of a sample.
+func nowMilliseconds(clock Clock) int64 {
+	return clock.Now().UnixMilli()
+}
+
+// tsSamplesResponse returns samples as an array of arrays of their timestamps and values.
+func tsSamplesResponse(samples []tsSample) string {
+	elements := make([]string, len(samples))
+	for i, sample := range samples {
+		elements[i] = tsSampleResponse(sample)
+	}
+	return array(elements...)
+}
+
+func tsSampleResponse(sample tsSample) string {
+	return array(integer(int(sample.timestamp)), simpleString(formatFloat(sample.value)))
+}
+
+func NewTSCreateCommand(
+	store *Store,
+	clock Clock,
+	key string,
+	options TSCreateOptions,
+) *TSCreateCommand {
+	return &TSCreateCommand{
+		store:   store,
+		clock:   clock,
+		key:     key,
+		options: options,
+	}
+}
+
+// TSCreateCommand is TS.CREATE, which creates an empty time series.
+type TSCreateCommand struct {
+	store   *Store
+	clock   Clock
+	key     string
+	options TSCreateOptions
+}
+
+func (t *TSCreateCommand) Run() string {
+	var response string
+	t.store.write(t.clock.NowMonotonic(), func(tx *storeTx) {
+		if _, ok := tx.get(t.key); ok {
+			response = errorResponse(errTSExists)
+			return
+		}
+		tx.set(t.key, StoreValue{data: newTimeSeries(t.options)})
+		tx.notify(notifyModule, "ts.create", t.key)
+		response = simpleString("OK")
+	})
+	return response
+}
+
+func NewTSAddCommand(
+	store *Store,
+	clock Clock,
+	key string,
+	timestamp int64,
+	value float64,
+	options TSCreateOptions,
+	onDuplicate TSDuplicatePolicy,
+) *TSAddCommand {
+	return &TSAddCommand{
+		store:       store,
+		clock:       clock,
+		key:         key,
+		timestamp:   timestamp,
+		value:       value,
+		options:     options,
+		onDuplicate: onDuplicate,
+	}
+}
+
+// TSAddCommand is TS.ADD, which adds a sample to a time series, creating it with options if it
+// doesn't exist, and replies with the sample's timestamp.
+type TSAddCommand struct {
+	store     *Store
+	clock     Clock
+	key       string
+	timestamp int64
+	value     float64
+	options   TSCreateOptions
+	// onDuplicate is ON_DUPLICATE, which overrides the series's duplicate policy for this sample.
+	onDuplicate TSDuplicatePolicy
+}
+
+func (t *TSAddCommand) Run() string {
+	var response string
+	t.store.write(t.clock.NowMonotonic(), func(tx *storeTx) {
+		series, err := getOrCreateTimeSeries(tx, t.key, t.options)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		timestamp := t.timestamp
+		if timestamp == TSTimestampNow {
+			timestamp = nowMilliseconds(t.clock)
+		}
+		if err := tsAdd(tx, series, timestamp, t.value, t.onDuplicate); err != nil {
+			response = errorResponse(err)
+			return
+		}
+		tx.notify(notifyModule, "ts.add", t.key)
+		response = integer(int(timestamp))
+	})
+	return response
+}
+
+// TSMAddSample is a sample of TS.MADD and the key of the time series to add it to.
+type TSMAddSample struct {
+	Key       string
+	Timestamp int64
+	Value     float64
+}
+
+func NewTSMAddCommand(store *Store, clock Clock, samples []TSMAddSample) *TSMAddCommand {
+	return &TSMAddCommand{
+		store:   store,
+		clock:   clock,
+		samples: samples,
+	}
+}
+
+// TSMAddCommand is TS.MADD, which adds samples to existing time series and replies with an
+// array of each sample's timestamp, or the error adding it.
+type TSMAddCommand struct {
+	store   *Store
+	clock   Clock
+	samples []TSMAddSample
+}
+
+func (t *TSMAddCommand) Run() string {
+	var response string
+	t.store.write(t.clock.NowMonotonic(), func(tx *storeTx) {
+		now := nowMilliseconds(t.clock)
+		elements := make([]string, len(t.samples))
+		for i, sample := range t.samples {
+			timestamp := sample.Timestamp
+			if timestamp == TSTimestampNow {
+				timestamp = now
+			}
+			series, err := getTimeSeries(tx, sample.Key)
+			if err == nil {
+				err = tsAdd(tx, series, timestamp, sample.Value, TSDuplicateDefault)
+			}
+			if err != nil {
+				elements[i] = errorResponse(err)
+			} else {
+				tx.notify(notifyModule, "ts.add", sample.Key)
+				elements[i] = integer(int(timestamp))
+			}
+		}
+		response = array(elements...)
+	})
+	return response
+}
+
+func NewTSIncrByCommand(
+	store *Store,
+	clock Clock,
+	key string,
+	increment float64,
+	timestamp int64,
+	options TSCreateOptions,
+) *TSIncrByCommand {
+	return &TSIncrByCommand{
+		store:     store,
+		clock:     clock,
+		key:       key,
+		increment: increment,
+		timestamp: timestamp,
+		options:   options,
+	}
+}
+
+// TSIncrByCommand is TS.INCRBY, which adds a sample with the value of the latest sample plus
+// increment, or TS.DECRBY, which subtracts it. The sample can't be older than the latest, and
+// replaces it if it has the same timestamp. It replies with the sample's timestamp.
+type TSIncrByCommand struct {
+	store     *Store
+	clock     Clock
+	key       string
+	increment float64
+	timestamp int64
+	options   TSCreateOptions
+}
+
+func NewTSDecrByCommand(
+	store *Store,
+	clock Clock,
+	key string,
+	decrement float64,
+	timestamp int64,
+	options TSCreateOptions,
+) *TSIncrByCommand {
+	return NewTSIncrByCommand(store, clock, key, -decrement, timestamp, options)
+}
+
+func (t *TSIncrByCommand) Run() string {
+	var response string
+	t.store.write(t.clock.NowMonotonic(), func(tx *storeTx) {
+		series, err := getOrCreateTimeSeries(tx, t.key, t.options)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		timestamp := t.timestamp
+		if timestamp == TSTimestampNow {
+			timestamp = nowMilliseconds(t.clock)
+		}
+		value := t.increment
+		if n := len(series.samples); n > 0 {
+			latest := series.samples[n-1]
+			if timestamp < latest.timestamp {
+				response = errorResponse(errTSIncrTimestamp)
+				return
+			}
+			value += latest.value
+		}
+		if err := tsAdd(tx, series, timestamp, value, TSDuplicateLast); err != nil {
+			response = errorResponse(err)
+			return
+		}
+		tx.notify(notifyModule, "ts.incrby", t.key)
+		response = integer(int(timestamp))
+	})
+	return response
+}
+
+func NewTSGetCommand(store *Store, clock Clock, key string) *TSGetCommand {
+	return &TSGetCommand{
+		store: store,
+		clock: clock,
+		key:   key,
+	}
+}
+
+// TSGetCommand is TS.GET, which replies with the latest sample of a time series, or an empty
+// array if it has none.
+type TSGetCommand struct {
+	store *Store
+	clock Clock
+	key   string
+}
+
+func (t *TSGetCommand) Run() string {
+	var response string
+	t.store.read(t.clock.NowMonotonic(), func(tx *storeTx) {
+		series, err := getTimeSeries(tx, t.key)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		if len(series.samples) == 0 {
+			response = array()
+			return
+		}
+		response = tsSampleResponse(series.samples[len(series.samples)-1])
+	})
+	return response
+}
+
+// TSRangeStart and TSRangeEnd are the From and To of a TSRangeQuery for - and +, the timestamps
+// of the first and last samples of each time series.
+const (
+	TSRangeStart int64 = math.MinInt64
+	TSRangeEnd   int64 = math.MaxInt64
+)
+
+// TSAlignment is what the buckets of the aggregation of a TSRangeQuery are aligned to.
+type TSAlignment int
+
+const (
+	// TSAlignTimestamp aligns them to the Align of the aggregation.
+	TSAlignTimestamp TSAlignment = iota
+	// TSAlignStart aligns them to the start of the range, which is ALIGN start or -.
+	TSAlignStart
+	// TSAlignEnd aligns them to the end of the range, which is ALIGN end or +.
+	TSAlignEnd
+)
+
+// TSRangeQuery is the range of samples of TS.RANGE and TS.MRANGE: those with timestamps from
+// From to To inclusive, aggregated if Aggregation isn't nil, and only the first Count if it
+// isn't 0.
+type TSRangeQuery struct {
+	From        int64
+	To          int64
+	Count       int
+	Aggregation *TSAggregation
+	Alignment   TSAlignment
+}
+
+// samples returns the samples of series that q selects, latest first if reverse.
+func (q TSRangeQuery) samples(series *timeSeries, reverse bool) []tsSample {
+	samples := series.between(q.From, q.To)
+	if q.Aggregation != nil {
+		aggregation := *q.Aggregation
+		switch {
+		case len(series.samples) == 0:
+		case q.Alignment == TSAlignStart && q.From == TSRangeStart:
+			aggregation.Align = series.samples[0].timestamp
+		case q.Alignment == TSAlignStart:
+			aggregation.Align = q.From
+		case q.Alignment == TSAlignEnd && q.To == TSRangeEnd:
+			aggregation.Align = series.samples[len(series.samples)-1].timestamp
+		case q.Alignment == TSAlignEnd:
+			aggregation.Align = q.To
+		}
+		samples = aggregation.apply(samples)
+	}
+	if reverse {
+		reversed := make([]tsSample, len(samples))
+		for i, sample := range samples {
+			reversed[len(samples)-1-i] = sample
+		}
+		samples = reversed
+	}
+	if q.Count > 0 && len(samples) > q.Count {
+		samples = samples[:q.Count]
+	}
+	return samples
+}
+
+func NewTSRangeCommand(store *Store, clock Clock, key string, query TSRangeQuery) *TSRangeCommand {
+	return &TSRangeCommand{
+		store: store,
+		clock: clock,
+		key:   key,
+		query: query,
+	}
+}
+
+// TSRangeCommand is TS.RANGE, which replies with a range of samples of a time series, or
+// TS.REVRANGE, which replies with them latest first.
+type TSRangeCommand struct {
+	store   *Store
+	clock   Clock
+	key     string
+	query   TSRangeQuery
+	reverse bool
+}
+
+func NewTSRevRangeCommand(
+	store *Store,
+	clock Clock,
+	key string,
+	query TSRangeQuery,
+) *TSRangeCommand {
+	result := NewTSRangeCommand(store, clock, key, query)
+	result.reverse = true
+	return result
+}
+
+func (t *TSRangeCommand) Run() string {
+	var response string
+	t.store.read(t.clock.NowMonotonic(), func(tx *storeTx) {
+		series, err := getTimeSeries(tx, t.key)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		response = tsSamplesResponse(t.query.samples(series, t.reverse))
+	})
+	return response
+}
+
+// TSFilter is a FILTER of TS.MRANGE, which matches time series whose label called Label has one
+// of Values, or doesn't if Negate. A missing label has the value "".
+type TSFilter struct {
+	Label  string
+	Values []string
+	Negate bool
+}
+
+func (f TSFilter) matches(series *timeSeries) bool {
+	value, _ := series.label(f.Label)
+	for _, v := range f.Values {
+		if v == value {
+			return !f.Negate
+		}
+	}
+	return f.Negate
+}
+
+func NewTSMRangeCommand(
+	store *Store,
+	clock Clock,
+	query TSRangeQuery,
+	filters []TSFilter,
+	options ...func(*TSMRangeCommand),
+) *TSMRangeCommand {
+	result := &TSMRangeCommand{
+		store:   store,
+		clock:   clock,
+		query:   query,
+		filters: filters,
+	}
+	for _, option := range options {
+		option(result)
+	}
+	return result
+}
+
+// TSMRangeCommand is TS.MRANGE, which replies with a range of samples of every time series that
+// matches all of filters, or TS.MREVRANGE, which replies with them latest first. Each series is
+// an array of its key, its labels and its samples, in order of their keys.
+type TSMRangeCommand struct {
+	store      *Store
+	clock      Clock
+	query      TSRangeQuery
+	filters    []TSFilter
+	withLabels bool
+	reverse    bool
+}
+
+func NewTSMRevRangeCommand(
+	store *Store,
+	clock Clock,
+	query TSRangeQuery,
+	filters []TSFilter,
+	options ...func(*TSMRangeCommand),
+) *TSMRangeCommand {
+	result := NewTSMRangeCommand(store, clock, query, filters, options...)
+	result.reverse = true
+	return result
+}
+
+// TSMRangeWithLabels is WITHLABELS, which replies with the labels of each time series, rather
+// than an empty array.
+func TSMRangeWithLabels() func(*TSMRangeCommand) {
+	return func(command *TSMRangeCommand) {
+		command.withLabels = true
+	}
+}
+
+func (t *TSMRangeCommand) Run() string {
+	var response string
+	t.store.read(t.clock.NowMonotonic(), func(tx *storeTx) {
+		keys := tx.keys()
+		sort.Strings(keys)
+		var elements []string
+	keys:
+		for _, key := range keys {
+			value, _ := tx.get(key)
+			series := value.timeSeries()
+			if series == nil {
+				continue
+			}
+			for _, filter := range t.filters {
+				if !filter.matches(series) {
+					continue keys
+				}
+			}
+			var labels []string
+			if t.withLabels {
+				for _, label := range series.labels {
+					labels = append(labels, bulkStringArray([]string{label.Name, label.Value}))
+				}
+			}
+			elements = append(elements, array(
+				bulkString(key),
+				array(labels...),
+				tsSamplesResponse(t.query.samples(series, t.reverse)),
+			))
+		}
+		response = array(elements...)
+	})
+	return response
+}
+
+func NewTSCreateRuleCommand(
+	store *Store,
+	clock Clock,
+	source string,
+	destination string,
+	aggregation TSAggregation,
+) *TSCreateRuleCommand {
+	return &TSCreateRuleCommand{
+		store:       store,
+		clock:       clock,
+		source:      source,
+		destination: destination,
+		aggregation: aggregation,
+	}
+}
+
+// TSCreateRuleCommand is TS.CREATERULE, which compacts the samples added to one time series
+// into another from then on. A series can only be the destination of one rule.
+type TSCreateRuleCommand struct {
+	store       *Store
+	clock       Clock
+	source      string
+	destination string
+	aggregation TSAggregation
+}
+
+func (t *TSCreateRuleCommand) Run() string {
+	var response string
+	t.store.write(t.clock.NowMonotonic(), func(tx *storeTx) {
+		if t.source == t.destination {
+			response = errorResponse(CommandError(
+				"ERR TSDB: the source key and destination key should be different",
+			))
+			return
+		}
+		source, err := getTimeSeries(tx, t.source)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		destination, err := getTimeSeries(tx, t.destination)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		if destination.source != "" {
+			response = errorResponse(CommandError(
+				"ERR TSDB: the destination key already has a src rule",
+			))
+			return
+		}
+		source.rules = append(source.rules, &tsRule{
+			destination: t.destination,
+			aggregation: t.aggregation,
+		})
+		destination.source = t.source
+		tx.notify(notifyModule, "ts.createrule", t.source)
+		tx.notify(notifyModule, "ts.createrule", t.destination)
+		response = simpleString("OK")
+	})
+	return response
+}
+
+func NewTSDeleteRuleCommand(
+	store *Store,
+	clock Clock,
+	source string,
+	destination string,
+) *TSDeleteRuleCommand {
+	return &TSDeleteRuleCommand{
+		store:       store,
+		clock:       clock,
+		source:      source,
+		destination: destination,
+	}
+}
+
+// TSDeleteRuleCommand is TS.DELETERULE, which removes the rule of TS.CREATERULE.
+type TSDeleteRuleCommand struct {
+	store       *Store
+	clock       Clock
+	source      string
+	destination string
+}
+
+func (t *TSDeleteRuleCommand) Run() string {
+	var response string
+	t.store.write(t.clock.NowMonotonic(), func(tx *storeTx) {
+		source, err := getTimeSeries(tx, t.source)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		for i, rule := range source.rules {
+			if rule.destination != t.destination {
+				continue
+			}
+			source.rules = append(source.rules[:i], source.rules[i+1:]...)
+			tx.notify(notifyModule, "ts.deleterule", t.source)
+			if destination, err := getTimeSeries(tx, t.destination); err == nil {
+				destination.source = ""
+				tx.notify(notifyModule, "ts.deleterule", t.destination)
+			}
+			response = simpleString("OK")
+			return
+		}
+		response = errorResponse(CommandError("ERR TSDB: compaction rule does not exist"))
+	})
+	return response
+}
@@ -0,0 +1,673 @@
+package redis_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/redis"
+)
+
+func TestTSAddCommand(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	store.Set("string", "zelda")
+	clock := FakeClock{CurrentTime: time.UnixMilli(5000)}
+	redis.NewTSCreateCommand(store, clock, "sum", redis.TSCreateOptions{
+		DuplicatePolicy: redis.TSDuplicateSum,
+	}).Run()
+	redis.NewTSCreateCommand(store, clock, "retained", redis.TSCreateOptions{
+		Retention: 1000,
+	}).Run()
+	redis.NewTSCreateCommand(store, clock, "empty", redis.TSCreateOptions{}).Run()
+
+	tests := []struct {
+		name     string
+		command  redis.Command
+		response string
+	}{
+		{
+			name: "create existing key",
+			command: redis.NewTSCreateCommand(
+				store, clock, "string", redis.TSCreateOptions{},
+			),
+			response: "-ERR TSDB: key already exists\r\n",
+		},
+		{
+			name: "add to new key",
+			command: redis.NewTSAddCommand(
+				store, clock, "ts", 1000, 1.5, redis.TSCreateOptions{}, redis.TSDuplicateDefault,
+			),
+			response: ":1000\r\n",
+		},
+		{
+			name: "add at current time",
+			command: redis.NewTSAddCommand(
+				store, clock, "ts", redis.TSTimestampNow, 2, redis.TSCreateOptions{},
+				redis.TSDuplicateDefault,
+			),
+			response: ":5000\r\n",
+		},
+		{
+			name: "add duplicate with block policy",
+			command: redis.NewTSAddCommand(
+				store, clock, "ts", 1000, 3, redis.TSCreateOptions{}, redis.TSDuplicateDefault,
+			),
+			response: "-ERR TSDB: Error at upsert, update is not supported when " +
+				"DUPLICATE_POLICY is set to BLOCK mode\r\n",
+		},
+		{
+			name: "add duplicate with ON_DUPLICATE",
+			command: redis.NewTSAddCommand(
+				store, clock, "ts", 1000, 3, redis.TSCreateOptions{}, redis.TSDuplicateMax,
+			),
+			response: ":1000\r\n",
+		},
+		{
+			name: "add earlier sample",
+			command: redis.NewTSAddCommand(
+				store, clock, "ts", 500, 0.25, redis.TSCreateOptions{}, redis.TSDuplicateDefault,
+			),
+			response: ":500\r\n",
+		},
+		{
+			name: "range",
+			command: redis.NewTSRangeCommand(
+				store, clock, "ts", redis.TSRangeQuery{From: 0, To: 10000},
+			),
+			response: "*3\r\n*2\r\n:500\r\n+0.25\r\n*2\r\n:1000\r\n+3\r\n*2\r\n:5000\r\n+2\r\n",
+		},
+		{
+			name: "add duplicate with sum policy",
+			command: redis.NewTSMAddCommand(store, clock, []redis.TSMAddSample{
+				{Key: "sum", Timestamp: 10, Value: 1},
+				{Key: "sum", Timestamp: 10, Value: 2.5},
+				{Key: "missing", Timestamp: 10, Value: 1},
+				{Key: "string", Timestamp: 10, Value: 1},
+			}),
+			response: "*4\r\n:10\r\n:10\r\n-ERR TSDB: the key does not exist\r\n" +
+				"-WRONGTYPE Operation against a key holding the wrong kind of value\r\n",
+		},
+		{
+			name:     "get",
+			command:  redis.NewTSGetCommand(store, clock, "sum"),
+			response: "*2\r\n:10\r\n+3.5\r\n",
+		},
+		{
+			name: "add long before the current time with retention",
+			command: redis.NewTSAddCommand(
+				store, clock, "retained", 1000, 1, redis.TSCreateOptions{}, redis.TSDuplicateDefault,
+			),
+			response: ":1000\r\n",
+		},
+		{
+			name: "add later sample with retention",
+			command: redis.NewTSAddCommand(
+				store, clock, "retained", 2001, 2, redis.TSCreateOptions{}, redis.TSDuplicateDefault,
+			),
+			response: ":2001\r\n",
+		},
+		{
+			name: "add older than retention",
+			command: redis.NewTSAddCommand(
+				store, clock, "retained", 1000, 3, redis.TSCreateOptions{}, redis.TSDuplicateDefault,
+			),
+			response: "-ERR TSDB: Timestamp is older than retention\r\n",
+		},
+		{
+			name:     "get empty series",
+			command:  redis.NewTSGetCommand(store, clock, "empty"),
+			response: "*0\r\n",
+		},
+		{
+			name:     "get missing key",
+			command:  redis.NewTSGetCommand(store, clock, "missing"),
+			response: "-ERR TSDB: the key does not exist\r\n",
+		},
+		{
+			name: "wrong type",
+			command: redis.NewTSAddCommand(
+				store, clock, "string", 1, 1, redis.TSCreateOptions{}, redis.TSDuplicateDefault,
+			),
+			response: "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			if response := test.command.Run(); response != test.response {
+				t.Errorf("command expected to return %#v but was %#v", test.response, response)
+			}
+		})
+	}
+}
+
+func TestTSAddCommand_Retention(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := &FakeClock{CurrentTime: time.UnixMilli(1000)}
+	redis.NewTSCreateCommand(store, clock, "ts", redis.TSCreateOptions{Retention: 100}).Run()
+	add := func(timestamp int64, value float64) string {
+		return redis.NewTSAddCommand(
+			store, clock, "ts", timestamp, value, redis.TSCreateOptions{}, redis.TSDuplicateDefault,
+		).Run()
+	}
+	add(950, 1)
+	add(1000, 1)
+	query := redis.TSRangeQuery{From: 0, To: 2000}
+
+	// The retention is relative to the latest sample, so samples aren't hidden as time passes.
+	clock.CurrentTime = time.UnixMilli(5000)
+	want := "*2\r\n*2\r\n:950\r\n+1\r\n*2\r\n:1000\r\n+1\r\n"
+	if response := redis.NewTSRangeCommand(store, clock, "ts", query).Run(); response != want {
+		t.Errorf("expected samples within the retention to be kept but TS.RANGE returned %#v",
+			response)
+	}
+
+	// Adding a later sample trims the ones that are now older than the retention.
+	add(1060, 2)
+	want = "*2\r\n*2\r\n:1000\r\n+1\r\n*2\r\n:1060\r\n+2\r\n"
+	if response := redis.NewTSRangeCommand(store, clock, "ts", query).Run(); response != want {
+		t.Errorf("expected samples older than the retention to be trimmed but TS.RANGE returned %#v",
+			response)
+	}
+	want = "-ERR TSDB: Timestamp is older than retention\r\n"
+	if response := add(955, 3); response != want {
+		t.Errorf("command expected to return %#v but was %#v", want, response)
+	}
+}
+
+func TestTSIncrByCommand(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{CurrentTime: time.UnixMilli(5000)}
+	options := redis.TSCreateOptions{Labels: []redis.TSLabel{{Name: "type", Value: "counter"}}}
+
+	tests := []struct {
+		name     string
+		command  redis.Command
+		response string
+	}{
+		{
+			name:     "new key",
+			command:  redis.NewTSIncrByCommand(store, clock, "ts", 2, 100, options),
+			response: ":100\r\n",
+		},
+		{
+			name:     "same timestamp",
+			command:  redis.NewTSIncrByCommand(store, clock, "ts", 3, 100, options),
+			response: ":100\r\n",
+		},
+		{
+			name: "current time",
+			command: redis.NewTSDecrByCommand(
+				store, clock, "ts", 1.5, redis.TSTimestampNow, options,
+			),
+			response: ":5000\r\n",
+		},
+		{
+			name:    "earlier timestamp",
+			command: redis.NewTSIncrByCommand(store, clock, "ts", 1, 200, options),
+			response: "-ERR TSDB: timestamp must be equal to or higher than the maximum existing " +
+				"timestamp\r\n",
+		},
+		{
+			name: "range",
+			command: redis.NewTSRangeCommand(
+				store, clock, "ts", redis.TSRangeQuery{From: 0, To: 10000},
+			),
+			response: "*2\r\n*2\r\n:100\r\n+5\r\n*2\r\n:5000\r\n+3.5\r\n",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			if response := test.command.Run(); response != test.response {
+				t.Errorf("command expected to return %#v but was %#v", test.response, response)
+			}
+		})
+	}
+}
+
+func TestTSRangeCommand(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	store.Set("string", "zelda")
+	clock := FakeClock{}
+	for i, value := range []float64{1, 2, 3, 4, 5, 6} {
+		redis.NewTSAddCommand(
+			store, clock, "ts", int64(i*10), value, redis.TSCreateOptions{},
+			redis.TSDuplicateDefault,
+		).Run()
+	}
+	for i, value := range []float64{1, 2, 3, 4} {
+		redis.NewTSAddCommand(
+			store, clock, "offset", int64(5+i*10), value, redis.TSCreateOptions{},
+			redis.TSDuplicateDefault,
+		).Run()
+	}
+
+	tests := []struct {
+		name     string
+		command  redis.Command
+		response string
+	}{
+		{
+			name: "range",
+			command: redis.NewTSRangeCommand(
+				store, clock, "ts", redis.TSRangeQuery{From: 15, To: 30},
+			),
+			response: "*2\r\n*2\r\n:20\r\n+3\r\n*2\r\n:30\r\n+4\r\n",
+		},
+		{
+			name: "reverse range with count",
+			command: redis.NewTSRevRangeCommand(
+				store, clock, "ts", redis.TSRangeQuery{From: 0, To: 50, Count: 2},
+			),
+			response: "*2\r\n*2\r\n:50\r\n+6\r\n*2\r\n:40\r\n+5\r\n",
+		},
+		{
+			name: "average",
+			command: redis.NewTSRangeCommand(store, clock, "ts", redis.TSRangeQuery{
+				From: 0,
+				To:   50,
+				Aggregation: &redis.TSAggregation{
+					Aggregator:     redis.TSAggregatorAvg,
+					BucketDuration: 20,
+				},
+			}),
+			response: "*3\r\n*2\r\n:0\r\n+1.5\r\n*2\r\n:20\r\n+3.5\r\n*2\r\n:40\r\n+5.5\r\n",
+		},
+		{
+			name: "aligned count",
+			command: redis.NewTSRangeCommand(store, clock, "ts", redis.TSRangeQuery{
+				From: 0,
+				To:   50,
+				Aggregation: &redis.TSAggregation{
+					Aggregator:     redis.TSAggregatorCount,
+					BucketDuration: 20,
+					Align:          10,
+				},
+			}),
+			response: "*4\r\n*2\r\n:-10\r\n+1\r\n*2\r\n:10\r\n+2\r\n*2\r\n:30\r\n+2\r\n" +
+				"*2\r\n:50\r\n+1\r\n",
+		},
+		{
+			name: "aligned to the first sample",
+			command: redis.NewTSRangeCommand(store, clock, "offset", redis.TSRangeQuery{
+				From: redis.TSRangeStart,
+				To:   redis.TSRangeEnd,
+				Aggregation: &redis.TSAggregation{
+					Aggregator:     redis.TSAggregatorCount,
+					BucketDuration: 20,
+				},
+				Alignment: redis.TSAlignStart,
+			}),
+			response: "*2\r\n*2\r\n:5\r\n+2\r\n*2\r\n:25\r\n+2\r\n",
+		},
+		{
+			name: "aligned to the last sample",
+			command: redis.NewTSRangeCommand(store, clock, "offset", redis.TSRangeQuery{
+				From: redis.TSRangeStart,
+				To:   redis.TSRangeEnd,
+				Aggregation: &redis.TSAggregation{
+					Aggregator:     redis.TSAggregatorCount,
+					BucketDuration: 20,
+				},
+				Alignment: redis.TSAlignEnd,
+			}),
+			response: "*3\r\n*2\r\n:-5\r\n+1\r\n*2\r\n:15\r\n+2\r\n*2\r\n:35\r\n+1\r\n",
+		},
+		{
+			name: "aligned to the end of the range",
+			command: redis.NewTSRangeCommand(store, clock, "offset", redis.TSRangeQuery{
+				From: 0,
+				To:   30,
+				Aggregation: &redis.TSAggregation{
+					Aggregator:     redis.TSAggregatorCount,
+					BucketDuration: 20,
+				},
+				Alignment: redis.TSAlignEnd,
+			}),
+			response: "*2\r\n*2\r\n:-10\r\n+1\r\n*2\r\n:10\r\n+2\r\n",
+		},
+		{
+			name: "reverse maximum with count",
+			command: redis.NewTSRevRangeCommand(store, clock, "ts", redis.TSRangeQuery{
+				From:  0,
+				To:    50,
+				Count: 1,
+				Aggregation: &redis.TSAggregation{
+					Aggregator:     redis.TSAggregatorMax,
+					BucketDuration: 30,
+				},
+			}),
+			response: "*1\r\n*2\r\n:30\r\n+6\r\n",
+		},
+		{
+			name: "range aggregator",
+			command: redis.NewTSRangeCommand(store, clock, "ts", redis.TSRangeQuery{
+				From: 10,
+				To:   30,
+				Aggregation: &redis.TSAggregation{
+					Aggregator:     redis.TSAggregatorRange,
+					BucketDuration: 100,
+				},
+			}),
+			response: "*1\r\n*2\r\n:0\r\n+2\r\n",
+		},
+		{
+			name: "missing key",
+			command: redis.NewTSRangeCommand(
+				store, clock, "missing", redis.TSRangeQuery{From: 0, To: 50},
+			),
+			response: "-ERR TSDB: the key does not exist\r\n",
+		},
+		{
+			name: "wrong type",
+			command: redis.NewTSRangeCommand(
+				store, clock, "string", redis.TSRangeQuery{From: 0, To: 50},
+			),
+			response: "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			if response := test.command.Run(); response != test.response {
+				t.Errorf("command expected to return %#v but was %#v", test.response, response)
+			}
+		})
+	}
+}
+
+func TestTSMRangeCommand(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	store.Set("string", "zelda")
+	clock := FakeClock{}
+	series := []struct {
+		key    string
+		labels []redis.TSLabel
+	}{
+		{
+			key:    "cpu:1",
+			labels: []redis.TSLabel{{Name: "type", Value: "cpu"}, {Name: "host", Value: "a"}},
+		},
+		{
+			key:    "cpu:2",
+			labels: []redis.TSLabel{{Name: "type", Value: "cpu"}, {Name: "host", Value: "b"}},
+		},
+		{
+			key:    "mem:1",
+			labels: []redis.TSLabel{{Name: "type", Value: "mem"}},
+		},
+	}
+	for i, s := range series {
+		options := redis.TSCreateOptions{Labels: s.labels}
+		redis.NewTSCreateCommand(store, clock, s.key, options).Run()
+		for _, timestamp := range []int64{10, 20} {
+			redis.NewTSAddCommand(
+				store, clock, s.key, timestamp, float64(i), options, redis.TSDuplicateDefault,
+			).Run()
+		}
+	}
+	query := redis.TSRangeQuery{From: 0, To: 100}
+
+	tests := []struct {
+		name     string
+		command  redis.Command
+		response string
+	}{
+		{
+			name: "filter by label",
+			command: redis.NewTSMRangeCommand(store, clock, query, []redis.TSFilter{
+				{Label: "type", Values: []string{"cpu"}},
+			}),
+			response: "*2\r\n" +
+				"*3\r\n$5\r\ncpu:1\r\n*0\r\n*2\r\n*2\r\n:10\r\n+0\r\n*2\r\n:20\r\n+0\r\n" +
+				"*3\r\n$5\r\ncpu:2\r\n*0\r\n*2\r\n*2\r\n:10\r\n+1\r\n*2\r\n:20\r\n+1\r\n",
+		},
+		{
+			name: "negated filter with labels",
+			command: redis.NewTSMRevRangeCommand(
+				store, clock, redis.TSRangeQuery{From: 0, To: 100, Count: 1},
+				[]redis.TSFilter{
+					{Label: "type", Values: []string{"cpu", "mem"}},
+					{Label: "host", Values: []string{"a"}, Negate: true},
+				},
+				redis.TSMRangeWithLabels(),
+			),
+			response: "*2\r\n" +
+				"*3\r\n$5\r\ncpu:2\r\n*2\r\n*2\r\n$4\r\ntype\r\n$3\r\ncpu\r\n" +
+				"*2\r\n$4\r\nhost\r\n$1\r\nb\r\n*1\r\n*2\r\n:20\r\n+1\r\n" +
+				"*3\r\n$5\r\nmem:1\r\n*1\r\n*2\r\n$4\r\ntype\r\n$3\r\nmem\r\n" +
+				"*1\r\n*2\r\n:20\r\n+2\r\n",
+		},
+		{
+			name: "missing label",
+			command: redis.NewTSMRangeCommand(store, clock, query, []redis.TSFilter{
+				{Label: "type", Values: []string{"mem"}},
+				{Label: "host", Values: []string{""}},
+			}),
+			response: "*1\r\n" +
+				"*3\r\n$5\r\nmem:1\r\n*0\r\n*2\r\n*2\r\n:10\r\n+2\r\n*2\r\n:20\r\n+2\r\n",
+		},
+		{
+			name: "aggregation",
+			command: redis.NewTSMRangeCommand(store, clock, redis.TSRangeQuery{
+				From: 0,
+				To:   100,
+				Aggregation: &redis.TSAggregation{
+					Aggregator:     redis.TSAggregatorSum,
+					BucketDuration: 100,
+				},
+			}, []redis.TSFilter{{Label: "host", Values: []string{"b"}}}),
+			response: "*1\r\n*3\r\n$5\r\ncpu:2\r\n*0\r\n*1\r\n*2\r\n:0\r\n+2\r\n",
+		},
+		{
+			name: "no match",
+			command: redis.NewTSMRangeCommand(store, clock, query, []redis.TSFilter{
+				{Label: "type", Values: []string{"disk"}},
+			}),
+			response: "*0\r\n",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			if response := test.command.Run(); response != test.response {
+				t.Errorf("command expected to return %#v but was %#v", test.response, response)
+			}
+		})
+	}
+}
+
+func TestTSCreateRuleCommand(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{}
+	for _, key := range []string{"raw", "avg", "max", "other"} {
+		redis.NewTSCreateCommand(store, clock, key, redis.TSCreateOptions{}).Run()
+	}
+	average := redis.TSAggregation{Aggregator: redis.TSAggregatorAvg, BucketDuration: 10}
+	maximum := redis.TSAggregation{Aggregator: redis.TSAggregatorMax, BucketDuration: 20}
+	query := redis.TSRangeQuery{From: 0, To: 100}
+	add := func(timestamp int64, value float64) redis.Command {
+		return redis.NewTSAddCommand(
+			store, clock, "raw", timestamp, value, redis.TSCreateOptions{}, redis.TSDuplicateDefault,
+		)
+	}
+
+	tests := []struct {
+		name     string
+		command  redis.Command
+		response string
+	}{
+		{
+			name:     "create rule",
+			command:  redis.NewTSCreateRuleCommand(store, clock, "raw", "avg", average),
+			response: "+OK\r\n",
+		},
+		{
+			name:     "create another rule",
+			command:  redis.NewTSCreateRuleCommand(store, clock, "raw", "max", maximum),
+			response: "+OK\r\n",
+		},
+		{
+			name:     "destination with a rule",
+			command:  redis.NewTSCreateRuleCommand(store, clock, "other", "avg", average),
+			response: "-ERR TSDB: the destination key already has a src rule\r\n",
+		},
+		{
+			name:     "same source and destination",
+			command:  redis.NewTSCreateRuleCommand(store, clock, "raw", "raw", average),
+			response: "-ERR TSDB: the source key and destination key should be different\r\n",
+		},
+		{
+			name:     "missing destination",
+			command:  redis.NewTSCreateRuleCommand(store, clock, "raw", "missing", average),
+			response: "-ERR TSDB: the key does not exist\r\n",
+		},
+		{
+			name:     "add first sample",
+			command:  add(1, 1),
+			response: ":1\r\n",
+		},
+		{
+			name:     "add to the same bucket",
+			command:  add(5, 3),
+			response: ":5\r\n",
+		},
+		{
+			name:     "open bucket isn't compacted",
+			command:  redis.NewTSRangeCommand(store, clock, "avg", query),
+			response: "*0\r\n",
+		},
+		{
+			name:     "add to the next bucket",
+			command:  add(12, 10),
+			response: ":12\r\n",
+		},
+		{
+			name:     "compacted bucket",
+			command:  redis.NewTSRangeCommand(store, clock, "avg", query),
+			response: "*1\r\n*2\r\n:0\r\n+2\r\n",
+		},
+		{
+			name:     "add late sample",
+			command:  add(8, 5),
+			response: ":8\r\n",
+		},
+		{
+			name:     "recompacted bucket",
+			command:  redis.NewTSRangeCommand(store, clock, "avg", query),
+			response: "*1\r\n*2\r\n:0\r\n+3\r\n",
+		},
+		{
+			name:     "add to a later bucket",
+			command:  add(25, 4),
+			response: ":25\r\n",
+		},
+		{
+			name:     "compacted buckets",
+			command:  redis.NewTSRangeCommand(store, clock, "avg", query),
+			response: "*2\r\n*2\r\n:0\r\n+3\r\n*2\r\n:10\r\n+10\r\n",
+		},
+		{
+			name:     "compacted with another rule",
+			command:  redis.NewTSRangeCommand(store, clock, "max", query),
+			response: "*1\r\n*2\r\n:0\r\n+10\r\n",
+		},
+		{
+			name:     "delete rule",
+			command:  redis.NewTSDeleteRuleCommand(store, clock, "raw", "avg"),
+			response: "+OK\r\n",
+		},
+		{
+			name:     "delete missing rule",
+			command:  redis.NewTSDeleteRuleCommand(store, clock, "raw", "avg"),
+			response: "-ERR TSDB: compaction rule does not exist\r\n",
+		},
+		{
+			name:     "add after deleting rule",
+			command:  add(35, 1),
+			response: ":35\r\n",
+		},
+		{
+			name:     "no longer compacted",
+			command:  redis.NewTSRangeCommand(store, clock, "avg", query),
+			response: "*2\r\n*2\r\n:0\r\n+3\r\n*2\r\n:10\r\n+10\r\n",
+		},
+		{
+			name:     "destination can have a new rule",
+			command:  redis.NewTSCreateRuleCommand(store, clock, "other", "avg", average),
+			response: "+OK\r\n",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			if response := test.command.Run(); response != test.response {
+				t.Errorf("command expected to return %#v but was %#v", test.response, response)
+			}
+		})
+	}
+}
+
+func TestTSCreateRuleCommand_Retention(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{}
+	redis.NewTSCreateCommand(store, clock, "raw", redis.TSCreateOptions{
+		Retention:       15,
+		DuplicatePolicy: redis.TSDuplicateLast,
+	}).Run()
+	redis.NewTSCreateCommand(store, clock, "avg", redis.TSCreateOptions{}).Run()
+	average := redis.TSAggregation{Aggregator: redis.TSAggregatorAvg, BucketDuration: 10}
+	redis.NewTSCreateRuleCommand(store, clock, "raw", "avg", average).Run()
+	for _, sample := range []struct {
+		timestamp int64
+		value     float64
+	}{
+		{1, 1},
+		{5, 3},
+		// The samples of the bucket at 0 are older than the retention once this is added, but
+		// they're compacted before they're trimmed.
+		{22, 10},
+		// The bucket at 0 isn't compacted again from only the samples left in it.
+		{8, 100},
+		{25, 20},
+		{31, 1},
+		// An upsert into a compacted bucket compacts it again.
+		{25, 40},
+	} {
+		redis.NewTSAddCommand(
+			store, clock, "raw", sample.timestamp, sample.value, redis.TSCreateOptions{},
+			redis.TSDuplicateDefault,
+		).Run()
+	}
+
+	query := redis.TSRangeQuery{From: 0, To: 100}
+	response := redis.NewTSRangeCommand(store, clock, "avg", query).Run()
+
+	want := "*2\r\n*2\r\n:0\r\n+2\r\n*2\r\n:20\r\n+25\r\n"
+	if response != want {
+		t.Errorf("command expected to return %#v but was %#v", want, response)
+	}
+}
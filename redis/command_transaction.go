@@ -0,0 +1,160 @@
+package redis
+
+// transaction is the state of a client's transaction, between MULTI and EXEC or DISCARD.
+type transaction struct {
+	commands []Command
+	// failed is true if a command couldn't be queued, like one with the wrong number of
+	// arguments, which makes EXEC discard the transaction.
+	failed bool
+}
+
+const (
+	errNestedMulti    CommandError = "ERR MULTI calls can not be nested"
+	errExecNoMulti    CommandError = "ERR EXEC without MULTI"
+	errDiscardNoMulti CommandError = "ERR DISCARD without MULTI"
+	errExecAbort      CommandError = "EXECABORT Transaction discarded because of previous errors."
+	errWatchInMulti   CommandError = "ERR WATCH inside MULTI is not allowed"
+)
+
+func NewMultiCommand(client *Client) *MultiCommand {
+	return &MultiCommand{client: client}
+}
+
+// MultiCommand is MULTI, which starts a transaction: the commands that the client sends after it
+// are queued rather than run, until EXEC runs them all at once or DISCARD discards them.
+type MultiCommand struct {
+	client *Client
+}
+
+func (m *MultiCommand) Run() string {
+	if !m.client.startTransaction() {
+		return errorResponse(errNestedMulti)
+	}
+	return simpleString("OK")
+}
+
+func NewQueuedCommand(client *Client, command Command) *QueuedCommand {
+	return &QueuedCommand{
+		client:  client,
+		command: command,
+	}
+}
+
+// QueuedCommand is a command sent in a transaction, which adds it to the client's transaction
+// for EXEC to run.
+type QueuedCommand struct {
+	client  *Client
+	command Command
+}
+
+func (q *QueuedCommand) Run() string {
+	q.client.queue(q.command)
+	return simpleString("QUEUED")
+}
+
+func NewExecCommand(store *Store, clock Clock, client *Client) *ExecCommand {
+	return &ExecCommand{
+		store:  store,
+		clock:  clock,
+		client: client,
+	}
+}
+
+// ExecCommand is EXEC, which runs the commands queued in the client's transaction and replies
+// with an array of their responses. No other client's commands run in between them. Like Redis,
+// a command that fails doesn't stop the rest from running, but if any command couldn't be
+// queued, none of them run. If any key that the client watches changed since it watched it, none
+// of them run either, and it replies with a null array.
+type ExecCommand struct {
+	store  *Store
+	clock  Clock
+	client *Client
+}
+
+func (e *ExecCommand) Run() string {
+	transaction := e.client.endTransaction()
+	switch {
+	case transaction == nil:
+		return errorResponse(errExecNoMulti)
+	case transaction.failed:
+		e.client.unwatch()
+		return errorResponse(errExecAbort)
+	}
+	var response string
+	e.store.exclusively(e.clock.NowMonotonic(), func(tx *storeTx) {
+		if tx.unwatch(e.client) {
+			response = nullArray
+			return
+		}
+		responses := make([]string, len(transaction.commands))
+		for i, command := range transaction.commands {
+			responses[i] = command.Run()
+		}
+		response = array(responses...)
+	})
+	return response
+}
+
+func NewDiscardCommand(client *Client) *DiscardCommand {
+	return &DiscardCommand{client: client}
+}
+
+// DiscardCommand is DISCARD, which ends the client's transaction without running the commands
+// queued in it, and stops it watching any keys.
+type DiscardCommand struct {
+	client *Client
+}
+
+func (d *DiscardCommand) Run() string {
+	if d.client.endTransaction() == nil {
+		return errorResponse(errDiscardNoMulti)
+	}
+	d.client.unwatch()
+	return simpleString("OK")
+}
+
+func NewWatchCommand(store *Store, clock Clock, client *Client, keys []string) *WatchCommand {
+	return &WatchCommand{
+		store:  store,
+		clock:  clock,
+		client: client,
+		keys:   keys,
+	}
+}
+
+// WatchCommand is WATCH, which makes the client watch keys, so that its next transaction is
+// discarded if any of them change before EXEC. Like Redis, changes that the client makes itself
+// count too, as do keys expiring.
+type WatchCommand struct {
+	store  *Store
+	clock  Clock
+	client *Client
+	keys   []string
+}
+
+func (w *WatchCommand) Run() string {
+	if w.client.inTransaction() {
+		return errorResponse(errWatchInMulti)
+	}
+	w.store.read(w.clock.NowMonotonic(), func(tx *storeTx) {
+		for _, key := range w.keys {
+			value, ok := tx.store.entries[key]
+			tx.store.watches.watch(w.client, key, ok && value.expiredAt(tx.now))
+		}
+	})
+	return simpleString("OK")
+}
+
+func NewUnwatchCommand(client *Client) *UnwatchCommand {
+	return &UnwatchCommand{client: client}
+}
+
+// UnwatchCommand is UNWATCH, which stops the client watching any keys.
+type UnwatchCommand struct {
+	client *Client
+}
+
+func (u *UnwatchCommand) Run() string {
+	u.client.unwatch()
+	return simpleString("OK")
+}
@@ -0,0 +1,362 @@
+package redis_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/redis"
+)
+
+func TestExecCommand(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		// requests are sent in order, and want is the response to the last one.
+		requests [][]string
+		want     string
+	}{
+		{
+			name: "commands run in order",
+			requests: [][]string{
+				{"MULTI"},
+				{"SET", "a", "1"},
+				{"APPEND", "a", "2"},
+				{"GET", "a"},
+				{"EXEC"},
+			},
+			want: "*3\r\n+OK\r\n:2\r\n" + bulkString("12"),
+		},
+		{
+			name:     "empty transaction",
+			requests: [][]string{{"MULTI"}, {"EXEC"}},
+			want:     "*0\r\n",
+		},
+		{
+			name: "a command that fails doesn't stop the others",
+			requests: [][]string{
+				{"SET", "a", "x"},
+				{"MULTI"},
+				{"LLEN", "a"},
+				{"SET", "b", "1"},
+				{"EXEC"},
+			},
+			want: "*2\r\n-WRONGTYPE Operation against a key holding the wrong kind of value\r\n" +
+				"+OK\r\n",
+		},
+		{
+			name: "a command that can't be queued discards the transaction",
+			requests: [][]string{
+				{"MULTI"},
+				{"NOPE"},
+				{"SET", "b", "1"},
+				{"EXEC"},
+			},
+			want: "-EXECABORT Transaction discarded because of previous errors.\r\n",
+		},
+		{
+			name: "blocking commands don't block",
+			requests: [][]string{
+				{"MULTI"},
+				{"BLPOP", "list", "0"},
+				{"EXEC"},
+			},
+			want: "*1\r\n*-1\r\n",
+		},
+		{
+			name: "blocking moves reply with a null bulk string",
+			requests: [][]string{
+				{"MULTI"},
+				{"BLMOVE", "list", "other", "LEFT", "RIGHT", "0"},
+				{"BRPOPLPUSH", "list", "other", "0"},
+				{"EXEC"},
+			},
+			want: "*2\r\n$-1\r\n$-1\r\n",
+		},
+		{
+			name:     "EXEC without MULTI",
+			requests: [][]string{{"EXEC"}},
+			want:     "-ERR EXEC without MULTI\r\n",
+		},
+		{
+			name:     "nested MULTI",
+			requests: [][]string{{"MULTI"}, {"MULTI"}},
+			want:     "-ERR MULTI calls can not be nested\r\n",
+		},
+		{
+			name:     "DISCARD without MULTI",
+			requests: [][]string{{"DISCARD"}},
+			want:     "-ERR DISCARD without MULTI\r\n",
+		},
+		{
+			name: "DISCARD",
+			requests: [][]string{
+				{"MULTI"},
+				{"SET", "a", "1"},
+				{"DISCARD"},
+				{"GET", "a"},
+			},
+			want: "$-1\r\n",
+		},
+		{
+			name: "RESET",
+			requests: [][]string{
+				{"MULTI"},
+				{"RESET"},
+				{"EXEC"},
+			},
+			want: "-ERR EXEC without MULTI\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser := redis.NewParser(
+				zeroValueRedisConfig, redis.NewStore(), &FakeClock{CurrentTime: time.UnixMilli(0)},
+			)
+			run := requestRunner(t, parser, parser.NewClient())
+
+			var response string
+			for _, request := range tt.requests {
+				response = run(request...)
+			}
+
+			if response != tt.want {
+				t.Errorf("command expected to return %#v but was %#v", tt.want, response)
+			}
+		})
+	}
+}
+
+func TestExecCommand_Queued(t *testing.T) {
+	t.Parallel()
+
+	parser := redis.NewParser(zeroValueRedisConfig, redis.NewStore(), FakeClock{})
+	run := requestRunner(t, parser, parser.NewClient())
+	other := requestRunner(t, parser, parser.NewClient())
+
+	run("MULTI")
+	if response := run("SET", "a", "1"); response != "+QUEUED\r\n" {
+		t.Errorf(`command expected to return "+QUEUED\r\n" but was %#v`, response)
+	}
+	// Queued commands don't run until EXEC.
+	if response := other("GET", "a"); response != "$-1\r\n" {
+		t.Errorf(`command expected to return "$-1\r\n" but was %#v`, response)
+	}
+	run("EXEC")
+	if want, response := bulkString("1"), other("GET", "a"); response != want {
+		t.Errorf("command expected to return %#v but was %#v", want, response)
+	}
+}
+
+func TestExecCommand_WrongNumberOfArguments(t *testing.T) {
+	t.Parallel()
+
+	for _, request := range [][]string{{"SET", "k"}, {"GET"}, {"ECHO"}, {"INFO"}} {
+		t.Run(strings.Join(request, " "), func(t *testing.T) {
+			parser := redis.NewParser(zeroValueRedisConfig, redis.NewStore(), FakeClock{})
+			run := requestRunner(t, parser, parser.NewClient())
+
+			run("MULTI")
+			want := fmt.Sprintf(
+				"-ERR wrong number of arguments for '%s' command\r\n", strings.ToLower(request[0]),
+			)
+			if response := run(request...); response != want {
+				t.Errorf("command expected to return %#v but was %#v", want, response)
+			}
+			want = "-EXECABORT Transaction discarded because of previous errors.\r\n"
+			if response := run("EXEC"); response != want {
+				t.Errorf("command expected to return %#v but was %#v", want, response)
+			}
+		})
+	}
+}
+
+func TestExecCommand_BlockedClients(t *testing.T) {
+	t.Parallel()
+
+	parser := redis.NewParser(zeroValueRedisConfig, redis.NewStore(), FakeClock{})
+	run := requestRunner(t, parser, parser.NewClient())
+	blockedClient := parser.NewClient()
+	blocked := requestRunner(t, parser, blockedClient)
+
+	done := make(chan string)
+	go func() {
+		done <- blocked("BLPOP", "list", "0")
+	}()
+	waitUntilBlocked(t, blockedClient)
+
+	// The blocked client is only served once the transaction is over, so it doesn't see the list
+	// in between its commands.
+	run("MULTI")
+	run("RPUSH", "list", "a", "b")
+	run("LLEN", "list")
+	want := "*2\r\n:2\r\n:2\r\n"
+	if response := run("EXEC"); response != want {
+		t.Errorf("command expected to return %#v but was %#v", want, response)
+	}
+	want = bulkStringArray("list", "a")
+	if response := <-done; response != want {
+		t.Errorf("command expected to return %#v but was %#v", want, response)
+	}
+	if response := run("LRANGE", "list", "0", "-1"); response != bulkStringArray("b") {
+		t.Errorf("command expected to return %#v but was %#v", bulkStringArray("b"), response)
+	}
+}
+
+func TestWatchCommand(t *testing.T) {
+	t.Parallel()
+
+	type request struct {
+		// other is true if the request is sent by a client other than the one that watches.
+		other bool
+		args  []string
+	}
+	transaction := []request{{args: []string{"MULTI"}}, {args: []string{"GET", "a"}}}
+
+	tests := []struct {
+		name     string
+		requests []request
+		want     string
+	}{
+		{
+			name: "unchanged",
+			requests: []request{
+				{args: []string{"WATCH", "a", "b"}},
+				{other: true, args: []string{"GET", "a"}},
+			},
+			want: "*1\r\n$-1\r\n",
+		},
+		{
+			name: "set by another client",
+			requests: []request{
+				{args: []string{"WATCH", "a"}},
+				{other: true, args: []string{"SET", "a", "1"}},
+			},
+			want: "*-1\r\n",
+		},
+		{
+			name: "set by the client itself",
+			requests: []request{
+				{args: []string{"WATCH", "a"}},
+				{args: []string{"SET", "a", "1"}},
+			},
+			want: "*-1\r\n",
+		},
+		{
+			name: "deleted",
+			requests: []request{
+				{args: []string{"SET", "a", "1"}},
+				{args: []string{"WATCH", "a"}},
+				{other: true, args: []string{"GETDEL", "a"}},
+			},
+			want: "*-1\r\n",
+		},
+		{
+			name: "another key changed",
+			requests: []request{
+				{args: []string{"WATCH", "a"}},
+				{other: true, args: []string{"SET", "b", "1"}},
+			},
+			want: "*1\r\n$-1\r\n",
+		},
+		{
+			name: "module type changed",
+			requests: []request{
+				{args: []string{"WATCH", "filter"}},
+				{other: true, args: []string{"BF.ADD", "filter", "a"}},
+			},
+			want: "*-1\r\n",
+		},
+		{
+			name: "UNWATCH",
+			requests: []request{
+				{args: []string{"WATCH", "a"}},
+				{args: []string{"UNWATCH"}},
+				{other: true, args: []string{"SET", "a", "1"}},
+			},
+			want: "*1\r\n" + bulkString("1"),
+		},
+		{
+			name: "DISCARD",
+			requests: []request{
+				{args: []string{"WATCH", "a"}},
+				{args: []string{"MULTI"}},
+				{args: []string{"DISCARD"}},
+				{other: true, args: []string{"SET", "a", "1"}},
+			},
+			want: "*1\r\n" + bulkString("1"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser := redis.NewParser(
+				defaultEncodingRedisConfig, redis.NewStore(), &FakeClock{CurrentTime: time.UnixMilli(0)},
+			)
+			run := requestRunner(t, parser, parser.NewClient())
+			other := requestRunner(t, parser, parser.NewClient())
+
+			for _, request := range append(tt.requests, transaction...) {
+				if request.other {
+					other(request.args...)
+				} else {
+					run(request.args...)
+				}
+			}
+
+			if response := run("EXEC"); response != tt.want {
+				t.Errorf("command expected to return %#v but was %#v", tt.want, response)
+			}
+		})
+	}
+}
+
+func TestWatchCommand_Expired(t *testing.T) {
+	t.Parallel()
+
+	clock := &FakeClock{CurrentTime: time.UnixMilli(0)}
+	parser := redis.NewParser(zeroValueRedisConfig, redis.NewStore(), clock)
+	run := requestRunner(t, parser, parser.NewClient())
+	other := requestRunner(t, parser, parser.NewClient())
+
+	// A key that expires after it's watched has changed, even if it hasn't been deleted yet.
+	run("SET", "a", "1", "PX", "100")
+	run("WATCH", "a")
+	clock.CurrentTime = time.UnixMilli(200)
+	run("MULTI")
+	run("SET", "b", "1")
+	if response := run("EXEC"); response != "*-1\r\n" {
+		t.Errorf(`command expected to return "*-1\r\n" but was %#v`, response)
+	}
+
+	// A key that had already expired when it was watched hasn't, even once it's deleted.
+	run("SET", "c", "1", "PX", "100")
+	clock.CurrentTime = time.UnixMilli(400)
+	run("WATCH", "c")
+	other("GET", "c")
+	run("MULTI")
+	run("SET", "b", "1")
+	if response := run("EXEC"); response != "*1\r\n+OK\r\n" {
+		t.Errorf(`command expected to return "*1\r\n+OK\r\n" but was %#v`, response)
+	}
+}
+
+func TestWatchCommand_InMulti(t *testing.T) {
+	t.Parallel()
+
+	parser := redis.NewParser(zeroValueRedisConfig, redis.NewStore(), FakeClock{})
+	run := requestRunner(t, parser, parser.NewClient())
+
+	run("MULTI")
+	want := "-ERR WATCH inside MULTI is not allowed\r\n"
+	if response := run("WATCH", "a"); response != want {
+		t.Errorf("command expected to return %#v but was %#v", want, response)
+	}
+	// The transaction can still be executed.
+	if response := run("EXEC"); response != "*0\r\n" {
+		t.Errorf(`command expected to return "*0\r\n" but was %#v`, response)
+	}
+}
@@ -0,0 +1,960 @@
+package redis
+
+import (
+	"math"
+	"math/rand"
+	"strconv"
+	"time"
+)
+
+func createZSet(tx *storeTx, key string) *zset {
+	z := newZSet()
+	tx.set(key, StoreValue{data: z})
+	return z
+}
+
+// deleteZSetIfEmpty deletes the sorted set at key if it no longer has any members.
+func deleteZSetIfEmpty(tx *storeTx, key string, z *zset) {
+	if z.len() == 0 {
+		tx.deleteEmpty(key)
+	}
+}
+
+// scoreMembersResponse returns entries as an array of their members, each followed by its score
+// if withScores is true.
+func scoreMembersResponse(entries []ScoreMember, withScores bool) string {
+	elements := make([]string, 0, len(entries)*2)
+	for _, entry := range entries {
+		elements = append(elements, bulkString(entry.Member))
+		if withScores {
+			elements = append(elements, bulkString(formatScore(entry.Score)))
+		}
+	}
+	return array(elements...)
+}
+
+const errScoreNaN CommandError = "ERR resulting score is not a number (NaN)"
+
+func NewZAddCommand(
+	store *Store,
+	clock Clock,
+	key string,
+	scoreMembers []ScoreMember,
+	options ...func(*ZAddCommand),
+) *ZAddCommand {
+	result := &ZAddCommand{
+		store:        store,
+		clock:        clock,
+		key:          key,
+		scoreMembers: scoreMembers,
+	}
+	for _, option := range options {
+		option(result)
+	}
+	return result
+}
+
+// ZAddCommand is ZADD, or ZINCRBY if it increments.
+type ZAddCommand struct {
+	store        *Store
+	clock        Clock
+	key          string
+	scoreMembers []ScoreMember
+	// onlyIfAbsent and onlyIfExists are NX and XX, which only add new members and only update
+	// existing members.
+	onlyIfAbsent bool
+	onlyIfExists bool
+	// onlyIfGreater and onlyIfLess are GT and LT, which only update existing members if their
+	// new score is greater or less than their current one.
+	onlyIfGreater bool
+	onlyIfLess    bool
+	// changed is CH, which replies with the number of members added or updated rather than only
+	// those added.
+	changed bool
+	// increment is INCR, which adds to a member's score rather than setting it and replies with
+	// the new score.
+	increment bool
+}
+
+func ZAddNX() func(*ZAddCommand) {
+	return func(command *ZAddCommand) {
+		command.onlyIfAbsent = true
+	}
+}
+
+func ZAddXX() func(*ZAddCommand) {
+	return func(command *ZAddCommand) {
+		command.onlyIfExists = true
+	}
+}
+
+func ZAddGT() func(*ZAddCommand) {
+	return func(command *ZAddCommand) {
+		command.onlyIfGreater = true
+	}
+}
+
+func ZAddLT() func(*ZAddCommand) {
+	return func(command *ZAddCommand) {
+		command.onlyIfLess = true
+	}
+}
+
+func ZAddCH() func(*ZAddCommand) {
+	return func(command *ZAddCommand) {
+		command.changed = true
+	}
+}
+
+func ZAddIncr() func(*ZAddCommand) {
+	return func(command *ZAddCommand) {
+		command.increment = true
+	}
+}
+
+func (z *ZAddCommand) Run() string {
+	var response string
+	z.store.write(z.clock.NowMonotonic(), func(tx *storeTx) {
+		value, ok, err := tx.getTyped(z.key, ValueTypeZSet)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		zset := value.zset()
+
+		added, updated := 0, 0
+		response = nullBulkString
+		for _, scoreMember := range z.scoreMembers {
+			score := scoreMember.Score
+			current, exists := 0.0, false
+			if ok {
+				current, exists = zset.score(scoreMember.Member)
+			}
+			if z.increment && exists {
+				score += current
+				if math.IsNaN(score) {
+					response = errorResponse(errScoreNaN)
+					return
+				}
+			}
+
+			switch {
+			case exists && z.onlyIfAbsent,
+				!exists && z.onlyIfExists,
+				exists && z.onlyIfGreater && score <= current,
+				exists && z.onlyIfLess && score >= current:
+				continue
+			case !exists:
+				if !ok {
+					zset, ok = createZSet(tx, z.key), true
+				}
+				zset.add(scoreMember.Member, score)
+				added++
+			case score != current:
+				zset.add(scoreMember.Member, score)
+				updated++
+			}
+			response = bulkString(formatScore(score))
+		}
+
+		switch {
+		case z.increment && added+updated > 0:
+			tx.notify(notifyZSet, "zincr", z.key)
+		case added+updated > 0:
+			tx.notify(notifyZSet, "zadd", z.key)
+		}
+		if added > 0 {
+			tx.signalKeyAsReady(z.key)
+		}
+		if z.increment {
+			return
+		}
+		if z.changed {
+			added += updated
+		}
+		response = integer(added)
+	})
+	return response
+}
+
+func NewZRangeCommand(
+	store *Store,
+	clock Clock,
+	key string,
+	query ZRangeQuery,
+	withScores bool,
+) *ZRangeCommand {
+	return &ZRangeCommand{
+		store:      store,
+		clock:      clock,
+		key:        key,
+		query:      query,
+		withScores: withScores,
+	}
+}
+
+// ZRangeCommand is ZRANGE, or one of the older commands like ZREVRANGE and ZRANGEBYSCORE that
+// it replaces.
+type ZRangeCommand struct {
+	store      *Store
+	clock      Clock
+	key        string
+	query      ZRangeQuery
+	withScores bool
+}
+
+func (z *ZRangeCommand) Run() string {
+	response := array()
+	z.store.read(z.clock.NowMonotonic(), func(tx *storeTx) {
+		value, ok, err := tx.getTyped(z.key, ValueTypeZSet)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		if ok {
+			response = scoreMembersResponse(value.zset().query(z.query), z.withScores)
+		}
+	})
+	return response
+}
+
+func NewZCountCommand(store *Store, clock Clock, key string, query ZRangeQuery) *ZCountCommand {
+	return &ZCountCommand{
+		store: store,
+		clock: clock,
+		key:   key,
+		query: query,
+	}
+}
+
+// ZCountCommand is ZCOUNT or ZLEXCOUNT, depending on how its query selects members.
+type ZCountCommand struct {
+	store *Store
+	clock Clock
+	key   string
+	query ZRangeQuery
+}
+
+func (z *ZCountCommand) Run() string {
+	response := integer(0)
+	z.store.read(z.clock.NowMonotonic(), func(tx *storeTx) {
+		value, ok, err := tx.getTyped(z.key, ValueTypeZSet)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		if ok {
+			response = integer(value.zset().count(z.query))
+		}
+	})
+	return response
+}
+
+func NewZRemRangeCommand(store *Store, clock Clock, key string, query ZRangeQuery) *ZRemRangeCommand {
+	return &ZRemRangeCommand{
+		store: store,
+		clock: clock,
+		key:   key,
+		query: query,
+	}
+}
+
+// ZRemRangeCommand is ZREMRANGEBYRANK, ZREMRANGEBYSCORE or ZREMRANGEBYLEX, depending on how its
+// query selects members.
+type ZRemRangeCommand struct {
+	store *Store
+	clock Clock
+	key   string
+	query ZRangeQuery
+}
+
+func (z *ZRemRangeCommand) Run() string {
+	response := integer(0)
+	z.store.write(z.clock.NowMonotonic(), func(tx *storeTx) {
+		value, ok, err := tx.getTyped(z.key, ValueTypeZSet)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		if !ok {
+			return
+		}
+		zset := value.zset()
+		entries := zset.query(z.query)
+		for _, entry := range entries {
+			zset.remove(entry.Member)
+		}
+		if len(entries) > 0 {
+			tx.notify(notifyZSet, z.query.By.removeEvent(), z.key)
+		}
+		deleteZSetIfEmpty(tx, z.key, zset)
+		response = integer(len(entries))
+	})
+	return response
+}
+
+func NewZCardCommand(store *Store, clock Clock, key string) *ZCardCommand {
+	return &ZCardCommand{
+		store: store,
+		clock: clock,
+		key:   key,
+	}
+}
+
+type ZCardCommand struct {
+	store *Store
+	clock Clock
+	key   string
+}
+
+func (z *ZCardCommand) Run() string {
+	response := integer(0)
+	z.store.read(z.clock.NowMonotonic(), func(tx *storeTx) {
+		value, ok, err := tx.getTyped(z.key, ValueTypeZSet)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		if ok {
+			response = integer(value.zset().len())
+		}
+	})
+	return response
+}
+
+func NewZRankCommand(
+	store *Store,
+	clock Clock,
+	key, member string,
+	reverse, withScore bool,
+) *ZRankCommand {
+	return &ZRankCommand{
+		store:     store,
+		clock:     clock,
+		key:       key,
+		member:    member,
+		reverse:   reverse,
+		withScore: withScore,
+	}
+}
+
+// ZRankCommand is ZRANK, or ZREVRANK if it's reversed.
+type ZRankCommand struct {
+	store     *Store
+	clock     Clock
+	key       string
+	member    string
+	reverse   bool
+	withScore bool
+}
+
+func (z *ZRankCommand) Run() string {
+	response := nullBulkString
+	if z.withScore {
+		response = nullArray
+	}
+	z.store.read(z.clock.NowMonotonic(), func(tx *storeTx) {
+		value, ok, err := tx.getTyped(z.key, ValueTypeZSet)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		if !ok {
+			return
+		}
+		rank, ok := value.zset().rank(z.member, z.reverse)
+		switch {
+		case !ok:
+		case z.withScore:
+			score, _ := value.zset().score(z.member)
+			response = array(integer(rank), bulkString(formatScore(score)))
+		default:
+			response = integer(rank)
+		}
+	})
+	return response
+}
+
+func NewZScoreCommand(store *Store, clock Clock, key string, members []string) *ZScoreCommand {
+	return &ZScoreCommand{
+		store:   store,
+		clock:   clock,
+		key:     key,
+		members: members,
+	}
+}
+
+// ZScoreCommand is ZSCORE, which replies with the score of one member, or ZMSCORE, which replies
+// with an array of the scores of its members.
+type ZScoreCommand struct {
+	store   *Store
+	clock   Clock
+	key     string
+	members []string
+	multi   bool
+}
+
+func NewZMScoreCommand(store *Store, clock Clock, key string, members []string) *ZScoreCommand {
+	result := NewZScoreCommand(store, clock, key, members)
+	result.multi = true
+	return result
+}
+
+func (z *ZScoreCommand) Run() string {
+	var response string
+	z.store.read(z.clock.NowMonotonic(), func(tx *storeTx) {
+		value, ok, err := tx.getTyped(z.key, ValueTypeZSet)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		scores := make([]string, len(z.members))
+		for i, member := range z.members {
+			scores[i] = nullBulkString
+			if !ok {
+				continue
+			}
+			if score, exists := value.zset().score(member); exists {
+				scores[i] = bulkString(formatScore(score))
+			}
+		}
+		if z.multi {
+			response = array(scores...)
+		} else {
+			response = scores[0]
+		}
+	})
+	return response
+}
+
+func NewZRemCommand(store *Store, clock Clock, key string, members []string) *ZRemCommand {
+	return &ZRemCommand{
+		store:   store,
+		clock:   clock,
+		key:     key,
+		members: members,
+	}
+}
+
+type ZRemCommand struct {
+	store   *Store
+	clock   Clock
+	key     string
+	members []string
+}
+
+func (z *ZRemCommand) Run() string {
+	response := integer(0)
+	z.store.write(z.clock.NowMonotonic(), func(tx *storeTx) {
+		value, ok, err := tx.getTyped(z.key, ValueTypeZSet)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		if !ok {
+			return
+		}
+		zset := value.zset()
+		removed := 0
+		for _, member := range z.members {
+			if zset.remove(member) {
+				removed++
+			}
+		}
+		if removed > 0 {
+			tx.notify(notifyZSet, "zrem", z.key)
+		}
+		deleteZSetIfEmpty(tx, z.key, zset)
+		response = integer(removed)
+	})
+	return response
+}
+
+// ZSetEnd is the end of a sorted set that ZPOPMIN and ZPOPMAX pop from.
+type ZSetEnd int
+
+const (
+	ZSetEndMin ZSetEnd = iota
+	ZSetEndMax
+)
+
+// popEvent returns the keyspace event of popping from z: zpopmin or zpopmax.
+func (z ZSetEnd) popEvent() string {
+	if z == ZSetEndMin {
+		return "zpopmin"
+	}
+	return "zpopmax"
+}
+
+// popZSetEntries removes up to count members from end of the sorted set at key, deleting it if
+// it's left empty and notifying the keyspace events, and returns them with their scores.
+func popZSetEntries(tx *storeTx, key string, zset *zset, end ZSetEnd, count int) []ScoreMember {
+	if count > zset.len() {
+		count = zset.len()
+	}
+	var entries []ScoreMember
+	if end == ZSetEndMin {
+		entries = zset.entries(0, count-1, false)
+	} else {
+		entries = zset.entries(zset.len()-count, zset.len()-1, true)
+	}
+	for _, entry := range entries {
+		zset.remove(entry.Member)
+	}
+	if len(entries) > 0 {
+		tx.notify(notifyZSet, end.popEvent(), key)
+	}
+	deleteZSetIfEmpty(tx, key, zset)
+	return entries
+}
+
+func NewZPopCommand(store *Store, clock Clock, key string, end ZSetEnd, count int) *ZPopCommand {
+	return &ZPopCommand{
+		store: store,
+		clock: clock,
+		key:   key,
+		end:   end,
+		count: count,
+	}
+}
+
+// ZPopCommand is ZPOPMIN or ZPOPMAX, depending on its end.
+type ZPopCommand struct {
+	store *Store
+	clock Clock
+	key   string
+	end   ZSetEnd
+	count int
+}
+
+func (z *ZPopCommand) Run() string {
+	response := array()
+	z.store.write(z.clock.NowMonotonic(), func(tx *storeTx) {
+		value, ok, err := tx.getTyped(z.key, ValueTypeZSet)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		if !ok {
+			return
+		}
+		response = scoreMembersResponse(popZSetEntries(tx, z.key, value.zset(), z.end, z.count), true)
+	})
+	return response
+}
+
+func NewBZPopCommand(
+	store *Store,
+	clock Clock,
+	client *Client,
+	keys []string,
+	end ZSetEnd,
+	timeout time.Duration,
+) *BZPopCommand {
+	return &BZPopCommand{
+		store:   store,
+		clock:   clock,
+		client:  client,
+		keys:    keys,
+		end:     end,
+		timeout: timeout,
+	}
+}
+
+// BZPopCommand is BZPOPMIN or BZPOPMAX, depending on its end. It pops a member from the first
+// non-empty sorted set of keys, blocking until one of them has a member if they're all empty.
+type BZPopCommand struct {
+	store   *Store
+	clock   Clock
+	client  *Client
+	keys    []string
+	end     ZSetEnd
+	timeout time.Duration
+}
+
+func (b *BZPopCommand) Run() string {
+	return blockForKeys(
+		b.store,
+		b.clock,
+		b.client,
+		b.keys,
+		b.timeout,
+		nullArray,
+		func(tx *storeTx, key string) (string, bool, error) {
+			value, ok, err := tx.getTyped(key, ValueTypeZSet)
+			if err != nil || !ok {
+				return "", false, err
+			}
+			entry := popZSetEntries(tx, key, value.zset(), b.end, 1)[0]
+			response := array(
+				bulkString(key), bulkString(entry.Member), bulkString(formatScore(entry.Score)),
+			)
+			return response, true, nil
+		},
+	)
+}
+
+// ZAggregate is how ZUNION and ZINTER combine the scores of a member that's in several sorted
+// sets.
+type ZAggregate int
+
+const (
+	ZAggregateSum ZAggregate = iota
+	ZAggregateMin
+	ZAggregateMax
+)
+
+func (a ZAggregate) apply(x, y float64) float64 {
+	switch a {
+	case ZAggregateMin:
+		return math.Min(x, y)
+	case ZAggregateMax:
+		return math.Max(x, y)
+	}
+	// Like Redis, treat inf + -inf as 0 rather than NaN.
+	if sum := x + y; !math.IsNaN(sum) {
+		return sum
+	}
+	return 0
+}
+
+func NewZSetOpCommand(
+	store *Store,
+	clock Clock,
+	op SetOp,
+	keys []string,
+	options ...func(*ZSetOpCommand),
+) *ZSetOpCommand {
+	result := &ZSetOpCommand{
+		store: store,
+		clock: clock,
+		op:    op,
+		keys:  keys,
+	}
+	for _, option := range options {
+		option(result)
+	}
+	return result
+}
+
+// ZSetOpCommand is ZUNION, ZINTER or ZDIFF depending on its op, or ZUNIONSTORE, ZINTERSTORE or
+// ZDIFFSTORE if it has a destination. Sets can be used as input too, as if every member had a
+// score of 1.
+type ZSetOpCommand struct {
+	store *Store
+	clock Clock
+	op    SetOp
+	keys  []string
+	// weights multiply the scores of each of keys, if set.
+	weights     []float64
+	aggregate   ZAggregate
+	withScores  bool
+	destination *string
+}
+
+func ZSetOpWeights(weights []float64) func(*ZSetOpCommand) {
+	return func(command *ZSetOpCommand) {
+		command.weights = weights
+	}
+}
+
+func ZSetOpAggregate(aggregate ZAggregate) func(*ZSetOpCommand) {
+	return func(command *ZSetOpCommand) {
+		command.aggregate = aggregate
+	}
+}
+
+func ZSetOpWithScores() func(*ZSetOpCommand) {
+	return func(command *ZSetOpCommand) {
+		command.withScores = true
+	}
+}
+
+// ZSetOpStore makes a ZSetOpCommand store its result at destination, replacing whatever is
+// there, and reply with the result's size.
+func ZSetOpStore(destination string) func(*ZSetOpCommand) {
+	return func(command *ZSetOpCommand) {
+		command.destination = &destination
+	}
+}
+
+func (z *ZSetOpCommand) Run() string {
+	var response string
+	run := z.store.read
+	if z.destination != nil {
+		run = z.store.write
+	}
+	run(z.clock.NowMonotonic(), func(tx *storeTx) {
+		sources, err := z.sources(tx)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		result := z.apply(sources)
+		if z.destination == nil {
+			response = scoreMembersResponse(result.query(NewZRangeQuery(0, -1)), z.withScores)
+			return
+		}
+
+		response = integer(result.len())
+		if result.len() == 0 {
+			if tx.delete(*z.destination) {
+				tx.notify(notifyGeneric, "del", *z.destination)
+			}
+			return
+		}
+		tx.set(*z.destination, StoreValue{data: result})
+		tx.notify(notifyZSet, "z"+z.op.storeEvent(), *z.destination)
+		tx.signalKeyAsReady(*z.destination)
+	})
+	return response
+}
+
+// sources returns the members of each of z's keys with their weighted scores, or nil for keys
+// that don't exist.
+func (z *ZSetOpCommand) sources(tx *storeTx) ([]map[string]float64, error) {
+	sources := make([]map[string]float64, len(z.keys))
+	for i, key := range z.keys {
+		weight := 1.0
+		if z.weights != nil {
+			weight = z.weights[i]
+		}
+		weighted := func(score float64) float64 {
+			// Like Redis, treat inf * 0 as 0 rather than NaN.
+			if result := score * weight; !math.IsNaN(result) {
+				return result
+			}
+			return 0
+		}
+
+		value, ok := tx.get(key)
+		switch {
+		case !ok:
+		case value.Type() == ValueTypeZSet:
+			sources[i] = make(map[string]float64, value.zset().len())
+			for member, score := range value.zset().scores {
+				sources[i][member] = weighted(score)
+			}
+		case value.Type() == ValueTypeSet:
+			sources[i] = make(map[string]float64, value.set().len())
+			for _, member := range value.set().members() {
+				sources[i][member] = weighted(1)
+			}
+		default:
+			return nil, errWrongType
+		}
+	}
+	return sources, nil
+}
+
+func (z *ZSetOpCommand) apply(sources []map[string]float64) *zset {
+	result := newZSet()
+	switch z.op {
+	case SetOpUnion:
+		scores := make(map[string]float64)
+		for _, source := range sources {
+			for member, score := range source {
+				if current, ok := scores[member]; ok {
+					score = z.aggregate.apply(current, score)
+				}
+				scores[member] = score
+			}
+		}
+		for member, score := range scores {
+			result.add(member, score)
+		}
+	case SetOpInter:
+		smallest := 0
+		for i, source := range sources {
+			if source == nil {
+				return result
+			}
+			if len(source) < len(sources[smallest]) {
+				smallest = i
+			}
+		}
+	members:
+		for member := range sources[smallest] {
+			score := 0.0
+			for i, source := range sources {
+				other, ok := source[member]
+				if !ok {
+					continue members
+				}
+				if i == 0 {
+					score = other
+				} else {
+					score = z.aggregate.apply(score, other)
+				}
+			}
+			result.add(member, score)
+		}
+	case SetOpDiff:
+		for member, score := range sources[0] {
+			inOther := false
+			for _, source := range sources[1:] {
+				if _, ok := source[member]; ok {
+					inOther = true
+					break
+				}
+			}
+			if !inOther {
+				result.add(member, score)
+			}
+		}
+	}
+	return result
+}
+
+func NewZRandMemberCommand(
+	store *Store,
+	clock Clock,
+	key string,
+	options ...func(*ZRandMemberCommand),
+) *ZRandMemberCommand {
+	result := &ZRandMemberCommand{
+		store: store,
+		clock: clock,
+		key:   key,
+	}
+	for _, option := range options {
+		option(result)
+	}
+	return result
+}
+
+// ZRandMemberCommand is ZRANDMEMBER. Without a count it returns one random member. With a
+// positive count it returns up to that many distinct members, and with a negative count it
+// returns exactly that many members, which may repeat.
+type ZRandMemberCommand struct {
+	store      *Store
+	clock      Clock
+	key        string
+	count      *int
+	withScores bool
+}
+
+func ZRandMemberCount(count int) func(*ZRandMemberCommand) {
+	return func(command *ZRandMemberCommand) {
+		command.count = &count
+	}
+}
+
+func ZRandMemberWithScores() func(*ZRandMemberCommand) {
+	return func(command *ZRandMemberCommand) {
+		command.withScores = true
+	}
+}
+
+func (z *ZRandMemberCommand) Run() string {
+	response := nullBulkString
+	if z.count != nil {
+		response = array()
+	}
+	z.store.read(z.clock.NowMonotonic(), func(tx *storeTx) {
+		value, ok, err := tx.getTyped(z.key, ValueTypeZSet)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		if !ok {
+			return
+		}
+		zset := value.zset()
+		if z.count == nil {
+			response = bulkString(zset.random().Member)
+			return
+		}
+
+		var chosen []ScoreMember
+		if *z.count >= 0 {
+			entries := zset.query(NewZRangeQuery(0, -1))
+			rand.Shuffle(len(entries), func(i, j int) {
+				entries[i], entries[j] = entries[j], entries[i]
+			})
+			if *z.count < len(entries) {
+				entries = entries[:*z.count]
+			}
+			chosen = entries
+		} else {
+			chosen = make([]ScoreMember, -*z.count)
+			for i := range chosen {
+				chosen[i] = zset.random()
+			}
+		}
+		response = scoreMembersResponse(chosen, z.withScores)
+	})
+	return response
+}
+
+func NewZScanCommand(
+	store *Store,
+	clock Clock,
+	key string,
+	cursor uint64,
+	options ...func(*ZScanCommand),
+) *ZScanCommand {
+	result := &ZScanCommand{
+		store:  store,
+		clock:  clock,
+		key:    key,
+		cursor: cursor,
+		count:  defaultScanCount,
+	}
+	for _, option := range options {
+		option(result)
+	}
+	return result
+}
+
+type ZScanCommand struct {
+	store   *Store
+	clock   Clock
+	key     string
+	cursor  uint64
+	pattern *string
+	count   int
+}
+
+func ZScanMatch(pattern string) func(*ZScanCommand) {
+	return func(command *ZScanCommand) {
+		command.pattern = &pattern
+	}
+}
+
+func ZScanCount(count int) func(*ZScanCommand) {
+	return func(command *ZScanCommand) {
+		command.count = count
+	}
+}
+
+func (z *ZScanCommand) Run() string {
+	response := array(bulkString("0"), array())
+	z.store.read(z.clock.NowMonotonic(), func(tx *storeTx) {
+		value, ok, err := tx.getTyped(z.key, ValueTypeZSet)
+		if err != nil {
+			response = errorResponse(err)
+			return
+		}
+		if !ok {
+			return
+		}
+		zset := value.zset()
+		members := make([]string, 0, zset.len())
+		for member := range zset.scores {
+			members = append(members, member)
+		}
+		members, next := scanPage(members, z.cursor, z.count)
+
+		var elements []string
+		for _, member := range members {
+			if z.pattern != nil && !globMatch(*z.pattern, member) {
+				continue
+			}
+			score, _ := zset.score(member)
+			elements = append(elements, bulkString(member), bulkString(formatScore(score)))
+		}
+		response = array(bulkString(strconv.FormatUint(next, 10)), array(elements...))
+	})
+	return response
+}
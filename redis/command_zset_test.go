@@ -0,0 +1,769 @@
+package redis_test
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/redis"
+)
+
+func TestZAddCommand(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		scoreMembers []redis.ScoreMember
+		options      []func(*redis.ZAddCommand)
+		response     string
+		scores       string
+	}{
+		{
+			name:         "adds and updates",
+			scoreMembers: []redis.ScoreMember{{Score: 5, Member: "a"}, {Score: 3, Member: "c"}},
+			response:     ":1\r\n",
+			scores:       "*3\r\n$1\r\n5\r\n$1\r\n2\r\n$1\r\n3\r\n",
+		},
+		{
+			name:         "CH",
+			scoreMembers: []redis.ScoreMember{{Score: 5, Member: "a"}, {Score: 2, Member: "b"}, {Score: 3, Member: "c"}},
+			options:      []func(*redis.ZAddCommand){redis.ZAddCH()},
+			response:     ":2\r\n",
+			scores:       "*3\r\n$1\r\n5\r\n$1\r\n2\r\n$1\r\n3\r\n",
+		},
+		{
+			name:         "NX",
+			scoreMembers: []redis.ScoreMember{{Score: 5, Member: "a"}, {Score: 3, Member: "c"}},
+			options:      []func(*redis.ZAddCommand){redis.ZAddNX()},
+			response:     ":1\r\n",
+			scores:       "*3\r\n$1\r\n1\r\n$1\r\n2\r\n$1\r\n3\r\n",
+		},
+		{
+			name:         "XX",
+			scoreMembers: []redis.ScoreMember{{Score: 5, Member: "a"}, {Score: 3, Member: "c"}},
+			options:      []func(*redis.ZAddCommand){redis.ZAddXX(), redis.ZAddCH()},
+			response:     ":1\r\n",
+			scores:       "*3\r\n$1\r\n5\r\n$1\r\n2\r\n$-1\r\n",
+		},
+		{
+			name:         "GT",
+			scoreMembers: []redis.ScoreMember{{Score: 5, Member: "a"}, {Score: 0, Member: "b"}, {Score: 3, Member: "c"}},
+			options:      []func(*redis.ZAddCommand){redis.ZAddGT(), redis.ZAddCH()},
+			response:     ":2\r\n",
+			scores:       "*3\r\n$1\r\n5\r\n$1\r\n2\r\n$1\r\n3\r\n",
+		},
+		{
+			name:         "LT",
+			scoreMembers: []redis.ScoreMember{{Score: 5, Member: "a"}, {Score: 0, Member: "b"}},
+			options:      []func(*redis.ZAddCommand){redis.ZAddLT(), redis.ZAddCH()},
+			response:     ":1\r\n",
+			scores:       "*3\r\n$1\r\n1\r\n$1\r\n0\r\n$-1\r\n",
+		},
+		{
+			name:         "INCR",
+			scoreMembers: []redis.ScoreMember{{Score: 1.5, Member: "b"}},
+			options:      []func(*redis.ZAddCommand){redis.ZAddIncr()},
+			response:     "$3\r\n3.5\r\n",
+			scores:       "*3\r\n$1\r\n1\r\n$3\r\n3.5\r\n$-1\r\n",
+		},
+		{
+			name:         "INCR new member",
+			scoreMembers: []redis.ScoreMember{{Score: 1.5, Member: "c"}},
+			options:      []func(*redis.ZAddCommand){redis.ZAddIncr()},
+			response:     "$3\r\n1.5\r\n",
+			scores:       "*3\r\n$1\r\n1\r\n$1\r\n2\r\n$3\r\n1.5\r\n",
+		},
+		{
+			name:         "INCR aborted by GT",
+			scoreMembers: []redis.ScoreMember{{Score: -1, Member: "b"}},
+			options:      []func(*redis.ZAddCommand){redis.ZAddIncr(), redis.ZAddGT()},
+			response:     redisNullBulkString,
+			scores:       "*3\r\n$1\r\n1\r\n$1\r\n2\r\n$-1\r\n",
+		},
+		{
+			name:         "INCR to NaN",
+			scoreMembers: []redis.ScoreMember{{Score: math.Inf(-1), Member: "inf"}},
+			options:      []func(*redis.ZAddCommand){redis.ZAddIncr()},
+			response:     "-ERR resulting score is not a number (NaN)\r\n",
+			scores:       "*3\r\n$1\r\n1\r\n$1\r\n2\r\n$-1\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := redis.NewStore()
+			clock := FakeClock{}
+			parser := redis.NewParser(defaultEncodingRedisConfig, store, clock)
+			keysWith(t, parser, []string{"ZADD", "zset", "1", "a", "2", "b", "inf", "inf"})
+
+			response := redis.NewZAddCommand(store, clock, "zset", tt.scoreMembers, tt.options...).Run()
+
+			if response != tt.response {
+				t.Errorf(`command expected to return %#v but was %#v`, tt.response, response)
+			}
+			response = redis.NewZMScoreCommand(store, clock, "zset", []string{"a", "b", "c"}).Run()
+			if response != tt.scores {
+				t.Errorf(`ZMSCORE expected to return %#v but was %#v`, tt.scores, response)
+			}
+		})
+	}
+
+	t.Run("XX doesn't create the key", func(t *testing.T) {
+		store := redis.NewStore()
+		clock := FakeClock{}
+
+		redis.NewZAddCommand(store, clock, "zset", []redis.ScoreMember{{Score: 1, Member: "a"}}, redis.ZAddXX()).Run()
+
+		if _, ok := store.Get("zset"); ok {
+			t.Errorf(`store expected not to contain "zset"`)
+		}
+	})
+}
+
+func TestSortedSetCommands(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{}
+	parser := redis.NewParser(defaultEncodingRedisConfig, store, clock)
+	keysWith(t, parser,
+		[]string{"ZADD", "zset", "1", "one", "2", "two", "2", "deux", "3", "three", "-inf", "min"})
+
+	tests := []struct {
+		name     string
+		command  redis.Command
+		response string
+	}{
+		{name: "ZCARD", command: redis.NewZCardCommand(store, clock, "zset"), response: ":5\r\n"},
+		{name: "ZCARD absent", command: redis.NewZCardCommand(store, clock, "nope"), response: ":0\r\n"},
+		{name: "ZSCORE", command: redis.NewZScoreCommand(store, clock, "zset", []string{"two"}), response: "$1\r\n2\r\n"},
+		{name: "ZSCORE -inf", command: redis.NewZScoreCommand(store, clock, "zset", []string{"min"}), response: "$4\r\n-inf\r\n"},
+		{name: "ZSCORE absent", command: redis.NewZScoreCommand(store, clock, "zset", []string{"nope"}), response: redisNullBulkString},
+		{
+			name:     "ZMSCORE absent key",
+			command:  redis.NewZMScoreCommand(store, clock, "nope", []string{"one"}),
+			response: "*1\r\n$-1\r\n",
+		},
+		{name: "ZRANK", command: redis.NewZRankCommand(store, clock, "zset", "two", false, false), response: ":3\r\n"},
+		{name: "ZRANK ties by member", command: redis.NewZRankCommand(store, clock, "zset", "deux", false, false), response: ":2\r\n"},
+		{name: "ZREVRANK", command: redis.NewZRankCommand(store, clock, "zset", "three", true, false), response: ":0\r\n"},
+		{
+			name:     "ZRANK WITHSCORE",
+			command:  redis.NewZRankCommand(store, clock, "zset", "one", false, true),
+			response: "*2\r\n:1\r\n$1\r\n1\r\n",
+		},
+		{name: "ZRANK absent", command: redis.NewZRankCommand(store, clock, "zset", "nope", false, false), response: redisNullBulkString},
+		{
+			name:     "ZRANK WITHSCORE absent",
+			command:  redis.NewZRankCommand(store, clock, "zset", "nope", false, true),
+			response: "*-1\r\n",
+		},
+		{
+			name: "ZCOUNT",
+			command: redis.NewZCountCommand(store, clock, "zset", redis.ZRangeQuery{
+				By:     redis.ZRangeByScore,
+				Scores: redis.ScoreRange{Min: 1, Max: 3, MinExclusive: true},
+			}),
+			response: ":3\r\n",
+		},
+		{
+			name: "ZCOUNT -inf +inf",
+			command: redis.NewZCountCommand(store, clock, "zset", redis.ZRangeQuery{
+				By:     redis.ZRangeByScore,
+				Scores: redis.ScoreRange{Min: math.Inf(-1), Max: math.Inf(1)},
+			}),
+			response: ":5\r\n",
+		},
+		{
+			name: "ZCOUNT empty",
+			command: redis.NewZCountCommand(store, clock, "zset", redis.ZRangeQuery{
+				By:     redis.ZRangeByScore,
+				Scores: redis.ScoreRange{Min: 2, Max: 2, MaxExclusive: true},
+			}),
+			response: ":0\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if response := tt.command.Run(); response != tt.response {
+				t.Errorf(`command expected to return %#v but was %#v`, tt.response, response)
+			}
+		})
+	}
+}
+
+func TestSortedSetCommands_WrongType(t *testing.T) {
+	t.Parallel()
+
+	const wrongType = "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n"
+	store := redis.NewStore()
+	store.Set("string", "zelda")
+	clock := FakeClock{}
+
+	tests := []struct {
+		name    string
+		command redis.Command
+	}{
+		{name: "ZADD", command: redis.NewZAddCommand(store, clock, "string", []redis.ScoreMember{{Score: 1, Member: "a"}})},
+		{name: "ZRANGE", command: redis.NewZRangeCommand(store, clock, "string", redis.NewZRangeQuery(0, -1), false)},
+		{name: "ZCARD", command: redis.NewZCardCommand(store, clock, "string")},
+		{name: "ZSCORE", command: redis.NewZScoreCommand(store, clock, "string", []string{"a"})},
+		{name: "ZRANK", command: redis.NewZRankCommand(store, clock, "string", "a", false, false)},
+		{name: "ZREM", command: redis.NewZRemCommand(store, clock, "string", []string{"a"})},
+		{name: "ZPOPMIN", command: redis.NewZPopCommand(store, clock, "string", redis.ZSetEndMin, 1)},
+		{name: "ZUNION", command: redis.NewZSetOpCommand(store, clock, redis.SetOpUnion, []string{"nope", "string"})},
+		{name: "ZRANDMEMBER", command: redis.NewZRandMemberCommand(store, clock, "string")},
+		{name: "ZSCAN", command: redis.NewZScanCommand(store, clock, "string", 0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if response := tt.command.Run(); response != wrongType {
+				t.Errorf(`command expected to return %#v but was %#v`, wrongType, response)
+			}
+		})
+	}
+}
+
+func TestZRangeCommand(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{}
+	parser := redis.NewParser(defaultEncodingRedisConfig, store, clock)
+	keysWith(t, parser,
+		[]string{"ZADD", "zset", "1", "a", "2", "b", "3", "c", "4", "d", "5", "e"},
+		[]string{"ZADD", "lex", "0", "a", "0", "b", "0", "c", "0", "d"},
+	)
+	byScore := func(min, max float64, minExclusive, maxExclusive bool) redis.ZRangeQuery {
+		return redis.ZRangeQuery{
+			By:     redis.ZRangeByScore,
+			Scores: redis.ScoreRange{Min: min, Max: max, MinExclusive: minExclusive, MaxExclusive: maxExclusive},
+			Count:  -1,
+		}
+	}
+
+	tests := []struct {
+		name       string
+		key        string
+		query      redis.ZRangeQuery
+		withScores bool
+		want       []string
+	}{
+		{name: "all", key: "zset", query: redis.NewZRangeQuery(0, -1), want: []string{"a", "b", "c", "d", "e"}},
+		{name: "ranks", key: "zset", query: redis.NewZRangeQuery(1, 2), want: []string{"b", "c"}},
+		{name: "negative ranks", key: "zset", query: redis.NewZRangeQuery(-2, -1), want: []string{"d", "e"}},
+		{name: "ranks out of range", key: "zset", query: redis.NewZRangeQuery(-10, 10), want: []string{"a", "b", "c", "d", "e"}},
+		{name: "start after stop", key: "zset", query: redis.NewZRangeQuery(3, 1), want: nil},
+		{name: "start after end", key: "zset", query: redis.NewZRangeQuery(5, 10), want: nil},
+		{
+			name:       "WITHSCORES",
+			key:        "zset",
+			query:      redis.NewZRangeQuery(0, 1),
+			withScores: true,
+			want:       []string{"a", "1", "b", "2"},
+		},
+		{
+			name:  "REV",
+			key:   "zset",
+			query: redis.ZRangeQuery{By: redis.ZRangeByRank, Start: 0, Stop: 1, Reverse: true, Count: -1},
+			want:  []string{"e", "d"},
+		},
+		{name: "BYSCORE", key: "zset", query: byScore(2, 4, false, false), want: []string{"b", "c", "d"}},
+		{name: "BYSCORE exclusive", key: "zset", query: byScore(2, 4, true, true), want: []string{"c"}},
+		{name: "BYSCORE infinite", key: "zset", query: byScore(math.Inf(-1), math.Inf(1), false, false), want: []string{"a", "b", "c", "d", "e"}},
+		{name: "BYSCORE empty", key: "zset", query: byScore(6, 10, false, false), want: nil},
+		{
+			name: "BYSCORE LIMIT",
+			key:  "zset",
+			query: redis.ZRangeQuery{
+				By: redis.ZRangeByScore, Scores: redis.ScoreRange{Min: 2, Max: 5}, Offset: 1, Count: 2,
+			},
+			want: []string{"c", "d"},
+		},
+		{
+			name: "BYSCORE LIMIT negative count",
+			key:  "zset",
+			query: redis.ZRangeQuery{
+				By: redis.ZRangeByScore, Scores: redis.ScoreRange{Min: 2, Max: 5}, Offset: 2, Count: -1,
+			},
+			want: []string{"d", "e"},
+		},
+		{
+			name: "BYSCORE LIMIT negative offset",
+			key:  "zset",
+			query: redis.ZRangeQuery{
+				By: redis.ZRangeByScore, Scores: redis.ScoreRange{Min: 2, Max: 5}, Offset: -1, Count: 2,
+			},
+			want: nil,
+		},
+		{
+			name: "BYSCORE REV LIMIT",
+			key:  "zset",
+			query: redis.ZRangeQuery{
+				By: redis.ZRangeByScore, Scores: redis.ScoreRange{Min: 1, Max: 4}, Reverse: true, Offset: 1, Count: 2,
+			},
+			want: []string{"c", "b"},
+		},
+		{
+			name: "BYLEX",
+			key:  "lex",
+			query: redis.ZRangeQuery{
+				By: redis.ZRangeByLex,
+				Lex: redis.LexRange{
+					Min: redis.LexBound{Value: "b"}, Max: redis.LexBound{Value: "d", Exclusive: true},
+				},
+				Count: -1,
+			},
+			want: []string{"b", "c"},
+		},
+		{
+			name: "BYLEX infinite",
+			key:  "lex",
+			query: redis.ZRangeQuery{
+				By:    redis.ZRangeByLex,
+				Lex:   redis.LexRange{Min: redis.LexBound{Infinity: -1}, Max: redis.LexBound{Infinity: 1}},
+				Count: -1,
+			},
+			want: []string{"a", "b", "c", "d"},
+		},
+		{
+			name: "BYLEX + to -",
+			key:  "lex",
+			query: redis.ZRangeQuery{
+				By:    redis.ZRangeByLex,
+				Lex:   redis.LexRange{Min: redis.LexBound{Infinity: 1}, Max: redis.LexBound{Infinity: -1}},
+				Count: -1,
+			},
+			want: nil,
+		},
+		{
+			name: "BYLEX REV",
+			key:  "lex",
+			query: redis.ZRangeQuery{
+				By:      redis.ZRangeByLex,
+				Lex:     redis.LexRange{Min: redis.LexBound{Value: "b", Exclusive: true}, Max: redis.LexBound{Infinity: 1}},
+				Reverse: true,
+				Count:   -1,
+			},
+			want: []string{"d", "c"},
+		},
+		{name: "absent key", key: "nope", query: redis.NewZRangeQuery(0, -1), want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := redis.NewZRangeCommand(store, clock, tt.key, tt.query, tt.withScores).Run()
+			if got := bulkStrings(t, response); !equalStrings(got, tt.want) {
+				t.Errorf(`command expected to return %#v but was %#v`, tt.want, response)
+			}
+		})
+	}
+}
+
+func TestZRemRangeCommand(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		query    redis.ZRangeQuery
+		response string
+		want     []string
+	}{
+		{name: "by rank", query: redis.NewZRangeQuery(0, 1), response: ":2\r\n", want: []string{"c", "d"}},
+		{
+			name:     "by score",
+			query:    redis.ZRangeQuery{By: redis.ZRangeByScore, Scores: redis.ScoreRange{Min: 2, Max: 3}, Count: -1},
+			response: ":2\r\n",
+			want:     []string{"a", "d"},
+		},
+		{
+			name: "by lex",
+			query: redis.ZRangeQuery{
+				By:    redis.ZRangeByLex,
+				Lex:   redis.LexRange{Min: redis.LexBound{Value: "c"}, Max: redis.LexBound{Infinity: 1}},
+				Count: -1,
+			},
+			response: ":2\r\n",
+			want:     []string{"a", "b"},
+		},
+		{name: "everything", query: redis.NewZRangeQuery(0, -1), response: ":4\r\n", want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := redis.NewStore()
+			clock := FakeClock{}
+			parser := redis.NewParser(defaultEncodingRedisConfig, store, clock)
+			keysWith(t, parser, []string{"ZADD", "zset", "1", "a", "2", "b", "3", "c", "4", "d"})
+
+			if response := redis.NewZRemRangeCommand(store, clock, "zset", tt.query).Run(); response != tt.response {
+				t.Errorf(`command expected to return %#v but was %#v`, tt.response, response)
+			}
+			response := redis.NewZRangeCommand(store, clock, "zset", redis.NewZRangeQuery(0, -1), false).Run()
+			if got := bulkStrings(t, response); !equalStrings(got, tt.want) {
+				t.Errorf(`ZRANGE expected to return %#v but was %#v`, tt.want, response)
+			}
+			if _, ok := store.Get("zset"); ok != (len(tt.want) > 0) {
+				t.Errorf(`store expected to contain "zset" only while it has members`)
+			}
+		})
+	}
+}
+
+func TestZRemCommand(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{}
+	parser := redis.NewParser(defaultEncodingRedisConfig, store, clock)
+	keysWith(t, parser, []string{"ZADD", "zset", "1", "a", "2", "b"})
+
+	if response := redis.NewZRemCommand(store, clock, "zset", []string{"a", "c"}).Run(); response != ":1\r\n" {
+		t.Errorf(`command expected to return ":1\r\n" but was %#v`, response)
+	}
+	if response := redis.NewZRemCommand(store, clock, "zset", []string{"b"}).Run(); response != ":1\r\n" {
+		t.Errorf(`command expected to return ":1\r\n" but was %#v`, response)
+	}
+	if _, ok := store.Get("zset"); ok {
+		t.Errorf(`store expected not to contain "zset" once its last member was removed`)
+	}
+}
+
+// TestSortedSet_Ranks checks ranks against a sorted slice after many random changes, which
+// exercises the skiplist's spans.
+func TestSortedSet_Ranks(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{}
+	random := rand.New(rand.NewSource(1))
+	scores := make(map[string]float64)
+	for i := 0; i < 2000; i++ {
+		member := "member:" + strconv.Itoa(random.Intn(500))
+		if random.Intn(3) == 0 {
+			redis.NewZRemCommand(store, clock, "zset", []string{member}).Run()
+			delete(scores, member)
+			continue
+		}
+		score := float64(random.Intn(100))
+		redis.NewZAddCommand(store, clock, "zset", []redis.ScoreMember{{Score: score, Member: member}}).Run()
+		scores[member] = score
+	}
+
+	var want []string
+	for member := range scores {
+		want = append(want, member)
+	}
+	sort.Slice(want, func(i, j int) bool {
+		if scores[want[i]] != scores[want[j]] {
+			return scores[want[i]] < scores[want[j]]
+		}
+		return want[i] < want[j]
+	})
+
+	response := redis.NewZRangeCommand(store, clock, "zset", redis.NewZRangeQuery(0, -1), false).Run()
+	if got := bulkStrings(t, response); !equalStrings(got, want) {
+		t.Fatalf(`ZRANGE expected to return %#v but was %#v`, want, got)
+	}
+	for rank, member := range want {
+		response := redis.NewZRankCommand(store, clock, "zset", member, false, false).Run()
+		if wantRank := ":" + strconv.Itoa(rank) + "\r\n"; response != wantRank {
+			t.Fatalf(`ZRANK %s expected to return %#v but was %#v`, member, wantRank, response)
+		}
+		response = redis.NewZRangeCommand(store, clock, "zset", redis.NewZRangeQuery(rank, rank), false).Run()
+		if got := bulkStrings(t, response); !equalStrings(got, []string{member}) {
+			t.Fatalf(`ZRANGE %d %d expected to return %#v but was %#v`, rank, rank, member, got)
+		}
+	}
+}
+
+func TestSortedSet_ScoreFormat(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		score float64
+		want  string
+	}{
+		{score: 0, want: "0"},
+		{score: 1.5, want: "1.5"},
+		{score: -2.25, want: "-2.25"},
+		{score: 0.1, want: "0.1"},
+		{score: 1000000, want: "1000000"},
+		{score: 123456789, want: "123456789"},
+		{score: 1e20, want: "1e+20"},
+		{score: 1.5e21, want: "1.5e+21"},
+		{score: 0.000001, want: "0.000001"},
+		{score: 1e-7, want: "1e-7"},
+		{score: 1.25e-10, want: "1.25e-10"},
+		{score: math.Inf(1), want: "inf"},
+		{score: math.Inf(-1), want: "-inf"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			store := redis.NewStore()
+			clock := FakeClock{}
+			redis.NewZAddCommand(store, clock, "zset", []redis.ScoreMember{{Score: tt.score, Member: "a"}}).Run()
+
+			response := redis.NewZScoreCommand(store, clock, "zset", []string{"a"}).Run()
+			if want := "$" + strconv.Itoa(len(tt.want)) + "\r\n" + tt.want + "\r\n"; response != want {
+				t.Errorf(`ZSCORE expected to return %#v but was %#v`, want, response)
+			}
+		})
+	}
+}
+
+func TestZPopCommand(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{}
+	parser := redis.NewParser(defaultEncodingRedisConfig, store, clock)
+	keysWith(t, parser, []string{"ZADD", "zset", "1", "a", "2", "b", "3", "c", "4", "d"})
+
+	tests := []struct {
+		name     string
+		end      redis.ZSetEnd
+		count    int
+		response string
+	}{
+		{name: "ZPOPMIN", end: redis.ZSetEndMin, count: 1, response: "*2\r\n$1\r\na\r\n$1\r\n1\r\n"},
+		{name: "ZPOPMAX 2", end: redis.ZSetEndMax, count: 2, response: "*4\r\n$1\r\nd\r\n$1\r\n4\r\n$1\r\nc\r\n$1\r\n3\r\n"},
+		{name: "ZPOPMIN 0", end: redis.ZSetEndMin, count: 0, response: "*0\r\n"},
+		{name: "ZPOPMIN 5", end: redis.ZSetEndMin, count: 5, response: "*2\r\n$1\r\nb\r\n$1\r\n2\r\n"},
+		{name: "ZPOPMIN absent", end: redis.ZSetEndMin, count: 1, response: "*0\r\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if response := redis.NewZPopCommand(store, clock, "zset", tt.end, tt.count).Run(); response != tt.response {
+				t.Errorf(`command expected to return %#v but was %#v`, tt.response, response)
+			}
+		})
+	}
+	if _, ok := store.Get("zset"); ok {
+		t.Errorf(`store expected not to contain "zset" once its last member was popped`)
+	}
+}
+
+func TestBZPopCommand(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{}
+	parser := redis.NewParser(defaultEncodingRedisConfig, store, clock)
+	clients := redis.NewClients()
+	keysWith(t, parser, []string{"ZADD", "zset", "1", "a", "2", "b"})
+
+	response := redis.NewBZPopCommand(
+		store, clock, clients.NewClient(), []string{"nope", "zset"}, redis.ZSetEndMax, 0,
+	).Run()
+	if want := "*3\r\n$4\r\nzset\r\n$1\r\nb\r\n$1\r\n2\r\n"; response != want {
+		t.Errorf(`command expected to return %#v but was %#v`, want, response)
+	}
+
+	client := clients.NewClient()
+	responses := make(chan string, 1)
+	go func() {
+		responses <- redis.NewBZPopCommand(
+			store, clock, client, []string{"other"}, redis.ZSetEndMin, 0,
+		).Run()
+	}()
+	waitUntilBlocked(t, client)
+
+	keysWith(t, parser, []string{"ZADD", "other", "5", "x", "3", "y"})
+
+	if want := "*3\r\n$5\r\nother\r\n$1\r\ny\r\n$1\r\n3\r\n"; <-responses != want {
+		t.Errorf(`blocked command expected to return %#v`, want)
+	}
+
+	timeout := make(chan time.Time)
+	clock.Timeout = timeout
+	client = clients.NewClient()
+	go func() {
+		responses <- redis.NewBZPopCommand(
+			store, clock, client, []string{"nope"}, redis.ZSetEndMin, time.Second,
+		).Run()
+	}()
+	waitUntilBlocked(t, client)
+	close(timeout)
+
+	if response := <-responses; response != "*-1\r\n" {
+		t.Errorf(`command expected to time out with "*-1\r\n" but returned %#v`, response)
+	}
+}
+
+func TestZSetOpCommand(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{}
+	parser := redis.NewParser(defaultEncodingRedisConfig, store, clock)
+	keysWith(t, parser,
+		[]string{"ZADD", "a", "1", "x", "2", "y", "3", "z"},
+		[]string{"ZADD", "b", "10", "y", "20", "z", "30", "w"},
+		[]string{"SADD", "set", "z", "v"},
+	)
+
+	tests := []struct {
+		name    string
+		op      redis.SetOp
+		keys    []string
+		options []func(*redis.ZSetOpCommand)
+		want    []string
+	}{
+		{
+			name: "ZUNION",
+			op:   redis.SetOpUnion,
+			keys: []string{"a", "b"},
+			want: []string{"x", "1", "y", "12", "z", "23", "w", "30"},
+		},
+		{
+			name:    "ZUNION WEIGHTS AGGREGATE MAX",
+			op:      redis.SetOpUnion,
+			keys:    []string{"a", "b"},
+			options: []func(*redis.ZSetOpCommand){redis.ZSetOpWeights([]float64{10, 1}), redis.ZSetOpAggregate(redis.ZAggregateMax)},
+			want:    []string{"x", "10", "y", "20", "w", "30", "z", "30"},
+		},
+		{
+			name: "ZINTER",
+			op:   redis.SetOpInter,
+			keys: []string{"a", "b"},
+			want: []string{"y", "12", "z", "23"},
+		},
+		{
+			name:    "ZINTER AGGREGATE MIN with a set",
+			op:      redis.SetOpInter,
+			keys:    []string{"b", "set"},
+			options: []func(*redis.ZSetOpCommand){redis.ZSetOpAggregate(redis.ZAggregateMin)},
+			want:    []string{"z", "1"},
+		},
+		{
+			name: "ZINTER absent key",
+			op:   redis.SetOpInter,
+			keys: []string{"a", "nope"},
+			want: nil,
+		},
+		{
+			name: "ZDIFF",
+			op:   redis.SetOpDiff,
+			keys: []string{"a", "b"},
+			want: []string{"x", "1"},
+		},
+		{
+			name:    "ZUNION inf weights",
+			op:      redis.SetOpUnion,
+			keys:    []string{"a"},
+			options: []func(*redis.ZSetOpCommand){redis.ZSetOpWeights([]float64{math.Inf(1)})},
+			want:    []string{"x", "inf", "y", "inf", "z", "inf"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := append([]func(*redis.ZSetOpCommand){redis.ZSetOpWithScores()}, tt.options...)
+			response := redis.NewZSetOpCommand(store, clock, tt.op, tt.keys, options...).Run()
+			if got := bulkStrings(t, response); !equalStrings(got, tt.want) {
+				t.Errorf(`command expected to return %#v but was %#v`, tt.want, response)
+			}
+
+			options = append([]func(*redis.ZSetOpCommand){redis.ZSetOpStore("destination")}, tt.options...)
+			response = redis.NewZSetOpCommand(store, clock, tt.op, tt.keys, options...).Run()
+			if want := ":" + strconv.Itoa(len(tt.want)/2) + "\r\n"; response != want {
+				t.Errorf(`STORE command expected to return %#v but was %#v`, want, response)
+			}
+			response = redis.NewZRangeCommand(store, clock, "destination", redis.NewZRangeQuery(0, -1), true).Run()
+			if got := bulkStrings(t, response); !equalStrings(got, tt.want) {
+				t.Errorf(`ZRANGE expected to return %#v but was %#v`, tt.want, response)
+			}
+		})
+	}
+}
+
+func TestZRandMemberCommand(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{}
+	parser := redis.NewParser(defaultEncodingRedisConfig, store, clock)
+	keysWith(t, parser, []string{"ZADD", "zset", "1", "a", "2", "b", "3", "c"})
+
+	response := redis.NewZRandMemberCommand(store, clock, "zset").Run()
+	if response != "$1\r\na\r\n" && response != "$1\r\nb\r\n" && response != "$1\r\nc\r\n" {
+		t.Errorf(`command expected to return a member but was %#v`, response)
+	}
+
+	response = redis.NewZRandMemberCommand(store, clock, "zset", redis.ZRandMemberCount(5)).Run()
+	if members := bulkStrings(t, response); !equalSorted(members, []string{"a", "b", "c"}) {
+		t.Errorf(`command expected to return every member once but was %#v`, response)
+	}
+
+	response = redis.NewZRandMemberCommand(store, clock, "zset", redis.ZRandMemberCount(-5)).Run()
+	if members := bulkStrings(t, response); len(members) != 5 {
+		t.Errorf(`command expected to return 5 members but was %#v`, response)
+	}
+
+	response = redis.NewZRandMemberCommand(
+		store, clock, "zset", redis.ZRandMemberCount(1), redis.ZRandMemberWithScores(),
+	).Run()
+	if response != "*2\r\n$1\r\na\r\n$1\r\n1\r\n" &&
+		response != "*2\r\n$1\r\nb\r\n$1\r\n2\r\n" &&
+		response != "*2\r\n$1\r\nc\r\n$1\r\n3\r\n" {
+		t.Errorf(`command expected to return a member and its score but was %#v`, response)
+	}
+
+	if response := redis.NewZRandMemberCommand(store, clock, "nope").Run(); response != redisNullBulkString {
+		t.Errorf(`command expected to return %#v but was %#v`, redisNullBulkString, response)
+	}
+}
+
+func TestZScanCommand(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{}
+	parser := redis.NewParser(defaultEncodingRedisConfig, store, clock)
+	var scoreMembers []string
+	var members []string
+	for i := 0; i < 50; i++ {
+		scoreMembers = append(scoreMembers, strconv.Itoa(i), "member:"+strconv.Itoa(i))
+		members = append(members, "member:"+strconv.Itoa(i))
+	}
+	keysWith(t, parser, append([]string{"ZADD", "zset"}, scoreMembers...))
+
+	var seen []string
+	var cursor uint64
+	for calls := 0; ; calls++ {
+		if calls > 50 {
+			t.Fatalf("ZSCAN expected to finish")
+		}
+		elements := bulkStrings(t, redis.NewZScanCommand(store, clock, "zset", cursor, redis.ZScanCount(7)).Run())
+		for i := 1; i < len(elements); i += 2 {
+			if want := "member:" + elements[i+1]; elements[i] != want {
+				t.Errorf("ZSCAN expected to return %#v with its score but returned %#v", want, elements[i])
+			}
+			seen = append(seen, elements[i])
+		}
+		if elements[0] == "0" {
+			break
+		}
+		var err error
+		cursor, err = strconv.ParseUint(elements[0], 10, 64)
+		if err != nil {
+			t.Fatalf("ZSCAN returned invalid cursor %#v", elements[0])
+		}
+	}
+	if !equalSorted(seen, members) {
+		t.Errorf("ZSCAN expected to return every member once but returned %#v", seen)
+	}
+
+	response := redis.NewZScanCommand(store, clock, "zset", 0, redis.ZScanMatch("member:4?"), redis.ZScanCount(100)).Run()
+	if got := bulkStrings(t, response); len(got) != 21 {
+		t.Errorf(`ZSCAN MATCH expected to return 10 members with their scores but was %#v`, response)
+	}
+}
@@ -1,9 +1,86 @@
 package redis
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
 
 type Config struct {
 	Replication ReplicationConfig
+	Encoding    EncodingConfig
+	// PubSubOutputBufferLimit is client-output-buffer-limit pubsub, which limits the messages
+	// waiting to be written to a subscribed client.
+	PubSubOutputBufferLimit OutputBufferLimit
+	// NotifyKeyspaceEvents is notify-keyspace-events, the classes of keyspace events that are
+	// published.
+	NotifyKeyspaceEvents KeyspaceEvents
+	// BusyReplyThreshold is busy-reply-threshold, how long a script can run before other
+	// clients' commands are rejected with a BUSY error rather than waiting for it. Zero
+	// disables it.
+	BusyReplyThreshold time.Duration
+}
+
+// DefaultBusyReplyThreshold is the BusyReplyThreshold that Redis uses by default.
+const DefaultBusyReplyThreshold = 5 * time.Second
+
+// OutputBufferLimit is a limit on the output waiting to be written to a client, past which the
+// client is disconnected: when it reaches HardBytes, or stays at or above SoftBytes for longer
+// than SoftDuration. A limit of 0 bytes is disabled.
+type OutputBufferLimit struct {
+	HardBytes    int
+	SoftBytes    int
+	SoftDuration time.Duration
+}
+
+// DefaultPubSubOutputBufferLimit returns the OutputBufferLimit that Redis uses for subscribed
+// clients by default.
+func DefaultPubSubOutputBufferLimit() OutputBufferLimit {
+	return OutputBufferLimit{
+		HardBytes:    32 * 1024 * 1024,
+		SoftBytes:    8 * 1024 * 1024,
+		SoftDuration: 60 * time.Second,
+	}
+}
+
+// exceeded returns true if output of size bytes exceeds the limit at time now. overSoftSince is
+// the time that the output reached the soft limit, which exceeded keeps track of.
+func (l OutputBufferLimit) exceeded(size int, overSoftSince *time.Time, now time.Time) bool {
+	if l.HardBytes > 0 && size >= l.HardBytes {
+		return true
+	}
+	if l.SoftBytes == 0 || size < l.SoftBytes {
+		*overSoftSince = time.Time{}
+		return false
+	}
+	if overSoftSince.IsZero() {
+		*overSoftSince = now
+	}
+	return now.Sub(*overSoftSince) > l.SoftDuration
+}
+
+// EncodingConfig holds the limits up to which values are stored in compact encodings, after
+// Redis's config parameters of the same names.
+type EncodingConfig struct {
+	// HashMaxListpackEntries is the most fields a hash can have and stay a listpack.
+	HashMaxListpackEntries int
+	// HashMaxListpackValue is the longest field or value, in bytes, that a hash can have and stay
+	// a listpack.
+	HashMaxListpackValue int
+	// SetMaxIntsetEntries is the most members a set of integers can have and stay an intset.
+	SetMaxIntsetEntries int
+	// HllSparseMaxBytes is the largest, in bytes including the header, that a HyperLogLog can be
+	// and stay in the sparse representation.
+	HllSparseMaxBytes int
+}
+
+// DefaultEncodingConfig returns the EncodingConfig that Redis uses by default.
+func DefaultEncodingConfig() EncodingConfig {
+	return EncodingConfig{
+		HashMaxListpackEntries: 128,
+		HashMaxListpackValue:   64,
+		SetMaxIntsetEntries:    512,
+		HllSparseMaxBytes:      3000,
+	}
 }
 
 type ReplicationConfig struct {
@@ -10,6 +10,10 @@ type FakeClock struct {
 	// Note: When CurrentTime is initialized with anything other than time.Now, it will never be
 	// monotonic, but for the purpose of testing that's probably fine.
 	CurrentTime time.Time
+
+	// Timeout is the channel that After returns for any duration, so that tests can decide when
+	// timeouts happen by sending on or closing it. If it's nil, timeouts never happen.
+	Timeout chan time.Time
 }
 
 // NowMonotonic returns CurrentTime.
@@ -19,3 +23,13 @@ type FakeClock struct {
 func (c FakeClock) NowMonotonic() time.Time {
 	return c.CurrentTime
 }
+
+// Now returns CurrentTime.
+func (c FakeClock) Now() time.Time {
+	return c.CurrentTime
+}
+
+// After returns Timeout, ignoring d.
+func (c FakeClock) After(time.Duration) <-chan time.Time {
+	return c.Timeout
+}
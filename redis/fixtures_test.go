@@ -1,6 +1,13 @@
 package redis_test
 
-import "github.com/codecrafters-io/redis-starter-go/redis"
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/codecrafters-io/redis-starter-go/redis"
+)
 
 var (
 	masterRedisConfig = &redis.Config{
@@ -22,5 +29,81 @@ var (
 	slaveRedisConfig = &redis.Config{
 		Replication: redis.ReplicationConfig{},
 	}
-	zeroValueRedisConfig = &redis.Config{}
+	zeroValueRedisConfig       = &redis.Config{}
+	defaultEncodingRedisConfig = &redis.Config{Encoding: redis.DefaultEncodingConfig()}
 )
+
+// keysWith sets up the keys of a test by running requests with parser, failing the test if any of
+// them replies with an error.
+func keysWith(t *testing.T, parser redis.Parser, requests ...[]string) {
+	t.Helper()
+
+	for _, request := range requests {
+		resp := fmt.Sprintf("*%d\r\n", len(request))
+		for _, arg := range request {
+			resp += fmt.Sprintf("$%d\r\n%s\r\n", len(arg), arg)
+		}
+		command, err := parser.Parse(strings.NewReader(resp))
+		if err != nil {
+			t.Fatalf("%s expected to parse but was %v", request[0], err)
+		}
+		if response := command.Run(); strings.HasPrefix(response, "-") {
+			t.Fatalf("%s expected to succeed but was %#v", request[0], response)
+		}
+	}
+}
+
+// bulkString returns s as a RESP bulk string.
+func bulkString(s string) string {
+	return fmt.Sprintf("$%d\r\n%s\r\n", len(s), s)
+}
+
+// bulkStringArray returns elements as a RESP array of bulk strings.
+func bulkStringArray(elements ...string) string {
+	result := fmt.Sprintf("*%d\r\n", len(elements))
+	for _, element := range elements {
+		result += bulkString(element)
+	}
+	return result
+}
+
+// bulkStrings returns the bulk strings in the RESP array response, failing the test if it isn't
+// an array.
+func bulkStrings(t *testing.T, response string) []string {
+	t.Helper()
+
+	if !strings.HasPrefix(response, "*") {
+		t.Fatalf("expected an array but was %#v", response)
+	}
+	lines := strings.Split(strings.TrimSuffix(response, "\r\n"), "\r\n")
+	var result []string
+	for i := 1; i < len(lines); i++ {
+		if strings.HasPrefix(lines[i], "$") && i+1 < len(lines) {
+			result = append(result, lines[i+1])
+			i++
+		}
+	}
+	return result
+}
+
+// equalStrings reports whether a and b hold the same strings in the same order.
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// equalSorted reports whether a and b hold the same strings in any order.
+func equalSorted(a, b []string) bool {
+	a = append([]string(nil), a...)
+	b = append([]string(nil), b...)
+	sort.Strings(a)
+	sort.Strings(b)
+	return strings.Join(a, "\x00") == strings.Join(b, "\x00")
+}
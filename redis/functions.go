@@ -0,0 +1,442 @@
+package redis
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc64"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/lua"
+)
+
+// functionLoadTimeout is how long the code of a library can run for when it's loaded.
+const functionLoadTimeout = 500 * time.Millisecond
+
+// luaFunctionFlags are the flags that functions can be registered with, in the order that
+// FUNCTION LIST gives them. Only no-writes changes anything here, since there's no cluster or
+// replication to be stale, and no memory limit.
+var luaFunctionFlags = []string{
+	"no-writes", "allow-oom", "allow-stale", "no-cluster", "allow-cross-slot-keys",
+}
+
+// luaLibrary is a library of functions that FUNCTION LOAD loaded. Like Redis, its code stays
+// loaded in a Lua state of its own, so that its functions share its local variables.
+type luaLibrary struct {
+	name string
+	code string
+	L    *lua.LState
+	// redis is the library's redis table, whose functions are bound to the client that calls
+	// one of the library's functions each time.
+	redis     *lua.LTable
+	functions map[string]*luaFunction
+}
+
+// luaFunction is a function that a library registered with redis.register_function.
+type luaFunction struct {
+	name        string
+	description string
+	flags       map[string]bool
+	callback    *lua.LFunction
+	library     *luaLibrary
+}
+
+// FunctionRestorePolicy is what FUNCTION RESTORE, or FUNCTION LOAD, does with the libraries
+// that are already loaded.
+type FunctionRestorePolicy int
+
+const (
+	// FunctionRestoreAppend fails if a library is already loaded with the same name.
+	FunctionRestoreAppend FunctionRestorePolicy = iota
+	// FunctionRestoreReplace replaces any libraries with the same names.
+	FunctionRestoreReplace
+	// FunctionRestoreFlush deletes every library first.
+	FunctionRestoreFlush
+)
+
+// validFunctionName returns true if name can name a library or a function: it's made of letters,
+// numbers and underscores.
+func validFunctionName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// parseLibraryMetadata parses the first line of the code of a library, like "#!lua name=mylib",
+// returning the name of the library.
+func parseLibraryMetadata(line string) (string, error) {
+	if !strings.HasPrefix(line, "#!") {
+		return "", CommandError("ERR Missing library metadata")
+	}
+	parts := strings.Fields(line[2:])
+	if len(parts) == 0 || !strings.EqualFold(parts[0], "lua") {
+		engine := ""
+		if len(parts) > 0 {
+			engine = parts[0]
+		}
+		return "", CommandError(fmt.Sprintf("ERR Engine '%s' not found", engine))
+	}
+	name := ""
+	for _, part := range parts[1:] {
+		if !strings.HasPrefix(part, "name=") {
+			return "", CommandError("ERR Invalid metadata value given: " + part)
+		}
+		name = part[len("name="):]
+	}
+	switch {
+	case name == "":
+		return "", CommandError("ERR Library name was not given")
+	case !validFunctionName(name):
+		return "", CommandError("ERR Library names can only contain letters, numbers, or " +
+			"underscores(_) and must be at least one character long")
+	}
+	return name, nil
+}
+
+// loadLibrary compiles code and runs it, returning the library of the functions that it
+// registers, unless it runs for longer than functionLoadTimeout on clock.
+func loadLibrary(code string, clock Clock) (*luaLibrary, error) {
+	line, _, _ := strings.Cut(code, "\n")
+	name, err := parseLibraryMetadata(line)
+	if err != nil {
+		return nil, err
+	}
+	// The metadata is left out, but not its line, so that errors give the right lines.
+	proto, err := compileLua(code[len(line):], luaFunctionChunk)
+	if err != nil {
+		return nil, CommandError("ERR Error compiling function: " + err.Error())
+	}
+
+	L := newLuaState()
+	library := &luaLibrary{
+		name:      name,
+		code:      code,
+		L:         L,
+		redis:     L.GetGlobal("redis").(*lua.LTable),
+		functions: make(map[string]*luaFunction),
+	}
+	// While it's loaded, a library can only register functions, not call commands.
+	openLuaRedisLibrary(L, library.redis)
+	library.redis.RawSetString("register_function", L.NewFunction(library.registerFunction))
+	protectLuaGlobals(L)
+	start := clock.NowMonotonic()
+	L.SetContext(luaContext{
+		Context: context.Background(),
+		done: func() <-chan struct{} {
+			if clock.NowMonotonic().Sub(start) > functionLoadTimeout {
+				return closedChannel
+			}
+			return nil
+		},
+		err: errors.New("FUNCTION LOAD timeout"),
+	})
+	defer L.RemoveContext()
+
+	L.Push(L.NewFunctionFromProto(proto))
+	if err := L.PCall(0, 0, nil); err != nil {
+		message := err.Error()
+		if apiErr, ok := err.(*lua.ApiError); ok {
+			message = apiErr.Object.String()
+			if reply, ok := apiErr.Object.(*lua.LTable); ok {
+				message = reply.RawGetString("err").String()
+			}
+		}
+		return nil, CommandError("ERR Error registering functions: " + luaSanitizeError(message))
+	}
+	if len(library.functions) == 0 {
+		return nil, CommandError("ERR No functions registered")
+	}
+	return library, nil
+}
+
+// registerFunction implements redis.register_function, which is called either with the name of
+// the function and its callback, or with a table of named arguments: function_name, callback,
+// and optionally description and flags.
+func (l *luaLibrary) registerFunction(L *lua.LState) int {
+	function := &luaFunction{flags: make(map[string]bool), library: l}
+	var flags *lua.LTable
+	switch L.GetTop() {
+	case 1:
+		named, ok := L.Get(1).(*lua.LTable)
+		if !ok {
+			L.RaiseError("calling redis.register_function with a single argument is only " +
+				"applicable to Lua table (representing named arguments).")
+		}
+		named.ForEach(func(key, value lua.LValue) {
+			var ok bool
+			switch key.String() {
+			case "function_name":
+				var name lua.LString
+				name, ok = value.(lua.LString)
+				function.name = string(name)
+			case "callback":
+				function.callback, ok = value.(*lua.LFunction)
+			case "description":
+				var description lua.LString
+				description, ok = value.(lua.LString)
+				function.description = string(description)
+			case "flags":
+				flags, ok = value.(*lua.LTable)
+			default:
+				L.RaiseError("unknown argument given to redis.register_function")
+			}
+			if !ok {
+				L.RaiseError("%s argument given to redis.register_function has the wrong type",
+					key.String())
+			}
+		})
+	case 2:
+		name, ok := L.Get(1).(lua.LString)
+		if !ok {
+			L.RaiseError("first argument to redis.register_function must be a string")
+		}
+		function.name = string(name)
+		if function.callback, ok = L.Get(2).(*lua.LFunction); !ok {
+			L.RaiseError("second argument to redis.register_function must be a function")
+		}
+	default:
+		L.RaiseError("wrong number of arguments to redis.register_function")
+	}
+
+	switch {
+	case function.name == "":
+		L.RaiseError("redis.register_function must get a function name argument")
+	case function.callback == nil:
+		L.RaiseError("redis.register_function must get a callback argument")
+	case !validFunctionName(function.name):
+		L.RaiseError("Function names can only contain letters, numbers, or underscores(_) and " +
+			"must be at least one character long")
+	case l.functions[function.name] != nil:
+		L.RaiseError("Function already exists in the library")
+	}
+	if flags != nil {
+		flags.ForEach(func(_, flag lua.LValue) {
+			name, ok := flag.(lua.LString)
+			if !ok || !luaKnownFunctionFlag(string(name)) {
+				L.RaiseError("unknown flag given")
+			}
+			function.flags[string(name)] = true
+		})
+	}
+	l.functions[function.name] = function
+	return 0
+}
+
+func luaKnownFunctionFlag(name string) bool {
+	for _, flag := range luaFunctionFlags {
+		if flag == name {
+			return true
+		}
+	}
+	return false
+}
+
+// addLibraries adds libraries, following policy for the libraries that are already loaded. If a
+// library or function of the same name is already loaded, and not replaced, it adds none of
+// them.
+func (s *Scripts) addLibraries(libraries []*luaLibrary, policy FunctionRestorePolicy) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kept := s.libraries
+	if policy == FunctionRestoreFlush {
+		kept = nil
+	}
+	replaced := make(map[string]bool)
+	for _, library := range libraries {
+		if _, ok := kept[library.name]; ok {
+			if policy != FunctionRestoreReplace {
+				return CommandError(fmt.Sprintf("ERR Library '%s' already exists", library.name))
+			}
+			replaced[library.name] = true
+		}
+	}
+	result := make(map[string]*luaLibrary)
+	functions := make(map[string]*luaFunction)
+	for name, library := range kept {
+		if !replaced[name] {
+			result[name] = library
+			for functionName, function := range library.functions {
+				functions[functionName] = function
+			}
+		}
+	}
+	for _, library := range libraries {
+		result[library.name] = library
+		for name, function := range library.functions {
+			if _, ok := functions[name]; ok {
+				return CommandError(fmt.Sprintf("ERR Function %s already exists", name))
+			}
+			functions[name] = function
+		}
+	}
+	s.libraries = result
+	s.functions = functions
+	return nil
+}
+
+// function returns the function called name.
+func (s *Scripts) function(name string) (*luaFunction, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	function, ok := s.functions[name]
+	return function, ok
+}
+
+// deleteLibrary deletes the library called name and its functions, returning false if there's no
+// such library.
+func (s *Scripts) deleteLibrary(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	library, ok := s.libraries[name]
+	if !ok {
+		return false
+	}
+	delete(s.libraries, name)
+	for functionName := range library.functions {
+		delete(s.functions, functionName)
+	}
+	return true
+}
+
+// flushLibraries deletes every library.
+func (s *Scripts) flushLibraries() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.libraries = make(map[string]*luaLibrary)
+	s.functions = make(map[string]*luaFunction)
+}
+
+// sortedLibraries returns the libraries in order of their names.
+func (s *Scripts) sortedLibraries() []*luaLibrary {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	libraries := make([]*luaLibrary, 0, len(s.libraries))
+	for _, library := range s.libraries {
+		libraries = append(libraries, library)
+	}
+	sort.Slice(libraries, func(i, j int) bool { return libraries[i].name < libraries[j].name })
+	return libraries
+}
+
+// runFunction runs function with keys and args on behalf of the client that p parses requests
+// for, returning its reply. p's Store must already be locked exclusively.
+func (s *Scripts) runFunction(p Parser, function *luaFunction, keys, args []string) string {
+	library := function.library
+	L := library.L
+	state := newRunningScript(p.clock.NowMonotonic(), true, function.flags["no-writes"])
+	return s.run(p, L, library.redis, state, function.name, function.callback,
+		luaStringArray(L, keys), luaStringArray(L, args))
+}
+
+// The parts of the payloads of FUNCTION DUMP, which are in Redis's format: each library's code
+// in RDB's encoding, followed by the RDB version and a CRC64 checksum.
+const (
+	rdbOpcodeFunction = 245
+	rdbVersion        = 11
+)
+
+var rdbCRC64Table = crc64.MakeTable(0x95ac9329ac4bc9b5)
+
+// rdbChecksum returns the CRC64 checksum of data that RDB uses, with the Jones polynomial.
+func rdbChecksum(data []byte) uint64 {
+	return ^crc64.Update(^uint64(0), rdbCRC64Table, data)
+}
+
+// dumpLibraries returns the payload of FUNCTION DUMP for libraries.
+func dumpLibraries(libraries []*luaLibrary) string {
+	var payload []byte
+	for _, library := range libraries {
+		payload = append(payload, rdbOpcodeFunction)
+		payload = appendRDBLength(payload, len(library.code))
+		payload = append(payload, library.code...)
+	}
+	payload = binary.LittleEndian.AppendUint16(payload, rdbVersion)
+	payload = binary.LittleEndian.AppendUint64(payload, rdbChecksum(payload))
+	return string(payload)
+}
+
+// appendRDBLength appends n to b in RDB's length encoding.
+func appendRDBLength(b []byte, n int) []byte {
+	switch {
+	case n < 1<<6:
+		return append(b, byte(n))
+	case n < 1<<14:
+		return append(b, byte(n>>8)|0x40, byte(n))
+	case n <= 1<<32-1:
+		return binary.BigEndian.AppendUint32(append(b, 0x80), uint32(n))
+	}
+	return binary.BigEndian.AppendUint64(append(b, 0x81), uint64(n))
+}
+
+// restoreLibraries returns the codes of the libraries in payload, dumped by FUNCTION DUMP.
+// Compressed strings aren't supported, since FUNCTION DUMP never compresses them.
+func restoreLibraries(payload string) ([]string, error) {
+	errPayload := CommandError("ERR payload version or checksum are wrong")
+	if len(payload) < 10 {
+		return nil, errPayload
+	}
+	footer := len(payload) - 10
+	version := binary.LittleEndian.Uint16([]byte(payload[footer:]))
+	checksum := binary.LittleEndian.Uint64([]byte(payload[footer+2:]))
+	if version > rdbVersion || checksum != rdbChecksum([]byte(payload[:len(payload)-8])) {
+		return nil, errPayload
+	}
+
+	var codes []string
+	data := payload[:footer]
+	for len(data) > 0 {
+		if data[0] != rdbOpcodeFunction {
+			return nil, CommandError("ERR given type is not a function")
+		}
+		n, rest, ok := readRDBLength(data[1:])
+		if !ok || n > len(rest) {
+			return nil, CommandError("ERR failed loading the given functions payload")
+		}
+		codes = append(codes, rest[:n])
+		data = rest[n:]
+	}
+	return codes, nil
+}
+
+// readRDBLength reads a length in RDB's length encoding from the start of data, returning the
+// data after it, and false if data doesn't start with one.
+func readRDBLength(data string) (int, string, bool) {
+	if len(data) == 0 {
+		return 0, "", false
+	}
+	switch data[0] >> 6 {
+	case 0:
+		return int(data[0] & 0x3f), data[1:], true
+	case 1:
+		if len(data) < 2 {
+			return 0, "", false
+		}
+		return int(data[0]&0x3f)<<8 | int(data[1]), data[2:], true
+	}
+	switch {
+	case data[0] == 0x80 && len(data) >= 5:
+		return int(binary.BigEndian.Uint32([]byte(data[1:5]))), data[5:], true
+	case data[0] == 0x81 && len(data) >= 9:
+		n := binary.BigEndian.Uint64([]byte(data[1:9]))
+		if n > uint64(len(data)) {
+			return 0, "", false
+		}
+		return int(n), data[9:], true
+	}
+	return 0, "", false
+}
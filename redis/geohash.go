@@ -0,0 +1,304 @@
+package redis
+
+import (
+	"math"
+)
+
+// Positions are indexed as sorted set members whose scores are 52-bit geohashes, like Redis:
+// 26 bits each of longitude and latitude interleaved, so that members close together in the
+// sorted set tend to be close together on the map.
+const (
+	geoStepMax = 26
+	geoLonMin  = -180.0
+	geoLonMax  = 180.0
+	// geoLatMin and geoLatMax are the limits of EPSG:900913, the Web Mercator projection.
+	geoLatMin = -85.05112878
+	geoLatMax = 85.05112878
+
+	earthRadiusMeters = 6372797.560856
+	mercatorMax       = 20037726.37
+)
+
+// geoHash is a geohash with step bits each of longitude, in its odd bits, and latitude, in its
+// even bits.
+type geoHash struct {
+	bits uint64
+	step uint
+}
+
+// geoRange is the range covered by a geohash in one dimension.
+type geoRange struct {
+	min, max float64
+}
+
+// geoArea is the area covered by a geohash.
+type geoArea struct {
+	longitude, latitude geoRange
+}
+
+// validGeoPosition returns whether a position can be indexed.
+func validGeoPosition(longitude, latitude float64) bool {
+	return longitude >= geoLonMin && longitude <= geoLonMax &&
+		latitude >= geoLatMin && latitude <= geoLatMax
+}
+
+// geoEncode returns the geohash of a position with step bits of each coordinate, within the
+// given range of latitudes.
+func geoEncode(longitude, latitude float64, step uint, latRange geoRange) geoHash {
+	latOffset := (latitude - latRange.min) / (latRange.max - latRange.min)
+	lonOffset := (longitude - geoLonMin) / (geoLonMax - geoLonMin)
+	latOffset *= float64(uint64(1) << step)
+	lonOffset *= float64(uint64(1) << step)
+	bits := spreadBits(uint32(latOffset)) | spreadBits(uint32(lonOffset))<<1
+	return geoHash{bits: bits, step: step}
+}
+
+// geoEncodeScore returns the sorted set score of a valid position.
+func geoEncodeScore(longitude, latitude float64) float64 {
+	return float64(geoEncode(longitude, latitude, geoStepMax, geoRange{geoLatMin, geoLatMax}).bits)
+}
+
+// decode returns the area that h covers.
+func (h geoHash) decode() geoArea {
+	lat, lon := squashBits(h.bits), squashBits(h.bits>>1)
+	cells := float64(uint64(1) << h.step)
+	return geoArea{
+		longitude: geoRange{
+			min: geoLonMin + float64(lon)/cells*(geoLonMax-geoLonMin),
+			max: geoLonMin + float64(lon+1)/cells*(geoLonMax-geoLonMin),
+		},
+		latitude: geoRange{
+			min: geoLatMin + float64(lat)/cells*(geoLatMax-geoLatMin),
+			max: geoLatMin + float64(lat+1)/cells*(geoLatMax-geoLatMin),
+		},
+	}
+}
+
+// geoDecodeScore returns the position at the centre of the area a sorted set score covers.
+func geoDecodeScore(score float64) (longitude, latitude float64) {
+	area := geoHash{bits: uint64(score), step: geoStepMax}.decode()
+	longitude = (area.longitude.min + area.longitude.max) / 2
+	latitude = (area.latitude.min + area.latitude.max) / 2
+	longitude = math.Max(geoLonMin, math.Min(geoLonMax, longitude))
+	latitude = math.Max(geoLatMin, math.Min(geoLatMax, latitude))
+	return longitude, latitude
+}
+
+// spreadBits spreads the bits of v out into the even bits of the result.
+func spreadBits(v uint32) uint64 {
+	x := uint64(v)
+	x = (x | x<<16) & 0x0000ffff0000ffff
+	x = (x | x<<8) & 0x00ff00ff00ff00ff
+	x = (x | x<<4) & 0x0f0f0f0f0f0f0f0f
+	x = (x | x<<2) & 0x3333333333333333
+	x = (x | x<<1) & 0x5555555555555555
+	return x
+}
+
+// squashBits is the inverse of spreadBits, ignoring the odd bits of x.
+func squashBits(x uint64) uint32 {
+	x &= 0x5555555555555555
+	x = (x | x>>1) & 0x3333333333333333
+	x = (x | x>>2) & 0x0f0f0f0f0f0f0f0f
+	x = (x | x>>4) & 0x00ff00ff00ff00ff
+	x = (x | x>>8) & 0x0000ffff0000ffff
+	x = (x | x>>16) & 0x00000000ffffffff
+	return uint32(x)
+}
+
+// moveX returns the neighbouring geohash to the east if d is positive, or to the west if not.
+func (h geoHash) moveX(d int) geoHash {
+	x := h.bits & 0xaaaaaaaaaaaaaaaa
+	y := h.bits & 0x5555555555555555
+	zz := uint64(0x5555555555555555) >> (64 - h.step*2)
+	if d > 0 {
+		x += zz + 1
+	} else {
+		x = (x | zz) - (zz + 1)
+	}
+	x &= 0xaaaaaaaaaaaaaaaa >> (64 - h.step*2)
+	return geoHash{bits: x | y, step: h.step}
+}
+
+// moveY returns the neighbouring geohash to the north if d is positive, or to the south if not.
+func (h geoHash) moveY(d int) geoHash {
+	x := h.bits & 0xaaaaaaaaaaaaaaaa
+	y := h.bits & 0x5555555555555555
+	zz := uint64(0xaaaaaaaaaaaaaaaa) >> (64 - h.step*2)
+	if d > 0 {
+		y += zz + 1
+	} else {
+		y = (y | zz) - (zz + 1)
+	}
+	y &= 0x5555555555555555 >> (64 - h.step*2)
+	return geoHash{bits: x | y, step: h.step}
+}
+
+// scoreRange returns the range of sorted set scores, from min inclusive to max exclusive, of
+// positions within the area h covers.
+func (h geoHash) scoreRange() (min, max float64) {
+	shift := 2 * (geoStepMax - h.step)
+	return float64(h.bits << shift), float64((h.bits + 1) << shift)
+}
+
+func degreesToRadians(degrees float64) float64 {
+	return degrees * math.Pi / 180
+}
+
+func radiansToDegrees(radians float64) float64 {
+	return radians * 180 / math.Pi
+}
+
+// geoDistance returns the distance in meters between two positions, using the haversine
+// formula.
+func geoDistance(lon1, lat1, lon2, lat2 float64) float64 {
+	v := math.Sin((degreesToRadians(lon2) - degreesToRadians(lon1)) / 2)
+	if v == 0 {
+		return geoLatDistance(lat1, lat2)
+	}
+	lat1r, lat2r := degreesToRadians(lat1), degreesToRadians(lat2)
+	u := math.Sin((lat2r - lat1r) / 2)
+	a := u*u + math.Cos(lat1r)*math.Cos(lat2r)*v*v
+	return 2 * earthRadiusMeters * math.Asin(math.Sqrt(a))
+}
+
+// geoLatDistance returns the distance in meters between two latitudes on the same meridian.
+func geoLatDistance(lat1, lat2 float64) float64 {
+	return earthRadiusMeters * math.Abs(degreesToRadians(lat2)-degreesToRadians(lat1))
+}
+
+// geoEstimateStep returns the geohash step whose areas are about big enough that a search of
+// radius meters around latitude only needs to look at one area and its neighbours.
+func geoEstimateStep(radius, latitude float64) uint {
+	if radius == 0 {
+		return geoStepMax
+	}
+	step := 1
+	for ; radius < mercatorMax; radius *= 2 {
+		step++
+	}
+	// Make sure the radius is covered in most cases.
+	step -= 2
+	// Meridians are closer together towards the poles.
+	if latitude > 66 || latitude < -66 {
+		step--
+		if latitude > 80 || latitude < -80 {
+			step--
+		}
+	}
+	if step < 1 {
+		step = 1
+	}
+	if step > geoStepMax {
+		step = geoStepMax
+	}
+	return uint(step)
+}
+
+// geoSearchAreas returns the geohashes of the areas that cover a search, in the order that
+// Redis searches them: the area containing the centre of the search followed by its neighbours
+// to the north, south, east, west, north east, north west, south east and south west, leaving
+// out any that can't contain results.
+func geoSearchAreas(shape GeoShape, longitude, latitude float64) []geoHash {
+	// Find the bounding box of the search.
+	height, width := shape.Radius, shape.Radius
+	if shape.Box {
+		height, width = shape.Height/2, shape.Width/2
+	}
+	height, width = height*shape.Unit, width*shape.Unit
+	latDelta := radiansToDegrees(height / earthRadiusMeters)
+	// Meridians converge towards the poles, so the box spans the most longitude on its poleward
+	// edge.
+	poleward := latitude + latDelta
+	if latitude < 0 {
+		poleward = latitude - latDelta
+	}
+	lonDelta := radiansToDegrees(width / earthRadiusMeters / math.Cos(degreesToRadians(poleward)))
+	minLon, maxLon := longitude-lonDelta, longitude+lonDelta
+	minLat, maxLat := latitude-latDelta, latitude+latDelta
+
+	radius := shape.Radius
+	if shape.Box {
+		radius = math.Sqrt(shape.Width/2*shape.Width/2 + shape.Height/2*shape.Height/2)
+	}
+	step := geoEstimateStep(radius*shape.Unit, latitude)
+
+	latRange := geoRange{geoLatMin, geoLatMax}
+	hash := geoEncode(longitude, latitude, step, latRange)
+	north, south, east, west := hash.moveY(1), hash.moveY(-1), hash.moveX(1), hash.moveX(-1)
+	// The estimated step can be too big when the search is near the edge of its area, in which
+	// case the neighbours don't cover all of it.
+	if step > 1 && (north.decode().latitude.max < maxLat || south.decode().latitude.min > minLat ||
+		east.decode().longitude.max < maxLon || west.decode().longitude.min > minLon) {
+		step--
+		hash = geoEncode(longitude, latitude, step, latRange)
+		north, south, east, west = hash.moveY(1), hash.moveY(-1), hash.moveX(1), hash.moveX(-1)
+	}
+	areas := []geoHash{
+		hash,
+		north,
+		south,
+		east,
+		west,
+		north.moveX(1),
+		north.moveX(-1),
+		south.moveX(1),
+		south.moveX(-1),
+	}
+
+	// Leave out neighbours on the sides where the search doesn't extend past the centre area.
+	const n, s, e, w, ne, nw, se, sw = 1, 2, 3, 4, 5, 6, 7, 8
+	var excluded [9]bool
+	if step >= 2 {
+		area := hash.decode()
+		if area.latitude.min < minLat {
+			excluded[s], excluded[sw], excluded[se] = true, true, true
+		}
+		if area.latitude.max > maxLat {
+			excluded[n], excluded[ne], excluded[nw] = true, true, true
+		}
+		if area.longitude.min < minLon {
+			excluded[w], excluded[sw], excluded[nw] = true, true, true
+		}
+		if area.longitude.max > maxLon {
+			excluded[e], excluded[se], excluded[ne] = true, true, true
+		}
+	}
+
+	var result []geoHash
+	for i, area := range areas {
+		// Neighbours can be the same area as the one before for huge searches.
+		if excluded[i] || (len(result) > 0 && area == result[len(result)-1]) {
+			continue
+		}
+		result = append(result, area)
+	}
+	return result
+}
+
+// GeoShape is the shape of a GEOSEARCH: a circle of Radius with BYRADIUS, or a rectangle of
+// Width by Height with BYBOX, in units of Unit meters.
+type GeoShape struct {
+	Box                   bool
+	Radius, Width, Height float64
+	Unit                  float64
+}
+
+// contains returns whether the position at (lon, lat) is within the shape centred on
+// (centreLon, centreLat), and its distance in meters from the centre if so.
+func (s GeoShape) contains(centreLon, centreLat, lon, lat float64) (float64, bool) {
+	if s.Box {
+		// The latitude distance is cheaper to check.
+		if geoLatDistance(lat, centreLat) > s.Height*s.Unit/2 {
+			return 0, false
+		}
+		if geoDistance(lon, lat, centreLon, lat) > s.Width*s.Unit/2 {
+			return 0, false
+		}
+	}
+	distance := geoDistance(centreLon, centreLat, lon, lat)
+	if !s.Box && distance > s.Radius*s.Unit {
+		return 0, false
+	}
+	return distance, true
+}
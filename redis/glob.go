@@ -0,0 +1,103 @@
+package redis
+
+// globMatch reports whether s matches the glob-style pattern, like Redis's KEYS and SCAN's MATCH
+// option. In pattern, "*" matches any sequence of bytes, "?" matches any single byte, "[abc]"
+// matches any byte in the brackets, "[^abc]" any byte not in them, "[a-z]" any byte in the
+// range, and "\" matches the byte after it literally.
+func globMatch(pattern, s string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for len(pattern) > 1 && pattern[1] == '*' {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 1 {
+				return true
+			}
+			for i := 0; i <= len(s); i++ {
+				if globMatch(pattern[1:], s[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(s) == 0 {
+				return false
+			}
+			s = s[1:]
+			pattern = pattern[1:]
+		case '[':
+			if len(s) == 0 {
+				return false
+			}
+			var ok bool
+			ok, pattern = matchBracket(pattern[1:], s[0])
+			if !ok {
+				return false
+			}
+			s = s[1:]
+		default:
+			if pattern[0] == '\\' && len(pattern) > 1 {
+				pattern = pattern[1:]
+			}
+			if len(s) == 0 || s[0] != pattern[0] {
+				return false
+			}
+			s = s[1:]
+			pattern = pattern[1:]
+		}
+	}
+	return len(s) == 0
+}
+
+// matchBracket reports whether c matches the bracket expression at the start of pattern, just
+// after its "[", and returns the rest of pattern after the closing "]". Like Redis, a missing
+// "]" ends the expression at the end of pattern.
+func matchBracket(pattern string, c byte) (bool, string) {
+	not := len(pattern) > 0 && pattern[0] == '^'
+	if not {
+		pattern = pattern[1:]
+	}
+	match := false
+	for len(pattern) > 0 && pattern[0] != ']' {
+		switch {
+		case pattern[0] == '\\' && len(pattern) > 1:
+			match = match || pattern[1] == c
+			pattern = pattern[2:]
+		case len(pattern) > 2 && pattern[1] == '-':
+			start, end := pattern[0], pattern[2]
+			if start > end {
+				start, end = end, start
+			}
+			match = match || (c >= start && c <= end)
+			pattern = pattern[3:]
+		default:
+			match = match || pattern[0] == c
+			pattern = pattern[1:]
+		}
+	}
+	if len(pattern) > 0 {
+		// Skip the "]".
+		pattern = pattern[1:]
+	}
+	return match != not, pattern
+}
+
+// globPrefix returns the literal prefix of the glob-style pattern, which every string that
+// matches it starts with.
+func globPrefix(pattern string) string {
+	var prefix []byte
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*', '?', '[':
+			return string(prefix)
+		case '\\':
+			if len(pattern) > 1 {
+				pattern = pattern[1:]
+			}
+		}
+		prefix = append(prefix, pattern[0])
+		pattern = pattern[1:]
+	}
+	return string(prefix)
+}
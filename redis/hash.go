@@ -0,0 +1,215 @@
+package redis
+
+import "time"
+
+// hash is the hash data type. Like Redis, small hashes are kept in a "listpack": a slice of
+// fields in insertion order that's searched linearly, which is compact and fast while it's
+// short. Once a hash has more fields, or longer fields or values, than its Config allows, it's
+// converted to a "hashtable", a map, for good.
+//
+// Fields may have their own expiry times. Expired fields are treated as absent, and removed by
+// removeExpired, which commands call before writing to a hash.
+type hash struct {
+	listpack []hashField
+	// table is nil while the hash is a listpack.
+	table map[string]hashField
+	// expiring counts the fields that have expiry times, so that hashes without any can skip
+	// looking for expired fields.
+	expiring int
+}
+
+type hashField struct {
+	field      string
+	value      string
+	expiryTime *time.Time
+}
+
+func newHash() *hash {
+	return &hash{}
+}
+
+func (h *hash) expired(f hashField, now time.Time) bool {
+	return f.expiryTime != nil && now.After(*f.expiryTime)
+}
+
+// lookup returns field, even if it has expired.
+func (h *hash) lookup(field string) (hashField, bool) {
+	if h.table != nil {
+		f, ok := h.table[field]
+		return f, ok
+	}
+	for _, f := range h.listpack {
+		if f.field == field {
+			return f, true
+		}
+	}
+	return hashField{}, false
+}
+
+// get returns the value of field, unless it doesn't exist or has expired at time now.
+func (h *hash) get(field string, now time.Time) (string, bool) {
+	f, ok := h.lookup(field)
+	if !ok || h.expired(f, now) {
+		return "", false
+	}
+	return f.value, true
+}
+
+// set sets field to value and removes any expiry time it had, unless keepExpiryTime is true.
+// It returns true if field is new. The caller must remove expired fields first.
+func (h *hash) set(field, value string, keepExpiryTime bool) bool {
+	f, exists := h.lookup(field)
+	if !keepExpiryTime && f.expiryTime != nil {
+		h.expiring--
+		f.expiryTime = nil
+	}
+	f.field = field
+	f.value = value
+	h.put(f)
+	return !exists
+}
+
+// put stores f, replacing any field with the same name.
+func (h *hash) put(f hashField) {
+	if h.table != nil {
+		h.table[f.field] = f
+		return
+	}
+	for i := range h.listpack {
+		if h.listpack[i].field == f.field {
+			h.listpack[i] = f
+			return
+		}
+	}
+	h.listpack = append(h.listpack, f)
+}
+
+// delete removes field, returning true if it existed.
+func (h *hash) delete(field string) bool {
+	f, ok := h.lookup(field)
+	if !ok {
+		return false
+	}
+	if f.expiryTime != nil {
+		h.expiring--
+	}
+	if h.table != nil {
+		delete(h.table, field)
+		return true
+	}
+	for i := range h.listpack {
+		if h.listpack[i].field == field {
+			h.listpack = append(h.listpack[:i], h.listpack[i+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+// setExpiryTime sets the expiry time of field, which must exist, or removes it if expiryTime is
+// nil.
+func (h *hash) setExpiryTime(field string, expiryTime *time.Time) {
+	f, _ := h.lookup(field)
+	if f.expiryTime != nil {
+		h.expiring--
+	}
+	if expiryTime != nil {
+		h.expiring++
+	}
+	f.expiryTime = expiryTime
+	h.put(f)
+}
+
+// removeExpired removes every field that has expired at time now, and returns true if there
+// were any.
+func (h *hash) removeExpired(now time.Time) bool {
+	if h.expiring == 0 {
+		return false
+	}
+	expiring := h.expiring
+	if h.table != nil {
+		for field, f := range h.table {
+			if h.expired(f, now) {
+				h.expiring--
+				delete(h.table, field)
+			}
+		}
+		return h.expiring < expiring
+	}
+	kept := h.listpack[:0]
+	for _, f := range h.listpack {
+		if h.expired(f, now) {
+			h.expiring--
+			continue
+		}
+		kept = append(kept, f)
+	}
+	h.listpack = kept
+	return h.expiring < expiring
+}
+
+// len returns the number of fields that haven't expired at time now.
+func (h *hash) len(now time.Time) int {
+	if h.expiring == 0 {
+		if h.table != nil {
+			return len(h.table)
+		}
+		return len(h.listpack)
+	}
+	result := 0
+	h.each(now, func(string, string) {
+		result++
+	})
+	return result
+}
+
+// each calls fn with every field that hasn't expired at time now, and its value. Listpack fields
+// are visited in insertion order, and hashtable fields in no particular order.
+func (h *hash) each(now time.Time, fn func(field, value string)) {
+	if h.table != nil {
+		for _, f := range h.table {
+			if !h.expired(f, now) {
+				fn(f.field, f.value)
+			}
+		}
+		return
+	}
+	for _, f := range h.listpack {
+		if !h.expired(f, now) {
+			fn(f.field, f.value)
+		}
+	}
+}
+
+// convertIfNeeded converts a listpack to a hashtable if it has grown past config's limits.
+func (h *hash) convertIfNeeded(config EncodingConfig) {
+	if h.table != nil {
+		return
+	}
+	tooLong := len(h.listpack) > config.HashMaxListpackEntries
+	for _, f := range h.listpack {
+		if len(f.field) > config.HashMaxListpackValue || len(f.value) > config.HashMaxListpackValue {
+			tooLong = true
+			break
+		}
+	}
+	if !tooLong {
+		return
+	}
+	h.table = make(map[string]hashField, len(h.listpack))
+	for _, f := range h.listpack {
+		h.table[f.field] = f
+	}
+	h.listpack = nil
+}
+
+// encoding returns the name of the hash's encoding, as returned by OBJECT ENCODING.
+func (h *hash) encoding() string {
+	switch {
+	case h.table != nil:
+		return "hashtable"
+	case h.expiring > 0:
+		return "listpackex"
+	}
+	return "listpack"
+}
@@ -0,0 +1,462 @@
+package redis
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// A HyperLogLog is stored as a string in exactly the format that Redis uses, so that values can
+// be moved between this server and Redis with GET and SET. It's a 16 byte header followed by
+// 2^14 6-bit registers, either densely packed or run-length encoded as a sparse representation
+// that's much smaller while most registers are zero.
+//
+// The header is the magic "HYLL", a byte for the encoding, three unused bytes and the cached
+// cardinality as a little endian 64-bit integer, whose most significant bit is set when it needs
+// to be computed again.
+const (
+	hllP           = 14
+	hllQ           = 64 - hllP
+	hllRegisters   = 1 << hllP
+	hllPMask       = hllRegisters - 1
+	hllBits        = 6
+	hllRegisterMax = 1<<hllBits - 1
+	hllHeaderSize  = 16
+	hllDenseSize   = hllHeaderSize + (hllRegisters*hllBits+7)/8
+	hllAlphaInf    = 0.721347520444481703680
+
+	hllEncodingDense  = 0
+	hllEncodingSparse = 1
+)
+
+// The sparse representation is a sequence of opcodes that each cover a run of registers:
+//
+//   - ZERO, 00xxxxxx: up to 64 registers set to 0.
+//   - XZERO, 01xxxxxx yyyyyyyy: up to 16384 registers set to 0.
+//   - VAL, 1vvvvvxx: up to 4 registers set to a value from 1 to 32.
+//
+// Lengths and values are stored minus one.
+const (
+	hllSparseValMaxValue = 32
+	hllSparseValMaxLen   = 4
+	hllSparseZeroMaxLen  = 64
+)
+
+const (
+	errInvalidHLL   CommandError = "WRONGTYPE Key is not a valid HyperLogLog string value."
+	errCorruptedHLL CommandError = "INVALIDOBJ Corrupted HLL object detected"
+)
+
+// hyperLogLog is the string value of a HyperLogLog.
+type hyperLogLog []byte
+
+// newHyperLogLog returns an empty HyperLogLog, which is sparse with all its registers covered
+// by a single XZERO opcode.
+func newHyperLogLog() hyperLogLog {
+	h := make(hyperLogLog, hllHeaderSize, hllHeaderSize+2)
+	copy(h, "HYLL")
+	h[4] = hllEncodingSparse
+	return append(h, hllSparseXZero(hllRegisters)...)
+}
+
+// parseHyperLogLog returns data as a hyperLogLog, or errInvalidHLL if it isn't one.
+func parseHyperLogLog(data []byte) (hyperLogLog, error) {
+	if len(data) < hllHeaderSize || string(data[:4]) != "HYLL" || data[4] > hllEncodingSparse {
+		return nil, errInvalidHLL
+	}
+	if data[4] == hllEncodingDense && len(data) != hllDenseSize {
+		return nil, errInvalidHLL
+	}
+	return hyperLogLog(data), nil
+}
+
+func (h hyperLogLog) encoding() byte {
+	return h[4]
+}
+
+func (h hyperLogLog) invalidateCache() {
+	h[15] |= 1 << 7
+}
+
+// hllPatternLength hashes element and returns the register it belongs to along with the length
+// of the run of zero bits in the rest of its hash, plus one.
+func hllPatternLength(element []byte) (index, count int) {
+	hash := murmurHash64A(element, 0xadc83b19)
+	index = int(hash & hllPMask)
+	hash >>= hllP
+	// Make sure the count is at most Q+1.
+	hash |= 1 << hllQ
+	count = 1
+	for bit := uint64(1); hash&bit == 0; bit <<= 1 {
+		count++
+	}
+	return index, count
+}
+
+// murmurHash64A is the 64-bit MurmurHash2 by Austin Appleby that Redis uses to hash the elements
+// of a HyperLogLog, for little endian machines.
+func murmurHash64A(key []byte, seed uint64) uint64 {
+	const m = 0xc6a4a7935bd1e995
+	const r = 47
+	h := seed ^ (uint64(len(key)) * m)
+	for ; len(key) >= 8; key = key[8:] {
+		k := binary.LittleEndian.Uint64(key)
+		k *= m
+		k ^= k >> r
+		k *= m
+		h ^= k
+		h *= m
+	}
+	if len(key) > 0 {
+		for i := len(key) - 1; i >= 0; i-- {
+			h ^= uint64(key[i]) << (8 * i)
+		}
+		h *= m
+	}
+	h ^= h >> r
+	h *= m
+	h ^= h >> r
+	return h
+}
+
+// hllDenseGet returns register i of densely packed registers.
+func hllDenseGet(registers []byte, i int) int {
+	byteIndex := i * hllBits / 8
+	fb := uint(i * hllBits & 7)
+	b0 := uint(registers[byteIndex])
+	var b1 uint
+	if byteIndex+1 < len(registers) {
+		b1 = uint(registers[byteIndex+1])
+	}
+	return int((b0>>fb | b1<<(8-fb)) & hllRegisterMax)
+}
+
+// hllDenseSet sets register i of densely packed registers to value.
+func hllDenseSet(registers []byte, i, value int) {
+	byteIndex := i * hllBits / 8
+	fb := uint(i * hllBits & 7)
+	v := uint(value)
+	registers[byteIndex] &^= byte(hllRegisterMax << fb)
+	registers[byteIndex] |= byte(v << fb)
+	if byteIndex+1 < len(registers) {
+		registers[byteIndex+1] &^= byte(hllRegisterMax >> (8 - fb))
+		registers[byteIndex+1] |= byte(v >> (8 - fb))
+	}
+}
+
+func hllSparseIsZero(b byte) bool  { return b&0xc0 == 0 }
+func hllSparseIsXZero(b byte) bool { return b&0xc0 == 0x40 }
+func hllSparseValValue(b byte) int { return int(b>>2&0x1f) + 1 }
+func hllSparseValLen(b byte) int   { return int(b&0x3) + 1 }
+func hllSparseZeroLen(b byte) int  { return int(b&0x3f) + 1 }
+
+func hllSparseXZeroLen(b0, b1 byte) int {
+	return (int(b0&0x3f)<<8 | int(b1)) + 1
+}
+
+func hllSparseVal(value, length int) byte {
+	return byte(0x80 | (value-1)<<2 | (length - 1))
+}
+
+// hllSparseZero returns a ZERO or XZERO opcode, whichever is smallest, for length registers.
+func hllSparseZero(length int) []byte {
+	if length <= hllSparseZeroMaxLen {
+		return []byte{byte(length - 1)}
+	}
+	return hllSparseXZero(length)
+}
+
+func hllSparseXZero(length int) []byte {
+	return []byte{byte(0x40 | (length-1)>>8), byte((length - 1) & 0xff)}
+}
+
+// forEachRun calls fn with each run of registers of a sparse HyperLogLog, returning
+// errCorruptedHLL if the runs don't cover all the registers exactly.
+func (h hyperLogLog) forEachRun(fn func(first, length, value int)) error {
+	i := 0
+	for p := hllHeaderSize; p < len(h); {
+		var length, value int
+		switch b := h[p]; {
+		case hllSparseIsZero(b):
+			length = hllSparseZeroLen(b)
+			p++
+		case hllSparseIsXZero(b):
+			if p+1 == len(h) {
+				return errCorruptedHLL
+			}
+			length = hllSparseXZeroLen(b, h[p+1])
+			p += 2
+		default:
+			length, value = hllSparseValLen(b), hllSparseValValue(b)
+			p++
+		}
+		if i+length > hllRegisters {
+			return errCorruptedHLL
+		}
+		fn(i, length, value)
+		i += length
+	}
+	if i != hllRegisters {
+		return errCorruptedHLL
+	}
+	return nil
+}
+
+// mergeInto sets each of registers to the greater of it and the corresponding register of h.
+func (h hyperLogLog) mergeInto(registers *[hllRegisters]uint8) error {
+	if h.encoding() == hllEncodingDense {
+		for i := range registers {
+			if value := uint8(hllDenseGet(h[hllHeaderSize:], i)); value > registers[i] {
+				registers[i] = value
+			}
+		}
+		return nil
+	}
+	return h.forEachRun(func(first, length, value int) {
+		for i := first; i < first+length; i++ {
+			if uint8(value) > registers[i] {
+				registers[i] = uint8(value)
+			}
+		}
+	})
+}
+
+// toDense converts h to the dense representation if it's sparse, keeping its cached
+// cardinality.
+func (h *hyperLogLog) toDense() error {
+	if h.encoding() == hllEncodingDense {
+		return nil
+	}
+	dense := make(hyperLogLog, hllDenseSize)
+	copy(dense, (*h)[:hllHeaderSize])
+	dense[4] = hllEncodingDense
+	err := h.forEachRun(func(first, length, value int) {
+		if value == 0 {
+			return
+		}
+		for i := first; i < first+length; i++ {
+			hllDenseSet(dense[hllHeaderSize:], i, value)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	*h = dense
+	return nil
+}
+
+// add adds element, returning whether any register changed. The sparse representation is
+// converted to dense if it would grow larger than sparseMaxBytes.
+func (h *hyperLogLog) add(element []byte, sparseMaxBytes int) (bool, error) {
+	index, count := hllPatternLength(element)
+	return h.set(index, count, sparseMaxBytes)
+}
+
+// set sets register index to count if that's greater than its current value, returning whether
+// it changed.
+func (h *hyperLogLog) set(index, count, sparseMaxBytes int) (bool, error) {
+	var changed bool
+	var err error
+	if h.encoding() == hllEncodingDense {
+		registers := (*h)[hllHeaderSize:]
+		if changed = hllDenseGet(registers, index) < count; changed {
+			hllDenseSet(registers, index, count)
+		}
+	} else {
+		changed, err = h.sparseSet(index, count, sparseMaxBytes)
+	}
+	if changed {
+		h.invalidateCache()
+	}
+	return changed, err
+}
+
+// sparseSet is set for the sparse representation. It updates the opcodes in place just like
+// Redis does, so that the representation is byte for byte the same.
+func (h *hyperLogLog) sparseSet(index, count, sparseMaxBytes int) (bool, error) {
+	if count > hllSparseValMaxValue {
+		return h.promoteAndSet(index, count)
+	}
+
+	// Find the opcode that covers the register, and the one before it.
+	data := *h
+	p, prev := hllHeaderSize, -1
+	first, span, opLen := 0, 0, 1
+	for p < len(data) {
+		opLen = 1
+		switch b := data[p]; {
+		case hllSparseIsZero(b):
+			span = hllSparseZeroLen(b)
+		case hllSparseIsXZero(b):
+			if p+1 == len(data) {
+				return false, errCorruptedHLL
+			}
+			span, opLen = hllSparseXZeroLen(b, data[p+1]), 2
+		default:
+			span = hllSparseValLen(b)
+		}
+		if index <= first+span-1 {
+			break
+		}
+		prev = p
+		p += opLen
+		first += span
+	}
+	if span == 0 || p >= len(data) {
+		return false, errCorruptedHLL
+	}
+
+	b := data[p]
+	isVal := !hllSparseIsZero(b) && !hllSparseIsXZero(b)
+	switch {
+	case isVal && hllSparseValValue(b) >= count:
+		return false, nil
+	case isVal && span == 1, hllSparseIsZero(b) && span == 1:
+		data[p] = hllSparseVal(count, 1)
+	default:
+		// Split the opcode into up to three: the registers before index, index itself and the
+		// registers after it.
+		var seq []byte
+		last := first + span - 1
+		if isVal {
+			value := hllSparseValValue(b)
+			if index != first {
+				seq = append(seq, hllSparseVal(value, index-first))
+			}
+			seq = append(seq, hllSparseVal(count, 1))
+			if index != last {
+				seq = append(seq, hllSparseVal(value, last-index))
+			}
+		} else {
+			if index != first {
+				seq = append(seq, hllSparseZero(index-first)...)
+			}
+			seq = append(seq, hllSparseVal(count, 1))
+			if index != last {
+				seq = append(seq, hllSparseZero(last-index)...)
+			}
+		}
+		if delta := len(seq) - opLen; delta > 0 && len(data)+delta > sparseMaxBytes {
+			return h.promoteAndSet(index, count)
+		}
+		data = append(data[:p], append(seq, data[p+opLen:]...)...)
+	}
+
+	// Merge adjacent VAL opcodes with the same value, scanning up to five opcodes from the one
+	// before the updated one.
+	p = prev
+	if p < 0 {
+		p = hllHeaderSize
+	}
+	for scan := 5; p < len(data) && scan > 0; scan-- {
+		switch b := data[p]; {
+		case hllSparseIsXZero(b):
+			p += 2
+			continue
+		case hllSparseIsZero(b):
+			p++
+			continue
+		}
+		if p+1 < len(data) && !hllSparseIsZero(data[p+1]) && !hllSparseIsXZero(data[p+1]) {
+			value := hllSparseValValue(data[p])
+			length := hllSparseValLen(data[p]) + hllSparseValLen(data[p+1])
+			if value == hllSparseValValue(data[p+1]) && length <= hllSparseValMaxLen {
+				data[p+1] = hllSparseVal(value, length)
+				data = append(data[:p], data[p+1:]...)
+				// Try to merge the merged opcode with the one after it too.
+				continue
+			}
+		}
+		p++
+	}
+	*h = data
+	return true, nil
+}
+
+// promoteAndSet converts h to the dense representation and sets register index to count, which
+// can't be represented by the sparse representation as it stands.
+func (h *hyperLogLog) promoteAndSet(index, count int) (bool, error) {
+	if err := h.toDense(); err != nil {
+		return false, err
+	}
+	registers := (*h)[hllHeaderSize:]
+	if hllDenseGet(registers, index) < count {
+		hllDenseSet(registers, index, count)
+	}
+	return true, nil
+}
+
+// count returns the estimated cardinality, caching it in the header.
+func (h hyperLogLog) count() (uint64, error) {
+	count, cached, err := h.estimate()
+	if err == nil && !cached {
+		h.cache(count)
+	}
+	return count, err
+}
+
+// estimate returns the estimated cardinality without changing h, and true if it was cached in
+// the header.
+func (h hyperLogLog) estimate() (uint64, bool, error) {
+	if h[15]&(1<<7) == 0 {
+		return binary.LittleEndian.Uint64(h[8:16]), true, nil
+	}
+	var registers [hllRegisters]uint8
+	if err := h.mergeInto(&registers); err != nil {
+		return 0, false, err
+	}
+	return hllCount(&registers), false, nil
+}
+
+// cache caches the cardinality count in the header, until invalidateCache is called.
+func (h hyperLogLog) cache(count uint64) {
+	binary.LittleEndian.PutUint64(h[8:16], count)
+}
+
+// hllCount estimates the cardinality from the registers with the improved estimator by Otmar
+// Ertl, like Redis.
+func hllCount(registers *[hllRegisters]uint8) uint64 {
+	var histogram [64]int
+	for _, value := range registers {
+		histogram[value]++
+	}
+	m := float64(hllRegisters)
+	z := m * hllTau((m-float64(histogram[hllQ+1]))/m)
+	for j := hllQ; j >= 1; j-- {
+		z += float64(histogram[j])
+		z *= 0.5
+	}
+	z += m * hllSigma(float64(histogram[0])/m)
+	return uint64(math.Round(hllAlphaInf * m * m / z))
+}
+
+func hllSigma(x float64) float64 {
+	if x == 1 {
+		return math.Inf(1)
+	}
+	y, z := 1.0, x
+	for {
+		x *= x
+		zPrime := z
+		z += x * y
+		y += y
+		if zPrime == z {
+			return z
+		}
+	}
+}
+
+func hllTau(x float64) float64 {
+	if x == 0 || x == 1 {
+		return 0
+	}
+	y, z := 1.0, 1-x
+	for {
+		x = math.Sqrt(x)
+		zPrime := z
+		y *= 0.5
+		z -= math.Pow(1-x, 2) * y
+		if zPrime == z {
+			return z / 3
+		}
+	}
+}
@@ -0,0 +1,312 @@
+package redis
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// jsonDocument is the JSON data type, like RedisJSON's. Its root is any JSON value:
+//   - nil for null
+//   - a bool
+//   - an int64 for numbers that are integers, or a float64 for other numbers
+//   - a string
+//   - a *jsonArray
+//   - a *jsonObject
+type jsonDocument struct {
+	root any
+}
+
+type jsonArray struct {
+	elements []any
+}
+
+// jsonObject is a JSON object, which keeps its keys in the order they were added.
+type jsonObject struct {
+	keys   []string
+	values map[string]any
+}
+
+func newJSONObject() *jsonObject {
+	return &jsonObject{values: make(map[string]any)}
+}
+
+func (o *jsonObject) get(key string) (any, bool) {
+	value, ok := o.values[key]
+	return value, ok
+}
+
+func (o *jsonObject) set(key string, value any) {
+	if _, ok := o.values[key]; !ok {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = value
+}
+
+func (o *jsonObject) delete(key string) bool {
+	if _, ok := o.values[key]; !ok {
+		return false
+	}
+	delete(o.values, key)
+	for i, k := range o.keys {
+		if k == key {
+			o.keys = append(o.keys[:i], o.keys[i+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+// jsonTypeName returns the name of the type of a JSON value, as returned by JSON.TYPE.
+func jsonTypeName(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case int64:
+		return "integer"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case *jsonArray:
+		return "array"
+	case *jsonObject:
+		return "object"
+	}
+	panic(fmt.Sprintf("unknown JSON value type: %T", value))
+}
+
+// cloneJSON returns a deep copy of a JSON value.
+func cloneJSON(value any) any {
+	switch value := value.(type) {
+	case *jsonArray:
+		elements := make([]any, len(value.elements))
+		for i, element := range value.elements {
+			elements[i] = cloneJSON(element)
+		}
+		return &jsonArray{elements: elements}
+	case *jsonObject:
+		result := &jsonObject{
+			keys:   append([]string(nil), value.keys...),
+			values: make(map[string]any, len(value.values)),
+		}
+		for key, v := range value.values {
+			result.values[key] = cloneJSON(v)
+		}
+		return result
+	}
+	return value
+}
+
+// parseJSON parses a single JSON value.
+func parseJSON(s string) (any, error) {
+	decoder := json.NewDecoder(strings.NewReader(s))
+	decoder.UseNumber()
+	value, err := decodeJSON(decoder)
+	if err == nil {
+		if _, err = decoder.Token(); err == io.EOF {
+			return value, nil
+		}
+		if err == nil {
+			err = errors.New("trailing characters")
+		}
+	}
+	if err == io.EOF {
+		err = errors.New("EOF while parsing a value")
+	}
+	return nil, CommandError("ERR " + err.Error())
+}
+
+func decodeJSON(decoder *json.Decoder) (any, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch token := token.(type) {
+	case json.Delim:
+		if token == '[' {
+			array := &jsonArray{elements: []any{}}
+			for decoder.More() {
+				element, err := decodeJSON(decoder)
+				if err != nil {
+					return nil, err
+				}
+				array.elements = append(array.elements, element)
+			}
+			_, err := decoder.Token()
+			return array, err
+		}
+		object := newJSONObject()
+		for decoder.More() {
+			key, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeJSON(decoder)
+			if err != nil {
+				return nil, err
+			}
+			object.set(key.(string), value)
+		}
+		_, err := decoder.Token()
+		return object, err
+	case json.Number:
+		return parseJSONNumber(string(token))
+	}
+	return token, nil
+}
+
+// parseJSONNumber returns a JSON number as an int64 if it's an integer in range, or a float64
+// otherwise.
+func parseJSONNumber(s string) (any, error) {
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return i, nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsInf(f, 0) {
+		return nil, fmt.Errorf("number out of range: %s", s)
+	}
+	return f, nil
+}
+
+// JSONFormat is how JSON.GET formats JSON: its INDENT, NEWLINE and SPACE arguments, which are
+// all empty by default for the most compact form.
+type JSONFormat struct {
+	Indent, Newline, Space string
+}
+
+// formatJSON returns a JSON value formatted compactly.
+func formatJSON(value any) string {
+	return JSONFormat{}.format(value)
+}
+
+func (f JSONFormat) format(value any) string {
+	var b bytes.Buffer
+	f.write(&b, value, 0)
+	return b.String()
+}
+
+func (f JSONFormat) write(b *bytes.Buffer, value any, level int) {
+	switch value := value.(type) {
+	case nil:
+		b.WriteString("null")
+	case bool:
+		b.WriteString(strconv.FormatBool(value))
+	case int64:
+		b.WriteString(strconv.FormatInt(value, 10))
+	case float64:
+		b.WriteString(formatJSONFloat(value))
+	case string:
+		writeJSONString(b, value)
+	case *jsonArray:
+		if len(value.elements) == 0 {
+			b.WriteString("[]")
+			return
+		}
+		b.WriteByte('[')
+		for i, element := range value.elements {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			f.writeLine(b, level+1)
+			f.write(b, element, level+1)
+		}
+		f.writeLine(b, level)
+		b.WriteByte(']')
+	case *jsonObject:
+		if len(value.keys) == 0 {
+			b.WriteString("{}")
+			return
+		}
+		b.WriteByte('{')
+		for i, key := range value.keys {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			f.writeLine(b, level+1)
+			writeJSONString(b, key)
+			b.WriteByte(':')
+			b.WriteString(f.Space)
+			f.write(b, value.values[key], level+1)
+		}
+		f.writeLine(b, level)
+		b.WriteByte('}')
+	default:
+		panic(fmt.Sprintf("unknown JSON value type: %T", value))
+	}
+}
+
+func (f JSONFormat) writeLine(b *bytes.Buffer, level int) {
+	b.WriteString(f.Newline)
+	for i := 0; i < level; i++ {
+		b.WriteString(f.Indent)
+	}
+}
+
+// formatJSONFloat formats a float like RedisJSON: in decimal with at least one digit after the
+// point, unless it's very large or very small.
+func formatJSONFloat(f float64) string {
+	sign := ""
+	if f < 0 || (f == 0 && math.Signbit(f)) {
+		sign = "-"
+		f = -f
+	}
+	mantissa, exponent, _ := strings.Cut(strconv.FormatFloat(f, 'e', -1, 64), "e")
+	digits := strings.Replace(mantissa, ".", "", 1)
+	e, _ := strconv.Atoi(exponent)
+	// The value is 0.digits * 10^point.
+	point := e + 1
+
+	switch {
+	case point >= len(digits) && point <= 16:
+		return sign + digits + strings.Repeat("0", point-len(digits)) + ".0"
+	case point > 0 && point <= 16:
+		return sign + digits[:point] + "." + digits[point:]
+	case point > -5 && point <= 0:
+		return sign + "0." + strings.Repeat("0", -point) + digits
+	case len(digits) == 1:
+		return sign + digits + "e" + strconv.Itoa(point-1)
+	}
+	return sign + digits[:1] + "." + digits[1:] + "e" + strconv.Itoa(point-1)
+}
+
+// writeJSONString writes s quoted, escaping only what JSON requires. Strings that were parsed
+// from JSON are always valid UTF-8, so the rest is written as it is.
+func writeJSONString(b *bytes.Buffer, s string) {
+	const hex = "0123456789abcdef"
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			if c < 0x20 {
+				b.WriteString(`\u00`)
+				b.WriteByte(hex[c>>4])
+				b.WriteByte(hex[c&0xf])
+			} else {
+				b.WriteByte(c)
+			}
+		}
+	}
+	b.WriteByte('"')
+}
@@ -0,0 +1,659 @@
+package redis
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// JSONPath is a compiled path to values in a JSON document. It's either a JSONPath starting
+// with "$", or a legacy RedisJSON path like ".a.b" or "a[0]" that's relative to the root. The
+// subset of JSONPath supported is:
+//   - .name and ['name'] for a member of an object, and .* and [*] for every member
+//   - [n] for an element of an array, which counts back from the end if negative, [n,m] for
+//     several elements and [start:end:step] for a slice of them
+//   - ..name, ..* and ..[...] for the same anywhere under the current values
+//   - [?(expression)] for the members or elements that match a filter, which compares
+//     relative paths like @.price, absolute paths and literals with ==, !=, <, <=, >, >= and
+//     =~ for regular expressions, combined with &&, || and !
+//
+// Commands with legacy paths act on and reply with only the first value matched, like earlier
+// versions of RedisJSON, rather than replying with an array for every value.
+type JSONPath struct {
+	text   string
+	legacy bool
+	steps  []jsonPathStep
+}
+
+// jsonPathStep is a step of a JSONPath, which selects values from each of the values selected
+// by the steps before it, or from them and all of their descendants if it's recursive.
+type jsonPathStep struct {
+	recursive bool
+	// wildcard selects every member or element.
+	wildcard bool
+	// names selects the members of objects with these names.
+	names []string
+	// indices selects the elements of arrays at these indices.
+	indices []int
+	// slice selects the elements of arrays in a slice.
+	slice *jsonSlice
+	// filter selects the members or elements that match it.
+	filter *jsonFilter
+}
+
+// jsonSlice is a [start:end:step] slice of an array, with Python's semantics for positive
+// steps.
+type jsonSlice struct {
+	start, end *int
+	step       int
+}
+
+// jsonFilter is an expression of a filter step. It's a comparison if op is a comparison
+// operator, left && right if op is "&&", left || right if it's "||", or !left if it's "!".
+// A comparison with no op is true if its left operand exists.
+type jsonFilter struct {
+	op          string
+	left, right *jsonFilter
+	operand     *jsonOperand
+	other       *jsonOperand
+}
+
+// jsonOperand is an operand of a comparison in a filter: a path relative to the current value,
+// if relative is true, an absolute path, or a literal value.
+type jsonOperand struct {
+	relative bool
+	path     *JSONPath
+	literal  any
+}
+
+func errInvalidJSONPath(path string) error {
+	return CommandError(fmt.Sprintf("ERR invalid JSONPath '%s'", path))
+}
+
+// ParseJSONPath compiles a JSONPath or a legacy path.
+func ParseJSONPath(path string) (*JSONPath, error) {
+	result := &JSONPath{text: path}
+	p := &jsonPathParser{s: path}
+	switch {
+	case strings.HasPrefix(path, "$"):
+		p.pos = 1
+	case path == ".":
+		result.legacy = true
+		return result, nil
+	default:
+		result.legacy = true
+		if !strings.HasPrefix(path, ".") && !strings.HasPrefix(path, "[") {
+			// Legacy paths can start with a member name.
+			p.s = "." + path
+		}
+	}
+	steps, err := p.steps()
+	if err != nil || p.pos != len(p.s) {
+		return nil, errInvalidJSONPath(path)
+	}
+	result.steps = steps
+	return result, nil
+}
+
+// isStatic returns whether p can only ever select one value.
+func (p *JSONPath) isStatic() bool {
+	for _, step := range p.steps {
+		if step.recursive || step.wildcard || step.slice != nil || step.filter != nil ||
+			len(step.names)+len(step.indices) != 1 {
+			return false
+		}
+	}
+	return true
+}
+
+type jsonPathParser struct {
+	s   string
+	pos int
+}
+
+func (p *jsonPathParser) peek(prefix string) bool {
+	return strings.HasPrefix(p.s[p.pos:], prefix)
+}
+
+func (p *jsonPathParser) consume(prefix string) bool {
+	if p.peek(prefix) {
+		p.pos += len(prefix)
+		return true
+	}
+	return false
+}
+
+func (p *jsonPathParser) skipSpaces() {
+	for p.pos < len(p.s) && p.s[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+var errJSONPathSyntax = errors.New("syntax error")
+
+// steps parses steps until the end of the path or anything that isn't a step, like the end of
+// a path in a filter.
+func (p *jsonPathParser) steps() ([]jsonPathStep, error) {
+	var steps []jsonPathStep
+	for {
+		var step jsonPathStep
+		var err error
+		switch {
+		case p.consume(".."):
+			step.recursive = true
+			if p.peek("[") {
+				err = p.bracket(&step)
+			} else {
+				err = p.dotted(&step)
+			}
+		case p.consume("."):
+			err = p.dotted(&step)
+		case p.peek("["):
+			err = p.bracket(&step)
+		default:
+			return steps, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, step)
+	}
+}
+
+// dotted parses the name or * after a dot.
+func (p *jsonPathParser) dotted(step *jsonPathStep) error {
+	if p.consume("*") {
+		step.wildcard = true
+		return nil
+	}
+	start := p.pos
+	for p.pos < len(p.s) && !strings.ContainsRune(".[]()!=<>&|,'\" ~", rune(p.s[p.pos])) {
+		p.pos++
+	}
+	if p.pos == start {
+		return errJSONPathSyntax
+	}
+	step.names = []string{p.s[start:p.pos]}
+	return nil
+}
+
+// bracket parses a step in brackets.
+func (p *jsonPathParser) bracket(step *jsonPathStep) error {
+	p.consume("[")
+	p.skipSpaces()
+	switch {
+	case p.consume("*"):
+		step.wildcard = true
+	case p.consume("?("):
+		filter, err := p.filter()
+		if err != nil {
+			return err
+		}
+		p.skipSpaces()
+		if !p.consume(")") {
+			return errJSONPathSyntax
+		}
+		step.filter = filter
+	case p.peek("'") || p.peek(`"`):
+		for {
+			name, err := p.quoted()
+			if err != nil {
+				return err
+			}
+			step.names = append(step.names, name)
+			p.skipSpaces()
+			if !p.consume(",") {
+				break
+			}
+			p.skipSpaces()
+		}
+	default:
+		if err := p.indices(step); err != nil {
+			return err
+		}
+	}
+	p.skipSpaces()
+	if !p.consume("]") {
+		return errJSONPathSyntax
+	}
+	return nil
+}
+
+// indices parses a list of indices or a slice.
+func (p *jsonPathParser) indices(step *jsonPathStep) error {
+	var parts [3]*int
+	i := 0
+	for {
+		p.skipSpaces()
+		if n, ok := p.integer(); ok {
+			parts[i] = &n
+		}
+		p.skipSpaces()
+		if !p.consume(":") {
+			break
+		}
+		i++
+		if i == len(parts) {
+			return errJSONPathSyntax
+		}
+	}
+	if i > 0 {
+		step.slice = &jsonSlice{start: parts[0], end: parts[1], step: 1}
+		if parts[2] != nil {
+			step.slice.step = *parts[2]
+		}
+		return nil
+	}
+	if parts[0] == nil {
+		return errJSONPathSyntax
+	}
+	step.indices = append(step.indices, *parts[0])
+	for p.consume(",") {
+		p.skipSpaces()
+		n, ok := p.integer()
+		if !ok {
+			return errJSONPathSyntax
+		}
+		step.indices = append(step.indices, n)
+		p.skipSpaces()
+	}
+	return nil
+}
+
+func (p *jsonPathParser) integer() (int, bool) {
+	start := p.pos
+	if p.peek("-") {
+		p.pos++
+	}
+	for p.pos < len(p.s) && p.s[p.pos] >= '0' && p.s[p.pos] <= '9' {
+		p.pos++
+	}
+	n, err := strconv.Atoi(p.s[start:p.pos])
+	if err != nil {
+		p.pos = start
+		return 0, false
+	}
+	return n, true
+}
+
+// quoted parses a string in single or double quotes, with backslash escapes.
+func (p *jsonPathParser) quoted() (string, error) {
+	quote := p.s[p.pos]
+	p.pos++
+	var b strings.Builder
+	for p.pos < len(p.s) {
+		c := p.s[p.pos]
+		p.pos++
+		switch {
+		case c == quote:
+			return b.String(), nil
+		case c == '\\' && p.pos < len(p.s):
+			b.WriteByte(p.s[p.pos])
+			p.pos++
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", errJSONPathSyntax
+}
+
+// filter parses a filter expression, where || binds less tightly than &&.
+func (p *jsonPathParser) filter() (*jsonFilter, error) {
+	left, err := p.conjunction()
+	if err != nil {
+		return nil, err
+	}
+	for p.skipSpaces(); p.consume("||"); p.skipSpaces() {
+		right, err := p.conjunction()
+		if err != nil {
+			return nil, err
+		}
+		left = &jsonFilter{op: "||", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *jsonPathParser) conjunction() (*jsonFilter, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for p.skipSpaces(); p.consume("&&"); p.skipSpaces() {
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		left = &jsonFilter{op: "&&", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *jsonPathParser) unary() (*jsonFilter, error) {
+	p.skipSpaces()
+	if p.consume("!") {
+		operand, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return &jsonFilter{op: "!", left: operand}, nil
+	}
+	if p.consume("(") {
+		filter, err := p.filter()
+		if err != nil {
+			return nil, err
+		}
+		p.skipSpaces()
+		if !p.consume(")") {
+			return nil, errJSONPathSyntax
+		}
+		return filter, nil
+	}
+	return p.comparison()
+}
+
+func (p *jsonPathParser) comparison() (*jsonFilter, error) {
+	operand, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpaces()
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">", "=~"} {
+		if p.consume(op) {
+			p.skipSpaces()
+			other, err := p.operand()
+			if err != nil {
+				return nil, err
+			}
+			return &jsonFilter{op: op, operand: operand, other: other}, nil
+		}
+	}
+	if operand.path == nil {
+		return nil, errJSONPathSyntax
+	}
+	return &jsonFilter{operand: operand}, nil
+}
+
+func (p *jsonPathParser) operand() (*jsonOperand, error) {
+	switch {
+	case p.peek("@") || p.peek("$"):
+		relative := p.s[p.pos] == '@'
+		p.pos++
+		steps, err := p.steps()
+		if err != nil {
+			return nil, err
+		}
+		return &jsonOperand{relative: relative, path: &JSONPath{steps: steps}}, nil
+	case p.peek("'") || p.peek(`"`):
+		s, err := p.quoted()
+		if err != nil {
+			return nil, err
+		}
+		return &jsonOperand{literal: s}, nil
+	}
+	start := p.pos
+	for p.pos < len(p.s) && !strings.ContainsRune(" ()!=<>&|", rune(p.s[p.pos])) {
+		p.pos++
+	}
+	switch literal := p.s[start:p.pos]; literal {
+	case "true":
+		return &jsonOperand{literal: true}, nil
+	case "false":
+		return &jsonOperand{literal: false}, nil
+	case "null":
+		return &jsonOperand{literal: nil}, nil
+	default:
+		n, err := parseJSONNumber(literal)
+		if err != nil {
+			return nil, errJSONPathSyntax
+		}
+		return &jsonOperand{literal: n}, nil
+	}
+}
+
+// jsonNode is a value selected by a JSONPath, and where it is so that it can be replaced or
+// deleted: the member key of an object, the element index of an array, or the root of a
+// document.
+type jsonNode struct {
+	value  any
+	parent any
+	key    string
+	index  int
+}
+
+// set replaces the value at n.
+func (n jsonNode) set(value any) {
+	switch parent := n.parent.(type) {
+	case *jsonDocument:
+		parent.root = value
+	case *jsonObject:
+		parent.set(n.key, value)
+	case *jsonArray:
+		parent.elements[n.index] = value
+	}
+}
+
+// evaluate returns the nodes that p selects in doc, in document order for each step.
+func (p *JSONPath) evaluate(doc *jsonDocument) []jsonNode {
+	return p.evaluateFrom(doc, jsonNode{value: doc.root, parent: doc})
+}
+
+// evaluateFrom returns the nodes that p selects starting from node, with doc the document for
+// absolute paths in filters.
+func (p *JSONPath) evaluateFrom(doc *jsonDocument, node jsonNode) []jsonNode {
+	nodes := []jsonNode{node}
+	for _, step := range p.steps {
+		var selected []jsonNode
+		for _, node := range nodes {
+			if step.recursive {
+				for _, descendant := range descendants(node) {
+					selected = step.selectFrom(doc, descendant, selected)
+				}
+			} else {
+				selected = step.selectFrom(doc, node, selected)
+			}
+		}
+		nodes = selected
+	}
+	return nodes
+}
+
+// descendants returns node and every value under it, in document order.
+func descendants(node jsonNode) []jsonNode {
+	result := []jsonNode{node}
+	for _, child := range children(node.value) {
+		result = append(result, descendants(child)...)
+	}
+	return result
+}
+
+// children returns the members of an object or the elements of an array.
+func children(value any) []jsonNode {
+	switch value := value.(type) {
+	case *jsonObject:
+		result := make([]jsonNode, len(value.keys))
+		for i, key := range value.keys {
+			result[i] = jsonNode{value: value.values[key], parent: value, key: key}
+		}
+		return result
+	case *jsonArray:
+		result := make([]jsonNode, len(value.elements))
+		for i, element := range value.elements {
+			result[i] = jsonNode{value: element, parent: value, index: i}
+		}
+		return result
+	}
+	return nil
+}
+
+// selectFrom appends the nodes that s selects from node to selected.
+func (s jsonPathStep) selectFrom(doc *jsonDocument, node jsonNode, selected []jsonNode) []jsonNode {
+	switch {
+	case s.wildcard:
+		return append(selected, children(node.value)...)
+	case s.filter != nil:
+		for _, child := range children(node.value) {
+			if s.filter.matches(doc, child) {
+				selected = append(selected, child)
+			}
+		}
+		return selected
+	case s.names != nil:
+		object, ok := node.value.(*jsonObject)
+		if !ok {
+			return selected
+		}
+		for _, name := range s.names {
+			if value, ok := object.get(name); ok {
+				selected = append(selected, jsonNode{value: value, parent: object, key: name})
+			}
+		}
+		return selected
+	}
+
+	array, ok := node.value.(*jsonArray)
+	if !ok {
+		return selected
+	}
+	n := len(array.elements)
+	element := func(i int) jsonNode {
+		return jsonNode{value: array.elements[i], parent: array, index: i}
+	}
+	if s.slice == nil {
+		for _, i := range s.indices {
+			if i < 0 {
+				i += n
+			}
+			if i >= 0 && i < n {
+				selected = append(selected, element(i))
+			}
+		}
+		return selected
+	}
+	start, end := 0, n
+	bound := func(i int) int {
+		if i < 0 {
+			i += n
+		}
+		if i < 0 {
+			return 0
+		}
+		if i > n {
+			return n
+		}
+		return i
+	}
+	if s.slice.start != nil {
+		start = bound(*s.slice.start)
+	}
+	if s.slice.end != nil {
+		end = bound(*s.slice.end)
+	}
+	for i := start; i < end && s.slice.step > 0; i += s.slice.step {
+		selected = append(selected, element(i))
+	}
+	return selected
+}
+
+// matches returns whether node matches the filter.
+func (f *jsonFilter) matches(doc *jsonDocument, node jsonNode) bool {
+	switch f.op {
+	case "&&":
+		return f.left.matches(doc, node) && f.right.matches(doc, node)
+	case "||":
+		return f.left.matches(doc, node) || f.right.matches(doc, node)
+	case "!":
+		return !f.left.matches(doc, node)
+	}
+
+	left, ok := f.operand.value(doc, node)
+	if f.op == "" || !ok {
+		return ok
+	}
+	right, ok := f.other.value(doc, node)
+	if !ok {
+		return false
+	}
+
+	if f.op == "=~" {
+		s, ok := left.(string)
+		pattern, isString := right.(string)
+		if !ok || !isString {
+			return false
+		}
+		matched, err := regexp.MatchString(pattern, s)
+		return err == nil && matched
+	}
+	if x, ok := jsonNumber(left); ok {
+		y, ok := jsonNumber(right)
+		return ok && compareOrdered(f.op, x, y)
+	}
+	if x, ok := left.(string); ok {
+		y, ok := right.(string)
+		return ok && compareOrdered(f.op, x, y)
+	}
+	// Other values can only be compared for equality.
+	switch f.op {
+	case "==":
+		return jsonEqual(left, right)
+	case "!=":
+		return !jsonEqual(left, right)
+	}
+	return false
+}
+
+// value returns the value of the operand for node, or false if it's a path that selects
+// nothing.
+func (o *jsonOperand) value(doc *jsonDocument, node jsonNode) (any, bool) {
+	if o.path == nil {
+		return o.literal, true
+	}
+	start := jsonNode{value: doc.root, parent: doc}
+	if o.relative {
+		start = node
+	}
+	nodes := o.path.evaluateFrom(doc, start)
+	if len(nodes) == 0 {
+		return nil, false
+	}
+	return nodes[0].value, true
+}
+
+// jsonNumber returns a JSON number as a float64.
+func jsonNumber(value any) (float64, bool) {
+	switch value := value.(type) {
+	case int64:
+		return float64(value), true
+	case float64:
+		return value, true
+	}
+	return 0, false
+}
+
+func compareOrdered[T float64 | string](op string, x, y T) bool {
+	switch op {
+	case "==":
+		return x == y
+	case "!=":
+		return x != y
+	case "<":
+		return x < y
+	case "<=":
+		return x <= y
+	case ">":
+		return x > y
+	case ">=":
+		return x >= y
+	}
+	return false
+}
+
+// jsonEqual returns whether two JSON values are equal.
+func jsonEqual(x, y any) bool {
+	if a, ok := jsonNumber(x); ok {
+		b, ok := jsonNumber(y)
+		return ok && a == b
+	}
+	return formatJSON(x) == formatJSON(y)
+}
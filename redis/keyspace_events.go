@@ -0,0 +1,157 @@
+package redis
+
+import (
+	"fmt"
+	"strings"
+)
+
+// KeyspaceEvents is the classes of keyspace events that are notified over publish/subscribe,
+// after Redis's notify-keyspace-events config parameter. Events are only notified if it
+// includes notifyKeyspace or notifyKeyevent, and the class of the event.
+type KeyspaceEvents int
+
+const (
+	// notifyKeyspace publishes events to __keyspace@<db>__:<key>, with the event as the message.
+	notifyKeyspace KeyspaceEvents = 1 << iota
+	// notifyKeyevent publishes events to __keyevent@<db>__:<event>, with the key as the message.
+	notifyKeyevent
+	// notifyGeneric is the class of commands that aren't specific to a type, like DEL.
+	notifyGeneric
+	notifyString
+	notifyList
+	notifySet
+	notifyHash
+	notifyZSet
+	// notifyExpired is the class of the expired event, of keys deleted after their expiry time.
+	notifyExpired
+	// notifyEvicted is the class of the evicted event, of keys evicted by maxmemory.
+	notifyEvicted
+	notifyStream
+	// notifyKeyMiss is the class of the keymiss event, of commands that find a key absent.
+	notifyKeyMiss
+	// notifyModule is the class of events of module types.
+	notifyModule
+	// notifyNew is the class of the new event, of keys that are added.
+	notifyNew
+
+	// notifyAll is the classes of the "A" alias.
+	notifyAll = notifyGeneric | notifyString | notifyList | notifySet | notifyHash | notifyZSet |
+		notifyExpired | notifyEvicted | notifyStream | notifyModule
+)
+
+// keyspaceEventClasses are the characters of each class of events, in the order that Redis
+// formats them.
+var keyspaceEventClasses = []struct {
+	char  byte
+	class KeyspaceEvents
+}{
+	{'g', notifyGeneric},
+	{'$', notifyString},
+	{'l', notifyList},
+	{'s', notifySet},
+	{'h', notifyHash},
+	{'z', notifyZSet},
+	{'x', notifyExpired},
+	{'e', notifyEvicted},
+	{'t', notifyStream},
+	{'d', notifyModule},
+	{'K', notifyKeyspace},
+	{'E', notifyKeyevent},
+	{'m', notifyKeyMiss},
+	{'n', notifyNew},
+}
+
+// ParseKeyspaceEvents parses a value of notify-keyspace-events, a string of a character for
+// each class of events, where "A" is an alias for "g$lshzxetd".
+func ParseKeyspaceEvents(s string) (KeyspaceEvents, error) {
+	var result KeyspaceEvents
+	for i := 0; i < len(s); i++ {
+		if s[i] == 'A' {
+			result |= notifyAll
+			continue
+		}
+		found := false
+		for _, class := range keyspaceEventClasses {
+			if class.char == s[i] {
+				result |= class.class
+				found = true
+				break
+			}
+		}
+		if !found {
+			return 0, fmt.Errorf("invalid keyspace event class %q", s[i])
+		}
+	}
+	return result, nil
+}
+
+// String returns e like Redis's CONFIG GET notify-keyspace-events.
+func (e KeyspaceEvents) String() string {
+	var result strings.Builder
+	if e&notifyAll == notifyAll {
+		result.WriteByte('A')
+	}
+	for _, class := range keyspaceEventClasses {
+		if e&class.class == 0 || (e&notifyAll == notifyAll && notifyAll&class.class != 0) {
+			continue
+		}
+		result.WriteByte(class.char)
+	}
+	return result.String()
+}
+
+// keyspaceEvent is an event that happened to a key, to be notified once its write transaction
+// is over.
+type keyspaceEvent struct {
+	event string
+	key   string
+}
+
+// keyspaceNotifier publishes the keyspace events of the classes in events to pubSub.
+type keyspaceNotifier struct {
+	pubSub *PubSub
+	events KeyspaceEvents
+}
+
+// NotifyKeyspaceEvents makes s publish its keyspace events of the classes in events to pubSub.
+func (s *Store) NotifyKeyspaceEvents(pubSub *PubSub, events KeyspaceEvents) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.notifier = keyspaceNotifier{pubSub: pubSub, events: events}
+}
+
+// notify records that event, of class, happened to key. It's published once the transaction's
+// function returns, if the store notifies events of its class. Since every change to a key is
+// notified, it also records that key changed, like modified.
+func (tx *storeTx) notify(class KeyspaceEvents, event string, key string) {
+	tx.modified(key)
+	events := tx.store.notifier.events
+	if events&(notifyKeyspace|notifyKeyevent) == 0 || events&class == 0 {
+		return
+	}
+	tx.events = append(tx.events, keyspaceEvent{event: event, key: key})
+}
+
+// modified records that key changed without a keyspace event, like Redis's signalModifiedKey, so
+// that the transactions of the clients watching it are discarded and the clients that may have
+// cached it are sent invalidation messages.
+func (tx *storeTx) modified(key string) {
+	tx.checkWritable()
+	tx.changed = append(tx.changed, key)
+}
+
+// publishEvents publishes the events recorded by notify, in the order that they happened.
+func (tx *storeTx) publishEvents() {
+	notifier := tx.store.notifier
+	for _, e := range tx.events {
+		// There's only database 0.
+		if notifier.events&notifyKeyspace != 0 {
+			notifier.pubSub.publish("__keyspace@0__:"+e.key, e.event)
+		}
+		if notifier.events&notifyKeyevent != 0 {
+			notifier.pubSub.publish("__keyevent@0__:"+e.event, e.key)
+		}
+	}
+	tx.events = nil
+}
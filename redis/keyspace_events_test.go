@@ -0,0 +1,349 @@
+package redis_test
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/redis"
+)
+
+func TestParseKeyspaceEvents(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		value string
+		want  string
+		err   bool
+	}{
+		{value: "", want: ""},
+		{value: "Ex", want: "xE"},
+		{value: "KEA", want: "AKE"},
+		{value: "Ag$lshzxetdKE", want: "AKE"},
+		{value: "Kl$n", want: "$lKn"},
+		{value: "Kw", err: true},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.value, func(t *testing.T) {
+			events, err := redis.ParseKeyspaceEvents(test.value)
+			if test.err {
+				if err == nil {
+					t.Errorf("err expected to be non-nil but was nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("err: expected: nil; got: %v", err)
+			}
+			if events.String() != test.want {
+				t.Errorf("events expected to be %#v but were %#v", test.want, events.String())
+			}
+		})
+	}
+}
+
+// keyspaceEventsSubscriber returns a client subscribed to every keyspace and keyevent channel of
+// store, which notifies the classes of events.
+func keyspaceEventsSubscriber(
+	t *testing.T,
+	store *redis.Store,
+	clock redis.Clock,
+	events string,
+) *redis.Client {
+	t.Helper()
+
+	classes, err := redis.ParseKeyspaceEvents(events)
+	if err != nil {
+		t.Fatalf("err: expected: nil; got: %v", err)
+	}
+	pubSub := redis.NewPubSub(clock, redis.OutputBufferLimit{})
+	store.NotifyKeyspaceEvents(pubSub, classes)
+	client := redis.NewClients().NewClient()
+	redis.NewPSubscribeCommand(pubSub, client, []string{"__key*__:*"}).Run()
+	return client
+}
+
+// keyspaceEvent returns the messages that a subscriber to keyspace and keyevent channels is
+// pushed when event happens to key.
+func keyspaceEvent(event, key string) string {
+	return pmessage("__key*__:*", "__keyspace@0__:"+key, event) +
+		pmessage("__key*__:*", "__keyevent@0__:"+event, key)
+}
+
+func TestKeyspaceEvents(t *testing.T) {
+	t.Parallel()
+
+	config := defaultEncodingRedisConfig
+	clock := &FakeClock{CurrentTime: time.UnixMilli(0)}
+	one := 1
+
+	tests := []struct {
+		name     string
+		events   string
+		commands func(store *redis.Store) []redis.Command
+		want     string
+	}{
+		{
+			name:   "strings",
+			events: "KEA",
+			commands: func(store *redis.Store) []redis.Command {
+				return []redis.Command{
+					redis.NewSetCommand(store, "key", "a"),
+					redis.NewAppendCommand(store, clock, "key", "b"),
+					redis.NewSetRangeCommand(store, clock, "key", 0, "c"),
+					redis.NewGetExCommand(
+						store, clock, "key", redis.GetExExpiryTime(time.UnixMilli(10)),
+					),
+					redis.NewGetExCommand(store, clock, "key", redis.GetExPersist()),
+					redis.NewGetCommand(store, clock, "key"),
+					redis.NewGetDelCommand(store, clock, "key"),
+				}
+			},
+			want: keyspaceEvent("set", "key") + keyspaceEvent("append", "key") +
+				keyspaceEvent("setrange", "key") + keyspaceEvent("expire", "key") +
+				keyspaceEvent("persist", "key") + keyspaceEvent("del", "key"),
+		},
+		{
+			name:   "lists",
+			events: "KEA",
+			commands: func(store *redis.Store) []redis.Command {
+				return []redis.Command{
+					redis.NewPushCommand(store, clock, "list", redis.ListEndLeft, []string{"a", "b"}),
+					redis.NewLSetCommand(store, clock, "list", 0, "c"),
+					redis.NewLMoveCommand(
+						store, clock, "list", "other", redis.ListEndRight, redis.ListEndLeft,
+					),
+					redis.NewPopCommand(store, clock, "list", redis.ListEndLeft, &one),
+					redis.NewPopCommand(store, clock, "list", redis.ListEndLeft, &one),
+				}
+			},
+			want: keyspaceEvent("lpush", "list") + keyspaceEvent("lset", "list") +
+				keyspaceEvent("rpop", "list") + keyspaceEvent("lpush", "other") +
+				keyspaceEvent("lpop", "list") + keyspaceEvent("del", "list"),
+		},
+		{
+			name:   "hashes",
+			events: "KEA",
+			commands: func(store *redis.Store) []redis.Command {
+				return []redis.Command{
+					redis.NewHSetCommand(
+						store, clock, config, "hash", []redis.FieldValue{{Field: "f", Value: "1"}},
+					),
+					redis.NewHIncrByCommand(store, clock, config, "hash", "f", 1),
+					redis.NewHDelCommand(store, clock, "hash", []string{"g"}),
+					redis.NewHDelCommand(store, clock, "hash", []string{"f"}),
+				}
+			},
+			want: keyspaceEvent("hset", "hash") + keyspaceEvent("hincrby", "hash") +
+				keyspaceEvent("hdel", "hash") + keyspaceEvent("del", "hash"),
+		},
+		{
+			name:   "sets",
+			events: "KEA",
+			commands: func(store *redis.Store) []redis.Command {
+				return []redis.Command{
+					redis.NewSAddCommand(store, clock, config, "set", []string{"a", "b"}),
+					redis.NewSAddCommand(store, clock, config, "set", []string{"a"}),
+					redis.NewSMoveCommand(store, clock, config, "set", "other", "a"),
+					redis.NewSetOpStoreCommand(
+						store, clock, config, redis.SetOpUnion, "union", []string{"set", "other"},
+					),
+					redis.NewSRemCommand(store, clock, "set", []string{"b"}),
+				}
+			},
+			want: keyspaceEvent("sadd", "set") + keyspaceEvent("srem", "set") +
+				keyspaceEvent("sadd", "other") + keyspaceEvent("sunionstore", "union") +
+				keyspaceEvent("srem", "set") + keyspaceEvent("del", "set"),
+		},
+		{
+			name:   "sorted sets",
+			events: "KEA",
+			commands: func(store *redis.Store) []redis.Command {
+				return []redis.Command{
+					redis.NewZAddCommand(
+						store, clock, "zset", []redis.ScoreMember{{Score: 1, Member: "a"}},
+					),
+					redis.NewZAddCommand(
+						store,
+						clock,
+						"zset",
+						[]redis.ScoreMember{{Score: 1, Member: "a"}},
+						redis.ZAddIncr(),
+					),
+					redis.NewZRemRangeCommand(
+						store, clock, "zset", redis.NewZRangeQuery(1, -1),
+					),
+					redis.NewZPopCommand(store, clock, "zset", redis.ZSetEndMax, 1),
+				}
+			},
+			want: keyspaceEvent("zadd", "zset") + keyspaceEvent("zincr", "zset") +
+				keyspaceEvent("zpopmax", "zset") + keyspaceEvent("del", "zset"),
+		},
+		{
+			name:   "streams",
+			events: "KEA",
+			commands: func(store *redis.Store) []redis.Command {
+				return []redis.Command{
+					redis.NewXAddCommand(
+						store,
+						clock,
+						"stream",
+						redis.XAddID{ID: redis.StreamID{Ms: 1}},
+						[]string{"f", "v"},
+					),
+					redis.NewXGroupCreateCommand(
+						store, clock, "stream", "group", redis.XGroupID{},
+					),
+					redis.NewXDelCommand(store, clock, "stream", []redis.StreamID{{Ms: 1}}),
+				}
+			},
+			want: keyspaceEvent("xadd", "stream") + keyspaceEvent("xgroup-create", "stream") +
+				keyspaceEvent("xdel", "stream"),
+		},
+		{
+			name:   "only keyevent channels of some classes",
+			events: "El",
+			commands: func(store *redis.Store) []redis.Command {
+				return []redis.Command{
+					redis.NewSetCommand(store, "key", "a"),
+					redis.NewPushCommand(store, clock, "list", redis.ListEndRight, []string{"a"}),
+				}
+			},
+			want: pmessage("__key*__:*", "__keyevent@0__:rpush", "list"),
+		},
+		{
+			name:   "new keys",
+			events: "Kn",
+			commands: func(store *redis.Store) []redis.Command {
+				return []redis.Command{
+					redis.NewSetCommand(store, "key", "a"),
+					redis.NewSetCommand(store, "key", "b"),
+				}
+			},
+			want: pmessage("__key*__:*", "__keyspace@0__:key", "new"),
+		},
+		{
+			name:   "no keyspace or keyevent channels",
+			events: "A",
+			commands: func(store *redis.Store) []redis.Command {
+				return []redis.Command{redis.NewSetCommand(store, "key", "a")}
+			},
+			want: "",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			store := redis.NewStore()
+			client := keyspaceEventsSubscriber(t, store, clock, test.events)
+
+			for _, command := range test.commands(store) {
+				command.Run()
+			}
+
+			if pushes := client.TakePushes(); pushes != test.want {
+				t.Errorf("expected to be pushed %#v but was %#v", test.want, pushes)
+			}
+		})
+	}
+}
+
+func TestKeyspaceEvents_Expired(t *testing.T) {
+	t.Parallel()
+
+	clock := &FakeClock{CurrentTime: time.UnixMilli(0)}
+	store := redis.NewStore()
+	client := keyspaceEventsSubscriber(t, store, clock, "Ex")
+	store.SetWithExpiryTime("lazy", "a", time.UnixMilli(10))
+	store.SetWithExpiryTime("active", "b", time.UnixMilli(10))
+	store.SetWithExpiryTime("later", "c", time.UnixMilli(20))
+
+	clock.CurrentTime = time.UnixMilli(15)
+	if response := redis.NewGetCommand(store, clock, "lazy").Run(); response != "$-1\r\n" {
+		t.Errorf("GET expected to return a null bulk string but returned %#v", response)
+	}
+	want := pmessage("__key*__:*", "__keyevent@0__:expired", "lazy")
+	if pushes := client.TakePushes(); pushes != want {
+		t.Errorf("expected to be pushed %#v but was %#v", want, pushes)
+	}
+
+	if deleted := store.DeleteExpiredKeys(clock.NowMonotonic()); deleted != 1 {
+		t.Errorf("expected 1 key to be deleted but %d were", deleted)
+	}
+	want = pmessage("__key*__:*", "__keyevent@0__:expired", "active")
+	if pushes := client.TakePushes(); pushes != want {
+		t.Errorf("expected to be pushed %#v but was %#v", want, pushes)
+	}
+	if _, ok := store.Get("active"); ok {
+		t.Errorf("expected active to be deleted")
+	}
+	if _, ok := store.Get("later"); !ok {
+		t.Errorf("expected later not to be deleted")
+	}
+}
+
+func TestStore_DeleteExpiredKeys(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	for i := 0; i < 1000; i++ {
+		store.SetWithExpiryTime("key"+strconv.Itoa(i), "value", time.UnixMilli(10))
+	}
+	store.SetWithExpiryTime("later", "value", time.UnixMilli(1000))
+
+	// Every sample has expired keys until only one key is left, so it carries on until then.
+	if deleted := store.DeleteExpiredKeys(time.UnixMilli(100)); deleted != 1000 {
+		t.Errorf("expected 1000 keys to be deleted but %d were", deleted)
+	}
+	if _, ok := store.Get("later"); !ok {
+		t.Errorf("expected later not to be deleted")
+	}
+}
+
+func TestStore_DeleteExpiredKeys_HashFields(t *testing.T) {
+	t.Parallel()
+
+	clock := &FakeClock{CurrentTime: time.UnixMilli(0)}
+	store := redis.NewStore()
+	redis.NewHSetCommand(
+		store, clock, defaultEncodingRedisConfig, "hash",
+		[]redis.FieldValue{{Field: "a", Value: "1"}, {Field: "b", Value: "2"}},
+	).Run()
+	redis.NewHExpireCommand(
+		store, clock, "hash", time.UnixMilli(10), redis.ExpireAlways, []string{"a"},
+	).Run()
+	redis.NewHExpireCommand(
+		store, clock, "hash", time.UnixMilli(20), redis.ExpireAlways, []string{"b"},
+	).Run()
+	client := keyspaceEventsSubscriber(t, store, clock, "Egh")
+
+	// Expired fields are removed without the hash being written.
+	clock.CurrentTime = time.UnixMilli(15)
+	if deleted := store.DeleteExpiredKeys(clock.NowMonotonic()); deleted != 0 {
+		t.Errorf("expected no keys to be deleted but %d were", deleted)
+	}
+	want := pmessage("__key*__:*", "__keyevent@0__:hexpired", "hash")
+	if pushes := client.TakePushes(); pushes != want {
+		t.Errorf("expected to be pushed %#v but was %#v", want, pushes)
+	}
+	if _, ok := store.Get("hash"); !ok {
+		t.Errorf("expected hash not to be deleted")
+	}
+
+	// A hash left empty is deleted.
+	clock.CurrentTime = time.UnixMilli(25)
+	if deleted := store.DeleteExpiredKeys(clock.NowMonotonic()); deleted != 1 {
+		t.Errorf("expected 1 key to be deleted but %d were", deleted)
+	}
+	want = pmessage("__key*__:*", "__keyevent@0__:hexpired", "hash") +
+		pmessage("__key*__:*", "__keyevent@0__:del", "hash")
+	if pushes := client.TakePushes(); pushes != want {
+		t.Errorf("expected to be pushed %#v but was %#v", want, pushes)
+	}
+	if _, ok := store.Get("hash"); ok {
+		t.Errorf("expected hash to be deleted")
+	}
+}
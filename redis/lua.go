@@ -0,0 +1,184 @@
+package redis
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/lua"
+	"github.com/codecrafters-io/redis-starter-go/internal/lua/parse"
+)
+
+// Scripts run in the Lua 5.1 of internal/lua, with the libraries that Redis gives them: the base,
+// string, table and math libraries, with math.random seeded the same way every time, and bit,
+// cjson, cmsgpack and struct.
+
+// The names that errors give the source of scripts and of function libraries, like Redis.
+const (
+	luaScriptChunk   = "user_script"
+	luaFunctionChunk = "user_function"
+)
+
+// luaLibraries are the global tables of libraries, which scripts can't change.
+var luaLibraries = []string{
+	"string", "table", "math", "bit", "cjson", "cmsgpack", "struct", "redis",
+}
+
+// compileLua compiles source, whose errors say that they're in chunk.
+func compileLua(source, chunk string) (*lua.FunctionProto, error) {
+	tree, err := parse.Parse(strings.NewReader(source), chunk)
+	if err != nil {
+		var syntaxErr *parse.Error
+		if !errors.As(err, &syntaxErr) {
+			return nil, err
+		}
+		line, token := syntaxErr.Pos.Line, "'"+syntaxErr.Token+"'"
+		if line == parse.EOF {
+			line, token = strings.Count(source, "\n")+1, "'<eof>'"
+		}
+		return nil, fmt.Errorf("%s:%d: %s near %s", chunk, line,
+			strings.TrimSpace(syntaxErr.Message), token)
+	}
+	proto, err := lua.Compile(tree, chunk)
+	if err != nil {
+		var compileErr *lua.CompileError
+		if !errors.As(err, &compileErr) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%s:%d: %s", chunk, compileErr.Line, compileErr.Message)
+	}
+	return proto, nil
+}
+
+// newLuaState returns a Lua state with the libraries that scripts can use, and a redis table.
+// Its globals can still be set until protectLuaGlobals is called.
+func newLuaState() *lua.LState {
+	L := lua.NewState(lua.Options{SkipOpenLibs: true})
+	for _, library := range []struct {
+		name string
+		open lua.LGFunction
+	}{
+		{lua.BaseLibName, lua.OpenBase},
+		{lua.TabLibName, lua.OpenTable},
+		{lua.StringLibName, lua.OpenString},
+		{lua.MathLibName, lua.OpenMath},
+	} {
+		L.Push(L.NewFunction(library.open))
+		L.Push(lua.LString(library.name))
+		L.Call(1, 0)
+	}
+	// Scripts can't load files, or modules.
+	for _, name := range []string{"dofile", "loadfile", "module", "require", "_printregs",
+		"_GOPHER_LUA_VERSION"} {
+		L.SetGlobal(name, lua.LNil)
+	}
+	openLuaMath(L)
+	L.SetGlobal("bit", newLuaBitLibrary(L))
+	L.SetGlobal("cjson", newLuaCJSONLibrary(L))
+	L.SetGlobal("cmsgpack", newLuaCMsgpackLibrary(L))
+	L.SetGlobal("struct", newLuaStructLibrary(L))
+	L.SetGlobal("redis", L.NewTable())
+	return L
+}
+
+// openLuaMath replaces math.random and math.randomseed with ones of a generator of the state's
+// own, which is seeded the same way every time, so that scripts are deterministic like in Redis.
+func openLuaMath(L *lua.LState) {
+	random := rand.New(rand.NewSource(0))
+	library := L.GetGlobal("math").(*lua.LTable)
+	library.RawSetString("huge", lua.LNumber(math.Inf(1)))
+	library.RawSetString("random", L.NewFunction(func(L *lua.LState) int {
+		var low, high int
+		switch L.GetTop() {
+		case 0:
+			L.Push(lua.LNumber(random.Float64()))
+			return 1
+		case 1:
+			low, high = 1, L.CheckInt(1)
+		case 2:
+			low, high = L.CheckInt(1), L.CheckInt(2)
+		default:
+			L.RaiseError("wrong number of arguments")
+		}
+		if low > high {
+			L.ArgError(L.GetTop(), "interval is empty")
+		}
+		L.Push(lua.LNumber(low + random.Intn(high-low+1)))
+		return 1
+	}))
+	library.RawSetString("randomseed", L.NewFunction(func(L *lua.LState) int {
+		random.Seed(int64(L.CheckInt(1)))
+		return 0
+	}))
+}
+
+// protectLuaGlobals makes the globals of L and its libraries readonly, like Redis, so that
+// scripts can't set globals or change the libraries, and reading a global that isn't set is an
+// error. The code that L runs afterwards sees them through readonly proxies.
+func protectLuaGlobals(L *lua.LState) {
+	globals := L.G.Global
+	for _, name := range luaLibraries {
+		library := globals.RawGetString(name).(*lua.LTable)
+		globals.RawSetString(name, luaReadonlyTable(L, library))
+	}
+	// Strings' methods are those of the string library, like in Lua.
+	L.GetMetatable(lua.LString("")).(*lua.LTable).RawSetString("__index",
+		globals.RawGetString("string"))
+	proxy := luaReadonlyTable(L, L.NewFunction(func(L *lua.LState) int {
+		name := L.Get(2)
+		value := globals.RawGet(name)
+		if value == lua.LNil {
+			L.RaiseError("Script attempted to access nonexistent global variable '%s'",
+				lua.LVAsString(name))
+		}
+		L.Push(value)
+		return 1
+	}))
+	globals.RawSetString("_G", proxy)
+	L.Env = proxy
+}
+
+// luaReadonlyTable returns a table that can't be changed, whose fields are looked up in index, a
+// table or a function.
+func luaReadonlyTable(L *lua.LState, index lua.LValue) *lua.LTable {
+	meta := L.NewTable()
+	meta.RawSetString("__index", index)
+	meta.RawSetString("__newindex", L.NewFunction(func(L *lua.LState) int {
+		L.RaiseError("Attempt to modify a readonly table")
+		return 0
+	}))
+	meta.RawSetString("__metatable", lua.LFalse)
+	proxy := L.NewTable()
+	L.SetMetatable(proxy, meta)
+	return proxy
+}
+
+// luaStringArray returns a table of values.
+func luaStringArray(L *lua.LState, values []string) *lua.LTable {
+	t := L.CreateTable(len(values), 0)
+	for _, value := range values {
+		t.Append(lua.LString(value))
+	}
+	return t
+}
+
+// luaErrorReply returns the table that stands for an error reply in Lua.
+func luaErrorReply(L *lua.LState, message string) *lua.LTable {
+	reply := L.NewTable()
+	reply.RawSetString("err", lua.LString(message))
+	return reply
+}
+
+// luaStatusReply returns the table that stands for a status reply in Lua.
+func luaStatusReply(L *lua.LState, status string) *lua.LTable {
+	reply := L.NewTable()
+	reply.RawSetString("ok", lua.LString(status))
+	return reply
+}
+
+// luaIsInteger returns true if n is a whole number.
+func luaIsInteger(n lua.LNumber) bool {
+	return float64(n) == math.Trunc(float64(n)) && !math.IsInf(float64(n), 0)
+}
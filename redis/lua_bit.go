@@ -0,0 +1,82 @@
+package redis
+
+import (
+	"math"
+	"math/bits"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/lua"
+)
+
+// newLuaBitLibrary returns the bit library of LuaBitOp, whose operations work on numbers as 32-bit
+// integers, and return them as signed ones.
+func newLuaBitLibrary(L *lua.LState) *lua.LTable {
+	library := L.NewTable()
+	unary := func(op func(uint32) uint32) lua.LGFunction {
+		return func(L *lua.LState) int {
+			L.Push(luaBitResult(op(luaCheckBit(L, 1))))
+			return 1
+		}
+	}
+	variadic := func(op func(a, b uint32) uint32) lua.LGFunction {
+		return func(L *lua.LState) int {
+			result := luaCheckBit(L, 1)
+			for i := 2; i <= L.GetTop(); i++ {
+				result = op(result, luaCheckBit(L, i))
+			}
+			L.Push(luaBitResult(result))
+			return 1
+		}
+	}
+	shift := func(op func(x uint32, n uint) uint32) lua.LGFunction {
+		return func(L *lua.LState) int {
+			L.Push(luaBitResult(op(luaCheckBit(L, 1), uint(luaCheckBit(L, 2)&31))))
+			return 1
+		}
+	}
+	L.SetFuncs(library, map[string]lua.LGFunction{
+		"tobit":   unary(func(x uint32) uint32 { return x }),
+		"bnot":    unary(func(x uint32) uint32 { return ^x }),
+		"bswap":   unary(bits.ReverseBytes32),
+		"band":    variadic(func(a, b uint32) uint32 { return a & b }),
+		"bor":     variadic(func(a, b uint32) uint32 { return a | b }),
+		"bxor":    variadic(func(a, b uint32) uint32 { return a ^ b }),
+		"lshift":  shift(func(x uint32, n uint) uint32 { return x << n }),
+		"rshift":  shift(func(x uint32, n uint) uint32 { return x >> n }),
+		"arshift": shift(func(x uint32, n uint) uint32 { return uint32(int32(x) >> n) }),
+		"rol":     shift(func(x uint32, n uint) uint32 { return bits.RotateLeft32(x, int(n)) }),
+		"ror":     shift(func(x uint32, n uint) uint32 { return bits.RotateLeft32(x, -int(n)) }),
+		"tohex": func(L *lua.LState) int {
+			x := luaCheckBit(L, 1)
+			n := int32(8)
+			if L.GetTop() >= 2 {
+				n = int32(luaCheckBit(L, 2))
+			}
+			digits := "0123456789abcdef"
+			if n < 0 {
+				digits, n = "0123456789ABCDEF", -n
+			}
+			if n > 8 || n < 0 {
+				n = 8
+			}
+			hex := make([]byte, n)
+			for i := n - 1; i >= 0; i-- {
+				hex[i] = digits[x&15]
+				x >>= 4
+			}
+			L.Push(lua.LString(hex))
+			return 1
+		},
+	})
+	return library
+}
+
+// luaCheckBit returns argument n of the function running in L, which must be a number, as a
+// 32-bit integer. Like LuaBitOp, it's rounded to an integer, and taken modulo 2^32.
+func luaCheckBit(L *lua.LState, n int) uint32 {
+	x := math.RoundToEven(float64(L.CheckNumber(n)))
+	return uint32(int64(math.Mod(x, 1<<32)))
+}
+
+func luaBitResult(x uint32) lua.LNumber {
+	return lua.LNumber(int32(x))
+}
@@ -0,0 +1,589 @@
+package redis
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/lua"
+)
+
+// luaCJSON is an instance of the cjson library of lua-cjson, which converts between Lua values
+// and JSON, with its settings.
+type luaCJSON struct {
+	// null is cjson.null, which stands for JSON's null in tables.
+	null *lua.LUserData
+	// Arrays that are sparser than sparseRatio times as many elements as their length, and
+	// longer than sparseSafe, are encoded as objects if sparseConvert is true, or else fail.
+	sparseConvert   bool
+	sparseRatio     int
+	sparseSafe      int
+	encodeMaxDepth  int
+	decodeMaxDepth  int
+	numberPrecision int
+	// keepBuffer is only reported, since there's no buffer to keep between calls.
+	keepBuffer bool
+	// encodeInvalid is true if infinity and NaN are encoded, as null if encodeInvalidNull is
+	// true, and decodeInvalid if they're decoded, as well as hexadecimal numbers.
+	encodeInvalid     bool
+	encodeInvalidNull bool
+	decodeInvalid     bool
+}
+
+// newLuaCJSONLibrary returns the cjson library, with lua-cjson's default settings.
+func newLuaCJSONLibrary(L *lua.LState) *lua.LTable {
+	return newLuaCJSON(L.NewUserData()).table(L)
+}
+
+// newLuaCJSON returns an instance with lua-cjson's default settings, whose cjson.null is null.
+func newLuaCJSON(null *lua.LUserData) *luaCJSON {
+	return &luaCJSON{
+		null:            null,
+		sparseRatio:     2,
+		sparseSafe:      10,
+		encodeMaxDepth:  1000,
+		decodeMaxDepth:  1000,
+		numberPrecision: 14,
+		keepBuffer:      true,
+		decodeInvalid:   true,
+	}
+}
+
+func (c *luaCJSON) table(L *lua.LState) *lua.LTable {
+	library := L.NewTable()
+	L.SetFuncs(library, map[string]lua.LGFunction{
+		"encode": c.encode,
+		"decode": c.decode,
+		"new": func(L *lua.LState) int {
+			L.Push(newLuaCJSON(c.null).table(L))
+			return 1
+		},
+		"encode_sparse_array": func(L *lua.LState) int {
+			c.sparseConvert = luaCJSONOptBool(L, 1, c.sparseConvert)
+			c.sparseRatio = luaCJSONOptInt(L, 2, c.sparseRatio, 0, math.MaxInt32)
+			c.sparseSafe = luaCJSONOptInt(L, 3, c.sparseSafe, 0, math.MaxInt32)
+			L.Push(lua.LBool(c.sparseConvert))
+			L.Push(lua.LNumber(c.sparseRatio))
+			L.Push(lua.LNumber(c.sparseSafe))
+			return 3
+		},
+		"encode_max_depth": func(L *lua.LState) int {
+			c.encodeMaxDepth = luaCJSONOptInt(L, 1, c.encodeMaxDepth, 1, math.MaxInt32)
+			L.Push(lua.LNumber(c.encodeMaxDepth))
+			return 1
+		},
+		"decode_max_depth": func(L *lua.LState) int {
+			c.decodeMaxDepth = luaCJSONOptInt(L, 1, c.decodeMaxDepth, 1, math.MaxInt32)
+			L.Push(lua.LNumber(c.decodeMaxDepth))
+			return 1
+		},
+		"encode_number_precision": func(L *lua.LState) int {
+			c.numberPrecision = luaCJSONOptInt(L, 1, c.numberPrecision, 1, 14)
+			L.Push(lua.LNumber(c.numberPrecision))
+			return 1
+		},
+		"encode_keep_buffer": func(L *lua.LState) int {
+			c.keepBuffer = luaCJSONOptBool(L, 1, c.keepBuffer)
+			L.Push(lua.LBool(c.keepBuffer))
+			return 1
+		},
+		"encode_invalid_numbers": func(L *lua.LState) int {
+			if s, ok := L.Get(1).(lua.LString); ok && s == "null" {
+				c.encodeInvalid, c.encodeInvalidNull = true, true
+			} else {
+				c.encodeInvalid = luaCJSONOptBool(L, 1, c.encodeInvalid)
+				c.encodeInvalidNull = false
+			}
+			if c.encodeInvalidNull {
+				L.Push(lua.LString("null"))
+			} else {
+				L.Push(lua.LBool(c.encodeInvalid))
+			}
+			return 1
+		},
+		"decode_invalid_numbers": func(L *lua.LState) int {
+			c.decodeInvalid = luaCJSONOptBool(L, 1, c.decodeInvalid)
+			L.Push(lua.LBool(c.decodeInvalid))
+			return 1
+		},
+	})
+	library.RawSetString("null", c.null)
+	library.RawSetString("_NAME", lua.LString("cjson"))
+	library.RawSetString("_VERSION", lua.LString("2.1.0"))
+	return library
+}
+
+// luaCJSONOptBool returns argument n of the function running in L, a boolean or "on" or "off",
+// or value if there's none.
+func luaCJSONOptBool(L *lua.LState, n int, value bool) bool {
+	switch arg := L.Get(n).(type) {
+	case *lua.LNilType:
+		return value
+	case lua.LBool:
+		return bool(arg)
+	case lua.LString:
+		switch arg {
+		case "on":
+			return true
+		case "off":
+			return false
+		}
+	}
+	L.ArgError(n, "invalid option")
+	return value
+}
+
+// luaCJSONOptInt returns argument n of the function running in L, an integer from low to high,
+// or value if there's none.
+func luaCJSONOptInt(L *lua.LState, n, value, low, high int) int {
+	if L.Get(n) == lua.LNil {
+		return value
+	}
+	value = L.CheckInt(n)
+	if value < low || value > high {
+		L.ArgError(n, fmt.Sprintf("expected integer between %d and %d", low, high))
+	}
+	return value
+}
+
+// encode implements cjson.encode(value), which returns value in JSON.
+func (c *luaCJSON) encode(L *lua.LState) int {
+	if L.GetTop() != 1 {
+		L.ArgError(1, "expected 1 argument")
+	}
+	var b strings.Builder
+	c.encodeValue(L, &b, L.Get(1), 0)
+	L.Push(lua.LString(b.String()))
+	return 1
+}
+
+func (c *luaCJSON) encodeValue(L *lua.LState, b *strings.Builder, value lua.LValue, depth int) {
+	switch value := value.(type) {
+	case *lua.LNilType:
+		b.WriteString("null")
+	case lua.LBool:
+		b.WriteString(value.String())
+	case lua.LNumber:
+		c.encodeNumber(L, b, value)
+	case lua.LString:
+		encodeJSONString(b, string(value))
+	case *lua.LTable:
+		depth++
+		if depth > c.encodeMaxDepth {
+			L.RaiseError("Cannot serialise, excessive nesting (%d)", depth)
+		}
+		if n := c.arrayLength(L, value); n > 0 {
+			b.WriteByte('[')
+			for i := 1; i <= n; i++ {
+				if i > 1 {
+					b.WriteByte(',')
+				}
+				c.encodeValue(L, b, value.RawGetInt(i), depth)
+			}
+			b.WriteByte(']')
+			return
+		}
+		b.WriteByte('{')
+		first := true
+		value.ForEach(func(key, element lua.LValue) {
+			if !first {
+				b.WriteByte(',')
+			}
+			first = false
+			switch key := key.(type) {
+			case lua.LNumber:
+				b.WriteByte('"')
+				c.encodeNumber(L, b, key)
+				b.WriteByte('"')
+			case lua.LString:
+				encodeJSONString(b, string(key))
+			default:
+				L.RaiseError("Cannot serialise %s: table key must be a number or string",
+					key.Type().String())
+			}
+			b.WriteByte(':')
+			c.encodeValue(L, b, element, depth)
+		})
+		b.WriteByte('}')
+	default:
+		if value == c.null {
+			b.WriteString("null")
+			return
+		}
+		L.RaiseError("Cannot serialise %s: type not supported", value.Type().String())
+	}
+}
+
+func (c *luaCJSON) encodeNumber(L *lua.LState, b *strings.Builder, n lua.LNumber) {
+	f := float64(n)
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		switch {
+		case !c.encodeInvalid:
+			L.RaiseError("Cannot serialise number: must not be NaN or Inf")
+		case c.encodeInvalidNull:
+			b.WriteString("null")
+			return
+		case math.IsNaN(f):
+			b.WriteString("nan")
+			return
+		case f > 0:
+			b.WriteString("inf")
+			return
+		}
+		b.WriteString("-inf")
+		return
+	}
+	b.WriteString(strconv.FormatFloat(f, 'g', c.numberPrecision, 64))
+}
+
+// arrayLength returns the length of t if it's an array, whose keys are all positive integers,
+// or else -1. Sparse arrays are objects, or fail, depending on the settings.
+func (c *luaCJSON) arrayLength(L *lua.LState, t *lua.LTable) int {
+	items, max := 0, 0
+	array := true
+	t.ForEach(func(key, _ lua.LValue) {
+		n, ok := key.(lua.LNumber)
+		if !ok || n < 1 || !luaIsInteger(n) {
+			array = false
+			return
+		}
+		items++
+		if int(n) > max {
+			max = int(n)
+		}
+	})
+	switch {
+	case !array:
+		return -1
+	case c.sparseRatio > 0 && max > items*c.sparseRatio && max > c.sparseSafe:
+		if !c.sparseConvert {
+			L.RaiseError("Cannot serialise table: excessively sparse array")
+		}
+		return -1
+	}
+	return max
+}
+
+// encodeJSONString writes s to b as a JSON string, escaped like lua-cjson does.
+func encodeJSONString(b *strings.Builder, s string) {
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '"', '\\', '/':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			if c < 0x20 || c == 0x7f {
+				fmt.Fprintf(b, `\u%04x`, c)
+			} else {
+				b.WriteByte(c)
+			}
+		}
+	}
+	b.WriteByte('"')
+}
+
+// decode implements cjson.decode(json), which returns the Lua value of json.
+func (c *luaCJSON) decode(L *lua.LState) int {
+	if L.GetTop() != 1 {
+		L.ArgError(1, "expected 1 argument")
+	}
+	d := &cjsonDecoder{L: L, cjson: c, json: L.CheckString(1)}
+	value := d.value(d.next(), 0)
+	if token := d.next(); token.kind != cjsonEnd {
+		d.fail("the end", token)
+	}
+	L.Push(value)
+	return 1
+}
+
+// The kinds of the tokens of JSON, named like lua-cjson names them in errors.
+const (
+	cjsonObjectBegin = "T_OBJ_BEGIN"
+	cjsonObjectEnd   = "T_OBJ_END"
+	cjsonArrayBegin  = "T_ARR_BEGIN"
+	cjsonArrayEnd    = "T_ARR_END"
+	cjsonString      = "T_STRING"
+	cjsonNumber      = "T_NUMBER"
+	cjsonBoolean     = "T_BOOLEAN"
+	cjsonNull        = "T_NULL"
+	cjsonColon       = "T_COLON"
+	cjsonComma       = "T_COMMA"
+	cjsonEnd         = "T_END"
+	cjsonError       = "T_ERROR"
+)
+
+type cjsonToken struct {
+	kind string
+	// offset is where the token starts in the JSON.
+	offset int
+	value  lua.LValue
+	// err is what's wrong with a token of kind cjsonError.
+	err string
+}
+
+// cjsonDecoder decodes JSON into Lua values, raising errors like lua-cjson.
+type cjsonDecoder struct {
+	L      *lua.LState
+	cjson  *luaCJSON
+	json   string
+	offset int
+}
+
+// fail raises the error for finding token when what was expected.
+func (d *cjsonDecoder) fail(expected string, token cjsonToken) {
+	found := token.kind
+	if token.kind == cjsonError {
+		found = token.err
+	}
+	d.L.RaiseError("Expected %s but found %s at character %d", expected, found, token.offset+1)
+}
+
+func (d *cjsonDecoder) value(token cjsonToken, depth int) lua.LValue {
+	switch token.kind {
+	case cjsonString, cjsonNumber, cjsonBoolean, cjsonNull:
+		return token.value
+	case cjsonObjectBegin:
+		return d.object(token, depth+1)
+	case cjsonArrayBegin:
+		return d.array(token, depth+1)
+	}
+	d.fail("value", token)
+	return lua.LNil
+}
+
+func (d *cjsonDecoder) checkDepth(token cjsonToken, depth int) {
+	if depth > d.cjson.decodeMaxDepth {
+		d.L.RaiseError("Found too many nested data structures (%d) at character %d", depth,
+			token.offset+1)
+	}
+}
+
+func (d *cjsonDecoder) object(begin cjsonToken, depth int) lua.LValue {
+	d.checkDepth(begin, depth)
+	t := d.L.NewTable()
+	token := d.next()
+	if token.kind == cjsonObjectEnd {
+		return t
+	}
+	for {
+		if token.kind != cjsonString {
+			d.fail("object key string", token)
+		}
+		key := token.value
+		if token = d.next(); token.kind != cjsonColon {
+			d.fail("colon", token)
+		}
+		t.RawSet(key, d.value(d.next(), depth))
+		token = d.next()
+		switch token.kind {
+		case cjsonObjectEnd:
+			return t
+		case cjsonComma:
+			token = d.next()
+		default:
+			d.fail("comma or object end", token)
+		}
+	}
+}
+
+func (d *cjsonDecoder) array(begin cjsonToken, depth int) lua.LValue {
+	d.checkDepth(begin, depth)
+	t := d.L.NewTable()
+	token := d.next()
+	if token.kind == cjsonArrayEnd {
+		return t
+	}
+	for i := 1; ; i++ {
+		t.RawSetInt(i, d.value(token, depth))
+		token = d.next()
+		switch token.kind {
+		case cjsonArrayEnd:
+			return t
+		case cjsonComma:
+			token = d.next()
+		default:
+			d.fail("comma or array end", token)
+		}
+	}
+}
+
+// next returns the next token of the JSON.
+func (d *cjsonDecoder) next() cjsonToken {
+	for d.offset < len(d.json) && strings.IndexByte(" \t\n\r", d.json[d.offset]) >= 0 {
+		d.offset++
+	}
+	token := cjsonToken{offset: d.offset}
+	if d.offset == len(d.json) {
+		token.kind = cjsonEnd
+		return token
+	}
+	if kind, ok := map[byte]string{
+		'{': cjsonObjectBegin, '}': cjsonObjectEnd, '[': cjsonArrayBegin, ']': cjsonArrayEnd,
+		':': cjsonColon, ',': cjsonComma,
+	}[d.json[d.offset]]; ok {
+		d.offset++
+		token.kind = kind
+		return token
+	}
+	if d.json[d.offset] == '"' {
+		return d.string(token)
+	}
+	for _, literal := range []struct {
+		text  string
+		kind  string
+		value lua.LValue
+	}{
+		{"true", cjsonBoolean, lua.LTrue},
+		{"false", cjsonBoolean, lua.LFalse},
+		{"null", cjsonNull, d.cjson.null},
+	} {
+		if strings.HasPrefix(d.json[d.offset:], literal.text) {
+			d.offset += len(literal.text)
+			token.kind, token.value = literal.kind, literal.value
+			return token
+		}
+	}
+	if c := d.json[d.offset]; c == '-' || '0' <= c && c <= '9' ||
+		d.cjson.decodeInvalid && strings.IndexByte("iInN", c) >= 0 {
+		return d.number(token)
+	}
+	token.kind, token.err = cjsonError, "invalid token"
+	return token
+}
+
+// number reads a number. Like JSON, numbers can't start with "+" or a leading zero, and
+// infinity, NaN and hexadecimal numbers are only allowed if invalid numbers are.
+func (d *cjsonDecoder) number(token cjsonToken) cjsonToken {
+	rest := d.json[d.offset:]
+	sign := ""
+	if strings.HasPrefix(rest, "-") {
+		sign, rest = "-", rest[1:]
+	}
+	number := func(length int, n float64) cjsonToken {
+		d.offset += len(sign) + length
+		token.kind, token.value = cjsonNumber, lua.LNumber(n)
+		return token
+	}
+	if d.cjson.decodeInvalid {
+		for _, word := range []string{"inf", "nan"} {
+			if len(rest) >= len(word) && strings.EqualFold(rest[:len(word)], word) {
+				n, _ := strconv.ParseFloat(sign+word, 64)
+				return number(len(word), n)
+			}
+		}
+		if len(rest) > 2 && rest[0] == '0' && (rest[1] == 'x' || rest[1] == 'X') {
+			end := 2 + len(rest[2:]) - len(strings.TrimLeft(rest[2:], "0123456789abcdefABCDEF"))
+			if n, err := strconv.ParseUint(rest[2:end], 16, 64); err == nil {
+				if sign != "" {
+					return number(end, -float64(n))
+				}
+				return number(end, float64(n))
+			}
+		}
+	}
+	end := len(rest) - len(strings.TrimLeft(rest, "+-0123456789.eE"))
+	n, err := strconv.ParseFloat(sign+rest[:end], 64)
+	if err != nil && !errors.Is(err, strconv.ErrRange) || strings.HasPrefix(rest, "+") ||
+		len(rest) > 1 && rest[0] == '0' && '0' <= rest[1] && rest[1] <= '9' {
+		token.kind, token.err = cjsonError, "invalid number"
+		return token
+	}
+	return number(end, n)
+}
+
+// string reads a string, with JSON's escapes.
+func (d *cjsonDecoder) string(token cjsonToken) cjsonToken {
+	var b strings.Builder
+	i := d.offset + 1
+	for {
+		if i == len(d.json) {
+			token.kind, token.err = cjsonError, "unexpected end of string"
+			return token
+		}
+		c := d.json[i]
+		if c == '"' {
+			break
+		}
+		if c != '\\' {
+			b.WriteByte(c)
+			i++
+			continue
+		}
+		if i+1 == len(d.json) {
+			token.kind, token.err = cjsonError, "unexpected end of string"
+			return token
+		}
+		switch escape := d.json[i+1]; escape {
+		case '"', '\\', '/':
+			b.WriteByte(escape)
+		case 'b':
+			b.WriteByte('\b')
+		case 'f':
+			b.WriteByte('\f')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case 'u':
+			r, n := d.codepoint(i)
+			if n == 0 {
+				token.kind, token.err = cjsonError, "invalid unicode escape code"
+				return token
+			}
+			b.WriteRune(r)
+			i += n
+			continue
+		default:
+			token.kind, token.err = cjsonError, "invalid escape code"
+			return token
+		}
+		i += 2
+	}
+	d.offset = i + 1
+	token.kind, token.value = cjsonString, lua.LString(b.String())
+	return token
+}
+
+// codepoint reads the \u escape at offset i, and the one for a surrogate pair after it, if any,
+// returning the code point and the length of the escapes, or 0 if they're invalid.
+func (d *cjsonDecoder) codepoint(i int) (rune, int) {
+	hex := func(i int) (rune, bool) {
+		if i+6 > len(d.json) || d.json[i+1] != 'u' {
+			return 0, false
+		}
+		n, err := strconv.ParseUint(d.json[i+2:i+6], 16, 16)
+		return rune(n), err == nil
+	}
+	r, ok := hex(i)
+	switch {
+	case !ok:
+		return 0, 0
+	case utf16.IsSurrogate(r):
+		if d.json[i+6:] == "" || d.json[i+6] != '\\' {
+			return 0, 0
+		}
+		low, ok := hex(i + 6)
+		if pair := utf16.DecodeRune(r, low); ok && pair != utf8.RuneError {
+			return pair, 12
+		}
+		return 0, 0
+	}
+	return r, 6
+}
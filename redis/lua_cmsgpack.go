@@ -0,0 +1,304 @@
+package redis
+
+import (
+	"encoding/binary"
+	"math"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/lua"
+)
+
+// luaMsgpackMaxNesting is how deeply tables can be nested in what cmsgpack.pack packs, beyond
+// which they're packed as nil.
+const luaMsgpackMaxNesting = 16
+
+// The errors of cmsgpack.unpack for data that isn't MessagePack.
+const (
+	errLuaMsgpackMissingBytes = "Missing bytes in input."
+	errLuaMsgpackBadFormat    = "Bad data format in input."
+)
+
+// newLuaCMsgpackLibrary returns the cmsgpack library of lua-cmsgpack, which converts between Lua
+// values and MessagePack.
+func newLuaCMsgpackLibrary(L *lua.LState) *lua.LTable {
+	library := L.NewTable()
+	L.SetFuncs(library, map[string]lua.LGFunction{
+		"pack": luaMsgpackPack,
+		"unpack": func(L *lua.LState) int {
+			return luaMsgpackUnpack(L, -1, 0, true)
+		},
+		"unpack_one": func(L *lua.LState) int {
+			return luaMsgpackUnpack(L, 1, L.OptInt(2, 0), false)
+		},
+		"unpack_limit": func(L *lua.LState) int {
+			return luaMsgpackUnpack(L, L.CheckInt(2), L.OptInt(3, 0), false)
+		},
+	})
+	library.RawSetString("_NAME", lua.LString("cmsgpack"))
+	library.RawSetString("_VERSION", lua.LString("lua-cmsgpack 0.4.0"))
+	return library
+}
+
+// luaMsgpackPack implements cmsgpack.pack(...), which returns its arguments packed one after
+// the other.
+func luaMsgpackPack(L *lua.LState) int {
+	if L.GetTop() == 0 {
+		L.ArgError(0, "MessagePack pack needs input.")
+	}
+	var b []byte
+	for i := 1; i <= L.GetTop(); i++ {
+		b = appendMsgpack(b, L.Get(i), 0)
+	}
+	L.Push(lua.LString(b))
+	return 1
+}
+
+// appendMsgpack appends value, nested in level tables, to b in MessagePack.
+func appendMsgpack(b []byte, value lua.LValue, level int) []byte {
+	switch value := value.(type) {
+	case lua.LBool:
+		if value {
+			return append(b, 0xc3)
+		}
+		return append(b, 0xc2)
+	case lua.LNumber:
+		n := float64(value)
+		if !math.IsInf(n, 0) && float64(int64(n)) == n {
+			return appendMsgpackInteger(b, int64(n))
+		}
+		if f := float32(n); float64(f) == n {
+			return binary.BigEndian.AppendUint32(append(b, 0xca), math.Float32bits(f))
+		}
+		return binary.BigEndian.AppendUint64(append(b, 0xcb), math.Float64bits(n))
+	case lua.LString:
+		switch n := len(value); {
+		case n < 32:
+			b = append(b, 0xa0|byte(n))
+		case n <= math.MaxUint8:
+			b = append(b, 0xd9, byte(n))
+		case n <= math.MaxUint16:
+			b = binary.BigEndian.AppendUint16(append(b, 0xda), uint16(n))
+		default:
+			b = binary.BigEndian.AppendUint32(append(b, 0xdb), uint32(n))
+		}
+		return append(b, value...)
+	case *lua.LTable:
+		if level >= luaMsgpackMaxNesting {
+			break
+		}
+		if luaIsArray(value) {
+			n := value.Len()
+			b = appendMsgpackHeader(b, n, 0x90, 0xdc)
+			for i := 1; i <= n; i++ {
+				b = appendMsgpack(b, value.RawGetInt(i), level+1)
+			}
+			return b
+		}
+		n := 0
+		value.ForEach(func(lua.LValue, lua.LValue) { n++ })
+		b = appendMsgpackHeader(b, n, 0x80, 0xde)
+		value.ForEach(func(key, element lua.LValue) {
+			b = appendMsgpack(b, key, level+1)
+			b = appendMsgpack(b, element, level+1)
+		})
+		return b
+	}
+	return append(b, 0xc0)
+}
+
+// luaIsArray returns true if the keys of t are the integers from 1 up to some number, or it's
+// empty.
+func luaIsArray(t *lua.LTable) bool {
+	count, max := 0, 0
+	array := true
+	t.ForEach(func(key, _ lua.LValue) {
+		n, ok := key.(lua.LNumber)
+		if !ok || n <= 0 || !luaIsInteger(n) {
+			array = false
+			return
+		}
+		count++
+		if int(n) > max {
+			max = int(n)
+		}
+	})
+	return array && count == max
+}
+
+func appendMsgpackInteger(b []byte, n int64) []byte {
+	switch {
+	case n >= 0 && n <= math.MaxInt8:
+		return append(b, byte(n))
+	case n >= 0 && n <= math.MaxUint8:
+		return append(b, 0xcc, byte(n))
+	case n >= 0 && n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, 0xcd), uint16(n))
+	case n >= 0 && n <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(b, 0xce), uint32(n))
+	case n >= 0:
+		return binary.BigEndian.AppendUint64(append(b, 0xcf), uint64(n))
+	case n >= -32:
+		return append(b, byte(n))
+	case n >= math.MinInt8:
+		return append(b, 0xd0, byte(n))
+	case n >= math.MinInt16:
+		return binary.BigEndian.AppendUint16(append(b, 0xd1), uint16(n))
+	case n >= math.MinInt32:
+		return binary.BigEndian.AppendUint32(append(b, 0xd2), uint32(n))
+	}
+	return binary.BigEndian.AppendUint64(append(b, 0xd3), uint64(n))
+}
+
+// appendMsgpackHeader appends the header of an array or map of n elements, whose type is fix
+// for up to 15 elements, or else the 16-bit type, or the 32-bit type after it.
+func appendMsgpackHeader(b []byte, n int, fix, type16 byte) []byte {
+	switch {
+	case n <= 15:
+		return append(b, fix|byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, type16), uint16(n))
+	}
+	return binary.BigEndian.AppendUint32(append(b, type16+1), uint32(n))
+}
+
+// luaMsgpackUnpack unpacks up to limit values, or all of them if all is true, from the
+// MessagePack of the first argument from offset, pushing them. Unless all is true, it pushes the
+// offset after them first, or -1 if there's nothing left.
+func luaMsgpackUnpack(L *lua.LState, limit, offset int, all bool) int {
+	data := L.CheckString(1)
+	switch {
+	case offset < 0 || limit < 0 && !all:
+		L.RaiseError("Invalid request to unpack with offset of %d and limit of %d.", offset, limit)
+	case offset > len(data):
+		L.RaiseError("Start offset %d greater than input length %d.", offset, len(data))
+	}
+	d := &msgpackDecoder{L: L, data: data[offset:]}
+	var values []lua.LValue
+	for len(d.data) > 0 && (all || len(values) < limit) {
+		values = append(values, d.decode())
+		if d.err != "" {
+			L.RaiseError(d.err)
+		}
+	}
+	if !all {
+		next := len(data) - len(d.data)
+		if len(d.data) == 0 {
+			next = -1
+		}
+		L.Push(lua.LNumber(next))
+	}
+	for _, value := range values {
+		L.Push(value)
+	}
+	if all {
+		return len(values)
+	}
+	return len(values) + 1
+}
+
+// msgpackDecoder decodes Lua values from MessagePack data, setting err if it can't.
+type msgpackDecoder struct {
+	L    *lua.LState
+	data string
+	err  string
+}
+
+// take returns the next n bytes of the data, or nil if there aren't that many.
+func (d *msgpackDecoder) take(n uint64) []byte {
+	if d.err != "" || n > uint64(len(d.data)) {
+		if d.err == "" {
+			d.err = errLuaMsgpackMissingBytes
+		}
+		return nil
+	}
+	b := []byte(d.data[:n])
+	d.data = d.data[n:]
+	return b
+}
+
+// uint returns the next n-byte big-endian integer of the data.
+func (d *msgpackDecoder) uint(n uint64) uint64 {
+	var value uint64
+	for _, c := range d.take(n) {
+		value = value<<8 | uint64(c)
+	}
+	return value
+}
+
+func (d *msgpackDecoder) decode() lua.LValue {
+	t := d.take(1)
+	if t == nil {
+		return lua.LNil
+	}
+	switch c := t[0]; {
+	case c <= 0x7f:
+		return lua.LNumber(c)
+	case c >= 0xe0:
+		return lua.LNumber(int8(c))
+	case c&0xe0 == 0xa0:
+		return d.string(uint64(c & 0x1f))
+	case c&0xf0 == 0x90:
+		return d.array(uint64(c & 0x0f))
+	case c&0xf0 == 0x80:
+		return d.table(uint64(c & 0x0f))
+	case c == 0xc0:
+		return lua.LNil
+	case c == 0xc2:
+		return lua.LFalse
+	case c == 0xc3:
+		return lua.LTrue
+	case c == 0xcc, c == 0xcd, c == 0xce, c == 0xcf:
+		return lua.LNumber(d.uint(1 << (c - 0xcc)))
+	case c == 0xd0:
+		return lua.LNumber(int8(d.uint(1)))
+	case c == 0xd1:
+		return lua.LNumber(int16(d.uint(2)))
+	case c == 0xd2:
+		return lua.LNumber(int32(d.uint(4)))
+	case c == 0xd3:
+		return lua.LNumber(int64(d.uint(8)))
+	case c == 0xca:
+		return lua.LNumber(math.Float32frombits(uint32(d.uint(4))))
+	case c == 0xcb:
+		return lua.LNumber(math.Float64frombits(d.uint(8)))
+	case c == 0xc4, c == 0xd9:
+		return d.string(d.uint(1))
+	case c == 0xc5, c == 0xda:
+		return d.string(d.uint(2))
+	case c == 0xc6, c == 0xdb:
+		return d.string(d.uint(4))
+	case c == 0xdc:
+		return d.array(d.uint(2))
+	case c == 0xdd:
+		return d.array(d.uint(4))
+	case c == 0xde:
+		return d.table(d.uint(2))
+	case c == 0xdf:
+		return d.table(d.uint(4))
+	}
+	d.err = errLuaMsgpackBadFormat
+	return lua.LNil
+}
+
+func (d *msgpackDecoder) string(n uint64) lua.LValue {
+	return lua.LString(d.take(n))
+}
+
+func (d *msgpackDecoder) array(n uint64) lua.LValue {
+	t := d.L.NewTable()
+	for i := uint64(1); i <= n && d.err == ""; i++ {
+		t.RawSetInt(int(i), d.decode())
+	}
+	return t
+}
+
+func (d *msgpackDecoder) table(n uint64) lua.LValue {
+	t := d.L.NewTable()
+	for i := uint64(0); i < n && d.err == ""; i++ {
+		key := d.decode()
+		value := d.decode()
+		if key != lua.LNil {
+			t.RawSet(key, value)
+		}
+	}
+	return t
+}
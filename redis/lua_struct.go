@@ -0,0 +1,301 @@
+package redis
+
+import (
+	"encoding/binary"
+	"math"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/lua"
+)
+
+// The limits of the formats of the struct library: the largest integer, in bytes, and the
+// alignment that "!" sets without a number.
+const (
+	luaStructMaxIntSize = 32
+	luaStructMaxAlign   = 8
+)
+
+// luaStructFormat reads the format string of struct.pack, struct.unpack or struct.size, keeping
+// the options that it sets: the byte order, and the alignment.
+type luaStructFormat struct {
+	L      *lua.LState
+	format string
+	order  interface {
+		binary.ByteOrder
+		binary.AppendByteOrder
+	}
+	align int
+}
+
+// newLuaStructLibrary returns the struct library, which converts between Lua values and strings
+// of C structs, described by format strings of options like "<i4".
+func newLuaStructLibrary(L *lua.LState) *lua.LTable {
+	library := L.NewTable()
+	L.SetFuncs(library, map[string]lua.LGFunction{
+		"pack":   luaStructPack,
+		"unpack": luaStructUnpack,
+		"size":   luaStructSize,
+	})
+	return library
+}
+
+// next returns the next option of the format and its size, or 0 at the end of the format.
+func (f *luaStructFormat) next() (byte, int) {
+	if f.format == "" {
+		return 0, 0
+	}
+	option := f.format[0]
+	f.format = f.format[1:]
+	switch option {
+	case 'b', 'B', 'x':
+		return option, 1
+	case 'h', 'H':
+		return option, 2
+	case 'l', 'L', 'T', 'd':
+		return option, 8
+	case 'f':
+		return option, 4
+	case 'c':
+		return option, f.number(1)
+	case 'i', 'I':
+		size := f.number(4)
+		if size > luaStructMaxIntSize {
+			f.L.RaiseError("integral size %d is larger than limit of %d", size,
+				luaStructMaxIntSize)
+		}
+		return option, size
+	}
+	return option, 0
+}
+
+// number reads the number that follows an option, returning n if there's none.
+func (f *luaStructFormat) number(n int) int {
+	digits := len(f.format) - len(strings.TrimLeft(f.format, "0123456789"))
+	if digits == 0 {
+		return n
+	}
+	n = 0
+	for _, c := range f.format[:digits] {
+		if n > (math.MaxInt32-int(c-'0'))/10 {
+			f.L.RaiseError("integral size overflow")
+		}
+		n = n*10 + int(c-'0')
+	}
+	f.format = f.format[digits:]
+	return n
+}
+
+// padding returns how many bytes to pad offset with to align a value of the option and size.
+func (f *luaStructFormat) padding(offset int, option byte, size int) int {
+	if size == 0 || option == 'c' {
+		return 0
+	}
+	if size > f.align {
+		size = f.align
+	}
+	return (size - offset&(size-1)) & (size - 1)
+}
+
+// control handles an option that isn't of a value, which sets the byte order or the alignment.
+func (f *luaStructFormat) control(option byte) {
+	switch option {
+	case ' ':
+	case '>':
+		f.order = binary.BigEndian
+	case '<', '=':
+		f.order = binary.LittleEndian
+	case '!':
+		align := f.number(luaStructMaxAlign)
+		if align&(align-1) != 0 {
+			f.L.RaiseError("alignment %d is not a power of 2", align)
+		}
+		f.align = align
+	default:
+		f.L.ArgError(1, "invalid format option '"+string(option)+"'")
+	}
+}
+
+// putInteger appends n to b as an integer of size bytes.
+func (f *luaStructFormat) putInteger(b []byte, n lua.LNumber, size int) []byte {
+	var value uint64
+	if n < 0 {
+		value = uint64(int64(n))
+	} else {
+		value = uint64(n)
+	}
+	buf := make([]byte, size)
+	for i := 0; i < size; i++ {
+		j := i
+		if f.order == binary.BigEndian {
+			j = size - 1 - i
+		}
+		buf[j] = byte(value)
+		value >>= 8
+	}
+	return append(b, buf...)
+}
+
+// integer returns the integer of data, signed or not.
+func (f *luaStructFormat) integer(data string, signed bool) lua.LNumber {
+	var value uint64
+	for i := 0; i < len(data); i++ {
+		j := len(data) - 1 - i
+		if f.order == binary.BigEndian {
+			j = i
+		}
+		value = value<<8 | uint64(data[j])
+	}
+	if !signed {
+		return lua.LNumber(value)
+	}
+	if mask := ^uint64(0) << (len(data)*8 - 1); value&mask != 0 {
+		value |= mask
+	}
+	return lua.LNumber(int64(value))
+}
+
+func newLuaStructFormat(L *lua.LState) *luaStructFormat {
+	return &luaStructFormat{
+		L:      L,
+		format: L.CheckString(1),
+		order:  binary.LittleEndian,
+		align:  1,
+	}
+}
+
+// luaStructPack implements struct.pack(format, ...), which returns the string of the struct of
+// the values.
+func luaStructPack(L *lua.LState) int {
+	f := newLuaStructFormat(L)
+	var b []byte
+	arg := 2
+	for {
+		option, size := f.next()
+		if option == 0 {
+			break
+		}
+		for padding := f.padding(len(b), option, size); padding > 0; padding-- {
+			b = append(b, 0)
+		}
+		switch option {
+		case 'b', 'B', 'h', 'H', 'l', 'L', 'T', 'i', 'I':
+			b = f.putInteger(b, L.CheckNumber(arg), size)
+			arg++
+		case 'x':
+			b = append(b, 0)
+		case 'f':
+			b = f.order.AppendUint32(b, math.Float32bits(float32(L.CheckNumber(arg))))
+			arg++
+		case 'd':
+			b = f.order.AppendUint64(b, math.Float64bits(float64(L.CheckNumber(arg))))
+			arg++
+		case 'c', 's':
+			s := L.CheckString(arg)
+			if size == 0 {
+				size = len(s)
+			}
+			if len(s) < size {
+				L.ArgError(arg, "string too short")
+			}
+			b = append(b, s[:size]...)
+			if option == 's' {
+				b = append(b, 0)
+			}
+			arg++
+		default:
+			f.control(option)
+		}
+	}
+	L.Push(lua.LString(b))
+	return 1
+}
+
+// luaStructUnpack implements struct.unpack(format, data, [offset]), which returns the values of
+// the struct in data from offset, and the offset after it.
+func luaStructUnpack(L *lua.LState) int {
+	f := newLuaStructFormat(L)
+	data := L.CheckString(2)
+	offset := L.OptInt(3, 1) - 1
+	if offset < 0 || offset > len(data) {
+		L.ArgError(3, "offset must be 1 or greater")
+	}
+	n := 0
+	for {
+		option, size := f.next()
+		if option == 0 {
+			break
+		}
+		offset += f.padding(offset, option, size)
+		if size > len(data) || offset > len(data)-size {
+			L.ArgError(2, "data string too short")
+		}
+		switch option {
+		case 'b', 'B', 'h', 'H', 'l', 'L', 'T', 'i', 'I':
+			signed := 'a' <= option && option <= 'z'
+			L.Push(f.integer(data[offset:offset+size], signed))
+			n++
+		case 'x':
+		case 'f':
+			bits := f.order.Uint32([]byte(data[offset : offset+size]))
+			L.Push(lua.LNumber(math.Float32frombits(bits)))
+			n++
+		case 'd':
+			bits := f.order.Uint64([]byte(data[offset : offset+size]))
+			L.Push(lua.LNumber(math.Float64frombits(bits)))
+			n++
+		case 'c':
+			if size == 0 {
+				previous, ok := L.Get(-1).(lua.LNumber)
+				if n == 0 || !ok {
+					L.RaiseError("format 'c0' needs a previous size")
+				}
+				size = int(previous)
+				L.Pop(1)
+				n--
+				if size < 0 || size > len(data) || offset > len(data)-size {
+					L.ArgError(2, "data string too short")
+				}
+			}
+			L.Push(lua.LString(data[offset : offset+size]))
+			n++
+		case 's':
+			end := strings.IndexByte(data[offset:], 0)
+			if end < 0 {
+				L.RaiseError("unfinished string in data")
+			}
+			size = end + 1
+			L.Push(lua.LString(data[offset : offset+end]))
+			n++
+		default:
+			f.control(option)
+		}
+		offset += size
+	}
+	L.Push(lua.LNumber(offset + 1))
+	return n + 1
+}
+
+// luaStructSize implements struct.size(format), which returns the size of the struct.
+func luaStructSize(L *lua.LState) int {
+	f := newLuaStructFormat(L)
+	offset := 0
+	for {
+		option, size := f.next()
+		if option == 0 {
+			break
+		}
+		offset += f.padding(offset, option, size)
+		switch {
+		case option == 's':
+			L.ArgError(1, "options 's' has no fixed size")
+		case option == 'c' && size == 0:
+			L.ArgError(1, "options 'c0' has no fixed size")
+		case !('a' <= option && option <= 'z' || 'A' <= option && option <= 'Z' ||
+			'0' <= option && option <= '9'):
+			f.control(option)
+		}
+		offset += size
+	}
+	L.Push(lua.LNumber(offset))
+	return 1
+}
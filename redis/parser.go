@@ -3,24 +3,60 @@ package redis
 import (
 	"bufio"
 	"errors"
+	"fmt"
 	"io"
 	"strconv"
 	"strings"
 	"time"
 )
 
+// NewParser returns a Parser for commands on store, whose keyspace events it has published with
+// its publish/subscribe broker.
 func NewParser(config *Config, store *Store, clock Clock) Parser {
+	pubSub := NewPubSub(clock, config.PubSubOutputBufferLimit)
+	store.NotifyKeyspaceEvents(pubSub, config.NotifyKeyspaceEvents)
 	return Parser{
-		config: config,
-		store:  store,
-		clock:  clock,
+		config:  config,
+		store:   store,
+		clock:   clock,
+		clients: NewClients(),
+		pubSub:  pubSub,
+		scripts: NewScripts(),
+		shutdown: &shutdown{
+			done: make(chan struct{}),
+		},
 	}
 }
 
 type Parser struct {
-	config *Config
-	store  *Store
-	clock  Clock
+	config  *Config
+	store   *Store
+	clock   Clock
+	clients *Clients
+	pubSub  *PubSub
+	scripts *Scripts
+	// shutdown is closed once a client has sent SHUTDOWN.
+	shutdown *shutdown
+	// client is the client that requests are parsed on behalf of, if any.
+	client *Client
+}
+
+// NewClient registers a new client connection. Use ForClient to parse its requests.
+func (p Parser) NewClient() *Client {
+	return p.clients.NewClient()
+}
+
+// ShutdownRequested returns a channel that's closed once a client has sent SHUTDOWN, when the
+// server should exit.
+func (p Parser) ShutdownRequested() <-chan struct{} {
+	return p.shutdown.done
+}
+
+// ForClient returns a copy of p that parses requests on behalf of client, which commands like
+// BLPOP and CLIENT ID need.
+func (p Parser) ForClient(client *Client) Parser {
+	p.client = client
+	return p
 }
 
 func (p Parser) Parse(reader io.Reader) (Command, error) {
@@ -34,11 +70,25 @@ func (p Parser) Parse(reader io.Reader) (Command, error) {
 	case '*':
 		return p.processArrayRequest(bufReader)
 	default:
-		// TODO: return error that server.go can match on
-		panic("unexpected")
+		return p.processInlineRequest(bufReader)
 	}
 }
 
+// processInlineRequest parses an inline request, like redis-cli over telnet sends: a line of
+// arguments separated by spaces.
+func (p Parser) processInlineRequest(bufReader *bufio.Reader) (Command, error) {
+	line, err := bufReader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	array := strings.Fields(line)
+	if len(array) == 0 {
+		// Like Redis, empty requests are skipped.
+		return p.Parse(bufReader)
+	}
+	return p.processRequest(array)
+}
+
 func (p Parser) processArrayRequest(bufReader *bufio.Reader) (Command, error) {
 	array, err := readArray(bufReader)
 	if err != nil {
@@ -46,26 +96,481 @@ func (p Parser) processArrayRequest(bufReader *bufio.Reader) (Command, error) {
 	}
 
 	if len(array) == 0 {
-		// TODO: return error that server.go can match on
+		// Like Redis, empty requests are skipped.
+		return p.Parse(bufReader)
 	}
+	return p.processRequest(array)
+}
 
+// processRequest returns the command for the request array, whose first element is the name of
+// the command and the rest its arguments.
+func (p Parser) processRequest(array []string) (Command, error) {
+	if err := p.scripts.busy(p.clock.NowMonotonic(), p.config.BusyReplyThreshold); err != nil &&
+		!allowedWhenBusy(array) {
+		return nil, err
+	}
+	// RESP3 clients can be sent pushes alongside replies, so they can send any command while
+	// subscribed.
+	if p.subscribedRESP2() && !allowedWhenSubscribed(array[0]) {
+		return nil, CommandError(fmt.Sprintf(
+			"ERR Can't execute '%s': only (P|S)SUBSCRIBE / (P|S)UNSUBSCRIBE / PING / QUIT / "+
+				"RESET are allowed in this context",
+			strings.ToLower(array[0]),
+		))
+	}
+	if p.client != nil {
+		// The keys that the command reads are tracked for the client, depending on whether it
+		// sent CLIENT CACHING just before.
+		p.store = p.store.forClient(p.client, p.client.takeCaching())
+		if p.client.inTransaction() && !runInTransaction(array[0]) {
+			return p.queueRequest(array)
+		}
+	}
+	return p.newCommand(array)
+}
+
+// newCommand returns the command for the request array, without checking whether the client can
+// send it right now.
+func (p Parser) newCommand(array []string) (Command, error) {
 	switch {
+	case strings.EqualFold(array[0], "APPEND"):
+		return p.newAppendCommand(array)
+	case strings.EqualFold(array[0], "BF.ADD"):
+		return p.newBFAddCommand(array)
+	case strings.EqualFold(array[0], "BF.EXISTS"):
+		return p.newBFExistsCommand(array)
+	case strings.EqualFold(array[0], "BF.INFO"):
+		return p.newBFInfoCommand(array)
+	case strings.EqualFold(array[0], "BF.MADD"):
+		return p.newBFMAddCommand(array)
+	case strings.EqualFold(array[0], "BF.MEXISTS"):
+		return p.newBFMExistsCommand(array)
+	case strings.EqualFold(array[0], "BF.RESERVE"):
+		return p.newBFReserveCommand(array)
+	case strings.EqualFold(array[0], "BITCOUNT"):
+		return p.newBitCountCommand(array)
+	case strings.EqualFold(array[0], "BITFIELD"):
+		return p.newBitfieldCommand(array)
+	case strings.EqualFold(array[0], "BITFIELD_RO"):
+		return p.newBitfieldROCommand(array)
+	case strings.EqualFold(array[0], "BITOP"):
+		return p.newBitOpCommand(array)
+	case strings.EqualFold(array[0], "BITPOS"):
+		return p.newBitPosCommand(array)
+	case strings.EqualFold(array[0], "BLMOVE"):
+		return p.newBLMoveCommand(array)
+	case strings.EqualFold(array[0], "BLMPOP"):
+		return p.newBLMPopCommand(array)
+	case strings.EqualFold(array[0], "BLPOP"):
+		return p.newBLPopCommand(array)
+	case strings.EqualFold(array[0], "BRPOP"):
+		return p.newBRPopCommand(array)
+	case strings.EqualFold(array[0], "BRPOPLPUSH"):
+		return p.newBRPopLPushCommand(array)
+	case strings.EqualFold(array[0], "BZPOPMAX"):
+		return p.newBZPopMaxCommand(array)
+	case strings.EqualFold(array[0], "BZPOPMIN"):
+		return p.newBZPopMinCommand(array)
+	case strings.EqualFold(array[0], "CF.ADD"):
+		return p.newCFAddCommand(array)
+	case strings.EqualFold(array[0], "CF.ADDNX"):
+		return p.newCFAddNXCommand(array)
+	case strings.EqualFold(array[0], "CF.COUNT"):
+		return p.newCFCountCommand(array)
+	case strings.EqualFold(array[0], "CF.DEL"):
+		return p.newCFDelCommand(array)
+	case strings.EqualFold(array[0], "CF.EXISTS"):
+		return p.newCFExistsCommand(array)
+	case strings.EqualFold(array[0], "CF.INFO"):
+		return p.newCFInfoCommand(array)
+	case strings.EqualFold(array[0], "CF.MEXISTS"):
+		return p.newCFMExistsCommand(array)
+	case strings.EqualFold(array[0], "CF.RESERVE"):
+		return p.newCFReserveCommand(array)
+	case strings.EqualFold(array[0], "CLIENT"):
+		return p.newClientCommand(array)
+	case strings.EqualFold(array[0], "CMS.INCRBY"):
+		return p.newCMSIncrByCommand(array)
+	case strings.EqualFold(array[0], "CMS.INFO"):
+		return p.newCMSInfoCommand(array)
+	case strings.EqualFold(array[0], "CMS.INITBYDIM"):
+		return p.newCMSInitByDimCommand(array)
+	case strings.EqualFold(array[0], "CMS.INITBYPROB"):
+		return p.newCMSInitByProbCommand(array)
+	case strings.EqualFold(array[0], "CMS.MERGE"):
+		return p.newCMSMergeCommand(array)
+	case strings.EqualFold(array[0], "CMS.QUERY"):
+		return p.newCMSQueryCommand(array)
+	case strings.EqualFold(array[0], "DISCARD"):
+		return p.newDiscardCommand(array)
 	case strings.EqualFold(array[0], "ECHO"):
 		return p.makeEchoCommand(array)
+	case strings.EqualFold(array[0], "EVAL"):
+		return p.newEvalCommand(array)
+	case strings.EqualFold(array[0], "EVALSHA"):
+		return p.newEvalShaCommand(array)
+	case strings.EqualFold(array[0], "EXEC"):
+		return p.newExecCommand(array)
+	case strings.EqualFold(array[0], "FCALL"):
+		return p.newFCallCommand(array)
+	case strings.EqualFold(array[0], "FCALL_RO"):
+		return p.newFCallROCommand(array)
+	case strings.EqualFold(array[0], "FUNCTION"):
+		return p.newFunctionCommand(array)
+	case strings.EqualFold(array[0], "GEOADD"):
+		return p.newGeoAddCommand(array)
+	case strings.EqualFold(array[0], "GEODIST"):
+		return p.newGeoDistCommand(array)
+	case strings.EqualFold(array[0], "GEOHASH"):
+		return p.newGeoHashCommand(array)
+	case strings.EqualFold(array[0], "GEOPOS"):
+		return p.newGeoPosCommand(array)
+	case strings.EqualFold(array[0], "GEOSEARCH"):
+		return p.newGeoSearchCommand(array)
+	case strings.EqualFold(array[0], "GEOSEARCHSTORE"):
+		return p.newGeoSearchStoreCommand(array)
 	case strings.EqualFold(array[0], "GET"):
 		return p.newGetCommand(array)
+	case strings.EqualFold(array[0], "GETBIT"):
+		return p.newGetBitCommand(array)
+	case strings.EqualFold(array[0], "GETDEL"):
+		return p.newGetDelCommand(array)
+	case strings.EqualFold(array[0], "GETEX"):
+		return p.newGetExCommand(array)
+	case strings.EqualFold(array[0], "GETRANGE"):
+		return p.newGetRangeCommand(array)
+	case strings.EqualFold(array[0], "GETSET"):
+		return p.newGetSetCommand(array)
+	case strings.EqualFold(array[0], "HDEL"):
+		return p.newHDelCommand(array)
+	case strings.EqualFold(array[0], "HELLO"):
+		return p.newHelloCommand(array)
+	case strings.EqualFold(array[0], "HEXISTS"):
+		return p.newHExistsCommand(array)
+	case strings.EqualFold(array[0], "HEXPIRE"):
+		return p.newHExpireCommand(array)
+	case strings.EqualFold(array[0], "HEXPIREAT"):
+		return p.newHExpireAtCommand(array)
+	case strings.EqualFold(array[0], "HEXPIRETIME"):
+		return p.newHExpireTimeCommand(array)
+	case strings.EqualFold(array[0], "HGET"):
+		return p.newHGetCommand(array)
+	case strings.EqualFold(array[0], "HGETALL"):
+		return p.newHGetAllCommand(array)
+	case strings.EqualFold(array[0], "HINCRBY"):
+		return p.newHIncrByCommand(array)
+	case strings.EqualFold(array[0], "HINCRBYFLOAT"):
+		return p.newHIncrByFloatCommand(array)
+	case strings.EqualFold(array[0], "HKEYS"):
+		return p.newHKeysCommand(array)
+	case strings.EqualFold(array[0], "HLEN"):
+		return p.newHLenCommand(array)
+	case strings.EqualFold(array[0], "HMGET"):
+		return p.newHMGetCommand(array)
+	case strings.EqualFold(array[0], "HMSET"):
+		return p.newHMSetCommand(array)
+	case strings.EqualFold(array[0], "HPERSIST"):
+		return p.newHPersistCommand(array)
+	case strings.EqualFold(array[0], "HPEXPIRE"):
+		return p.newHPExpireCommand(array)
+	case strings.EqualFold(array[0], "HPEXPIREAT"):
+		return p.newHPExpireAtCommand(array)
+	case strings.EqualFold(array[0], "HPEXPIRETIME"):
+		return p.newHPExpireTimeCommand(array)
+	case strings.EqualFold(array[0], "HPTTL"):
+		return p.newHPTTLCommand(array)
+	case strings.EqualFold(array[0], "HRANDFIELD"):
+		return p.newHRandFieldCommand(array)
+	case strings.EqualFold(array[0], "HSCAN"):
+		return p.newHScanCommand(array)
+	case strings.EqualFold(array[0], "HSET"):
+		return p.newHSetCommand(array)
+	case strings.EqualFold(array[0], "HSETNX"):
+		return p.newHSetNXCommand(array)
+	case strings.EqualFold(array[0], "HSTRLEN"):
+		return p.newHStrLenCommand(array)
+	case strings.EqualFold(array[0], "HTTL"):
+		return p.newHTTLCommand(array)
+	case strings.EqualFold(array[0], "HVALS"):
+		return p.newHValsCommand(array)
 	case strings.EqualFold(array[0], "INFO"):
 		return p.makeInfoCommand(array)
+	case strings.EqualFold(array[0], "JSON.ARRAPPEND"):
+		return p.newJSONArrAppendCommand(array)
+	case strings.EqualFold(array[0], "JSON.ARRPOP"):
+		return p.newJSONArrPopCommand(array)
+	case strings.EqualFold(array[0], "JSON.DEL"):
+		return p.newJSONDelCommand(array)
+	case strings.EqualFold(array[0], "JSON.GET"):
+		return p.newJSONGetCommand(array)
+	case strings.EqualFold(array[0], "JSON.MGET"):
+		return p.newJSONMGetCommand(array)
+	case strings.EqualFold(array[0], "JSON.NUMINCRBY"):
+		return p.newJSONNumIncrByCommand(array)
+	case strings.EqualFold(array[0], "JSON.OBJKEYS"):
+		return p.newJSONObjKeysCommand(array)
+	case strings.EqualFold(array[0], "JSON.SET"):
+		return p.newJSONSetCommand(array)
+	case strings.EqualFold(array[0], "JSON.STRAPPEND"):
+		return p.newJSONStrAppendCommand(array)
+	case strings.EqualFold(array[0], "JSON.TYPE"):
+		return p.newJSONTypeCommand(array)
+	case strings.EqualFold(array[0], "LCS"):
+		return p.newLCSCommand(array)
+	case strings.EqualFold(array[0], "LINDEX"):
+		return p.newLIndexCommand(array)
+	case strings.EqualFold(array[0], "LINSERT"):
+		return p.newLInsertCommand(array)
+	case strings.EqualFold(array[0], "LLEN"):
+		return p.newLLenCommand(array)
+	case strings.EqualFold(array[0], "LMOVE"):
+		return p.newLMoveCommand(array)
+	case strings.EqualFold(array[0], "LMPOP"):
+		return p.newLMPopCommand(array)
+	case strings.EqualFold(array[0], "LPOP"):
+		return p.newLPopCommand(array)
+	case strings.EqualFold(array[0], "LPOS"):
+		return p.newLPosCommand(array)
+	case strings.EqualFold(array[0], "LPUSH"):
+		return p.newLPushCommand(array)
+	case strings.EqualFold(array[0], "LPUSHX"):
+		return p.newLPushXCommand(array)
+	case strings.EqualFold(array[0], "LRANGE"):
+		return p.newLRangeCommand(array)
+	case strings.EqualFold(array[0], "LREM"):
+		return p.newLRemCommand(array)
+	case strings.EqualFold(array[0], "LSET"):
+		return p.newLSetCommand(array)
+	case strings.EqualFold(array[0], "LTRIM"):
+		return p.newLTrimCommand(array)
+	case strings.EqualFold(array[0], "MGET"):
+		return p.newMGetCommand(array)
+	case strings.EqualFold(array[0], "MSET"):
+		return p.newMSetCommand(array)
+	case strings.EqualFold(array[0], "MSETNX"):
+		return p.newMSetNXCommand(array)
+	case strings.EqualFold(array[0], "MULTI"):
+		return p.newMultiCommand(array)
+	case strings.EqualFold(array[0], "OBJECT"):
+		return p.newObjectCommand(array)
+	case strings.EqualFold(array[0], "PFADD"):
+		return p.newPFAddCommand(array)
+	case strings.EqualFold(array[0], "PFCOUNT"):
+		return p.newPFCountCommand(array)
+	case strings.EqualFold(array[0], "PFDEBUG"):
+		return p.newPFDebugCommand(array)
+	case strings.EqualFold(array[0], "PFMERGE"):
+		return p.newPFMergeCommand(array)
+	case strings.EqualFold(array[0], "PFSELFTEST"):
+		return p.newPFSelfTestCommand(array)
 	case strings.EqualFold(array[0], "PING"):
 		return p.makePingCommand(array)
+	case strings.EqualFold(array[0], "PSUBSCRIBE"):
+		return p.newPSubscribeCommand(array)
+	case strings.EqualFold(array[0], "PUBLISH"):
+		return p.newPublishCommand(array)
+	case strings.EqualFold(array[0], "PUBSUB"):
+		return p.newPubSubCommand(array)
+	case strings.EqualFold(array[0], "PUNSUBSCRIBE"):
+		return p.newPUnsubscribeCommand(array)
+	case strings.EqualFold(array[0], "QUIT"):
+		return p.newQuitCommand(array)
+	case strings.EqualFold(array[0], "RESET"):
+		return p.newResetCommand(array)
+	case strings.EqualFold(array[0], "RPOP"):
+		return p.newRPopCommand(array)
+	case strings.EqualFold(array[0], "RPOPLPUSH"):
+		return p.newRPopLPushCommand(array)
+	case strings.EqualFold(array[0], "RPUSH"):
+		return p.newRPushCommand(array)
+	case strings.EqualFold(array[0], "RPUSHX"):
+		return p.newRPushXCommand(array)
+	case strings.EqualFold(array[0], "SADD"):
+		return p.newSAddCommand(array)
+	case strings.EqualFold(array[0], "SCARD"):
+		return p.newSCardCommand(array)
+	case strings.EqualFold(array[0], "SCRIPT"):
+		return p.newScriptCommand(array)
+	case strings.EqualFold(array[0], "SDIFF"):
+		return p.newSDiffCommand(array)
+	case strings.EqualFold(array[0], "SDIFFSTORE"):
+		return p.newSDiffStoreCommand(array)
 	case strings.EqualFold(array[0], "SET"):
 		return p.newSetCommand(array)
+	case strings.EqualFold(array[0], "SETBIT"):
+		return p.newSetBitCommand(array)
+	case strings.EqualFold(array[0], "SETRANGE"):
+		return p.newSetRangeCommand(array)
+	case strings.EqualFold(array[0], "SHUTDOWN"):
+		return p.newShutdownCommand(array)
+	case strings.EqualFold(array[0], "SINTER"):
+		return p.newSInterCommand(array)
+	case strings.EqualFold(array[0], "SINTERCARD"):
+		return p.newSInterCardCommand(array)
+	case strings.EqualFold(array[0], "SINTERSTORE"):
+		return p.newSInterStoreCommand(array)
+	case strings.EqualFold(array[0], "SISMEMBER"):
+		return p.newSIsMemberCommand(array)
+	case strings.EqualFold(array[0], "SMEMBERS"):
+		return p.newSMembersCommand(array)
+	case strings.EqualFold(array[0], "SMISMEMBER"):
+		return p.newSMIsMemberCommand(array)
+	case strings.EqualFold(array[0], "SMOVE"):
+		return p.newSMoveCommand(array)
+	case strings.EqualFold(array[0], "SPOP"):
+		return p.newSPopCommand(array)
+	case strings.EqualFold(array[0], "SPUBLISH"):
+		return p.newSPublishCommand(array)
+	case strings.EqualFold(array[0], "SRANDMEMBER"):
+		return p.newSRandMemberCommand(array)
+	case strings.EqualFold(array[0], "SREM"):
+		return p.newSRemCommand(array)
+	case strings.EqualFold(array[0], "SSCAN"):
+		return p.newSScanCommand(array)
+	case strings.EqualFold(array[0], "SSUBSCRIBE"):
+		return p.newSSubscribeCommand(array)
+	case strings.EqualFold(array[0], "STRLEN"):
+		return p.newStrLenCommand(array)
+	case strings.EqualFold(array[0], "SUBSCRIBE"):
+		return p.newSubscribeCommand(array)
+	case strings.EqualFold(array[0], "SUNION"):
+		return p.newSUnionCommand(array)
+	case strings.EqualFold(array[0], "SUNIONSTORE"):
+		return p.newSUnionStoreCommand(array)
+	case strings.EqualFold(array[0], "SUNSUBSCRIBE"):
+		return p.newSUnsubscribeCommand(array)
+	case strings.EqualFold(array[0], "TOPK.ADD"):
+		return p.newTopKAddCommand(array)
+	case strings.EqualFold(array[0], "TOPK.LIST"):
+		return p.newTopKListCommand(array)
+	case strings.EqualFold(array[0], "TOPK.QUERY"):
+		return p.newTopKQueryCommand(array)
+	case strings.EqualFold(array[0], "TOPK.RESERVE"):
+		return p.newTopKReserveCommand(array)
+	case strings.EqualFold(array[0], "TS.ADD"):
+		return p.newTSAddCommand(array)
+	case strings.EqualFold(array[0], "TS.CREATE"):
+		return p.newTSCreateCommand(array)
+	case strings.EqualFold(array[0], "TS.CREATERULE"):
+		return p.newTSCreateRuleCommand(array)
+	case strings.EqualFold(array[0], "TS.DECRBY"):
+		return p.newTSDecrByCommand(array)
+	case strings.EqualFold(array[0], "TS.DELETERULE"):
+		return p.newTSDeleteRuleCommand(array)
+	case strings.EqualFold(array[0], "TS.GET"):
+		return p.newTSGetCommand(array)
+	case strings.EqualFold(array[0], "TS.INCRBY"):
+		return p.newTSIncrByCommand(array)
+	case strings.EqualFold(array[0], "TS.MADD"):
+		return p.newTSMAddCommand(array)
+	case strings.EqualFold(array[0], "TS.MRANGE"):
+		return p.newTSMRangeCommand(array)
+	case strings.EqualFold(array[0], "TS.MREVRANGE"):
+		return p.newTSMRevRangeCommand(array)
+	case strings.EqualFold(array[0], "TS.RANGE"):
+		return p.newTSRangeCommand(array)
+	case strings.EqualFold(array[0], "TS.REVRANGE"):
+		return p.newTSRevRangeCommand(array)
+	case strings.EqualFold(array[0], "UNSUBSCRIBE"):
+		return p.newUnsubscribeCommand(array)
+	case strings.EqualFold(array[0], "UNWATCH"):
+		return p.newUnwatchCommand(array)
+	case strings.EqualFold(array[0], "WATCH"):
+		return p.newWatchCommand(array)
+	case strings.EqualFold(array[0], "XACK"):
+		return p.newXAckCommand(array)
+	case strings.EqualFold(array[0], "XADD"):
+		return p.newXAddCommand(array)
+	case strings.EqualFold(array[0], "XAUTOCLAIM"):
+		return p.newXAutoClaimCommand(array)
+	case strings.EqualFold(array[0], "XCLAIM"):
+		return p.newXClaimCommand(array)
+	case strings.EqualFold(array[0], "XDEL"):
+		return p.newXDelCommand(array)
+	case strings.EqualFold(array[0], "XGROUP"):
+		return p.newXGroupCommand(array)
+	case strings.EqualFold(array[0], "XINFO"):
+		return p.newXInfoCommand(array)
+	case strings.EqualFold(array[0], "XLEN"):
+		return p.newXLenCommand(array)
+	case strings.EqualFold(array[0], "XPENDING"):
+		return p.newXPendingCommand(array)
+	case strings.EqualFold(array[0], "XRANGE"):
+		return p.newXRangeCommand(array)
+	case strings.EqualFold(array[0], "XREAD"):
+		return p.newXReadCommand(array)
+	case strings.EqualFold(array[0], "XREADGROUP"):
+		return p.newXReadGroupCommand(array)
+	case strings.EqualFold(array[0], "XREVRANGE"):
+		return p.newXRevRangeCommand(array)
+	case strings.EqualFold(array[0], "XTRIM"):
+		return p.newXTrimCommand(array)
+	case strings.EqualFold(array[0], "ZADD"):
+		return p.newZAddCommand(array)
+	case strings.EqualFold(array[0], "ZCARD"):
+		return p.newZCardCommand(array)
+	case strings.EqualFold(array[0], "ZCOUNT"):
+		return p.newZCountCommand(array)
+	case strings.EqualFold(array[0], "ZDIFF"):
+		return p.newZDiffCommand(array)
+	case strings.EqualFold(array[0], "ZDIFFSTORE"):
+		return p.newZDiffStoreCommand(array)
+	case strings.EqualFold(array[0], "ZINCRBY"):
+		return p.newZIncrByCommand(array)
+	case strings.EqualFold(array[0], "ZINTER"):
+		return p.newZInterCommand(array)
+	case strings.EqualFold(array[0], "ZINTERSTORE"):
+		return p.newZInterStoreCommand(array)
+	case strings.EqualFold(array[0], "ZLEXCOUNT"):
+		return p.newZLexCountCommand(array)
+	case strings.EqualFold(array[0], "ZMSCORE"):
+		return p.newZMScoreCommand(array)
+	case strings.EqualFold(array[0], "ZPOPMAX"):
+		return p.newZPopMaxCommand(array)
+	case strings.EqualFold(array[0], "ZPOPMIN"):
+		return p.newZPopMinCommand(array)
+	case strings.EqualFold(array[0], "ZRANDMEMBER"):
+		return p.newZRandMemberCommand(array)
+	case strings.EqualFold(array[0], "ZRANGE"):
+		return p.newZRangeCommand(array)
+	case strings.EqualFold(array[0], "ZRANGEBYLEX"):
+		return p.newZRangeByLexCommand(array)
+	case strings.EqualFold(array[0], "ZRANGEBYSCORE"):
+		return p.newZRangeByScoreCommand(array)
+	case strings.EqualFold(array[0], "ZRANK"):
+		return p.newZRankCommand(array)
+	case strings.EqualFold(array[0], "ZREM"):
+		return p.newZRemCommand(array)
+	case strings.EqualFold(array[0], "ZREMRANGEBYLEX"):
+		return p.newZRemRangeByLexCommand(array)
+	case strings.EqualFold(array[0], "ZREMRANGEBYRANK"):
+		return p.newZRemRangeByRankCommand(array)
+	case strings.EqualFold(array[0], "ZREMRANGEBYSCORE"):
+		return p.newZRemRangeByScoreCommand(array)
+	case strings.EqualFold(array[0], "ZREVRANGE"):
+		return p.newZRevRangeCommand(array)
+	case strings.EqualFold(array[0], "ZREVRANGEBYLEX"):
+		return p.newZRevRangeByLexCommand(array)
+	case strings.EqualFold(array[0], "ZREVRANGEBYSCORE"):
+		return p.newZRevRangeByScoreCommand(array)
+	case strings.EqualFold(array[0], "ZREVRANK"):
+		return p.newZRevRankCommand(array)
+	case strings.EqualFold(array[0], "ZSCAN"):
+		return p.newZScanCommand(array)
+	case strings.EqualFold(array[0], "ZSCORE"):
+		return p.newZScoreCommand(array)
+	case strings.EqualFold(array[0], "ZUNION"):
+		return p.newZUnionCommand(array)
+	case strings.EqualFold(array[0], "ZUNIONSTORE"):
+		return p.newZUnionStoreCommand(array)
 	}
-	// TODO: return error that server.go can match on
-	panic("unexpected")
+	return nil, unknownCommandError(array)
 }
 
 func (p Parser) newSetCommand(array []string) (Command, error) {
+	if len(array) < 3 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
 	if len(array) == 5 {
 		if !strings.EqualFold(array[3], "PX") {
 			// TODO: return error that server.go can match on
@@ -89,16 +594,58 @@ func (p Parser) newSetCommand(array []string) (Command, error) {
 	return NewSetCommand(p.store, array[1], array[2]), nil
 }
 
+// subscribedRESP2 returns whether p parses requests on behalf of a RESP2 client that's subscribed
+// to a channel or pattern, which can only send the commands that manage subscriptions.
+func (p Parser) subscribedRESP2() bool {
+	return p.client != nil && p.client.protocol() == 2 && p.pubSub.subscribed(p.client)
+}
+
+// protocol returns the version of RESP that the client that p parses requests for speaks.
+func (p Parser) protocol() int {
+	if p.client == nil {
+		return 2
+	}
+	return p.client.protocol()
+}
+
 func (p Parser) makePingCommand(array []string) (Command, error) {
-	if len(array) != 1 {
-		// TODO: return error that server.go can match on
+	if len(array) > 2 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	if p.subscribedRESP2() {
+		var message string
+		if len(array) == 2 {
+			message = array[1]
+		}
+		return SubscribedPingCommand(message), nil
+	}
+	if len(array) == 2 {
+		return EchoCommand(array[1]), nil
 	}
 	return PingCommand{}, nil
 }
 
+func (p Parser) newShutdownCommand(array []string) (Command, error) {
+	var noSave, save bool
+	for _, arg := range array[1:] {
+		switch {
+		case strings.EqualFold(arg, "NOSAVE"):
+			noSave = true
+		case strings.EqualFold(arg, "SAVE"):
+			save = true
+		default:
+			return nil, errSyntax
+		}
+	}
+	if noSave && save {
+		return nil, errSyntax
+	}
+	return NewShutdownCommand(p, noSave), nil
+}
+
 func (p Parser) makeInfoCommand(array []string) (Command, error) {
-	if len(array) != 2 {
-		// TODO: return error that server.go can match on
+	if len(array) < 2 {
+		return nil, wrongNumberOfArgumentsError(array)
 	}
 	if array[1] != string(InfoKindReplication) {
 		// TODO: return error that server.go can match on
@@ -107,19 +654,83 @@ func (p Parser) makeInfoCommand(array []string) (Command, error) {
 }
 
 func (p Parser) newGetCommand(array []string) (Command, error) {
-	if len(array) != 2 {
-		// TODO: return error that server.go can match on
+	if len(array) < 2 {
+		return nil, wrongNumberOfArgumentsError(array)
 	}
 	return NewGetCommand(p.store, p.clock, array[1]), nil
 }
 
 func (p Parser) makeEchoCommand(array []string) (Command, error) {
 	if len(array) != 2 {
-		// TODO: return error that server.go can match on
+		return nil, wrongNumberOfArgumentsError(array)
 	}
 	return EchoCommand(array[1]), nil
 }
 
+func (p Parser) newObjectCommand(array []string) (Command, error) {
+	if len(array) < 2 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	if !strings.EqualFold(array[1], "ENCODING") {
+		return nil, CommandError(
+			fmt.Sprintf("ERR unknown subcommand '%s'. Try OBJECT HELP.", array[1]),
+		)
+	}
+	if len(array) != 3 {
+		return nil, wrongNumberOfArgumentsError([]string{"object|encoding"})
+	}
+	return NewObjectEncodingCommand(p.store, p.clock, array[2]), nil
+}
+
+// CommandError is returned by Parser.Parse when a request is valid RESP but isn't a valid
+// command, for example because it has the wrong number of arguments. The connection is still
+// usable afterwards; Response returns the Redis error reply to send back to the client.
+type CommandError string
+
+func (c CommandError) Error() string {
+	return string(c)
+}
+
+func (c CommandError) Response() string {
+	return simpleError(string(c))
+}
+
+const (
+	errSyntax     CommandError = "ERR syntax error"
+	errNotInteger CommandError = "ERR value is not an integer or out of range"
+)
+
+// unknownCommandError returns the error for the request array of a command that doesn't exist,
+// which quotes its arguments like Redis.
+func unknownCommandError(array []string) CommandError {
+	var args strings.Builder
+	for _, arg := range array[1:] {
+		fmt.Fprintf(&args, "'%s' ", arg)
+	}
+	return CommandError(fmt.Sprintf(
+		"ERR unknown command '%s', with args beginning with: %s", array[0], args.String(),
+	))
+}
+
+func wrongNumberOfArgumentsError(array []string) CommandError {
+	return CommandError(
+		fmt.Sprintf("ERR wrong number of arguments for '%s' command", strings.ToLower(array[0])),
+	)
+}
+
+// parseInteger parses s like Redis parses integer arguments, returning errNotInteger if s isn't
+// a base-10 64-bit integer.
+func parseInteger(s string) (int, error) {
+	if len(s) > 1 && s[0] == '+' {
+		return 0, errNotInteger
+	}
+	i, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, errNotInteger
+	}
+	return int(i), nil
+}
+
 func readArray(reader *bufio.Reader) ([]string, error) {
 	err := expect(reader, '*')
 	if err != nil {
@@ -0,0 +1,259 @@
+package redis
+
+import (
+	"strconv"
+	"strings"
+)
+
+// maxBitOffset is one more than the largest bit offset that can be set, so that strings don't
+// grow past maxStringLength.
+const maxBitOffset = maxStringLength * 8
+
+const errBitOffset CommandError = "ERR bit offset is not an integer or out of range"
+
+func (p Parser) newSetBitCommand(array []string) (Command, error) {
+	if len(array) != 4 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	offset, err := parseBitOffset(array[2], 1)
+	if err != nil {
+		return nil, err
+	}
+	if array[3] != "0" && array[3] != "1" {
+		return nil, CommandError("ERR bit is not an integer or out of range")
+	}
+	return NewSetBitCommand(p.store, p.clock, array[1], offset, array[3][0]-'0'), nil
+}
+
+func (p Parser) newGetBitCommand(array []string) (Command, error) {
+	if len(array) != 3 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	offset, err := parseBitOffset(array[2], 1)
+	if err != nil {
+		return nil, err
+	}
+	return NewGetBitCommand(p.store, p.clock, array[1], offset), nil
+}
+
+// parseBitOffset parses a bit offset for a field of the given number of bits. Like BITFIELD's
+// offsets, s may be prefixed with "#" to multiply it by bits.
+func parseBitOffset(s string, bits int) (int, error) {
+	multiplier := 1
+	if strings.HasPrefix(s, "#") {
+		multiplier = bits
+		s = s[1:]
+	}
+	offset, err := parseInteger(s)
+	if err != nil || offset < 0 || offset > (maxBitOffset-bits)/multiplier {
+		return 0, errBitOffset
+	}
+	return offset * multiplier, nil
+}
+
+func (p Parser) newBitCountCommand(array []string) (Command, error) {
+	if len(array) < 2 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	if len(array) == 2 {
+		return NewBitCountCommand(p.store, p.clock, array[1], nil), nil
+	}
+	if len(array) != 4 && len(array) != 5 {
+		return nil, errSyntax
+	}
+	bitRange, err := parseBitRange(array[2:])
+	if err != nil {
+		return nil, err
+	}
+	return NewBitCountCommand(p.store, p.clock, array[1], &bitRange), nil
+}
+
+// parseBitRange parses the "start end [BYTE|BIT]" arguments of BITCOUNT and BITPOS.
+func parseBitRange(args []string) (BitRange, error) {
+	start, err := parseInteger(args[0])
+	if err != nil {
+		return BitRange{}, err
+	}
+	end, err := parseInteger(args[1])
+	if err != nil {
+		return BitRange{}, err
+	}
+	result := BitRange{Start: start, End: end}
+	if len(args) == 3 {
+		switch {
+		case strings.EqualFold(args[2], "BIT"):
+			result.Bits = true
+		case strings.EqualFold(args[2], "BYTE"):
+		default:
+			return BitRange{}, errSyntax
+		}
+	}
+	return result, nil
+}
+
+func (p Parser) newBitPosCommand(array []string) (Command, error) {
+	if len(array) < 3 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	if len(array) > 6 {
+		return nil, errSyntax
+	}
+	bit, err := parseInteger(array[2])
+	if err != nil {
+		return nil, err
+	}
+	if bit != 0 && bit != 1 {
+		return nil, CommandError("ERR The bit argument must be 1 or 0.")
+	}
+
+	var start *int
+	var bitRange *BitRange
+	switch len(array) {
+	case 4:
+		s, err := parseInteger(array[3])
+		if err != nil {
+			return nil, err
+		}
+		start = &s
+	case 5, 6:
+		r, err := parseBitRange(array[3:])
+		if err != nil {
+			return nil, err
+		}
+		bitRange = &r
+	}
+	return NewBitPosCommand(p.store, p.clock, array[1], byte(bit), start, bitRange), nil
+}
+
+func (p Parser) newBitOpCommand(array []string) (Command, error) {
+	if len(array) < 4 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	var op BitOp
+	switch {
+	case strings.EqualFold(array[1], "AND"):
+		op = BitOpAnd
+	case strings.EqualFold(array[1], "OR"):
+		op = BitOpOr
+	case strings.EqualFold(array[1], "XOR"):
+		op = BitOpXor
+	case strings.EqualFold(array[1], "NOT"):
+		op = BitOpNot
+		if len(array) != 4 {
+			return nil, CommandError("ERR BITOP NOT must be called with a single source key.")
+		}
+	default:
+		return nil, errSyntax
+	}
+	return NewBitOpCommand(p.store, p.clock, op, array[2], array[3:]), nil
+}
+
+func (p Parser) newBitfieldCommand(array []string) (Command, error) {
+	if len(array) < 2 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	ops, err := parseBitfieldOps(array[2:])
+	if err != nil {
+		return nil, err
+	}
+	return NewBitfieldCommand(p.store, p.clock, array[1], ops), nil
+}
+
+func (p Parser) newBitfieldROCommand(array []string) (Command, error) {
+	if len(array) < 2 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	ops, err := parseBitfieldOps(array[2:])
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range ops {
+		if op.Kind != BitfieldGet {
+			return nil, CommandError("ERR BITFIELD_RO only supports the GET subcommand")
+		}
+	}
+	return NewBitfieldCommand(p.store, p.clock, array[1], ops), nil
+}
+
+// parseBitfieldOps parses the subcommands of BITFIELD. OVERFLOW applies to every SET and
+// INCRBY after it, so it's folded into those ops rather than being an op of its own.
+func parseBitfieldOps(args []string) ([]BitfieldOp, error) {
+	var ops []BitfieldOp
+	overflow := BitfieldOverflowWrap
+	for i := 0; i < len(args); i++ {
+		var kind BitfieldOpKind
+		argCount := 2
+		switch {
+		case strings.EqualFold(args[i], "GET"):
+			kind = BitfieldGet
+		case strings.EqualFold(args[i], "SET"):
+			kind = BitfieldSet
+			argCount = 3
+		case strings.EqualFold(args[i], "INCRBY"):
+			kind = BitfieldIncrBy
+			argCount = 3
+		case strings.EqualFold(args[i], "OVERFLOW") && i+1 < len(args):
+			switch {
+			case strings.EqualFold(args[i+1], "WRAP"):
+				overflow = BitfieldOverflowWrap
+			case strings.EqualFold(args[i+1], "SAT"):
+				overflow = BitfieldOverflowSat
+			case strings.EqualFold(args[i+1], "FAIL"):
+				overflow = BitfieldOverflowFail
+			default:
+				return nil, CommandError("ERR Invalid OVERFLOW type specified")
+			}
+			i++
+			continue
+		default:
+			return nil, errSyntax
+		}
+		if i+argCount >= len(args) {
+			return nil, errSyntax
+		}
+
+		signed, bits, err := parseBitfieldType(args[i+1])
+		if err != nil {
+			return nil, err
+		}
+		offset, err := parseBitOffset(args[i+2], bits)
+		if err != nil {
+			return nil, err
+		}
+		op := BitfieldOp{
+			Kind:     kind,
+			Signed:   signed,
+			Bits:     bits,
+			Offset:   offset,
+			Overflow: overflow,
+		}
+		if argCount == 3 {
+			value, err := strconv.ParseInt(args[i+3], 10, 64)
+			if err != nil {
+				return nil, errNotInteger
+			}
+			op.Value = value
+		}
+		ops = append(ops, op)
+		i += argCount
+	}
+	return ops, nil
+}
+
+// parseBitfieldType parses a BITFIELD type like i8 or u16. Signed types can be 1 to 64 bits wide,
+// but unsigned types can only be 1 to 63 bits wide since BITFIELD replies with signed integers.
+func parseBitfieldType(s string) (signed bool, bits int, err error) {
+	errType := CommandError(
+		"ERR Invalid bitfield type. Use something like i16 u8. " +
+			"Note that u64 is not supported but i64 is.",
+	)
+	if len(s) < 2 || (s[0] != 'i' && s[0] != 'I' && s[0] != 'u' && s[0] != 'U') {
+		return false, 0, errType
+	}
+	signed = s[0] == 'i' || s[0] == 'I'
+	bits, err = strconv.Atoi(s[1:])
+	if err != nil || bits < 1 || (signed && bits > 64) || (!signed && bits > 63) {
+		return false, 0, errType
+	}
+	return signed, bits, nil
+}
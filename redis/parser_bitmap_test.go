@@ -0,0 +1,167 @@
+package redis_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/codecrafters-io/redis-starter-go/redis"
+)
+
+func TestParser_ParseBitmapRequest(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{}
+
+	tests := []struct {
+		name    string
+		request string
+		want    redis.Command
+	}{
+		{
+			name:    "SETBIT k 7 1",
+			request: "*4\r\n$6\r\nSETBIT\r\n$1\r\nk\r\n$1\r\n7\r\n$1\r\n1\r\n",
+			want:    redis.NewSetBitCommand(store, clock, "k", 7, 1),
+		},
+		{
+			name:    "GETBIT k 7",
+			request: "*3\r\n$6\r\nGETBIT\r\n$1\r\nk\r\n$1\r\n7\r\n",
+			want:    redis.NewGetBitCommand(store, clock, "k", 7),
+		},
+		{
+			name:    "BITCOUNT k",
+			request: "*2\r\n$8\r\nBITCOUNT\r\n$1\r\nk\r\n",
+			want:    redis.NewBitCountCommand(store, clock, "k", nil),
+		},
+		{
+			name:    "BITCOUNT k 1 -1 bit",
+			request: "*5\r\n$8\r\nBITCOUNT\r\n$1\r\nk\r\n$1\r\n1\r\n$2\r\n-1\r\n$3\r\nbit\r\n",
+			want:    redis.NewBitCountCommand(store, clock, "k", &redis.BitRange{Start: 1, End: -1, Bits: true}),
+		},
+		{
+			name:    "BITPOS k 0 2",
+			request: "*4\r\n$6\r\nBITPOS\r\n$1\r\nk\r\n$1\r\n0\r\n$1\r\n2\r\n",
+			want:    redis.NewBitPosCommand(store, clock, "k", 0, ptr(2), nil),
+		},
+		{
+			name:    "BITPOS k 1 2 3 BYTE",
+			request: "*6\r\n$6\r\nBITPOS\r\n$1\r\nk\r\n$1\r\n1\r\n$1\r\n2\r\n$1\r\n3\r\n$4\r\nBYTE\r\n",
+			want:    redis.NewBitPosCommand(store, clock, "k", 1, nil, &redis.BitRange{Start: 2, End: 3}),
+		},
+		{
+			name:    "BITOP xor dest a b",
+			request: "*5\r\n$5\r\nBITOP\r\n$3\r\nxor\r\n$4\r\ndest\r\n$1\r\na\r\n$1\r\nb\r\n",
+			want:    redis.NewBitOpCommand(store, clock, redis.BitOpXor, "dest", []string{"a", "b"}),
+		},
+		{
+			name: "BITFIELD k GET u8 #1 OVERFLOW SAT INCRBY i16 3 -5",
+			request: "*11\r\n$8\r\nBITFIELD\r\n$1\r\nk\r\n$3\r\nGET\r\n$2\r\nu8\r\n$2\r\n#1\r\n" +
+				"$8\r\nOVERFLOW\r\n$3\r\nSAT\r\n$6\r\nINCRBY\r\n$3\r\ni16\r\n$1\r\n3\r\n$2\r\n-5\r\n",
+			want: redis.NewBitfieldCommand(store, clock, "k", []redis.BitfieldOp{
+				{Kind: redis.BitfieldGet, Bits: 8, Offset: 8},
+				{
+					Kind:     redis.BitfieldIncrBy,
+					Signed:   true,
+					Bits:     16,
+					Offset:   3,
+					Value:    -5,
+					Overflow: redis.BitfieldOverflowSat,
+				},
+			}),
+		},
+		{
+			name:    "BITFIELD_RO k GET i8 0",
+			request: "*5\r\n$11\r\nBITFIELD_RO\r\n$1\r\nk\r\n$3\r\nGET\r\n$2\r\ni8\r\n$1\r\n0\r\n",
+			want: redis.NewBitfieldCommand(store, clock, "k", []redis.BitfieldOp{
+				{Kind: redis.BitfieldGet, Signed: true, Bits: 8, Offset: 0},
+			}),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requestReader := strings.NewReader(tt.request)
+
+			command, err := redis.NewParser(zeroValueRedisConfig, store, clock).Parse(requestReader)
+
+			if err != nil {
+				t.Errorf("err: expected: nil; got: %v", err)
+			}
+			if !reflect.DeepEqual(command, tt.want) {
+				t.Errorf("command expected to be %#v but was %#v", tt.want, command)
+			}
+		})
+	}
+}
+
+func TestParser_ParseInvalidBitmapRequest(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		request string
+		err     redis.CommandError
+	}{
+		{
+			name:    "SETBIT k -1 1",
+			request: "*4\r\n$6\r\nSETBIT\r\n$1\r\nk\r\n$2\r\n-1\r\n$1\r\n1\r\n",
+			err:     "ERR bit offset is not an integer or out of range",
+		},
+		{
+			name:    "SETBIT k 4294967296 1",
+			request: "*4\r\n$6\r\nSETBIT\r\n$1\r\nk\r\n$10\r\n4294967296\r\n$1\r\n1\r\n",
+			err:     "ERR bit offset is not an integer or out of range",
+		},
+		{
+			name:    "SETBIT k 0 2",
+			request: "*4\r\n$6\r\nSETBIT\r\n$1\r\nk\r\n$1\r\n0\r\n$1\r\n2\r\n",
+			err:     "ERR bit is not an integer or out of range",
+		},
+		{
+			name:    "BITCOUNT k 0",
+			request: "*3\r\n$8\r\nBITCOUNT\r\n$1\r\nk\r\n$1\r\n0\r\n",
+			err:     "ERR syntax error",
+		},
+		{
+			name:    "BITPOS k 2",
+			request: "*3\r\n$6\r\nBITPOS\r\n$1\r\nk\r\n$1\r\n2\r\n",
+			err:     "ERR The bit argument must be 1 or 0.",
+		},
+		{
+			name:    "BITOP NOT dest a b",
+			request: "*5\r\n$5\r\nBITOP\r\n$3\r\nNOT\r\n$4\r\ndest\r\n$1\r\na\r\n$1\r\nb\r\n",
+			err:     "ERR BITOP NOT must be called with a single source key.",
+		},
+		{
+			name:    "BITFIELD k GET u64 0",
+			request: "*5\r\n$8\r\nBITFIELD\r\n$1\r\nk\r\n$3\r\nGET\r\n$3\r\nu64\r\n$1\r\n0\r\n",
+			err: "ERR Invalid bitfield type. Use something like i16 u8. " +
+				"Note that u64 is not supported but i64 is.",
+		},
+		{
+			name:    "BITFIELD k OVERFLOW NOPE",
+			request: "*4\r\n$8\r\nBITFIELD\r\n$1\r\nk\r\n$8\r\nOVERFLOW\r\n$4\r\nNOPE\r\n",
+			err:     "ERR Invalid OVERFLOW type specified",
+		},
+		{
+			name:    "BITFIELD_RO k SET u8 0 1",
+			request: "*6\r\n$11\r\nBITFIELD_RO\r\n$1\r\nk\r\n$3\r\nSET\r\n$2\r\nu8\r\n$1\r\n0\r\n$1\r\n1\r\n",
+			err:     "ERR BITFIELD_RO only supports the GET subcommand",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := redis.NewStore()
+			clock := FakeClock{}
+			requestReader := strings.NewReader(tt.request)
+
+			_, err := redis.NewParser(zeroValueRedisConfig, store, clock).Parse(requestReader)
+
+			if err != tt.err {
+				t.Errorf("err: expected: %v; got: %v", tt.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,195 @@
+package redis
+
+import (
+	"strconv"
+	"strings"
+)
+
+func (p Parser) newBFReserveCommand(array []string) (Command, error) {
+	if len(array) < 4 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	errorRate, err := strconv.ParseFloat(array[2], 64)
+	if err != nil {
+		return nil, CommandError("ERR bad error rate")
+	}
+	if errorRate <= 0 || errorRate >= 1 {
+		return nil, CommandError("ERR (0 < error rate range < 1)")
+	}
+	capacity, err := parseInteger(array[3])
+	if err != nil {
+		return nil, CommandError("ERR bad capacity")
+	}
+	if capacity <= 0 {
+		return nil, CommandError("ERR (capacity should be larger than 0)")
+	}
+
+	expansion := defaultBloomExpansion
+	expansionSet, nonScaling := false, false
+	for i := 4; i < len(array); i++ {
+		switch {
+		case strings.EqualFold(array[i], "NONSCALING"):
+			nonScaling = true
+		case strings.EqualFold(array[i], "EXPANSION") && i+1 < len(array):
+			i++
+			if expansion, err = parseInteger(array[i]); err != nil {
+				return nil, CommandError("ERR bad expansion")
+			}
+			if expansion < 1 {
+				return nil, CommandError("ERR expansion should be greater or equal to 1")
+			}
+			expansionSet = true
+		default:
+			return nil, errSyntax
+		}
+	}
+	if nonScaling {
+		if expansionSet {
+			return nil, CommandError("ERR Nonscaling filters cannot expand")
+		}
+		expansion = 0
+	}
+	return NewBFReserveCommand(p.store, p.clock, array[1], errorRate, capacity, expansion), nil
+}
+
+func (p Parser) newBFAddCommand(array []string) (Command, error) {
+	if len(array) != 3 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	return NewBFAddCommand(p.store, p.clock, array[1], array[2:]), nil
+}
+
+func (p Parser) newBFMAddCommand(array []string) (Command, error) {
+	if len(array) < 3 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	return NewBFMAddCommand(p.store, p.clock, array[1], array[2:]), nil
+}
+
+func (p Parser) newBFExistsCommand(array []string) (Command, error) {
+	if len(array) != 3 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	return NewBFExistsCommand(p.store, p.clock, array[1], array[2:]), nil
+}
+
+func (p Parser) newBFMExistsCommand(array []string) (Command, error) {
+	if len(array) < 3 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	return NewBFMExistsCommand(p.store, p.clock, array[1], array[2:]), nil
+}
+
+func (p Parser) newBFInfoCommand(array []string) (Command, error) {
+	if len(array) != 2 && len(array) != 3 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	field := BFInfoAll
+	if len(array) == 3 {
+		switch {
+		case strings.EqualFold(array[2], "CAPACITY"):
+			field = BFInfoCapacity
+		case strings.EqualFold(array[2], "SIZE"):
+			field = BFInfoSize
+		case strings.EqualFold(array[2], "FILTERS"):
+			field = BFInfoFilters
+		case strings.EqualFold(array[2], "ITEMS"):
+			field = BFInfoItems
+		case strings.EqualFold(array[2], "EXPANSION"):
+			field = BFInfoExpansion
+		default:
+			return nil, CommandError("ERR Invalid information value")
+		}
+	}
+	return NewBFInfoCommand(p.store, p.clock, array[1], field), nil
+}
+
+func (p Parser) newCFReserveCommand(array []string) (Command, error) {
+	if len(array) < 3 || len(array)%2 == 0 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	capacity, err := parseInteger(array[2])
+	if err != nil || capacity <= 0 {
+		return nil, CommandError("ERR Bad capacity")
+	}
+	bucketSize := defaultCuckooBucketSize
+	maxIterations := defaultCuckooMaxIterations
+	expansion := defaultCuckooExpansion
+	for i := 3; i < len(array); i += 2 {
+		value, err := parseInteger(array[i+1])
+		switch {
+		case strings.EqualFold(array[i], "BUCKETSIZE"):
+			if err != nil || value < 1 || value > 255 {
+				return nil, CommandError("ERR Bad bucket size")
+			}
+			bucketSize = value
+		case strings.EqualFold(array[i], "MAXITERATIONS"):
+			if err != nil || value < 1 || value > 65535 {
+				return nil, CommandError("ERR Bad maxIterations")
+			}
+			maxIterations = value
+		case strings.EqualFold(array[i], "EXPANSION"):
+			if err != nil || value < 0 || value > 32768 {
+				return nil, CommandError("ERR Bad expansion")
+			}
+			expansion = value
+		default:
+			return nil, errSyntax
+		}
+	}
+	if capacity < bucketSize*2 {
+		return nil, CommandError("ERR Capacity must be at least (BucketSize * 2)")
+	}
+	return NewCFReserveCommand(
+		p.store, p.clock, array[1], capacity, bucketSize, maxIterations, expansion,
+	), nil
+}
+
+func (p Parser) newCFAddCommand(array []string) (Command, error) {
+	if len(array) != 3 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	return NewCFAddCommand(p.store, p.clock, array[1], array[2]), nil
+}
+
+func (p Parser) newCFAddNXCommand(array []string) (Command, error) {
+	if len(array) != 3 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	return NewCFAddNXCommand(p.store, p.clock, array[1], array[2]), nil
+}
+
+func (p Parser) newCFExistsCommand(array []string) (Command, error) {
+	if len(array) != 3 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	return NewCFExistsCommand(p.store, p.clock, array[1], array[2:]), nil
+}
+
+func (p Parser) newCFMExistsCommand(array []string) (Command, error) {
+	if len(array) < 3 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	return NewCFMExistsCommand(p.store, p.clock, array[1], array[2:]), nil
+}
+
+func (p Parser) newCFDelCommand(array []string) (Command, error) {
+	if len(array) != 3 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	return NewCFDelCommand(p.store, p.clock, array[1], array[2]), nil
+}
+
+func (p Parser) newCFCountCommand(array []string) (Command, error) {
+	if len(array) != 3 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	return NewCFCountCommand(p.store, p.clock, array[1], array[2]), nil
+}
+
+func (p Parser) newCFInfoCommand(array []string) (Command, error) {
+	if len(array) != 2 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	return NewCFInfoCommand(p.store, p.clock, array[1]), nil
+}
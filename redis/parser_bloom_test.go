@@ -0,0 +1,309 @@
+package redis_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/codecrafters-io/redis-starter-go/redis"
+)
+
+func TestParser_ParseBloomRequest(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{}
+
+	tests := []struct {
+		name    string
+		request string
+		want    redis.Command
+	}{
+		{
+			name:    "BF.RESERVE bf 0.01 100",
+			request: "*4\r\n$10\r\nBF.RESERVE\r\n$2\r\nbf\r\n$4\r\n0.01\r\n$3\r\n100\r\n",
+			want:    redis.NewBFReserveCommand(store, clock, "bf", 0.01, 100, 2),
+		},
+		{
+			name: "BF.RESERVE bf 0.001 1000 expansion 4",
+			request: "*6\r\n$10\r\nBF.RESERVE\r\n$2\r\nbf\r\n$5\r\n0.001\r\n$4\r\n1000\r\n" +
+				"$9\r\nexpansion\r\n$1\r\n4\r\n",
+			want: redis.NewBFReserveCommand(store, clock, "bf", 0.001, 1000, 4),
+		},
+		{
+			name: "BF.RESERVE bf 0.1 10 NONSCALING",
+			request: "*5\r\n$10\r\nBF.RESERVE\r\n$2\r\nbf\r\n$3\r\n0.1\r\n$2\r\n10\r\n" +
+				"$10\r\nNONSCALING\r\n",
+			want: redis.NewBFReserveCommand(store, clock, "bf", 0.1, 10, 0),
+		},
+		{
+			name:    "BF.ADD bf a",
+			request: "*3\r\n$6\r\nBF.ADD\r\n$2\r\nbf\r\n$1\r\na\r\n",
+			want:    redis.NewBFAddCommand(store, clock, "bf", []string{"a"}),
+		},
+		{
+			name:    "BF.MADD bf a b",
+			request: "*4\r\n$7\r\nBF.MADD\r\n$2\r\nbf\r\n$1\r\na\r\n$1\r\nb\r\n",
+			want:    redis.NewBFMAddCommand(store, clock, "bf", []string{"a", "b"}),
+		},
+		{
+			name:    "BF.EXISTS bf a",
+			request: "*3\r\n$9\r\nBF.EXISTS\r\n$2\r\nbf\r\n$1\r\na\r\n",
+			want:    redis.NewBFExistsCommand(store, clock, "bf", []string{"a"}),
+		},
+		{
+			name:    "BF.MEXISTS bf a b",
+			request: "*4\r\n$10\r\nBF.MEXISTS\r\n$2\r\nbf\r\n$1\r\na\r\n$1\r\nb\r\n",
+			want:    redis.NewBFMExistsCommand(store, clock, "bf", []string{"a", "b"}),
+		},
+		{
+			name:    "BF.INFO bf",
+			request: "*2\r\n$7\r\nBF.INFO\r\n$2\r\nbf\r\n",
+			want:    redis.NewBFInfoCommand(store, clock, "bf", redis.BFInfoAll),
+		},
+		{
+			name:    "BF.INFO bf capacity",
+			request: "*3\r\n$7\r\nBF.INFO\r\n$2\r\nbf\r\n$8\r\ncapacity\r\n",
+			want:    redis.NewBFInfoCommand(store, clock, "bf", redis.BFInfoCapacity),
+		},
+		{
+			name:    "BF.INFO bf EXPANSION",
+			request: "*3\r\n$7\r\nBF.INFO\r\n$2\r\nbf\r\n$9\r\nEXPANSION\r\n",
+			want:    redis.NewBFInfoCommand(store, clock, "bf", redis.BFInfoExpansion),
+		},
+		{
+			name:    "CF.RESERVE cf 1000",
+			request: "*3\r\n$10\r\nCF.RESERVE\r\n$2\r\ncf\r\n$4\r\n1000\r\n",
+			want:    redis.NewCFReserveCommand(store, clock, "cf", 1000, 2, 20, 1),
+		},
+		{
+			name: "CF.RESERVE cf 1000 bucketsize 4 MAXITERATIONS 50 EXPANSION 0",
+			request: "*9\r\n$10\r\nCF.RESERVE\r\n$2\r\ncf\r\n$4\r\n1000\r\n" +
+				"$10\r\nbucketsize\r\n$1\r\n4\r\n$13\r\nMAXITERATIONS\r\n$2\r\n50\r\n" +
+				"$9\r\nEXPANSION\r\n$1\r\n0\r\n",
+			want: redis.NewCFReserveCommand(store, clock, "cf", 1000, 4, 50, 0),
+		},
+		{
+			name:    "CF.ADD cf a",
+			request: "*3\r\n$6\r\nCF.ADD\r\n$2\r\ncf\r\n$1\r\na\r\n",
+			want:    redis.NewCFAddCommand(store, clock, "cf", "a"),
+		},
+		{
+			name:    "CF.ADDNX cf a",
+			request: "*3\r\n$8\r\nCF.ADDNX\r\n$2\r\ncf\r\n$1\r\na\r\n",
+			want:    redis.NewCFAddNXCommand(store, clock, "cf", "a"),
+		},
+		{
+			name:    "CF.EXISTS cf a",
+			request: "*3\r\n$9\r\nCF.EXISTS\r\n$2\r\ncf\r\n$1\r\na\r\n",
+			want:    redis.NewCFExistsCommand(store, clock, "cf", []string{"a"}),
+		},
+		{
+			name:    "CF.MEXISTS cf a b",
+			request: "*4\r\n$10\r\nCF.MEXISTS\r\n$2\r\ncf\r\n$1\r\na\r\n$1\r\nb\r\n",
+			want:    redis.NewCFMExistsCommand(store, clock, "cf", []string{"a", "b"}),
+		},
+		{
+			name:    "CF.DEL cf a",
+			request: "*3\r\n$6\r\nCF.DEL\r\n$2\r\ncf\r\n$1\r\na\r\n",
+			want:    redis.NewCFDelCommand(store, clock, "cf", "a"),
+		},
+		{
+			name:    "CF.COUNT cf a",
+			request: "*3\r\n$8\r\nCF.COUNT\r\n$2\r\ncf\r\n$1\r\na\r\n",
+			want:    redis.NewCFCountCommand(store, clock, "cf", "a"),
+		},
+		{
+			name:    "CF.INFO cf",
+			request: "*2\r\n$7\r\nCF.INFO\r\n$2\r\ncf\r\n",
+			want:    redis.NewCFInfoCommand(store, clock, "cf"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requestReader := strings.NewReader(tt.request)
+
+			command, err := redis.NewParser(zeroValueRedisConfig, store, clock).Parse(requestReader)
+
+			if err != nil {
+				t.Errorf("err: expected: nil; got: %v", err)
+			}
+			if !reflect.DeepEqual(command, tt.want) {
+				t.Errorf("command expected to be %#v but was %#v", tt.want, command)
+			}
+		})
+	}
+}
+
+func TestParser_ParseInvalidBloomRequest(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		request string
+		err     redis.CommandError
+	}{
+		{
+			name:    "BF.RESERVE bf 0.01",
+			request: "*3\r\n$10\r\nBF.RESERVE\r\n$2\r\nbf\r\n$4\r\n0.01\r\n",
+			err:     "ERR wrong number of arguments for 'bf.reserve' command",
+		},
+		{
+			name:    "BF.RESERVE bf low 100",
+			request: "*4\r\n$10\r\nBF.RESERVE\r\n$2\r\nbf\r\n$3\r\nlow\r\n$3\r\n100\r\n",
+			err:     "ERR bad error rate",
+		},
+		{
+			name:    "BF.RESERVE bf 1 100",
+			request: "*4\r\n$10\r\nBF.RESERVE\r\n$2\r\nbf\r\n$1\r\n1\r\n$3\r\n100\r\n",
+			err:     "ERR (0 < error rate range < 1)",
+		},
+		{
+			name:    "BF.RESERVE bf 0.01 many",
+			request: "*4\r\n$10\r\nBF.RESERVE\r\n$2\r\nbf\r\n$4\r\n0.01\r\n$4\r\nmany\r\n",
+			err:     "ERR bad capacity",
+		},
+		{
+			name:    "BF.RESERVE bf 0.01 0",
+			request: "*4\r\n$10\r\nBF.RESERVE\r\n$2\r\nbf\r\n$4\r\n0.01\r\n$1\r\n0\r\n",
+			err:     "ERR (capacity should be larger than 0)",
+		},
+		{
+			name: "BF.RESERVE bf 0.01 100 EXPANSION 0",
+			request: "*6\r\n$10\r\nBF.RESERVE\r\n$2\r\nbf\r\n$4\r\n0.01\r\n$3\r\n100\r\n" +
+				"$9\r\nEXPANSION\r\n$1\r\n0\r\n",
+			err: "ERR expansion should be greater or equal to 1",
+		},
+		{
+			name: "BF.RESERVE bf 0.01 100 EXPANSION",
+			request: "*5\r\n$10\r\nBF.RESERVE\r\n$2\r\nbf\r\n$4\r\n0.01\r\n$3\r\n100\r\n" +
+				"$9\r\nEXPANSION\r\n",
+			err: "ERR syntax error",
+		},
+		{
+			name: "BF.RESERVE bf 0.01 100 EXPANSION 2 NONSCALING",
+			request: "*7\r\n$10\r\nBF.RESERVE\r\n$2\r\nbf\r\n$4\r\n0.01\r\n$3\r\n100\r\n" +
+				"$9\r\nEXPANSION\r\n$1\r\n2\r\n$10\r\nNONSCALING\r\n",
+			err: "ERR Nonscaling filters cannot expand",
+		},
+		{
+			name:    "BF.ADD bf a b",
+			request: "*4\r\n$6\r\nBF.ADD\r\n$2\r\nbf\r\n$1\r\na\r\n$1\r\nb\r\n",
+			err:     "ERR wrong number of arguments for 'bf.add' command",
+		},
+		{
+			name:    "BF.MADD bf",
+			request: "*2\r\n$7\r\nBF.MADD\r\n$2\r\nbf\r\n",
+			err:     "ERR wrong number of arguments for 'bf.madd' command",
+		},
+		{
+			name:    "BF.EXISTS bf",
+			request: "*2\r\n$9\r\nBF.EXISTS\r\n$2\r\nbf\r\n",
+			err:     "ERR wrong number of arguments for 'bf.exists' command",
+		},
+		{
+			name:    "BF.MEXISTS bf",
+			request: "*2\r\n$10\r\nBF.MEXISTS\r\n$2\r\nbf\r\n",
+			err:     "ERR wrong number of arguments for 'bf.mexists' command",
+		},
+		{
+			name:    "BF.INFO bf WIDTH",
+			request: "*3\r\n$7\r\nBF.INFO\r\n$2\r\nbf\r\n$5\r\nWIDTH\r\n",
+			err:     "ERR Invalid information value",
+		},
+		{
+			name:    "CF.RESERVE cf",
+			request: "*2\r\n$10\r\nCF.RESERVE\r\n$2\r\ncf\r\n",
+			err:     "ERR wrong number of arguments for 'cf.reserve' command",
+		},
+		{
+			name:    "CF.RESERVE cf 1000 BUCKETSIZE",
+			request: "*4\r\n$10\r\nCF.RESERVE\r\n$2\r\ncf\r\n$4\r\n1000\r\n$10\r\nBUCKETSIZE\r\n",
+			err:     "ERR wrong number of arguments for 'cf.reserve' command",
+		},
+		{
+			name:    "CF.RESERVE cf 0",
+			request: "*3\r\n$10\r\nCF.RESERVE\r\n$2\r\ncf\r\n$1\r\n0\r\n",
+			err:     "ERR Bad capacity",
+		},
+		{
+			name: "CF.RESERVE cf 1000 BUCKETSIZE 256",
+			request: "*5\r\n$10\r\nCF.RESERVE\r\n$2\r\ncf\r\n$4\r\n1000\r\n" +
+				"$10\r\nBUCKETSIZE\r\n$3\r\n256\r\n",
+			err: "ERR Bad bucket size",
+		},
+		{
+			name: "CF.RESERVE cf 1000 MAXITERATIONS 0",
+			request: "*5\r\n$10\r\nCF.RESERVE\r\n$2\r\ncf\r\n$4\r\n1000\r\n" +
+				"$13\r\nMAXITERATIONS\r\n$1\r\n0\r\n",
+			err: "ERR Bad maxIterations",
+		},
+		{
+			name: "CF.RESERVE cf 1000 EXPANSION -1",
+			request: "*5\r\n$10\r\nCF.RESERVE\r\n$2\r\ncf\r\n$4\r\n1000\r\n" +
+				"$9\r\nEXPANSION\r\n$2\r\n-1\r\n",
+			err: "ERR Bad expansion",
+		},
+		{
+			name: "CF.RESERVE cf 1000 SIZE 4",
+			request: "*5\r\n$10\r\nCF.RESERVE\r\n$2\r\ncf\r\n$4\r\n1000\r\n$4\r\nSIZE\r\n" +
+				"$1\r\n4\r\n",
+			err: "ERR syntax error",
+		},
+		{
+			name:    "CF.RESERVE cf 3",
+			request: "*3\r\n$10\r\nCF.RESERVE\r\n$2\r\ncf\r\n$1\r\n3\r\n",
+			err:     "ERR Capacity must be at least (BucketSize * 2)",
+		},
+		{
+			name:    "CF.ADD cf",
+			request: "*2\r\n$6\r\nCF.ADD\r\n$2\r\ncf\r\n",
+			err:     "ERR wrong number of arguments for 'cf.add' command",
+		},
+		{
+			name:    "CF.ADDNX cf a b",
+			request: "*4\r\n$8\r\nCF.ADDNX\r\n$2\r\ncf\r\n$1\r\na\r\n$1\r\nb\r\n",
+			err:     "ERR wrong number of arguments for 'cf.addnx' command",
+		},
+		{
+			name:    "CF.EXISTS cf a b",
+			request: "*4\r\n$9\r\nCF.EXISTS\r\n$2\r\ncf\r\n$1\r\na\r\n$1\r\nb\r\n",
+			err:     "ERR wrong number of arguments for 'cf.exists' command",
+		},
+		{
+			name:    "CF.MEXISTS cf",
+			request: "*2\r\n$10\r\nCF.MEXISTS\r\n$2\r\ncf\r\n",
+			err:     "ERR wrong number of arguments for 'cf.mexists' command",
+		},
+		{
+			name:    "CF.DEL cf",
+			request: "*2\r\n$6\r\nCF.DEL\r\n$2\r\ncf\r\n",
+			err:     "ERR wrong number of arguments for 'cf.del' command",
+		},
+		{
+			name:    "CF.COUNT cf",
+			request: "*2\r\n$8\r\nCF.COUNT\r\n$2\r\ncf\r\n",
+			err:     "ERR wrong number of arguments for 'cf.count' command",
+		},
+		{
+			name:    "CF.INFO",
+			request: "*1\r\n$7\r\nCF.INFO\r\n",
+			err:     "ERR wrong number of arguments for 'cf.info' command",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := redis.NewStore()
+			clock := FakeClock{}
+			requestReader := strings.NewReader(tt.request)
+
+			_, err := redis.NewParser(zeroValueRedisConfig, store, clock).Parse(requestReader)
+
+			if err != tt.err {
+				t.Errorf("err: expected: %v; got: %v", tt.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package redis
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+func (p Parser) newClientCommand(array []string) (Command, error) {
+	if len(array) < 2 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	switch {
+	case strings.EqualFold(array[1], "ID"):
+		if len(array) != 2 {
+			return nil, wrongNumberOfArgumentsError([]string{"client|id"})
+		}
+		return NewClientIDCommand(p.client), nil
+	case strings.EqualFold(array[1], "UNBLOCK"):
+		return p.newClientUnblockCommand(array)
+	case strings.EqualFold(array[1], "TRACKING"):
+		return p.newClientTrackingCommand(array)
+	case strings.EqualFold(array[1], "CACHING"):
+		if len(array) != 3 {
+			return nil, wrongNumberOfArgumentsError([]string{"client|caching"})
+		}
+		switch {
+		case strings.EqualFold(array[2], "YES"):
+			return NewClientCachingCommand(p.store, p.client, true), nil
+		case strings.EqualFold(array[2], "NO"):
+			return NewClientCachingCommand(p.store, p.client, false), nil
+		}
+		return nil, errSyntax
+	case strings.EqualFold(array[1], "GETREDIR"):
+		if len(array) != 2 {
+			return nil, wrongNumberOfArgumentsError([]string{"client|getredir"})
+		}
+		return NewClientGetRedirCommand(p.store, p.client), nil
+	}
+	return nil, CommandError(
+		fmt.Sprintf("ERR unknown subcommand '%s'. Try CLIENT HELP.", array[1]),
+	)
+}
+
+func (p Parser) newClientUnblockCommand(array []string) (Command, error) {
+	if len(array) != 3 && len(array) != 4 {
+		return nil, wrongNumberOfArgumentsError([]string{"client|unblock"})
+	}
+	id, err := parseInteger(array[2])
+	if err != nil {
+		return nil, err
+	}
+	var withError bool
+	if len(array) == 4 {
+		switch {
+		case strings.EqualFold(array[3], "TIMEOUT"):
+			withError = false
+		case strings.EqualFold(array[3], "ERROR"):
+			withError = true
+		default:
+			return nil, CommandError("ERR CLIENT UNBLOCK reason should be TIMEOUT or ERROR")
+		}
+	}
+	return NewClientUnblockCommand(p.clients, id, withError), nil
+}
+
+func (p Parser) newClientTrackingCommand(array []string) (Command, error) {
+	if len(array) < 3 {
+		return nil, wrongNumberOfArgumentsError([]string{"client|tracking"})
+	}
+	var on bool
+	switch {
+	case strings.EqualFold(array[2], "ON"):
+		on = true
+	case strings.EqualFold(array[2], "OFF"):
+		on = false
+	default:
+		return nil, errSyntax
+	}
+
+	var options []func(*ClientTrackingCommand)
+	var prefixes []string
+	var broadcast, optIn, optOut bool
+	for i := 3; i < len(array); i++ {
+		switch {
+		case strings.EqualFold(array[i], "REDIRECT") && i+1 < len(array):
+			i++
+			id, err := parseInteger(array[i])
+			if err != nil {
+				return nil, err
+			}
+			options = append(options, ClientTrackingRedirect(id))
+		case strings.EqualFold(array[i], "PREFIX") && i+1 < len(array):
+			i++
+			prefixes = append(prefixes, array[i])
+		case strings.EqualFold(array[i], "BCAST"):
+			broadcast = true
+		case strings.EqualFold(array[i], "OPTIN"):
+			optIn = true
+			options = append(options, ClientTrackingOptIn())
+		case strings.EqualFold(array[i], "OPTOUT"):
+			optOut = true
+			options = append(options, ClientTrackingOptOut())
+		case strings.EqualFold(array[i], "NOLOOP"):
+			options = append(options, ClientTrackingNoLoop())
+		default:
+			return nil, errSyntax
+		}
+	}
+	switch {
+	case len(prefixes) > 0 && !broadcast:
+		return nil, CommandError("ERR PREFIX option requires BCAST mode to be enabled")
+	case optIn && optOut:
+		return nil, CommandError("ERR You can't use both OPTIN and OPTOUT")
+	case broadcast && (optIn || optOut):
+		return nil, CommandError("ERR OPTIN and OPTOUT are not compatible with BCAST")
+	}
+	if broadcast {
+		options = append(options, ClientTrackingBroadcast(prefixes))
+	}
+	return NewClientTrackingCommand(p.store, p.clients, p.client, on, options...), nil
+}
+
+func (p Parser) newHelloCommand(array []string) (Command, error) {
+	if len(array) > 2 {
+		// AUTH and SETNAME aren't supported.
+		return nil, errSyntax
+	}
+	var protocol int
+	if len(array) == 2 {
+		var err error
+		protocol, err = strconv.Atoi(array[1])
+		if err != nil {
+			return nil, CommandError("ERR Protocol version is not an integer or out of range")
+		}
+	}
+	return NewHelloCommand(p.config, p.client, protocol), nil
+}
+
+func (p Parser) newQuitCommand(array []string) (Command, error) {
+	return NewQuitCommand(p.client), nil
+}
+
+func (p Parser) newResetCommand(array []string) (Command, error) {
+	if len(array) != 1 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	return NewResetCommand(p.pubSub, p.client), nil
+}
@@ -0,0 +1,148 @@
+package redis_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/codecrafters-io/redis-starter-go/redis"
+)
+
+func TestParser_ParseClientIDRequest(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{}
+	parser := redis.NewParser(zeroValueRedisConfig, store, clock)
+	client := parser.NewClient()
+	requestReader := strings.NewReader("*2\r\n$6\r\nCLIENT\r\n$2\r\nID\r\n")
+
+	command, err := parser.ForClient(client).Parse(requestReader)
+
+	if err != nil {
+		t.Errorf("err: expected: nil; got: %v", err)
+	}
+	if want := redis.NewClientIDCommand(client); !reflect.DeepEqual(command, want) {
+		t.Errorf("command expected to be %#v but was %#v", want, command)
+	}
+}
+
+func TestParser_ParseClientUnblockRequest(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{}
+	clients := redis.NewClients()
+
+	tests := []struct {
+		name    string
+		request string
+		want    redis.Command
+	}{
+		{
+			name:    "client unblock 1",
+			request: "*3\r\n$6\r\nclient\r\n$7\r\nunblock\r\n$1\r\n1\r\n",
+			want:    redis.NewClientUnblockCommand(clients, 1, false),
+		},
+		{
+			name:    "CLIENT UNBLOCK 1 ERROR",
+			request: "*4\r\n$6\r\nCLIENT\r\n$7\r\nUNBLOCK\r\n$1\r\n1\r\n$5\r\nERROR\r\n",
+			want:    redis.NewClientUnblockCommand(clients, 1, true),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requestReader := strings.NewReader(tt.request)
+
+			command, err := redis.NewParser(zeroValueRedisConfig, store, clock).Parse(requestReader)
+
+			if err != nil {
+				t.Errorf("err: expected: nil; got: %v", err)
+			}
+			if !reflect.DeepEqual(command, tt.want) {
+				t.Errorf("command expected to be %#v but was %#v", tt.want, command)
+			}
+		})
+	}
+}
+
+func TestParser_ParseInvalidClientRequest(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		request string
+		err     redis.CommandError
+	}{
+		{
+			name:    "CLIENT",
+			request: "*1\r\n$6\r\nCLIENT\r\n",
+			err:     "ERR wrong number of arguments for 'client' command",
+		},
+		{
+			name:    "CLIENT NOPE",
+			request: "*2\r\n$6\r\nCLIENT\r\n$4\r\nNOPE\r\n",
+			err:     "ERR unknown subcommand 'NOPE'. Try CLIENT HELP.",
+		},
+		{
+			name:    "CLIENT ID 1",
+			request: "*3\r\n$6\r\nCLIENT\r\n$2\r\nID\r\n$1\r\n1\r\n",
+			err:     "ERR wrong number of arguments for 'client|id' command",
+		},
+		{
+			name:    "CLIENT UNBLOCK one",
+			request: "*3\r\n$6\r\nCLIENT\r\n$7\r\nUNBLOCK\r\n$3\r\none\r\n",
+			err:     "ERR value is not an integer or out of range",
+		},
+		{
+			name:    "CLIENT UNBLOCK 1 NOW",
+			request: "*4\r\n$6\r\nCLIENT\r\n$7\r\nUNBLOCK\r\n$1\r\n1\r\n$3\r\nNOW\r\n",
+			err:     "ERR CLIENT UNBLOCK reason should be TIMEOUT or ERROR",
+		},
+		{
+			name:    "CLIENT TRACKING MAYBE",
+			request: "*3\r\n$6\r\nCLIENT\r\n$8\r\nTRACKING\r\n$5\r\nMAYBE\r\n",
+			err:     "ERR syntax error",
+		},
+		{
+			name:    "CLIENT TRACKING ON PREFIX a",
+			request: "*5\r\n$6\r\nCLIENT\r\n$8\r\nTRACKING\r\n$2\r\nON\r\n$6\r\nPREFIX\r\n$1\r\na\r\n",
+			err:     "ERR PREFIX option requires BCAST mode to be enabled",
+		},
+		{
+			name:    "CLIENT TRACKING ON OPTIN OPTOUT",
+			request: "*5\r\n$6\r\nCLIENT\r\n$8\r\nTRACKING\r\n$2\r\nON\r\n$5\r\nOPTIN\r\n$6\r\nOPTOUT\r\n",
+			err:     "ERR You can't use both OPTIN and OPTOUT",
+		},
+		{
+			name:    "CLIENT TRACKING ON BCAST OPTIN",
+			request: "*5\r\n$6\r\nCLIENT\r\n$8\r\nTRACKING\r\n$2\r\nON\r\n$5\r\nBCAST\r\n$5\r\nOPTIN\r\n",
+			err:     "ERR OPTIN and OPTOUT are not compatible with BCAST",
+		},
+		{
+			name:    "CLIENT CACHING MAYBE",
+			request: "*3\r\n$6\r\nCLIENT\r\n$7\r\nCACHING\r\n$5\r\nMAYBE\r\n",
+			err:     "ERR syntax error",
+		},
+		{
+			name:    "HELLO three",
+			request: "*2\r\n$5\r\nHELLO\r\n$5\r\nthree\r\n",
+			err:     "ERR Protocol version is not an integer or out of range",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := redis.NewStore()
+			clock := FakeClock{}
+			requestReader := strings.NewReader(tt.request)
+
+			_, err := redis.NewParser(zeroValueRedisConfig, store, clock).Parse(requestReader)
+
+			if err != tt.err {
+				t.Errorf("err: expected: %v; got: %v", tt.err, err)
+			}
+		})
+	}
+}
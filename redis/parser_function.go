@@ -0,0 +1,114 @@
+package redis
+
+import (
+	"fmt"
+	"strings"
+)
+
+func (p Parser) newFCallCommand(array []string) (Command, error) {
+	keys, args, err := parseScriptArguments(array)
+	if err != nil {
+		return nil, err
+	}
+	return NewFCallCommand(p, array[1], keys, args, false), nil
+}
+
+func (p Parser) newFCallROCommand(array []string) (Command, error) {
+	keys, args, err := parseScriptArguments(array)
+	if err != nil {
+		return nil, err
+	}
+	return NewFCallCommand(p, array[1], keys, args, true), nil
+}
+
+func (p Parser) newFunctionCommand(array []string) (Command, error) {
+	if len(array) < 2 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	switch {
+	case strings.EqualFold(array[1], "LOAD"):
+		return p.newFunctionLoadCommand(array)
+	case strings.EqualFold(array[1], "LIST"):
+		return p.newFunctionListCommand(array)
+	case strings.EqualFold(array[1], "DELETE"):
+		if len(array) != 3 {
+			return nil, wrongNumberOfArgumentsError([]string{"function|delete"})
+		}
+		return NewFunctionDeleteCommand(p.scripts, array[2]), nil
+	case strings.EqualFold(array[1], "FLUSH"):
+		// Flushing is quick either way, so ASYNC flushes synchronously too.
+		if len(array) > 3 ||
+			(len(array) == 3 && !strings.EqualFold(array[2], "ASYNC") &&
+				!strings.EqualFold(array[2], "SYNC")) {
+			return nil, CommandError("ERR FUNCTION FLUSH only supports SYNC|ASYNC option")
+		}
+		return NewFunctionFlushCommand(p.scripts), nil
+	case strings.EqualFold(array[1], "DUMP"):
+		if len(array) != 2 {
+			return nil, wrongNumberOfArgumentsError([]string{"function|dump"})
+		}
+		return NewFunctionDumpCommand(p.scripts), nil
+	case strings.EqualFold(array[1], "RESTORE"):
+		return p.newFunctionRestoreCommand(array)
+	case strings.EqualFold(array[1], "KILL"):
+		if len(array) != 2 {
+			return nil, wrongNumberOfArgumentsError([]string{"function|kill"})
+		}
+		return NewFunctionKillCommand(p.scripts), nil
+	}
+	return nil, CommandError(
+		fmt.Sprintf("ERR unknown subcommand '%s'. Try FUNCTION HELP.", array[1]),
+	)
+}
+
+func (p Parser) newFunctionLoadCommand(array []string) (Command, error) {
+	switch {
+	case len(array) == 3:
+		return NewFunctionLoadCommand(p.scripts, p.clock, array[2], false), nil
+	case len(array) != 4:
+		return nil, wrongNumberOfArgumentsError([]string{"function|load"})
+	case !strings.EqualFold(array[2], "REPLACE"):
+		return nil, CommandError("ERR Unknown option given: " + array[2])
+	}
+	return NewFunctionLoadCommand(p.scripts, p.clock, array[3], true), nil
+}
+
+func (p Parser) newFunctionListCommand(array []string) (Command, error) {
+	pattern := "*"
+	withCode := false
+	for i := 2; i < len(array); i++ {
+		switch {
+		case strings.EqualFold(array[i], "WITHCODE"):
+			withCode = true
+		case strings.EqualFold(array[i], "LIBRARYNAME"):
+			if i+1 == len(array) {
+				return nil, CommandError("ERR library name argument was not given")
+			}
+			i++
+			pattern = array[i]
+		default:
+			return nil, CommandError("ERR Unknown argument " + array[i])
+		}
+	}
+	return NewFunctionListCommand(p.scripts, pattern, withCode), nil
+}
+
+func (p Parser) newFunctionRestoreCommand(array []string) (Command, error) {
+	if len(array) != 3 && len(array) != 4 {
+		return nil, wrongNumberOfArgumentsError([]string{"function|restore"})
+	}
+	policy := FunctionRestoreAppend
+	if len(array) == 4 {
+		switch {
+		case strings.EqualFold(array[3], "APPEND"):
+		case strings.EqualFold(array[3], "REPLACE"):
+			policy = FunctionRestoreReplace
+		case strings.EqualFold(array[3], "FLUSH"):
+			policy = FunctionRestoreFlush
+		default:
+			return nil, CommandError("ERR Wrong restore policy given, value should be either " +
+				"FLUSH, APPEND or REPLACE.")
+		}
+	}
+	return NewFunctionRestoreCommand(p.scripts, p.clock, array[2], policy), nil
+}
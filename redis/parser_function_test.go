@@ -0,0 +1,171 @@
+package redis_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/codecrafters-io/redis-starter-go/redis"
+)
+
+func TestParser_ParseFunctionRequest(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		request string
+		want    redis.Command
+	}{
+		{
+			name:    "FUNCTION LOAD",
+			request: "*3\r\n$8\r\nFUNCTION\r\n$4\r\nLOAD\r\n$4\r\ncode\r\n",
+			want:    redis.NewFunctionLoadCommand(redis.NewScripts(), FakeClock{}, "code", false),
+		},
+		{
+			name:    "function load replace",
+			request: "*4\r\n$8\r\nfunction\r\n$4\r\nload\r\n$7\r\nreplace\r\n$4\r\ncode\r\n",
+			want:    redis.NewFunctionLoadCommand(redis.NewScripts(), FakeClock{}, "code", true),
+		},
+		{
+			name:    "FUNCTION LIST",
+			request: "*2\r\n$8\r\nFUNCTION\r\n$4\r\nLIST\r\n",
+			want:    redis.NewFunctionListCommand(redis.NewScripts(), "*", false),
+		},
+		{
+			name: "FUNCTION LIST LIBRARYNAME WITHCODE",
+			request: "*5\r\n$8\r\nFUNCTION\r\n$4\r\nLIST\r\n$11\r\nLIBRARYNAME\r\n$3\r\nmy*\r\n" +
+				"$8\r\nWITHCODE\r\n",
+			want: redis.NewFunctionListCommand(redis.NewScripts(), "my*", true),
+		},
+		{
+			name:    "FUNCTION DELETE",
+			request: "*3\r\n$8\r\nFUNCTION\r\n$6\r\nDELETE\r\n$5\r\nmylib\r\n",
+			want:    redis.NewFunctionDeleteCommand(redis.NewScripts(), "mylib"),
+		},
+		{
+			name:    "FUNCTION FLUSH SYNC",
+			request: "*3\r\n$8\r\nFUNCTION\r\n$5\r\nFLUSH\r\n$4\r\nSYNC\r\n",
+			want:    redis.NewFunctionFlushCommand(redis.NewScripts()),
+		},
+		{
+			name:    "FUNCTION DUMP",
+			request: "*2\r\n$8\r\nFUNCTION\r\n$4\r\nDUMP\r\n",
+			want:    redis.NewFunctionDumpCommand(redis.NewScripts()),
+		},
+		{
+			name:    "FUNCTION RESTORE",
+			request: "*3\r\n$8\r\nFUNCTION\r\n$7\r\nRESTORE\r\n$7\r\npayload\r\n",
+			want: redis.NewFunctionRestoreCommand(
+				redis.NewScripts(), FakeClock{}, "payload", redis.FunctionRestoreAppend,
+			),
+		},
+		{
+			name:    "FUNCTION RESTORE FLUSH",
+			request: "*4\r\n$8\r\nFUNCTION\r\n$7\r\nRESTORE\r\n$7\r\npayload\r\n$5\r\nFLUSH\r\n",
+			want: redis.NewFunctionRestoreCommand(
+				redis.NewScripts(), FakeClock{}, "payload", redis.FunctionRestoreFlush,
+			),
+		},
+		{
+			name:    "FUNCTION KILL",
+			request: "*2\r\n$8\r\nFUNCTION\r\n$4\r\nKILL\r\n",
+			want:    redis.NewFunctionKillCommand(redis.NewScripts()),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser := redis.NewParser(zeroValueRedisConfig, redis.NewStore(), FakeClock{})
+			requestReader := strings.NewReader(tt.request)
+
+			command, err := parser.Parse(requestReader)
+
+			if err != nil {
+				t.Errorf("err: expected: nil; got: %v", err)
+			}
+			if !reflect.DeepEqual(command, tt.want) {
+				t.Errorf("command expected to be %#v but was %#v", tt.want, command)
+			}
+		})
+	}
+}
+
+func TestParser_ParseInvalidFunctionRequest(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		request string
+		err     error
+	}{
+		{
+			name:    "FCALL without numkeys",
+			request: "*2\r\n$5\r\nFCALL\r\n$1\r\nf\r\n",
+			err:     redis.CommandError("ERR wrong number of arguments for 'fcall' command"),
+		},
+		{
+			name:    "FCALL_RO numkeys more than keys",
+			request: "*3\r\n$8\r\nFCALL_RO\r\n$1\r\nf\r\n$1\r\n1\r\n",
+			err: redis.CommandError(
+				"ERR Number of keys can't be greater than number of args",
+			),
+		},
+		{
+			name:    "FUNCTION",
+			request: "*1\r\n$8\r\nFUNCTION\r\n",
+			err:     redis.CommandError("ERR wrong number of arguments for 'function' command"),
+		},
+		{
+			name:    "FUNCTION LOAD without code",
+			request: "*2\r\n$8\r\nFUNCTION\r\n$4\r\nLOAD\r\n",
+			err: redis.CommandError(
+				"ERR wrong number of arguments for 'function|load' command",
+			),
+		},
+		{
+			name:    "FUNCTION LOAD with an unknown option",
+			request: "*4\r\n$8\r\nFUNCTION\r\n$4\r\nLOAD\r\n$4\r\nKEEP\r\n$4\r\ncode\r\n",
+			err:     redis.CommandError("ERR Unknown option given: KEEP"),
+		},
+		{
+			name:    "FUNCTION LIST LIBRARYNAME without a pattern",
+			request: "*3\r\n$8\r\nFUNCTION\r\n$4\r\nLIST\r\n$11\r\nLIBRARYNAME\r\n",
+			err:     redis.CommandError("ERR library name argument was not given"),
+		},
+		{
+			name:    "FUNCTION LIST with an unknown argument",
+			request: "*3\r\n$8\r\nFUNCTION\r\n$4\r\nLIST\r\n$4\r\nCODE\r\n",
+			err:     redis.CommandError("ERR Unknown argument CODE"),
+		},
+		{
+			name:    "FUNCTION FLUSH LATER",
+			request: "*3\r\n$8\r\nFUNCTION\r\n$5\r\nFLUSH\r\n$5\r\nLATER\r\n",
+			err:     redis.CommandError("ERR FUNCTION FLUSH only supports SYNC|ASYNC option"),
+		},
+		{
+			name:    "FUNCTION RESTORE with an unknown policy",
+			request: "*4\r\n$8\r\nFUNCTION\r\n$7\r\nRESTORE\r\n$7\r\npayload\r\n$5\r\nMERGE\r\n",
+			err: redis.CommandError(
+				"ERR Wrong restore policy given, value should be either FLUSH, APPEND or REPLACE.",
+			),
+		},
+		{
+			name:    "FUNCTION STATS",
+			request: "*2\r\n$8\r\nFUNCTION\r\n$5\r\nSTATS\r\n",
+			err:     redis.CommandError("ERR unknown subcommand 'STATS'. Try FUNCTION HELP."),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requestReader := strings.NewReader(tt.request)
+
+			_, err := redis.NewParser(zeroValueRedisConfig, redis.NewStore(), FakeClock{}).
+				Parse(requestReader)
+
+			if err != tt.err {
+				t.Errorf("err: expected: %v; got: %v", tt.err, err)
+			}
+		})
+	}
+}
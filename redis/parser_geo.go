@@ -0,0 +1,254 @@
+package redis
+
+import (
+	"fmt"
+	"strings"
+)
+
+func (p Parser) newGeoAddCommand(array []string) (Command, error) {
+	if len(array) < 5 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+
+	var options []func(*ZAddCommand)
+	var nx, xx bool
+	i := 2
+flags:
+	for ; i < len(array); i++ {
+		switch {
+		case strings.EqualFold(array[i], "NX"):
+			nx = true
+			options = append(options, ZAddNX())
+		case strings.EqualFold(array[i], "XX"):
+			xx = true
+			options = append(options, ZAddXX())
+		case strings.EqualFold(array[i], "CH"):
+			options = append(options, ZAddCH())
+		default:
+			break flags
+		}
+	}
+	arguments := array[i:]
+	if len(arguments) == 0 || len(arguments)%3 != 0 || (nx && xx) {
+		return nil, CommandError(
+			"ERR syntax error. Try GEOADD key [x1] [y1] [name1] [x2] [y2] [name2] ... ",
+		)
+	}
+
+	members := make([]GeoMember, 0, len(arguments)/3)
+	for j := 0; j < len(arguments); j += 3 {
+		longitude, latitude, err := parseGeoPosition(arguments[j], arguments[j+1])
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, GeoMember{
+			Longitude: longitude,
+			Latitude:  latitude,
+			Member:    arguments[j+2],
+		})
+	}
+	return NewGeoAddCommand(p.store, p.clock, array[1], members, options...), nil
+}
+
+// parseGeoPosition parses a longitude and latitude that can be indexed.
+func parseGeoPosition(longitude, latitude string) (float64, float64, error) {
+	lon, err := parseFloat(longitude)
+	if err != nil {
+		return 0, 0, err
+	}
+	lat, err := parseFloat(latitude)
+	if err != nil {
+		return 0, 0, err
+	}
+	if !validGeoPosition(lon, lat) {
+		return 0, 0, CommandError(
+			fmt.Sprintf("ERR invalid longitude,latitude pair %f,%f", lon, lat),
+		)
+	}
+	return lon, lat, nil
+}
+
+// parseGeoUnit parses a unit of distance, returning how many meters it is.
+func parseGeoUnit(s string) (float64, error) {
+	switch {
+	case strings.EqualFold(s, "m"):
+		return 1, nil
+	case strings.EqualFold(s, "km"):
+		return 1000, nil
+	case strings.EqualFold(s, "ft"):
+		return 0.3048, nil
+	case strings.EqualFold(s, "mi"):
+		return 1609.34, nil
+	}
+	return 0, CommandError("ERR unsupported unit provided. please use M, KM, FT, MI")
+}
+
+func (p Parser) newGeoDistCommand(array []string) (Command, error) {
+	if len(array) < 4 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	if len(array) > 5 {
+		return nil, errSyntax
+	}
+	unit := 1.0
+	if len(array) == 5 {
+		var err error
+		if unit, err = parseGeoUnit(array[4]); err != nil {
+			return nil, err
+		}
+	}
+	return NewGeoDistCommand(p.store, p.clock, array[1], array[2], array[3], unit), nil
+}
+
+func (p Parser) newGeoPosCommand(array []string) (Command, error) {
+	if len(array) < 2 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	return NewGeoPosCommand(p.store, p.clock, array[1], array[2:]), nil
+}
+
+func (p Parser) newGeoHashCommand(array []string) (Command, error) {
+	if len(array) < 2 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	return NewGeoHashCommand(p.store, p.clock, array[1], array[2:]), nil
+}
+
+func (p Parser) newGeoSearchCommand(array []string) (Command, error) {
+	if len(array) < 7 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	return p.newGeoSearchCommandFrom(array, array[1], 2, nil)
+}
+
+func (p Parser) newGeoSearchStoreCommand(array []string) (Command, error) {
+	if len(array) < 8 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	options := []func(*GeoSearchCommand){GeoSearchStore(array[1])}
+	return p.newGeoSearchCommandFrom(array, array[2], 3, options)
+}
+
+// newGeoSearchCommandFrom parses the arguments of GEOSEARCH or GEOSEARCHSTORE from start
+// onwards, which differ only in whether a destination comes before the key and the options
+// they accept.
+func (p Parser) newGeoSearchCommandFrom(
+	array []string,
+	key string,
+	start int,
+	options []func(*GeoSearchCommand),
+) (Command, error) {
+	store := len(options) > 0
+	var query GeoSearchQuery
+	fromLonLat, byRadius, byBox, withFields := false, false, false, false
+	for i := start; i < len(array); i++ {
+		remaining := len(array) - 1 - i
+		switch {
+		case strings.EqualFold(array[i], "FROMMEMBER") &&
+			remaining >= 1 && !query.FromMember && !fromLonLat:
+			query.FromMember, query.Member = true, array[i+1]
+			i++
+		case strings.EqualFold(array[i], "FROMLONLAT") &&
+			remaining >= 2 && !query.FromMember && !fromLonLat:
+			var err error
+			query.Longitude, query.Latitude, err = parseGeoPosition(array[i+1], array[i+2])
+			if err != nil {
+				return nil, err
+			}
+			fromLonLat = true
+			i += 2
+		case strings.EqualFold(array[i], "BYRADIUS") && remaining >= 2 && !byRadius && !byBox:
+			radius, err := parseGeoDistance(array[i+1], "radius")
+			if err != nil {
+				return nil, err
+			}
+			if radius < 0 {
+				return nil, CommandError("ERR radius cannot be negative")
+			}
+			unit, err := parseGeoUnit(array[i+2])
+			if err != nil {
+				return nil, err
+			}
+			query.Shape = GeoShape{Radius: radius, Unit: unit}
+			byRadius = true
+			i += 2
+		case strings.EqualFold(array[i], "BYBOX") && remaining >= 3 && !byRadius && !byBox:
+			width, err := parseGeoDistance(array[i+1], "width")
+			if err != nil {
+				return nil, err
+			}
+			height, err := parseGeoDistance(array[i+2], "height")
+			if err != nil {
+				return nil, err
+			}
+			if width < 0 || height < 0 {
+				return nil, CommandError("ERR height or width cannot be negative")
+			}
+			unit, err := parseGeoUnit(array[i+3])
+			if err != nil {
+				return nil, err
+			}
+			query.Shape = GeoShape{Box: true, Width: width, Height: height, Unit: unit}
+			byBox = true
+			i += 3
+		case strings.EqualFold(array[i], "ASC"):
+			query.Order = GeoOrderAsc
+		case strings.EqualFold(array[i], "DESC"):
+			query.Order = GeoOrderDesc
+		case strings.EqualFold(array[i], "COUNT") && remaining >= 1:
+			count, err := parseInteger(array[i+1])
+			if err != nil {
+				return nil, err
+			}
+			if count <= 0 {
+				return nil, CommandError("ERR COUNT must be > 0")
+			}
+			query.Count = count
+			i++
+		case strings.EqualFold(array[i], "ANY"):
+			query.Any = true
+		case strings.EqualFold(array[i], "WITHCOORD"):
+			options = append(options, GeoSearchWithCoord())
+			withFields = true
+		case strings.EqualFold(array[i], "WITHDIST"):
+			options = append(options, GeoSearchWithDist())
+			withFields = true
+		case strings.EqualFold(array[i], "WITHHASH"):
+			options = append(options, GeoSearchWithHash())
+			withFields = true
+		case store && strings.EqualFold(array[i], "STOREDIST"):
+			options = append(options, GeoSearchStoreDist())
+		default:
+			return nil, errSyntax
+		}
+	}
+
+	if store && withFields {
+		return nil, CommandError(
+			"ERR GEOSEARCHSTORE is not compatible with WITHDIST, WITHHASH and WITHCOORD options",
+		)
+	}
+	if !query.FromMember && !fromLonLat {
+		return nil, CommandError(fmt.Sprintf(
+			"ERR exactly one of FROMMEMBER or FROMLONLAT can be specified for %s", array[0],
+		))
+	}
+	if !byRadius && !byBox {
+		return nil, CommandError(fmt.Sprintf(
+			"ERR exactly one of BYRADIUS and BYBOX can be specified for %s", array[0],
+		))
+	}
+	if query.Any && query.Count == 0 {
+		return nil, CommandError("ERR the ANY argument requires COUNT argument")
+	}
+	return NewGeoSearchCommand(p.store, p.clock, key, query, options...), nil
+}
+
+// parseGeoDistance parses a distance argument, whose error names what it is.
+func parseGeoDistance(s string, name string) (float64, error) {
+	distance, err := parseFloat(s)
+	if err != nil {
+		return 0, CommandError("ERR need numeric " + name)
+	}
+	return distance, nil
+}
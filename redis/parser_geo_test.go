@@ -0,0 +1,389 @@
+package redis_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/codecrafters-io/redis-starter-go/redis"
+)
+
+func TestParser_ParseGeoRequest(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{}
+
+	tests := []struct {
+		name    string
+		request string
+		want    redis.Command
+	}{
+		{
+			name: "GEOADD Sicily 13.361389 38.115556 Palermo",
+			request: "*5\r\n$6\r\nGEOADD\r\n$6\r\nSicily\r\n$9\r\n13.361389\r\n" +
+				"$9\r\n38.115556\r\n$7\r\nPalermo\r\n",
+			want: redis.NewGeoAddCommand(
+				store, clock, "Sicily",
+				[]redis.GeoMember{{Longitude: 13.361389, Latitude: 38.115556, Member: "Palermo"}},
+			),
+		},
+		{
+			name: "GEOADD Sicily nx ch 13.361389 38.115556 Palermo 15.087269 37.502669 Catania",
+			request: "*10\r\n$6\r\nGEOADD\r\n$6\r\nSicily\r\n$2\r\nnx\r\n$2\r\nch\r\n" +
+				"$9\r\n13.361389\r\n$9\r\n38.115556\r\n$7\r\nPalermo\r\n" +
+				"$9\r\n15.087269\r\n$9\r\n37.502669\r\n$7\r\nCatania\r\n",
+			want: redis.NewGeoAddCommand(
+				store, clock, "Sicily",
+				[]redis.GeoMember{
+					{Longitude: 13.361389, Latitude: 38.115556, Member: "Palermo"},
+					{Longitude: 15.087269, Latitude: 37.502669, Member: "Catania"},
+				},
+				redis.ZAddNX(), redis.ZAddCH(),
+			),
+		},
+		{
+			name: "GEOADD Sicily XX 180 -85.05112878 edge",
+			request: "*6\r\n$6\r\nGEOADD\r\n$6\r\nSicily\r\n$2\r\nXX\r\n$3\r\n180\r\n" +
+				"$12\r\n-85.05112878\r\n$4\r\nedge\r\n",
+			want: redis.NewGeoAddCommand(
+				store, clock, "Sicily",
+				[]redis.GeoMember{{Longitude: 180, Latitude: -85.05112878, Member: "edge"}},
+				redis.ZAddXX(),
+			),
+		},
+		{
+			name:    "GEODIST Sicily Palermo Catania",
+			request: "*4\r\n$7\r\nGEODIST\r\n$6\r\nSicily\r\n$7\r\nPalermo\r\n$7\r\nCatania\r\n",
+			want:    redis.NewGeoDistCommand(store, clock, "Sicily", "Palermo", "Catania", 1),
+		},
+		{
+			name: "GEODIST Sicily Palermo Catania KM",
+			request: "*5\r\n$7\r\nGEODIST\r\n$6\r\nSicily\r\n$7\r\nPalermo\r\n" +
+				"$7\r\nCatania\r\n$2\r\nKM\r\n",
+			want: redis.NewGeoDistCommand(store, clock, "Sicily", "Palermo", "Catania", 1000),
+		},
+		{
+			name: "GEODIST Sicily Palermo Catania ft",
+			request: "*5\r\n$7\r\nGEODIST\r\n$6\r\nSicily\r\n$7\r\nPalermo\r\n" +
+				"$7\r\nCatania\r\n$2\r\nft\r\n",
+			want: redis.NewGeoDistCommand(store, clock, "Sicily", "Palermo", "Catania", 0.3048),
+		},
+		{
+			name: "GEODIST Sicily Palermo Catania mi",
+			request: "*5\r\n$7\r\nGEODIST\r\n$6\r\nSicily\r\n$7\r\nPalermo\r\n" +
+				"$7\r\nCatania\r\n$2\r\nmi\r\n",
+			want: redis.NewGeoDistCommand(store, clock, "Sicily", "Palermo", "Catania", 1609.34),
+		},
+		{
+			name:    "GEOPOS Sicily",
+			request: "*2\r\n$6\r\nGEOPOS\r\n$6\r\nSicily\r\n",
+			want:    redis.NewGeoPosCommand(store, clock, "Sicily", []string{}),
+		},
+		{
+			name:    "GEOPOS Sicily Palermo Catania",
+			request: "*4\r\n$6\r\nGEOPOS\r\n$6\r\nSicily\r\n$7\r\nPalermo\r\n$7\r\nCatania\r\n",
+			want:    redis.NewGeoPosCommand(store, clock, "Sicily", []string{"Palermo", "Catania"}),
+		},
+		{
+			name:    "GEOHASH Sicily Palermo",
+			request: "*3\r\n$7\r\nGEOHASH\r\n$6\r\nSicily\r\n$7\r\nPalermo\r\n",
+			want:    redis.NewGeoHashCommand(store, clock, "Sicily", []string{"Palermo"}),
+		},
+		{
+			name: "GEOSEARCH Sicily FROMMEMBER Palermo BYRADIUS 200 km",
+			request: "*7\r\n$9\r\nGEOSEARCH\r\n$6\r\nSicily\r\n$10\r\nFROMMEMBER\r\n" +
+				"$7\r\nPalermo\r\n$8\r\nBYRADIUS\r\n$3\r\n200\r\n$2\r\nkm\r\n",
+			want: redis.NewGeoSearchCommand(
+				store, clock, "Sicily",
+				redis.GeoSearchQuery{
+					FromMember: true,
+					Member:     "Palermo",
+					Shape:      redis.GeoShape{Radius: 200, Unit: 1000},
+				},
+			),
+		},
+		{
+			name: "GEOSEARCH Sicily byradius 3 km fromlonlat 15 37 asc count 5 any",
+			request: "*12\r\n$9\r\nGEOSEARCH\r\n$6\r\nSicily\r\n$8\r\nbyradius\r\n" +
+				"$1\r\n3\r\n$2\r\nkm\r\n$10\r\nfromlonlat\r\n$2\r\n15\r\n$2\r\n37\r\n" +
+				"$3\r\nasc\r\n$5\r\ncount\r\n$1\r\n5\r\n$3\r\nany\r\n",
+			want: redis.NewGeoSearchCommand(
+				store, clock, "Sicily",
+				redis.GeoSearchQuery{
+					Longitude: 15,
+					Latitude:  37,
+					Shape:     redis.GeoShape{Radius: 3, Unit: 1000},
+					Order:     redis.GeoOrderAsc,
+					Count:     5,
+					Any:       true,
+				},
+			),
+		},
+		{
+			name: "GEOSEARCH Sicily FROMLONLAT 15 37 BYBOX 400 300.5 m DESC WITHCOORD WITHDIST WITHHASH",
+			request: "*13\r\n$9\r\nGEOSEARCH\r\n$6\r\nSicily\r\n$10\r\nFROMLONLAT\r\n" +
+				"$2\r\n15\r\n$2\r\n37\r\n$5\r\nBYBOX\r\n$3\r\n400\r\n$5\r\n300.5\r\n" +
+				"$1\r\nm\r\n$4\r\nDESC\r\n$9\r\nWITHCOORD\r\n$8\r\nWITHDIST\r\n" +
+				"$8\r\nWITHHASH\r\n",
+			want: redis.NewGeoSearchCommand(
+				store, clock, "Sicily",
+				redis.GeoSearchQuery{
+					Longitude: 15,
+					Latitude:  37,
+					Shape:     redis.GeoShape{Box: true, Width: 400, Height: 300.5, Unit: 1},
+					Order:     redis.GeoOrderDesc,
+				},
+				redis.GeoSearchWithCoord(), redis.GeoSearchWithDist(), redis.GeoSearchWithHash(),
+			),
+		},
+		{
+			name: "GEOSEARCHSTORE dest Sicily FROMMEMBER Palermo BYRADIUS 10 mi",
+			request: "*8\r\n$14\r\nGEOSEARCHSTORE\r\n$4\r\ndest\r\n$6\r\nSicily\r\n" +
+				"$10\r\nFROMMEMBER\r\n$7\r\nPalermo\r\n$8\r\nBYRADIUS\r\n$2\r\n10\r\n" +
+				"$2\r\nmi\r\n",
+			want: redis.NewGeoSearchCommand(
+				store, clock, "Sicily",
+				redis.GeoSearchQuery{
+					FromMember: true,
+					Member:     "Palermo",
+					Shape:      redis.GeoShape{Radius: 10, Unit: 1609.34},
+				},
+				redis.GeoSearchStore("dest"),
+			),
+		},
+		{
+			name: "GEOSEARCHSTORE dest Sicily FROMMEMBER Palermo BYRADIUS 10 mi COUNT 3 STOREDIST",
+			request: "*11\r\n$14\r\nGEOSEARCHSTORE\r\n$4\r\ndest\r\n$6\r\nSicily\r\n" +
+				"$10\r\nFROMMEMBER\r\n$7\r\nPalermo\r\n$8\r\nBYRADIUS\r\n$2\r\n10\r\n" +
+				"$2\r\nmi\r\n$5\r\nCOUNT\r\n$1\r\n3\r\n$9\r\nSTOREDIST\r\n",
+			want: redis.NewGeoSearchCommand(
+				store, clock, "Sicily",
+				redis.GeoSearchQuery{
+					FromMember: true,
+					Member:     "Palermo",
+					Shape:      redis.GeoShape{Radius: 10, Unit: 1609.34},
+					Count:      3,
+				},
+				redis.GeoSearchStore("dest"), redis.GeoSearchStoreDist(),
+			),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requestReader := strings.NewReader(tt.request)
+
+			command, err := redis.NewParser(zeroValueRedisConfig, store, clock).Parse(requestReader)
+
+			if err != nil {
+				t.Errorf("err: expected: nil; got: %v", err)
+			}
+			if !reflect.DeepEqual(command, tt.want) {
+				t.Errorf("command expected to be %#v but was %#v", tt.want, command)
+			}
+		})
+	}
+}
+
+func TestParser_ParseInvalidGeoRequest(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		request string
+		err     redis.CommandError
+	}{
+		{
+			name:    "GEOADD Sicily 13.361389 38.115556",
+			request: "*4\r\n$6\r\nGEOADD\r\n$6\r\nSicily\r\n$9\r\n13.361389\r\n$9\r\n38.115556\r\n",
+			err:     "ERR wrong number of arguments for 'geoadd' command",
+		},
+		{
+			name: "GEOADD Sicily 13.361389 38.115556 Palermo 15.087269",
+			request: "*6\r\n$6\r\nGEOADD\r\n$6\r\nSicily\r\n$9\r\n13.361389\r\n" +
+				"$9\r\n38.115556\r\n$7\r\nPalermo\r\n$9\r\n15.087269\r\n",
+			err: "ERR syntax error. Try GEOADD key [x1] [y1] [name1] [x2] [y2] [name2] ... ",
+		},
+		{
+			name: "GEOADD Sicily NX XX 13.361389 38.115556 Palermo",
+			request: "*7\r\n$6\r\nGEOADD\r\n$6\r\nSicily\r\n$2\r\nNX\r\n$2\r\nXX\r\n" +
+				"$9\r\n13.361389\r\n$9\r\n38.115556\r\n$7\r\nPalermo\r\n",
+			err: "ERR syntax error. Try GEOADD key [x1] [y1] [name1] [x2] [y2] [name2] ... ",
+		},
+		{
+			name: "GEOADD Sicily GT 13.361389 38.115556 Palermo",
+			request: "*6\r\n$6\r\nGEOADD\r\n$6\r\nSicily\r\n$2\r\nGT\r\n$9\r\n13.361389\r\n" +
+				"$9\r\n38.115556\r\n$7\r\nPalermo\r\n",
+			err: "ERR syntax error. Try GEOADD key [x1] [y1] [name1] [x2] [y2] [name2] ... ",
+		},
+		{
+			name: "GEOADD Sicily east 38.115556 Palermo",
+			request: "*5\r\n$6\r\nGEOADD\r\n$6\r\nSicily\r\n$4\r\neast\r\n" +
+				"$9\r\n38.115556\r\n$7\r\nPalermo\r\n",
+			err: "ERR value is not a valid float",
+		},
+		{
+			name: "GEOADD Sicily 181 38.115556 Palermo",
+			request: "*5\r\n$6\r\nGEOADD\r\n$6\r\nSicily\r\n$3\r\n181\r\n$9\r\n38.115556\r\n" +
+				"$7\r\nPalermo\r\n",
+			err: "ERR invalid longitude,latitude pair 181.000000,38.115556",
+		},
+		{
+			name: "GEOADD Sicily 13.361389 85.1 Palermo",
+			request: "*5\r\n$6\r\nGEOADD\r\n$6\r\nSicily\r\n$9\r\n13.361389\r\n" +
+				"$4\r\n85.1\r\n$7\r\nPalermo\r\n",
+			err: "ERR invalid longitude,latitude pair 13.361389,85.100000",
+		},
+		{
+			name:    "GEODIST Sicily Palermo",
+			request: "*3\r\n$7\r\nGEODIST\r\n$6\r\nSicily\r\n$7\r\nPalermo\r\n",
+			err:     "ERR wrong number of arguments for 'geodist' command",
+		},
+		{
+			name: "GEODIST Sicily Palermo Catania km extra",
+			request: "*6\r\n$7\r\nGEODIST\r\n$6\r\nSicily\r\n$7\r\nPalermo\r\n" +
+				"$7\r\nCatania\r\n$2\r\nkm\r\n$5\r\nextra\r\n",
+			err: "ERR syntax error",
+		},
+		{
+			name: "GEODIST Sicily Palermo Catania yd",
+			request: "*5\r\n$7\r\nGEODIST\r\n$6\r\nSicily\r\n$7\r\nPalermo\r\n" +
+				"$7\r\nCatania\r\n$2\r\nyd\r\n",
+			err: "ERR unsupported unit provided. please use M, KM, FT, MI",
+		},
+		{
+			name:    "GEOPOS",
+			request: "*1\r\n$6\r\nGEOPOS\r\n",
+			err:     "ERR wrong number of arguments for 'geopos' command",
+		},
+		{
+			name:    "GEOHASH",
+			request: "*1\r\n$7\r\nGEOHASH\r\n",
+			err:     "ERR wrong number of arguments for 'geohash' command",
+		},
+		{
+			name: "GEOSEARCH Sicily FROMMEMBER Palermo BYRADIUS 200",
+			request: "*6\r\n$9\r\nGEOSEARCH\r\n$6\r\nSicily\r\n$10\r\nFROMMEMBER\r\n" +
+				"$7\r\nPalermo\r\n$8\r\nBYRADIUS\r\n$3\r\n200\r\n",
+			err: "ERR wrong number of arguments for 'geosearch' command",
+		},
+		{
+			name: "GEOSEARCH Sicily FROMMEMBER Palermo FROMLONLAT 15 37 BYRADIUS 200 km",
+			request: "*10\r\n$9\r\nGEOSEARCH\r\n$6\r\nSicily\r\n$10\r\nFROMMEMBER\r\n" +
+				"$7\r\nPalermo\r\n$10\r\nFROMLONLAT\r\n$2\r\n15\r\n$2\r\n37\r\n" +
+				"$8\r\nBYRADIUS\r\n$3\r\n200\r\n$2\r\nkm\r\n",
+			err: "ERR syntax error",
+		},
+		{
+			name: "GEOSEARCH Sicily FROMMEMBER Palermo BYRADIUS 200 km BYBOX 1 1 km",
+			request: "*11\r\n$9\r\nGEOSEARCH\r\n$6\r\nSicily\r\n$10\r\nFROMMEMBER\r\n" +
+				"$7\r\nPalermo\r\n$8\r\nBYRADIUS\r\n$3\r\n200\r\n$2\r\nkm\r\n" +
+				"$5\r\nBYBOX\r\n$1\r\n1\r\n$1\r\n1\r\n$2\r\nkm\r\n",
+			err: "ERR syntax error",
+		},
+		{
+			name: "GEOSEARCH Sicily BYRADIUS 200 km ASC WITHDIST",
+			request: "*7\r\n$9\r\nGEOSEARCH\r\n$6\r\nSicily\r\n$8\r\nBYRADIUS\r\n" +
+				"$3\r\n200\r\n$2\r\nkm\r\n$3\r\nASC\r\n$8\r\nWITHDIST\r\n",
+			err: "ERR exactly one of FROMMEMBER or FROMLONLAT can be specified for GEOSEARCH",
+		},
+		{
+			name: "GEOSEARCH Sicily FROMMEMBER Palermo ASC WITHDIST WITHHASH",
+			request: "*7\r\n$9\r\nGEOSEARCH\r\n$6\r\nSicily\r\n$10\r\nFROMMEMBER\r\n" +
+				"$7\r\nPalermo\r\n$3\r\nASC\r\n$8\r\nWITHDIST\r\n$8\r\nWITHHASH\r\n",
+			err: "ERR exactly one of BYRADIUS and BYBOX can be specified for GEOSEARCH",
+		},
+		{
+			name: "GEOSEARCH Sicily FROMMEMBER Palermo BYRADIUS far km",
+			request: "*7\r\n$9\r\nGEOSEARCH\r\n$6\r\nSicily\r\n$10\r\nFROMMEMBER\r\n" +
+				"$7\r\nPalermo\r\n$8\r\nBYRADIUS\r\n$3\r\nfar\r\n$2\r\nkm\r\n",
+			err: "ERR need numeric radius",
+		},
+		{
+			name: "GEOSEARCH Sicily FROMMEMBER Palermo BYRADIUS -1 km",
+			request: "*7\r\n$9\r\nGEOSEARCH\r\n$6\r\nSicily\r\n$10\r\nFROMMEMBER\r\n" +
+				"$7\r\nPalermo\r\n$8\r\nBYRADIUS\r\n$2\r\n-1\r\n$2\r\nkm\r\n",
+			err: "ERR radius cannot be negative",
+		},
+		{
+			name: "GEOSEARCH Sicily FROMMEMBER Palermo BYBOX 1 high km",
+			request: "*8\r\n$9\r\nGEOSEARCH\r\n$6\r\nSicily\r\n$10\r\nFROMMEMBER\r\n" +
+				"$7\r\nPalermo\r\n$5\r\nBYBOX\r\n$1\r\n1\r\n$4\r\nhigh\r\n$2\r\nkm\r\n",
+			err: "ERR need numeric height",
+		},
+		{
+			name: "GEOSEARCH Sicily FROMMEMBER Palermo BYBOX 1 -1 km",
+			request: "*8\r\n$9\r\nGEOSEARCH\r\n$6\r\nSicily\r\n$10\r\nFROMMEMBER\r\n" +
+				"$7\r\nPalermo\r\n$5\r\nBYBOX\r\n$1\r\n1\r\n$2\r\n-1\r\n$2\r\nkm\r\n",
+			err: "ERR height or width cannot be negative",
+		},
+		{
+			name: "GEOSEARCH Sicily FROMMEMBER Palermo BYRADIUS 200 yd",
+			request: "*7\r\n$9\r\nGEOSEARCH\r\n$6\r\nSicily\r\n$10\r\nFROMMEMBER\r\n" +
+				"$7\r\nPalermo\r\n$8\r\nBYRADIUS\r\n$3\r\n200\r\n$2\r\nyd\r\n",
+			err: "ERR unsupported unit provided. please use M, KM, FT, MI",
+		},
+		{
+			name: "GEOSEARCH Sicily FROMLONLAT 15 90 BYRADIUS 200 km",
+			request: "*8\r\n$9\r\nGEOSEARCH\r\n$6\r\nSicily\r\n$10\r\nFROMLONLAT\r\n" +
+				"$2\r\n15\r\n$2\r\n90\r\n$8\r\nBYRADIUS\r\n$3\r\n200\r\n$2\r\nkm\r\n",
+			err: "ERR invalid longitude,latitude pair 15.000000,90.000000",
+		},
+		{
+			name: "GEOSEARCH Sicily FROMMEMBER Palermo BYRADIUS 200 km COUNT 0",
+			request: "*9\r\n$9\r\nGEOSEARCH\r\n$6\r\nSicily\r\n$10\r\nFROMMEMBER\r\n" +
+				"$7\r\nPalermo\r\n$8\r\nBYRADIUS\r\n$3\r\n200\r\n$2\r\nkm\r\n" +
+				"$5\r\nCOUNT\r\n$1\r\n0\r\n",
+			err: "ERR COUNT must be > 0",
+		},
+		{
+			name: "GEOSEARCH Sicily FROMMEMBER Palermo BYRADIUS 200 km COUNT few",
+			request: "*9\r\n$9\r\nGEOSEARCH\r\n$6\r\nSicily\r\n$10\r\nFROMMEMBER\r\n" +
+				"$7\r\nPalermo\r\n$8\r\nBYRADIUS\r\n$3\r\n200\r\n$2\r\nkm\r\n" +
+				"$5\r\nCOUNT\r\n$3\r\nfew\r\n",
+			err: "ERR value is not an integer or out of range",
+		},
+		{
+			name: "GEOSEARCH Sicily FROMMEMBER Palermo BYRADIUS 200 km ANY",
+			request: "*8\r\n$9\r\nGEOSEARCH\r\n$6\r\nSicily\r\n$10\r\nFROMMEMBER\r\n" +
+				"$7\r\nPalermo\r\n$8\r\nBYRADIUS\r\n$3\r\n200\r\n$2\r\nkm\r\n" +
+				"$3\r\nANY\r\n",
+			err: "ERR the ANY argument requires COUNT argument",
+		},
+		{
+			name: "GEOSEARCH Sicily FROMMEMBER Palermo BYRADIUS 200 km STOREDIST",
+			request: "*8\r\n$9\r\nGEOSEARCH\r\n$6\r\nSicily\r\n$10\r\nFROMMEMBER\r\n" +
+				"$7\r\nPalermo\r\n$8\r\nBYRADIUS\r\n$3\r\n200\r\n$2\r\nkm\r\n" +
+				"$9\r\nSTOREDIST\r\n",
+			err: "ERR syntax error",
+		},
+		{
+			name: "GEOSEARCHSTORE dest Sicily FROMMEMBER Palermo BYRADIUS 200",
+			request: "*7\r\n$14\r\nGEOSEARCHSTORE\r\n$4\r\ndest\r\n$6\r\nSicily\r\n" +
+				"$10\r\nFROMMEMBER\r\n$7\r\nPalermo\r\n$8\r\nBYRADIUS\r\n$3\r\n200\r\n",
+			err: "ERR wrong number of arguments for 'geosearchstore' command",
+		},
+		{
+			name: "GEOSEARCHSTORE dest Sicily FROMMEMBER Palermo BYRADIUS 200 km WITHDIST",
+			request: "*9\r\n$14\r\nGEOSEARCHSTORE\r\n$4\r\ndest\r\n$6\r\nSicily\r\n" +
+				"$10\r\nFROMMEMBER\r\n$7\r\nPalermo\r\n$8\r\nBYRADIUS\r\n$3\r\n200\r\n" +
+				"$2\r\nkm\r\n$8\r\nWITHDIST\r\n",
+			err: "ERR GEOSEARCHSTORE is not compatible with WITHDIST, WITHHASH and WITHCOORD options",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := redis.NewStore()
+			clock := FakeClock{}
+			requestReader := strings.NewReader(tt.request)
+
+			_, err := redis.NewParser(zeroValueRedisConfig, store, clock).Parse(requestReader)
+
+			if err != tt.err {
+				t.Errorf("err: expected: %v; got: %v", tt.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,320 @@
+package redis
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+func (p Parser) newHSetCommand(array []string) (Command, error) {
+	fieldValues, err := parseFieldValues(array)
+	if err != nil {
+		return nil, err
+	}
+	return NewHSetCommand(p.store, p.clock, p.config, array[1], fieldValues), nil
+}
+
+func (p Parser) newHMSetCommand(array []string) (Command, error) {
+	fieldValues, err := parseFieldValues(array)
+	if err != nil {
+		return nil, err
+	}
+	return NewHSetCommand(p.store, p.clock, p.config, array[1], fieldValues, HSetReplyOK()), nil
+}
+
+// parseFieldValues parses the "key field value [field value ...]" arguments of HSET and HMSET.
+func parseFieldValues(array []string) ([]FieldValue, error) {
+	if len(array) < 4 || len(array)%2 != 0 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	fieldValues := make([]FieldValue, 0, len(array)/2-1)
+	for i := 2; i < len(array); i += 2 {
+		fieldValues = append(fieldValues, FieldValue{Field: array[i], Value: array[i+1]})
+	}
+	return fieldValues, nil
+}
+
+func (p Parser) newHSetNXCommand(array []string) (Command, error) {
+	if len(array) != 4 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	return NewHSetNXCommand(p.store, p.clock, p.config, array[1], array[2], array[3]), nil
+}
+
+func (p Parser) newHGetCommand(array []string) (Command, error) {
+	if len(array) != 3 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	return NewHGetCommand(p.store, p.clock, array[1], array[2]), nil
+}
+
+func (p Parser) newHMGetCommand(array []string) (Command, error) {
+	if len(array) < 3 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	return NewHMGetCommand(p.store, p.clock, array[1], array[2:]), nil
+}
+
+func (p Parser) newHDelCommand(array []string) (Command, error) {
+	if len(array) < 3 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	return NewHDelCommand(p.store, p.clock, array[1], array[2:]), nil
+}
+
+func (p Parser) newHLenCommand(array []string) (Command, error) {
+	if len(array) != 2 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	return NewHLenCommand(p.store, p.clock, array[1]), nil
+}
+
+func (p Parser) newHStrLenCommand(array []string) (Command, error) {
+	if len(array) != 3 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	return NewHStrLenCommand(p.store, p.clock, array[1], array[2]), nil
+}
+
+func (p Parser) newHExistsCommand(array []string) (Command, error) {
+	if len(array) != 3 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	return NewHExistsCommand(p.store, p.clock, array[1], array[2]), nil
+}
+
+func (p Parser) newHKeysCommand(array []string) (Command, error) {
+	if len(array) != 2 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	return NewHKeysCommand(p.store, p.clock, array[1]), nil
+}
+
+func (p Parser) newHValsCommand(array []string) (Command, error) {
+	if len(array) != 2 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	return NewHValsCommand(p.store, p.clock, array[1]), nil
+}
+
+func (p Parser) newHGetAllCommand(array []string) (Command, error) {
+	if len(array) != 2 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	return NewHGetAllCommand(p.store, p.clock, array[1]), nil
+}
+
+func (p Parser) newHIncrByCommand(array []string) (Command, error) {
+	if len(array) != 4 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	increment, err := parseInteger(array[3])
+	if err != nil {
+		return nil, err
+	}
+	return NewHIncrByCommand(p.store, p.clock, p.config, array[1], array[2], increment), nil
+}
+
+func (p Parser) newHIncrByFloatCommand(array []string) (Command, error) {
+	if len(array) != 4 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	increment, err := parseFloat(array[3])
+	if err != nil {
+		return nil, err
+	}
+	return NewHIncrByFloatCommand(p.store, p.clock, p.config, array[1], array[2], increment), nil
+}
+
+func (p Parser) newHRandFieldCommand(array []string) (Command, error) {
+	if len(array) < 2 || len(array) > 4 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	var options []func(*HRandFieldCommand)
+	if len(array) >= 3 {
+		count, err := parseInteger(array[2])
+		if err != nil {
+			return nil, err
+		}
+		if count < -math.MaxInt/2 || count > math.MaxInt/2 {
+			return nil, CommandError("ERR value is out of range")
+		}
+		options = append(options, HRandFieldCount(count))
+	}
+	if len(array) == 4 {
+		if !strings.EqualFold(array[3], "WITHVALUES") {
+			return nil, errSyntax
+		}
+		options = append(options, HRandFieldWithValues())
+	}
+	return NewHRandFieldCommand(p.store, p.clock, array[1], options...), nil
+}
+
+func (p Parser) newHScanCommand(array []string) (Command, error) {
+	if len(array) < 3 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	cursor, err := parseScanCursor(array[2])
+	if err != nil {
+		return nil, err
+	}
+
+	var options []func(*HScanCommand)
+	for i := 3; i < len(array); i++ {
+		switch {
+		case strings.EqualFold(array[i], "MATCH") && i+1 < len(array):
+			options = append(options, HScanMatch(array[i+1]))
+			i++
+		case strings.EqualFold(array[i], "COUNT") && i+1 < len(array):
+			count, err := parseInteger(array[i+1])
+			if err != nil {
+				return nil, err
+			}
+			if count < 1 {
+				return nil, errSyntax
+			}
+			options = append(options, HScanCount(count))
+			i++
+		case strings.EqualFold(array[i], "NOVALUES"):
+			options = append(options, HScanNoValues())
+		default:
+			return nil, errSyntax
+		}
+	}
+	return NewHScanCommand(p.store, p.clock, array[1], cursor, options...), nil
+}
+
+func (p Parser) newHExpireCommand(array []string) (Command, error) {
+	return p.newHExpireCommandWithUnit(array, time.Second, false)
+}
+
+func (p Parser) newHPExpireCommand(array []string) (Command, error) {
+	return p.newHExpireCommandWithUnit(array, time.Millisecond, false)
+}
+
+func (p Parser) newHExpireAtCommand(array []string) (Command, error) {
+	return p.newHExpireCommandWithUnit(array, time.Second, true)
+}
+
+func (p Parser) newHPExpireAtCommand(array []string) (Command, error) {
+	return p.newHExpireCommandWithUnit(array, time.Millisecond, true)
+}
+
+// maxHashFieldExpiryTime is the latest Unix time in milliseconds that a hash field can expire
+// at, like Redis.
+const maxHashFieldExpiryTime = 1<<48 - 1
+
+// newHExpireCommandWithUnit parses HEXPIRE and friends, where the expiry time is in seconds or
+// milliseconds depending on unit, and is a Unix time if absolute is true or relative to now
+// otherwise.
+func (p Parser) newHExpireCommandWithUnit(
+	array []string,
+	unit time.Duration,
+	absolute bool,
+) (Command, error) {
+	if len(array) < 6 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	n, err := parseInteger(array[2])
+	if err != nil {
+		return nil, err
+	}
+	errInvalidExpireTime := CommandError(
+		fmt.Sprintf("ERR invalid expire time in '%s' command", strings.ToLower(array[0])),
+	)
+	if n < 0 || n > maxHashFieldExpiryTime/int(unit.Milliseconds()) {
+		return nil, errInvalidExpireTime
+	}
+	milliseconds := int64(n) * unit.Milliseconds()
+	now := p.clock.NowMonotonic()
+	if !absolute {
+		milliseconds += now.UnixMilli()
+	}
+	if milliseconds > maxHashFieldExpiryTime {
+		return nil, errInvalidExpireTime
+	}
+	// Keep now's monotonic time component, like parseExpiryTime.
+	expiryTime := now.Add(time.UnixMilli(milliseconds).Sub(now))
+
+	condition := ExpireAlways
+	fieldsIndex := 3
+	switch {
+	case strings.EqualFold(array[3], "NX"):
+		condition = ExpireNX
+	case strings.EqualFold(array[3], "XX"):
+		condition = ExpireXX
+	case strings.EqualFold(array[3], "GT"):
+		condition = ExpireGT
+	case strings.EqualFold(array[3], "LT"):
+		condition = ExpireLT
+	}
+	if condition != ExpireAlways {
+		fieldsIndex++
+	}
+	fields, err := parseFieldsArgument(array[fieldsIndex:])
+	if err != nil {
+		return nil, err
+	}
+	return NewHExpireCommand(p.store, p.clock, array[1], expiryTime, condition, fields), nil
+}
+
+// parseFieldsArgument parses the "FIELDS numfields field [field ...]" arguments of HEXPIRE and
+// friends.
+func parseFieldsArgument(arguments []string) ([]string, error) {
+	if len(arguments) < 2 || !strings.EqualFold(arguments[0], "FIELDS") {
+		return nil, CommandError(
+			"ERR Mandatory argument FIELDS is missing or not at the right position",
+		)
+	}
+	numFields, err := parseInteger(arguments[1])
+	if err != nil || numFields <= 0 {
+		return nil, CommandError("ERR Parameter `numFields` should be greater than 0")
+	}
+	if numFields != len(arguments)-2 {
+		return nil, CommandError("ERR The `numfields` parameter must match the number of arguments")
+	}
+	return arguments[2:], nil
+}
+
+func (p Parser) newHTTLCommand(array []string) (Command, error) {
+	return p.newHTTLCommandWithUnit(array, time.Second, false)
+}
+
+func (p Parser) newHPTTLCommand(array []string) (Command, error) {
+	return p.newHTTLCommandWithUnit(array, time.Millisecond, false)
+}
+
+func (p Parser) newHExpireTimeCommand(array []string) (Command, error) {
+	return p.newHTTLCommandWithUnit(array, time.Second, true)
+}
+
+func (p Parser) newHPExpireTimeCommand(array []string) (Command, error) {
+	return p.newHTTLCommandWithUnit(array, time.Millisecond, true)
+}
+
+func (p Parser) newHTTLCommandWithUnit(
+	array []string,
+	unit time.Duration,
+	absolute bool,
+) (Command, error) {
+	if len(array) < 5 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	fields, err := parseFieldsArgument(array[2:])
+	if err != nil {
+		return nil, err
+	}
+	return NewHTTLCommand(p.store, p.clock, array[1], fields, unit, absolute), nil
+}
+
+func (p Parser) newHPersistCommand(array []string) (Command, error) {
+	if len(array) < 5 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	fields, err := parseFieldsArgument(array[2:])
+	if err != nil {
+		return nil, err
+	}
+	return NewHPersistCommand(p.store, p.clock, array[1], fields), nil
+}
@@ -0,0 +1,251 @@
+package redis_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/redis"
+)
+
+func TestParser_ParseHashRequest(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{CurrentTime: time.UnixMilli(1000)}
+
+	tests := []struct {
+		name    string
+		request string
+		want    redis.Command
+	}{
+		{
+			name:    "HSET hash a 1 b 2",
+			request: "*6\r\n$4\r\nHSET\r\n$4\r\nhash\r\n$1\r\na\r\n$1\r\n1\r\n$1\r\nb\r\n$1\r\n2\r\n",
+			want: redis.NewHSetCommand(
+				store, clock, zeroValueRedisConfig, "hash",
+				[]redis.FieldValue{{Field: "a", Value: "1"}, {Field: "b", Value: "2"}},
+			),
+		},
+		{
+			name:    "HMSET hash a 1",
+			request: "*4\r\n$5\r\nHMSET\r\n$4\r\nhash\r\n$1\r\na\r\n$1\r\n1\r\n",
+			want: redis.NewHSetCommand(
+				store, clock, zeroValueRedisConfig, "hash",
+				[]redis.FieldValue{{Field: "a", Value: "1"}}, redis.HSetReplyOK(),
+			),
+		},
+		{
+			name:    "HSETNX hash a 1",
+			request: "*4\r\n$6\r\nHSETNX\r\n$4\r\nhash\r\n$1\r\na\r\n$1\r\n1\r\n",
+			want:    redis.NewHSetNXCommand(store, clock, zeroValueRedisConfig, "hash", "a", "1"),
+		},
+		{
+			name:    "HGET hash a",
+			request: "*3\r\n$4\r\nHGET\r\n$4\r\nhash\r\n$1\r\na\r\n",
+			want:    redis.NewHGetCommand(store, clock, "hash", "a"),
+		},
+		{
+			name:    "HMGET hash a b",
+			request: "*4\r\n$5\r\nHMGET\r\n$4\r\nhash\r\n$1\r\na\r\n$1\r\nb\r\n",
+			want:    redis.NewHMGetCommand(store, clock, "hash", []string{"a", "b"}),
+		},
+		{
+			name:    "HDEL hash a",
+			request: "*3\r\n$4\r\nHDEL\r\n$4\r\nhash\r\n$1\r\na\r\n",
+			want:    redis.NewHDelCommand(store, clock, "hash", []string{"a"}),
+		},
+		{
+			name:    "HLEN hash",
+			request: "*2\r\n$4\r\nHLEN\r\n$4\r\nhash\r\n",
+			want:    redis.NewHLenCommand(store, clock, "hash"),
+		},
+		{
+			name:    "HSTRLEN hash a",
+			request: "*3\r\n$7\r\nHSTRLEN\r\n$4\r\nhash\r\n$1\r\na\r\n",
+			want:    redis.NewHStrLenCommand(store, clock, "hash", "a"),
+		},
+		{
+			name:    "HEXISTS hash a",
+			request: "*3\r\n$7\r\nHEXISTS\r\n$4\r\nhash\r\n$1\r\na\r\n",
+			want:    redis.NewHExistsCommand(store, clock, "hash", "a"),
+		},
+		{
+			name:    "HKEYS hash",
+			request: "*2\r\n$5\r\nHKEYS\r\n$4\r\nhash\r\n",
+			want:    redis.NewHKeysCommand(store, clock, "hash"),
+		},
+		{
+			name:    "HVALS hash",
+			request: "*2\r\n$5\r\nHVALS\r\n$4\r\nhash\r\n",
+			want:    redis.NewHValsCommand(store, clock, "hash"),
+		},
+		{
+			name:    "HGETALL hash",
+			request: "*2\r\n$7\r\nHGETALL\r\n$4\r\nhash\r\n",
+			want:    redis.NewHGetAllCommand(store, clock, "hash"),
+		},
+		{
+			name:    "HINCRBY hash a -3",
+			request: "*4\r\n$7\r\nHINCRBY\r\n$4\r\nhash\r\n$1\r\na\r\n$2\r\n-3\r\n",
+			want:    redis.NewHIncrByCommand(store, clock, zeroValueRedisConfig, "hash", "a", -3),
+		},
+		{
+			name:    "HINCRBYFLOAT hash a 1.5",
+			request: "*4\r\n$12\r\nHINCRBYFLOAT\r\n$4\r\nhash\r\n$1\r\na\r\n$3\r\n1.5\r\n",
+			want:    redis.NewHIncrByFloatCommand(store, clock, zeroValueRedisConfig, "hash", "a", 1.5),
+		},
+		{
+			name:    "HRANDFIELD hash -2 WITHVALUES",
+			request: "*4\r\n$10\r\nHRANDFIELD\r\n$4\r\nhash\r\n$2\r\n-2\r\n$10\r\nWITHVALUES\r\n",
+			want: redis.NewHRandFieldCommand(
+				store, clock, "hash", redis.HRandFieldCount(-2), redis.HRandFieldWithValues(),
+			),
+		},
+		{
+			name: "HSCAN hash 0 MATCH a* COUNT 5 NOVALUES",
+			request: "*8\r\n$5\r\nHSCAN\r\n$4\r\nhash\r\n$1\r\n0\r\n$5\r\nMATCH\r\n$2\r\na*\r\n" +
+				"$5\r\nCOUNT\r\n$1\r\n5\r\n$8\r\nNOVALUES\r\n",
+			want: redis.NewHScanCommand(
+				store, clock, "hash", 0, redis.HScanMatch("a*"), redis.HScanCount(5), redis.HScanNoValues(),
+			),
+		},
+		{
+			name: "HEXPIRE hash 10 NX FIELDS 1 a",
+			request: "*7\r\n$7\r\nHEXPIRE\r\n$4\r\nhash\r\n$2\r\n10\r\n$2\r\nNX\r\n" +
+				"$6\r\nFIELDS\r\n$1\r\n1\r\n$1\r\na\r\n",
+			want: redis.NewHExpireCommand(
+				store, clock, "hash", time.UnixMilli(11000), redis.ExpireNX, []string{"a"},
+			),
+		},
+		{
+			name: "HPEXPIREAT hash 1500 FIELDS 2 a b",
+			request: "*7\r\n$10\r\nHPEXPIREAT\r\n$4\r\nhash\r\n$4\r\n1500\r\n" +
+				"$6\r\nFIELDS\r\n$1\r\n2\r\n$1\r\na\r\n$1\r\nb\r\n",
+			want: redis.NewHExpireCommand(
+				store, clock, "hash", time.UnixMilli(1500), redis.ExpireAlways, []string{"a", "b"},
+			),
+		},
+		{
+			name:    "HTTL hash FIELDS 1 a",
+			request: "*5\r\n$4\r\nHTTL\r\n$4\r\nhash\r\n$6\r\nFIELDS\r\n$1\r\n1\r\n$1\r\na\r\n",
+			want:    redis.NewHTTLCommand(store, clock, "hash", []string{"a"}, time.Second, false),
+		},
+		{
+			name:    "HPEXPIRETIME hash FIELDS 1 a",
+			request: "*5\r\n$12\r\nHPEXPIRETIME\r\n$4\r\nhash\r\n$6\r\nFIELDS\r\n$1\r\n1\r\n$1\r\na\r\n",
+			want:    redis.NewHTTLCommand(store, clock, "hash", []string{"a"}, time.Millisecond, true),
+		},
+		{
+			name:    "HPERSIST hash FIELDS 1 a",
+			request: "*5\r\n$8\r\nHPERSIST\r\n$4\r\nhash\r\n$6\r\nFIELDS\r\n$1\r\n1\r\n$1\r\na\r\n",
+			want:    redis.NewHPersistCommand(store, clock, "hash", []string{"a"}),
+		},
+		{
+			name:    "OBJECT ENCODING hash",
+			request: "*3\r\n$6\r\nOBJECT\r\n$8\r\nENCODING\r\n$4\r\nhash\r\n",
+			want:    redis.NewObjectEncodingCommand(store, clock, "hash"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requestReader := strings.NewReader(tt.request)
+
+			command, err := redis.NewParser(zeroValueRedisConfig, store, clock).Parse(requestReader)
+
+			if err != nil {
+				t.Errorf("err: expected: nil; got: %v", err)
+			}
+			if !reflect.DeepEqual(command, tt.want) {
+				t.Errorf("command expected to be %#v but was %#v", tt.want, command)
+			}
+		})
+	}
+}
+
+func TestParser_ParseInvalidHashRequest(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		request string
+		err     redis.CommandError
+	}{
+		{
+			name:    "HSET hash a",
+			request: "*3\r\n$4\r\nHSET\r\n$4\r\nhash\r\n$1\r\na\r\n",
+			err:     "ERR wrong number of arguments for 'hset' command",
+		},
+		{
+			name:    "HINCRBY hash a x",
+			request: "*4\r\n$7\r\nHINCRBY\r\n$4\r\nhash\r\n$1\r\na\r\n$1\r\nx\r\n",
+			err:     "ERR value is not an integer or out of range",
+		},
+		{
+			name:    "HINCRBYFLOAT hash a x",
+			request: "*4\r\n$12\r\nHINCRBYFLOAT\r\n$4\r\nhash\r\n$1\r\na\r\n$1\r\nx\r\n",
+			err:     "ERR value is not a valid float",
+		},
+		{
+			name:    "HRANDFIELD hash 1 VALUES",
+			request: "*4\r\n$10\r\nHRANDFIELD\r\n$4\r\nhash\r\n$1\r\n1\r\n$6\r\nVALUES\r\n",
+			err:     "ERR syntax error",
+		},
+		{
+			name:    "HSCAN hash x",
+			request: "*3\r\n$5\r\nHSCAN\r\n$4\r\nhash\r\n$1\r\nx\r\n",
+			err:     "ERR invalid cursor",
+		},
+		{
+			name:    "HSCAN hash 0 COUNT 0",
+			request: "*5\r\n$5\r\nHSCAN\r\n$4\r\nhash\r\n$1\r\n0\r\n$5\r\nCOUNT\r\n$1\r\n0\r\n",
+			err:     "ERR syntax error",
+		},
+		{
+			name:    "HEXPIRE hash 10 a b c",
+			request: "*6\r\n$7\r\nHEXPIRE\r\n$4\r\nhash\r\n$2\r\n10\r\n$1\r\na\r\n$1\r\nb\r\n$1\r\nc\r\n",
+			err:     "ERR Mandatory argument FIELDS is missing or not at the right position",
+		},
+		{
+			name:    "HEXPIRE hash 10 FIELDS 0 a",
+			request: "*6\r\n$7\r\nHEXPIRE\r\n$4\r\nhash\r\n$2\r\n10\r\n$6\r\nFIELDS\r\n$1\r\n0\r\n$1\r\na\r\n",
+			err:     "ERR Parameter `numFields` should be greater than 0",
+		},
+		{
+			name:    "HEXPIRE hash 10 FIELDS 2 a",
+			request: "*6\r\n$7\r\nHEXPIRE\r\n$4\r\nhash\r\n$2\r\n10\r\n$6\r\nFIELDS\r\n$1\r\n2\r\n$1\r\na\r\n",
+			err:     "ERR The `numfields` parameter must match the number of arguments",
+		},
+		{
+			name:    "HEXPIRE hash -1 FIELDS 1 a",
+			request: "*6\r\n$7\r\nHEXPIRE\r\n$4\r\nhash\r\n$2\r\n-1\r\n$6\r\nFIELDS\r\n$1\r\n1\r\n$1\r\na\r\n",
+			err:     "ERR invalid expire time in 'hexpire' command",
+		},
+		{
+			name:    "HTTL hash FIELDS x a",
+			request: "*5\r\n$4\r\nHTTL\r\n$4\r\nhash\r\n$6\r\nFIELDS\r\n$1\r\nx\r\n$1\r\na\r\n",
+			err:     "ERR Parameter `numFields` should be greater than 0",
+		},
+		{
+			name:    "OBJECT FREQ hash",
+			request: "*3\r\n$6\r\nOBJECT\r\n$4\r\nFREQ\r\n$4\r\nhash\r\n",
+			err:     "ERR unknown subcommand 'FREQ'. Try OBJECT HELP.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := redis.NewStore()
+			clock := FakeClock{}
+			requestReader := strings.NewReader(tt.request)
+
+			_, err := redis.NewParser(zeroValueRedisConfig, store, clock).Parse(requestReader)
+
+			if err != tt.err {
+				t.Errorf("err: expected: %v; got: %v", tt.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package redis
+
+import (
+	"fmt"
+	"strings"
+)
+
+func (p Parser) newPFAddCommand(array []string) (Command, error) {
+	if len(array) < 2 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	return NewPFAddCommand(p.store, p.clock, p.config, array[1], array[2:]), nil
+}
+
+func (p Parser) newPFCountCommand(array []string) (Command, error) {
+	if len(array) < 2 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	return NewPFCountCommand(p.store, p.clock, array[1:]), nil
+}
+
+func (p Parser) newPFMergeCommand(array []string) (Command, error) {
+	if len(array) < 2 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	return NewPFMergeCommand(p.store, p.clock, p.config, array[1], array[2:]), nil
+}
+
+func (p Parser) newPFDebugCommand(array []string) (Command, error) {
+	if len(array) != 3 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	var subcommand PFDebugSubcommand
+	switch {
+	case strings.EqualFold(array[1], "GETREG"):
+		subcommand = PFDebugGetReg
+	case strings.EqualFold(array[1], "DECODE"):
+		subcommand = PFDebugDecode
+	case strings.EqualFold(array[1], "ENCODING"):
+		subcommand = PFDebugEncoding
+	case strings.EqualFold(array[1], "TODENSE"):
+		subcommand = PFDebugToDense
+	default:
+		return nil, CommandError(fmt.Sprintf("ERR Unknown PFDEBUG subcommand '%s'", array[1]))
+	}
+	return NewPFDebugCommand(p.store, p.clock, subcommand, array[2]), nil
+}
+
+func (p Parser) newPFSelfTestCommand(array []string) (Command, error) {
+	if len(array) != 1 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	return NewPFSelfTestCommand(p.config), nil
+}
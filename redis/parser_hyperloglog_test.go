@@ -0,0 +1,147 @@
+package redis_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/codecrafters-io/redis-starter-go/redis"
+)
+
+func TestParser_ParseHyperLogLogRequest(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{}
+
+	tests := []struct {
+		name    string
+		request string
+		want    redis.Command
+	}{
+		{
+			name:    "PFADD hll",
+			request: "*2\r\n$5\r\nPFADD\r\n$3\r\nhll\r\n",
+			want:    redis.NewPFAddCommand(store, clock, zeroValueRedisConfig, "hll", []string{}),
+		},
+		{
+			name:    "PFADD hll a b",
+			request: "*4\r\n$5\r\nPFADD\r\n$3\r\nhll\r\n$1\r\na\r\n$1\r\nb\r\n",
+			want: redis.NewPFAddCommand(
+				store, clock, zeroValueRedisConfig, "hll", []string{"a", "b"},
+			),
+		},
+		{
+			name:    "PFCOUNT a b",
+			request: "*3\r\n$7\r\nPFCOUNT\r\n$1\r\na\r\n$1\r\nb\r\n",
+			want:    redis.NewPFCountCommand(store, clock, []string{"a", "b"}),
+		},
+		{
+			name:    "PFMERGE dest",
+			request: "*2\r\n$7\r\nPFMERGE\r\n$4\r\ndest\r\n",
+			want:    redis.NewPFMergeCommand(store, clock, zeroValueRedisConfig, "dest", []string{}),
+		},
+		{
+			name:    "PFMERGE dest a b",
+			request: "*4\r\n$7\r\nPFMERGE\r\n$4\r\ndest\r\n$1\r\na\r\n$1\r\nb\r\n",
+			want: redis.NewPFMergeCommand(
+				store, clock, zeroValueRedisConfig, "dest", []string{"a", "b"},
+			),
+		},
+		{
+			name:    "PFDEBUG GETREG hll",
+			request: "*3\r\n$7\r\nPFDEBUG\r\n$6\r\nGETREG\r\n$3\r\nhll\r\n",
+			want:    redis.NewPFDebugCommand(store, clock, redis.PFDebugGetReg, "hll"),
+		},
+		{
+			name:    "PFDEBUG decode hll",
+			request: "*3\r\n$7\r\nPFDEBUG\r\n$6\r\ndecode\r\n$3\r\nhll\r\n",
+			want:    redis.NewPFDebugCommand(store, clock, redis.PFDebugDecode, "hll"),
+		},
+		{
+			name:    "PFDEBUG ENCODING hll",
+			request: "*3\r\n$7\r\nPFDEBUG\r\n$8\r\nENCODING\r\n$3\r\nhll\r\n",
+			want:    redis.NewPFDebugCommand(store, clock, redis.PFDebugEncoding, "hll"),
+		},
+		{
+			name:    "PFDEBUG TODENSE hll",
+			request: "*3\r\n$7\r\nPFDEBUG\r\n$7\r\nTODENSE\r\n$3\r\nhll\r\n",
+			want:    redis.NewPFDebugCommand(store, clock, redis.PFDebugToDense, "hll"),
+		},
+		{
+			name:    "PFSELFTEST",
+			request: "*1\r\n$10\r\nPFSELFTEST\r\n",
+			want:    redis.NewPFSelfTestCommand(zeroValueRedisConfig),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requestReader := strings.NewReader(tt.request)
+
+			command, err := redis.NewParser(zeroValueRedisConfig, store, clock).Parse(requestReader)
+
+			if err != nil {
+				t.Errorf("err: expected: nil; got: %v", err)
+			}
+			if !reflect.DeepEqual(command, tt.want) {
+				t.Errorf("command expected to be %#v but was %#v", tt.want, command)
+			}
+		})
+	}
+}
+
+func TestParser_ParseInvalidHyperLogLogRequest(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		request string
+		err     redis.CommandError
+	}{
+		{
+			name:    "PFADD",
+			request: "*1\r\n$5\r\nPFADD\r\n",
+			err:     "ERR wrong number of arguments for 'pfadd' command",
+		},
+		{
+			name:    "PFCOUNT",
+			request: "*1\r\n$7\r\nPFCOUNT\r\n",
+			err:     "ERR wrong number of arguments for 'pfcount' command",
+		},
+		{
+			name:    "PFMERGE",
+			request: "*1\r\n$7\r\nPFMERGE\r\n",
+			err:     "ERR wrong number of arguments for 'pfmerge' command",
+		},
+		{
+			name:    "PFDEBUG GETREG",
+			request: "*2\r\n$7\r\nPFDEBUG\r\n$6\r\nGETREG\r\n",
+			err:     "ERR wrong number of arguments for 'pfdebug' command",
+		},
+		{
+			name:    "PFDEBUG FOO hll",
+			request: "*3\r\n$7\r\nPFDEBUG\r\n$3\r\nFOO\r\n$3\r\nhll\r\n",
+			err:     "ERR Unknown PFDEBUG subcommand 'FOO'",
+		},
+		{
+			name:    "PFSELFTEST now",
+			request: "*2\r\n$10\r\nPFSELFTEST\r\n$3\r\nnow\r\n",
+			err:     "ERR wrong number of arguments for 'pfselftest' command",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := redis.NewStore()
+			clock := FakeClock{}
+			requestReader := strings.NewReader(tt.request)
+
+			_, err := redis.NewParser(zeroValueRedisConfig, store, clock).Parse(requestReader)
+
+			if err != tt.err {
+				t.Errorf("err: expected: %v; got: %v", tt.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package redis
+
+import (
+	"strings"
+)
+
+// rootJSONPath is the legacy path to the root of a document, which is the default path of most
+// JSON commands.
+var rootJSONPath = &JSONPath{text: ".", legacy: true}
+
+// parseOptionalJSONPath parses the path at index i of array, or returns the root if there isn't
+// one.
+func parseOptionalJSONPath(array []string, i int) (*JSONPath, error) {
+	if i >= len(array) {
+		return rootJSONPath, nil
+	}
+	return ParseJSONPath(array[i])
+}
+
+func (p Parser) newJSONSetCommand(array []string) (Command, error) {
+	if len(array) != 4 && len(array) != 5 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	path, err := ParseJSONPath(array[2])
+	if err != nil {
+		return nil, err
+	}
+	var options []func(*JSONSetCommand)
+	if len(array) == 5 {
+		switch {
+		case strings.EqualFold(array[4], "NX"):
+			options = append(options, JSONSetNX())
+		case strings.EqualFold(array[4], "XX"):
+			options = append(options, JSONSetXX())
+		default:
+			return nil, errSyntax
+		}
+	}
+	return NewJSONSetCommand(p.store, p.clock, array[1], path, array[3], options...), nil
+}
+
+func (p Parser) newJSONGetCommand(array []string) (Command, error) {
+	if len(array) < 2 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	var format JSONFormat
+	i := 2
+options:
+	for ; i+1 < len(array); i += 2 {
+		switch {
+		case strings.EqualFold(array[i], "INDENT"):
+			format.Indent = array[i+1]
+		case strings.EqualFold(array[i], "NEWLINE"):
+			format.Newline = array[i+1]
+		case strings.EqualFold(array[i], "SPACE"):
+			format.Space = array[i+1]
+		default:
+			break options
+		}
+	}
+	paths := []*JSONPath{rootJSONPath}
+	if i < len(array) {
+		paths = make([]*JSONPath, len(array)-i)
+		for k, path := range array[i:] {
+			var err error
+			if paths[k], err = ParseJSONPath(path); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return NewJSONGetCommand(p.store, p.clock, array[1], paths, format), nil
+}
+
+func (p Parser) newJSONMGetCommand(array []string) (Command, error) {
+	if len(array) < 3 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	path, err := ParseJSONPath(array[len(array)-1])
+	if err != nil {
+		return nil, err
+	}
+	return NewJSONMGetCommand(p.store, p.clock, array[1:len(array)-1], path), nil
+}
+
+func (p Parser) newJSONDelCommand(array []string) (Command, error) {
+	if len(array) != 2 && len(array) != 3 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	path, err := parseOptionalJSONPath(array, 2)
+	if err != nil {
+		return nil, err
+	}
+	return NewJSONDelCommand(p.store, p.clock, array[1], path), nil
+}
+
+func (p Parser) newJSONTypeCommand(array []string) (Command, error) {
+	if len(array) != 2 && len(array) != 3 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	path, err := parseOptionalJSONPath(array, 2)
+	if err != nil {
+		return nil, err
+	}
+	return NewJSONTypeCommand(p.store, p.clock, array[1], path), nil
+}
+
+func (p Parser) newJSONNumIncrByCommand(array []string) (Command, error) {
+	if len(array) != 4 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	path, err := ParseJSONPath(array[2])
+	if err != nil {
+		return nil, err
+	}
+	return NewJSONNumIncrByCommand(p.store, p.clock, array[1], path, array[3]), nil
+}
+
+func (p Parser) newJSONArrAppendCommand(array []string) (Command, error) {
+	if len(array) < 4 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	path, err := ParseJSONPath(array[2])
+	if err != nil {
+		return nil, err
+	}
+	return NewJSONArrAppendCommand(p.store, p.clock, array[1], path, array[3:]), nil
+}
+
+func (p Parser) newJSONArrPopCommand(array []string) (Command, error) {
+	if len(array) < 2 || len(array) > 4 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	path, err := parseOptionalJSONPath(array, 2)
+	if err != nil {
+		return nil, err
+	}
+	index := -1
+	if len(array) == 4 {
+		if index, err = parseInteger(array[3]); err != nil {
+			return nil, err
+		}
+	}
+	return NewJSONArrPopCommand(p.store, p.clock, array[1], path, index), nil
+}
+
+func (p Parser) newJSONObjKeysCommand(array []string) (Command, error) {
+	if len(array) != 2 && len(array) != 3 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	path, err := parseOptionalJSONPath(array, 2)
+	if err != nil {
+		return nil, err
+	}
+	return NewJSONObjKeysCommand(p.store, p.clock, array[1], path), nil
+}
+
+func (p Parser) newJSONStrAppendCommand(array []string) (Command, error) {
+	if len(array) != 3 && len(array) != 4 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	path := rootJSONPath
+	if len(array) == 4 {
+		var err error
+		if path, err = ParseJSONPath(array[2]); err != nil {
+			return nil, err
+		}
+	}
+	return NewJSONStrAppendCommand(p.store, p.clock, array[1], path, array[len(array)-1]), nil
+}
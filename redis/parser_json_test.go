@@ -0,0 +1,251 @@
+package redis_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/codecrafters-io/redis-starter-go/redis"
+)
+
+func TestParser_ParseJSONRequest(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{}
+
+	tests := []struct {
+		name    string
+		request string
+		want    redis.Command
+	}{
+		{
+			name:    "JSON.SET doc $ {\"a\":1}",
+			request: "*4\r\n$8\r\nJSON.SET\r\n$3\r\ndoc\r\n$1\r\n$\r\n$7\r\n{\"a\":1}\r\n",
+			want:    redis.NewJSONSetCommand(store, clock, "doc", jsonPath(t, "$"), `{"a":1}`),
+		},
+		{
+			name:    "JSON.SET doc .a 2 nx",
+			request: "*5\r\n$8\r\nJSON.SET\r\n$3\r\ndoc\r\n$2\r\n.a\r\n$1\r\n2\r\n$2\r\nnx\r\n",
+			want: redis.NewJSONSetCommand(
+				store, clock, "doc", jsonPath(t, ".a"), "2", redis.JSONSetNX(),
+			),
+		},
+		{
+			name:    "JSON.SET doc $.a 2 XX",
+			request: "*5\r\n$8\r\nJSON.SET\r\n$3\r\ndoc\r\n$3\r\n$.a\r\n$1\r\n2\r\n$2\r\nXX\r\n",
+			want: redis.NewJSONSetCommand(
+				store, clock, "doc", jsonPath(t, "$.a"), "2", redis.JSONSetXX(),
+			),
+		},
+		{
+			name:    "JSON.GET doc",
+			request: "*2\r\n$8\r\nJSON.GET\r\n$3\r\ndoc\r\n",
+			want: redis.NewJSONGetCommand(
+				store, clock, "doc", []*redis.JSONPath{jsonPath(t, ".")}, redis.JSONFormat{},
+			),
+		},
+		{
+			name:    "JSON.GET doc $..a b[0]",
+			request: "*4\r\n$8\r\nJSON.GET\r\n$3\r\ndoc\r\n$4\r\n$..a\r\n$4\r\nb[0]\r\n",
+			want: redis.NewJSONGetCommand(
+				store, clock, "doc", []*redis.JSONPath{jsonPath(t, "$..a"), jsonPath(t, "b[0]")},
+				redis.JSONFormat{},
+			),
+		},
+		{
+			name: "JSON.GET doc indent -- newline nl SPACE _ $",
+			request: "*9\r\n$8\r\nJSON.GET\r\n$3\r\ndoc\r\n$6\r\nindent\r\n$2\r\n--\r\n" +
+				"$7\r\nnewline\r\n$2\r\nnl\r\n$5\r\nSPACE\r\n$1\r\n_\r\n$1\r\n$\r\n",
+			want: redis.NewJSONGetCommand(
+				store, clock, "doc", []*redis.JSONPath{jsonPath(t, "$")},
+				redis.JSONFormat{Indent: "--", Newline: "nl", Space: "_"},
+			),
+		},
+		{
+			name:    "JSON.MGET a b $.x",
+			request: "*4\r\n$9\r\nJSON.MGET\r\n$1\r\na\r\n$1\r\nb\r\n$3\r\n$.x\r\n",
+			want:    redis.NewJSONMGetCommand(store, clock, []string{"a", "b"}, jsonPath(t, "$.x")),
+		},
+		{
+			name:    "JSON.DEL doc",
+			request: "*2\r\n$8\r\nJSON.DEL\r\n$3\r\ndoc\r\n",
+			want:    redis.NewJSONDelCommand(store, clock, "doc", jsonPath(t, ".")),
+		},
+		{
+			name:    "JSON.DEL doc $.a[1:]",
+			request: "*3\r\n$8\r\nJSON.DEL\r\n$3\r\ndoc\r\n$7\r\n$.a[1:]\r\n",
+			want:    redis.NewJSONDelCommand(store, clock, "doc", jsonPath(t, "$.a[1:]")),
+		},
+		{
+			name:    "JSON.TYPE doc",
+			request: "*2\r\n$9\r\nJSON.TYPE\r\n$3\r\ndoc\r\n",
+			want:    redis.NewJSONTypeCommand(store, clock, "doc", jsonPath(t, ".")),
+		},
+		{
+			name:    "JSON.TYPE doc $.*",
+			request: "*3\r\n$9\r\nJSON.TYPE\r\n$3\r\ndoc\r\n$3\r\n$.*\r\n",
+			want:    redis.NewJSONTypeCommand(store, clock, "doc", jsonPath(t, "$.*")),
+		},
+		{
+			name:    "JSON.NUMINCRBY doc $.a -1.5",
+			request: "*4\r\n$14\r\nJSON.NUMINCRBY\r\n$3\r\ndoc\r\n$3\r\n$.a\r\n$4\r\n-1.5\r\n",
+			want:    redis.NewJSONNumIncrByCommand(store, clock, "doc", jsonPath(t, "$.a"), "-1.5"),
+		},
+		{
+			name: "JSON.ARRAPPEND doc $.a 1 \"x\"",
+			request: "*5\r\n$14\r\nJSON.ARRAPPEND\r\n$3\r\ndoc\r\n$3\r\n$.a\r\n$1\r\n1\r\n" +
+				"$3\r\n\"x\"\r\n",
+			want: redis.NewJSONArrAppendCommand(
+				store, clock, "doc", jsonPath(t, "$.a"), []string{"1", `"x"`},
+			),
+		},
+		{
+			name:    "JSON.ARRPOP doc",
+			request: "*2\r\n$11\r\nJSON.ARRPOP\r\n$3\r\ndoc\r\n",
+			want:    redis.NewJSONArrPopCommand(store, clock, "doc", jsonPath(t, "."), -1),
+		},
+		{
+			name:    "JSON.ARRPOP doc $.a 0",
+			request: "*4\r\n$11\r\nJSON.ARRPOP\r\n$3\r\ndoc\r\n$3\r\n$.a\r\n$1\r\n0\r\n",
+			want:    redis.NewJSONArrPopCommand(store, clock, "doc", jsonPath(t, "$.a"), 0),
+		},
+		{
+			name:    "JSON.OBJKEYS doc",
+			request: "*2\r\n$12\r\nJSON.OBJKEYS\r\n$3\r\ndoc\r\n",
+			want:    redis.NewJSONObjKeysCommand(store, clock, "doc", jsonPath(t, ".")),
+		},
+		{
+			name:    "JSON.OBJKEYS doc $..b",
+			request: "*3\r\n$12\r\nJSON.OBJKEYS\r\n$3\r\ndoc\r\n$4\r\n$..b\r\n",
+			want:    redis.NewJSONObjKeysCommand(store, clock, "doc", jsonPath(t, "$..b")),
+		},
+		{
+			name:    "JSON.STRAPPEND doc \"x\"",
+			request: "*3\r\n$14\r\nJSON.STRAPPEND\r\n$3\r\ndoc\r\n$3\r\n\"x\"\r\n",
+			want:    redis.NewJSONStrAppendCommand(store, clock, "doc", jsonPath(t, "."), `"x"`),
+		},
+		{
+			name:    "JSON.STRAPPEND doc $.a \"x\"",
+			request: "*4\r\n$14\r\nJSON.STRAPPEND\r\n$3\r\ndoc\r\n$3\r\n$.a\r\n$3\r\n\"x\"\r\n",
+			want:    redis.NewJSONStrAppendCommand(store, clock, "doc", jsonPath(t, "$.a"), `"x"`),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requestReader := strings.NewReader(tt.request)
+
+			command, err := redis.NewParser(zeroValueRedisConfig, store, clock).Parse(requestReader)
+
+			if err != nil {
+				t.Errorf("err: expected: nil; got: %v", err)
+			}
+			if !reflect.DeepEqual(command, tt.want) {
+				t.Errorf("command expected to be %#v but was %#v", tt.want, command)
+			}
+		})
+	}
+}
+
+func TestParser_ParseInvalidJSONRequest(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		request string
+		err     redis.CommandError
+	}{
+		{
+			name:    "JSON.SET doc $",
+			request: "*3\r\n$8\r\nJSON.SET\r\n$3\r\ndoc\r\n$1\r\n$\r\n",
+			err:     "ERR wrong number of arguments for 'json.set' command",
+		},
+		{
+			name: "JSON.SET doc $ 1 NX XX",
+			request: "*6\r\n$8\r\nJSON.SET\r\n$3\r\ndoc\r\n$1\r\n$\r\n$1\r\n1\r\n" +
+				"$2\r\nNX\r\n$2\r\nXX\r\n",
+			err: "ERR wrong number of arguments for 'json.set' command",
+		},
+		{
+			name:    "JSON.SET doc $ 1 GT",
+			request: "*5\r\n$8\r\nJSON.SET\r\n$3\r\ndoc\r\n$1\r\n$\r\n$1\r\n1\r\n$2\r\nGT\r\n",
+			err:     "ERR syntax error",
+		},
+		{
+			name:    "JSON.SET doc $.a[ 1",
+			request: "*4\r\n$8\r\nJSON.SET\r\n$3\r\ndoc\r\n$4\r\n$.a[\r\n$1\r\n1\r\n",
+			err:     "ERR invalid JSONPath '$.a['",
+		},
+		{
+			name:    "JSON.GET",
+			request: "*1\r\n$8\r\nJSON.GET\r\n",
+			err:     "ERR wrong number of arguments for 'json.get' command",
+		},
+		{
+			name:    "JSON.GET doc $.a]",
+			request: "*3\r\n$8\r\nJSON.GET\r\n$3\r\ndoc\r\n$4\r\n$.a]\r\n",
+			err:     "ERR invalid JSONPath '$.a]'",
+		},
+		{
+			name:    "JSON.MGET doc",
+			request: "*2\r\n$9\r\nJSON.MGET\r\n$3\r\ndoc\r\n",
+			err:     "ERR wrong number of arguments for 'json.mget' command",
+		},
+		{
+			name:    "JSON.DEL doc $ extra",
+			request: "*4\r\n$8\r\nJSON.DEL\r\n$3\r\ndoc\r\n$1\r\n$\r\n$5\r\nextra\r\n",
+			err:     "ERR wrong number of arguments for 'json.del' command",
+		},
+		{
+			name:    "JSON.TYPE",
+			request: "*1\r\n$9\r\nJSON.TYPE\r\n",
+			err:     "ERR wrong number of arguments for 'json.type' command",
+		},
+		{
+			name:    "JSON.NUMINCRBY doc $.a",
+			request: "*3\r\n$14\r\nJSON.NUMINCRBY\r\n$3\r\ndoc\r\n$3\r\n$.a\r\n",
+			err:     "ERR wrong number of arguments for 'json.numincrby' command",
+		},
+		{
+			name:    "JSON.ARRAPPEND doc $.a",
+			request: "*3\r\n$14\r\nJSON.ARRAPPEND\r\n$3\r\ndoc\r\n$3\r\n$.a\r\n",
+			err:     "ERR wrong number of arguments for 'json.arrappend' command",
+		},
+		{
+			name:    "JSON.ARRPOP doc $.a one",
+			request: "*4\r\n$11\r\nJSON.ARRPOP\r\n$3\r\ndoc\r\n$3\r\n$.a\r\n$3\r\none\r\n",
+			err:     "ERR value is not an integer or out of range",
+		},
+		{
+			name: "JSON.ARRPOP doc $.a 0 extra",
+			request: "*5\r\n$11\r\nJSON.ARRPOP\r\n$3\r\ndoc\r\n$3\r\n$.a\r\n$1\r\n0\r\n" +
+				"$5\r\nextra\r\n",
+			err: "ERR wrong number of arguments for 'json.arrpop' command",
+		},
+		{
+			name:    "JSON.OBJKEYS doc $ extra",
+			request: "*4\r\n$12\r\nJSON.OBJKEYS\r\n$3\r\ndoc\r\n$1\r\n$\r\n$5\r\nextra\r\n",
+			err:     "ERR wrong number of arguments for 'json.objkeys' command",
+		},
+		{
+			name:    "JSON.STRAPPEND doc",
+			request: "*2\r\n$14\r\nJSON.STRAPPEND\r\n$3\r\ndoc\r\n",
+			err:     "ERR wrong number of arguments for 'json.strappend' command",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := redis.NewStore()
+			clock := FakeClock{}
+			requestReader := strings.NewReader(tt.request)
+
+			_, err := redis.NewParser(zeroValueRedisConfig, store, clock).Parse(requestReader)
+
+			if err != tt.err {
+				t.Errorf("err: expected: %v; got: %v", tt.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,340 @@
+package redis
+
+import "strings"
+
+func (p Parser) newLPushCommand(array []string) (Command, error) {
+	return p.newPushCommand(array, ListEndLeft)
+}
+
+func (p Parser) newRPushCommand(array []string) (Command, error) {
+	return p.newPushCommand(array, ListEndRight)
+}
+
+func (p Parser) newLPushXCommand(array []string) (Command, error) {
+	return p.newPushCommand(array, ListEndLeft, PushOnlyIfExists())
+}
+
+func (p Parser) newRPushXCommand(array []string) (Command, error) {
+	return p.newPushCommand(array, ListEndRight, PushOnlyIfExists())
+}
+
+func (p Parser) newPushCommand(
+	array []string,
+	end ListEnd,
+	options ...func(*PushCommand),
+) (Command, error) {
+	if len(array) < 3 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	return NewPushCommand(p.store, p.clock, array[1], end, array[2:], options...), nil
+}
+
+func (p Parser) newLPopCommand(array []string) (Command, error) {
+	return p.newPopCommand(array, ListEndLeft)
+}
+
+func (p Parser) newRPopCommand(array []string) (Command, error) {
+	return p.newPopCommand(array, ListEndRight)
+}
+
+func (p Parser) newPopCommand(array []string, end ListEnd) (Command, error) {
+	if len(array) != 2 && len(array) != 3 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	var count *int
+	if len(array) == 3 {
+		c, err := parsePositiveInteger(array[2])
+		if err != nil {
+			return nil, err
+		}
+		count = &c
+	}
+	return NewPopCommand(p.store, p.clock, array[1], end, count), nil
+}
+
+// parsePositiveInteger is like parseInteger, but also returns an error if s is negative.
+func parsePositiveInteger(s string) (int, error) {
+	i, err := parseInteger(s)
+	if err != nil {
+		return 0, err
+	}
+	if i < 0 {
+		return 0, CommandError("ERR value is out of range, must be positive")
+	}
+	return i, nil
+}
+
+func (p Parser) newLLenCommand(array []string) (Command, error) {
+	if len(array) != 2 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	return NewLLenCommand(p.store, p.clock, array[1]), nil
+}
+
+func (p Parser) newLRangeCommand(array []string) (Command, error) {
+	if len(array) != 4 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	start, err := parseInteger(array[2])
+	if err != nil {
+		return nil, err
+	}
+	stop, err := parseInteger(array[3])
+	if err != nil {
+		return nil, err
+	}
+	return NewLRangeCommand(p.store, p.clock, array[1], start, stop), nil
+}
+
+func (p Parser) newLIndexCommand(array []string) (Command, error) {
+	if len(array) != 3 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	index, err := parseInteger(array[2])
+	if err != nil {
+		return nil, err
+	}
+	return NewLIndexCommand(p.store, p.clock, array[1], index), nil
+}
+
+func (p Parser) newLSetCommand(array []string) (Command, error) {
+	if len(array) != 4 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	index, err := parseInteger(array[2])
+	if err != nil {
+		return nil, err
+	}
+	return NewLSetCommand(p.store, p.clock, array[1], index, array[3]), nil
+}
+
+func (p Parser) newLRemCommand(array []string) (Command, error) {
+	if len(array) != 4 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	count, err := parseInteger(array[2])
+	if err != nil {
+		return nil, err
+	}
+	return NewLRemCommand(p.store, p.clock, array[1], count, array[3]), nil
+}
+
+func (p Parser) newLTrimCommand(array []string) (Command, error) {
+	if len(array) != 4 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	start, err := parseInteger(array[2])
+	if err != nil {
+		return nil, err
+	}
+	stop, err := parseInteger(array[3])
+	if err != nil {
+		return nil, err
+	}
+	return NewLTrimCommand(p.store, p.clock, array[1], start, stop), nil
+}
+
+func (p Parser) newLInsertCommand(array []string) (Command, error) {
+	if len(array) != 5 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	var before bool
+	switch {
+	case strings.EqualFold(array[2], "BEFORE"):
+		before = true
+	case strings.EqualFold(array[2], "AFTER"):
+		before = false
+	default:
+		return nil, errSyntax
+	}
+	return NewLInsertCommand(p.store, p.clock, array[1], before, array[3], array[4]), nil
+}
+
+func (p Parser) newLPosCommand(array []string) (Command, error) {
+	if len(array) < 3 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+
+	var options []func(*LPosCommand)
+	for i := 3; i < len(array); i += 2 {
+		if i+1 >= len(array) {
+			return nil, errSyntax
+		}
+		n, err := parseInteger(array[i+1])
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case strings.EqualFold(array[i], "RANK"):
+			if n == 0 {
+				return nil, CommandError(
+					"ERR RANK can't be zero: use 1 to start from the first match, " +
+						"2 from the second ... or use negative to start from the end of the list",
+				)
+			}
+			options = append(options, LPosRank(n))
+		case strings.EqualFold(array[i], "COUNT"):
+			if n < 0 {
+				return nil, CommandError("ERR COUNT can't be negative")
+			}
+			options = append(options, LPosCount(n))
+		case strings.EqualFold(array[i], "MAXLEN"):
+			if n < 0 {
+				return nil, CommandError("ERR MAXLEN can't be negative")
+			}
+			options = append(options, LPosMaxLen(n))
+		default:
+			return nil, errSyntax
+		}
+	}
+	return NewLPosCommand(p.store, p.clock, array[1], array[2], options...), nil
+}
+
+func (p Parser) newLMoveCommand(array []string) (Command, error) {
+	if len(array) != 5 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	from, err := parseListEnd(array[3])
+	if err != nil {
+		return nil, err
+	}
+	to, err := parseListEnd(array[4])
+	if err != nil {
+		return nil, err
+	}
+	return NewLMoveCommand(p.store, p.clock, array[1], array[2], from, to), nil
+}
+
+func (p Parser) newRPopLPushCommand(array []string) (Command, error) {
+	if len(array) != 3 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	return NewLMoveCommand(p.store, p.clock, array[1], array[2], ListEndRight, ListEndLeft), nil
+}
+
+func parseListEnd(s string) (ListEnd, error) {
+	switch {
+	case strings.EqualFold(s, "LEFT"):
+		return ListEndLeft, nil
+	case strings.EqualFold(s, "RIGHT"):
+		return ListEndRight, nil
+	}
+	return 0, errSyntax
+}
+
+func (p Parser) newLMPopCommand(array []string) (Command, error) {
+	if len(array) < 4 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	keys, end, count, err := parseMPopArguments(array[1:])
+	if err != nil {
+		return nil, err
+	}
+	return NewLMPopCommand(p.store, p.clock, keys, end, count), nil
+}
+
+// parseMPopArguments parses the "numkeys key [key ...] LEFT|RIGHT [COUNT count]" arguments of
+// LMPOP and BLMPOP.
+func parseMPopArguments(arguments []string) (keys []string, end ListEnd, count int, err error) {
+	numKeys, err := parseInteger(arguments[0])
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	if numKeys <= 0 {
+		return nil, 0, 0, CommandError("ERR numkeys should be greater than 0")
+	}
+	if numKeys+1 >= len(arguments) {
+		return nil, 0, 0, errSyntax
+	}
+	keys = arguments[1 : numKeys+1]
+	end, err = parseListEnd(arguments[numKeys+1])
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	count = 1
+	options := arguments[numKeys+2:]
+	switch {
+	case len(options) == 0:
+	case len(options) == 2 && strings.EqualFold(options[0], "COUNT"):
+		count, err = parseInteger(options[1])
+		if err != nil {
+			return nil, 0, 0, err
+		}
+		if count <= 0 {
+			return nil, 0, 0, CommandError("ERR count should be greater than 0")
+		}
+	default:
+		return nil, 0, 0, errSyntax
+	}
+	return keys, end, count, nil
+}
+
+func (p Parser) newBLPopCommand(array []string) (Command, error) {
+	return p.newBlockingPopCommand(array, ListEndLeft)
+}
+
+func (p Parser) newBRPopCommand(array []string) (Command, error) {
+	return p.newBlockingPopCommand(array, ListEndRight)
+}
+
+func (p Parser) newBlockingPopCommand(array []string, end ListEnd) (Command, error) {
+	if len(array) < 3 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	timeout, err := parseTimeout(array[len(array)-1])
+	if err != nil {
+		return nil, err
+	}
+	keys := array[1 : len(array)-1]
+	return NewBlockingPopCommand(p.store, p.clock, p.client, keys, end, timeout), nil
+}
+
+func (p Parser) newBLMPopCommand(array []string) (Command, error) {
+	if len(array) < 5 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	timeout, err := parseTimeout(array[1])
+	if err != nil {
+		return nil, err
+	}
+	keys, end, count, err := parseMPopArguments(array[2:])
+	if err != nil {
+		return nil, err
+	}
+	return NewBLMPopCommand(p.store, p.clock, p.client, keys, end, count, timeout), nil
+}
+
+func (p Parser) newBLMoveCommand(array []string) (Command, error) {
+	if len(array) != 6 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	from, err := parseListEnd(array[3])
+	if err != nil {
+		return nil, err
+	}
+	to, err := parseListEnd(array[4])
+	if err != nil {
+		return nil, err
+	}
+	timeout, err := parseTimeout(array[5])
+	if err != nil {
+		return nil, err
+	}
+	return NewBLMoveCommand(
+		p.store, p.clock, p.client, array[1], array[2], from, to, timeout,
+	), nil
+}
+
+func (p Parser) newBRPopLPushCommand(array []string) (Command, error) {
+	if len(array) != 4 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	timeout, err := parseTimeout(array[3])
+	if err != nil {
+		return nil, err
+	}
+	return NewBLMoveCommand(
+		p.store, p.clock, p.client, array[1], array[2], ListEndRight, ListEndLeft, timeout,
+	), nil
+}
@@ -0,0 +1,261 @@
+package redis_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/redis"
+)
+
+func TestParser_ParseListRequest(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{}
+
+	tests := []struct {
+		name    string
+		request string
+		want    redis.Command
+	}{
+		{
+			name:    "LPUSH list a b",
+			request: "*4\r\n$5\r\nLPUSH\r\n$4\r\nlist\r\n$1\r\na\r\n$1\r\nb\r\n",
+			want:    redis.NewPushCommand(store, clock, "list", redis.ListEndLeft, []string{"a", "b"}),
+		},
+		{
+			name:    "rpushx list a",
+			request: "*3\r\n$6\r\nrpushx\r\n$4\r\nlist\r\n$1\r\na\r\n",
+			want: redis.NewPushCommand(
+				store, clock, "list", redis.ListEndRight, []string{"a"}, redis.PushOnlyIfExists(),
+			),
+		},
+		{
+			name:    "LPOP list",
+			request: "*2\r\n$4\r\nLPOP\r\n$4\r\nlist\r\n",
+			want:    redis.NewPopCommand(store, clock, "list", redis.ListEndLeft, nil),
+		},
+		{
+			name:    "RPOP list 2",
+			request: "*3\r\n$4\r\nRPOP\r\n$4\r\nlist\r\n$1\r\n2\r\n",
+			want:    redis.NewPopCommand(store, clock, "list", redis.ListEndRight, ptr(2)),
+		},
+		{
+			name:    "LLEN list",
+			request: "*2\r\n$4\r\nLLEN\r\n$4\r\nlist\r\n",
+			want:    redis.NewLLenCommand(store, clock, "list"),
+		},
+		{
+			name:    "LRANGE list 0 -1",
+			request: "*4\r\n$6\r\nLRANGE\r\n$4\r\nlist\r\n$1\r\n0\r\n$2\r\n-1\r\n",
+			want:    redis.NewLRangeCommand(store, clock, "list", 0, -1),
+		},
+		{
+			name:    "LINDEX list -2",
+			request: "*3\r\n$6\r\nLINDEX\r\n$4\r\nlist\r\n$2\r\n-2\r\n",
+			want:    redis.NewLIndexCommand(store, clock, "list", -2),
+		},
+		{
+			name:    "LSET list 1 a",
+			request: "*4\r\n$4\r\nLSET\r\n$4\r\nlist\r\n$1\r\n1\r\n$1\r\na\r\n",
+			want:    redis.NewLSetCommand(store, clock, "list", 1, "a"),
+		},
+		{
+			name:    "LREM list -1 a",
+			request: "*4\r\n$4\r\nLREM\r\n$4\r\nlist\r\n$2\r\n-1\r\n$1\r\na\r\n",
+			want:    redis.NewLRemCommand(store, clock, "list", -1, "a"),
+		},
+		{
+			name:    "LTRIM list 1 -1",
+			request: "*4\r\n$5\r\nLTRIM\r\n$4\r\nlist\r\n$1\r\n1\r\n$2\r\n-1\r\n",
+			want:    redis.NewLTrimCommand(store, clock, "list", 1, -1),
+		},
+		{
+			name:    "LINSERT list after a b",
+			request: "*5\r\n$7\r\nLINSERT\r\n$4\r\nlist\r\n$5\r\nafter\r\n$1\r\na\r\n$1\r\nb\r\n",
+			want:    redis.NewLInsertCommand(store, clock, "list", false, "a", "b"),
+		},
+		{
+			name: "LPOS list a RANK -1 COUNT 2 MAXLEN 10",
+			request: "*9\r\n$4\r\nLPOS\r\n$4\r\nlist\r\n$1\r\na\r\n$4\r\nRANK\r\n$2\r\n-1\r\n" +
+				"$5\r\nCOUNT\r\n$1\r\n2\r\n$6\r\nMAXLEN\r\n$2\r\n10\r\n",
+			want: redis.NewLPosCommand(
+				store,
+				clock,
+				"list",
+				"a",
+				redis.LPosRank(-1),
+				redis.LPosCount(2),
+				redis.LPosMaxLen(10),
+			),
+		},
+		{
+			name:    "LMOVE list other RIGHT left",
+			request: "*5\r\n$5\r\nLMOVE\r\n$4\r\nlist\r\n$5\r\nother\r\n$5\r\nRIGHT\r\n$4\r\nleft\r\n",
+			want:    redis.NewLMoveCommand(store, clock, "list", "other", redis.ListEndRight, redis.ListEndLeft),
+		},
+		{
+			name:    "LMPOP 2 list other RIGHT COUNT 3",
+			request: "*7\r\n$5\r\nLMPOP\r\n$1\r\n2\r\n$4\r\nlist\r\n$5\r\nother\r\n$5\r\nRIGHT\r\n$5\r\nCOUNT\r\n$1\r\n3\r\n",
+			want:    redis.NewLMPopCommand(store, clock, []string{"list", "other"}, redis.ListEndRight, 3),
+		},
+		{
+			name:    "BLPOP list other 0",
+			request: "*4\r\n$5\r\nBLPOP\r\n$4\r\nlist\r\n$5\r\nother\r\n$1\r\n0\r\n",
+			want: redis.NewBlockingPopCommand(
+				store, clock, nil, []string{"list", "other"}, redis.ListEndLeft, 0,
+			),
+		},
+		{
+			name:    "BRPOP list 0.0015",
+			request: "*3\r\n$5\r\nBRPOP\r\n$4\r\nlist\r\n$6\r\n0.0015\r\n",
+			want: redis.NewBlockingPopCommand(
+				store, clock, nil, []string{"list"}, redis.ListEndRight, 2*time.Millisecond,
+			),
+		},
+		{
+			name:    "BLMPOP 1.5 1 list LEFT",
+			request: "*5\r\n$6\r\nBLMPOP\r\n$3\r\n1.5\r\n$1\r\n1\r\n$4\r\nlist\r\n$4\r\nLEFT\r\n",
+			want: redis.NewBLMPopCommand(
+				store, clock, nil, []string{"list"}, redis.ListEndLeft, 1, 1500*time.Millisecond,
+			),
+		},
+		{
+			name:    "BLMOVE list other LEFT RIGHT 10",
+			request: "*6\r\n$6\r\nBLMOVE\r\n$4\r\nlist\r\n$5\r\nother\r\n$4\r\nLEFT\r\n$5\r\nRIGHT\r\n$2\r\n10\r\n",
+			want: redis.NewBLMoveCommand(
+				store, clock, nil, "list", "other", redis.ListEndLeft, redis.ListEndRight, 10*time.Second,
+			),
+		},
+		{
+			name:    "BRPOPLPUSH list other 0",
+			request: "*4\r\n$10\r\nBRPOPLPUSH\r\n$4\r\nlist\r\n$5\r\nother\r\n$1\r\n0\r\n",
+			want: redis.NewBLMoveCommand(
+				store, clock, nil, "list", "other", redis.ListEndRight, redis.ListEndLeft, 0,
+			),
+		},
+		{
+			name:    "RPOPLPUSH list other",
+			request: "*3\r\n$9\r\nRPOPLPUSH\r\n$4\r\nlist\r\n$5\r\nother\r\n",
+			want:    redis.NewLMoveCommand(store, clock, "list", "other", redis.ListEndRight, redis.ListEndLeft),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requestReader := strings.NewReader(tt.request)
+
+			command, err := redis.NewParser(zeroValueRedisConfig, store, clock).Parse(requestReader)
+
+			if err != nil {
+				t.Errorf("err: expected: nil; got: %v", err)
+			}
+			if !reflect.DeepEqual(command, tt.want) {
+				t.Errorf("command expected to be %#v but was %#v", tt.want, command)
+			}
+		})
+	}
+}
+
+func TestParser_ParseInvalidListRequest(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		request string
+		err     redis.CommandError
+	}{
+		{
+			name:    "LPUSH list",
+			request: "*2\r\n$5\r\nLPUSH\r\n$4\r\nlist\r\n",
+			err:     "ERR wrong number of arguments for 'lpush' command",
+		},
+		{
+			name:    "LPOP list -1",
+			request: "*3\r\n$4\r\nLPOP\r\n$4\r\nlist\r\n$2\r\n-1\r\n",
+			err:     "ERR value is out of range, must be positive",
+		},
+		{
+			name:    "LRANGE list a 1",
+			request: "*4\r\n$6\r\nLRANGE\r\n$4\r\nlist\r\n$1\r\na\r\n$1\r\n1\r\n",
+			err:     "ERR value is not an integer or out of range",
+		},
+		{
+			name:    "LINSERT list during a b",
+			request: "*5\r\n$7\r\nLINSERT\r\n$4\r\nlist\r\n$6\r\nduring\r\n$1\r\na\r\n$1\r\nb\r\n",
+			err:     "ERR syntax error",
+		},
+		{
+			name:    "LPOS list a RANK 0",
+			request: "*5\r\n$4\r\nLPOS\r\n$4\r\nlist\r\n$1\r\na\r\n$4\r\nRANK\r\n$1\r\n0\r\n",
+			err: "ERR RANK can't be zero: use 1 to start from the first match, " +
+				"2 from the second ... or use negative to start from the end of the list",
+		},
+		{
+			name:    "LPOS list a COUNT -1",
+			request: "*5\r\n$4\r\nLPOS\r\n$4\r\nlist\r\n$1\r\na\r\n$5\r\nCOUNT\r\n$2\r\n-1\r\n",
+			err:     "ERR COUNT can't be negative",
+		},
+		{
+			name:    "LPOS list a RANK",
+			request: "*4\r\n$4\r\nLPOS\r\n$4\r\nlist\r\n$1\r\na\r\n$4\r\nRANK\r\n",
+			err:     "ERR syntax error",
+		},
+		{
+			name:    "LMPOP 0 list LEFT",
+			request: "*4\r\n$5\r\nLMPOP\r\n$1\r\n0\r\n$4\r\nlist\r\n$4\r\nLEFT\r\n",
+			err:     "ERR numkeys should be greater than 0",
+		},
+		{
+			name:    "LMPOP 2 list LEFT",
+			request: "*4\r\n$5\r\nLMPOP\r\n$1\r\n2\r\n$4\r\nlist\r\n$4\r\nLEFT\r\n",
+			err:     "ERR syntax error",
+		},
+		{
+			name:    "LMPOP 1 list LEFT COUNT 0",
+			request: "*6\r\n$5\r\nLMPOP\r\n$1\r\n1\r\n$4\r\nlist\r\n$4\r\nLEFT\r\n$5\r\nCOUNT\r\n$1\r\n0\r\n",
+			err:     "ERR count should be greater than 0",
+		},
+		{
+			name:    "BLPOP list -1",
+			request: "*3\r\n$5\r\nBLPOP\r\n$4\r\nlist\r\n$2\r\n-1\r\n",
+			err:     "ERR timeout is negative",
+		},
+		{
+			name:    "BLPOP list soon",
+			request: "*3\r\n$5\r\nBLPOP\r\n$4\r\nlist\r\n$4\r\nsoon\r\n",
+			err:     "ERR timeout is not a float or out of range",
+		},
+		{
+			name:    "BLPOP list 1e300",
+			request: "*3\r\n$5\r\nBLPOP\r\n$4\r\nlist\r\n$5\r\n1e300\r\n",
+			err:     "ERR timeout is out of range",
+		},
+		{
+			name:    "BLPOP list",
+			request: "*2\r\n$5\r\nBLPOP\r\n$4\r\nlist\r\n",
+			err:     "ERR wrong number of arguments for 'blpop' command",
+		},
+		{
+			name:    "LMOVE list other UP LEFT",
+			request: "*5\r\n$5\r\nLMOVE\r\n$4\r\nlist\r\n$5\r\nother\r\n$2\r\nUP\r\n$4\r\nLEFT\r\n",
+			err:     "ERR syntax error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := redis.NewStore()
+			clock := FakeClock{}
+			requestReader := strings.NewReader(tt.request)
+
+			_, err := redis.NewParser(zeroValueRedisConfig, store, clock).Parse(requestReader)
+
+			if err != tt.err {
+				t.Errorf("err: expected: %v; got: %v", tt.err, err)
+			}
+		})
+	}
+}
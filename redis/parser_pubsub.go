@@ -0,0 +1,118 @@
+package redis
+
+import (
+	"fmt"
+	"strings"
+)
+
+// allowedWhenSubscribed returns true if the command called name can be sent in subscribed mode.
+func allowedWhenSubscribed(name string) bool {
+	switch strings.ToUpper(name) {
+	case "SUBSCRIBE", "UNSUBSCRIBE", "PSUBSCRIBE", "PUNSUBSCRIBE", "SSUBSCRIBE", "SUNSUBSCRIBE",
+		"PING", "QUIT", "RESET":
+		return true
+	}
+	return false
+}
+
+func (p Parser) newSubscribeCommand(array []string) (Command, error) {
+	if len(array) < 2 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	return NewSubscribeCommand(p.pubSub, p.client, array[1:]), nil
+}
+
+func (p Parser) newUnsubscribeCommand(array []string) (Command, error) {
+	return NewUnsubscribeCommand(p.pubSub, p.client, array[1:]), nil
+}
+
+func (p Parser) newPSubscribeCommand(array []string) (Command, error) {
+	if len(array) < 2 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	return NewPSubscribeCommand(p.pubSub, p.client, array[1:]), nil
+}
+
+func (p Parser) newPUnsubscribeCommand(array []string) (Command, error) {
+	return NewPUnsubscribeCommand(p.pubSub, p.client, array[1:]), nil
+}
+
+func (p Parser) newSSubscribeCommand(array []string) (Command, error) {
+	if len(array) < 2 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	if err := sameSlot(array[1:]); err != nil {
+		return nil, err
+	}
+	return NewSSubscribeCommand(p.pubSub, p.client, array[1:]), nil
+}
+
+func (p Parser) newSUnsubscribeCommand(array []string) (Command, error) {
+	if err := sameSlot(array[1:]); err != nil {
+		return nil, err
+	}
+	return NewSUnsubscribeCommand(p.pubSub, p.client, array[1:]), nil
+}
+
+// sameSlot returns errCrossSlot unless the shard channels all hash to the same cluster slot, as
+// they would have to be served by the same node.
+func sameSlot(channels []string) error {
+	for _, channel := range channels {
+		if keyHashSlot(channel) != keyHashSlot(channels[0]) {
+			return errCrossSlot
+		}
+	}
+	return nil
+}
+
+func (p Parser) newPublishCommand(array []string) (Command, error) {
+	if len(array) != 3 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	return NewPublishCommand(p.pubSub, array[1], array[2]), nil
+}
+
+func (p Parser) newSPublishCommand(array []string) (Command, error) {
+	if len(array) != 3 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	return NewSPublishCommand(p.pubSub, array[1], array[2]), nil
+}
+
+func (p Parser) newPubSubCommand(array []string) (Command, error) {
+	if len(array) < 2 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	switch {
+	case strings.EqualFold(array[1], "CHANNELS"):
+		if len(array) > 3 {
+			return nil, wrongNumberOfArgumentsError([]string{"pubsub|channels"})
+		}
+		pattern := "*"
+		if len(array) == 3 {
+			pattern = array[2]
+		}
+		return NewPubSubChannelsCommand(p.pubSub, pattern), nil
+	case strings.EqualFold(array[1], "NUMPAT"):
+		if len(array) != 2 {
+			return nil, wrongNumberOfArgumentsError([]string{"pubsub|numpat"})
+		}
+		return NewPubSubNumPatCommand(p.pubSub), nil
+	case strings.EqualFold(array[1], "NUMSUB"):
+		return NewPubSubNumSubCommand(p.pubSub, array[2:]), nil
+	case strings.EqualFold(array[1], "SHARDCHANNELS"):
+		if len(array) > 3 {
+			return nil, wrongNumberOfArgumentsError([]string{"pubsub|shardchannels"})
+		}
+		pattern := "*"
+		if len(array) == 3 {
+			pattern = array[2]
+		}
+		return NewPubSubShardChannelsCommand(p.pubSub, pattern), nil
+	case strings.EqualFold(array[1], "SHARDNUMSUB"):
+		return NewPubSubShardNumSubCommand(p.pubSub, array[2:]), nil
+	}
+	return nil, CommandError(
+		fmt.Sprintf("ERR unknown subcommand '%s'. Try PUBSUB HELP.", array[1]),
+	)
+}
@@ -0,0 +1,328 @@
+package redis_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/codecrafters-io/redis-starter-go/redis"
+)
+
+func TestParser_ParsePubSubRequest(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{}
+	pubSub := redis.NewPubSub(clock, redis.OutputBufferLimit{})
+
+	tests := []struct {
+		name    string
+		request string
+		want    redis.Command
+	}{
+		{
+			name:    "SUBSCRIBE news",
+			request: "*2\r\n$9\r\nSUBSCRIBE\r\n$4\r\nnews\r\n",
+			want:    redis.NewSubscribeCommand(pubSub, nil, []string{"news"}),
+		},
+		{
+			name:    "subscribe news sport",
+			request: "*3\r\n$9\r\nsubscribe\r\n$4\r\nnews\r\n$5\r\nsport\r\n",
+			want:    redis.NewSubscribeCommand(pubSub, nil, []string{"news", "sport"}),
+		},
+		{
+			name:    "UNSUBSCRIBE",
+			request: "*1\r\n$11\r\nUNSUBSCRIBE\r\n",
+			want:    redis.NewUnsubscribeCommand(pubSub, nil, []string{}),
+		},
+		{
+			name:    "UNSUBSCRIBE news",
+			request: "*2\r\n$11\r\nUNSUBSCRIBE\r\n$4\r\nnews\r\n",
+			want:    redis.NewUnsubscribeCommand(pubSub, nil, []string{"news"}),
+		},
+		{
+			name:    "PUBLISH news hello",
+			request: "*3\r\n$7\r\nPUBLISH\r\n$4\r\nnews\r\n$5\r\nhello\r\n",
+			want:    redis.NewPublishCommand(pubSub, "news", "hello"),
+		},
+		{
+			name:    "PUBSUB CHANNELS",
+			request: "*2\r\n$6\r\nPUBSUB\r\n$8\r\nCHANNELS\r\n",
+			want:    redis.NewPubSubChannelsCommand(pubSub, "*"),
+		},
+		{
+			name:    "pubsub channels n*",
+			request: "*3\r\n$6\r\npubsub\r\n$8\r\nchannels\r\n$2\r\nn*\r\n",
+			want:    redis.NewPubSubChannelsCommand(pubSub, "n*"),
+		},
+		{
+			name:    "PUBSUB NUMSUB",
+			request: "*2\r\n$6\r\nPUBSUB\r\n$6\r\nNUMSUB\r\n",
+			want:    redis.NewPubSubNumSubCommand(pubSub, []string{}),
+		},
+		{
+			name:    "PUBSUB NUMSUB news sport",
+			request: "*4\r\n$6\r\nPUBSUB\r\n$6\r\nNUMSUB\r\n$4\r\nnews\r\n$5\r\nsport\r\n",
+			want:    redis.NewPubSubNumSubCommand(pubSub, []string{"news", "sport"}),
+		},
+		{
+			name:    "PING hello",
+			request: "*2\r\n$4\r\nPING\r\n$5\r\nhello\r\n",
+			want:    redis.EchoCommand("hello"),
+		},
+		{
+			name:    "QUIT",
+			request: "*1\r\n$4\r\nQUIT\r\n",
+			want:    redis.NewQuitCommand(nil),
+		},
+		{
+			name:    "RESET",
+			request: "*1\r\n$5\r\nRESET\r\n",
+			want:    redis.NewResetCommand(pubSub, nil),
+		},
+		{
+			name:    "PSUBSCRIBE news.* h?llo",
+			request: "*3\r\n$10\r\nPSUBSCRIBE\r\n$6\r\nnews.*\r\n$5\r\nh?llo\r\n",
+			want:    redis.NewPSubscribeCommand(pubSub, nil, []string{"news.*", "h?llo"}),
+		},
+		{
+			name:    "PUNSUBSCRIBE",
+			request: "*1\r\n$12\r\nPUNSUBSCRIBE\r\n",
+			want:    redis.NewPUnsubscribeCommand(pubSub, nil, []string{}),
+		},
+		{
+			name:    "PUNSUBSCRIBE news.*",
+			request: "*2\r\n$12\r\nPUNSUBSCRIBE\r\n$6\r\nnews.*\r\n",
+			want:    redis.NewPUnsubscribeCommand(pubSub, nil, []string{"news.*"}),
+		},
+		{
+			name:    "PUBSUB NUMPAT",
+			request: "*2\r\n$6\r\nPUBSUB\r\n$6\r\nNUMPAT\r\n",
+			want:    redis.NewPubSubNumPatCommand(pubSub),
+		},
+		{
+			name:    "SSUBSCRIBE {user}a {user}b",
+			request: "*3\r\n$10\r\nSSUBSCRIBE\r\n$7\r\n{user}a\r\n$7\r\n{user}b\r\n",
+			want:    redis.NewSSubscribeCommand(pubSub, nil, []string{"{user}a", "{user}b"}),
+		},
+		{
+			name:    "SUNSUBSCRIBE",
+			request: "*1\r\n$12\r\nSUNSUBSCRIBE\r\n",
+			want:    redis.NewSUnsubscribeCommand(pubSub, nil, []string{}),
+		},
+		{
+			name:    "SUNSUBSCRIBE {user}a",
+			request: "*2\r\n$12\r\nSUNSUBSCRIBE\r\n$7\r\n{user}a\r\n",
+			want:    redis.NewSUnsubscribeCommand(pubSub, nil, []string{"{user}a"}),
+		},
+		{
+			name:    "SPUBLISH {user}a hello",
+			request: "*3\r\n$8\r\nSPUBLISH\r\n$7\r\n{user}a\r\n$5\r\nhello\r\n",
+			want:    redis.NewSPublishCommand(pubSub, "{user}a", "hello"),
+		},
+		{
+			name:    "PUBSUB SHARDCHANNELS",
+			request: "*2\r\n$6\r\nPUBSUB\r\n$13\r\nSHARDCHANNELS\r\n",
+			want:    redis.NewPubSubShardChannelsCommand(pubSub, "*"),
+		},
+		{
+			name:    "pubsub shardchannels {user}*",
+			request: "*3\r\n$6\r\npubsub\r\n$13\r\nshardchannels\r\n$7\r\n{user}*\r\n",
+			want:    redis.NewPubSubShardChannelsCommand(pubSub, "{user}*"),
+		},
+		{
+			name:    "PUBSUB SHARDNUMSUB {user}a",
+			request: "*3\r\n$6\r\nPUBSUB\r\n$11\r\nSHARDNUMSUB\r\n$7\r\n{user}a\r\n",
+			want:    redis.NewPubSubShardNumSubCommand(pubSub, []string{"{user}a"}),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requestReader := strings.NewReader(tt.request)
+
+			command, err := redis.NewParser(zeroValueRedisConfig, store, clock).Parse(requestReader)
+
+			if err != nil {
+				t.Errorf("err: expected: nil; got: %v", err)
+			}
+			if !reflect.DeepEqual(command, tt.want) {
+				t.Errorf("command expected to be %#v but was %#v", tt.want, command)
+			}
+		})
+	}
+}
+
+func TestParser_ParseInvalidPubSubRequest(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		request string
+		err     redis.CommandError
+	}{
+		{
+			name:    "SUBSCRIBE",
+			request: "*1\r\n$9\r\nSUBSCRIBE\r\n",
+			err:     "ERR wrong number of arguments for 'subscribe' command",
+		},
+		{
+			name:    "PUBLISH news",
+			request: "*2\r\n$7\r\nPUBLISH\r\n$4\r\nnews\r\n",
+			err:     "ERR wrong number of arguments for 'publish' command",
+		},
+		{
+			name:    "PUBSUB",
+			request: "*1\r\n$6\r\nPUBSUB\r\n",
+			err:     "ERR wrong number of arguments for 'pubsub' command",
+		},
+		{
+			name:    "PUBSUB CHANNELS a b",
+			request: "*4\r\n$6\r\nPUBSUB\r\n$8\r\nCHANNELS\r\n$1\r\na\r\n$1\r\nb\r\n",
+			err:     "ERR wrong number of arguments for 'pubsub|channels' command",
+		},
+		{
+			name:    "PUBSUB SHARDS",
+			request: "*2\r\n$6\r\nPUBSUB\r\n$6\r\nSHARDS\r\n",
+			err:     "ERR unknown subcommand 'SHARDS'. Try PUBSUB HELP.",
+		},
+		{
+			name:    "PING a b",
+			request: "*3\r\n$4\r\nPING\r\n$1\r\na\r\n$1\r\nb\r\n",
+			err:     "ERR wrong number of arguments for 'ping' command",
+		},
+		{
+			name:    "RESET now",
+			request: "*2\r\n$5\r\nRESET\r\n$3\r\nnow\r\n",
+			err:     "ERR wrong number of arguments for 'reset' command",
+		},
+		{
+			name:    "PSUBSCRIBE",
+			request: "*1\r\n$10\r\nPSUBSCRIBE\r\n",
+			err:     "ERR wrong number of arguments for 'psubscribe' command",
+		},
+		{
+			name:    "PUBSUB NUMPAT a",
+			request: "*3\r\n$6\r\nPUBSUB\r\n$6\r\nNUMPAT\r\n$1\r\na\r\n",
+			err:     "ERR wrong number of arguments for 'pubsub|numpat' command",
+		},
+		{
+			name:    "SSUBSCRIBE",
+			request: "*1\r\n$10\r\nSSUBSCRIBE\r\n",
+			err:     "ERR wrong number of arguments for 'ssubscribe' command",
+		},
+		{
+			name:    "SSUBSCRIBE foo bar",
+			request: "*3\r\n$10\r\nSSUBSCRIBE\r\n$3\r\nfoo\r\n$3\r\nbar\r\n",
+			err:     "CROSSSLOT Keys in request don't hash to the same slot",
+		},
+		{
+			name:    "SUNSUBSCRIBE foo bar",
+			request: "*3\r\n$12\r\nSUNSUBSCRIBE\r\n$3\r\nfoo\r\n$3\r\nbar\r\n",
+			err:     "CROSSSLOT Keys in request don't hash to the same slot",
+		},
+		{
+			name:    "SPUBLISH foo",
+			request: "*2\r\n$8\r\nSPUBLISH\r\n$3\r\nfoo\r\n",
+			err:     "ERR wrong number of arguments for 'spublish' command",
+		},
+		{
+			name:    "PUBSUB SHARDCHANNELS a b",
+			request: "*4\r\n$6\r\nPUBSUB\r\n$13\r\nSHARDCHANNELS\r\n$1\r\na\r\n$1\r\nb\r\n",
+			err:     "ERR wrong number of arguments for 'pubsub|shardchannels' command",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := redis.NewStore()
+			clock := FakeClock{}
+			requestReader := strings.NewReader(tt.request)
+
+			_, err := redis.NewParser(zeroValueRedisConfig, store, clock).Parse(requestReader)
+
+			if err != tt.err {
+				t.Errorf("err: expected: %v; got: %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestParser_ParseRequestInSubscribedMode(t *testing.T) {
+	t.Parallel()
+
+	parser := redis.NewParser(zeroValueRedisConfig, redis.NewStore(), FakeClock{})
+	client := parser.NewClient()
+	parser = parser.ForClient(client)
+	parse := func(request string) (redis.Command, error) {
+		return parser.Parse(strings.NewReader(request))
+	}
+	subscribe, err := parse("*2\r\n$9\r\nSUBSCRIBE\r\n$4\r\nnews\r\n")
+	if err != nil {
+		t.Fatalf("err: expected: nil; got: %v", err)
+	}
+	subscribe.Run()
+
+	tests := []struct {
+		name    string
+		request string
+		want    redis.Command
+		err     string
+	}{
+		{
+			name:    "PING",
+			request: "*1\r\n$4\r\nPING\r\n",
+			want:    redis.SubscribedPingCommand(""),
+		},
+		{
+			name:    "PING hello",
+			request: "*2\r\n$4\r\nPING\r\n$5\r\nhello\r\n",
+			want:    redis.SubscribedPingCommand("hello"),
+		},
+		{
+			name:    "GET key",
+			request: "*2\r\n$3\r\nGET\r\n$3\r\nkey\r\n",
+			err: "ERR Can't execute 'get': only (P|S)SUBSCRIBE / (P|S)UNSUBSCRIBE / PING / " +
+				"QUIT / RESET are allowed in this context",
+		},
+		{
+			name:    "publish news hello",
+			request: "*3\r\n$7\r\npublish\r\n$4\r\nnews\r\n$5\r\nhello\r\n",
+			err: "ERR Can't execute 'publish': only (P|S)SUBSCRIBE / (P|S)UNSUBSCRIBE / PING / " +
+				"QUIT / RESET are allowed in this context",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			command, err := parse(tt.request)
+
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Errorf("err: expected: %v; got: %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("err: expected: nil; got: %v", err)
+			}
+			if !reflect.DeepEqual(command, tt.want) {
+				t.Errorf("command expected to be %#v but was %#v", tt.want, command)
+			}
+		})
+	}
+
+	// Unsubscribing from every channel leaves subscribed mode.
+	unsubscribe, err := parse("*1\r\n$11\r\nUNSUBSCRIBE\r\n")
+	if err != nil {
+		t.Fatalf("err: expected: nil; got: %v", err)
+	}
+	unsubscribe.Run()
+	command, err := parse("*2\r\n$3\r\nGET\r\n$3\r\nkey\r\n")
+	if err != nil {
+		t.Errorf("err: expected: nil; got: %v", err)
+	}
+	if _, ok := command.(*redis.GetCommand); !ok {
+		t.Errorf("command expected to be a *redis.GetCommand but was %#v", command)
+	}
+}
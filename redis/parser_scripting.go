@@ -0,0 +1,98 @@
+package redis
+
+import (
+	"fmt"
+	"strings"
+)
+
+// allowedInScript returns true if scripts can call the command name. Like Redis, they can't
+// call the commands that manage transactions, subscriptions, scripts, functions or the
+// connection.
+func allowedInScript(name string) bool {
+	switch strings.ToUpper(name) {
+	case "CLIENT", "DISCARD", "EVAL", "EVALSHA", "EXEC", "FCALL", "FCALL_RO", "FUNCTION", "HELLO",
+		"MULTI", "PSUBSCRIBE", "PUNSUBSCRIBE", "QUIT", "RESET", "SCRIPT", "SHUTDOWN", "SSUBSCRIBE",
+		"SUBSCRIBE", "SUNSUBSCRIBE", "UNSUBSCRIBE", "UNWATCH", "WATCH":
+		return false
+	}
+	return true
+}
+
+// allowedWhenBusy returns true if the request array can be sent while a script or function has
+// been running for too long. SHUTDOWN is, though it's rejected unless it's SHUTDOWN NOSAVE.
+func allowedWhenBusy(array []string) bool {
+	if strings.EqualFold(array[0], "SHUTDOWN") {
+		return true
+	}
+	return len(array) == 2 &&
+		(strings.EqualFold(array[0], "SCRIPT") || strings.EqualFold(array[0], "FUNCTION")) &&
+		strings.EqualFold(array[1], "KILL")
+}
+
+func (p Parser) newEvalCommand(array []string) (Command, error) {
+	keys, args, err := parseScriptArguments(array)
+	if err != nil {
+		return nil, err
+	}
+	return NewEvalCommand(p, array[1], keys, args), nil
+}
+
+func (p Parser) newEvalShaCommand(array []string) (Command, error) {
+	keys, args, err := parseScriptArguments(array)
+	if err != nil {
+		return nil, err
+	}
+	return NewEvalShaCommand(p, array[1], keys, args), nil
+}
+
+// parseScriptArguments parses the numkeys argument of the request array of EVAL or EVALSHA,
+// returning the keys that follow it and the other arguments after them.
+func parseScriptArguments(array []string) (keys, args []string, err error) {
+	if len(array) < 3 {
+		return nil, nil, wrongNumberOfArgumentsError(array)
+	}
+	numKeys, err := parseInteger(array[2])
+	switch {
+	case err != nil:
+		return nil, nil, err
+	case numKeys < 0:
+		return nil, nil, errNumKeysNegative
+	case numKeys > len(array)-3:
+		return nil, nil, errNumKeysTooLarge
+	}
+	return array[3 : 3+numKeys], array[3+numKeys:], nil
+}
+
+func (p Parser) newScriptCommand(array []string) (Command, error) {
+	if len(array) < 2 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	switch {
+	case strings.EqualFold(array[1], "LOAD"):
+		if len(array) != 3 {
+			return nil, wrongNumberOfArgumentsError([]string{"script|load"})
+		}
+		return NewScriptLoadCommand(p.scripts, array[2]), nil
+	case strings.EqualFold(array[1], "EXISTS"):
+		if len(array) < 3 {
+			return nil, wrongNumberOfArgumentsError([]string{"script|exists"})
+		}
+		return NewScriptExistsCommand(p.scripts, array[2:]), nil
+	case strings.EqualFold(array[1], "FLUSH"):
+		// Flushing is quick either way, so ASYNC flushes synchronously too.
+		if len(array) > 3 ||
+			(len(array) == 3 && !strings.EqualFold(array[2], "ASYNC") &&
+				!strings.EqualFold(array[2], "SYNC")) {
+			return nil, CommandError("ERR SCRIPT FLUSH only support SYNC|ASYNC option")
+		}
+		return NewScriptFlushCommand(p.scripts), nil
+	case strings.EqualFold(array[1], "KILL"):
+		if len(array) != 2 {
+			return nil, wrongNumberOfArgumentsError([]string{"script|kill"})
+		}
+		return NewScriptKillCommand(p.scripts), nil
+	}
+	return nil, CommandError(
+		fmt.Sprintf("ERR unknown subcommand '%s'. Try SCRIPT HELP.", array[1]),
+	)
+}
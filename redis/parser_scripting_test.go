@@ -0,0 +1,144 @@
+package redis_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/codecrafters-io/redis-starter-go/redis"
+)
+
+func TestParser_ParseScriptRequest(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		request string
+		want    redis.Command
+	}{
+		{
+			name:    "SCRIPT LOAD",
+			request: "*3\r\n$6\r\nSCRIPT\r\n$4\r\nLOAD\r\n$8\r\nreturn 1\r\n",
+			want:    redis.NewScriptLoadCommand(redis.NewScripts(), "return 1"),
+		},
+		{
+			name:    "script exists",
+			request: "*4\r\n$6\r\nscript\r\n$6\r\nexists\r\n$1\r\na\r\n$1\r\nb\r\n",
+			want:    redis.NewScriptExistsCommand(redis.NewScripts(), []string{"a", "b"}),
+		},
+		{
+			name:    "SCRIPT FLUSH",
+			request: "*2\r\n$6\r\nSCRIPT\r\n$5\r\nFLUSH\r\n",
+			want:    redis.NewScriptFlushCommand(redis.NewScripts()),
+		},
+		{
+			name:    "SCRIPT FLUSH ASYNC",
+			request: "*3\r\n$6\r\nSCRIPT\r\n$5\r\nFLUSH\r\n$5\r\nASYNC\r\n",
+			want:    redis.NewScriptFlushCommand(redis.NewScripts()),
+		},
+		{
+			name:    "SCRIPT KILL",
+			request: "*2\r\n$6\r\nSCRIPT\r\n$4\r\nKILL\r\n",
+			want:    redis.NewScriptKillCommand(redis.NewScripts()),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser := redis.NewParser(zeroValueRedisConfig, redis.NewStore(), FakeClock{})
+			requestReader := strings.NewReader(tt.request)
+
+			command, err := parser.Parse(requestReader)
+
+			if err != nil {
+				t.Errorf("err: expected: nil; got: %v", err)
+			}
+			if !reflect.DeepEqual(command, tt.want) {
+				t.Errorf("command expected to be %#v but was %#v", tt.want, command)
+			}
+		})
+	}
+}
+
+func TestParser_ParseInvalidScriptRequest(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		request string
+		err     error
+	}{
+		{
+			name:    "EVAL without numkeys",
+			request: "*2\r\n$4\r\nEVAL\r\n$8\r\nreturn 1\r\n",
+			err:     redis.CommandError("ERR wrong number of arguments for 'eval' command"),
+		},
+		{
+			name:    "EVAL numkeys not an integer",
+			request: "*3\r\n$4\r\nEVAL\r\n$8\r\nreturn 1\r\n$3\r\nabc\r\n",
+			err:     redis.CommandError("ERR value is not an integer or out of range"),
+		},
+		{
+			name:    "EVAL numkeys negative",
+			request: "*3\r\n$4\r\nEVAL\r\n$8\r\nreturn 1\r\n$2\r\n-1\r\n",
+			err:     redis.CommandError("ERR Number of keys can't be negative"),
+		},
+		{
+			name:    "EVAL numkeys more than keys",
+			request: "*4\r\n$4\r\nEVAL\r\n$8\r\nreturn 1\r\n$1\r\n2\r\n$1\r\na\r\n",
+			err: redis.CommandError(
+				"ERR Number of keys can't be greater than number of args",
+			),
+		},
+		{
+			name:    "EVALSHA without numkeys",
+			request: "*2\r\n$7\r\nEVALSHA\r\n$3\r\nabc\r\n",
+			err:     redis.CommandError("ERR wrong number of arguments for 'evalsha' command"),
+		},
+		{
+			name:    "SCRIPT",
+			request: "*1\r\n$6\r\nSCRIPT\r\n",
+			err:     redis.CommandError("ERR wrong number of arguments for 'script' command"),
+		},
+		{
+			name:    "SCRIPT LOAD without a script",
+			request: "*2\r\n$6\r\nSCRIPT\r\n$4\r\nLOAD\r\n",
+			err:     redis.CommandError("ERR wrong number of arguments for 'script|load' command"),
+		},
+		{
+			name:    "SCRIPT EXISTS without digests",
+			request: "*2\r\n$6\r\nSCRIPT\r\n$6\r\nEXISTS\r\n",
+			err: redis.CommandError(
+				"ERR wrong number of arguments for 'script|exists' command",
+			),
+		},
+		{
+			name:    "SCRIPT FLUSH LATER",
+			request: "*3\r\n$6\r\nSCRIPT\r\n$5\r\nFLUSH\r\n$5\r\nLATER\r\n",
+			err:     redis.CommandError("ERR SCRIPT FLUSH only support SYNC|ASYNC option"),
+		},
+		{
+			name:    "SCRIPT KILL with an argument",
+			request: "*3\r\n$6\r\nSCRIPT\r\n$4\r\nKILL\r\n$1\r\na\r\n",
+			err:     redis.CommandError("ERR wrong number of arguments for 'script|kill' command"),
+		},
+		{
+			name:    "SCRIPT DEBUG",
+			request: "*3\r\n$6\r\nSCRIPT\r\n$5\r\nDEBUG\r\n$3\r\nYES\r\n",
+			err:     redis.CommandError("ERR unknown subcommand 'DEBUG'. Try SCRIPT HELP."),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requestReader := strings.NewReader(tt.request)
+
+			_, err := redis.NewParser(zeroValueRedisConfig, redis.NewStore(), FakeClock{}).
+				Parse(requestReader)
+
+			if err != tt.err {
+				t.Errorf("err: expected: %v; got: %v", tt.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,192 @@
+package redis
+
+import (
+	"math"
+	"strings"
+)
+
+func (p Parser) newSAddCommand(array []string) (Command, error) {
+	if len(array) < 3 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	return NewSAddCommand(p.store, p.clock, p.config, array[1], array[2:]), nil
+}
+
+func (p Parser) newSRemCommand(array []string) (Command, error) {
+	if len(array) < 3 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	return NewSRemCommand(p.store, p.clock, array[1], array[2:]), nil
+}
+
+func (p Parser) newSMembersCommand(array []string) (Command, error) {
+	if len(array) != 2 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	return NewSMembersCommand(p.store, p.clock, array[1]), nil
+}
+
+func (p Parser) newSIsMemberCommand(array []string) (Command, error) {
+	if len(array) != 3 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	return NewSIsMemberCommand(p.store, p.clock, array[1], array[2]), nil
+}
+
+func (p Parser) newSMIsMemberCommand(array []string) (Command, error) {
+	if len(array) < 3 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	return NewSMIsMemberCommand(p.store, p.clock, array[1], array[2:]), nil
+}
+
+func (p Parser) newSCardCommand(array []string) (Command, error) {
+	if len(array) != 2 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	return NewSCardCommand(p.store, p.clock, array[1]), nil
+}
+
+func (p Parser) newSPopCommand(array []string) (Command, error) {
+	if len(array) != 2 && len(array) != 3 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	var count *int
+	if len(array) == 3 {
+		c, err := parsePositiveInteger(array[2])
+		if err != nil {
+			return nil, err
+		}
+		count = &c
+	}
+	return NewSPopCommand(p.store, p.clock, array[1], count), nil
+}
+
+func (p Parser) newSRandMemberCommand(array []string) (Command, error) {
+	if len(array) != 2 && len(array) != 3 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	var count *int
+	if len(array) == 3 {
+		c, err := parseInteger(array[2])
+		if err != nil {
+			return nil, err
+		}
+		if c < -math.MaxInt/2 || c > math.MaxInt/2 {
+			return nil, CommandError("ERR value is out of range")
+		}
+		count = &c
+	}
+	return NewSRandMemberCommand(p.store, p.clock, array[1], count), nil
+}
+
+func (p Parser) newSMoveCommand(array []string) (Command, error) {
+	if len(array) != 4 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	return NewSMoveCommand(p.store, p.clock, p.config, array[1], array[2], array[3]), nil
+}
+
+func (p Parser) newSUnionCommand(array []string) (Command, error) {
+	return p.newSetOpCommand(array, SetOpUnion)
+}
+
+func (p Parser) newSInterCommand(array []string) (Command, error) {
+	return p.newSetOpCommand(array, SetOpInter)
+}
+
+func (p Parser) newSDiffCommand(array []string) (Command, error) {
+	return p.newSetOpCommand(array, SetOpDiff)
+}
+
+func (p Parser) newSetOpCommand(array []string, op SetOp) (Command, error) {
+	if len(array) < 2 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	return NewSetOpCommand(p.store, p.clock, op, array[1:]), nil
+}
+
+func (p Parser) newSUnionStoreCommand(array []string) (Command, error) {
+	return p.newSetOpStoreCommand(array, SetOpUnion)
+}
+
+func (p Parser) newSInterStoreCommand(array []string) (Command, error) {
+	return p.newSetOpStoreCommand(array, SetOpInter)
+}
+
+func (p Parser) newSDiffStoreCommand(array []string) (Command, error) {
+	return p.newSetOpStoreCommand(array, SetOpDiff)
+}
+
+func (p Parser) newSetOpStoreCommand(array []string, op SetOp) (Command, error) {
+	if len(array) < 3 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	return NewSetOpStoreCommand(p.store, p.clock, p.config, op, array[1], array[2:]), nil
+}
+
+func (p Parser) newSInterCardCommand(array []string) (Command, error) {
+	if len(array) < 3 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	numKeys, err := parseInteger(array[1])
+	if err != nil {
+		return nil, err
+	}
+	if numKeys <= 0 {
+		return nil, CommandError("ERR numkeys should be greater than 0")
+	}
+	if numKeys > len(array)-2 {
+		return nil, CommandError("ERR Number of keys can't be greater than number of args")
+	}
+	keys := array[2 : numKeys+2]
+
+	limit := 0
+	options := array[numKeys+2:]
+	switch {
+	case len(options) == 0:
+	case len(options) == 2 && strings.EqualFold(options[0], "LIMIT"):
+		limit, err = parseInteger(options[1])
+		if err != nil {
+			return nil, err
+		}
+		if limit < 0 {
+			return nil, CommandError("ERR LIMIT can't be negative")
+		}
+	default:
+		return nil, errSyntax
+	}
+	return NewSInterCardCommand(p.store, p.clock, keys, limit), nil
+}
+
+func (p Parser) newSScanCommand(array []string) (Command, error) {
+	if len(array) < 3 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	cursor, err := parseScanCursor(array[2])
+	if err != nil {
+		return nil, err
+	}
+
+	var options []func(*SScanCommand)
+	for i := 3; i < len(array); i++ {
+		switch {
+		case strings.EqualFold(array[i], "MATCH") && i+1 < len(array):
+			options = append(options, SScanMatch(array[i+1]))
+			i++
+		case strings.EqualFold(array[i], "COUNT") && i+1 < len(array):
+			count, err := parseInteger(array[i+1])
+			if err != nil {
+				return nil, err
+			}
+			if count < 1 {
+				return nil, errSyntax
+			}
+			options = append(options, SScanCount(count))
+			i++
+		default:
+			return nil, errSyntax
+		}
+	}
+	return NewSScanCommand(p.store, p.clock, array[1], cursor, options...), nil
+}
@@ -0,0 +1,192 @@
+package redis_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/codecrafters-io/redis-starter-go/redis"
+)
+
+func TestParser_ParseSetTypeRequest(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{}
+
+	tests := []struct {
+		name    string
+		request string
+		want    redis.Command
+	}{
+		{
+			name:    "SADD set a b",
+			request: "*4\r\n$4\r\nSADD\r\n$3\r\nset\r\n$1\r\na\r\n$1\r\nb\r\n",
+			want:    redis.NewSAddCommand(store, clock, zeroValueRedisConfig, "set", []string{"a", "b"}),
+		},
+		{
+			name:    "SREM set a",
+			request: "*3\r\n$4\r\nSREM\r\n$3\r\nset\r\n$1\r\na\r\n",
+			want:    redis.NewSRemCommand(store, clock, "set", []string{"a"}),
+		},
+		{
+			name:    "SMEMBERS set",
+			request: "*2\r\n$8\r\nSMEMBERS\r\n$3\r\nset\r\n",
+			want:    redis.NewSMembersCommand(store, clock, "set"),
+		},
+		{
+			name:    "SISMEMBER set a",
+			request: "*3\r\n$9\r\nSISMEMBER\r\n$3\r\nset\r\n$1\r\na\r\n",
+			want:    redis.NewSIsMemberCommand(store, clock, "set", "a"),
+		},
+		{
+			name:    "SMISMEMBER set a b",
+			request: "*4\r\n$10\r\nSMISMEMBER\r\n$3\r\nset\r\n$1\r\na\r\n$1\r\nb\r\n",
+			want:    redis.NewSMIsMemberCommand(store, clock, "set", []string{"a", "b"}),
+		},
+		{
+			name:    "SCARD set",
+			request: "*2\r\n$5\r\nSCARD\r\n$3\r\nset\r\n",
+			want:    redis.NewSCardCommand(store, clock, "set"),
+		},
+		{
+			name:    "SPOP set",
+			request: "*2\r\n$4\r\nSPOP\r\n$3\r\nset\r\n",
+			want:    redis.NewSPopCommand(store, clock, "set", nil),
+		},
+		{
+			name:    "SPOP set 2",
+			request: "*3\r\n$4\r\nSPOP\r\n$3\r\nset\r\n$1\r\n2\r\n",
+			want:    redis.NewSPopCommand(store, clock, "set", ptr(2)),
+		},
+		{
+			name:    "SRANDMEMBER set -3",
+			request: "*3\r\n$11\r\nSRANDMEMBER\r\n$3\r\nset\r\n$2\r\n-3\r\n",
+			want:    redis.NewSRandMemberCommand(store, clock, "set", ptr(-3)),
+		},
+		{
+			name:    "SMOVE set other a",
+			request: "*4\r\n$5\r\nSMOVE\r\n$3\r\nset\r\n$5\r\nother\r\n$1\r\na\r\n",
+			want:    redis.NewSMoveCommand(store, clock, zeroValueRedisConfig, "set", "other", "a"),
+		},
+		{
+			name:    "SUNION a b",
+			request: "*3\r\n$6\r\nSUNION\r\n$1\r\na\r\n$1\r\nb\r\n",
+			want:    redis.NewSetOpCommand(store, clock, redis.SetOpUnion, []string{"a", "b"}),
+		},
+		{
+			name:    "SINTER a",
+			request: "*2\r\n$6\r\nSINTER\r\n$1\r\na\r\n",
+			want:    redis.NewSetOpCommand(store, clock, redis.SetOpInter, []string{"a"}),
+		},
+		{
+			name:    "SDIFF a b",
+			request: "*3\r\n$5\r\nSDIFF\r\n$1\r\na\r\n$1\r\nb\r\n",
+			want:    redis.NewSetOpCommand(store, clock, redis.SetOpDiff, []string{"a", "b"}),
+		},
+		{
+			name:    "SINTERSTORE dest a b",
+			request: "*4\r\n$11\r\nSINTERSTORE\r\n$4\r\ndest\r\n$1\r\na\r\n$1\r\nb\r\n",
+			want: redis.NewSetOpStoreCommand(
+				store, clock, zeroValueRedisConfig, redis.SetOpInter, "dest", []string{"a", "b"},
+			),
+		},
+		{
+			name: "SINTERCARD 2 a b LIMIT 5",
+			request: "*6\r\n$10\r\nSINTERCARD\r\n$1\r\n2\r\n$1\r\na\r\n$1\r\nb\r\n$5\r\nLIMIT\r\n" +
+				"$1\r\n5\r\n",
+			want: redis.NewSInterCardCommand(store, clock, []string{"a", "b"}, 5),
+		},
+		{
+			name: "SSCAN set 0 MATCH a* COUNT 5",
+			request: "*7\r\n$5\r\nSSCAN\r\n$3\r\nset\r\n$1\r\n0\r\n$5\r\nMATCH\r\n$2\r\na*\r\n" +
+				"$5\r\nCOUNT\r\n$1\r\n5\r\n",
+			want: redis.NewSScanCommand(store, clock, "set", 0, redis.SScanMatch("a*"), redis.SScanCount(5)),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requestReader := strings.NewReader(tt.request)
+
+			command, err := redis.NewParser(zeroValueRedisConfig, store, clock).Parse(requestReader)
+
+			if err != nil {
+				t.Errorf("err: expected: nil; got: %v", err)
+			}
+			if !reflect.DeepEqual(command, tt.want) {
+				t.Errorf("command expected to be %#v but was %#v", tt.want, command)
+			}
+		})
+	}
+}
+
+func TestParser_ParseInvalidSetTypeRequest(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		request string
+		err     redis.CommandError
+	}{
+		{
+			name:    "SADD set",
+			request: "*2\r\n$4\r\nSADD\r\n$3\r\nset\r\n",
+			err:     "ERR wrong number of arguments for 'sadd' command",
+		},
+		{
+			name:    "SPOP set -1",
+			request: "*3\r\n$4\r\nSPOP\r\n$3\r\nset\r\n$2\r\n-1\r\n",
+			err:     "ERR value is out of range, must be positive",
+		},
+		{
+			name:    "SRANDMEMBER set x",
+			request: "*3\r\n$11\r\nSRANDMEMBER\r\n$3\r\nset\r\n$1\r\nx\r\n",
+			err:     "ERR value is not an integer or out of range",
+		},
+		{
+			name:    "SUNIONSTORE dest",
+			request: "*2\r\n$11\r\nSUNIONSTORE\r\n$4\r\ndest\r\n",
+			err:     "ERR wrong number of arguments for 'sunionstore' command",
+		},
+		{
+			name:    "SINTERCARD 0 a",
+			request: "*3\r\n$10\r\nSINTERCARD\r\n$1\r\n0\r\n$1\r\na\r\n",
+			err:     "ERR numkeys should be greater than 0",
+		},
+		{
+			name:    "SINTERCARD 3 a b",
+			request: "*4\r\n$10\r\nSINTERCARD\r\n$1\r\n3\r\n$1\r\na\r\n$1\r\nb\r\n",
+			err:     "ERR Number of keys can't be greater than number of args",
+		},
+		{
+			name:    "SINTERCARD 1 a LIMIT -1",
+			request: "*5\r\n$10\r\nSINTERCARD\r\n$1\r\n1\r\n$1\r\na\r\n$5\r\nLIMIT\r\n$2\r\n-1\r\n",
+			err:     "ERR LIMIT can't be negative",
+		},
+		{
+			name:    "SINTERCARD 1 a TOP 1",
+			request: "*5\r\n$10\r\nSINTERCARD\r\n$1\r\n1\r\n$1\r\na\r\n$3\r\nTOP\r\n$1\r\n1\r\n",
+			err:     "ERR syntax error",
+		},
+		{
+			name:    "SSCAN set 0 NOVALUES",
+			request: "*4\r\n$5\r\nSSCAN\r\n$3\r\nset\r\n$1\r\n0\r\n$8\r\nNOVALUES\r\n",
+			err:     "ERR syntax error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := redis.NewStore()
+			clock := FakeClock{}
+			requestReader := strings.NewReader(tt.request)
+
+			_, err := redis.NewParser(zeroValueRedisConfig, store, clock).Parse(requestReader)
+
+			if err != tt.err {
+				t.Errorf("err: expected: %v; got: %v", tt.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package redis
+
+import (
+	"strconv"
+	"strings"
+)
+
+func (p Parser) newCMSInitByDimCommand(array []string) (Command, error) {
+	if len(array) != 4 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	width, err := parseInteger(array[2])
+	if err != nil || width < 1 {
+		return nil, CommandError("ERR CMS: invalid width")
+	}
+	depth, err := parseInteger(array[3])
+	if err != nil || depth < 1 {
+		return nil, CommandError("ERR CMS: invalid depth")
+	}
+	return NewCMSInitCommand(p.store, p.clock, array[1], width, depth), nil
+}
+
+func (p Parser) newCMSInitByProbCommand(array []string) (Command, error) {
+	if len(array) != 4 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	errorRate, err := strconv.ParseFloat(array[2], 64)
+	if err != nil || errorRate <= 0 || errorRate >= 1 {
+		return nil, CommandError("ERR CMS: invalid overestimation value")
+	}
+	probability, err := strconv.ParseFloat(array[3], 64)
+	if err != nil || probability <= 0 || probability >= 1 {
+		return nil, CommandError("ERR CMS: invalid prob value")
+	}
+	width, depth := cmsDimensionsByProb(errorRate, probability)
+	return NewCMSInitCommand(p.store, p.clock, array[1], width, depth), nil
+}
+
+// parseCMSCount parses a non-negative increment or weight of a Count-Min sketch.
+func parseCMSCount(s string) (int64, error) {
+	i, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || i < 0 {
+		return 0, CommandError("ERR CMS: Cannot parse number")
+	}
+	return i, nil
+}
+
+func (p Parser) newCMSIncrByCommand(array []string) (Command, error) {
+	if len(array) < 4 || len(array)%2 != 0 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	increments := make([]CMSIncrement, 0, (len(array)-2)/2)
+	for i := 2; i < len(array); i += 2 {
+		increment, err := parseCMSCount(array[i+1])
+		if err != nil {
+			return nil, err
+		}
+		increments = append(increments, CMSIncrement{Item: array[i], Increment: increment})
+	}
+	return NewCMSIncrByCommand(p.store, p.clock, array[1], increments), nil
+}
+
+func (p Parser) newCMSQueryCommand(array []string) (Command, error) {
+	if len(array) < 3 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	return NewCMSQueryCommand(p.store, p.clock, array[1], array[2:]), nil
+}
+
+func (p Parser) newCMSMergeCommand(array []string) (Command, error) {
+	if len(array) < 4 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	numKeys, err := parseInteger(array[2])
+	if err != nil || numKeys < 1 {
+		return nil, CommandError("ERR CMS: invalid numkeys")
+	}
+	rest := array[3:]
+	weighted := len(rest) == numKeys*2+1 && strings.EqualFold(rest[numKeys], "WEIGHTS")
+	if len(rest) != numKeys && !weighted {
+		return nil, CommandError("ERR CMS: wrong number of keys/weights")
+	}
+	weights := make([]int64, numKeys)
+	for i := range weights {
+		weights[i] = 1
+		if weighted {
+			if weights[i], err = parseCMSCount(rest[numKeys+1+i]); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return NewCMSMergeCommand(p.store, p.clock, array[1], rest[:numKeys], weights), nil
+}
+
+func (p Parser) newCMSInfoCommand(array []string) (Command, error) {
+	if len(array) != 2 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	return NewCMSInfoCommand(p.store, p.clock, array[1]), nil
+}
+
+func (p Parser) newTopKReserveCommand(array []string) (Command, error) {
+	if len(array) != 3 && len(array) != 6 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	k, err := parseInteger(array[2])
+	if err != nil || k < 1 {
+		return nil, CommandError("ERR TopK: invalid k")
+	}
+	width, depth, decay := defaultTopKWidth, defaultTopKDepth, defaultTopKDecay
+	if len(array) == 6 {
+		if width, err = parseInteger(array[3]); err != nil || width < 1 {
+			return nil, CommandError("ERR TopK: invalid width")
+		}
+		if depth, err = parseInteger(array[4]); err != nil || depth < 1 {
+			return nil, CommandError("ERR TopK: invalid depth")
+		}
+		if decay, err = strconv.ParseFloat(array[5], 64); err != nil || decay <= 0 || decay > 1 {
+			return nil, CommandError("ERR TopK: invalid decay value")
+		}
+	}
+	return NewTopKReserveCommand(p.store, p.clock, array[1], k, width, depth, decay), nil
+}
+
+func (p Parser) newTopKAddCommand(array []string) (Command, error) {
+	if len(array) < 3 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	return NewTopKAddCommand(p.store, p.clock, array[1], array[2:]), nil
+}
+
+func (p Parser) newTopKListCommand(array []string) (Command, error) {
+	if len(array) != 2 && len(array) != 3 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	var options []func(*TopKListCommand)
+	if len(array) == 3 {
+		if !strings.EqualFold(array[2], "WITHCOUNT") {
+			return nil, errSyntax
+		}
+		options = append(options, TopKListWithCount())
+	}
+	return NewTopKListCommand(p.store, p.clock, array[1], options...), nil
+}
+
+func (p Parser) newTopKQueryCommand(array []string) (Command, error) {
+	if len(array) < 3 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	return NewTopKQueryCommand(p.store, p.clock, array[1], array[2:]), nil
+}
@@ -0,0 +1,260 @@
+package redis_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/codecrafters-io/redis-starter-go/redis"
+)
+
+func TestParser_ParseSketchRequest(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{}
+
+	tests := []struct {
+		name    string
+		request string
+		want    redis.Command
+	}{
+		{
+			name:    "CMS.INITBYDIM cms 2000 5",
+			request: "*4\r\n$13\r\nCMS.INITBYDIM\r\n$3\r\ncms\r\n$4\r\n2000\r\n$1\r\n5\r\n",
+			want:    redis.NewCMSInitCommand(store, clock, "cms", 2000, 5),
+		},
+		{
+			name:    "CMS.INITBYPROB cms 0.001 0.01",
+			request: "*4\r\n$14\r\nCMS.INITBYPROB\r\n$3\r\ncms\r\n$5\r\n0.001\r\n$4\r\n0.01\r\n",
+			want:    redis.NewCMSInitCommand(store, clock, "cms", 2000, 7),
+		},
+		{
+			name: "CMS.INCRBY cms a 1 b 20",
+			request: "*6\r\n$10\r\nCMS.INCRBY\r\n$3\r\ncms\r\n$1\r\na\r\n$1\r\n1\r\n" +
+				"$1\r\nb\r\n$2\r\n20\r\n",
+			want: redis.NewCMSIncrByCommand(
+				store, clock, "cms",
+				[]redis.CMSIncrement{{Item: "a", Increment: 1}, {Item: "b", Increment: 20}},
+			),
+		},
+		{
+			name:    "CMS.QUERY cms a b",
+			request: "*4\r\n$9\r\nCMS.QUERY\r\n$3\r\ncms\r\n$1\r\na\r\n$1\r\nb\r\n",
+			want:    redis.NewCMSQueryCommand(store, clock, "cms", []string{"a", "b"}),
+		},
+		{
+			name:    "CMS.MERGE dest 2 a b",
+			request: "*5\r\n$9\r\nCMS.MERGE\r\n$4\r\ndest\r\n$1\r\n2\r\n$1\r\na\r\n$1\r\nb\r\n",
+			want:    redis.NewCMSMergeCommand(store, clock, "dest", []string{"a", "b"}, []int64{1, 1}),
+		},
+		{
+			name: "CMS.MERGE dest 2 a b weights 3 0",
+			request: "*8\r\n$9\r\nCMS.MERGE\r\n$4\r\ndest\r\n$1\r\n2\r\n$1\r\na\r\n" +
+				"$1\r\nb\r\n$7\r\nweights\r\n$1\r\n3\r\n$1\r\n0\r\n",
+			want: redis.NewCMSMergeCommand(store, clock, "dest", []string{"a", "b"}, []int64{3, 0}),
+		},
+		{
+			name:    "CMS.INFO cms",
+			request: "*2\r\n$8\r\nCMS.INFO\r\n$3\r\ncms\r\n",
+			want:    redis.NewCMSInfoCommand(store, clock, "cms"),
+		},
+		{
+			name:    "TOPK.RESERVE topk 10",
+			request: "*3\r\n$12\r\nTOPK.RESERVE\r\n$4\r\ntopk\r\n$2\r\n10\r\n",
+			want:    redis.NewTopKReserveCommand(store, clock, "topk", 10, 8, 7, 0.9),
+		},
+		{
+			name: "TOPK.RESERVE topk 10 50 4 0.5",
+			request: "*6\r\n$12\r\nTOPK.RESERVE\r\n$4\r\ntopk\r\n$2\r\n10\r\n$2\r\n50\r\n" +
+				"$1\r\n4\r\n$3\r\n0.5\r\n",
+			want: redis.NewTopKReserveCommand(store, clock, "topk", 10, 50, 4, 0.5),
+		},
+		{
+			name:    "TOPK.ADD topk a b",
+			request: "*4\r\n$8\r\nTOPK.ADD\r\n$4\r\ntopk\r\n$1\r\na\r\n$1\r\nb\r\n",
+			want:    redis.NewTopKAddCommand(store, clock, "topk", []string{"a", "b"}),
+		},
+		{
+			name:    "TOPK.LIST topk",
+			request: "*2\r\n$9\r\nTOPK.LIST\r\n$4\r\ntopk\r\n",
+			want:    redis.NewTopKListCommand(store, clock, "topk"),
+		},
+		{
+			name:    "TOPK.LIST topk withcount",
+			request: "*3\r\n$9\r\nTOPK.LIST\r\n$4\r\ntopk\r\n$9\r\nwithcount\r\n",
+			want:    redis.NewTopKListCommand(store, clock, "topk", redis.TopKListWithCount()),
+		},
+		{
+			name:    "TOPK.QUERY topk a b",
+			request: "*4\r\n$10\r\nTOPK.QUERY\r\n$4\r\ntopk\r\n$1\r\na\r\n$1\r\nb\r\n",
+			want:    redis.NewTopKQueryCommand(store, clock, "topk", []string{"a", "b"}),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requestReader := strings.NewReader(tt.request)
+
+			command, err := redis.NewParser(zeroValueRedisConfig, store, clock).Parse(requestReader)
+
+			if err != nil {
+				t.Errorf("err: expected: nil; got: %v", err)
+			}
+			if !reflect.DeepEqual(command, tt.want) {
+				t.Errorf("command expected to be %#v but was %#v", tt.want, command)
+			}
+		})
+	}
+}
+
+func TestParser_ParseInvalidSketchRequest(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		request string
+		err     redis.CommandError
+	}{
+		{
+			name:    "CMS.INITBYDIM cms 2000",
+			request: "*3\r\n$13\r\nCMS.INITBYDIM\r\n$3\r\ncms\r\n$4\r\n2000\r\n",
+			err:     "ERR wrong number of arguments for 'cms.initbydim' command",
+		},
+		{
+			name:    "CMS.INITBYDIM cms 0 5",
+			request: "*4\r\n$13\r\nCMS.INITBYDIM\r\n$3\r\ncms\r\n$1\r\n0\r\n$1\r\n5\r\n",
+			err:     "ERR CMS: invalid width",
+		},
+		{
+			name:    "CMS.INITBYDIM cms 2000 deep",
+			request: "*4\r\n$13\r\nCMS.INITBYDIM\r\n$3\r\ncms\r\n$4\r\n2000\r\n$4\r\ndeep\r\n",
+			err:     "ERR CMS: invalid depth",
+		},
+		{
+			name:    "CMS.INITBYPROB cms 1 0.01",
+			request: "*4\r\n$14\r\nCMS.INITBYPROB\r\n$3\r\ncms\r\n$1\r\n1\r\n$4\r\n0.01\r\n",
+			err:     "ERR CMS: invalid overestimation value",
+		},
+		{
+			name:    "CMS.INITBYPROB cms 0.001 0",
+			request: "*4\r\n$14\r\nCMS.INITBYPROB\r\n$3\r\ncms\r\n$5\r\n0.001\r\n$1\r\n0\r\n",
+			err:     "ERR CMS: invalid prob value",
+		},
+		{
+			name:    "CMS.INCRBY cms a",
+			request: "*3\r\n$10\r\nCMS.INCRBY\r\n$3\r\ncms\r\n$1\r\na\r\n",
+			err:     "ERR wrong number of arguments for 'cms.incrby' command",
+		},
+		{
+			name:    "CMS.INCRBY cms a 1 b",
+			request: "*5\r\n$10\r\nCMS.INCRBY\r\n$3\r\ncms\r\n$1\r\na\r\n$1\r\n1\r\n$1\r\nb\r\n",
+			err:     "ERR wrong number of arguments for 'cms.incrby' command",
+		},
+		{
+			name:    "CMS.INCRBY cms a -1",
+			request: "*4\r\n$10\r\nCMS.INCRBY\r\n$3\r\ncms\r\n$1\r\na\r\n$2\r\n-1\r\n",
+			err:     "ERR CMS: Cannot parse number",
+		},
+		{
+			name:    "CMS.QUERY cms",
+			request: "*2\r\n$9\r\nCMS.QUERY\r\n$3\r\ncms\r\n",
+			err:     "ERR wrong number of arguments for 'cms.query' command",
+		},
+		{
+			name:    "CMS.MERGE dest 1",
+			request: "*3\r\n$9\r\nCMS.MERGE\r\n$4\r\ndest\r\n$1\r\n1\r\n",
+			err:     "ERR wrong number of arguments for 'cms.merge' command",
+		},
+		{
+			name:    "CMS.MERGE dest 0 a",
+			request: "*4\r\n$9\r\nCMS.MERGE\r\n$4\r\ndest\r\n$1\r\n0\r\n$1\r\na\r\n",
+			err:     "ERR CMS: invalid numkeys",
+		},
+		{
+			name:    "CMS.MERGE dest 2 a",
+			request: "*4\r\n$9\r\nCMS.MERGE\r\n$4\r\ndest\r\n$1\r\n2\r\n$1\r\na\r\n",
+			err:     "ERR CMS: wrong number of keys/weights",
+		},
+		{
+			name: "CMS.MERGE dest 2 a b WEIGHTS 1",
+			request: "*7\r\n$9\r\nCMS.MERGE\r\n$4\r\ndest\r\n$1\r\n2\r\n$1\r\na\r\n" +
+				"$1\r\nb\r\n$7\r\nWEIGHTS\r\n$1\r\n1\r\n",
+			err: "ERR CMS: wrong number of keys/weights",
+		},
+		{
+			name: "CMS.MERGE dest 1 a WEIGHTS x",
+			request: "*6\r\n$9\r\nCMS.MERGE\r\n$4\r\ndest\r\n$1\r\n1\r\n$1\r\na\r\n" +
+				"$7\r\nWEIGHTS\r\n$1\r\nx\r\n",
+			err: "ERR CMS: Cannot parse number",
+		},
+		{
+			name:    "CMS.INFO",
+			request: "*1\r\n$8\r\nCMS.INFO\r\n",
+			err:     "ERR wrong number of arguments for 'cms.info' command",
+		},
+		{
+			name:    "TOPK.RESERVE topk",
+			request: "*2\r\n$12\r\nTOPK.RESERVE\r\n$4\r\ntopk\r\n",
+			err:     "ERR wrong number of arguments for 'topk.reserve' command",
+		},
+		{
+			name: "TOPK.RESERVE topk 10 50 4",
+			request: "*5\r\n$12\r\nTOPK.RESERVE\r\n$4\r\ntopk\r\n$2\r\n10\r\n$2\r\n50\r\n" +
+				"$1\r\n4\r\n",
+			err: "ERR wrong number of arguments for 'topk.reserve' command",
+		},
+		{
+			name:    "TOPK.RESERVE topk 0",
+			request: "*3\r\n$12\r\nTOPK.RESERVE\r\n$4\r\ntopk\r\n$1\r\n0\r\n",
+			err:     "ERR TopK: invalid k",
+		},
+		{
+			name: "TOPK.RESERVE topk 10 0 4 0.5",
+			request: "*6\r\n$12\r\nTOPK.RESERVE\r\n$4\r\ntopk\r\n$2\r\n10\r\n$1\r\n0\r\n" +
+				"$1\r\n4\r\n$3\r\n0.5\r\n",
+			err: "ERR TopK: invalid width",
+		},
+		{
+			name: "TOPK.RESERVE topk 10 50 x 0.5",
+			request: "*6\r\n$12\r\nTOPK.RESERVE\r\n$4\r\ntopk\r\n$2\r\n10\r\n$2\r\n50\r\n" +
+				"$1\r\nx\r\n$3\r\n0.5\r\n",
+			err: "ERR TopK: invalid depth",
+		},
+		{
+			name: "TOPK.RESERVE topk 10 50 4 1.5",
+			request: "*6\r\n$12\r\nTOPK.RESERVE\r\n$4\r\ntopk\r\n$2\r\n10\r\n$2\r\n50\r\n" +
+				"$1\r\n4\r\n$3\r\n1.5\r\n",
+			err: "ERR TopK: invalid decay value",
+		},
+		{
+			name:    "TOPK.ADD topk",
+			request: "*2\r\n$8\r\nTOPK.ADD\r\n$4\r\ntopk\r\n",
+			err:     "ERR wrong number of arguments for 'topk.add' command",
+		},
+		{
+			name:    "TOPK.LIST topk WITHCOUNTS",
+			request: "*3\r\n$9\r\nTOPK.LIST\r\n$4\r\ntopk\r\n$10\r\nWITHCOUNTS\r\n",
+			err:     "ERR syntax error",
+		},
+		{
+			name:    "TOPK.QUERY topk",
+			request: "*2\r\n$10\r\nTOPK.QUERY\r\n$4\r\ntopk\r\n",
+			err:     "ERR wrong number of arguments for 'topk.query' command",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := redis.NewStore()
+			clock := FakeClock{}
+			requestReader := strings.NewReader(tt.request)
+
+			_, err := redis.NewParser(zeroValueRedisConfig, store, clock).Parse(requestReader)
+
+			if err != tt.err {
+				t.Errorf("err: expected: %v; got: %v", tt.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,340 @@
+package redis
+
+import (
+	"strconv"
+	"strings"
+)
+
+const errInvalidStreamID CommandError = "ERR Invalid stream ID specified as stream command argument"
+
+const (
+	errStreamTrimConflict CommandError = "ERR syntax error, MAXLEN and MINID options at the same " +
+		"time are not compatible"
+
+	errStreamLimitWithoutTrim CommandError = "ERR syntax error, LIMIT cannot be used without " +
+		"specifying a trimming strategy"
+
+	errStreamLimitNotApproximate CommandError = "ERR syntax error, LIMIT cannot be used without " +
+		"the special ~ option"
+
+	errXTrimWithoutTrim CommandError = "ERR syntax error, XTRIM must be called with a trimming " +
+		"strategy"
+)
+
+// parseStreamID parses a stream ID of the form "<ms>-<seq>", or "<ms>" with missingSeq as its
+// sequence number.
+func parseStreamID(s string, missingSeq uint64) (StreamID, error) {
+	msPart, seqPart, hasSeq := strings.Cut(s, "-")
+	ms, err := strconv.ParseUint(msPart, 10, 64)
+	if err != nil {
+		return StreamID{}, errInvalidStreamID
+	}
+	if !hasSeq {
+		return StreamID{Ms: ms, Seq: missingSeq}, nil
+	}
+	seq, err := strconv.ParseUint(seqPart, 10, 64)
+	if err != nil {
+		return StreamID{}, errInvalidStreamID
+	}
+	return StreamID{Ms: ms, Seq: seq}, nil
+}
+
+// parseStreamRangeID parses an end of a stream range: "-" or "+" for the least or greatest
+// possible ID, or an ID with missingSeq as its sequence number if it has none, which is excluded
+// from the range if it's prefixed with "(".
+func parseStreamRangeID(s string, missingSeq uint64) (id StreamID, exclusive bool, err error) {
+	switch {
+	case s == "-":
+		return StreamID{}, false, nil
+	case s == "+":
+		return maxStreamID, false, nil
+	case len(s) > 1 && s[0] == '(':
+		id, err = parseStreamID(s[1:], missingSeq)
+		return id, true, err
+	}
+	id, err = parseStreamID(s, missingSeq)
+	return id, false, err
+}
+
+// parseStreamRange parses the start and end of a stream range into the inclusive IDs between
+// them.
+func parseStreamRange(startArg, endArg string) (start, end StreamID, err error) {
+	start, exclusive, err := parseStreamRangeID(startArg, 0)
+	if err != nil {
+		return StreamID{}, StreamID{}, err
+	}
+	if exclusive {
+		var ok bool
+		if start, ok = start.next(); !ok {
+			return StreamID{}, StreamID{}, CommandError("ERR invalid start ID for the interval")
+		}
+	}
+	end, exclusive, err = parseStreamRangeID(endArg, maxStreamID.Seq)
+	if err != nil {
+		return StreamID{}, StreamID{}, err
+	}
+	if exclusive {
+		var ok bool
+		if end, ok = end.prev(); !ok {
+			return StreamID{}, StreamID{}, CommandError("ERR invalid end ID for the interval")
+		}
+	}
+	return start, end, nil
+}
+
+// parseStreamTrim parses the trimming options of XADD or XTRIM from array[i:], stopping at the
+// first argument that isn't one for XADD, and returns the index of that argument. trim is nil if
+// there are none.
+func parseStreamTrim(
+	array []string,
+	i int,
+	xadd bool,
+) (trim *StreamTrim, noMkStream bool, next int, err error) {
+	limit := -1
+	for ; i < len(array); i++ {
+		moreArgs := len(array) - 1 - i
+		isMaxLen := strings.EqualFold(array[i], "MAXLEN")
+		switch {
+		case xadd && array[i] == "*":
+			return finishStreamTrim(trim, limit, noMkStream, i, xadd)
+		case (isMaxLen || strings.EqualFold(array[i], "MINID")) && moreArgs > 0:
+			if trim != nil {
+				return nil, false, 0, errStreamTrimConflict
+			}
+			trim = &StreamTrim{}
+			if moreArgs >= 2 && (array[i+1] == "~" || array[i+1] == "=") {
+				trim.Approximate = array[i+1] == "~"
+				i++
+			}
+			i++
+			if isMaxLen {
+				trim.Strategy = StreamTrimMaxLen
+				if trim.MaxLen, err = parseInteger(array[i]); err != nil {
+					return nil, false, 0, err
+				}
+				if trim.MaxLen < 0 {
+					return nil, false, 0, CommandError("ERR The MAXLEN argument must be >= 0.")
+				}
+			} else {
+				trim.Strategy = StreamTrimMinID
+				if trim.MinID, err = parseStreamID(array[i], 0); err != nil {
+					return nil, false, 0, err
+				}
+			}
+		case strings.EqualFold(array[i], "LIMIT") && moreArgs > 0:
+			if limit, err = parseInteger(array[i+1]); err != nil {
+				return nil, false, 0, err
+			}
+			if limit < 0 {
+				return nil, false, 0, CommandError("ERR The LIMIT argument must be >= 0.")
+			}
+			i++
+		case xadd && strings.EqualFold(array[i], "NOMKSTREAM"):
+			noMkStream = true
+		case xadd:
+			return finishStreamTrim(trim, limit, noMkStream, i, xadd)
+		default:
+			return nil, false, 0, errSyntax
+		}
+	}
+	return finishStreamTrim(trim, limit, noMkStream, i, xadd)
+}
+
+// finishStreamTrim checks the trimming options parsed by parseStreamTrim, with limit being
+// negative if LIMIT wasn't given, and returns them.
+func finishStreamTrim(
+	trim *StreamTrim,
+	limit int,
+	noMkStream bool,
+	next int,
+	xadd bool,
+) (*StreamTrim, bool, int, error) {
+	switch {
+	case trim == nil && limit >= 0:
+		return nil, false, 0, errStreamLimitWithoutTrim
+	case trim == nil && !xadd:
+		return nil, false, 0, errXTrimWithoutTrim
+	case trim == nil:
+		return nil, noMkStream, next, nil
+	case limit >= 0 && !trim.Approximate:
+		return nil, false, 0, errStreamLimitNotApproximate
+	case limit >= 0:
+		trim.Limit = limit
+	case trim.Approximate:
+		trim.Limit = 100 * streamNodeMaxEntries
+	}
+	return trim, noMkStream, next, nil
+}
+
+func (p Parser) newXAddCommand(array []string) (Command, error) {
+	if len(array) < 5 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	trim, noMkStream, i, err := parseStreamTrim(array, 2, true)
+	if err != nil {
+		return nil, err
+	}
+	if fields := len(array) - i - 1; fields < 2 || fields%2 == 1 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+
+	var id XAddID
+	if array[i] == "*" {
+		id.Auto = true
+	} else if strings.HasSuffix(array[i], "-*") {
+		ms, err := strconv.ParseUint(strings.TrimSuffix(array[i], "-*"), 10, 64)
+		if err != nil {
+			return nil, errInvalidStreamID
+		}
+		id.ID.Ms, id.AutoSeq = ms, true
+	} else {
+		if id.ID, err = parseStreamID(array[i], 0); err != nil {
+			return nil, err
+		}
+		if id.ID == (StreamID{}) {
+			return nil, CommandError("ERR The ID specified in XADD must be greater than 0-0")
+		}
+	}
+
+	var options []func(*XAddCommand)
+	if noMkStream {
+		options = append(options, XAddNoMkStream())
+	}
+	if trim != nil {
+		options = append(options, XAddTrim(*trim))
+	}
+	return NewXAddCommand(p.store, p.clock, array[1], id, array[i+1:], options...), nil
+}
+
+func (p Parser) newXTrimCommand(array []string) (Command, error) {
+	if len(array) < 4 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	trim, _, _, err := parseStreamTrim(array, 2, false)
+	if err != nil {
+		return nil, err
+	}
+	return NewXTrimCommand(p.store, p.clock, array[1], *trim), nil
+}
+
+func (p Parser) newXRangeCommand(array []string) (Command, error) {
+	return p.newXRangeCommandWithDirection(array, false)
+}
+
+func (p Parser) newXRevRangeCommand(array []string) (Command, error) {
+	return p.newXRangeCommandWithDirection(array, true)
+}
+
+// newXRangeCommandWithDirection parses XRANGE, or XREVRANGE if reverse is true, which takes its
+// end before its start.
+func (p Parser) newXRangeCommandWithDirection(array []string, reverse bool) (Command, error) {
+	if len(array) < 4 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	startArg, endArg := array[2], array[3]
+	if reverse {
+		startArg, endArg = endArg, startArg
+	}
+	start, end, err := parseStreamRange(startArg, endArg)
+	if err != nil {
+		return nil, err
+	}
+
+	var options []func(*XRangeCommand)
+	if reverse {
+		options = append(options, XRangeReverse())
+	}
+	for i := 4; i < len(array); i += 2 {
+		if !strings.EqualFold(array[i], "COUNT") || i+1 == len(array) {
+			return nil, errSyntax
+		}
+		count, err := parseInteger(array[i+1])
+		if err != nil {
+			return nil, err
+		}
+		if count < 0 {
+			count = 0
+		}
+		options = append(options, XRangeCount(count))
+	}
+	return NewXRangeCommand(p.store, p.clock, array[1], start, end, options...), nil
+}
+
+func (p Parser) newXLenCommand(array []string) (Command, error) {
+	if len(array) != 2 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	return NewXLenCommand(p.store, p.clock, array[1]), nil
+}
+
+func (p Parser) newXDelCommand(array []string) (Command, error) {
+	if len(array) < 3 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	ids := make([]StreamID, len(array)-2)
+	for i, arg := range array[2:] {
+		id, err := parseStreamID(arg, 0)
+		if err != nil {
+			return nil, err
+		}
+		ids[i] = id
+	}
+	return NewXDelCommand(p.store, p.clock, array[1], ids), nil
+}
+
+func (p Parser) newXReadCommand(array []string) (Command, error) {
+	if len(array) < 4 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	var options []func(*XReadCommand)
+	streams := 0
+	for i := 1; i < len(array) && streams == 0; i++ {
+		moreArgs := len(array) - 1 - i
+		switch {
+		case strings.EqualFold(array[i], "COUNT") && moreArgs > 0:
+			count, err := parseInteger(array[i+1])
+			if err != nil {
+				return nil, err
+			}
+			options = append(options, XReadCount(count))
+			i++
+		case strings.EqualFold(array[i], "BLOCK") && moreArgs > 0:
+			timeout, err := parseMillisecondsTimeout(array[i+1])
+			if err != nil {
+				return nil, err
+			}
+			options = append(options, XReadBlock(timeout))
+			i++
+		case strings.EqualFold(array[i], "STREAMS") && moreArgs > 0:
+			streams = i + 1
+		default:
+			return nil, errSyntax
+		}
+	}
+	if streams == 0 {
+		return nil, errSyntax
+	}
+	if (len(array)-streams)%2 != 0 {
+		return nil, CommandError("ERR Unbalanced 'xread' list of streams: for each stream key an ID " +
+			"or '$' must be specified.")
+	}
+
+	half := (len(array) - streams) / 2
+	keys := array[streams : streams+half]
+	ids := make([]XReadID, half)
+	for i, arg := range array[streams+half:] {
+		switch arg {
+		case "$":
+			ids[i].New = true
+		case "+":
+			ids[i].Last = true
+		default:
+			id, err := parseStreamID(arg, 0)
+			if err != nil {
+				return nil, err
+			}
+			ids[i].ID = id
+		}
+	}
+	return NewXReadCommand(p.store, p.clock, p.client, keys, ids, options...), nil
+}
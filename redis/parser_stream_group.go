@@ -0,0 +1,390 @@
+package redis
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// parseXGroupID parses where XGROUP CREATE or SETID starts a group: "$" or an ID.
+func parseXGroupID(s string) (XGroupID, error) {
+	if s == "$" {
+		return XGroupID{New: true}, nil
+	}
+	id, err := parseStreamID(s, 0)
+	return XGroupID{ID: id}, err
+}
+
+// parseEntriesRead parses the ENTRIESREAD argument of XGROUP CREATE and SETID.
+func parseEntriesRead(s string) (int, error) {
+	entriesRead, err := parseInteger(s)
+	if err != nil {
+		return 0, err
+	}
+	if entriesRead < -1 {
+		return 0, CommandError("ERR value for ENTRIESREAD must be positive or -1")
+	}
+	return entriesRead, nil
+}
+
+// parseMinIdleTime parses the min-idle-time argument of XCLAIM or XAUTOCLAIM, which is a number
+// of milliseconds that's treated as 0 if it's negative.
+func parseMinIdleTime(s, command string) (time.Duration, error) {
+	milliseconds, err := parseInteger(s)
+	if err != nil || milliseconds > math.MaxInt64/int(time.Millisecond) {
+		return 0, CommandError(fmt.Sprintf("ERR Invalid min-idle-time argument for %s", command))
+	}
+	if milliseconds < 0 {
+		milliseconds = 0
+	}
+	return time.Duration(milliseconds) * time.Millisecond, nil
+}
+
+func (p Parser) newXGroupCommand(array []string) (Command, error) {
+	if len(array) < 2 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	switch {
+	case strings.EqualFold(array[1], "CREATE"):
+		return p.newXGroupCreateCommand(array)
+	case strings.EqualFold(array[1], "SETID"):
+		return p.newXGroupSetIDCommand(array)
+	case strings.EqualFold(array[1], "DESTROY"):
+		if len(array) != 4 {
+			return nil, wrongNumberOfArgumentsError([]string{"xgroup|destroy"})
+		}
+		return NewXGroupDestroyCommand(p.store, p.clock, array[2], array[3]), nil
+	case strings.EqualFold(array[1], "CREATECONSUMER"):
+		if len(array) != 5 {
+			return nil, wrongNumberOfArgumentsError([]string{"xgroup|createconsumer"})
+		}
+		return NewXGroupCreateConsumerCommand(p.store, p.clock, array[2], array[3], array[4]), nil
+	case strings.EqualFold(array[1], "DELCONSUMER"):
+		if len(array) != 5 {
+			return nil, wrongNumberOfArgumentsError([]string{"xgroup|delconsumer"})
+		}
+		return NewXGroupDelConsumerCommand(p.store, p.clock, array[2], array[3], array[4]), nil
+	}
+	return nil, CommandError(
+		fmt.Sprintf("ERR unknown subcommand '%s'. Try XGROUP HELP.", array[1]),
+	)
+}
+
+func (p Parser) newXGroupCreateCommand(array []string) (Command, error) {
+	if len(array) < 5 {
+		return nil, wrongNumberOfArgumentsError([]string{"xgroup|create"})
+	}
+	id, err := parseXGroupID(array[4])
+	if err != nil {
+		return nil, err
+	}
+	var options []func(*XGroupCreateCommand)
+	for i := 5; i < len(array); i++ {
+		switch {
+		case strings.EqualFold(array[i], "MKSTREAM"):
+			options = append(options, XGroupCreateMkStream())
+		case strings.EqualFold(array[i], "ENTRIESREAD") && i+1 < len(array):
+			entriesRead, err := parseEntriesRead(array[i+1])
+			if err != nil {
+				return nil, err
+			}
+			options = append(options, XGroupCreateEntriesRead(entriesRead))
+			i++
+		default:
+			return nil, errSyntax
+		}
+	}
+	return NewXGroupCreateCommand(p.store, p.clock, array[2], array[3], id, options...), nil
+}
+
+func (p Parser) newXGroupSetIDCommand(array []string) (Command, error) {
+	if len(array) != 5 && len(array) != 7 {
+		return nil, wrongNumberOfArgumentsError([]string{"xgroup|setid"})
+	}
+	id, err := parseXGroupID(array[4])
+	if err != nil {
+		return nil, err
+	}
+	entriesRead := -1
+	if len(array) == 7 {
+		if !strings.EqualFold(array[5], "ENTRIESREAD") {
+			return nil, errSyntax
+		}
+		if entriesRead, err = parseEntriesRead(array[6]); err != nil {
+			return nil, err
+		}
+	}
+	return NewXGroupSetIDCommand(p.store, p.clock, array[2], array[3], id, entriesRead), nil
+}
+
+func (p Parser) newXReadGroupCommand(array []string) (Command, error) {
+	if len(array) < 7 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	var options []func(*XReadGroupCommand)
+	var group, consumer string
+	hasGroup := false
+	streams := 0
+	for i := 1; i < len(array) && streams == 0; i++ {
+		moreArgs := len(array) - 1 - i
+		switch {
+		case strings.EqualFold(array[i], "GROUP") && moreArgs >= 2:
+			group, consumer, hasGroup = array[i+1], array[i+2], true
+			i += 2
+		case strings.EqualFold(array[i], "COUNT") && moreArgs > 0:
+			count, err := parseInteger(array[i+1])
+			if err != nil {
+				return nil, err
+			}
+			options = append(options, XReadGroupCount(count))
+			i++
+		case strings.EqualFold(array[i], "BLOCK") && moreArgs > 0:
+			timeout, err := parseMillisecondsTimeout(array[i+1])
+			if err != nil {
+				return nil, err
+			}
+			options = append(options, XReadGroupBlock(timeout))
+			i++
+		case strings.EqualFold(array[i], "NOACK"):
+			options = append(options, XReadGroupNoAck())
+		case strings.EqualFold(array[i], "STREAMS") && moreArgs > 0:
+			streams = i + 1
+		default:
+			return nil, errSyntax
+		}
+	}
+	if streams == 0 {
+		return nil, errSyntax
+	}
+	if (len(array)-streams)%2 != 0 {
+		return nil, CommandError("ERR Unbalanced 'xreadgroup' list of streams: for each stream key " +
+			"an ID or '>' must be specified.")
+	}
+	if !hasGroup {
+		return nil, CommandError("ERR Missing GROUP option for XREADGROUP")
+	}
+
+	half := (len(array) - streams) / 2
+	keys := array[streams : streams+half]
+	ids := make([]XReadGroupID, half)
+	for i, arg := range array[streams+half:] {
+		switch arg {
+		case ">":
+			ids[i].Undelivered = true
+		case "$":
+			return nil, CommandError("ERR The $ ID is meaningful only for XREAD")
+		default:
+			id, err := parseStreamID(arg, 0)
+			if err != nil {
+				return nil, err
+			}
+			ids[i].ID = id
+		}
+	}
+	return NewXReadGroupCommand(
+		p.store, p.clock, p.client, group, consumer, keys, ids, options...,
+	), nil
+}
+
+func (p Parser) newXAckCommand(array []string) (Command, error) {
+	if len(array) < 4 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	ids := make([]StreamID, len(array)-3)
+	for i, arg := range array[3:] {
+		id, err := parseStreamID(arg, 0)
+		if err != nil {
+			return nil, err
+		}
+		ids[i] = id
+	}
+	return NewXAckCommand(p.store, p.clock, array[1], array[2], ids), nil
+}
+
+func (p Parser) newXPendingCommand(array []string) (Command, error) {
+	if len(array) < 3 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	if len(array) == 3 {
+		return NewXPendingCommand(p.store, p.clock, array[1], array[2]), nil
+	}
+
+	var options []func(*XPendingCommand)
+	i := 3
+	if strings.EqualFold(array[3], "IDLE") && len(array) > 4 {
+		minIdle, err := parseInteger(array[4])
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, XPendingMinIdle(time.Duration(minIdle)*time.Millisecond))
+		i += 2
+	}
+	if rest := len(array) - i; rest != 3 && rest != 4 {
+		return nil, errSyntax
+	}
+	count, err := parseInteger(array[i+2])
+	if err != nil {
+		return nil, err
+	}
+	if count < 0 {
+		count = 0
+	}
+	start, end, err := parseStreamRange(array[i], array[i+1])
+	if err != nil {
+		return nil, err
+	}
+	options = append(options, XPendingRange(start, end, count))
+	if i+3 < len(array) {
+		options = append(options, XPendingConsumer(array[i+3]))
+	}
+	return NewXPendingCommand(p.store, p.clock, array[1], array[2], options...), nil
+}
+
+func (p Parser) newXClaimCommand(array []string) (Command, error) {
+	if len(array) < 6 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	minIdle, err := parseMinIdleTime(array[4], "XCLAIM")
+	if err != nil {
+		return nil, err
+	}
+	// The IDs carry on until the first argument that isn't one.
+	i := 5
+	var ids []StreamID
+	for ; i < len(array); i++ {
+		id, err := parseStreamID(array[i], 0)
+		if err != nil {
+			break
+		}
+		ids = append(ids, id)
+	}
+
+	var options []func(*XClaimCommand)
+	for ; i < len(array); i++ {
+		moreArgs := len(array) - 1 - i
+		switch {
+		case strings.EqualFold(array[i], "FORCE"):
+			options = append(options, XClaimForce())
+		case strings.EqualFold(array[i], "JUSTID"):
+			options = append(options, XClaimJustID())
+		case strings.EqualFold(array[i], "IDLE") && moreArgs > 0:
+			idle, err := parseInteger(array[i+1])
+			if err != nil {
+				return nil, CommandError("ERR Invalid IDLE option argument for XCLAIM")
+			}
+			options = append(options, XClaimIdle(time.Duration(idle)*time.Millisecond))
+			i++
+		case strings.EqualFold(array[i], "TIME") && moreArgs > 0:
+			milliseconds, err := parseInteger(array[i+1])
+			if err != nil {
+				return nil, CommandError("ERR Invalid TIME option argument for XCLAIM")
+			}
+			options = append(options, XClaimTime(time.UnixMilli(int64(milliseconds))))
+			i++
+		case strings.EqualFold(array[i], "RETRYCOUNT") && moreArgs > 0:
+			retryCount, err := parseInteger(array[i+1])
+			if err != nil || retryCount < 0 {
+				return nil, CommandError("ERR Invalid RETRYCOUNT option argument for XCLAIM")
+			}
+			options = append(options, XClaimRetryCount(retryCount))
+			i++
+		case strings.EqualFold(array[i], "LASTID") && moreArgs > 0:
+			id, err := parseStreamID(array[i+1], 0)
+			if err != nil {
+				return nil, err
+			}
+			options = append(options, XClaimLastID(id))
+			i++
+		default:
+			return nil, CommandError(fmt.Sprintf("ERR Unrecognized XCLAIM option '%s'", array[i]))
+		}
+	}
+	return NewXClaimCommand(
+		p.store, p.clock, array[1], array[2], array[3], minIdle, ids, options...,
+	), nil
+}
+
+func (p Parser) newXAutoClaimCommand(array []string) (Command, error) {
+	if len(array) < 6 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	minIdle, err := parseMinIdleTime(array[4], "XAUTOCLAIM")
+	if err != nil {
+		return nil, err
+	}
+	start, _, err := parseStreamRange(array[5], "+")
+	if err != nil {
+		return nil, err
+	}
+
+	var options []func(*XAutoClaimCommand)
+	for i := 6; i < len(array); i++ {
+		switch {
+		case strings.EqualFold(array[i], "JUSTID"):
+			options = append(options, XAutoClaimJustID())
+		case strings.EqualFold(array[i], "COUNT") && i+1 < len(array):
+			count, err := parseInteger(array[i+1])
+			if err != nil {
+				return nil, err
+			}
+			if count < 1 || count > math.MaxInt64/10 {
+				return nil, CommandError("ERR COUNT must be > 0")
+			}
+			options = append(options, XAutoClaimCount(count))
+			i++
+		default:
+			return nil, errSyntax
+		}
+	}
+	return NewXAutoClaimCommand(
+		p.store, p.clock, array[1], array[2], array[3], minIdle, start, options...,
+	), nil
+}
+
+func (p Parser) newXInfoCommand(array []string) (Command, error) {
+	if len(array) < 2 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	switch {
+	case strings.EqualFold(array[1], "STREAM"):
+		return p.newXInfoStreamCommand(array)
+	case strings.EqualFold(array[1], "GROUPS"):
+		if len(array) != 3 {
+			return nil, wrongNumberOfArgumentsError([]string{"xinfo|groups"})
+		}
+		return NewXInfoGroupsCommand(p.store, p.clock, array[2]), nil
+	case strings.EqualFold(array[1], "CONSUMERS"):
+		if len(array) != 4 {
+			return nil, wrongNumberOfArgumentsError([]string{"xinfo|consumers"})
+		}
+		return NewXInfoConsumersCommand(p.store, p.clock, array[2], array[3]), nil
+	}
+	return nil, CommandError(
+		fmt.Sprintf("ERR unknown subcommand '%s'. Try XINFO HELP.", array[1]),
+	)
+}
+
+func (p Parser) newXInfoStreamCommand(array []string) (Command, error) {
+	if len(array) < 3 {
+		return nil, wrongNumberOfArgumentsError([]string{"xinfo|stream"})
+	}
+	switch {
+	case len(array) == 3:
+		return NewXInfoStreamCommand(p.store, p.clock, array[2]), nil
+	case !strings.EqualFold(array[3], "FULL"):
+		return nil, errSyntax
+	case len(array) == 4:
+		return NewXInfoStreamCommand(p.store, p.clock, array[2], XInfoStreamFull(10)), nil
+	case len(array) != 6 || !strings.EqualFold(array[4], "COUNT"):
+		return nil, errSyntax
+	}
+	count, err := parseInteger(array[5])
+	if err != nil {
+		return nil, err
+	}
+	if count < 0 {
+		count = 10
+	}
+	return NewXInfoStreamCommand(p.store, p.clock, array[2], XInfoStreamFull(count)), nil
+}
@@ -0,0 +1,398 @@
+package redis_test
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/redis"
+)
+
+func TestParser_ParseStreamGroupRequest(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{}
+
+	tests := []struct {
+		name    string
+		request string
+		want    redis.Command
+	}{
+		{
+			name:    "XGROUP CREATE s g 0",
+			request: "*5\r\n$6\r\nXGROUP\r\n$6\r\nCREATE\r\n$1\r\ns\r\n$1\r\ng\r\n$1\r\n0\r\n",
+			want:    redis.NewXGroupCreateCommand(store, clock, "s", "g", redis.XGroupID{}),
+		},
+		{
+			name: "XGROUP create s g $ MKSTREAM ENTRIESREAD 5",
+			request: "*8\r\n$6\r\nXGROUP\r\n$6\r\ncreate\r\n$1\r\ns\r\n$1\r\ng\r\n" +
+				"$1\r\n$\r\n$8\r\nMKSTREAM\r\n$11\r\nENTRIESREAD\r\n$1\r\n5\r\n",
+			want: redis.NewXGroupCreateCommand(
+				store, clock, "s", "g", redis.XGroupID{New: true}, redis.XGroupCreateMkStream(),
+				redis.XGroupCreateEntriesRead(5),
+			),
+		},
+		{
+			name:    "XGROUP SETID s g 1-2",
+			request: "*5\r\n$6\r\nXGROUP\r\n$5\r\nSETID\r\n$1\r\ns\r\n$1\r\ng\r\n$3\r\n1-2\r\n",
+			want: redis.NewXGroupSetIDCommand(
+				store, clock, "s", "g", redis.XGroupID{ID: redis.StreamID{Ms: 1, Seq: 2}}, -1,
+			),
+		},
+		{
+			name: "XGROUP SETID s g $ ENTRIESREAD 3",
+			request: "*7\r\n$6\r\nXGROUP\r\n$5\r\nSETID\r\n$1\r\ns\r\n$1\r\ng\r\n$1\r\n$\r\n" +
+				"$11\r\nENTRIESREAD\r\n$1\r\n3\r\n",
+			want: redis.NewXGroupSetIDCommand(store, clock, "s", "g", redis.XGroupID{New: true}, 3),
+		},
+		{
+			name:    "XGROUP DESTROY s g",
+			request: "*4\r\n$6\r\nXGROUP\r\n$7\r\nDESTROY\r\n$1\r\ns\r\n$1\r\ng\r\n",
+			want:    redis.NewXGroupDestroyCommand(store, clock, "s", "g"),
+		},
+		{
+			name: "XGROUP CREATECONSUMER s g c",
+			request: "*5\r\n$6\r\nXGROUP\r\n$14\r\nCREATECONSUMER\r\n$1\r\ns\r\n$1\r\ng\r\n" +
+				"$1\r\nc\r\n",
+			want: redis.NewXGroupCreateConsumerCommand(store, clock, "s", "g", "c"),
+		},
+		{
+			name: "XGROUP DELCONSUMER s g c",
+			request: "*5\r\n$6\r\nXGROUP\r\n$11\r\nDELCONSUMER\r\n$1\r\ns\r\n$1\r\ng\r\n" +
+				"$1\r\nc\r\n",
+			want: redis.NewXGroupDelConsumerCommand(store, clock, "s", "g", "c"),
+		},
+		{
+			name: "XREADGROUP GROUP g c STREAMS a b > 1",
+			request: "*9\r\n$10\r\nXREADGROUP\r\n$5\r\nGROUP\r\n$1\r\ng\r\n$1\r\nc\r\n" +
+				"$7\r\nSTREAMS\r\n$1\r\na\r\n$1\r\nb\r\n$1\r\n>\r\n$1\r\n1\r\n",
+			want: redis.NewXReadGroupCommand(
+				store, clock, nil, "g", "c", []string{"a", "b"},
+				[]redis.XReadGroupID{{Undelivered: true}, {ID: redis.StreamID{Ms: 1}}},
+			),
+		},
+		{
+			name: "XREADGROUP GROUP g c COUNT 2 BLOCK 100 NOACK STREAMS a >",
+			request: "*12\r\n$10\r\nXREADGROUP\r\n$5\r\nGROUP\r\n$1\r\ng\r\n$1\r\nc\r\n" +
+				"$5\r\nCOUNT\r\n$1\r\n2\r\n$5\r\nBLOCK\r\n$3\r\n100\r\n$5\r\nNOACK\r\n" +
+				"$7\r\nSTREAMS\r\n$1\r\na\r\n$1\r\n>\r\n",
+			want: redis.NewXReadGroupCommand(
+				store, clock, nil, "g", "c", []string{"a"},
+				[]redis.XReadGroupID{{Undelivered: true}}, redis.XReadGroupCount(2),
+				redis.XReadGroupBlock(100*time.Millisecond), redis.XReadGroupNoAck(),
+			),
+		},
+		{
+			name:    "XACK s g 1 2-3",
+			request: "*5\r\n$4\r\nXACK\r\n$1\r\ns\r\n$1\r\ng\r\n$1\r\n1\r\n$3\r\n2-3\r\n",
+			want: redis.NewXAckCommand(
+				store, clock, "s", "g", []redis.StreamID{{Ms: 1}, {Ms: 2, Seq: 3}},
+			),
+		},
+		{
+			name:    "XPENDING s g",
+			request: "*3\r\n$8\r\nXPENDING\r\n$1\r\ns\r\n$1\r\ng\r\n",
+			want:    redis.NewXPendingCommand(store, clock, "s", "g"),
+		},
+		{
+			name: "XPENDING s g - + 10",
+			request: "*6\r\n$8\r\nXPENDING\r\n$1\r\ns\r\n$1\r\ng\r\n$1\r\n-\r\n$1\r\n+\r\n" +
+				"$2\r\n10\r\n",
+			want: redis.NewXPendingCommand(
+				store, clock, "s", "g", redis.XPendingRange(redis.StreamID{}, maxStreamID, 10),
+			),
+		},
+		{
+			name: "XPENDING s g IDLE 500 (1 5 -1 c",
+			request: "*9\r\n$8\r\nXPENDING\r\n$1\r\ns\r\n$1\r\ng\r\n$4\r\nIDLE\r\n" +
+				"$3\r\n500\r\n$2\r\n(1\r\n$1\r\n5\r\n$2\r\n-1\r\n$1\r\nc\r\n",
+			want: redis.NewXPendingCommand(
+				store, clock, "s", "g", redis.XPendingMinIdle(500*time.Millisecond),
+				redis.XPendingRange(
+					redis.StreamID{Ms: 1, Seq: 1}, redis.StreamID{Ms: 5, Seq: math.MaxUint64}, 0,
+				),
+				redis.XPendingConsumer("c"),
+			),
+		},
+		{
+			name: "XCLAIM s g c 1000 1 2-0",
+			request: "*7\r\n$6\r\nXCLAIM\r\n$1\r\ns\r\n$1\r\ng\r\n$1\r\nc\r\n$4\r\n1000\r\n" +
+				"$1\r\n1\r\n$3\r\n2-0\r\n",
+			want: redis.NewXClaimCommand(
+				store, clock, "s", "g", "c", time.Second, []redis.StreamID{{Ms: 1}, {Ms: 2}},
+			),
+		},
+		{
+			name: "XCLAIM s g c -5 1 IDLE 10 RETRYCOUNT 3 FORCE JUSTID LASTID 4",
+			request: "*14\r\n$6\r\nXCLAIM\r\n$1\r\ns\r\n$1\r\ng\r\n$1\r\nc\r\n$2\r\n-5\r\n" +
+				"$1\r\n1\r\n$4\r\nIDLE\r\n$2\r\n10\r\n$10\r\nRETRYCOUNT\r\n$1\r\n3\r\n" +
+				"$5\r\nFORCE\r\n$6\r\nJUSTID\r\n$6\r\nLASTID\r\n$1\r\n4\r\n",
+			want: redis.NewXClaimCommand(
+				store, clock, "s", "g", "c", 0, []redis.StreamID{{Ms: 1}},
+				redis.XClaimIdle(10*time.Millisecond), redis.XClaimRetryCount(3),
+				redis.XClaimForce(), redis.XClaimJustID(),
+				redis.XClaimLastID(redis.StreamID{Ms: 4}),
+			),
+		},
+		{
+			name: "XCLAIM s g c 0 1 TIME 1500",
+			request: "*8\r\n$6\r\nXCLAIM\r\n$1\r\ns\r\n$1\r\ng\r\n$1\r\nc\r\n$1\r\n0\r\n" +
+				"$1\r\n1\r\n$4\r\nTIME\r\n$4\r\n1500\r\n",
+			want: redis.NewXClaimCommand(
+				store, clock, "s", "g", "c", 0, []redis.StreamID{{Ms: 1}},
+				redis.XClaimTime(time.UnixMilli(1500)),
+			),
+		},
+		{
+			name: "XAUTOCLAIM s g c 1000 0",
+			request: "*6\r\n$10\r\nXAUTOCLAIM\r\n$1\r\ns\r\n$1\r\ng\r\n$1\r\nc\r\n" +
+				"$4\r\n1000\r\n$1\r\n0\r\n",
+			want: redis.NewXAutoClaimCommand(
+				store, clock, "s", "g", "c", time.Second, redis.StreamID{},
+			),
+		},
+		{
+			name: "XAUTOCLAIM s g c 0 (1-0 COUNT 5 JUSTID",
+			request: "*9\r\n$10\r\nXAUTOCLAIM\r\n$1\r\ns\r\n$1\r\ng\r\n$1\r\nc\r\n" +
+				"$1\r\n0\r\n$4\r\n(1-0\r\n$5\r\nCOUNT\r\n$1\r\n5\r\n$6\r\nJUSTID\r\n",
+			want: redis.NewXAutoClaimCommand(
+				store, clock, "s", "g", "c", 0, redis.StreamID{Ms: 1, Seq: 1},
+				redis.XAutoClaimCount(5), redis.XAutoClaimJustID(),
+			),
+		},
+		{
+			name:    "XINFO STREAM s",
+			request: "*3\r\n$5\r\nXINFO\r\n$6\r\nSTREAM\r\n$1\r\ns\r\n",
+			want:    redis.NewXInfoStreamCommand(store, clock, "s"),
+		},
+		{
+			name:    "XINFO STREAM s FULL",
+			request: "*4\r\n$5\r\nXINFO\r\n$6\r\nSTREAM\r\n$1\r\ns\r\n$4\r\nFULL\r\n",
+			want:    redis.NewXInfoStreamCommand(store, clock, "s", redis.XInfoStreamFull(10)),
+		},
+		{
+			name: "XINFO STREAM s FULL COUNT 0",
+			request: "*6\r\n$5\r\nXINFO\r\n$6\r\nSTREAM\r\n$1\r\ns\r\n$4\r\nFULL\r\n" +
+				"$5\r\nCOUNT\r\n$1\r\n0\r\n",
+			want: redis.NewXInfoStreamCommand(store, clock, "s", redis.XInfoStreamFull(0)),
+		},
+		{
+			name:    "XINFO GROUPS s",
+			request: "*3\r\n$5\r\nXINFO\r\n$6\r\nGROUPS\r\n$1\r\ns\r\n",
+			want:    redis.NewXInfoGroupsCommand(store, clock, "s"),
+		},
+		{
+			name:    "XINFO CONSUMERS s g",
+			request: "*4\r\n$5\r\nXINFO\r\n$9\r\nCONSUMERS\r\n$1\r\ns\r\n$1\r\ng\r\n",
+			want:    redis.NewXInfoConsumersCommand(store, clock, "s", "g"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requestReader := strings.NewReader(tt.request)
+
+			command, err := redis.NewParser(zeroValueRedisConfig, store, clock).Parse(requestReader)
+
+			if err != nil {
+				t.Errorf("err: expected: nil; got: %v", err)
+			}
+			if !reflect.DeepEqual(command, tt.want) {
+				t.Errorf("command expected to be %#v but was %#v", tt.want, command)
+			}
+		})
+	}
+}
+
+func TestParser_ParseInvalidStreamGroupRequest(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		request string
+		err     redis.CommandError
+	}{
+		{
+			name:    "XGROUP",
+			request: "*1\r\n$6\r\nXGROUP\r\n",
+			err:     "ERR wrong number of arguments for 'xgroup' command",
+		},
+		{
+			name:    "XGROUP FOO s",
+			request: "*3\r\n$6\r\nXGROUP\r\n$3\r\nFOO\r\n$1\r\ns\r\n",
+			err:     "ERR unknown subcommand 'FOO'. Try XGROUP HELP.",
+		},
+		{
+			name:    "XGROUP CREATE s g",
+			request: "*4\r\n$6\r\nXGROUP\r\n$6\r\nCREATE\r\n$1\r\ns\r\n$1\r\ng\r\n",
+			err:     "ERR wrong number of arguments for 'xgroup|create' command",
+		},
+		{
+			name:    "XGROUP CREATE s g x",
+			request: "*5\r\n$6\r\nXGROUP\r\n$6\r\nCREATE\r\n$1\r\ns\r\n$1\r\ng\r\n$1\r\nx\r\n",
+			err:     "ERR Invalid stream ID specified as stream command argument",
+		},
+		{
+			name: "XGROUP CREATE s g 0 FOO",
+			request: "*6\r\n$6\r\nXGROUP\r\n$6\r\nCREATE\r\n$1\r\ns\r\n$1\r\ng\r\n" +
+				"$1\r\n0\r\n$3\r\nFOO\r\n",
+			err: "ERR syntax error",
+		},
+		{
+			name: "XGROUP CREATE s g 0 ENTRIESREAD -2",
+			request: "*7\r\n$6\r\nXGROUP\r\n$6\r\nCREATE\r\n$1\r\ns\r\n$1\r\ng\r\n" +
+				"$1\r\n0\r\n$11\r\nENTRIESREAD\r\n$2\r\n-2\r\n",
+			err: "ERR value for ENTRIESREAD must be positive or -1",
+		},
+		{
+			name: "XGROUP SETID s g 0 ENTRIESREAD",
+			request: "*6\r\n$6\r\nXGROUP\r\n$5\r\nSETID\r\n$1\r\ns\r\n$1\r\ng\r\n$1\r\n0\r\n" +
+				"$11\r\nENTRIESREAD\r\n",
+			err: "ERR wrong number of arguments for 'xgroup|setid' command",
+		},
+		{
+			name:    "XGROUP DESTROY s",
+			request: "*3\r\n$6\r\nXGROUP\r\n$7\r\nDESTROY\r\n$1\r\ns\r\n",
+			err:     "ERR wrong number of arguments for 'xgroup|destroy' command",
+		},
+		{
+			name:    "XGROUP DELCONSUMER s g",
+			request: "*4\r\n$6\r\nXGROUP\r\n$11\r\nDELCONSUMER\r\n$1\r\ns\r\n$1\r\ng\r\n",
+			err:     "ERR wrong number of arguments for 'xgroup|delconsumer' command",
+		},
+		{
+			name: "XREADGROUP GROUP g c STREAMS a",
+			request: "*6\r\n$10\r\nXREADGROUP\r\n$5\r\nGROUP\r\n$1\r\ng\r\n$1\r\nc\r\n" +
+				"$7\r\nSTREAMS\r\n$1\r\na\r\n",
+			err: "ERR wrong number of arguments for 'xreadgroup' command",
+		},
+		{
+			name: "XREADGROUP GROUP g c STREAMS a b >",
+			request: "*8\r\n$10\r\nXREADGROUP\r\n$5\r\nGROUP\r\n$1\r\ng\r\n$1\r\nc\r\n" +
+				"$7\r\nSTREAMS\r\n$1\r\na\r\n$1\r\nb\r\n$1\r\n>\r\n",
+			err: "ERR Unbalanced 'xreadgroup' list of streams: for each stream key an ID or '>' must be specified.",
+		},
+		{
+			name: "XREADGROUP COUNT 1 NOACK STREAMS a >",
+			request: "*7\r\n$10\r\nXREADGROUP\r\n$5\r\nCOUNT\r\n$1\r\n1\r\n$5\r\nNOACK\r\n" +
+				"$7\r\nSTREAMS\r\n$1\r\na\r\n$1\r\n>\r\n",
+			err: "ERR Missing GROUP option for XREADGROUP",
+		},
+		{
+			name: "XREADGROUP GROUP g c STREAMS a $",
+			request: "*7\r\n$10\r\nXREADGROUP\r\n$5\r\nGROUP\r\n$1\r\ng\r\n$1\r\nc\r\n" +
+				"$7\r\nSTREAMS\r\n$1\r\na\r\n$1\r\n$\r\n",
+			err: "ERR The $ ID is meaningful only for XREAD",
+		},
+		{
+			name: "XREADGROUP GROUP g c FOO STREAMS a >",
+			request: "*8\r\n$10\r\nXREADGROUP\r\n$5\r\nGROUP\r\n$1\r\ng\r\n$1\r\nc\r\n" +
+				"$3\r\nFOO\r\n$7\r\nSTREAMS\r\n$1\r\na\r\n$1\r\n>\r\n",
+			err: "ERR syntax error",
+		},
+		{
+			name:    "XACK s g",
+			request: "*3\r\n$4\r\nXACK\r\n$1\r\ns\r\n$1\r\ng\r\n",
+			err:     "ERR wrong number of arguments for 'xack' command",
+		},
+		{
+			name:    "XACK s g x",
+			request: "*4\r\n$4\r\nXACK\r\n$1\r\ns\r\n$1\r\ng\r\n$1\r\nx\r\n",
+			err:     "ERR Invalid stream ID specified as stream command argument",
+		},
+		{
+			name:    "XPENDING s",
+			request: "*2\r\n$8\r\nXPENDING\r\n$1\r\ns\r\n",
+			err:     "ERR wrong number of arguments for 'xpending' command",
+		},
+		{
+			name:    "XPENDING s g - +",
+			request: "*5\r\n$8\r\nXPENDING\r\n$1\r\ns\r\n$1\r\ng\r\n$1\r\n-\r\n$1\r\n+\r\n",
+			err:     "ERR syntax error",
+		},
+		{
+			name: "XPENDING s g IDLE 5 - +",
+			request: "*7\r\n$8\r\nXPENDING\r\n$1\r\ns\r\n$1\r\ng\r\n$4\r\nIDLE\r\n" +
+				"$1\r\n5\r\n$1\r\n-\r\n$1\r\n+\r\n",
+			err: "ERR syntax error",
+		},
+		{
+			name: "XPENDING s g - + x",
+			request: "*6\r\n$8\r\nXPENDING\r\n$1\r\ns\r\n$1\r\ng\r\n$1\r\n-\r\n$1\r\n+\r\n" +
+				"$1\r\nx\r\n",
+			err: "ERR value is not an integer or out of range",
+		},
+		{
+			name:    "XCLAIM s g c 0",
+			request: "*5\r\n$6\r\nXCLAIM\r\n$1\r\ns\r\n$1\r\ng\r\n$1\r\nc\r\n$1\r\n0\r\n",
+			err:     "ERR wrong number of arguments for 'xclaim' command",
+		},
+		{
+			name: "XCLAIM s g c x 1",
+			request: "*6\r\n$6\r\nXCLAIM\r\n$1\r\ns\r\n$1\r\ng\r\n$1\r\nc\r\n$1\r\nx\r\n" +
+				"$1\r\n1\r\n",
+			err: "ERR Invalid min-idle-time argument for XCLAIM",
+		},
+		{
+			name: "XCLAIM s g c 0 1 FOO",
+			request: "*7\r\n$6\r\nXCLAIM\r\n$1\r\ns\r\n$1\r\ng\r\n$1\r\nc\r\n$1\r\n0\r\n" +
+				"$1\r\n1\r\n$3\r\nFOO\r\n",
+			err: "ERR Unrecognized XCLAIM option 'FOO'",
+		},
+		{
+			name: "XCLAIM s g c 0 1 RETRYCOUNT -1",
+			request: "*8\r\n$6\r\nXCLAIM\r\n$1\r\ns\r\n$1\r\ng\r\n$1\r\nc\r\n$1\r\n0\r\n" +
+				"$1\r\n1\r\n$10\r\nRETRYCOUNT\r\n$2\r\n-1\r\n",
+			err: "ERR Invalid RETRYCOUNT option argument for XCLAIM",
+		},
+		{
+			name: "XAUTOCLAIM s g c 0 0 COUNT 0",
+			request: "*8\r\n$10\r\nXAUTOCLAIM\r\n$1\r\ns\r\n$1\r\ng\r\n$1\r\nc\r\n" +
+				"$1\r\n0\r\n$1\r\n0\r\n$5\r\nCOUNT\r\n$1\r\n0\r\n",
+			err: "ERR COUNT must be > 0",
+		},
+		{
+			name: "XAUTOCLAIM s g c 0 0 FOO",
+			request: "*7\r\n$10\r\nXAUTOCLAIM\r\n$1\r\ns\r\n$1\r\ng\r\n$1\r\nc\r\n" +
+				"$1\r\n0\r\n$1\r\n0\r\n$3\r\nFOO\r\n",
+			err: "ERR syntax error",
+		},
+		{
+			name:    "XINFO FOO s",
+			request: "*3\r\n$5\r\nXINFO\r\n$3\r\nFOO\r\n$1\r\ns\r\n",
+			err:     "ERR unknown subcommand 'FOO'. Try XINFO HELP.",
+		},
+		{
+			name:    "XINFO STREAM s FOO",
+			request: "*4\r\n$5\r\nXINFO\r\n$6\r\nSTREAM\r\n$1\r\ns\r\n$3\r\nFOO\r\n",
+			err:     "ERR syntax error",
+		},
+		{
+			name:    "XINFO GROUPS",
+			request: "*2\r\n$5\r\nXINFO\r\n$6\r\nGROUPS\r\n",
+			err:     "ERR wrong number of arguments for 'xinfo|groups' command",
+		},
+		{
+			name:    "XINFO CONSUMERS s",
+			request: "*3\r\n$5\r\nXINFO\r\n$9\r\nCONSUMERS\r\n$1\r\ns\r\n",
+			err:     "ERR wrong number of arguments for 'xinfo|consumers' command",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := redis.NewStore()
+			clock := FakeClock{}
+			requestReader := strings.NewReader(tt.request)
+
+			_, err := redis.NewParser(zeroValueRedisConfig, store, clock).Parse(requestReader)
+
+			if err != tt.err {
+				t.Errorf("err: expected: %v; got: %v", tt.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,392 @@
+package redis_test
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/redis"
+)
+
+func TestParser_ParseStreamRequest(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{}
+
+	tests := []struct {
+		name    string
+		request string
+		want    redis.Command
+	}{
+		{
+			name:    "XADD stream * a 1",
+			request: "*5\r\n$4\r\nXADD\r\n$6\r\nstream\r\n$1\r\n*\r\n$1\r\na\r\n$1\r\n1\r\n",
+			want: redis.NewXAddCommand(
+				store, clock, "stream", redis.XAddID{Auto: true}, []string{"a", "1"},
+			),
+		},
+		{
+			name:    "XADD stream 5-* a 1",
+			request: "*5\r\n$4\r\nXADD\r\n$6\r\nstream\r\n$3\r\n5-*\r\n$1\r\na\r\n$1\r\n1\r\n",
+			want: redis.NewXAddCommand(
+				store, clock, "stream", redis.XAddID{ID: redis.StreamID{Ms: 5}, AutoSeq: true},
+				[]string{"a", "1"},
+			),
+		},
+		{
+			name: "XADD stream 5 a 1 b 2",
+			request: "*7\r\n$4\r\nXADD\r\n$6\r\nstream\r\n$1\r\n5\r\n$1\r\na\r\n$1\r\n1\r\n" +
+				"$1\r\nb\r\n$1\r\n2\r\n",
+			want: redis.NewXAddCommand(
+				store, clock, "stream", redis.XAddID{ID: redis.StreamID{Ms: 5}},
+				[]string{"a", "1", "b", "2"},
+			),
+		},
+		{
+			name: "XADD stream NOMKSTREAM MAXLEN ~ 10 1-2 a 1",
+			request: "*9\r\n$4\r\nXADD\r\n$6\r\nstream\r\n$10\r\nNOMKSTREAM\r\n" +
+				"$6\r\nMAXLEN\r\n$1\r\n~\r\n$2\r\n10\r\n$3\r\n1-2\r\n$1\r\na\r\n" +
+				"$1\r\n1\r\n",
+			want: redis.NewXAddCommand(
+				store, clock, "stream", redis.XAddID{ID: redis.StreamID{Ms: 1, Seq: 2}},
+				[]string{"a", "1"}, redis.XAddNoMkStream(),
+				redis.XAddTrim(
+					redis.StreamTrim{
+						Strategy:    redis.StreamTrimMaxLen,
+						MaxLen:      10,
+						Approximate: true,
+						Limit:       10000,
+					},
+				),
+			),
+		},
+		{
+			name: "XADD stream MINID 5-1 * MAXLEN 1",
+			request: "*7\r\n$4\r\nXADD\r\n$6\r\nstream\r\n$5\r\nMINID\r\n$3\r\n5-1\r\n" +
+				"$1\r\n*\r\n$6\r\nMAXLEN\r\n$1\r\n1\r\n",
+			want: redis.NewXAddCommand(
+				store, clock, "stream", redis.XAddID{Auto: true}, []string{"MAXLEN", "1"},
+				redis.XAddTrim(
+					redis.StreamTrim{
+						Strategy: redis.StreamTrimMinID,
+						MinID:    redis.StreamID{Ms: 5, Seq: 1},
+					},
+				),
+			),
+		},
+		{
+			name: "XTRIM stream MAXLEN ~ 10 LIMIT 50",
+			request: "*7\r\n$5\r\nXTRIM\r\n$6\r\nstream\r\n$6\r\nMAXLEN\r\n$1\r\n~\r\n" +
+				"$2\r\n10\r\n$5\r\nLIMIT\r\n$2\r\n50\r\n",
+			want: redis.NewXTrimCommand(
+				store, clock, "stream",
+				redis.StreamTrim{
+					Strategy:    redis.StreamTrimMaxLen,
+					MaxLen:      10,
+					Approximate: true,
+					Limit:       50,
+				},
+			),
+		},
+		{
+			name:    "XTRIM stream MINID 5",
+			request: "*4\r\n$5\r\nXTRIM\r\n$6\r\nstream\r\n$5\r\nMINID\r\n$1\r\n5\r\n",
+			want: redis.NewXTrimCommand(
+				store, clock, "stream",
+				redis.StreamTrim{Strategy: redis.StreamTrimMinID, MinID: redis.StreamID{Ms: 5}},
+			),
+		},
+		{
+			name:    "XRANGE stream - +",
+			request: "*4\r\n$6\r\nXRANGE\r\n$6\r\nstream\r\n$1\r\n-\r\n$1\r\n+\r\n",
+			want:    redis.NewXRangeCommand(store, clock, "stream", redis.StreamID{}, maxStreamID),
+		},
+		{
+			name: "XRANGE stream 5 6 COUNT 2",
+			request: "*6\r\n$6\r\nXRANGE\r\n$6\r\nstream\r\n$1\r\n5\r\n$1\r\n6\r\n" +
+				"$5\r\nCOUNT\r\n$1\r\n2\r\n",
+			want: redis.NewXRangeCommand(
+				store, clock, "stream", redis.StreamID{Ms: 5},
+				redis.StreamID{Ms: 6, Seq: math.MaxUint64}, redis.XRangeCount(2),
+			),
+		},
+		{
+			name:    "XRANGE stream (5-1 (7",
+			request: "*4\r\n$6\r\nXRANGE\r\n$6\r\nstream\r\n$4\r\n(5-1\r\n$2\r\n(7\r\n",
+			want: redis.NewXRangeCommand(
+				store, clock, "stream", redis.StreamID{Ms: 5, Seq: 2},
+				redis.StreamID{Ms: 7, Seq: math.MaxUint64 - 1},
+			),
+		},
+		{
+			name: "XRANGE stream (5-18446744073709551615 + COUNT -1",
+			request: "*6\r\n$6\r\nXRANGE\r\n$6\r\nstream\r\n" +
+				"$23\r\n(5-18446744073709551615\r\n$1\r\n+\r\n$5\r\nCOUNT\r\n" +
+				"$2\r\n-1\r\n",
+			want: redis.NewXRangeCommand(
+				store, clock, "stream", redis.StreamID{Ms: 6}, maxStreamID, redis.XRangeCount(0),
+			),
+		},
+		{
+			name: "XREVRANGE stream + 5 COUNT 1",
+			request: "*6\r\n$9\r\nXREVRANGE\r\n$6\r\nstream\r\n$1\r\n+\r\n$1\r\n5\r\n" +
+				"$5\r\nCOUNT\r\n$1\r\n1\r\n",
+			want: redis.NewXRangeCommand(
+				store, clock, "stream", redis.StreamID{Ms: 5}, maxStreamID, redis.XRangeReverse(),
+				redis.XRangeCount(1),
+			),
+		},
+		{
+			name:    "XLEN stream",
+			request: "*2\r\n$4\r\nXLEN\r\n$6\r\nstream\r\n",
+			want:    redis.NewXLenCommand(store, clock, "stream"),
+		},
+		{
+			name:    "XDEL stream 1-1 2",
+			request: "*4\r\n$4\r\nXDEL\r\n$6\r\nstream\r\n$3\r\n1-1\r\n$1\r\n2\r\n",
+			want: redis.NewXDelCommand(
+				store, clock, "stream", []redis.StreamID{{Ms: 1, Seq: 1}, {Ms: 2}},
+			),
+		},
+		{
+			name: "XREAD STREAMS a b 1 $",
+			request: "*6\r\n$5\r\nXREAD\r\n$7\r\nSTREAMS\r\n$1\r\na\r\n$1\r\nb\r\n" +
+				"$1\r\n1\r\n$1\r\n$\r\n",
+			want: redis.NewXReadCommand(
+				store, clock, nil, []string{"a", "b"},
+				[]redis.XReadID{{ID: redis.StreamID{Ms: 1}}, {New: true}},
+			),
+		},
+		{
+			name: "XREAD count 2 BLOCK 1500 STREAMS a 1-2",
+			request: "*8\r\n$5\r\nXREAD\r\n$5\r\ncount\r\n$1\r\n2\r\n$5\r\nBLOCK\r\n" +
+				"$4\r\n1500\r\n$7\r\nSTREAMS\r\n$1\r\na\r\n$3\r\n1-2\r\n",
+			want: redis.NewXReadCommand(
+				store, clock, nil, []string{"a"},
+				[]redis.XReadID{{ID: redis.StreamID{Ms: 1, Seq: 2}}}, redis.XReadCount(2),
+				redis.XReadBlock(1500*time.Millisecond),
+			),
+		},
+		{
+			name: "XREAD BLOCK 0 STREAMS a +",
+			request: "*6\r\n$5\r\nXREAD\r\n$5\r\nBLOCK\r\n$1\r\n0\r\n$7\r\nSTREAMS\r\n" +
+				"$1\r\na\r\n$1\r\n+\r\n",
+			want: redis.NewXReadCommand(
+				store, clock, nil, []string{"a"}, []redis.XReadID{{Last: true}},
+				redis.XReadBlock(0),
+			),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requestReader := strings.NewReader(tt.request)
+
+			command, err := redis.NewParser(zeroValueRedisConfig, store, clock).Parse(requestReader)
+
+			if err != nil {
+				t.Errorf("err: expected: nil; got: %v", err)
+			}
+			if !reflect.DeepEqual(command, tt.want) {
+				t.Errorf("command expected to be %#v but was %#v", tt.want, command)
+			}
+		})
+	}
+}
+
+func TestParser_ParseInvalidStreamRequest(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		request string
+		err     redis.CommandError
+	}{
+		{
+			name:    "XADD stream * a",
+			request: "*4\r\n$4\r\nXADD\r\n$6\r\nstream\r\n$1\r\n*\r\n$1\r\na\r\n",
+			err:     "ERR wrong number of arguments for 'xadd' command",
+		},
+		{
+			name: "XADD stream * a 1 b",
+			request: "*6\r\n$4\r\nXADD\r\n$6\r\nstream\r\n$1\r\n*\r\n$1\r\na\r\n$1\r\n1\r\n" +
+				"$1\r\nb\r\n",
+			err: "ERR wrong number of arguments for 'xadd' command",
+		},
+		{
+			name:    "XADD stream 0-0 a 1",
+			request: "*5\r\n$4\r\nXADD\r\n$6\r\nstream\r\n$3\r\n0-0\r\n$1\r\na\r\n$1\r\n1\r\n",
+			err:     "ERR The ID specified in XADD must be greater than 0-0",
+		},
+		{
+			name:    "XADD stream 0 a 1",
+			request: "*5\r\n$4\r\nXADD\r\n$6\r\nstream\r\n$1\r\n0\r\n$1\r\na\r\n$1\r\n1\r\n",
+			err:     "ERR The ID specified in XADD must be greater than 0-0",
+		},
+		{
+			name:    "XADD stream 1-x a 1",
+			request: "*5\r\n$4\r\nXADD\r\n$6\r\nstream\r\n$3\r\n1-x\r\n$1\r\na\r\n$1\r\n1\r\n",
+			err:     "ERR Invalid stream ID specified as stream command argument",
+		},
+		{
+			name:    "XADD stream x-* a 1",
+			request: "*5\r\n$4\r\nXADD\r\n$6\r\nstream\r\n$3\r\nx-*\r\n$1\r\na\r\n$1\r\n1\r\n",
+			err:     "ERR Invalid stream ID specified as stream command argument",
+		},
+		{
+			name: "XADD stream MAXLEN -1 * a 1",
+			request: "*7\r\n$4\r\nXADD\r\n$6\r\nstream\r\n$6\r\nMAXLEN\r\n$2\r\n-1\r\n" +
+				"$1\r\n*\r\n$1\r\na\r\n$1\r\n1\r\n",
+			err: "ERR The MAXLEN argument must be >= 0.",
+		},
+		{
+			name: "XADD stream MAXLEN x * a 1",
+			request: "*7\r\n$4\r\nXADD\r\n$6\r\nstream\r\n$6\r\nMAXLEN\r\n$1\r\nx\r\n" +
+				"$1\r\n*\r\n$1\r\na\r\n$1\r\n1\r\n",
+			err: "ERR value is not an integer or out of range",
+		},
+		{
+			name: "XADD stream MAXLEN 1 MINID 1 * a 1",
+			request: "*9\r\n$4\r\nXADD\r\n$6\r\nstream\r\n$6\r\nMAXLEN\r\n$1\r\n1\r\n" +
+				"$5\r\nMINID\r\n$1\r\n1\r\n$1\r\n*\r\n$1\r\na\r\n$1\r\n1\r\n",
+			err: "ERR syntax error, MAXLEN and MINID options at the same time are not compatible",
+		},
+		{
+			name: "XADD stream MAXLEN 1 LIMIT 5 * a 1",
+			request: "*9\r\n$4\r\nXADD\r\n$6\r\nstream\r\n$6\r\nMAXLEN\r\n$1\r\n1\r\n" +
+				"$5\r\nLIMIT\r\n$1\r\n5\r\n$1\r\n*\r\n$1\r\na\r\n$1\r\n1\r\n",
+			err: "ERR syntax error, LIMIT cannot be used without the special ~ option",
+		},
+		{
+			name: "XADD stream MAXLEN ~ 1 LIMIT -5 * a 1",
+			request: "*10\r\n$4\r\nXADD\r\n$6\r\nstream\r\n$6\r\nMAXLEN\r\n$1\r\n~\r\n" +
+				"$1\r\n1\r\n$5\r\nLIMIT\r\n$2\r\n-5\r\n$1\r\n*\r\n$1\r\na\r\n" +
+				"$1\r\n1\r\n",
+			err: "ERR The LIMIT argument must be >= 0.",
+		},
+		{
+			name: "XADD stream LIMIT 5 * a 1",
+			request: "*7\r\n$4\r\nXADD\r\n$6\r\nstream\r\n$5\r\nLIMIT\r\n$1\r\n5\r\n" +
+				"$1\r\n*\r\n$1\r\na\r\n$1\r\n1\r\n",
+			err: "ERR syntax error, LIMIT cannot be used without specifying a trimming strategy",
+		},
+		{
+			name:    "XTRIM stream LIMIT 5",
+			request: "*4\r\n$5\r\nXTRIM\r\n$6\r\nstream\r\n$5\r\nLIMIT\r\n$1\r\n5\r\n",
+			err:     "ERR syntax error, LIMIT cannot be used without specifying a trimming strategy",
+		},
+		{
+			name:    "XTRIM stream NOMKSTREAM MAXLEN",
+			request: "*4\r\n$5\r\nXTRIM\r\n$6\r\nstream\r\n$10\r\nNOMKSTREAM\r\n$6\r\nMAXLEN\r\n",
+			err:     "ERR syntax error",
+		},
+		{
+			name:    "XTRIM stream MAXLEN",
+			request: "*3\r\n$5\r\nXTRIM\r\n$6\r\nstream\r\n$6\r\nMAXLEN\r\n",
+			err:     "ERR wrong number of arguments for 'xtrim' command",
+		},
+		{
+			name:    "XRANGE stream -",
+			request: "*3\r\n$6\r\nXRANGE\r\n$6\r\nstream\r\n$1\r\n-\r\n",
+			err:     "ERR wrong number of arguments for 'xrange' command",
+		},
+		{
+			name:    "XRANGE stream x +",
+			request: "*4\r\n$6\r\nXRANGE\r\n$6\r\nstream\r\n$1\r\nx\r\n$1\r\n+\r\n",
+			err:     "ERR Invalid stream ID specified as stream command argument",
+		},
+		{
+			name:    "XRANGE stream (- +",
+			request: "*4\r\n$6\r\nXRANGE\r\n$6\r\nstream\r\n$2\r\n(-\r\n$1\r\n+\r\n",
+			err:     "ERR Invalid stream ID specified as stream command argument",
+		},
+		{
+			name: "XRANGE stream (18446744073709551615-18446744073709551615 +",
+			request: "*4\r\n$6\r\nXRANGE\r\n$6\r\nstream\r\n" +
+				"$42\r\n(18446744073709551615-18446744073709551615\r\n$1\r\n+\r\n",
+			err: "ERR invalid start ID for the interval",
+		},
+		{
+			name:    "XRANGE stream - (0-0",
+			request: "*4\r\n$6\r\nXRANGE\r\n$6\r\nstream\r\n$1\r\n-\r\n$4\r\n(0-0\r\n",
+			err:     "ERR invalid end ID for the interval",
+		},
+		{
+			name:    "XRANGE stream - + COUNT",
+			request: "*5\r\n$6\r\nXRANGE\r\n$6\r\nstream\r\n$1\r\n-\r\n$1\r\n+\r\n$5\r\nCOUNT\r\n",
+			err:     "ERR syntax error",
+		},
+		{
+			name: "XRANGE stream - + LIMIT 1",
+			request: "*6\r\n$6\r\nXRANGE\r\n$6\r\nstream\r\n$1\r\n-\r\n$1\r\n+\r\n" +
+				"$5\r\nLIMIT\r\n$1\r\n1\r\n",
+			err: "ERR syntax error",
+		},
+		{
+			name:    "XDEL stream 1 x",
+			request: "*4\r\n$4\r\nXDEL\r\n$6\r\nstream\r\n$1\r\n1\r\n$1\r\nx\r\n",
+			err:     "ERR Invalid stream ID specified as stream command argument",
+		},
+		{
+			name: "XADD stream MINID = 5 LIMIT 0 * a 1",
+			request: "*10\r\n$4\r\nXADD\r\n$6\r\nstream\r\n$5\r\nMINID\r\n$1\r\n=\r\n" +
+				"$1\r\n5\r\n$5\r\nLIMIT\r\n$1\r\n0\r\n$1\r\n*\r\n$1\r\na\r\n$1\r\n1\r\n",
+			err: "ERR syntax error, LIMIT cannot be used without the special ~ option",
+		},
+		{
+			name:    "XREAD STREAMS a",
+			request: "*3\r\n$5\r\nXREAD\r\n$7\r\nSTREAMS\r\n$1\r\na\r\n",
+			err:     "ERR wrong number of arguments for 'xread' command",
+		},
+		{
+			name:    "XREAD STREAMS a b 1",
+			request: "*5\r\n$5\r\nXREAD\r\n$7\r\nSTREAMS\r\n$1\r\na\r\n$1\r\nb\r\n$1\r\n1\r\n",
+			err:     "ERR Unbalanced 'xread' list of streams: for each stream key an ID or '$' must be specified.",
+		},
+		{
+			name:    "XREAD COUNT 1 a 1",
+			request: "*5\r\n$5\r\nXREAD\r\n$5\r\nCOUNT\r\n$1\r\n1\r\n$1\r\na\r\n$1\r\n1\r\n",
+			err:     "ERR syntax error",
+		},
+		{
+			name: "XREAD BLOCK -1 STREAMS a 1",
+			request: "*6\r\n$5\r\nXREAD\r\n$5\r\nBLOCK\r\n$2\r\n-1\r\n$7\r\nSTREAMS\r\n" +
+				"$1\r\na\r\n$1\r\n1\r\n",
+			err: "ERR timeout is negative",
+		},
+		{
+			name: "XREAD BLOCK soon STREAMS a 1",
+			request: "*6\r\n$5\r\nXREAD\r\n$5\r\nBLOCK\r\n$4\r\nsoon\r\n$7\r\nSTREAMS\r\n" +
+				"$1\r\na\r\n$1\r\n1\r\n",
+			err: "ERR timeout is not an integer or out of range",
+		},
+		{
+			name: "XREAD COUNT x STREAMS a 1",
+			request: "*6\r\n$5\r\nXREAD\r\n$5\r\nCOUNT\r\n$1\r\nx\r\n$7\r\nSTREAMS\r\n" +
+				"$1\r\na\r\n$1\r\n1\r\n",
+			err: "ERR value is not an integer or out of range",
+		},
+		{
+			name:    "XREAD STREAMS a x",
+			request: "*4\r\n$5\r\nXREAD\r\n$7\r\nSTREAMS\r\n$1\r\na\r\n$1\r\nx\r\n",
+			err:     "ERR Invalid stream ID specified as stream command argument",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := redis.NewStore()
+			clock := FakeClock{}
+			requestReader := strings.NewReader(tt.request)
+
+			_, err := redis.NewParser(zeroValueRedisConfig, store, clock).Parse(requestReader)
+
+			if err != tt.err {
+				t.Errorf("err: expected: %v; got: %v", tt.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,211 @@
+package redis
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+func (p Parser) newAppendCommand(array []string) (Command, error) {
+	if len(array) != 3 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	return NewAppendCommand(p.store, p.clock, array[1], array[2]), nil
+}
+
+func (p Parser) newStrLenCommand(array []string) (Command, error) {
+	if len(array) != 2 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	return NewStrLenCommand(p.store, p.clock, array[1]), nil
+}
+
+func (p Parser) newGetRangeCommand(array []string) (Command, error) {
+	if len(array) != 4 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	start, err := parseInteger(array[2])
+	if err != nil {
+		return nil, err
+	}
+	end, err := parseInteger(array[3])
+	if err != nil {
+		return nil, err
+	}
+	return NewGetRangeCommand(p.store, p.clock, array[1], start, end), nil
+}
+
+func (p Parser) newSetRangeCommand(array []string) (Command, error) {
+	if len(array) != 4 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	offset, err := parseInteger(array[2])
+	if err != nil {
+		return nil, err
+	}
+	if offset < 0 {
+		return nil, CommandError("ERR offset is out of range")
+	}
+	return NewSetRangeCommand(p.store, p.clock, array[1], offset, array[3]), nil
+}
+
+func (p Parser) newGetDelCommand(array []string) (Command, error) {
+	if len(array) != 2 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	return NewGetDelCommand(p.store, p.clock, array[1]), nil
+}
+
+func (p Parser) newGetExCommand(array []string) (Command, error) {
+	if len(array) < 2 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+
+	var options []func(*GetExCommand)
+	for i := 2; i < len(array); i++ {
+		if len(options) > 0 {
+			// EX, PX, EXAT, PXAT and PERSIST are mutually exclusive.
+			return nil, errSyntax
+		}
+		switch {
+		case strings.EqualFold(array[i], "PERSIST"):
+			options = append(options, GetExPersist())
+		case isExpiryOption(array[i]) && i+1 < len(array):
+			expiryTime, err := parseExpiryTime(p.clock.NowMonotonic(), array[i], array[i+1], "getex")
+			if err != nil {
+				return nil, err
+			}
+			options = append(options, GetExExpiryTime(expiryTime))
+			i++
+		default:
+			return nil, errSyntax
+		}
+	}
+	return NewGetExCommand(p.store, p.clock, array[1], options...), nil
+}
+
+func isExpiryOption(option string) bool {
+	return strings.EqualFold(option, "EX") ||
+		strings.EqualFold(option, "PX") ||
+		strings.EqualFold(option, "EXAT") ||
+		strings.EqualFold(option, "PXAT")
+}
+
+// parseExpiryTime returns the expiry time that the EX, PX, EXAT or PXAT option, with the given
+// value, refers to. EXAT and PXAT are Unix times, so they're converted into times relative to
+// now to keep now's monotonic time component.
+func parseExpiryTime(now time.Time, option, value, commandName string) (time.Time, error) {
+	n, err := parseInteger(value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	errInvalidExpireTime := CommandError(
+		fmt.Sprintf("ERR invalid expire time in '%s' command", commandName),
+	)
+	if n <= 0 {
+		return time.Time{}, errInvalidExpireTime
+	}
+
+	switch {
+	case strings.EqualFold(option, "EX"):
+		if n > math.MaxInt64/int(time.Second) {
+			return time.Time{}, errInvalidExpireTime
+		}
+		return now.Add(time.Duration(n) * time.Second), nil
+	case strings.EqualFold(option, "PX"):
+		if n > math.MaxInt64/int(time.Millisecond) {
+			return time.Time{}, errInvalidExpireTime
+		}
+		return now.Add(time.Duration(n) * time.Millisecond), nil
+	case strings.EqualFold(option, "EXAT"):
+		if n > math.MaxInt64/1000 {
+			return time.Time{}, errInvalidExpireTime
+		}
+		return now.Add(time.UnixMilli(int64(n) * 1000).Sub(now)), nil
+	case strings.EqualFold(option, "PXAT"):
+		return now.Add(time.UnixMilli(int64(n)).Sub(now)), nil
+	}
+	panic(fmt.Sprintf("unknown expiry option: %s", option))
+}
+
+func (p Parser) newGetSetCommand(array []string) (Command, error) {
+	if len(array) != 3 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	return NewGetSetCommand(p.store, p.clock, array[1], array[2]), nil
+}
+
+func (p Parser) newLCSCommand(array []string) (Command, error) {
+	if len(array) < 3 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+
+	var options []func(*LCSCommand)
+	var length, indexes bool
+	for i := 3; i < len(array); i++ {
+		switch {
+		case strings.EqualFold(array[i], "LEN"):
+			length = true
+			options = append(options, LCSLen())
+		case strings.EqualFold(array[i], "IDX"):
+			indexes = true
+			options = append(options, LCSIdx())
+		case strings.EqualFold(array[i], "WITHMATCHLEN"):
+			options = append(options, LCSWithMatchLen())
+		case strings.EqualFold(array[i], "MINMATCHLEN") && i+1 < len(array):
+			minMatchLen, err := parseInteger(array[i+1])
+			if err != nil {
+				return nil, err
+			}
+			if minMatchLen < 0 {
+				minMatchLen = 0
+			}
+			options = append(options, LCSMinMatchLen(minMatchLen))
+			i++
+		default:
+			return nil, errSyntax
+		}
+	}
+	if length && indexes {
+		return nil, CommandError(
+			"ERR If you want both the length and indexes, please just use IDX.",
+		)
+	}
+	return NewLCSCommand(p.store, p.clock, array[1], array[2], options...), nil
+}
+
+func (p Parser) newMSetCommand(array []string) (Command, error) {
+	keyValues, err := parseKeyValues(array)
+	if err != nil {
+		return nil, err
+	}
+	return NewMSetCommand(p.store, keyValues), nil
+}
+
+func (p Parser) newMSetNXCommand(array []string) (Command, error) {
+	keyValues, err := parseKeyValues(array)
+	if err != nil {
+		return nil, err
+	}
+	return NewMSetNXCommand(p.store, p.clock, keyValues), nil
+}
+
+// parseKeyValues parses the "key value [key value ...]" arguments of MSET and MSETNX.
+func parseKeyValues(array []string) ([]KeyValue, error) {
+	if len(array) < 3 || len(array)%2 != 1 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	keyValues := make([]KeyValue, 0, len(array)/2)
+	for i := 1; i < len(array); i += 2 {
+		keyValues = append(keyValues, KeyValue{Key: array[i], Value: array[i+1]})
+	}
+	return keyValues, nil
+}
+
+func (p Parser) newMGetCommand(array []string) (Command, error) {
+	if len(array) < 2 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	return NewMGetCommand(p.store, p.clock, array[1:]), nil
+}
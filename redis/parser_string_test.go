@@ -0,0 +1,201 @@
+package redis_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/redis"
+)
+
+func TestParser_ParseStringRequest(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{CurrentTime: time.UnixMilli(1000)}
+
+	tests := []struct {
+		name    string
+		request string
+		want    redis.Command
+	}{
+		{
+			name:    "APPEND link zelda",
+			request: "*3\r\n$6\r\nAPPEND\r\n$4\r\nlink\r\n$5\r\nzelda\r\n",
+			want:    redis.NewAppendCommand(store, clock, "link", "zelda"),
+		},
+		{
+			name:    "strlen link",
+			request: "*2\r\n$6\r\nstrlen\r\n$4\r\nlink\r\n",
+			want:    redis.NewStrLenCommand(store, clock, "link"),
+		},
+		{
+			name:    "GETRANGE link 0 -1",
+			request: "*4\r\n$8\r\nGETRANGE\r\n$4\r\nlink\r\n$1\r\n0\r\n$2\r\n-1\r\n",
+			want:    redis.NewGetRangeCommand(store, clock, "link", 0, -1),
+		},
+		{
+			name:    "SETRANGE link 6 zelda",
+			request: "*4\r\n$8\r\nSETRANGE\r\n$4\r\nlink\r\n$1\r\n6\r\n$5\r\nzelda\r\n",
+			want:    redis.NewSetRangeCommand(store, clock, "link", 6, "zelda"),
+		},
+		{
+			name:    "GETDEL link",
+			request: "*2\r\n$6\r\nGETDEL\r\n$4\r\nlink\r\n",
+			want:    redis.NewGetDelCommand(store, clock, "link"),
+		},
+		{
+			name:    "GETEX link",
+			request: "*2\r\n$5\r\nGETEX\r\n$4\r\nlink\r\n",
+			want:    redis.NewGetExCommand(store, clock, "link"),
+		},
+		{
+			name:    "GETEX link EX 10",
+			request: "*4\r\n$5\r\nGETEX\r\n$4\r\nlink\r\n$2\r\nEX\r\n$2\r\n10\r\n",
+			want: redis.NewGetExCommand(
+				store, clock, "link", redis.GetExExpiryTime(time.UnixMilli(11000)),
+			),
+		},
+		{
+			name:    "GETEX link px 10",
+			request: "*4\r\n$5\r\nGETEX\r\n$4\r\nlink\r\n$2\r\npx\r\n$2\r\n10\r\n",
+			want: redis.NewGetExCommand(
+				store, clock, "link", redis.GetExExpiryTime(time.UnixMilli(1010)),
+			),
+		},
+		{
+			name:    "GETEX link EXAT 10",
+			request: "*4\r\n$5\r\nGETEX\r\n$4\r\nlink\r\n$4\r\nEXAT\r\n$2\r\n10\r\n",
+			want: redis.NewGetExCommand(
+				store, clock, "link", redis.GetExExpiryTime(time.UnixMilli(10000)),
+			),
+		},
+		{
+			name:    "GETEX link PXAT 10",
+			request: "*4\r\n$5\r\nGETEX\r\n$4\r\nlink\r\n$4\r\nPXAT\r\n$2\r\n10\r\n",
+			want: redis.NewGetExCommand(
+				store, clock, "link", redis.GetExExpiryTime(time.UnixMilli(10)),
+			),
+		},
+		{
+			name:    "GETEX link PERSIST",
+			request: "*3\r\n$5\r\nGETEX\r\n$4\r\nlink\r\n$7\r\nPERSIST\r\n",
+			want:    redis.NewGetExCommand(store, clock, "link", redis.GetExPersist()),
+		},
+		{
+			name:    "GETSET link zelda",
+			request: "*3\r\n$6\r\nGETSET\r\n$4\r\nlink\r\n$5\r\nzelda\r\n",
+			want:    redis.NewGetSetCommand(store, clock, "link", "zelda"),
+		},
+		{
+			name: "LCS a b IDX MINMATCHLEN 4 WITHMATCHLEN",
+			request: "*7\r\n$3\r\nLCS\r\n$1\r\na\r\n$1\r\nb\r\n$3\r\nIDX\r\n" +
+				"$11\r\nMINMATCHLEN\r\n$1\r\n4\r\n$12\r\nWITHMATCHLEN\r\n",
+			want: redis.NewLCSCommand(
+				store,
+				clock,
+				"a",
+				"b",
+				redis.LCSIdx(),
+				redis.LCSMinMatchLen(4),
+				redis.LCSWithMatchLen(),
+			),
+		},
+		{
+			name:    "MSET link zelda grape banana",
+			request: "*5\r\n$4\r\nMSET\r\n$4\r\nlink\r\n$5\r\nzelda\r\n$5\r\ngrape\r\n$6\r\nbanana\r\n",
+			want: redis.NewMSetCommand(store, []redis.KeyValue{
+				{Key: "link", Value: "zelda"},
+				{Key: "grape", Value: "banana"},
+			}),
+		},
+		{
+			name:    "MSETNX link zelda",
+			request: "*3\r\n$6\r\nMSETNX\r\n$4\r\nlink\r\n$5\r\nzelda\r\n",
+			want: redis.NewMSetNXCommand(store, clock, []redis.KeyValue{
+				{Key: "link", Value: "zelda"},
+			}),
+		},
+		{
+			name:    "MGET link grape",
+			request: "*3\r\n$4\r\nMGET\r\n$4\r\nlink\r\n$5\r\ngrape\r\n",
+			want:    redis.NewMGetCommand(store, clock, []string{"link", "grape"}),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requestReader := strings.NewReader(tt.request)
+
+			command, err := redis.NewParser(zeroValueRedisConfig, store, clock).Parse(requestReader)
+
+			if err != nil {
+				t.Errorf("err: expected: nil; got: %v", err)
+			}
+			if !reflect.DeepEqual(command, tt.want) {
+				t.Errorf("command expected to be %#v but was %#v", tt.want, command)
+			}
+		})
+	}
+}
+
+func TestParser_ParseInvalidStringRequest(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		request string
+		err     redis.CommandError
+	}{
+		{
+			name:    "APPEND link",
+			request: "*2\r\n$6\r\nAPPEND\r\n$4\r\nlink\r\n",
+			err:     "ERR wrong number of arguments for 'append' command",
+		},
+		{
+			name:    "MSET link zelda grape",
+			request: "*4\r\n$4\r\nMSET\r\n$4\r\nlink\r\n$5\r\nzelda\r\n$5\r\ngrape\r\n",
+			err:     "ERR wrong number of arguments for 'mset' command",
+		},
+		{
+			name:    "GETRANGE link a 1",
+			request: "*4\r\n$8\r\nGETRANGE\r\n$4\r\nlink\r\n$1\r\na\r\n$1\r\n1\r\n",
+			err:     "ERR value is not an integer or out of range",
+		},
+		{
+			name:    "SETRANGE link -1 zelda",
+			request: "*4\r\n$8\r\nSETRANGE\r\n$4\r\nlink\r\n$2\r\n-1\r\n$5\r\nzelda\r\n",
+			err:     "ERR offset is out of range",
+		},
+		{
+			name:    "GETEX link EX 0",
+			request: "*4\r\n$5\r\nGETEX\r\n$4\r\nlink\r\n$2\r\nEX\r\n$1\r\n0\r\n",
+			err:     "ERR invalid expire time in 'getex' command",
+		},
+		{
+			name:    "GETEX link EX 10 PERSIST",
+			request: "*5\r\n$5\r\nGETEX\r\n$4\r\nlink\r\n$2\r\nEX\r\n$2\r\n10\r\n$7\r\nPERSIST\r\n",
+			err:     "ERR syntax error",
+		},
+		{
+			name:    "LCS a b LEN IDX",
+			request: "*5\r\n$3\r\nLCS\r\n$1\r\na\r\n$1\r\nb\r\n$3\r\nLEN\r\n$3\r\nIDX\r\n",
+			err:     "ERR If you want both the length and indexes, please just use IDX.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := redis.NewStore()
+			clock := FakeClock{}
+			requestReader := strings.NewReader(tt.request)
+
+			_, err := redis.NewParser(zeroValueRedisConfig, store, clock).Parse(requestReader)
+
+			if err != tt.err {
+				t.Errorf("err: expected: %v; got: %v", tt.err, err)
+			}
+		})
+	}
+}
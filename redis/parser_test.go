@@ -288,3 +288,96 @@ func TestParser_ParseSetRequest(t *testing.T) {
 		})
 	}
 }
+
+func TestParser_ParseInlineRequest(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		request string
+		want    redis.Command
+	}{
+		{
+			name:    "PING",
+			request: "PING\r\n",
+			want:    redis.PingCommand{},
+		},
+		{
+			name:    "echo hey without CR",
+			request: "echo hey\n",
+			want:    redis.EchoCommand("hey"),
+		},
+		{
+			name:    "ECHO between spaces",
+			request: "  ECHO   hey  \r\n",
+			want:    redis.EchoCommand("hey"),
+		},
+		{
+			name:    "empty lines are skipped",
+			request: "\r\n  \r\nECHO hey\r\n",
+			want:    redis.EchoCommand("hey"),
+		},
+		{
+			name:    "empty arrays are skipped",
+			request: "*0\r\n*2\r\n$4\r\nECHO\r\n$3\r\nhey\r\n",
+			want:    redis.EchoCommand("hey"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := redis.NewStore()
+			clock := FakeClock{}
+			requestReader := strings.NewReader(tt.request)
+
+			command, err := redis.NewParser(zeroValueRedisConfig, store, clock).Parse(requestReader)
+
+			if err != nil {
+				t.Errorf("err: expected: nil; got: %v", err)
+			}
+			if !reflect.DeepEqual(command, tt.want) {
+				t.Errorf("command expected to be %#v but was %#v", tt.want, command)
+			}
+		})
+	}
+}
+
+func TestParser_ParseUnknownRequest(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		request string
+		err     redis.CommandError
+	}{
+		{
+			name:    "FOO",
+			request: "*1\r\n$3\r\nFOO\r\n",
+			err:     "ERR unknown command 'FOO', with args beginning with: ",
+		},
+		{
+			name:    "foo bar baz",
+			request: "*3\r\n$3\r\nfoo\r\n$3\r\nbar\r\n$3\r\nbaz\r\n",
+			err:     "ERR unknown command 'foo', with args beginning with: 'bar' 'baz' ",
+		},
+		{
+			name:    "inline foo bar",
+			request: "foo bar\r\n",
+			err:     "ERR unknown command 'foo', with args beginning with: 'bar' ",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := redis.NewStore()
+			clock := FakeClock{}
+			requestReader := strings.NewReader(tt.request)
+
+			_, err := redis.NewParser(zeroValueRedisConfig, store, clock).Parse(requestReader)
+
+			if err != tt.err {
+				t.Errorf("err: expected: %v; got: %v", tt.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,430 @@
+package redis
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	errTSTimestamp   CommandError = "ERR TSDB: invalid timestamp"
+	errTSValue       CommandError = "ERR TSDB: invalid value"
+	errTSRetention   CommandError = "ERR TSDB: Couldn't parse RETENTION"
+	errTSPolicy      CommandError = "ERR TSDB: Unknown DUPLICATE_POLICY"
+	errTSAggregation CommandError = "ERR TSDB: Unknown aggregation type"
+	errTSBucket      CommandError = "ERR TSDB: bucketDuration must be greater than zero"
+	errTSLabels      CommandError = "ERR TSDB: failed parsing labels"
+	errTSCount       CommandError = "ERR TSDB: Couldn't parse COUNT"
+	errTSAlign       CommandError = "ERR TSDB: unknown ALIGN parameter"
+	errTSFilter      CommandError = "ERR TSDB: failed parsing filter"
+	errTSNoMatcher   CommandError = "ERR TSDB: please provide at least one matcher"
+)
+
+var tsDuplicatePolicies = map[string]TSDuplicatePolicy{
+	"BLOCK": TSDuplicateBlock,
+	"FIRST": TSDuplicateFirst,
+	"LAST":  TSDuplicateLast,
+	"MIN":   TSDuplicateMin,
+	"MAX":   TSDuplicateMax,
+	"SUM":   TSDuplicateSum,
+}
+
+var tsAggregators = map[string]TSAggregator{
+	"AVG":   TSAggregatorAvg,
+	"SUM":   TSAggregatorSum,
+	"MIN":   TSAggregatorMin,
+	"MAX":   TSAggregatorMax,
+	"RANGE": TSAggregatorRange,
+	"COUNT": TSAggregatorCount,
+	"FIRST": TSAggregatorFirst,
+	"LAST":  TSAggregatorLast,
+}
+
+// parseTSTimestamp parses the timestamp of a sample, which is either non-negative or * for the
+// current time.
+func parseTSTimestamp(s string) (int64, error) {
+	if s == "*" {
+		return TSTimestampNow, nil
+	}
+	timestamp, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || timestamp < 0 {
+		return 0, errTSTimestamp
+	}
+	return timestamp, nil
+}
+
+// parseTSRangeTimestamp parses a bound of a range of samples, where - is the earliest timestamp
+// and + the latest.
+func parseTSRangeTimestamp(s string) (int64, error) {
+	switch s {
+	case "-":
+		return TSRangeStart, nil
+	case "+":
+		return TSRangeEnd, nil
+	}
+	timestamp, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || timestamp < 0 {
+		return 0, errTSTimestamp
+	}
+	return timestamp, nil
+}
+
+func parseTSValue(s string) (float64, error) {
+	value, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(value) {
+		return 0, errTSValue
+	}
+	return value, nil
+}
+
+func parseTSDuplicatePolicy(s string) (TSDuplicatePolicy, error) {
+	policy, ok := tsDuplicatePolicies[strings.ToUpper(s)]
+	if !ok {
+		return 0, errTSPolicy
+	}
+	return policy, nil
+}
+
+// tsOptions are the options of TS.CREATE, TS.ADD and TS.INCRBY, which each accept some of them.
+type tsOptions struct {
+	create      TSCreateOptions
+	onDuplicate TSDuplicatePolicy
+	timestamp   int64
+}
+
+// parseTSOptions parses options, accepting ON_DUPLICATE if onDuplicate and TIMESTAMP if
+// timestamp. LABELS takes the rest of the options.
+func parseTSOptions(options []string, onDuplicate bool, timestamp bool) (tsOptions, error) {
+	result := tsOptions{timestamp: TSTimestampNow}
+	for i := 0; i < len(options); i += 2 {
+		option := strings.ToUpper(options[i])
+		if option == "LABELS" {
+			labels := options[i+1:]
+			if len(labels)%2 != 0 {
+				return tsOptions{}, errTSLabels
+			}
+			for j := 0; j < len(labels); j += 2 {
+				result.create.Labels = append(result.create.Labels, TSLabel{
+					Name:  labels[j],
+					Value: labels[j+1],
+				})
+			}
+			break
+		}
+		if i+1 == len(options) {
+			return tsOptions{}, errSyntax
+		}
+		var err error
+		switch {
+		case option == "RETENTION":
+			retention, parseErr := strconv.ParseInt(options[i+1], 10, 64)
+			if parseErr != nil || retention < 0 {
+				return tsOptions{}, errTSRetention
+			}
+			result.create.Retention = retention
+		case option == "DUPLICATE_POLICY":
+			result.create.DuplicatePolicy, err = parseTSDuplicatePolicy(options[i+1])
+		case option == "ON_DUPLICATE" && onDuplicate:
+			result.onDuplicate, err = parseTSDuplicatePolicy(options[i+1])
+		case option == "TIMESTAMP" && timestamp:
+			result.timestamp, err = parseTSTimestamp(options[i+1])
+		default:
+			return tsOptions{}, errSyntax
+		}
+		if err != nil {
+			return tsOptions{}, err
+		}
+	}
+	return result, nil
+}
+
+// parseTSAggregation parses the aggregator and bucket duration of AGGREGATION.
+func parseTSAggregation(aggregator string, bucketDuration string) (TSAggregation, error) {
+	function, ok := tsAggregators[strings.ToUpper(aggregator)]
+	if !ok {
+		return TSAggregation{}, errTSAggregation
+	}
+	duration, err := strconv.ParseInt(bucketDuration, 10, 64)
+	if err != nil || duration < 1 {
+		return TSAggregation{}, errTSBucket
+	}
+	return TSAggregation{Aggregator: function, BucketDuration: duration}, nil
+}
+
+// parseTSRangeQuery parses the range of TS.RANGE or TS.MRANGE, and their options up to FILTER,
+// which it returns the index of, or len(array) if there isn't one.
+func parseTSRangeQuery(
+	array []string,
+	i int,
+	withLabels func(),
+) (query TSRangeQuery, filter int, err error) {
+	if query.From, err = parseTSRangeTimestamp(array[i]); err != nil {
+		return TSRangeQuery{}, 0, err
+	}
+	if query.To, err = parseTSRangeTimestamp(array[i+1]); err != nil {
+		return TSRangeQuery{}, 0, err
+	}
+	align := ""
+	for i += 2; i < len(array); i++ {
+		option := strings.ToUpper(array[i])
+		switch {
+		case option == "FILTER":
+			return resolveTSAlign(query, align, i)
+		case option == "WITHLABELS" && withLabels != nil:
+			withLabels()
+			continue
+		case option == "AGGREGATION" && i+2 < len(array):
+			aggregation, err := parseTSAggregation(array[i+1], array[i+2])
+			if err != nil {
+				return TSRangeQuery{}, 0, err
+			}
+			query.Aggregation = &aggregation
+			i += 2
+			continue
+		case i+1 == len(array):
+			return TSRangeQuery{}, 0, errSyntax
+		}
+		i++
+		switch option {
+		case "COUNT":
+			count, err := strconv.Atoi(array[i])
+			if err != nil || count < 1 {
+				return TSRangeQuery{}, 0, errTSCount
+			}
+			query.Count = count
+		case "ALIGN":
+			align = array[i]
+		default:
+			return TSRangeQuery{}, 0, errSyntax
+		}
+	}
+	return resolveTSAlign(query, align, len(array))
+}
+
+// resolveTSAlign sets the alignment of the aggregation of query to align, which is a timestamp,
+// or start or - for the start of the range, or end or + for the end, which for - and + are
+// resolved for each series when it's queried.
+func resolveTSAlign(
+	query TSRangeQuery,
+	align string,
+	filter int,
+) (TSRangeQuery, int, error) {
+	if align == "" {
+		return query, filter, nil
+	}
+	if query.Aggregation == nil {
+		return TSRangeQuery{}, 0, errTSAlign
+	}
+	switch strings.ToLower(align) {
+	case "start", "-":
+		query.Alignment = TSAlignStart
+	case "end", "+":
+		query.Alignment = TSAlignEnd
+	default:
+		timestamp, err := strconv.ParseInt(align, 10, 64)
+		if err != nil {
+			return TSRangeQuery{}, 0, errTSAlign
+		}
+		query.Aggregation.Align = timestamp
+	}
+	return query, filter, nil
+}
+
+// parseTSFilter parses a FILTER expression of TS.MRANGE: label=value, label!=value,
+// label=(value,...) or label!=(value,...), where an empty value matches a missing label.
+func parseTSFilter(s string) (TSFilter, error) {
+	label, values, ok := strings.Cut(s, "=")
+	if !ok || label == "" || label == "!" {
+		return TSFilter{}, errTSFilter
+	}
+	var result TSFilter
+	if strings.HasSuffix(label, "!") {
+		label = label[:len(label)-1]
+		result.Negate = true
+	}
+	result.Label = label
+	if strings.HasPrefix(values, "(") && strings.HasSuffix(values, ")") {
+		result.Values = strings.Split(values[1:len(values)-1], ",")
+	} else {
+		result.Values = []string{values}
+	}
+	return result, nil
+}
+
+func (p Parser) newTSCreateCommand(array []string) (Command, error) {
+	if len(array) < 2 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	options, err := parseTSOptions(array[2:], false, false)
+	if err != nil {
+		return nil, err
+	}
+	return NewTSCreateCommand(p.store, p.clock, array[1], options.create), nil
+}
+
+func (p Parser) newTSAddCommand(array []string) (Command, error) {
+	if len(array) < 4 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	timestamp, err := parseTSTimestamp(array[2])
+	if err != nil {
+		return nil, err
+	}
+	value, err := parseTSValue(array[3])
+	if err != nil {
+		return nil, err
+	}
+	options, err := parseTSOptions(array[4:], true, false)
+	if err != nil {
+		return nil, err
+	}
+	return NewTSAddCommand(
+		p.store, p.clock, array[1], timestamp, value, options.create, options.onDuplicate,
+	), nil
+}
+
+func (p Parser) newTSMAddCommand(array []string) (Command, error) {
+	if len(array) < 4 || len(array)%3 != 1 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	samples := make([]TSMAddSample, 0, len(array)/3)
+	for i := 1; i < len(array); i += 3 {
+		timestamp, err := parseTSTimestamp(array[i+1])
+		if err != nil {
+			return nil, err
+		}
+		value, err := parseTSValue(array[i+2])
+		if err != nil {
+			return nil, err
+		}
+		samples = append(samples, TSMAddSample{Key: array[i], Timestamp: timestamp, Value: value})
+	}
+	return NewTSMAddCommand(p.store, p.clock, samples), nil
+}
+
+func (p Parser) newTSIncrByCommand(array []string) (Command, error) {
+	return p.newTSIncrByOrDecrByCommand(array, NewTSIncrByCommand)
+}
+
+func (p Parser) newTSDecrByCommand(array []string) (Command, error) {
+	return p.newTSIncrByOrDecrByCommand(array, NewTSDecrByCommand)
+}
+
+func (p Parser) newTSIncrByOrDecrByCommand(
+	array []string,
+	newCommand func(*Store, Clock, string, float64, int64, TSCreateOptions) *TSIncrByCommand,
+) (Command, error) {
+	if len(array) < 3 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	value, err := parseTSValue(array[2])
+	if err != nil {
+		return nil, err
+	}
+	options, err := parseTSOptions(array[3:], false, true)
+	if err != nil {
+		return nil, err
+	}
+	return newCommand(p.store, p.clock, array[1], value, options.timestamp, options.create), nil
+}
+
+func (p Parser) newTSGetCommand(array []string) (Command, error) {
+	if len(array) != 2 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	return NewTSGetCommand(p.store, p.clock, array[1]), nil
+}
+
+func (p Parser) newTSRangeCommand(array []string) (Command, error) {
+	return p.newTSRangeOrRevRangeCommand(array, NewTSRangeCommand)
+}
+
+func (p Parser) newTSRevRangeCommand(array []string) (Command, error) {
+	return p.newTSRangeOrRevRangeCommand(array, NewTSRevRangeCommand)
+}
+
+func (p Parser) newTSRangeOrRevRangeCommand(
+	array []string,
+	newCommand func(*Store, Clock, string, TSRangeQuery) *TSRangeCommand,
+) (Command, error) {
+	if len(array) < 4 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	query, filter, err := parseTSRangeQuery(array, 2, nil)
+	if err != nil {
+		return nil, err
+	}
+	if filter != len(array) {
+		return nil, errSyntax
+	}
+	return newCommand(p.store, p.clock, array[1], query), nil
+}
+
+func (p Parser) newTSMRangeCommand(array []string) (Command, error) {
+	return p.newTSMRangeOrMRevRangeCommand(array, NewTSMRangeCommand)
+}
+
+func (p Parser) newTSMRevRangeCommand(array []string) (Command, error) {
+	return p.newTSMRangeOrMRevRangeCommand(array, NewTSMRevRangeCommand)
+}
+
+func (p Parser) newTSMRangeOrMRevRangeCommand(
+	array []string,
+	newCommand func(
+		*Store, Clock, TSRangeQuery, []TSFilter, ...func(*TSMRangeCommand),
+	) *TSMRangeCommand,
+) (Command, error) {
+	if len(array) < 5 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	var options []func(*TSMRangeCommand)
+	query, filter, err := parseTSRangeQuery(array, 1, func() {
+		options = append(options, TSMRangeWithLabels())
+	})
+	if err != nil {
+		return nil, err
+	}
+	if filter >= len(array)-1 {
+		return nil, errSyntax
+	}
+	filters := make([]TSFilter, 0, len(array)-filter-1)
+	positive := false
+	for _, expression := range array[filter+1:] {
+		f, err := parseTSFilter(expression)
+		if err != nil {
+			return nil, err
+		}
+		positive = positive || (!f.Negate && !(len(f.Values) == 1 && f.Values[0] == ""))
+		filters = append(filters, f)
+	}
+	if !positive {
+		return nil, errTSNoMatcher
+	}
+	return newCommand(p.store, p.clock, query, filters, options...), nil
+}
+
+func (p Parser) newTSCreateRuleCommand(array []string) (Command, error) {
+	if len(array) != 6 && len(array) != 7 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	if !strings.EqualFold(array[3], "AGGREGATION") {
+		return nil, errSyntax
+	}
+	aggregation, err := parseTSAggregation(array[4], array[5])
+	if err != nil {
+		return nil, err
+	}
+	if len(array) == 7 {
+		if aggregation.Align, err = strconv.ParseInt(array[6], 10, 64); err != nil {
+			return nil, errTSAlign
+		}
+	}
+	return NewTSCreateRuleCommand(p.store, p.clock, array[1], array[2], aggregation), nil
+}
+
+func (p Parser) newTSDeleteRuleCommand(array []string) (Command, error) {
+	if len(array) != 3 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	return NewTSDeleteRuleCommand(p.store, p.clock, array[1], array[2]), nil
+}
@@ -0,0 +1,371 @@
+package redis_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/codecrafters-io/redis-starter-go/redis"
+)
+
+func TestParser_ParseTimeSeriesRequest(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{}
+
+	tests := []struct {
+		name    string
+		request string
+		want    redis.Command
+	}{
+		{
+			name:    "TS.CREATE ts",
+			request: "*2\r\n$9\r\nTS.CREATE\r\n$2\r\nts\r\n",
+			want:    redis.NewTSCreateCommand(store, clock, "ts", redis.TSCreateOptions{}),
+		},
+		{
+			name: "TS.CREATE ts RETENTION 1000 DUPLICATE_POLICY max LABELS type cpu host a",
+			request: "*11\r\n$9\r\nTS.CREATE\r\n$2\r\nts\r\n$9\r\nRETENTION\r\n" +
+				"$4\r\n1000\r\n$16\r\nDUPLICATE_POLICY\r\n$3\r\nmax\r\n$6\r\nLABELS\r\n" +
+				"$4\r\ntype\r\n$3\r\ncpu\r\n$4\r\nhost\r\n$1\r\na\r\n",
+			want: redis.NewTSCreateCommand(
+				store, clock, "ts",
+				redis.TSCreateOptions{
+					Retention:       1000,
+					DuplicatePolicy: redis.TSDuplicateMax,
+					Labels: []redis.TSLabel{
+						{Name: "type", Value: "cpu"},
+						{Name: "host", Value: "a"},
+					},
+				},
+			),
+		},
+		{
+			name:    "TS.ADD ts * 1.5",
+			request: "*4\r\n$6\r\nTS.ADD\r\n$2\r\nts\r\n$1\r\n*\r\n$3\r\n1.5\r\n",
+			want: redis.NewTSAddCommand(
+				store, clock, "ts", redis.TSTimestampNow, 1.5, redis.TSCreateOptions{},
+				redis.TSDuplicateDefault,
+			),
+		},
+		{
+			name: "TS.ADD ts 1000 2 RETENTION 10 ON_DUPLICATE sum",
+			request: "*8\r\n$6\r\nTS.ADD\r\n$2\r\nts\r\n$4\r\n1000\r\n$1\r\n2\r\n" +
+				"$9\r\nRETENTION\r\n$2\r\n10\r\n$12\r\nON_DUPLICATE\r\n$3\r\nsum\r\n",
+			want: redis.NewTSAddCommand(
+				store, clock, "ts", 1000, 2, redis.TSCreateOptions{Retention: 10},
+				redis.TSDuplicateSum,
+			),
+		},
+		{
+			name: "TS.MADD a 1 1 b * 2",
+			request: "*7\r\n$7\r\nTS.MADD\r\n$1\r\na\r\n$1\r\n1\r\n$1\r\n1\r\n$1\r\nb\r\n" +
+				"$1\r\n*\r\n$1\r\n2\r\n",
+			want: redis.NewTSMAddCommand(
+				store, clock,
+				[]redis.TSMAddSample{
+					{Key: "a", Timestamp: 1, Value: 1},
+					{Key: "b", Timestamp: redis.TSTimestampNow, Value: 2},
+				},
+			),
+		},
+		{
+			name:    "TS.INCRBY ts 2",
+			request: "*3\r\n$9\r\nTS.INCRBY\r\n$2\r\nts\r\n$1\r\n2\r\n",
+			want: redis.NewTSIncrByCommand(
+				store, clock, "ts", 2, redis.TSTimestampNow, redis.TSCreateOptions{},
+			),
+		},
+		{
+			name: "TS.DECRBY ts 2 TIMESTAMP 100 LABELS a b",
+			request: "*8\r\n$9\r\nTS.DECRBY\r\n$2\r\nts\r\n$1\r\n2\r\n$9\r\nTIMESTAMP\r\n" +
+				"$3\r\n100\r\n$6\r\nLABELS\r\n$1\r\na\r\n$1\r\nb\r\n",
+			want: redis.NewTSDecrByCommand(
+				store, clock, "ts", 2, 100,
+				redis.TSCreateOptions{Labels: []redis.TSLabel{{Name: "a", Value: "b"}}},
+			),
+		},
+		{
+			name:    "TS.GET ts",
+			request: "*2\r\n$6\r\nTS.GET\r\n$2\r\nts\r\n",
+			want:    redis.NewTSGetCommand(store, clock, "ts"),
+		},
+		{
+			name:    "TS.RANGE ts - +",
+			request: "*4\r\n$8\r\nTS.RANGE\r\n$2\r\nts\r\n$1\r\n-\r\n$1\r\n+\r\n",
+			want: redis.NewTSRangeCommand(
+				store, clock, "ts", redis.TSRangeQuery{From: redis.TSRangeStart, To: redis.TSRangeEnd},
+			),
+		},
+		{
+			name: "TS.RANGE ts 10 20 COUNT 5 AGGREGATION avg 5",
+			request: "*9\r\n$8\r\nTS.RANGE\r\n$2\r\nts\r\n$2\r\n10\r\n$2\r\n20\r\n" +
+				"$5\r\nCOUNT\r\n$1\r\n5\r\n$11\r\nAGGREGATION\r\n$3\r\navg\r\n" +
+				"$1\r\n5\r\n",
+			want: redis.NewTSRangeCommand(
+				store, clock, "ts",
+				redis.TSRangeQuery{
+					From:  10,
+					To:    20,
+					Count: 5,
+					Aggregation: &redis.TSAggregation{
+						Aggregator:     redis.TSAggregatorAvg,
+						BucketDuration: 5,
+					},
+				},
+			),
+		},
+		{
+			name: "TS.REVRANGE ts 10 20 ALIGN start AGGREGATION count 5",
+			request: "*9\r\n$11\r\nTS.REVRANGE\r\n$2\r\nts\r\n$2\r\n10\r\n$2\r\n20\r\n" +
+				"$5\r\nALIGN\r\n$5\r\nstart\r\n$11\r\nAGGREGATION\r\n$5\r\ncount\r\n" +
+				"$1\r\n5\r\n",
+			want: redis.NewTSRevRangeCommand(
+				store, clock, "ts",
+				redis.TSRangeQuery{
+					From: 10,
+					To:   20,
+					Aggregation: &redis.TSAggregation{
+						Aggregator:     redis.TSAggregatorCount,
+						BucketDuration: 5,
+					},
+					Alignment: redis.TSAlignStart,
+				},
+			),
+		},
+		{
+			name: "TS.RANGE ts 10 20 AGGREGATION last 5 ALIGN 3",
+			request: "*9\r\n$8\r\nTS.RANGE\r\n$2\r\nts\r\n$2\r\n10\r\n$2\r\n20\r\n" +
+				"$11\r\nAGGREGATION\r\n$4\r\nlast\r\n$1\r\n5\r\n$5\r\nALIGN\r\n" +
+				"$1\r\n3\r\n",
+			want: redis.NewTSRangeCommand(
+				store, clock, "ts",
+				redis.TSRangeQuery{
+					From: 10,
+					To:   20,
+					Aggregation: &redis.TSAggregation{
+						Aggregator:     redis.TSAggregatorLast,
+						BucketDuration: 5,
+						Align:          3,
+					},
+				},
+			),
+		},
+		{
+			name: "TS.MRANGE - + FILTER type=cpu",
+			request: "*5\r\n$9\r\nTS.MRANGE\r\n$1\r\n-\r\n$1\r\n+\r\n$6\r\nFILTER\r\n" +
+				"$8\r\ntype=cpu\r\n",
+			want: redis.NewTSMRangeCommand(
+				store, clock, redis.TSRangeQuery{From: redis.TSRangeStart, To: redis.TSRangeEnd},
+				[]redis.TSFilter{{Label: "type", Values: []string{"cpu"}}},
+			),
+		},
+		{
+			name: "TS.MREVRANGE 0 10 WITHLABELS COUNT 1 FILTER type=(cpu,mem) host!=",
+			request: "*9\r\n$12\r\nTS.MREVRANGE\r\n$1\r\n0\r\n$2\r\n10\r\n" +
+				"$10\r\nWITHLABELS\r\n$5\r\nCOUNT\r\n$1\r\n1\r\n$6\r\nFILTER\r\n" +
+				"$14\r\ntype=(cpu,mem)\r\n$6\r\nhost!=\r\n",
+			want: redis.NewTSMRevRangeCommand(
+				store, clock, redis.TSRangeQuery{From: 0, To: 10, Count: 1},
+				[]redis.TSFilter{
+					{Label: "type", Values: []string{"cpu", "mem"}},
+					{Label: "host", Values: []string{""}, Negate: true},
+				},
+				redis.TSMRangeWithLabels(),
+			),
+		},
+		{
+			name: "TS.CREATERULE src dst AGGREGATION max 60",
+			request: "*6\r\n$13\r\nTS.CREATERULE\r\n$3\r\nsrc\r\n$3\r\ndst\r\n" +
+				"$11\r\nAGGREGATION\r\n$3\r\nmax\r\n$2\r\n60\r\n",
+			want: redis.NewTSCreateRuleCommand(
+				store, clock, "src", "dst",
+				redis.TSAggregation{Aggregator: redis.TSAggregatorMax, BucketDuration: 60},
+			),
+		},
+		{
+			name: "TS.CREATERULE src dst AGGREGATION sum 60 30",
+			request: "*7\r\n$13\r\nTS.CREATERULE\r\n$3\r\nsrc\r\n$3\r\ndst\r\n" +
+				"$11\r\nAGGREGATION\r\n$3\r\nsum\r\n$2\r\n60\r\n$2\r\n30\r\n",
+			want: redis.NewTSCreateRuleCommand(
+				store, clock, "src", "dst",
+				redis.TSAggregation{
+					Aggregator:     redis.TSAggregatorSum,
+					BucketDuration: 60,
+					Align:          30,
+				},
+			),
+		},
+		{
+			name:    "TS.DELETERULE src dst",
+			request: "*3\r\n$13\r\nTS.DELETERULE\r\n$3\r\nsrc\r\n$3\r\ndst\r\n",
+			want:    redis.NewTSDeleteRuleCommand(store, clock, "src", "dst"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requestReader := strings.NewReader(tt.request)
+
+			command, err := redis.NewParser(zeroValueRedisConfig, store, clock).Parse(requestReader)
+
+			if err != nil {
+				t.Errorf("err: expected: nil; got: %v", err)
+			}
+			if !reflect.DeepEqual(command, tt.want) {
+				t.Errorf("command expected to be %#v but was %#v", tt.want, command)
+			}
+		})
+	}
+}
+
+func TestParser_ParseInvalidTimeSeriesRequest(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		request string
+		err     redis.CommandError
+	}{
+		{
+			name:    "TS.CREATE",
+			request: "*1\r\n$9\r\nTS.CREATE\r\n",
+			err:     "ERR wrong number of arguments for 'ts.create' command",
+		},
+		{
+			name:    "TS.CREATE ts RETENTION -1",
+			request: "*4\r\n$9\r\nTS.CREATE\r\n$2\r\nts\r\n$9\r\nRETENTION\r\n$2\r\n-1\r\n",
+			err:     "ERR TSDB: Couldn't parse RETENTION",
+		},
+		{
+			name: "TS.CREATE ts DUPLICATE_POLICY newest",
+			request: "*4\r\n$9\r\nTS.CREATE\r\n$2\r\nts\r\n$16\r\nDUPLICATE_POLICY\r\n" +
+				"$6\r\nnewest\r\n",
+			err: "ERR TSDB: Unknown DUPLICATE_POLICY",
+		},
+		{
+			name:    "TS.CREATE ts LABELS a",
+			request: "*4\r\n$9\r\nTS.CREATE\r\n$2\r\nts\r\n$6\r\nLABELS\r\n$1\r\na\r\n",
+			err:     "ERR TSDB: failed parsing labels",
+		},
+		{
+			name:    "TS.CREATE ts ON_DUPLICATE sum",
+			request: "*4\r\n$9\r\nTS.CREATE\r\n$2\r\nts\r\n$12\r\nON_DUPLICATE\r\n$3\r\nsum\r\n",
+			err:     "ERR syntax error",
+		},
+		{
+			name:    "TS.CREATE ts RETENTION",
+			request: "*3\r\n$9\r\nTS.CREATE\r\n$2\r\nts\r\n$9\r\nRETENTION\r\n",
+			err:     "ERR syntax error",
+		},
+		{
+			name:    "TS.ADD ts 1",
+			request: "*3\r\n$6\r\nTS.ADD\r\n$2\r\nts\r\n$1\r\n1\r\n",
+			err:     "ERR wrong number of arguments for 'ts.add' command",
+		},
+		{
+			name:    "TS.ADD ts -1 1",
+			request: "*4\r\n$6\r\nTS.ADD\r\n$2\r\nts\r\n$2\r\n-1\r\n$1\r\n1\r\n",
+			err:     "ERR TSDB: invalid timestamp",
+		},
+		{
+			name:    "TS.ADD ts 1 one",
+			request: "*4\r\n$6\r\nTS.ADD\r\n$2\r\nts\r\n$1\r\n1\r\n$3\r\none\r\n",
+			err:     "ERR TSDB: invalid value",
+		},
+		{
+			name: "TS.MADD a 1 1 b 2",
+			request: "*6\r\n$7\r\nTS.MADD\r\n$1\r\na\r\n$1\r\n1\r\n$1\r\n1\r\n$1\r\nb\r\n" +
+				"$1\r\n2\r\n",
+			err: "ERR wrong number of arguments for 'ts.madd' command",
+		},
+		{
+			name: "TS.INCRBY ts 1 TIMESTAMP now",
+			request: "*5\r\n$9\r\nTS.INCRBY\r\n$2\r\nts\r\n$1\r\n1\r\n$9\r\nTIMESTAMP\r\n" +
+				"$3\r\nnow\r\n",
+			err: "ERR TSDB: invalid timestamp",
+		},
+		{
+			name:    "TS.GET",
+			request: "*1\r\n$6\r\nTS.GET\r\n",
+			err:     "ERR wrong number of arguments for 'ts.get' command",
+		},
+		{
+			name:    "TS.RANGE ts 0",
+			request: "*3\r\n$8\r\nTS.RANGE\r\n$2\r\nts\r\n$1\r\n0\r\n",
+			err:     "ERR wrong number of arguments for 'ts.range' command",
+		},
+		{
+			name: "TS.RANGE ts 0 10 AGGREGATION median 5",
+			request: "*7\r\n$8\r\nTS.RANGE\r\n$2\r\nts\r\n$1\r\n0\r\n$2\r\n10\r\n" +
+				"$11\r\nAGGREGATION\r\n$6\r\nmedian\r\n$1\r\n5\r\n",
+			err: "ERR TSDB: Unknown aggregation type",
+		},
+		{
+			name: "TS.RANGE ts 0 10 AGGREGATION avg 0",
+			request: "*7\r\n$8\r\nTS.RANGE\r\n$2\r\nts\r\n$1\r\n0\r\n$2\r\n10\r\n" +
+				"$11\r\nAGGREGATION\r\n$3\r\navg\r\n$1\r\n0\r\n",
+			err: "ERR TSDB: bucketDuration must be greater than zero",
+		},
+		{
+			name: "TS.RANGE ts 0 10 COUNT 0",
+			request: "*6\r\n$8\r\nTS.RANGE\r\n$2\r\nts\r\n$1\r\n0\r\n$2\r\n10\r\n" +
+				"$5\r\nCOUNT\r\n$1\r\n0\r\n",
+			err: "ERR TSDB: Couldn't parse COUNT",
+		},
+		{
+			name: "TS.RANGE ts 0 10 ALIGN start",
+			request: "*6\r\n$8\r\nTS.RANGE\r\n$2\r\nts\r\n$1\r\n0\r\n$2\r\n10\r\n" +
+				"$5\r\nALIGN\r\n$5\r\nstart\r\n",
+			err: "ERR TSDB: unknown ALIGN parameter",
+		},
+		{
+			name: "TS.RANGE ts 0 10 FILTER a=b",
+			request: "*6\r\n$8\r\nTS.RANGE\r\n$2\r\nts\r\n$1\r\n0\r\n$2\r\n10\r\n" +
+				"$6\r\nFILTER\r\n$3\r\na=b\r\n",
+			err: "ERR syntax error",
+		},
+		{
+			name:    "TS.MRANGE 0 10 FILTER",
+			request: "*4\r\n$9\r\nTS.MRANGE\r\n$1\r\n0\r\n$2\r\n10\r\n$6\r\nFILTER\r\n",
+			err:     "ERR wrong number of arguments for 'ts.mrange' command",
+		},
+		{
+			name:    "TS.MRANGE 0 10 FILTER a",
+			request: "*5\r\n$9\r\nTS.MRANGE\r\n$1\r\n0\r\n$2\r\n10\r\n$6\r\nFILTER\r\n$1\r\na\r\n",
+			err:     "ERR TSDB: failed parsing filter",
+		},
+		{
+			name: "TS.MRANGE 0 10 FILTER a!=b",
+			request: "*5\r\n$9\r\nTS.MRANGE\r\n$1\r\n0\r\n$2\r\n10\r\n$6\r\nFILTER\r\n" +
+				"$4\r\na!=b\r\n",
+			err: "ERR TSDB: please provide at least one matcher",
+		},
+		{
+			name: "TS.CREATERULE src dst AGGREGATE avg 5",
+			request: "*6\r\n$13\r\nTS.CREATERULE\r\n$3\r\nsrc\r\n$3\r\ndst\r\n" +
+				"$9\r\nAGGREGATE\r\n$3\r\navg\r\n$1\r\n5\r\n",
+			err: "ERR syntax error",
+		},
+		{
+			name:    "TS.DELETERULE src",
+			request: "*2\r\n$13\r\nTS.DELETERULE\r\n$3\r\nsrc\r\n",
+			err:     "ERR wrong number of arguments for 'ts.deleterule' command",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := redis.NewStore()
+			clock := FakeClock{}
+			requestReader := strings.NewReader(tt.request)
+
+			_, err := redis.NewParser(zeroValueRedisConfig, store, clock).Parse(requestReader)
+
+			if err != tt.err {
+				t.Errorf("err: expected: %v; got: %v", tt.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package redis
+
+import "strings"
+
+// runInTransaction returns true if the command name is run right away in a transaction rather
+// than queued, like EXEC.
+func runInTransaction(name string) bool {
+	switch strings.ToUpper(name) {
+	case "EXEC", "DISCARD", "MULTI", "WATCH", "QUIT", "RESET":
+		return true
+	}
+	return false
+}
+
+// queueRequest returns a command that queues the command for the request array in the client's
+// transaction. If the request is invalid, the transaction fails and EXEC will discard it.
+func (p Parser) queueRequest(array []string) (Command, error) {
+	p.store = p.store.forTransaction()
+	command, err := p.newCommand(array)
+	if err != nil {
+		p.client.failTransaction()
+		return nil, err
+	}
+	return NewQueuedCommand(p.client, command), nil
+}
+
+func (p Parser) newMultiCommand(array []string) (Command, error) {
+	if len(array) != 1 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	return NewMultiCommand(p.client), nil
+}
+
+func (p Parser) newExecCommand(array []string) (Command, error) {
+	if len(array) != 1 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	return NewExecCommand(p.store, p.clock, p.client), nil
+}
+
+func (p Parser) newDiscardCommand(array []string) (Command, error) {
+	if len(array) != 1 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	return NewDiscardCommand(p.client), nil
+}
+
+func (p Parser) newWatchCommand(array []string) (Command, error) {
+	if len(array) < 2 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	return NewWatchCommand(p.store, p.clock, p.client, array[1:]), nil
+}
+
+func (p Parser) newUnwatchCommand(array []string) (Command, error) {
+	if len(array) != 1 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	return NewUnwatchCommand(p.client), nil
+}
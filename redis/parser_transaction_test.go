@@ -0,0 +1,136 @@
+package redis_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/codecrafters-io/redis-starter-go/redis"
+)
+
+func TestParser_ParseTransactionRequest(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{}
+	parser := redis.NewParser(zeroValueRedisConfig, store, clock)
+	client := parser.NewClient()
+	parser = parser.ForClient(client)
+
+	tests := []struct {
+		name    string
+		request string
+		want    redis.Command
+	}{
+		{
+			name:    "MULTI",
+			request: "*1\r\n$5\r\nMULTI\r\n",
+			want:    redis.NewMultiCommand(client),
+		},
+		{
+			name:    "discard",
+			request: "*1\r\n$7\r\ndiscard\r\n",
+			want:    redis.NewDiscardCommand(client),
+		},
+		{
+			name:    "UNWATCH",
+			request: "*1\r\n$7\r\nUNWATCH\r\n",
+			want:    redis.NewUnwatchCommand(client),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			command, err := parser.Parse(strings.NewReader(tt.request))
+
+			if err != nil {
+				t.Errorf("err: expected: nil; got: %v", err)
+			}
+			if !reflect.DeepEqual(command, tt.want) {
+				t.Errorf("command expected to be %#v but was %#v", tt.want, command)
+			}
+		})
+	}
+}
+
+func TestParser_ParseRequestInTransaction(t *testing.T) {
+	t.Parallel()
+
+	parser := redis.NewParser(zeroValueRedisConfig, redis.NewStore(), FakeClock{})
+	client := parser.NewClient()
+	parser = parser.ForClient(client)
+	parse := func(request string) (redis.Command, error) {
+		return parser.Parse(strings.NewReader(request))
+	}
+	multi, err := parse("*1\r\n$5\r\nMULTI\r\n")
+	if err != nil {
+		t.Fatalf("err: expected: nil; got: %v", err)
+	}
+	multi.Run()
+
+	command, err := parse("*2\r\n$3\r\nGET\r\n$3\r\nkey\r\n")
+	if err != nil {
+		t.Errorf("err: expected: nil; got: %v", err)
+	}
+	if _, ok := command.(*redis.QueuedCommand); !ok {
+		t.Errorf("command expected to be a *redis.QueuedCommand but was %#v", command)
+	}
+
+	command, err = parse("*1\r\n$4\r\nEXEC\r\n")
+	if err != nil {
+		t.Errorf("err: expected: nil; got: %v", err)
+	}
+	if _, ok := command.(*redis.ExecCommand); !ok {
+		t.Errorf("command expected to be a *redis.ExecCommand but was %#v", command)
+	}
+}
+
+func TestParser_ParseInvalidTransactionRequest(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		request string
+		err     redis.CommandError
+	}{
+		{
+			name:    "MULTI now",
+			request: "*2\r\n$5\r\nMULTI\r\n$3\r\nnow\r\n",
+			err:     "ERR wrong number of arguments for 'multi' command",
+		},
+		{
+			name:    "EXEC now",
+			request: "*2\r\n$4\r\nEXEC\r\n$3\r\nnow\r\n",
+			err:     "ERR wrong number of arguments for 'exec' command",
+		},
+		{
+			name:    "DISCARD now",
+			request: "*2\r\n$7\r\nDISCARD\r\n$3\r\nnow\r\n",
+			err:     "ERR wrong number of arguments for 'discard' command",
+		},
+		{
+			name:    "WATCH",
+			request: "*1\r\n$5\r\nWATCH\r\n",
+			err:     "ERR wrong number of arguments for 'watch' command",
+		},
+		{
+			name:    "UNWATCH a",
+			request: "*2\r\n$7\r\nUNWATCH\r\n$1\r\na\r\n",
+			err:     "ERR wrong number of arguments for 'unwatch' command",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := redis.NewStore()
+			clock := FakeClock{}
+			requestReader := strings.NewReader(tt.request)
+
+			_, err := redis.NewParser(zeroValueRedisConfig, store, clock).Parse(requestReader)
+
+			if err != tt.err {
+				t.Errorf("err: expected: %v; got: %v", tt.err, err)
+			}
+		})
+	}
+}
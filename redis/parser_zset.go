@@ -0,0 +1,523 @@
+package redis
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+func (p Parser) newZAddCommand(array []string) (Command, error) {
+	if len(array) < 4 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+
+	var options []func(*ZAddCommand)
+	var nx, xx, gt, lt, incr bool
+	i := 2
+flags:
+	for ; i < len(array); i++ {
+		switch {
+		case strings.EqualFold(array[i], "NX"):
+			nx = true
+			options = append(options, ZAddNX())
+		case strings.EqualFold(array[i], "XX"):
+			xx = true
+			options = append(options, ZAddXX())
+		case strings.EqualFold(array[i], "GT"):
+			gt = true
+			options = append(options, ZAddGT())
+		case strings.EqualFold(array[i], "LT"):
+			lt = true
+			options = append(options, ZAddLT())
+		case strings.EqualFold(array[i], "CH"):
+			options = append(options, ZAddCH())
+		case strings.EqualFold(array[i], "INCR"):
+			incr = true
+			options = append(options, ZAddIncr())
+		default:
+			break flags
+		}
+	}
+	arguments := array[i:]
+	if len(arguments) == 0 || len(arguments)%2 != 0 {
+		return nil, errSyntax
+	}
+	if nx && xx {
+		return nil, CommandError("ERR XX and NX options at the same time are not compatible")
+	}
+	if (gt && nx) || (lt && nx) || (gt && lt) {
+		return nil, CommandError("ERR GT, LT, and/or NX options at the same time are not compatible")
+	}
+	if incr && len(arguments) > 2 {
+		return nil, CommandError("ERR INCR option supports a single increment-element pair")
+	}
+
+	scoreMembers := make([]ScoreMember, 0, len(arguments)/2)
+	for j := 0; j < len(arguments); j += 2 {
+		score, err := parseFloat(arguments[j])
+		if err != nil {
+			return nil, err
+		}
+		scoreMembers = append(scoreMembers, ScoreMember{Score: score, Member: arguments[j+1]})
+	}
+	return NewZAddCommand(p.store, p.clock, array[1], scoreMembers, options...), nil
+}
+
+func (p Parser) newZIncrByCommand(array []string) (Command, error) {
+	if len(array) != 4 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	increment, err := parseFloat(array[2])
+	if err != nil {
+		return nil, err
+	}
+	scoreMembers := []ScoreMember{{Score: increment, Member: array[3]}}
+	return NewZAddCommand(p.store, p.clock, array[1], scoreMembers, ZAddIncr()), nil
+}
+
+func (p Parser) newZRangeCommand(array []string) (Command, error) {
+	return p.newZRangeCommandBy(array, ZRangeByRank, false, true)
+}
+
+func (p Parser) newZRevRangeCommand(array []string) (Command, error) {
+	return p.newZRangeCommandBy(array, ZRangeByRank, true, false)
+}
+
+func (p Parser) newZRangeByScoreCommand(array []string) (Command, error) {
+	return p.newZRangeCommandBy(array, ZRangeByScore, false, false)
+}
+
+func (p Parser) newZRevRangeByScoreCommand(array []string) (Command, error) {
+	return p.newZRangeCommandBy(array, ZRangeByScore, true, false)
+}
+
+func (p Parser) newZRangeByLexCommand(array []string) (Command, error) {
+	return p.newZRangeCommandBy(array, ZRangeByLex, false, false)
+}
+
+func (p Parser) newZRevRangeByLexCommand(array []string) (Command, error) {
+	return p.newZRangeCommandBy(array, ZRangeByLex, true, false)
+}
+
+// newZRangeCommandBy parses ZRANGE, if isZRange is true, or one of the older commands that select
+// members by and in the direction given.
+func (p Parser) newZRangeCommandBy(
+	array []string,
+	by ZRangeBy,
+	reverse bool,
+	isZRange bool,
+) (Command, error) {
+	if len(array) < 4 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+
+	query := ZRangeQuery{By: by, Reverse: reverse, Count: -1}
+	withScores, limited := false, false
+	for i := 4; i < len(array); i++ {
+		switch {
+		case isZRange && strings.EqualFold(array[i], "BYSCORE"):
+			query.By = ZRangeByScore
+		case isZRange && strings.EqualFold(array[i], "BYLEX"):
+			query.By = ZRangeByLex
+		case isZRange && strings.EqualFold(array[i], "REV"):
+			query.Reverse = true
+		case strings.EqualFold(array[i], "LIMIT") && i+2 < len(array):
+			var err error
+			if query.Offset, err = parseInteger(array[i+1]); err != nil {
+				return nil, err
+			}
+			if query.Count, err = parseInteger(array[i+2]); err != nil {
+				return nil, err
+			}
+			limited = true
+			i += 2
+		case strings.EqualFold(array[i], "WITHSCORES"):
+			withScores = true
+		default:
+			return nil, errSyntax
+		}
+	}
+	if limited && query.By == ZRangeByRank {
+		return nil, CommandError(
+			"ERR syntax error, LIMIT is only supported in combination with either BYSCORE or BYLEX",
+		)
+	}
+	if withScores && query.By == ZRangeByLex {
+		return nil, CommandError("ERR syntax error, WITHSCORES not supported in combination with BYLEX")
+	}
+
+	min, max := array[2], array[3]
+	if query.Reverse && query.By != ZRangeByRank {
+		min, max = max, min
+	}
+	var err error
+	switch query.By {
+	case ZRangeByRank:
+		if query.Start, err = parseInteger(min); err != nil {
+			return nil, err
+		}
+		if query.Stop, err = parseInteger(max); err != nil {
+			return nil, err
+		}
+	case ZRangeByScore:
+		if query.Scores, err = parseScoreRange(min, max); err != nil {
+			return nil, err
+		}
+	case ZRangeByLex:
+		if query.Lex, err = parseLexRange(min, max); err != nil {
+			return nil, err
+		}
+	}
+	return NewZRangeCommand(p.store, p.clock, array[1], query, withScores), nil
+}
+
+// parseScoreRange parses the min and max arguments of commands like ZRANGEBYSCORE, which are
+// floats that are exclusive if they start with "(".
+func parseScoreRange(min, max string) (ScoreRange, error) {
+	var result ScoreRange
+	var minOK, maxOK bool
+	result.Min, result.MinExclusive, minOK = parseScoreBound(min)
+	result.Max, result.MaxExclusive, maxOK = parseScoreBound(max)
+	if !minOK || !maxOK {
+		return ScoreRange{}, CommandError("ERR min or max is not a float")
+	}
+	return result, nil
+}
+
+func parseScoreBound(s string) (score float64, exclusive bool, ok bool) {
+	if strings.HasPrefix(s, "(") {
+		s = s[1:]
+		exclusive = true
+	}
+	score, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(score) {
+		return 0, false, false
+	}
+	return score, exclusive, true
+}
+
+// parseLexRange parses the min and max arguments of commands like ZRANGEBYLEX, which are "-",
+// "+", or a member that starts with "[" if it's inclusive or "(" if it's exclusive.
+func parseLexRange(min, max string) (LexRange, error) {
+	var result LexRange
+	var minOK, maxOK bool
+	result.Min, minOK = parseLexBound(min)
+	result.Max, maxOK = parseLexBound(max)
+	if !minOK || !maxOK {
+		return LexRange{}, CommandError("ERR min or max not valid string range item")
+	}
+	return result, nil
+}
+
+func parseLexBound(s string) (LexBound, bool) {
+	switch {
+	case s == "-":
+		return LexBound{Infinity: -1}, true
+	case s == "+":
+		return LexBound{Infinity: 1}, true
+	case strings.HasPrefix(s, "["):
+		return LexBound{Value: s[1:]}, true
+	case strings.HasPrefix(s, "("):
+		return LexBound{Value: s[1:], Exclusive: true}, true
+	}
+	return LexBound{}, false
+}
+
+func (p Parser) newZCountCommand(array []string) (Command, error) {
+	if len(array) != 4 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	scores, err := parseScoreRange(array[2], array[3])
+	if err != nil {
+		return nil, err
+	}
+	query := ZRangeQuery{By: ZRangeByScore, Scores: scores, Count: -1}
+	return NewZCountCommand(p.store, p.clock, array[1], query), nil
+}
+
+func (p Parser) newZLexCountCommand(array []string) (Command, error) {
+	if len(array) != 4 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	lex, err := parseLexRange(array[2], array[3])
+	if err != nil {
+		return nil, err
+	}
+	query := ZRangeQuery{By: ZRangeByLex, Lex: lex, Count: -1}
+	return NewZCountCommand(p.store, p.clock, array[1], query), nil
+}
+
+func (p Parser) newZRemRangeByRankCommand(array []string) (Command, error) {
+	if len(array) != 4 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	start, err := parseInteger(array[2])
+	if err != nil {
+		return nil, err
+	}
+	stop, err := parseInteger(array[3])
+	if err != nil {
+		return nil, err
+	}
+	return NewZRemRangeCommand(p.store, p.clock, array[1], NewZRangeQuery(start, stop)), nil
+}
+
+func (p Parser) newZRemRangeByScoreCommand(array []string) (Command, error) {
+	if len(array) != 4 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	scores, err := parseScoreRange(array[2], array[3])
+	if err != nil {
+		return nil, err
+	}
+	query := ZRangeQuery{By: ZRangeByScore, Scores: scores, Count: -1}
+	return NewZRemRangeCommand(p.store, p.clock, array[1], query), nil
+}
+
+func (p Parser) newZRemRangeByLexCommand(array []string) (Command, error) {
+	if len(array) != 4 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	lex, err := parseLexRange(array[2], array[3])
+	if err != nil {
+		return nil, err
+	}
+	query := ZRangeQuery{By: ZRangeByLex, Lex: lex, Count: -1}
+	return NewZRemRangeCommand(p.store, p.clock, array[1], query), nil
+}
+
+func (p Parser) newZCardCommand(array []string) (Command, error) {
+	if len(array) != 2 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	return NewZCardCommand(p.store, p.clock, array[1]), nil
+}
+
+func (p Parser) newZRankCommand(array []string) (Command, error) {
+	return p.newZRankCommandWithOrder(array, false)
+}
+
+func (p Parser) newZRevRankCommand(array []string) (Command, error) {
+	return p.newZRankCommandWithOrder(array, true)
+}
+
+func (p Parser) newZRankCommandWithOrder(array []string, reverse bool) (Command, error) {
+	if len(array) != 3 && len(array) != 4 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	withScore := len(array) == 4
+	if withScore && !strings.EqualFold(array[3], "WITHSCORE") {
+		return nil, errSyntax
+	}
+	return NewZRankCommand(p.store, p.clock, array[1], array[2], reverse, withScore), nil
+}
+
+func (p Parser) newZScoreCommand(array []string) (Command, error) {
+	if len(array) != 3 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	return NewZScoreCommand(p.store, p.clock, array[1], array[2:]), nil
+}
+
+func (p Parser) newZMScoreCommand(array []string) (Command, error) {
+	if len(array) < 3 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	return NewZMScoreCommand(p.store, p.clock, array[1], array[2:]), nil
+}
+
+func (p Parser) newZRemCommand(array []string) (Command, error) {
+	if len(array) < 3 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	return NewZRemCommand(p.store, p.clock, array[1], array[2:]), nil
+}
+
+func (p Parser) newZPopMinCommand(array []string) (Command, error) {
+	return p.newZPopCommand(array, ZSetEndMin)
+}
+
+func (p Parser) newZPopMaxCommand(array []string) (Command, error) {
+	return p.newZPopCommand(array, ZSetEndMax)
+}
+
+func (p Parser) newZPopCommand(array []string, end ZSetEnd) (Command, error) {
+	if len(array) != 2 && len(array) != 3 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	count := 1
+	if len(array) == 3 {
+		var err error
+		if count, err = parsePositiveInteger(array[2]); err != nil {
+			return nil, err
+		}
+	}
+	return NewZPopCommand(p.store, p.clock, array[1], end, count), nil
+}
+
+func (p Parser) newBZPopMinCommand(array []string) (Command, error) {
+	return p.newBZPopCommand(array, ZSetEndMin)
+}
+
+func (p Parser) newBZPopMaxCommand(array []string) (Command, error) {
+	return p.newBZPopCommand(array, ZSetEndMax)
+}
+
+func (p Parser) newBZPopCommand(array []string, end ZSetEnd) (Command, error) {
+	if len(array) < 3 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	timeout, err := parseTimeout(array[len(array)-1])
+	if err != nil {
+		return nil, err
+	}
+	keys := array[1 : len(array)-1]
+	return NewBZPopCommand(p.store, p.clock, p.client, keys, end, timeout), nil
+}
+
+func (p Parser) newZUnionCommand(array []string) (Command, error) {
+	return p.newZSetOpCommand(array, SetOpUnion, false)
+}
+
+func (p Parser) newZInterCommand(array []string) (Command, error) {
+	return p.newZSetOpCommand(array, SetOpInter, false)
+}
+
+func (p Parser) newZDiffCommand(array []string) (Command, error) {
+	return p.newZSetOpCommand(array, SetOpDiff, false)
+}
+
+func (p Parser) newZUnionStoreCommand(array []string) (Command, error) {
+	return p.newZSetOpCommand(array, SetOpUnion, true)
+}
+
+func (p Parser) newZInterStoreCommand(array []string) (Command, error) {
+	return p.newZSetOpCommand(array, SetOpInter, true)
+}
+
+func (p Parser) newZDiffStoreCommand(array []string) (Command, error) {
+	return p.newZSetOpCommand(array, SetOpDiff, true)
+}
+
+// newZSetOpCommand parses ZUNION, ZINTER and ZDIFF, or their STORE variants if store is true,
+// which take a destination key before the others.
+func (p Parser) newZSetOpCommand(array []string, op SetOp, store bool) (Command, error) {
+	arguments := array[1:]
+	var options []func(*ZSetOpCommand)
+	if store {
+		if len(arguments) == 0 {
+			return nil, wrongNumberOfArgumentsError(array)
+		}
+		options = append(options, ZSetOpStore(arguments[0]))
+		arguments = arguments[1:]
+	}
+	if len(arguments) < 2 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+
+	numKeys, err := parseInteger(arguments[0])
+	if err != nil {
+		return nil, err
+	}
+	if numKeys < 1 {
+		return nil, CommandError(fmt.Sprintf(
+			"ERR at least 1 input key is needed for '%s' command", strings.ToLower(array[0]),
+		))
+	}
+	if numKeys > len(arguments)-1 {
+		return nil, errSyntax
+	}
+	keys := arguments[1 : numKeys+1]
+
+	for i := numKeys + 1; i < len(arguments); i++ {
+		switch {
+		case op != SetOpDiff && strings.EqualFold(arguments[i], "WEIGHTS") && i+numKeys < len(arguments):
+			weights := make([]float64, numKeys)
+			for j := range weights {
+				weight, err := strconv.ParseFloat(arguments[i+1+j], 64)
+				if err != nil || math.IsNaN(weight) {
+					return nil, CommandError("ERR weight value is not a float")
+				}
+				weights[j] = weight
+			}
+			options = append(options, ZSetOpWeights(weights))
+			i += numKeys
+		case op != SetOpDiff && strings.EqualFold(arguments[i], "AGGREGATE") && i+1 < len(arguments):
+			var aggregate ZAggregate
+			switch {
+			case strings.EqualFold(arguments[i+1], "SUM"):
+				aggregate = ZAggregateSum
+			case strings.EqualFold(arguments[i+1], "MIN"):
+				aggregate = ZAggregateMin
+			case strings.EqualFold(arguments[i+1], "MAX"):
+				aggregate = ZAggregateMax
+			default:
+				return nil, errSyntax
+			}
+			options = append(options, ZSetOpAggregate(aggregate))
+			i++
+		case !store && strings.EqualFold(arguments[i], "WITHSCORES"):
+			options = append(options, ZSetOpWithScores())
+		default:
+			return nil, errSyntax
+		}
+	}
+	return NewZSetOpCommand(p.store, p.clock, op, keys, options...), nil
+}
+
+func (p Parser) newZRandMemberCommand(array []string) (Command, error) {
+	if len(array) < 2 || len(array) > 4 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	var options []func(*ZRandMemberCommand)
+	if len(array) >= 3 {
+		count, err := parseInteger(array[2])
+		if err != nil {
+			return nil, err
+		}
+		if count < -math.MaxInt/2 || count > math.MaxInt/2 {
+			return nil, CommandError("ERR value is out of range")
+		}
+		options = append(options, ZRandMemberCount(count))
+	}
+	if len(array) == 4 {
+		if !strings.EqualFold(array[3], "WITHSCORES") {
+			return nil, errSyntax
+		}
+		options = append(options, ZRandMemberWithScores())
+	}
+	return NewZRandMemberCommand(p.store, p.clock, array[1], options...), nil
+}
+
+func (p Parser) newZScanCommand(array []string) (Command, error) {
+	if len(array) < 3 {
+		return nil, wrongNumberOfArgumentsError(array)
+	}
+	cursor, err := parseScanCursor(array[2])
+	if err != nil {
+		return nil, err
+	}
+
+	var options []func(*ZScanCommand)
+	for i := 3; i < len(array); i++ {
+		switch {
+		case strings.EqualFold(array[i], "MATCH") && i+1 < len(array):
+			options = append(options, ZScanMatch(array[i+1]))
+			i++
+		case strings.EqualFold(array[i], "COUNT") && i+1 < len(array):
+			count, err := parseInteger(array[i+1])
+			if err != nil {
+				return nil, err
+			}
+			if count < 1 {
+				return nil, errSyntax
+			}
+			options = append(options, ZScanCount(count))
+			i++
+		default:
+			return nil, errSyntax
+		}
+	}
+	return NewZScanCommand(p.store, p.clock, array[1], cursor, options...), nil
+}
@@ -0,0 +1,401 @@
+package redis_test
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/redis"
+)
+
+func TestParser_ParseZSetRequest(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{}
+
+	tests := []struct {
+		name    string
+		request string
+		want    redis.Command
+	}{
+		{
+			name: "ZADD zset 1 a 2.5 b",
+			request: "*6\r\n$4\r\nZADD\r\n$4\r\nzset\r\n$1\r\n1\r\n$1\r\na\r\n$3\r\n2.5\r\n" +
+				"$1\r\nb\r\n",
+			want: redis.NewZAddCommand(
+				store, clock, "zset",
+				[]redis.ScoreMember{{Score: 1, Member: "a"}, {Score: 2.5, Member: "b"}},
+			),
+		},
+		{
+			name: "ZADD zset NX CH 1 a",
+			request: "*6\r\n$4\r\nZADD\r\n$4\r\nzset\r\n$2\r\nNX\r\n$2\r\nCH\r\n$1\r\n1\r\n" +
+				"$1\r\na\r\n",
+			want: redis.NewZAddCommand(
+				store, clock, "zset", []redis.ScoreMember{{Score: 1, Member: "a"}}, redis.ZAddNX(),
+				redis.ZAddCH(),
+			),
+		},
+		{
+			name: "ZADD zset GT INCR -inf a",
+			request: "*6\r\n$4\r\nZADD\r\n$4\r\nzset\r\n$2\r\nGT\r\n$4\r\nINCR\r\n" +
+				"$4\r\n-inf\r\n$1\r\na\r\n",
+			want: redis.NewZAddCommand(
+				store, clock, "zset", []redis.ScoreMember{{Score: math.Inf(-1), Member: "a"}},
+				redis.ZAddGT(), redis.ZAddIncr(),
+			),
+		},
+		{
+			name:    "ZINCRBY zset 2 a",
+			request: "*4\r\n$7\r\nZINCRBY\r\n$4\r\nzset\r\n$1\r\n2\r\n$1\r\na\r\n",
+			want: redis.NewZAddCommand(
+				store, clock, "zset", []redis.ScoreMember{{Score: 2, Member: "a"}},
+				redis.ZAddIncr(),
+			),
+		},
+		{
+			name:    "ZRANGE zset 0 -1",
+			request: "*4\r\n$6\r\nZRANGE\r\n$4\r\nzset\r\n$1\r\n0\r\n$2\r\n-1\r\n",
+			want: redis.NewZRangeCommand(
+				store, clock, "zset", redis.NewZRangeQuery(0, -1), false,
+			),
+		},
+		{
+			name: "ZRANGE zset 0 -1 REV WITHSCORES",
+			request: "*6\r\n$6\r\nZRANGE\r\n$4\r\nzset\r\n$1\r\n0\r\n$2\r\n-1\r\n" +
+				"$3\r\nREV\r\n$10\r\nWITHSCORES\r\n",
+			want: redis.NewZRangeCommand(
+				store, clock, "zset",
+				redis.ZRangeQuery{By: redis.ZRangeByRank, Stop: -1, Reverse: true, Count: -1}, true,
+			),
+		},
+		{
+			name: "ZRANGE zset (1 +inf BYSCORE LIMIT 1 2",
+			request: "*8\r\n$6\r\nZRANGE\r\n$4\r\nzset\r\n$2\r\n(1\r\n$4\r\n+inf\r\n" +
+				"$7\r\nBYSCORE\r\n$5\r\nLIMIT\r\n$1\r\n1\r\n$1\r\n2\r\n",
+			want: redis.NewZRangeCommand(
+				store, clock, "zset",
+				redis.ZRangeQuery{
+					By:     redis.ZRangeByScore,
+					Scores: redis.ScoreRange{Min: 1, Max: math.Inf(1), MinExclusive: true},
+					Offset: 1,
+					Count:  2,
+				},
+				false,
+			),
+		},
+		{
+			name: "ZRANGE zset + [b BYLEX REV",
+			request: "*6\r\n$6\r\nZRANGE\r\n$4\r\nzset\r\n$1\r\n+\r\n$2\r\n[b\r\n" +
+				"$5\r\nBYLEX\r\n$3\r\nREV\r\n",
+			want: redis.NewZRangeCommand(
+				store, clock, "zset",
+				redis.ZRangeQuery{
+					By: redis.ZRangeByLex,
+					Lex: redis.LexRange{
+						Min: redis.LexBound{Value: "b"},
+						Max: redis.LexBound{Infinity: 1},
+					},
+					Reverse: true,
+					Count:   -1,
+				},
+				false,
+			),
+		},
+		{
+			name:    "ZREVRANGE zset 0 1",
+			request: "*4\r\n$9\r\nZREVRANGE\r\n$4\r\nzset\r\n$1\r\n0\r\n$1\r\n1\r\n",
+			want: redis.NewZRangeCommand(
+				store, clock, "zset",
+				redis.ZRangeQuery{By: redis.ZRangeByRank, Stop: 1, Reverse: true, Count: -1}, false,
+			),
+		},
+		{
+			name: "ZRANGEBYSCORE zset -inf (5 WITHSCORES",
+			request: "*5\r\n$13\r\nZRANGEBYSCORE\r\n$4\r\nzset\r\n$4\r\n-inf\r\n$2\r\n(5\r\n" +
+				"$10\r\nWITHSCORES\r\n",
+			want: redis.NewZRangeCommand(
+				store, clock, "zset",
+				redis.ZRangeQuery{
+					By:     redis.ZRangeByScore,
+					Scores: redis.ScoreRange{Min: math.Inf(-1), Max: 5, MaxExclusive: true},
+					Count:  -1,
+				},
+				true,
+			),
+		},
+		{
+			name:    "ZREVRANGEBYSCORE zset 5 1",
+			request: "*4\r\n$16\r\nZREVRANGEBYSCORE\r\n$4\r\nzset\r\n$1\r\n5\r\n$1\r\n1\r\n",
+			want: redis.NewZRangeCommand(
+				store, clock, "zset",
+				redis.ZRangeQuery{
+					By:      redis.ZRangeByScore,
+					Scores:  redis.ScoreRange{Min: 1, Max: 5},
+					Reverse: true,
+					Count:   -1,
+				},
+				false,
+			),
+		},
+		{
+			name:    "ZRANGEBYLEX zset - (c",
+			request: "*4\r\n$11\r\nZRANGEBYLEX\r\n$4\r\nzset\r\n$1\r\n-\r\n$2\r\n(c\r\n",
+			want: redis.NewZRangeCommand(
+				store, clock, "zset",
+				redis.ZRangeQuery{
+					By: redis.ZRangeByLex,
+					Lex: redis.LexRange{
+						Min: redis.LexBound{Infinity: -1},
+						Max: redis.LexBound{Value: "c", Exclusive: true},
+					},
+					Count: -1,
+				},
+				false,
+			),
+		},
+		{
+			name:    "ZCOUNT zset 1 2",
+			request: "*4\r\n$6\r\nZCOUNT\r\n$4\r\nzset\r\n$1\r\n1\r\n$1\r\n2\r\n",
+			want: redis.NewZCountCommand(
+				store, clock, "zset",
+				redis.ZRangeQuery{
+					By:     redis.ZRangeByScore,
+					Scores: redis.ScoreRange{Min: 1, Max: 2},
+					Count:  -1,
+				},
+			),
+		},
+		{
+			name:    "ZLEXCOUNT zset - +",
+			request: "*4\r\n$9\r\nZLEXCOUNT\r\n$4\r\nzset\r\n$1\r\n-\r\n$1\r\n+\r\n",
+			want: redis.NewZCountCommand(
+				store, clock, "zset",
+				redis.ZRangeQuery{
+					By: redis.ZRangeByLex,
+					Lex: redis.LexRange{
+						Min: redis.LexBound{Infinity: -1},
+						Max: redis.LexBound{Infinity: 1},
+					},
+					Count: -1,
+				},
+			),
+		},
+		{
+			name:    "ZREMRANGEBYRANK zset 0 1",
+			request: "*4\r\n$15\r\nZREMRANGEBYRANK\r\n$4\r\nzset\r\n$1\r\n0\r\n$1\r\n1\r\n",
+			want:    redis.NewZRemRangeCommand(store, clock, "zset", redis.NewZRangeQuery(0, 1)),
+		},
+		{
+			name:    "ZCARD zset",
+			request: "*2\r\n$5\r\nZCARD\r\n$4\r\nzset\r\n",
+			want:    redis.NewZCardCommand(store, clock, "zset"),
+		},
+		{
+			name:    "ZRANK zset a WITHSCORE",
+			request: "*4\r\n$5\r\nZRANK\r\n$4\r\nzset\r\n$1\r\na\r\n$9\r\nWITHSCORE\r\n",
+			want:    redis.NewZRankCommand(store, clock, "zset", "a", false, true),
+		},
+		{
+			name:    "ZREVRANK zset a",
+			request: "*3\r\n$8\r\nZREVRANK\r\n$4\r\nzset\r\n$1\r\na\r\n",
+			want:    redis.NewZRankCommand(store, clock, "zset", "a", true, false),
+		},
+		{
+			name:    "ZSCORE zset a",
+			request: "*3\r\n$6\r\nZSCORE\r\n$4\r\nzset\r\n$1\r\na\r\n",
+			want:    redis.NewZScoreCommand(store, clock, "zset", []string{"a"}),
+		},
+		{
+			name:    "ZMSCORE zset a b",
+			request: "*4\r\n$7\r\nZMSCORE\r\n$4\r\nzset\r\n$1\r\na\r\n$1\r\nb\r\n",
+			want:    redis.NewZMScoreCommand(store, clock, "zset", []string{"a", "b"}),
+		},
+		{
+			name:    "ZREM zset a b",
+			request: "*4\r\n$4\r\nZREM\r\n$4\r\nzset\r\n$1\r\na\r\n$1\r\nb\r\n",
+			want:    redis.NewZRemCommand(store, clock, "zset", []string{"a", "b"}),
+		},
+		{
+			name:    "ZPOPMIN zset",
+			request: "*2\r\n$7\r\nZPOPMIN\r\n$4\r\nzset\r\n",
+			want:    redis.NewZPopCommand(store, clock, "zset", redis.ZSetEndMin, 1),
+		},
+		{
+			name:    "ZPOPMAX zset 3",
+			request: "*3\r\n$7\r\nZPOPMAX\r\n$4\r\nzset\r\n$1\r\n3\r\n",
+			want:    redis.NewZPopCommand(store, clock, "zset", redis.ZSetEndMax, 3),
+		},
+		{
+			name:    "BZPOPMIN zset other 0.5",
+			request: "*4\r\n$8\r\nBZPOPMIN\r\n$4\r\nzset\r\n$5\r\nother\r\n$3\r\n0.5\r\n",
+			want: redis.NewBZPopCommand(
+				store, clock, nil, []string{"zset", "other"}, redis.ZSetEndMin,
+				500*time.Millisecond,
+			),
+		},
+		{
+			name: "ZUNION 2 a b WEIGHTS 2 3 AGGREGATE MAX WITHSCORES",
+			request: "*10\r\n$6\r\nZUNION\r\n$1\r\n2\r\n$1\r\na\r\n$1\r\nb\r\n" +
+				"$7\r\nWEIGHTS\r\n$1\r\n2\r\n$1\r\n3\r\n$9\r\nAGGREGATE\r\n" +
+				"$3\r\nMAX\r\n$10\r\nWITHSCORES\r\n",
+			want: redis.NewZSetOpCommand(
+				store, clock, redis.SetOpUnion, []string{"a", "b"},
+				redis.ZSetOpWeights([]float64{2, 3}), redis.ZSetOpAggregate(redis.ZAggregateMax),
+				redis.ZSetOpWithScores(),
+			),
+		},
+		{
+			name:    "ZINTERSTORE dest 2 a b",
+			request: "*5\r\n$11\r\nZINTERSTORE\r\n$4\r\ndest\r\n$1\r\n2\r\n$1\r\na\r\n$1\r\nb\r\n",
+			want: redis.NewZSetOpCommand(
+				store, clock, redis.SetOpInter, []string{"a", "b"}, redis.ZSetOpStore("dest"),
+			),
+		},
+		{
+			name:    "ZDIFF 1 a",
+			request: "*3\r\n$5\r\nZDIFF\r\n$1\r\n1\r\n$1\r\na\r\n",
+			want:    redis.NewZSetOpCommand(store, clock, redis.SetOpDiff, []string{"a"}),
+		},
+		{
+			name:    "ZRANDMEMBER zset -2 WITHSCORES",
+			request: "*4\r\n$11\r\nZRANDMEMBER\r\n$4\r\nzset\r\n$2\r\n-2\r\n$10\r\nWITHSCORES\r\n",
+			want: redis.NewZRandMemberCommand(
+				store, clock, "zset", redis.ZRandMemberCount(-2), redis.ZRandMemberWithScores(),
+			),
+		},
+		{
+			name: "ZSCAN zset 0 MATCH a* COUNT 5",
+			request: "*7\r\n$5\r\nZSCAN\r\n$4\r\nzset\r\n$1\r\n0\r\n$5\r\nMATCH\r\n" +
+				"$2\r\na*\r\n$5\r\nCOUNT\r\n$1\r\n5\r\n",
+			want: redis.NewZScanCommand(
+				store, clock, "zset", 0, redis.ZScanMatch("a*"), redis.ZScanCount(5),
+			),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requestReader := strings.NewReader(tt.request)
+
+			command, err := redis.NewParser(zeroValueRedisConfig, store, clock).Parse(requestReader)
+
+			if err != nil {
+				t.Errorf("err: expected: nil; got: %v", err)
+			}
+			if !reflect.DeepEqual(command, tt.want) {
+				t.Errorf("command expected to be %#v but was %#v", tt.want, command)
+			}
+		})
+	}
+}
+
+func TestParser_ParseInvalidZSetRequest(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		request string
+		err     redis.CommandError
+	}{
+		{
+			name:    "ZADD zset 1",
+			request: "*3\r\n$4\r\nZADD\r\n$4\r\nzset\r\n$1\r\n1\r\n",
+			err:     "ERR wrong number of arguments for 'zadd' command",
+		},
+		{
+			name:    "ZADD zset 1 a 2",
+			request: "*5\r\n$4\r\nZADD\r\n$4\r\nzset\r\n$1\r\n1\r\n$1\r\na\r\n$1\r\n2\r\n",
+			err:     "ERR syntax error",
+		},
+		{
+			name:    "ZADD zset one a",
+			request: "*4\r\n$4\r\nZADD\r\n$4\r\nzset\r\n$3\r\none\r\n$1\r\na\r\n",
+			err:     "ERR value is not a valid float",
+		},
+		{
+			name: "ZADD zset NX XX 1 a",
+			request: "*6\r\n$4\r\nZADD\r\n$4\r\nzset\r\n$2\r\nNX\r\n$2\r\nXX\r\n$1\r\n1\r\n" +
+				"$1\r\na\r\n",
+			err: "ERR XX and NX options at the same time are not compatible",
+		},
+		{
+			name: "ZADD zset GT LT 1 a",
+			request: "*6\r\n$4\r\nZADD\r\n$4\r\nzset\r\n$2\r\nGT\r\n$2\r\nLT\r\n$1\r\n1\r\n" +
+				"$1\r\na\r\n",
+			err: "ERR GT, LT, and/or NX options at the same time are not compatible",
+		},
+		{
+			name: "ZADD zset INCR 1 a 2 b",
+			request: "*7\r\n$4\r\nZADD\r\n$4\r\nzset\r\n$4\r\nINCR\r\n$1\r\n1\r\n$1\r\na\r\n" +
+				"$1\r\n2\r\n$1\r\nb\r\n",
+			err: "ERR INCR option supports a single increment-element pair",
+		},
+		{
+			name: "ZRANGE zset 0 1 LIMIT 0 1",
+			request: "*7\r\n$6\r\nZRANGE\r\n$4\r\nzset\r\n$1\r\n0\r\n$1\r\n1\r\n" +
+				"$5\r\nLIMIT\r\n$1\r\n0\r\n$1\r\n1\r\n",
+			err: "ERR syntax error, LIMIT is only supported in combination with either BYSCORE or BYLEX",
+		},
+		{
+			name: "ZRANGE zset - + BYLEX WITHSCORES",
+			request: "*6\r\n$6\r\nZRANGE\r\n$4\r\nzset\r\n$1\r\n-\r\n$1\r\n+\r\n" +
+				"$5\r\nBYLEX\r\n$10\r\nWITHSCORES\r\n",
+			err: "ERR syntax error, WITHSCORES not supported in combination with BYLEX",
+		},
+		{
+			name:    "ZRANGEBYSCORE zset a 1",
+			request: "*4\r\n$13\r\nZRANGEBYSCORE\r\n$4\r\nzset\r\n$1\r\na\r\n$1\r\n1\r\n",
+			err:     "ERR min or max is not a float",
+		},
+		{
+			name:    "ZRANGEBYLEX zset a b",
+			request: "*4\r\n$11\r\nZRANGEBYLEX\r\n$4\r\nzset\r\n$1\r\na\r\n$1\r\nb\r\n",
+			err:     "ERR min or max not valid string range item",
+		},
+		{
+			name:    "ZPOPMIN zset -1",
+			request: "*3\r\n$7\r\nZPOPMIN\r\n$4\r\nzset\r\n$2\r\n-1\r\n",
+			err:     "ERR value is out of range, must be positive",
+		},
+		{
+			name:    "ZUNION 0 a",
+			request: "*3\r\n$6\r\nZUNION\r\n$1\r\n0\r\n$1\r\na\r\n",
+			err:     "ERR at least 1 input key is needed for 'zunion' command",
+		},
+		{
+			name:    "ZUNION 3 a b",
+			request: "*4\r\n$6\r\nZUNION\r\n$1\r\n3\r\n$1\r\na\r\n$1\r\nb\r\n",
+			err:     "ERR syntax error",
+		},
+		{
+			name: "ZUNION 2 a b WEIGHTS 1 x",
+			request: "*7\r\n$6\r\nZUNION\r\n$1\r\n2\r\n$1\r\na\r\n$1\r\nb\r\n" +
+				"$7\r\nWEIGHTS\r\n$1\r\n1\r\n$1\r\nx\r\n",
+			err: "ERR weight value is not a float",
+		},
+		{
+			name: "ZDIFF 2 a b WEIGHTS 1 2",
+			request: "*7\r\n$5\r\nZDIFF\r\n$1\r\n2\r\n$1\r\na\r\n$1\r\nb\r\n" +
+				"$7\r\nWEIGHTS\r\n$1\r\n1\r\n$1\r\n2\r\n",
+			err: "ERR syntax error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := redis.NewStore()
+			clock := FakeClock{}
+			requestReader := strings.NewReader(tt.request)
+
+			_, err := redis.NewParser(zeroValueRedisConfig, store, clock).Parse(requestReader)
+
+			if err != tt.err {
+				t.Errorf("err: expected: %v; got: %v", tt.err, err)
+			}
+		})
+	}
+}
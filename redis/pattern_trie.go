@@ -0,0 +1,69 @@
+package redis
+
+// patternTrie indexes glob-style patterns by their literal prefixes, so that the patterns that
+// match a string can be found without testing every pattern, only those whose literal prefix
+// the string starts with.
+type patternTrie struct {
+	children map[byte]*patternTrie
+	// patterns are the patterns whose literal prefix leads to this node.
+	patterns map[string]struct{}
+}
+
+func newPatternTrie() *patternTrie {
+	return &patternTrie{}
+}
+
+func (t *patternTrie) add(pattern string) {
+	node := t
+	prefix := globPrefix(pattern)
+	for i := 0; i < len(prefix); i++ {
+		if node.children == nil {
+			node.children = make(map[byte]*patternTrie)
+		}
+		child, ok := node.children[prefix[i]]
+		if !ok {
+			child = &patternTrie{}
+			node.children[prefix[i]] = child
+		}
+		node = child
+	}
+	if node.patterns == nil {
+		node.patterns = make(map[string]struct{})
+	}
+	node.patterns[pattern] = struct{}{}
+}
+
+func (t *patternTrie) remove(pattern string) {
+	t.removeAt(pattern, globPrefix(pattern))
+}
+
+// removeAt removes pattern from the node that prefix leads to from t, pruning the nodes left
+// empty, and returns true if t is left empty.
+func (t *patternTrie) removeAt(pattern string, prefix string) bool {
+	if prefix == "" {
+		delete(t.patterns, pattern)
+	} else if child, ok := t.children[prefix[0]]; ok && child.removeAt(pattern, prefix[1:]) {
+		delete(t.children, prefix[0])
+	}
+	return len(t.patterns) == 0 && len(t.children) == 0
+}
+
+// match calls fn with each pattern that matches s.
+func (t *patternTrie) match(s string, fn func(pattern string)) {
+	node := t
+	for i := 0; ; i++ {
+		for pattern := range node.patterns {
+			if globMatch(pattern, s) {
+				fn(pattern)
+			}
+		}
+		if i == len(s) {
+			return
+		}
+		child, ok := node.children[s[i]]
+		if !ok {
+			return
+		}
+		node = child
+	}
+}
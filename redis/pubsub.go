@@ -0,0 +1,272 @@
+package redis
+
+import (
+	"sort"
+	"sync"
+)
+
+// subscriptionKind is what a subscription subscribes a client to.
+type subscriptionKind int
+
+const (
+	// channelSubscription is a subscription of SUBSCRIBE to a channel.
+	channelSubscription subscriptionKind = iota
+	// patternSubscription is a subscription of PSUBSCRIBE to the channels that match a
+	// glob-style pattern.
+	patternSubscription
+	// shardChannelSubscription is a subscription of SSUBSCRIBE to a shard channel, which hashes
+	// to a cluster slot like a key.
+	shardChannelSubscription
+	subscriptionKinds
+)
+
+// names returns the names of the replies to subscribing and unsubscribing with k, and of the
+// messages pushed to its subscribers.
+func (k subscriptionKind) names() (subscribe string, unsubscribe string, message string) {
+	switch k {
+	case patternSubscription:
+		return "psubscribe", "punsubscribe", "pmessage"
+	case shardChannelSubscription:
+		return "ssubscribe", "sunsubscribe", "smessage"
+	}
+	return "subscribe", "unsubscribe", "message"
+}
+
+func NewPubSub(clock Clock, limit OutputBufferLimit) *PubSub {
+	result := &PubSub{
+		clock:       clock,
+		limit:       limit,
+		patternTrie: newPatternTrie(),
+		subscribers: make(map[*Client]*subscriber),
+	}
+	for kind := range result.subscriptions {
+		result.subscriptions[kind] = make(map[string]map[*Client]struct{})
+	}
+	return result
+}
+
+// PubSub is the broker of publish/subscribe, which pushes the messages published to a channel
+// to the clients subscribed to it, or to a glob-style pattern that matches it, and those
+// published to a shard channel to the clients subscribed to it. A client that falls too far
+// behind on its messages, by limit, is closed rather than making publishers wait for it.
+type PubSub struct {
+	clock Clock
+	limit OutputBufferLimit
+
+	mu sync.Mutex
+	// subscriptions are, for each kind, the clients subscribed to each channel, pattern or shard
+	// channel with at least one subscriber.
+	subscriptions [subscriptionKinds]map[string]map[*Client]struct{}
+	// patternTrie indexes the patterns with at least one subscriber.
+	patternTrie *patternTrie
+	subscribers map[*Client]*subscriber
+}
+
+// subscriber is what a client with at least one subscription is subscribed to.
+type subscriber struct {
+	subscriptions [subscriptionKinds]map[string]struct{}
+}
+
+// count returns the number of subscriptions that the replies to subscribing and unsubscribing
+// with kind count: those to shard channels, or else those to channels and patterns.
+func (s *subscriber) count(kind subscriptionKind) int {
+	if s == nil {
+		return 0
+	}
+	if kind == shardChannelSubscription {
+		return len(s.subscriptions[shardChannelSubscription])
+	}
+	return len(s.subscriptions[channelSubscription]) + len(s.subscriptions[patternSubscription])
+}
+
+// subscribed returns true if client is subscribed to anything, which puts it in subscribed mode.
+func (p *PubSub) subscribed(client *Client) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	_, ok := p.subscribers[client]
+	return ok
+}
+
+// subscriptionCount returns client's number of subscriptions that the replies to subscribing and
+// unsubscribing with kind count.
+func (p *PubSub) subscriptionCount(client *Client, kind subscriptionKind) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.subscribers[client].count(kind)
+}
+
+// subscribe subscribes client to the channel, pattern or shard channel name, and returns its
+// number of subscriptions that the reply counts.
+func (p *PubSub) subscribe(client *Client, kind subscriptionKind, name string) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	s, ok := p.subscribers[client]
+	if !ok {
+		s = &subscriber{}
+		for kind := range s.subscriptions {
+			s.subscriptions[kind] = make(map[string]struct{})
+		}
+		p.subscribers[client] = s
+		client.mu.Lock()
+		client.pubSub = p
+		client.mu.Unlock()
+	}
+	s.subscriptions[kind][name] = struct{}{}
+
+	clients, ok := p.subscriptions[kind][name]
+	if !ok {
+		clients = make(map[*Client]struct{})
+		p.subscriptions[kind][name] = clients
+		if kind == patternSubscription {
+			p.patternTrie.add(name)
+		}
+	}
+	clients[client] = struct{}{}
+	return s.count(kind)
+}
+
+// unsubscribe unsubscribes client from the channel, pattern or shard channel name, and returns
+// its number of subscriptions that the reply counts.
+func (p *PubSub) unsubscribe(client *Client, kind subscriptionKind, name string) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	s := p.subscribers[client]
+	if s == nil {
+		return 0
+	}
+	delete(s.subscriptions[kind], name)
+	if clients, ok := p.subscriptions[kind][name]; ok {
+		delete(clients, client)
+		if len(clients) == 0 {
+			delete(p.subscriptions[kind], name)
+			if kind == patternSubscription {
+				p.patternTrie.remove(name)
+			}
+		}
+	}
+	if s.count(channelSubscription) == 0 && s.count(shardChannelSubscription) == 0 {
+		delete(p.subscribers, client)
+	}
+	return s.count(kind)
+}
+
+// subscribedTo returns the channels, patterns or shard channels that client is subscribed to,
+// in order.
+func (p *PubSub) subscribedTo(client *Client, kind subscriptionKind) []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	s := p.subscribers[client]
+	if s == nil {
+		return nil
+	}
+	return sortedKeys(s.subscriptions[kind])
+}
+
+// unsubscribeAll unsubscribes client from everything.
+func (p *PubSub) unsubscribeAll(client *Client) {
+	for kind := subscriptionKind(0); kind < subscriptionKinds; kind++ {
+		for _, name := range p.subscribedTo(client, kind) {
+			p.unsubscribe(client, kind, name)
+		}
+	}
+}
+
+// publish pushes message to the clients subscribed to channel, or to a pattern that matches it,
+// and returns how many messages were pushed: a client subscribed to several patterns that match
+// is pushed a message for each.
+func (p *PubSub) publish(channel string, message string) int {
+	return p.push(channel, message, func(push func(kind subscriptionKind, name string)) {
+		push(channelSubscription, channel)
+		p.patternTrie.match(channel, func(pattern string) {
+			push(patternSubscription, pattern)
+		})
+	})
+}
+
+// publishShard pushes message to the clients subscribed to the shard channel, and returns how
+// many there are.
+func (p *PubSub) publishShard(channel string, message string) int {
+	return p.push(channel, message, func(push func(kind subscriptionKind, name string)) {
+		push(shardChannelSubscription, channel)
+	})
+}
+
+// push pushes message, published to channel, to the subscribers of each subscription that
+// receivers calls its argument with, and returns how many messages were pushed.
+func (p *PubSub) push(
+	channel string,
+	message string,
+	receivers func(push func(kind subscriptionKind, name string)),
+) int {
+	p.mu.Lock()
+	now := p.clock.NowMonotonic()
+	var overLimit []*Client
+	pushed := 0
+	receivers(func(kind subscriptionKind, name string) {
+		_, _, messageKind := kind.names()
+		elements := []string{bulkString(messageKind), bulkString(name)}
+		if kind == patternSubscription {
+			elements = append(elements, bulkString(channel))
+		}
+		elements = append(elements, bulkString(message))
+		for client := range p.subscriptions[kind][name] {
+			if !client.push(client.pushMessage(elements...), p.limit, now) {
+				overLimit = append(overLimit, client)
+			}
+			pushed++
+		}
+	})
+	p.mu.Unlock()
+
+	// Closing a client unsubscribes it, so it has to wait until p is unlocked.
+	for _, client := range overLimit {
+		client.Close()
+	}
+	return pushed
+}
+
+// active returns the channels or shard channels with at least one subscriber that match the
+// glob-style pattern, in order.
+func (p *PubSub) active(kind subscriptionKind, pattern string) []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var result []string
+	for name := range p.subscriptions[kind] {
+		if globMatch(pattern, name) {
+			result = append(result, name)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// patternCount returns the number of patterns with at least one subscriber.
+func (p *PubSub) patternCount() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return len(p.subscriptions[patternSubscription])
+}
+
+// subscriberCount returns the number of clients subscribed to the channel or shard channel.
+func (p *PubSub) subscriberCount(kind subscriptionKind, channel string) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return len(p.subscriptions[kind][channel])
+}
+
+func sortedKeys(set map[string]struct{}) []string {
+	result := make([]string, 0, len(set))
+	for key := range set {
+		result = append(result, key)
+	}
+	sort.Strings(result)
+	return result
+}
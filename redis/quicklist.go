@@ -0,0 +1,246 @@
+package redis
+
+// quicklistNodeSize is the most elements a quicklist node holds. Like Redis's listpack nodes,
+// it keeps nodes small enough that inserting into or removing from the middle of one is cheap.
+const quicklistNodeSize = 128
+
+// quicklist is the list data type: a doubly linked list of nodes that each hold a small slice
+// of elements, after Redis's quicklist. Pushing and popping at either end is O(1), and walking
+// the list only has to follow one pointer per node rather than one per element.
+type quicklist struct {
+	head   *quicklistNode
+	tail   *quicklistNode
+	length int
+}
+
+type quicklistNode struct {
+	prev     *quicklistNode
+	next     *quicklistNode
+	elements []string
+}
+
+func newQuicklist() *quicklist {
+	return &quicklist{}
+}
+
+func (q *quicklist) len() int {
+	return q.length
+}
+
+func (q *quicklist) pushFront(element string) {
+	if q.head == nil || len(q.head.elements) >= quicklistNodeSize {
+		q.insertNodeAfter(nil, &quicklistNode{})
+	}
+	q.head.elements = append(q.head.elements, "")
+	copy(q.head.elements[1:], q.head.elements)
+	q.head.elements[0] = element
+	q.length++
+}
+
+func (q *quicklist) pushBack(element string) {
+	if q.tail == nil || len(q.tail.elements) >= quicklistNodeSize {
+		q.insertNodeAfter(q.tail, &quicklistNode{})
+	}
+	q.tail.elements = append(q.tail.elements, element)
+	q.length++
+}
+
+// popFront removes and returns the first element. The list must not be empty.
+func (q *quicklist) popFront() string {
+	element := q.head.elements[0]
+	q.removeAt(q.head, 0)
+	return element
+}
+
+// popBack removes and returns the last element. The list must not be empty.
+func (q *quicklist) popBack() string {
+	element := q.tail.elements[len(q.tail.elements)-1]
+	q.removeAt(q.tail, len(q.tail.elements)-1)
+	return element
+}
+
+// index returns the element at index i, which must be in [0, len()).
+func (q *quicklist) index(i int) string {
+	node, offset := q.find(i)
+	return node.elements[offset]
+}
+
+// set replaces the element at index i, which must be in [0, len()).
+func (q *quicklist) set(i int, element string) {
+	node, offset := q.find(i)
+	node.elements[offset] = element
+}
+
+// find returns the node holding the element at index i, and the element's offset in that node.
+// It walks from whichever end of the list is closer.
+func (q *quicklist) find(i int) (*quicklistNode, int) {
+	if i < q.length/2 {
+		node := q.head
+		for i >= len(node.elements) {
+			i -= len(node.elements)
+			node = node.next
+		}
+		return node, i
+	}
+	node := q.tail
+	i = q.length - 1 - i
+	for i >= len(node.elements) {
+		i -= len(node.elements)
+		node = node.prev
+	}
+	return node, len(node.elements) - 1 - i
+}
+
+// each calls fn with each element and its index, from first to last, until fn returns false.
+func (q *quicklist) each(fn func(i int, element string) bool) {
+	i := 0
+	for node := q.head; node != nil; node = node.next {
+		for _, element := range node.elements {
+			if !fn(i, element) {
+				return
+			}
+			i++
+		}
+	}
+}
+
+// eachReverse calls fn with each element and its index, from last to first, until fn returns
+// false.
+func (q *quicklist) eachReverse(fn func(i int, element string) bool) {
+	i := q.length - 1
+	for node := q.tail; node != nil; node = node.prev {
+		for j := len(node.elements) - 1; j >= 0; j-- {
+			if !fn(i, node.elements[j]) {
+				return
+			}
+			i--
+		}
+	}
+}
+
+// slice returns the elements from index start to index stop, inclusive, which must be in
+// [0, len()).
+func (q *quicklist) slice(start, stop int) []string {
+	result := make([]string, 0, stop-start+1)
+	node, offset := q.find(start)
+	for len(result) < stop-start+1 {
+		end := offset + (stop - start + 1 - len(result))
+		if end > len(node.elements) {
+			end = len(node.elements)
+		}
+		result = append(result, node.elements[offset:end]...)
+		node, offset = node.next, 0
+	}
+	return result
+}
+
+// insert inserts element at index i, which must be in [0, len()], shifting the element at that
+// index and every element after it along by one.
+func (q *quicklist) insert(i int, element string) {
+	switch i {
+	case 0:
+		q.pushFront(element)
+		return
+	case q.length:
+		q.pushBack(element)
+		return
+	}
+
+	node, offset := q.find(i)
+	if len(node.elements) >= quicklistNodeSize {
+		// Split the full node in half so there's room for the new element.
+		half := len(node.elements) / 2
+		newNode := &quicklistNode{elements: append([]string(nil), node.elements[half:]...)}
+		node.elements = node.elements[:half:half]
+		q.insertNodeAfter(node, newNode)
+		if offset >= half {
+			node, offset = newNode, offset-half
+		}
+	}
+	node.elements = append(node.elements, "")
+	copy(node.elements[offset+1:], node.elements[offset:])
+	node.elements[offset] = element
+	q.length++
+}
+
+// remove removes up to count elements equal to element, from first to last if count is
+// positive, from last to first if it's negative, or every such element if it's 0. It returns
+// the number of elements removed.
+func (q *quicklist) remove(element string, count int) int {
+	removed := 0
+	if count >= 0 {
+		for node := q.head; node != nil; {
+			next := node.next
+			for j := 0; j < len(node.elements); {
+				if node.elements[j] != element || (count > 0 && removed == count) {
+					j++
+					continue
+				}
+				q.removeAt(node, j)
+				removed++
+			}
+			node = next
+		}
+		return removed
+	}
+
+	for node := q.tail; node != nil; {
+		prev := node.prev
+		for j := len(node.elements) - 1; j >= 0; j-- {
+			if node.elements[j] == element && removed < -count {
+				q.removeAt(node, j)
+				removed++
+			}
+		}
+		node = prev
+	}
+	return removed
+}
+
+// trim removes every element before index start and after index stop.
+func (q *quicklist) trim(start, stop int) {
+	back := q.length - 1 - stop
+	for i := 0; i < start && q.length > 0; i++ {
+		q.popFront()
+	}
+	for i := 0; i < back && q.length > 0; i++ {
+		q.popBack()
+	}
+}
+
+// removeAt removes the element at offset in node, removing node from the list if it's now
+// empty.
+func (q *quicklist) removeAt(node *quicklistNode, offset int) {
+	node.elements = append(node.elements[:offset], node.elements[offset+1:]...)
+	q.length--
+	if len(node.elements) > 0 {
+		return
+	}
+	if node.prev != nil {
+		node.prev.next = node.next
+	} else {
+		q.head = node.next
+	}
+	if node.next != nil {
+		node.next.prev = node.prev
+	} else {
+		q.tail = node.prev
+	}
+}
+
+// insertNodeAfter inserts newNode after node, or at the front of the list if node is nil.
+func (q *quicklist) insertNodeAfter(node, newNode *quicklistNode) {
+	newNode.prev = node
+	if node == nil {
+		newNode.next = q.head
+		q.head = newNode
+	} else {
+		newNode.next = node.next
+		node.next = newNode
+	}
+	if newNode.next != nil {
+		newNode.next.prev = newNode
+	} else {
+		q.tail = newNode
+	}
+}
@@ -0,0 +1,62 @@
+package redis
+
+import (
+	"hash/fnv"
+	"sort"
+	"strconv"
+)
+
+// defaultScanCount is how many elements SCAN-style commands return per call without a COUNT.
+const defaultScanCount = 10
+
+// scanPage returns the page of a SCAN-style iteration over names that starts at cursor, along
+// with the cursor that the next page starts at, which is 0 when the iteration is complete.
+//
+// Names are visited in the order of their hashes, and a cursor is the hash to carry on from.
+// So, without keeping any state between calls, an iteration returns every name that's present
+// for the whole of it exactly once, however other names come and go in the meantime.
+func scanPage(names []string, cursor uint64, count int) (page []string, next uint64) {
+	type hashedName struct {
+		name string
+		hash uint64
+	}
+	hashed := make([]hashedName, 0, len(names))
+	for _, name := range names {
+		if h := scanHash(name); h >= cursor {
+			hashed = append(hashed, hashedName{name: name, hash: h})
+		}
+	}
+	sort.Slice(hashed, func(i, j int) bool {
+		return hashed[i].hash < hashed[j].hash
+	})
+
+	for i, h := range hashed {
+		// Never end a page between names with the same hash, since the cursor can't tell them
+		// apart.
+		if len(page) >= count && h.hash != hashed[i-1].hash {
+			return page, h.hash
+		}
+		page = append(page, h.name)
+	}
+	return page, 0
+}
+
+// scanHash returns the hash of name that scanPage orders it by. It's never 0, since a cursor of
+// 0 ends an iteration.
+func scanHash(name string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(name))
+	if sum := h.Sum64(); sum != 0 {
+		return sum
+	}
+	return 1
+}
+
+// parseScanCursor parses the cursor argument of a SCAN-style command.
+func parseScanCursor(s string) (uint64, error) {
+	cursor, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, CommandError("ERR invalid cursor")
+	}
+	return cursor, nil
+}
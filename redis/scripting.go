@@ -0,0 +1,660 @@
+package redis
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/lua"
+)
+
+func NewScripts() *Scripts {
+	return &Scripts{
+		cache:     make(map[string]*lua.FunctionProto),
+		libraries: make(map[string]*luaLibrary),
+		functions: make(map[string]*luaFunction),
+	}
+}
+
+// Scripts is the cache of the Lua scripts that EVAL and SCRIPT LOAD compiled, by their SHA1
+// digests, the function libraries that FUNCTION LOAD loaded, and the state of the script or
+// function that's running, if any, which every client shares.
+type Scripts struct {
+	mu    sync.Mutex
+	cache map[string]*lua.FunctionProto
+	// libraries are the function libraries by their names, and functions the functions that
+	// they registered by theirs.
+	libraries map[string]*luaLibrary
+	functions map[string]*luaFunction
+	running   *runningScript
+}
+
+// runningScript is the state of a running script or function.
+type runningScript struct {
+	start time.Time
+	// function is true for a function, which FUNCTION KILL kills rather than SCRIPT KILL.
+	function bool
+	// noWrites is true for a function with the no-writes flag, which can't write to the keyspace.
+	noWrites bool
+	// killed is set by SCRIPT KILL or FUNCTION KILL, which close done, making the script fail
+	// before its next instruction.
+	killed bool
+	done   chan struct{}
+	// wrote is true once the script has changed a key, after which it can't be killed.
+	wrote bool
+	// resp is the version of RESP that the replies of the commands that the script calls are
+	// converted from, which redis.setresp sets.
+	resp int
+}
+
+func newRunningScript(start time.Time, function, noWrites bool) *runningScript {
+	return &runningScript{
+		start:    start,
+		function: function,
+		noWrites: noWrites,
+		done:     make(chan struct{}),
+		resp:     2,
+	}
+}
+
+// killedError returns the error that the script fails with once it's killed.
+func (r *runningScript) killedError() CommandError {
+	if r.function {
+		return "ERR Script killed by user with FUNCTION KILL..."
+	}
+	return "ERR Script killed by user with SCRIPT KILL..."
+}
+
+// kill makes the script fail with killedError, unless it already has been killed.
+func (r *runningScript) kill() {
+	if !r.killed {
+		r.killed = true
+		close(r.done)
+	}
+}
+
+// luaContext is the context of the Lua state that a script or function runs in. Lua checks
+// whether it's done before every instruction, and raises its error if it is, again and again,
+// so that pcall can't catch it.
+type luaContext struct {
+	context.Context
+	done func() <-chan struct{}
+	err  error
+}
+
+func (c luaContext) Done() <-chan struct{} {
+	return c.done()
+}
+
+func (c luaContext) Err() error {
+	return c.err
+}
+
+// closedChannel is a channel that's always closed, for a luaContext that's done.
+var closedChannel = func() chan struct{} {
+	c := make(chan struct{})
+	close(c)
+	return c
+}()
+
+func scriptSHA1(script string) string {
+	sum := sha1.Sum([]byte(script))
+	return hex.EncodeToString(sum[:])
+}
+
+// load compiles script and adds it to the cache, if it isn't there already, returning its SHA1
+// digest.
+func (s *Scripts) load(script string) (string, *lua.FunctionProto, error) {
+	sha := scriptSHA1(script)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if proto, ok := s.cache[sha]; ok {
+		return sha, proto, nil
+	}
+	proto, err := compileLua(script, luaScriptChunk)
+	if err != nil {
+		return "", nil, CommandError("ERR Error compiling script (new function): " + err.Error())
+	}
+	s.cache[sha] = proto
+	return sha, proto, nil
+}
+
+// get returns the script with the SHA1 digest sha from the cache.
+func (s *Scripts) get(sha string) (*lua.FunctionProto, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	proto, ok := s.cache[strings.ToLower(sha)]
+	return proto, ok
+}
+
+// flush empties the cache.
+func (s *Scripts) flush() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.cache = make(map[string]*lua.FunctionProto)
+}
+
+// kill kills the running script, or function if function is true, unless it has already written
+// to the keyspace.
+func (s *Scripts) kill(function bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case s.running == nil || s.running.function != function:
+		return errNotBusy
+	case s.running.wrote:
+		return errUnkillable
+	}
+	s.running.kill()
+	return nil
+}
+
+// stop kills the running script or function, if any, even if it has already written to the
+// keyspace, since the server is shutting down.
+func (s *Scripts) stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.running != nil {
+		s.running.kill()
+	}
+}
+
+// busy returns an error if a script or function has been running for longer than threshold at
+// now, so that other clients' commands are rejected rather than waiting for it. A zero threshold
+// disables it.
+func (s *Scripts) busy(now time.Time, threshold time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case threshold == 0 || s.running == nil || now.Sub(s.running.start) <= threshold:
+		return nil
+	case s.running.function:
+		return errBusyFunction
+	}
+	return errBusy
+}
+
+// runScript runs the script proto, whose SHA1 digest is sha, with keys and args on behalf of the
+// client that p parses requests for, returning its reply. p's Store must already be locked
+// exclusively, so that the commands that the script calls run without any others in between.
+func (s *Scripts) runScript(
+	p Parser, sha string, proto *lua.FunctionProto, keys, args []string,
+) string {
+	L := newLuaState()
+	defer L.Close()
+	L.SetGlobal("KEYS", luaStringArray(L, keys))
+	L.SetGlobal("ARGV", luaStringArray(L, args))
+	library := L.GetGlobal("redis").(*lua.LTable)
+	protectLuaGlobals(L)
+	state := newRunningScript(p.clock.NowMonotonic(), false, false)
+	return s.run(p, L, library, state, sha, L.NewFunctionFromProto(proto))
+}
+
+// run calls fn with args in the Lua state L on behalf of the client that p parses requests for,
+// returning the reply to what it returns. The functions of library, L's redis table, are bound
+// to the client and state. Errors say that they were raised in name, the script's SHA1 digest or
+// the function's name. p's Store must already be locked exclusively.
+func (s *Scripts) run(
+	p Parser, L *lua.LState, library *lua.LTable, state *runningScript, name string,
+	fn *lua.LFunction, args ...lua.LValue,
+) string {
+	s.mu.Lock()
+	s.running = state
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.running = nil
+		s.mu.Unlock()
+	}()
+
+	p.store = p.store.forTransaction()
+	p.store.noWrites = state.noWrites
+	s.openRedisLibrary(L, library, p, state)
+	L.SetContext(luaContext{
+		Context: context.Background(),
+		done:    func() <-chan struct{} { return state.done },
+		err:     state.killedError(),
+	})
+	defer L.RemoveContext()
+
+	// The handler finds the line that raised an error, before the stack unwinds.
+	var line int
+	handler := L.NewFunction(func(L *lua.LState) int {
+		line = luaErrorLine(L)
+		return 1
+	})
+	L.Push(fn)
+	for _, arg := range args {
+		L.Push(arg)
+	}
+	if err := L.PCall(len(args), 1, handler); err != nil {
+		s.mu.Lock()
+		killed := state.killed
+		s.mu.Unlock()
+		var value lua.LValue = lua.LString(err.Error())
+		if apiErr, ok := err.(*lua.ApiError); ok {
+			value = apiErr.Object
+		}
+		if killed {
+			value = luaErrorReply(L, string(state.killedError()))
+		}
+		chunk := luaScriptChunk
+		if state.function {
+			chunk = luaFunctionChunk
+		}
+		return scriptErrorResponse(value, name, chunk, line)
+	}
+	value := L.Get(-1)
+	L.Pop(1)
+	return luaToResponse(value, p.protocol(), state.resp)
+}
+
+// luaErrorLine returns the line of the Lua code that called the function running in L, or 0 if
+// there's none.
+func luaErrorLine(L *lua.LState) int {
+	for level := 1; ; level++ {
+		dbg, ok := L.GetStack(level)
+		if !ok {
+			return 0
+		}
+		if _, err := L.GetInfo("l", dbg, lua.LNil); err == nil && dbg.CurrentLine > 0 {
+			return dbg.CurrentLine
+		}
+	}
+}
+
+// openRedisLibrary adds the functions that call commands on behalf of the script to the redis
+// table library, replacing those of any previous run.
+func (s *Scripts) openRedisLibrary(
+	L *lua.LState, library *lua.LTable, p Parser, state *runningScript,
+) {
+	library.RawSetString("register_function", lua.LNil)
+	L.SetFuncs(library, map[string]lua.LGFunction{
+		"call": func(L *lua.LState) int {
+			return s.call(L, p, state, true)
+		},
+		"pcall": func(L *lua.LState) int {
+			return s.call(L, p, state, false)
+		},
+		"setresp": func(L *lua.LState) int {
+			if L.GetTop() != 1 {
+				L.RaiseError("redis.setresp() requires one argument.")
+			}
+			resp := L.CheckInt(1)
+			if resp != 2 && resp != 3 {
+				L.RaiseError("RESP version must be 2 or 3.")
+			}
+			state.resp = resp
+			return 0
+		},
+		"acl_check_cmd": func(L *lua.LState) int {
+			request := luaCommandArguments(L)
+			if request == nil {
+				L.RaiseError("Please specify at least one argument for this redis lib call")
+			}
+			if _, err := p.newCommand(request); err == unknownCommandError(request) {
+				L.RaiseError("Invalid command passed to redis.acl_check_cmd()")
+			}
+			// There are no ACLs, so every user can run every command.
+			L.Push(lua.LTrue)
+			return 1
+		},
+	})
+	openLuaRedisLibrary(L, library)
+}
+
+// openLuaRedisLibrary adds the functions and constants of the redis table that don't call
+// commands, which libraries can use while they're loaded, too.
+func openLuaRedisLibrary(L *lua.LState, library *lua.LTable) {
+	L.SetFuncs(library, map[string]lua.LGFunction{
+		"error_reply": func(L *lua.LState) int {
+			L.Push(luaErrorReply(L, L.CheckString(1)))
+			return 1
+		},
+		"status_reply": func(L *lua.LState) int {
+			L.Push(luaStatusReply(L, L.CheckString(1)))
+			return 1
+		},
+		"sha1hex": func(L *lua.LState) int {
+			L.Push(lua.LString(scriptSHA1(L.CheckString(1))))
+			return 1
+		},
+		// There's no log file for scripts to write to.
+		"log": func(L *lua.LState) int {
+			if L.GetTop() < 2 {
+				L.RaiseError("redis.log() requires two arguments or more.")
+			}
+			if _, ok := L.Get(1).(lua.LNumber); !ok {
+				L.RaiseError("First argument must be a number (log level).")
+			}
+			return 0
+		},
+		// Scripts' effects are always replicated as the commands they call.
+		"set_repl": func(L *lua.LState) int {
+			if L.GetTop() != 1 {
+				L.RaiseError("redis.set_repl() requires one argument.")
+			}
+			if flags := L.CheckInt(1); flags < 0 || flags > 3 {
+				L.RaiseError("Invalid replication flags. Use REPL_AOF, REPL_REPLICA, REPL_ALL " +
+					"or REPL_NONE.")
+			}
+			return 0
+		},
+		"replicate_commands": func(L *lua.LState) int {
+			L.Push(lua.LTrue)
+			return 1
+		},
+		// There's no debugger to stop at breakpoints in.
+		"breakpoint": func(L *lua.LState) int {
+			L.Push(lua.LFalse)
+			return 1
+		},
+		"debug": func(L *lua.LState) int {
+			return 0
+		},
+	})
+	for i, level := range []string{"LOG_DEBUG", "LOG_VERBOSE", "LOG_NOTICE", "LOG_WARNING"} {
+		library.RawSetString(level, lua.LNumber(i))
+	}
+	for name, flags := range map[string]int{
+		"REPL_NONE": 0, "REPL_AOF": 1, "REPL_SLAVE": 2, "REPL_REPLICA": 2, "REPL_ALL": 3,
+	} {
+		library.RawSetString(name, lua.LNumber(flags))
+	}
+	library.RawSetString("REDIS_VERSION", lua.LString(redisVersion))
+	library.RawSetString("REDIS_VERSION_NUM", lua.LNumber(redisVersionNumber()))
+}
+
+// redisVersionNumber returns redisVersion as a number, 0x00MMmmpp for version MM.mm.pp.
+func redisVersionNumber() int {
+	n := 0
+	for _, part := range strings.SplitN(redisVersion, ".", 3) {
+		i, _ := strconv.Atoi(part)
+		n = n<<8 | i
+	}
+	return n
+}
+
+// call runs the command whose name and arguments are the arguments of redis.call or redis.pcall,
+// pushing its reply converted to a Lua value. If the command fails, it raises the error if raise
+// is true, like redis.call, or else returns it, like redis.pcall.
+func (s *Scripts) call(L *lua.LState, p Parser, state *runningScript, raise bool) int {
+	var response string
+	request := luaCommandArguments(L)
+	switch {
+	case request == nil:
+		response = simpleError("ERR Please specify at least one argument for this redis lib call")
+	case len(request) < L.GetTop():
+		response = simpleError("ERR Lua redis lib command arguments must be strings or integers")
+	case !allowedInScript(request[0]):
+		response = simpleError("ERR This Redis command is not allowed from script")
+	default:
+		response = s.runCommand(p, state, request)
+	}
+
+	value, _ := luaFromResponse(L, response, 0, state.resp)
+	if reply, ok := value.(*lua.LTable); ok && raise && reply.RawGetString("err") != lua.LNil {
+		L.Error(reply, 0)
+	}
+	L.Push(value)
+	return 1
+}
+
+// runCommand runs the command of request for the script with the state, returning its reply.
+func (s *Scripts) runCommand(p Parser, state *runningScript, request []string) string {
+	command, err := p.newCommand(request)
+	switch {
+	case err == unknownCommandError(request):
+		return simpleError("ERR Unknown Redis command called from script")
+	case err != nil:
+		return errorResponse(err)
+	}
+	dirty := p.store.dirty
+	response := runScriptCommand(command)
+	if p.store.dirty != dirty {
+		s.mu.Lock()
+		state.wrote = true
+		s.mu.Unlock()
+	}
+	return response
+}
+
+// luaCommandArguments returns the arguments of the Lua function running in L, which are the name
+// and arguments of a command, up to the first that isn't a string or a number.
+func luaCommandArguments(L *lua.LState) []string {
+	var request []string
+	for i := 1; i <= L.GetTop(); i++ {
+		switch arg := L.Get(i).(type) {
+		case lua.LString:
+			request = append(request, string(arg))
+		case lua.LNumber:
+			request = append(request, luaFormatArgument(arg))
+		default:
+			return request
+		}
+	}
+	return request
+}
+
+// runScriptCommand runs command, which a script called, replying with an error if it writes
+// to a Store that the script can't write to.
+func runScriptCommand(command Command) (response string) {
+	defer func() {
+		if r := recover(); r != nil {
+			if r != errWriteFromReadOnlyScript {
+				panic(r)
+			}
+			response = errorResponse(errWriteFromReadOnlyScript)
+		}
+	}()
+	return command.Run()
+}
+
+// luaFormatArgument formats a number passed to redis.call, as an integer if it is one.
+func luaFormatArgument(n lua.LNumber) string {
+	if luaIsInteger(n) && math.Abs(float64(n)) < 1e15 {
+		return strconv.FormatInt(int64(n), 10)
+	}
+	return strconv.FormatFloat(float64(n), 'g', 17, 64)
+}
+
+// luaFromResponse converts the RESP reply that starts at offset i of response to a Lua value,
+// returning the offset after it. Like Redis, integers become numbers, bulk strings strings,
+// arrays tables, and status and error replies tables with an ok or err field. Nulls become
+// false, unless resp is 3, when they become nil, like RESP3's other types become what they do in
+// Redis.
+func luaFromResponse(L *lua.LState, response string, i int, resp int) (lua.LValue, int) {
+	end := i + strings.Index(response[i:], "\r\n")
+	line := response[i+1 : end]
+	next := end + 2
+	null := lua.LValue(lua.LFalse)
+	if resp == 3 {
+		null = lua.LNil
+	}
+	single := func(key string, value lua.LValue) *lua.LTable {
+		t := L.NewTable()
+		t.RawSetString(key, value)
+		return t
+	}
+	switch response[i] {
+	case '+':
+		return luaStatusReply(L, line), next
+	case '-':
+		return luaErrorReply(L, line), next
+	case ':':
+		n, _ := strconv.ParseInt(line, 10, 64)
+		return lua.LNumber(n), next
+	case '_':
+		return null, next
+	case '#':
+		return lua.LBool(line == "t"), next
+	case ',':
+		n, _ := strconv.ParseFloat(line, 64)
+		return single("double", lua.LNumber(n)), next
+	case '(':
+		return single("big_number", lua.LString(line)), next
+	case '$', '=', '!':
+		n, _ := strconv.Atoi(line)
+		if n < 0 {
+			return null, next
+		}
+		s := response[next : next+n]
+		switch response[i] {
+		case '=':
+			verbatim := L.NewTable()
+			verbatim.RawSetString("format", lua.LString(s[:3]))
+			verbatim.RawSetString("string", lua.LString(s[4:]))
+			return single("verbatim_string", verbatim), next + n + 2
+		case '!':
+			return luaErrorReply(L, s), next + n + 2
+		}
+		return lua.LString(s), next + n + 2
+	case '*', '>', '~':
+		n, _ := strconv.Atoi(line)
+		if n < 0 {
+			return null, next
+		}
+		t := L.CreateTable(n, 0)
+		var element lua.LValue
+		for j := 0; j < n; j++ {
+			element, next = luaFromResponse(L, response, next, resp)
+			if response[i] == '~' {
+				t.RawSet(element, lua.LTrue)
+			} else {
+				t.Append(element)
+			}
+		}
+		if response[i] == '~' {
+			return single("set", t), next
+		}
+		return t, next
+	case '%':
+		n, _ := strconv.Atoi(line)
+		t := L.CreateTable(0, n)
+		var key, value lua.LValue
+		for j := 0; j < n; j++ {
+			key, next = luaFromResponse(L, response, next, resp)
+			value, next = luaFromResponse(L, response, next, resp)
+			t.RawSet(key, value)
+		}
+		return single("map", t), next
+	}
+	panic(fmt.Sprintf("unexpected reply %q to a command called from a script", response[i:]))
+}
+
+// luaToResponse converts the value that a script returned to a RESP reply, for a client that
+// speaks the version protocol of RESP, from a script that set the version resp with
+// redis.setresp. Like Redis, numbers are truncated to integers, true becomes 1, nil and false
+// null, and tables arrays of the elements up to the first nil, unless they have an err or ok
+// field, which become error and status replies, or a map, set, double or big_number field,
+// which become those RESP3 types, or what stands for them in RESP2.
+func luaToResponse(value lua.LValue, protocol, resp int) string {
+	null := nullBulkString
+	if protocol == 3 {
+		null = "_\r\n"
+	}
+	switch value := value.(type) {
+	case lua.LBool:
+		switch {
+		case resp == 3 && protocol == 3 && bool(value):
+			return "#t\r\n"
+		case resp == 3 && protocol == 3:
+			return "#f\r\n"
+		case resp == 3 && !bool(value):
+			return integer(0)
+		case bool(value):
+			return integer(1)
+		}
+	case lua.LNumber:
+		return integer(int(value))
+	case lua.LString:
+		return bulkString(string(value))
+	case *lua.LTable:
+		return luaTableToResponse(value, protocol, resp)
+	}
+	return null
+}
+
+func luaTableToResponse(value *lua.LTable, protocol, resp int) string {
+	if message, ok := value.RawGetString("err").(lua.LString); ok {
+		return simpleError(luaSanitizeError(string(message)))
+	}
+	if status, ok := value.RawGetString("ok").(lua.LString); ok {
+		return simpleString(luaSanitizeError(string(status)))
+	}
+	if n, ok := value.RawGetString("double").(lua.LNumber); ok {
+		if protocol == 3 {
+			return fmt.Sprintf(",%s\r\n", formatScore(float64(n)))
+		}
+		return bulkString(formatScore(float64(n)))
+	}
+	if n, ok := value.RawGetString("big_number").(lua.LString); ok {
+		if protocol == 3 {
+			return fmt.Sprintf("(%s\r\n", n)
+		}
+		return bulkString(string(n))
+	}
+	if m, ok := value.RawGetString("map").(*lua.LTable); ok {
+		var elements []string
+		m.ForEach(func(key, element lua.LValue) {
+			elements = append(elements,
+				luaToResponse(key, protocol, resp), luaToResponse(element, protocol, resp))
+		})
+		if protocol == 3 {
+			return fmt.Sprintf("%%%d\r\n", len(elements)/2) + strings.Join(elements, "")
+		}
+		return array(elements...)
+	}
+	if set, ok := value.RawGetString("set").(*lua.LTable); ok {
+		var elements []string
+		set.ForEach(func(key, _ lua.LValue) {
+			elements = append(elements, luaToResponse(key, protocol, resp))
+		})
+		if protocol == 3 {
+			return fmt.Sprintf("~%d\r\n", len(elements)) + strings.Join(elements, "")
+		}
+		return array(elements...)
+	}
+	var elements []string
+	for i := 1; value.RawGetInt(i) != lua.LNil; i++ {
+		elements = append(elements, luaToResponse(value.RawGetInt(i), protocol, resp))
+	}
+	return array(elements...)
+}
+
+// luaSanitizeError strips the leading "-" that error replies can be given in Lua with, and
+// replaces newlines, which can't be in a simple string or error.
+func luaSanitizeError(message string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(strings.TrimPrefix(message, "-"))
+}
+
+// scriptErrorResponse returns the error reply for the error value, raised on line of the script
+// whose SHA1 digest, or the function whose name, is name, and not caught, which says where it was
+// raised in the source chunk like Redis.
+func scriptErrorResponse(value lua.LValue, name, chunk string, line int) string {
+	message := "ERR unknown error"
+	switch value := value.(type) {
+	case *lua.LTable:
+		if s, ok := value.RawGetString("err").(lua.LString); ok {
+			message = string(s)
+		}
+	case lua.LString, lua.LNumber:
+		message = "ERR " + value.String()
+	}
+	if line > 0 {
+		message += fmt.Sprintf(" script: %s, on @%s:%d.", name, chunk, line)
+	}
+	return simpleError(luaSanitizeError(message))
+}
@@ -0,0 +1,160 @@
+package redis
+
+import (
+	"math/rand"
+	"sort"
+	"strconv"
+)
+
+// set is the set data type. Like Redis, sets whose members are all integers are kept in an
+// "intset": a sorted slice of integers that's binary searched, which is compact and keeps its
+// members in order. Once a set has a member that isn't an integer, or more members than its
+// Config allows, it's converted to a "hashtable", a map, for good.
+type set struct {
+	intset []int64
+	// table is nil while the set is an intset.
+	table map[string]struct{}
+}
+
+func newSet() *set {
+	return &set{}
+}
+
+// newSetWithMembers returns a set of members, encoded as if they were added one by one.
+func newSetWithMembers(members []string, config EncodingConfig) *set {
+	s := newSet()
+	for _, member := range members {
+		s.add(member, config)
+	}
+	return s
+}
+
+// parseIntsetMember returns member as an integer if it can be kept in an intset: that is, if it's
+// an integer written the way strconv.FormatInt would write it, so that no information is lost.
+func parseIntsetMember(member string) (int64, bool) {
+	n, err := strconv.ParseInt(member, 10, 64)
+	if err != nil || strconv.FormatInt(n, 10) != member {
+		return 0, false
+	}
+	return n, true
+}
+
+// intsetIndex returns the index of n in the intset, or the index it would be inserted at and
+// false if it's absent.
+func (s *set) intsetIndex(n int64) (int, bool) {
+	i := sort.Search(len(s.intset), func(i int) bool {
+		return s.intset[i] >= n
+	})
+	return i, i < len(s.intset) && s.intset[i] == n
+}
+
+func (s *set) contains(member string) bool {
+	if s.table != nil {
+		_, ok := s.table[member]
+		return ok
+	}
+	n, ok := parseIntsetMember(member)
+	if !ok {
+		return false
+	}
+	_, ok = s.intsetIndex(n)
+	return ok
+}
+
+// add adds member, converting an intset to a hashtable if member doesn't fit in it or it grows
+// past config's limit. It returns true if member is new.
+func (s *set) add(member string, config EncodingConfig) bool {
+	if s.table == nil {
+		if n, ok := parseIntsetMember(member); ok {
+			i, exists := s.intsetIndex(n)
+			if exists {
+				return false
+			}
+			if len(s.intset) < config.SetMaxIntsetEntries {
+				s.intset = append(s.intset, 0)
+				copy(s.intset[i+1:], s.intset[i:])
+				s.intset[i] = n
+				return true
+			}
+		}
+		s.convert()
+	}
+	if _, exists := s.table[member]; exists {
+		return false
+	}
+	s.table[member] = struct{}{}
+	return true
+}
+
+// convert converts an intset to a hashtable.
+func (s *set) convert() {
+	s.table = make(map[string]struct{}, len(s.intset)+1)
+	for _, n := range s.intset {
+		s.table[strconv.FormatInt(n, 10)] = struct{}{}
+	}
+	s.intset = nil
+}
+
+// remove removes member, returning true if it was present.
+func (s *set) remove(member string) bool {
+	if s.table != nil {
+		_, ok := s.table[member]
+		delete(s.table, member)
+		return ok
+	}
+	n, ok := parseIntsetMember(member)
+	if !ok {
+		return false
+	}
+	i, ok := s.intsetIndex(n)
+	if ok {
+		s.intset = append(s.intset[:i], s.intset[i+1:]...)
+	}
+	return ok
+}
+
+func (s *set) len() int {
+	if s.table != nil {
+		return len(s.table)
+	}
+	return len(s.intset)
+}
+
+// members returns the set's members: in ascending order for an intset, and in no particular
+// order for a hashtable.
+func (s *set) members() []string {
+	result := make([]string, 0, s.len())
+	if s.table != nil {
+		for member := range s.table {
+			result = append(result, member)
+		}
+		return result
+	}
+	for _, n := range s.intset {
+		result = append(result, strconv.FormatInt(n, 10))
+	}
+	return result
+}
+
+// random returns a random member. The set must not be empty.
+func (s *set) random() string {
+	if s.table != nil {
+		// Map iteration order isn't random enough to rely on, so pick an index instead.
+		i := rand.Intn(len(s.table))
+		for member := range s.table {
+			if i == 0 {
+				return member
+			}
+			i--
+		}
+	}
+	return strconv.FormatInt(s.intset[rand.Intn(len(s.intset))], 10)
+}
+
+// encoding returns the name of the set's encoding, as returned by OBJECT ENCODING.
+func (s *set) encoding() string {
+	if s.table != nil {
+		return "hashtable"
+	}
+	return "intset"
+}
@@ -0,0 +1,235 @@
+package redis
+
+import (
+	"container/heap"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+const (
+	errCMSExists     CommandError = "ERR CMS: key already exists"
+	errCMSMissing    CommandError = "ERR CMS: key does not exist"
+	errCMSDimensions CommandError = "ERR CMS: width/depth is not equal"
+)
+
+// countMinSketch is a Count-Min sketch: depth rows of width counters, where adding to an item
+// adds to one counter in each row, and an item's count is the smallest of its counters. Counts
+// can only be overestimated, by collisions with other items.
+type countMinSketch struct {
+	width    int
+	depth    int
+	counters []int64
+	count    int64
+}
+
+func newCountMinSketch(width, depth int) *countMinSketch {
+	return &countMinSketch{width: width, depth: depth, counters: make([]int64, width*depth)}
+}
+
+// cmsDimensionsByProb returns the dimensions of a sketch that overestimates counts by at most
+// errorRate of the total count, with a probability of at most probability of exceeding it.
+func cmsDimensionsByProb(errorRate, probability float64) (width, depth int) {
+	width = int(math.Ceil(2 / errorRate))
+	depth = int(math.Ceil(math.Log10(probability) / math.Log10(0.5)))
+	return width, depth
+}
+
+// counterIndices returns the index in counters of item's counter in each row.
+func (s *countMinSketch) counterIndices(item string) []int {
+	a, b := itemHashes(item)
+	result := make([]int, s.depth)
+	for row := range result {
+		result[row] = row*s.width + int((a+uint64(row)*b)%uint64(s.width))
+	}
+	return result
+}
+
+// incrBy adds increment to item's count and returns its new count.
+func (s *countMinSketch) incrBy(item string, increment int64) int64 {
+	result := int64(math.MaxInt64)
+	for _, i := range s.counterIndices(item) {
+		s.counters[i] += increment
+		if s.counters[i] < result {
+			result = s.counters[i]
+		}
+	}
+	s.count += increment
+	return result
+}
+
+func (s *countMinSketch) query(item string) int64 {
+	result := int64(math.MaxInt64)
+	for _, i := range s.counterIndices(item) {
+		if s.counters[i] < result {
+			result = s.counters[i]
+		}
+	}
+	return result
+}
+
+// merge replaces s's counters with the sum of sources' counters, each multiplied by its weight.
+// The sources must all have the same dimensions as s.
+func (s *countMinSketch) merge(sources []*countMinSketch, weights []int64) {
+	counters := make([]int64, len(s.counters))
+	count := int64(0)
+	for k, source := range sources {
+		for i, counter := range source.counters {
+			counters[i] += counter * weights[k]
+		}
+		count += source.count * weights[k]
+	}
+	s.counters = counters
+	s.count = count
+}
+
+const (
+	defaultTopKWidth = 8
+	defaultTopKDepth = 7
+	defaultTopKDecay = 0.9
+)
+
+const (
+	errTopKExists  CommandError = "ERR TopK: key already exists"
+	errTopKMissing CommandError = "ERR TopK: key does not exist"
+)
+
+// topK tracks the k items with the highest counts using HeavyKeeper, like RedisBloom: a
+// Count-Min sketch whose counters also hold the fingerprint of the item they count. An item
+// whose counter holds another item's fingerprint decays that counter with a probability that
+// falls exponentially with its count, and takes it over if it reaches zero. The items with the
+// highest counts are kept in a min-heap of at most k items.
+type topK struct {
+	k       int
+	width   int
+	depth   int
+	decay   float64
+	buckets []topKBucket
+	heap    topKHeap
+}
+
+type topKBucket struct {
+	fingerprint uint32
+	count       int64
+}
+
+type topKItem struct {
+	item  string
+	count int64
+}
+
+func newTopK(k, width, depth int, decay float64) *topK {
+	return &topK{
+		k:       k,
+		width:   width,
+		depth:   depth,
+		decay:   decay,
+		buckets: make([]topKBucket, width*depth),
+	}
+}
+
+// add adds increment to item's count. If that adds it to the top k items, it returns the item
+// it replaced, if any.
+func (t *topK) add(item string, increment int64) (expelled string, ok bool) {
+	a, b := itemHashes(item)
+	fingerprint := uint32(a >> 32)
+	count := int64(0)
+	for row := 0; row < t.depth; row++ {
+		bucket := &t.buckets[row*t.width+int((a+uint64(row)*b)%uint64(t.width))]
+		switch {
+		case bucket.count == 0:
+			bucket.fingerprint = fingerprint
+			bucket.count = increment
+		case bucket.fingerprint == fingerprint:
+			bucket.count += increment
+		default:
+			for n := increment; n > 0; n-- {
+				if rand.Float64() >= math.Pow(t.decay, float64(bucket.count)) {
+					continue
+				}
+				bucket.count--
+				if bucket.count == 0 {
+					bucket.fingerprint = fingerprint
+					bucket.count = n
+					break
+				}
+			}
+		}
+		if bucket.fingerprint == fingerprint && bucket.count > count {
+			count = bucket.count
+		}
+	}
+	if count == 0 {
+		return "", false
+	}
+
+	if i := t.heap.find(item); i >= 0 {
+		if count > t.heap[i].count {
+			t.heap[i].count = count
+			heap.Fix(&t.heap, i)
+		}
+		return "", false
+	}
+	if len(t.heap) < t.k {
+		heap.Push(&t.heap, topKItem{item: item, count: count})
+		return "", false
+	}
+	if count > t.heap[0].count {
+		expelled = t.heap[0].item
+		t.heap[0] = topKItem{item: item, count: count}
+		heap.Fix(&t.heap, 0)
+		return expelled, true
+	}
+	return "", false
+}
+
+func (t *topK) contains(item string) bool {
+	return t.heap.find(item) >= 0
+}
+
+// list returns the top k items, highest count first.
+func (t *topK) list() []topKItem {
+	result := append([]topKItem(nil), t.heap...)
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].count != result[j].count {
+			return result[i].count > result[j].count
+		}
+		return result[i].item < result[j].item
+	})
+	return result
+}
+
+// topKHeap is a min-heap of items by count, for container/heap.
+type topKHeap []topKItem
+
+func (h topKHeap) Len() int {
+	return len(h)
+}
+
+func (h topKHeap) Less(i, j int) bool {
+	return h[i].count < h[j].count
+}
+
+func (h topKHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+}
+
+func (h *topKHeap) Push(x any) {
+	*h = append(*h, x.(topKItem))
+}
+
+func (h *topKHeap) Pop() any {
+	old := *h
+	result := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return result
+}
+
+func (h topKHeap) find(item string) int {
+	for i, element := range h {
+		if element.item == item {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,203 @@
+package redis
+
+import "math/rand"
+
+const (
+	skiplistMaxLevel = 32
+	// skiplistP is the chance that a node with a level also has the level above it.
+	skiplistP = 0.25
+)
+
+// skiplist keeps the members of a sorted set in order, after Redis's zskiplist. Nodes are
+// ordered by score and then by member, and each node links forward on up to skiplistMaxLevel
+// levels, recording how many nodes each link spans. So finding a node by its score, by its rank
+// or finding a node's rank all take O(log N) time.
+type skiplist struct {
+	// header is a sentinel node before the first node, with a link on every level.
+	header *skiplistNode
+	tail   *skiplistNode
+	length int
+	// level is the highest level in use.
+	level int
+}
+
+type skiplistNode struct {
+	member   string
+	score    float64
+	backward *skiplistNode
+	levels   []skiplistLevel
+}
+
+type skiplistLevel struct {
+	forward *skiplistNode
+	// span is the number of nodes that forward is past this node, counting forward itself.
+	span int
+}
+
+func newSkiplist() *skiplist {
+	return &skiplist{
+		header: &skiplistNode{levels: make([]skiplistLevel, skiplistMaxLevel)},
+		level:  1,
+	}
+}
+
+// before reports whether n is ordered before a node with score and member.
+func (n *skiplistNode) before(score float64, member string) bool {
+	return n.score < score || (n.score == score && n.member < member)
+}
+
+func randomSkiplistLevel() int {
+	level := 1
+	for level < skiplistMaxLevel && rand.Float64() < skiplistP {
+		level++
+	}
+	return level
+}
+
+// insert adds a node for member with score. The caller must make sure that member isn't already
+// in the list.
+func (s *skiplist) insert(score float64, member string) {
+	// update holds the last node before the new one on each level, and rank holds its rank.
+	var update [skiplistMaxLevel]*skiplistNode
+	var rank [skiplistMaxLevel]int
+	x := s.header
+	for i := s.level - 1; i >= 0; i-- {
+		if i < s.level-1 {
+			rank[i] = rank[i+1]
+		}
+		for x.levels[i].forward != nil && x.levels[i].forward.before(score, member) {
+			rank[i] += x.levels[i].span
+			x = x.levels[i].forward
+		}
+		update[i] = x
+	}
+
+	level := randomSkiplistLevel()
+	if level > s.level {
+		for i := s.level; i < level; i++ {
+			update[i] = s.header
+			update[i].levels[i].span = s.length
+		}
+		s.level = level
+	}
+	x = &skiplistNode{member: member, score: score, levels: make([]skiplistLevel, level)}
+	for i := 0; i < level; i++ {
+		x.levels[i].forward = update[i].levels[i].forward
+		update[i].levels[i].forward = x
+		x.levels[i].span = update[i].levels[i].span - (rank[0] - rank[i])
+		update[i].levels[i].span = rank[0] - rank[i] + 1
+	}
+	for i := level; i < s.level; i++ {
+		update[i].levels[i].span++
+	}
+
+	if update[0] != s.header {
+		x.backward = update[0]
+	}
+	if x.levels[0].forward != nil {
+		x.levels[0].forward.backward = x
+	} else {
+		s.tail = x
+	}
+	s.length++
+}
+
+// delete removes the node for member with score, returning false if there isn't one.
+func (s *skiplist) delete(score float64, member string) bool {
+	var update [skiplistMaxLevel]*skiplistNode
+	x := s.header
+	for i := s.level - 1; i >= 0; i-- {
+		for x.levels[i].forward != nil && x.levels[i].forward.before(score, member) {
+			x = x.levels[i].forward
+		}
+		update[i] = x
+	}
+	x = x.levels[0].forward
+	if x == nil || x.score != score || x.member != member {
+		return false
+	}
+
+	for i := 0; i < s.level; i++ {
+		if update[i].levels[i].forward == x {
+			update[i].levels[i].span += x.levels[i].span - 1
+			update[i].levels[i].forward = x.levels[i].forward
+		} else {
+			update[i].levels[i].span--
+		}
+	}
+	if x.levels[0].forward != nil {
+		x.levels[0].forward.backward = x.backward
+	} else {
+		s.tail = x.backward
+	}
+	for s.level > 1 && s.header.levels[s.level-1].forward == nil {
+		s.level--
+	}
+	s.length--
+	return true
+}
+
+// rank returns the 1-based rank of the node for member with score, or 0 if there isn't one.
+func (s *skiplist) rank(score float64, member string) int {
+	rank := 0
+	x := s.header
+	for i := s.level - 1; i >= 0; i-- {
+		for x.levels[i].forward != nil &&
+			(x.levels[i].forward.before(score, member) ||
+				x.levels[i].forward.score == score && x.levels[i].forward.member == member) {
+			rank += x.levels[i].span
+			x = x.levels[i].forward
+		}
+		if x != s.header && x.score == score && x.member == member {
+			return rank
+		}
+	}
+	return 0
+}
+
+// byRank returns the node with the 1-based rank, or nil if there isn't one.
+func (s *skiplist) byRank(rank int) *skiplistNode {
+	traversed := 0
+	x := s.header
+	for i := s.level - 1; i >= 0; i-- {
+		for x.levels[i].forward != nil && traversed+x.levels[i].span <= rank {
+			traversed += x.levels[i].span
+			x = x.levels[i].forward
+		}
+		if traversed == rank && x != s.header {
+			return x
+		}
+	}
+	return nil
+}
+
+// first returns the first node that's aboveMin, if it's also belowMax. aboveMin must be false
+// for a prefix of the nodes and true for the rest, and belowMax true for a prefix and false for
+// the rest, like the two ends of a range.
+func (s *skiplist) first(aboveMin, belowMax func(*skiplistNode) bool) *skiplistNode {
+	x := s.header
+	for i := s.level - 1; i >= 0; i-- {
+		for x.levels[i].forward != nil && !aboveMin(x.levels[i].forward) {
+			x = x.levels[i].forward
+		}
+	}
+	x = x.levels[0].forward
+	if x == nil || !belowMax(x) {
+		return nil
+	}
+	return x
+}
+
+// last returns the last node that's belowMax, if it's also aboveMin, like first.
+func (s *skiplist) last(aboveMin, belowMax func(*skiplistNode) bool) *skiplistNode {
+	x := s.header
+	for i := s.level - 1; i >= 0; i-- {
+		for x.levels[i].forward != nil && belowMax(x.levels[i].forward) {
+			x = x.levels[i].forward
+		}
+	}
+	if x == s.header || !aboveMin(x) {
+		return nil
+	}
+	return x
+}
@@ -1,61 +1,626 @@
 package redis
 
 import (
+	"bytes"
+	"fmt"
+	"reflect"
 	"sync"
 	"time"
 )
 
 func NewStore() *Store {
 	return &Store{
-		entries: new(sync.Map),
+		keyspace: &keyspace{
+			entries:     make(map[string]StoreValue),
+			expires:     make(map[string]struct{}),
+			hashExpires: make(map[string]struct{}),
+			blocked:     make(map[string][]*blockedClient),
+			tracking:    newTracking(),
+			watches:     newWatches(),
+		},
 	}
 }
 
+// Store is the keyspace that commands read and write. The commands that a client sends get a
+// Store of their own from forClient, sharing the keyspace, so that it knows which client reads
+// and writes keys.
 type Store struct {
-	entries *sync.Map
+	*keyspace
+	// client is the client that sent the command that the Store is for, if any.
+	client *Client
+	// caching is true if the client sent CLIENT CACHING just before the command.
+	caching bool
+	// exclusive is true for a command queued in a transaction, which EXEC runs with the keyspace
+	// already locked, so it doesn't lock it itself, and doesn't block.
+	exclusive bool
+	// noWrites is true for the commands that a function with the no-writes flag calls, which
+	// panic with errWriteFromReadOnlyScript if they try to write.
+	noWrites bool
+}
+
+// keyspace is the keys and values that every client's Store shares.
+type keyspace struct {
+	mu      sync.RWMutex
+	entries map[string]StoreValue
+	// expires are the keys in entries with an expiry time, which DeleteExpiredKeys samples.
+	expires map[string]struct{}
+	// hashExpires are the keys in entries of hashes that have had fields given expiry times,
+	// which DeleteExpiredKeys samples too, since their fields are otherwise only removed when the
+	// hash is written. Keys whose hashes no longer have any are removed as they're sampled.
+	hashExpires map[string]struct{}
+	// blocked holds the clients blocked on each key, in the order that they blocked.
+	blocked  map[string][]*blockedClient
+	notifier keyspaceNotifier
+	tracking *tracking
+	watches  *watches
+	// readyKeys are the keys that the commands of the transaction being run signalled as ready,
+	// whose blocked clients are served once it's over.
+	readyKeys []string
+	// dirty counts the changes to keys, which tells whether a script has written any.
+	dirty int
+}
+
+// forClient returns a Store for a command that client sent, with caching true if it sent CLIENT
+// CACHING just before it.
+func (s *Store) forClient(client *Client, caching bool) *Store {
+	return &Store{keyspace: s.keyspace, client: client, caching: caching}
+}
+
+// forTransaction returns a copy of s for a command queued in a transaction.
+func (s *Store) forTransaction() *Store {
+	result := *s
+	result.exclusive = true
+	return &result
+}
+
+// exclusively runs fn in a write transaction, so that the commands of a transaction, whose
+// Stores are from forTransaction, run without any other reads or writes in between. Like Redis,
+// clients blocked on keys that they signal as ready are only served once fn returns.
+func (s *Store) exclusively(now time.Time, fn func(tx *storeTx)) {
+	s.write(now, func(tx *storeTx) {
+		fn(tx)
+		tx.readyKeys = s.readyKeys
+		s.readyKeys = nil
+	})
+}
+
+func (s *Store) lock() {
+	if !s.exclusive {
+		s.mu.Lock()
+	}
+}
+
+func (s *Store) unlock() {
+	if !s.exclusive {
+		s.mu.Unlock()
+	}
+}
+
+func (s *Store) rLock() {
+	if !s.exclusive {
+		s.mu.RLock()
+	}
+}
+
+func (s *Store) rUnlock() {
+	if !s.exclusive {
+		s.mu.RUnlock()
+	}
 }
 
 func (s *Store) Get(key string) (result StoreValue, ok bool) {
-	value, ok := s.entries.Load(key)
-	if ok {
-		result = value.(StoreValue)
+	s.rLock()
+	defer s.rUnlock()
+
+	result, ok = s.entries[key]
+	if data, isString := result.data.([]byte); isString {
+		// Copy the data, since commands like SETBIT change it in place.
+		result.data = bytes.Clone(data)
 	}
 	return
 }
 
+// Set sets key to the string value, without any expiry time. Since it isn't given the time, any
+// key it replaces is treated as not having expired yet.
 func (s *Store) Set(key, value string) {
-	s.entries.Store(key, StoreValue{data: value})
+	s.write(time.Time{}, func(tx *storeTx) {
+		tx.set(key, NewStoreValue(value))
+		tx.notify(notifyString, "set", key)
+	})
 }
 
+// SetWithExpiryTime is like Set, but key expires at expiryTime.
 func (s *Store) SetWithExpiryTime(key, value string, expiryTime time.Time) {
-	s.entries.Store(key, StoreValue{
-		data:       value,
-		expiryTime: &expiryTime,
+	s.write(time.Time{}, func(tx *storeTx) {
+		tx.set(key, NewStoreValueWithExpiryTime(value, expiryTime))
+		tx.notify(notifyString, "set", key)
+		tx.notify(notifyGeneric, "expire", key)
+	})
+}
+
+// KeyValue is a key and the string value to set it to.
+type KeyValue struct {
+	Key   string
+	Value string
+}
+
+// SetMultiple sets every key in keyValues to its value, without any expiry time. All the keys
+// are set at once, so no reader can see some of them set but not the others. If the same key
+// appears more than once, the last value wins.
+func (s *Store) SetMultiple(keyValues []KeyValue) {
+	s.write(time.Time{}, func(tx *storeTx) {
+		for _, keyValue := range keyValues {
+			tx.set(keyValue.Key, NewStoreValue(keyValue.Value))
+			tx.notify(notifyString, "set", keyValue.Key)
+		}
+	})
+}
+
+// SetMultipleIfAllAbsent is like SetMultiple, except it sets nothing if any of the keys exist
+// and haven't expired at time now. It returns true if the keys were set.
+func (s *Store) SetMultipleIfAllAbsent(now time.Time, keyValues []KeyValue) bool {
+	result := true
+	s.write(now, func(tx *storeTx) {
+		for _, keyValue := range keyValues {
+			if _, ok := tx.get(keyValue.Key); ok {
+				result = false
+				return
+			}
+		}
+		for _, keyValue := range keyValues {
+			tx.set(keyValue.Key, NewStoreValue(keyValue.Value))
+			tx.notify(notifyString, "set", keyValue.Key)
+		}
+	})
+	return result
+}
+
+// read runs fn with a storeTx that can only be read from. Other reads may run at the same time,
+// but no writes will. Any expired keys that fn comes across are deleted afterwards.
+func (s *Store) read(now time.Time, fn func(tx *storeTx)) {
+	tx := &storeTx{store: s, now: now}
+	func() {
+		s.rLock()
+		defer s.rUnlock()
+
+		fn(tx)
+	}()
+
+	// Read-only scripts leave expired keys for active expiry to delete.
+	if len(tx.expired) > 0 && !s.noWrites {
+		s.write(now, func(writeTx *storeTx) {
+			for _, key := range tx.expired {
+				// Getting the key deletes it if it's still expired.
+				writeTx.get(key)
+			}
+		})
+	}
+}
+
+// write runs fn with a storeTx that can be read from and written to. No other reads or writes
+// will run at the same time, so all of fn's changes appear to happen at once. Any clients
+// blocked on keys that fn signalled as ready are served, and then the transactions of the clients
+// watching the keys that changed are discarded, the clients that may have cached them are sent
+// invalidation messages and the keyspace events of fn and
+// of serving them are notified, before the next read or write. In a transaction, blocked clients
+// are served once the transaction is over instead.
+func (s *Store) write(now time.Time, fn func(tx *storeTx)) {
+	if s.noWrites {
+		panic(errWriteFromReadOnlyScript)
+	}
+	s.lock()
+	defer s.unlock()
+
+	tx := &storeTx{store: s, now: now, writable: true}
+	fn(tx)
+	if s.exclusive {
+		s.readyKeys = append(s.readyKeys, tx.readyKeys...)
+		tx.readyKeys = nil
+	}
+	s.dirty += len(tx.changed)
+	tx.serveBlockedClients()
+	s.watches.touch(tx.changed, func(key string) bool {
+		_, ok := s.entries[key]
+		return ok
+	})
+	s.tracking.invalidate(tx.changed, s.client)
+	tx.publishEvents()
+}
+
+// DeleteExpiredKeys deletes keys whose expiry time has passed at now, like Redis's active
+// expiry, so that keys that are never accessed again don't stay around forever. It samples keys
+// with an expiry time, carrying on while more than a quarter of a sample has expired, then does
+// the same for the expired fields of hashes, and returns how many keys it deleted.
+func (s *Store) DeleteExpiredKeys(now time.Time) int {
+	deleted := s.sampleExpired(now, s.expires, func(tx *storeTx, key string) bool {
+		_, ok := tx.get(key)
+		return !ok
+	})
+	s.sampleExpired(now, s.hashExpires, func(tx *storeTx, key string) bool {
+		if !deleteExpiredHashFields(tx, key) {
+			return false
+		}
+		if _, ok := s.entries[key]; !ok {
+			deleted++
+		}
+		return true
 	})
+	return deleted
 }
 
+// sampleExpired calls expire for samples of keys, carrying on while it returns true for more
+// than a quarter of a sample, and returns how many times it did.
+func (s *Store) sampleExpired(
+	now time.Time,
+	keys map[string]struct{},
+	expire func(tx *storeTx, key string) bool,
+) int {
+	const sampleSize = 20
+	total := 0
+	for {
+		sampled, expired := 0, 0
+		s.write(now, func(tx *storeTx) {
+			for key := range keys {
+				if sampled == sampleSize {
+					break
+				}
+				sampled++
+				if expire(tx, key) {
+					expired++
+				}
+			}
+		})
+		total += expired
+		if expired*4 <= sampled {
+			return total
+		}
+	}
+}
+
+// storeTx is a view of a Store's entries at a single point in time, now, where entries whose
+// expiry time has passed are treated as absent.
+type storeTx struct {
+	store     *Store
+	now       time.Time
+	writable  bool
+	readyKeys []string
+	// expired are the expired keys that a read-only transaction came across, to be deleted once
+	// it's over.
+	expired []string
+	events  []keyspaceEvent
+	// changed are the keys that the transaction changed, in order, with any repeated.
+	changed []string
+}
+
+// get returns the value of key. If key has expired, a writable transaction deletes it, notifying
+// the expired event. Keys that a read-only transaction gets are remembered for the client of its
+// Store, if it has CLIENT TRACKING on, since it may cache them.
+func (tx *storeTx) get(key string) (StoreValue, bool) {
+	if !tx.writable && tx.store.client != nil && tx.store.client.tracked() {
+		tx.store.tracking.remember(tx.store.client, tx.store.caching, key)
+	}
+	value, ok := tx.store.entries[key]
+	if !ok {
+		return StoreValue{}, false
+	}
+	if value.expiredAt(tx.now) {
+		if !tx.writable {
+			tx.expired = append(tx.expired, key)
+			return StoreValue{}, false
+		}
+		delete(tx.store.entries, key)
+		delete(tx.store.expires, key)
+		tx.notify(notifyExpired, "expired", key)
+		return StoreValue{}, false
+	}
+	return value, true
+}
+
+// getTyped is like get, but returns errWrongType if key holds a value of a type other than
+// valueType.
+func (tx *storeTx) getTyped(key string, valueType ValueType) (StoreValue, bool, error) {
+	value, ok := tx.get(key)
+	if ok && value.Type() != valueType {
+		return StoreValue{}, false, errWrongType
+	}
+	return value, ok, nil
+}
+
+// keys returns every key that hasn't expired, in no particular order.
+func (tx *storeTx) keys() []string {
+	var result []string
+	for key, value := range tx.store.entries {
+		if !value.expiredAt(tx.now) {
+			result = append(result, key)
+		}
+	}
+	return result
+}
+
+// set sets key to value, notifying the new event if key was absent.
+func (tx *storeTx) set(key string, value StoreValue) {
+	tx.checkWritable()
+	if _, ok := tx.get(key); !ok {
+		tx.notify(notifyNew, "new", key)
+	}
+	tx.store.entries[key] = value
+	if value.expiryTime != nil {
+		tx.store.expires[key] = struct{}{}
+	} else {
+		delete(tx.store.expires, key)
+	}
+	if h, ok := value.data.(*hash); ok && h.expiring > 0 {
+		tx.store.hashExpires[key] = struct{}{}
+	} else {
+		delete(tx.store.hashExpires, key)
+	}
+}
+
+// delete removes key, returning true if it was present and had not expired. It doesn't notify
+// any event, since what deleting key means depends on the command, but commands that delete a
+// key because it's left empty notify the del event with deleteEmpty instead.
+func (tx *storeTx) delete(key string) bool {
+	tx.checkWritable()
+	_, ok := tx.get(key)
+	delete(tx.store.entries, key)
+	delete(tx.store.expires, key)
+	delete(tx.store.hashExpires, key)
+	return ok
+}
+
+// deleteEmpty deletes key, whose value a command has left empty, notifying the del event.
+func (tx *storeTx) deleteEmpty(key string) {
+	tx.delete(key)
+	tx.notify(notifyGeneric, "del", key)
+}
+
+func (tx *storeTx) checkWritable() {
+	if !tx.writable {
+		panic("redis.storeTx: write to read-only transaction")
+	}
+}
+
+type ValueType int
+
+const (
+	ValueTypeString ValueType = iota
+	ValueTypeList
+	ValueTypeHash
+	ValueTypeSet
+	ValueTypeZSet
+	ValueTypeStream
+	ValueTypeJSON
+	ValueTypeBloom
+	ValueTypeCuckoo
+	ValueTypeCMS
+	ValueTypeTopK
+	ValueTypeTimeSeries
+)
+
+// String returns the name of v, as returned by Redis's TYPE command.
+func (v ValueType) String() string {
+	switch v {
+	case ValueTypeString:
+		return "string"
+	case ValueTypeList:
+		return "list"
+	case ValueTypeHash:
+		return "hash"
+	case ValueTypeSet:
+		return "set"
+	case ValueTypeZSet:
+		return "zset"
+	case ValueTypeStream:
+		return "stream"
+	case ValueTypeJSON:
+		return "ReJSON-RL"
+	case ValueTypeBloom:
+		return "MBbloom--"
+	case ValueTypeCuckoo:
+		return "MBbloomCF"
+	case ValueTypeCMS:
+		return "CMSk-TYPE"
+	case ValueTypeTopK:
+		return "TopK-TYPE"
+	case ValueTypeTimeSeries:
+		return "TSDB-TYPE"
+	}
+	panic(fmt.Sprintf("unknown redis.ValueType: %d", v))
+}
+
+const errWrongType CommandError = "WRONGTYPE Operation against a key holding the wrong kind of value"
+
 func NewStoreValue(data string) StoreValue {
 	return StoreValue{
-		data: data,
+		data: []byte(data),
 	}
 }
 
 func NewStoreValueWithExpiryTime(data string, expiryTime time.Time) StoreValue {
 	return StoreValue{
-		data:       data,
+		data:       []byte(data),
 		expiryTime: &expiryTime,
 	}
 }
 
 type StoreValue struct {
-	data       string
+	// data is the value itself. Its type depends on the value's type: a []byte for strings,
+	// rather than a string so that commands like SETBIT can change it in place, a *quicklist for
+	// lists, a *hash for hashes, a *set for sets, a *zset for sorted sets, a *stream for
+	// streams, a *jsonDocument for JSON, a *bloomFilter, *cuckooFilter, *countMinSketch or
+	// *topK for those probabilistic structures, or a *timeSeries for time series. It must only
+	// be read or changed while holding the Store's lock.
+	data       any
 	expiryTime *time.Time
 }
 
+// Data returns the value of a string. It returns "" for values of other types.
 func (s StoreValue) Data() string {
-	return s.data
+	return string(s.bytes())
+}
+
+func (s StoreValue) Type() ValueType {
+	switch s.data.(type) {
+	case []byte:
+		return ValueTypeString
+	case *quicklist:
+		return ValueTypeList
+	case *hash:
+		return ValueTypeHash
+	case *set:
+		return ValueTypeSet
+	case *zset:
+		return ValueTypeZSet
+	case *stream:
+		return ValueTypeStream
+	case *jsonDocument:
+		return ValueTypeJSON
+	case *bloomFilter:
+		return ValueTypeBloom
+	case *cuckooFilter:
+		return ValueTypeCuckoo
+	case *countMinSketch:
+		return ValueTypeCMS
+	case *topK:
+		return ValueTypeTopK
+	case *timeSeries:
+		return ValueTypeTimeSeries
+	}
+	panic(fmt.Sprintf("unknown redis.StoreValue data type: %T", s.data))
 }
 
 func (s StoreValue) ExpiryTime() *time.Time {
 	return s.expiryTime
 }
+
+// withData returns a copy of s with its data replaced, keeping its expiry time.
+func (s StoreValue) withData(data []byte) StoreValue {
+	s.data = data
+	return s
+}
+
+func (s StoreValue) Equal(other StoreValue) bool {
+	if !expiryTimesEqual(s.expiryTime, other.expiryTime) {
+		return false
+	}
+	if data, ok := s.data.([]byte); ok {
+		otherData, ok := other.data.([]byte)
+		return ok && bytes.Equal(data, otherData)
+	}
+	return reflect.DeepEqual(s.data, other.data)
+}
+
+func expiryTimesEqual(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+// bytes returns the value of a string, or nil for values of other types.
+func (s StoreValue) bytes() []byte {
+	data, _ := s.data.([]byte)
+	return data
+}
+
+// list returns the value of a list, or nil for values of other types.
+func (s StoreValue) list() *quicklist {
+	list, _ := s.data.(*quicklist)
+	return list
+}
+
+// encoding returns the name of the value's internal encoding, as returned by OBJECT ENCODING.
+func (s StoreValue) encoding() string {
+	switch s.Type() {
+	case ValueTypeString:
+		data := s.bytes()
+		if len(data) <= 20 {
+			if _, err := parseInteger(string(data)); err == nil {
+				return "int"
+			}
+		}
+		if len(data) <= 44 {
+			return "embstr"
+		}
+		return "raw"
+	case ValueTypeList:
+		return "quicklist"
+	case ValueTypeHash:
+		return s.hash().encoding()
+	case ValueTypeSet:
+		return s.set().encoding()
+	case ValueTypeZSet:
+		return "skiplist"
+	case ValueTypeStream:
+		return "stream"
+	case ValueTypeJSON, ValueTypeBloom, ValueTypeCuckoo, ValueTypeCMS, ValueTypeTopK:
+		return "raw"
+	case ValueTypeTimeSeries:
+		return "compressed"
+	}
+	panic(fmt.Sprintf("unknown redis.ValueType: %d", s.Type()))
+}
+
+// hash returns the value of a hash, or nil for values of other types.
+func (s StoreValue) hash() *hash {
+	hash, _ := s.data.(*hash)
+	return hash
+}
+
+// set returns the value of a set, or nil for values of other types.
+func (s StoreValue) set() *set {
+	set, _ := s.data.(*set)
+	return set
+}
+
+// zset returns the value of a sorted set, or nil for values of other types.
+func (s StoreValue) zset() *zset {
+	zset, _ := s.data.(*zset)
+	return zset
+}
+
+// stream returns the value of a stream, or nil for values of other types.
+func (s StoreValue) stream() *stream {
+	stream, _ := s.data.(*stream)
+	return stream
+}
+
+// json returns the value of a JSON document, or nil for values of other types.
+func (s StoreValue) json() *jsonDocument {
+	doc, _ := s.data.(*jsonDocument)
+	return doc
+}
+
+// bloom returns the value of a Bloom filter, or nil for values of other types.
+func (s StoreValue) bloom() *bloomFilter {
+	filter, _ := s.data.(*bloomFilter)
+	return filter
+}
+
+// cuckoo returns the value of a Cuckoo filter, or nil for values of other types.
+func (s StoreValue) cuckoo() *cuckooFilter {
+	filter, _ := s.data.(*cuckooFilter)
+	return filter
+}
+
+// cms returns the value of a Count-Min sketch, or nil for values of other types.
+func (s StoreValue) cms() *countMinSketch {
+	sketch, _ := s.data.(*countMinSketch)
+	return sketch
+}
+
+// topK returns the value of a Top-K, or nil for values of other types.
+func (s StoreValue) topK() *topK {
+	t, _ := s.data.(*topK)
+	return t
+}
+
+// timeSeries returns the value of a time series, or nil for values of other types.
+func (s StoreValue) timeSeries() *timeSeries {
+	series, _ := s.data.(*timeSeries)
+	return series
+}
+
+func (s StoreValue) expiredAt(now time.Time) bool {
+	return s.expiryTime != nil && now.After(*s.expiryTime)
+}
@@ -1,10 +1,13 @@
 package redis_test
 
 import (
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/codecrafters-io/redis-starter-go/redis"
+	. "github.com/onsi/gomega"
 )
 
 func TestNewStoreValue(t *testing.T) {
@@ -119,3 +122,221 @@ func TestStore(t *testing.T) {
 		}
 	})
 }
+
+func TestStore_SetMultiple(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	store.SetWithExpiryTime("link", "ganon", time.UnixMilli(0))
+
+	store.SetMultiple([]redis.KeyValue{
+		{Key: "link", Value: "zelda"},
+		{Key: "grape", Value: "apple"},
+		{Key: "grape", Value: "banana"},
+	})
+
+	for key, want := range map[string]string{"link": "zelda", "grape": "banana"} {
+		result, ok := store.Get(key)
+		if !ok {
+			t.Errorf(`ok expected to be true but was false`)
+		}
+		if result.Data() != want {
+			t.Errorf(`result.Data() expected to be %#v but was %#v`, want, result.Data())
+		}
+		if result.ExpiryTime() != nil {
+			t.Errorf(`result.ExpiryTime() expected to be nil but was %#v`, result.ExpiryTime())
+		}
+	}
+}
+
+func TestStore_SetMultiple_IsAtomic(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	clock := FakeClock{}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 1000; i++ {
+			value := strconv.Itoa(i)
+			store.SetMultiple([]redis.KeyValue{{Key: "a", Value: value}, {Key: "b", Value: value}})
+		}
+	}()
+
+	for {
+		select {
+		case <-done:
+			return
+		default:
+		}
+		response := redis.NewMGetCommand(store, clock, []string{"a", "b"}).Run()
+		lines := strings.Split(response, "\r\n")
+		if len(lines) == 6 && lines[2] != lines[4] {
+			t.Fatalf(`MGET a b expected to return equal values but was %#v`, response)
+		}
+	}
+}
+
+func TestStore_SetMultipleIfAllAbsent(t *testing.T) {
+	t.Parallel()
+
+	store := redis.NewStore()
+	store.SetWithExpiryTime("link", "ganon", time.UnixMilli(0))
+	now := time.UnixMilli(1)
+
+	ok := store.SetMultipleIfAllAbsent(now, []redis.KeyValue{
+		{Key: "link", Value: "zelda"},
+		{Key: "grape", Value: "banana"},
+	})
+	if !ok {
+		t.Errorf(`ok expected to be true but was false`)
+	}
+
+	ok = store.SetMultipleIfAllAbsent(now, []redis.KeyValue{
+		{Key: "apple", Value: "pear"},
+		{Key: "grape", Value: "cherry"},
+	})
+	if ok {
+		t.Errorf(`ok expected to be false but was true`)
+	}
+	if _, ok := store.Get("apple"); ok {
+		t.Errorf(`store expected not to contain "apple"`)
+	}
+	if result, _ := store.Get("grape"); result.Data() != "banana" {
+		t.Errorf(`result.Data() expected to be "banana" but was %#v`, result.Data())
+	}
+}
+
+func TestStoreValue_Equal(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		this  redis.StoreValue
+		other redis.StoreValue
+		want  bool
+	}{
+		{
+			name:  "same data",
+			this:  redis.NewStoreValue("zelda"),
+			other: redis.NewStoreValue("zelda"),
+			want:  true,
+		},
+		{
+			name:  "different data",
+			this:  redis.NewStoreValue("zelda"),
+			other: redis.NewStoreValue("link"),
+			want:  false,
+		},
+		{
+			name:  "same expiry time",
+			this:  redis.NewStoreValueWithExpiryTime("zelda", time.UnixMilli(0)),
+			other: redis.NewStoreValueWithExpiryTime("zelda", time.UnixMilli(0)),
+			want:  true,
+		},
+		{
+			name:  "different expiry time",
+			this:  redis.NewStoreValueWithExpiryTime("zelda", time.UnixMilli(0)),
+			other: redis.NewStoreValueWithExpiryTime("zelda", time.UnixMilli(1)),
+			want:  false,
+		},
+		{
+			name:  "missing expiry time",
+			this:  redis.NewStoreValueWithExpiryTime("zelda", time.UnixMilli(0)),
+			other: redis.NewStoreValue("zelda"),
+			want:  false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.this.Equal(tt.other); got != tt.want {
+				t.Errorf("Equal() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValueType_String(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		v    redis.ValueType
+		want string
+	}{
+		{
+			name: "ValueTypeString",
+			v:    redis.ValueTypeString,
+			want: "string",
+		},
+		{
+			name: "ValueTypeList",
+			v:    redis.ValueTypeList,
+			want: "list",
+		},
+		{
+			name: "ValueTypeHash",
+			v:    redis.ValueTypeHash,
+			want: "hash",
+		},
+		{
+			name: "ValueTypeSet",
+			v:    redis.ValueTypeSet,
+			want: "set",
+		},
+		{
+			name: "ValueTypeZSet",
+			v:    redis.ValueTypeZSet,
+			want: "zset",
+		},
+		{
+			name: "ValueTypeStream",
+			v:    redis.ValueTypeStream,
+			want: "stream",
+		},
+		{
+			name: "ValueTypeJSON",
+			v:    redis.ValueTypeJSON,
+			want: "ReJSON-RL",
+		},
+		{
+			name: "ValueTypeBloom",
+			v:    redis.ValueTypeBloom,
+			want: "MBbloom--",
+		},
+		{
+			name: "ValueTypeCuckoo",
+			v:    redis.ValueTypeCuckoo,
+			want: "MBbloomCF",
+		},
+		{
+			name: "ValueTypeCMS",
+			v:    redis.ValueTypeCMS,
+			want: "CMSk-TYPE",
+		},
+		{
+			name: "ValueTypeTopK",
+			v:    redis.ValueTypeTopK,
+			want: "TopK-TYPE",
+		},
+		{
+			name: "ValueTypeTimeSeries",
+			v:    redis.ValueTypeTimeSeries,
+			want: "TSDB-TYPE",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.v.String(); got != tt.want {
+				t.Errorf("String() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("unknown value type: -1", func(t *testing.T) {
+		g := NewWithT(t)
+
+		g.Expect(func() { _ = redis.ValueType(-1).String() }).
+			To(PanicWith("unknown redis.ValueType: -1"))
+	})
+}
@@ -0,0 +1,259 @@
+package redis
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// streamNodeMaxEntries is the most entries that a stream node holds, like Redis's
+// stream-node-max-entries. Approximate trimming only removes whole nodes.
+const streamNodeMaxEntries = 100
+
+// StreamID is the ID of a stream entry: the Unix time in milliseconds when it was added and a
+// sequence number for entries added in the same millisecond.
+type StreamID struct {
+	Ms, Seq uint64
+}
+
+// maxStreamID is the greatest possible StreamID, which "+" stands for in ranges.
+var maxStreamID = StreamID{Ms: math.MaxUint64, Seq: math.MaxUint64}
+
+// String returns id in the "<ms>-<seq>" form that Redis uses.
+func (id StreamID) String() string {
+	return strconv.FormatUint(id.Ms, 10) + "-" + strconv.FormatUint(id.Seq, 10)
+}
+
+func (id StreamID) less(other StreamID) bool {
+	return id.Ms < other.Ms || (id.Ms == other.Ms && id.Seq < other.Seq)
+}
+
+// next returns the ID after id, or false if id is the greatest possible ID.
+func (id StreamID) next() (StreamID, bool) {
+	switch {
+	case id.Seq < math.MaxUint64:
+		return StreamID{Ms: id.Ms, Seq: id.Seq + 1}, true
+	case id.Ms < math.MaxUint64:
+		return StreamID{Ms: id.Ms + 1}, true
+	}
+	return id, false
+}
+
+// prev returns the ID before id, or false if id is 0-0.
+func (id StreamID) prev() (StreamID, bool) {
+	switch {
+	case id.Seq > 0:
+		return StreamID{Ms: id.Ms, Seq: id.Seq - 1}, true
+	case id.Ms > 0:
+		return StreamID{Ms: id.Ms - 1, Seq: math.MaxUint64}, true
+	}
+	return id, false
+}
+
+type streamEntry struct {
+	id StreamID
+	// fields holds the entry's field names and values, alternately.
+	fields []string
+}
+
+// stream is the stream data type. Like Redis's radix tree of listpacks, it keeps its entries in
+// order of ID in nodes of up to streamNodeMaxEntries, so that trimming old entries is cheap.
+type stream struct {
+	nodes  [][]streamEntry
+	length int
+	// lastID is the ID of the last entry ever added, which new IDs must be greater than even if
+	// that entry has since been deleted.
+	lastID StreamID
+	// entriesAdded is the number of entries ever added, and maxDeletedID the greatest ID of an
+	// entry deleted with XDEL. Together they let consumer groups work out how far behind they
+	// are.
+	entriesAdded int
+	maxDeletedID StreamID
+	groups       map[string]*streamGroup
+}
+
+func newStream() *stream {
+	return &stream{}
+}
+
+func (s *stream) len() int {
+	return s.length
+}
+
+// add appends an entry, which must have an ID greater than lastID.
+func (s *stream) add(id StreamID, fields []string) {
+	last := len(s.nodes) - 1
+	if last < 0 || len(s.nodes[last]) >= streamNodeMaxEntries {
+		s.nodes = append(s.nodes, make([]streamEntry, 0, streamNodeMaxEntries))
+		last++
+	}
+	s.nodes[last] = append(s.nodes[last], streamEntry{id: id, fields: fields})
+	s.length++
+	s.lastID = id
+	s.entriesAdded++
+}
+
+// last returns the last entry, or false if the stream is empty.
+func (s *stream) last() (streamEntry, bool) {
+	if len(s.nodes) == 0 {
+		return streamEntry{}, false
+	}
+	entries := s.nodes[len(s.nodes)-1]
+	return entries[len(entries)-1], true
+}
+
+// firstID returns the ID of the first entry, or 0-0 if the stream is empty.
+func (s *stream) firstID() StreamID {
+	if len(s.nodes) == 0 {
+		return StreamID{}
+	}
+	return s.nodes[0][0].id
+}
+
+// entry returns the entry with id, or false if there isn't one.
+func (s *stream) entry(id StreamID) (streamEntry, bool) {
+	node, index := s.seek(id)
+	if node == len(s.nodes) || s.nodes[node][index].id != id {
+		return streamEntry{}, false
+	}
+	return s.nodes[node][index], true
+}
+
+// seek returns the position of the first entry with an ID of at least id, which is past the
+// last node if there isn't one.
+func (s *stream) seek(id StreamID) (node, index int) {
+	node = sort.Search(len(s.nodes), func(i int) bool {
+		entries := s.nodes[i]
+		return !entries[len(entries)-1].id.less(id)
+	})
+	if node == len(s.nodes) {
+		return node, 0
+	}
+	entries := s.nodes[node]
+	index = sort.Search(len(entries), func(i int) bool { return !entries[i].id.less(id) })
+	return node, index
+}
+
+// remove removes the entry with id, returning false if there isn't one.
+func (s *stream) remove(id StreamID) bool {
+	if _, ok := s.entry(id); !ok {
+		return false
+	}
+	node, index := s.seek(id)
+	entries := s.nodes[node]
+	if len(entries) == 1 {
+		s.nodes = append(s.nodes[:node], s.nodes[node+1:]...)
+	} else {
+		s.nodes[node] = append(entries[:index], entries[index+1:]...)
+	}
+	s.length--
+	if s.maxDeletedID.less(id) {
+		s.maxDeletedID = id
+	}
+	return true
+}
+
+// entries returns up to count entries with IDs from start to end inclusive, or all of them if
+// count isn't positive. They're in ascending order of ID, or descending from end if reverse.
+func (s *stream) entries(start, end StreamID, count int, reverse bool) []streamEntry {
+	if end.less(start) {
+		return nil
+	}
+	var result []streamEntry
+	full := func() bool { return count > 0 && len(result) >= count }
+	if !reverse {
+		node, index := s.seek(start)
+		for ; node < len(s.nodes); node, index = node+1, 0 {
+			for _, entry := range s.nodes[node][index:] {
+				if end.less(entry.id) || full() {
+					return result
+				}
+				result = append(result, entry)
+			}
+		}
+		return result
+	}
+
+	node, index := s.seek(end)
+	if node == len(s.nodes) || s.nodes[node][index].id != end {
+		// Start from the entry before the first one after end.
+		index--
+	}
+	for node >= 0 {
+		if index < 0 {
+			node--
+			if node >= 0 {
+				index = len(s.nodes[node]) - 1
+			}
+			continue
+		}
+		entry := s.nodes[node][index]
+		if entry.id.less(start) || full() {
+			return result
+		}
+		result = append(result, entry)
+		index--
+	}
+	return result
+}
+
+// StreamTrimStrategy is how XADD and XTRIM decide which entries to trim from a stream.
+type StreamTrimStrategy int
+
+const (
+	// StreamTrimMaxLen trims the oldest entries until the stream has at most MaxLen entries.
+	StreamTrimMaxLen StreamTrimStrategy = iota + 1
+	// StreamTrimMinID trims the entries with IDs less than MinID.
+	StreamTrimMinID
+)
+
+// StreamTrim is how to trim a stream, like the MAXLEN and MINID arguments of XADD and XTRIM.
+type StreamTrim struct {
+	Strategy StreamTrimStrategy
+	MaxLen   int
+	MinID    StreamID
+	// Approximate only trims whole nodes of entries, like "~", which is much cheaper, so that
+	// the stream may be left with a few more entries than asked for.
+	Approximate bool
+	// Limit is the most entries that an approximate trim removes, or unlimited if it's 0.
+	Limit int
+}
+
+// trim removes old entries as t says, returning how many it removed.
+func (s *stream) trim(t StreamTrim) int {
+	removed := 0
+	for len(s.nodes) > 0 {
+		entries := s.nodes[0]
+		var excess int
+		switch t.Strategy {
+		case StreamTrimMaxLen:
+			excess = s.length - t.MaxLen
+		case StreamTrimMinID:
+			excess = sort.Search(len(entries), func(i int) bool {
+				return !entries[i].id.less(t.MinID)
+			})
+		default:
+			panic(fmt.Sprintf("unknown redis.StreamTrimStrategy: %d", t.Strategy))
+		}
+		if excess <= 0 {
+			break
+		}
+		if excess >= len(entries) {
+			if t.Approximate && t.Limit > 0 && removed+len(entries) > t.Limit {
+				break
+			}
+			s.nodes = s.nodes[1:]
+			s.length -= len(entries)
+			removed += len(entries)
+			continue
+		}
+		if !t.Approximate {
+			s.nodes[0] = entries[excess:]
+			s.length -= excess
+			removed += excess
+		}
+		break
+	}
+	return removed
+}
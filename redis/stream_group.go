@@ -0,0 +1,257 @@
+package redis
+
+import (
+	"sort"
+	"time"
+)
+
+// pendingEntry is an entry that has been delivered to a consumer of a group but hasn't been
+// acknowledged yet.
+type pendingEntry struct {
+	id            StreamID
+	consumer      *streamConsumer
+	deliveryTime  time.Time
+	deliveryCount int
+}
+
+// pendingEntries is a pending entries list, in order of ID.
+type pendingEntries []*pendingEntry
+
+// search returns the index of the first entry with an ID of at least id.
+func (p pendingEntries) search(id StreamID) int {
+	return sort.Search(len(p), func(i int) bool { return !p[i].id.less(id) })
+}
+
+// find returns the entry with id, or false if there isn't one.
+func (p pendingEntries) find(id StreamID) (*pendingEntry, bool) {
+	i := p.search(id)
+	if i == len(p) || p[i].id != id {
+		return nil, false
+	}
+	return p[i], true
+}
+
+// insert adds entry, which mustn't already be in the list.
+func (p *pendingEntries) insert(entry *pendingEntry) {
+	// Entries are usually delivered in order of ID, so they can just be appended.
+	if n := len(*p); n == 0 || (*p)[n-1].id.less(entry.id) {
+		*p = append(*p, entry)
+		return
+	}
+	i := p.search(entry.id)
+	*p = append(*p, nil)
+	copy((*p)[i+1:], (*p)[i:])
+	(*p)[i] = entry
+}
+
+// remove removes the entry with id, returning false if there isn't one.
+func (p *pendingEntries) remove(id StreamID) bool {
+	i := p.search(id)
+	if i == len(*p) || (*p)[i].id != id {
+		return false
+	}
+	*p = append((*p)[:i], (*p)[i+1:]...)
+	return true
+}
+
+type streamConsumer struct {
+	name string
+	// seenTime is when the consumer last tried to read or claim entries, and activeTime when it
+	// last actually did, which is zero if it never has.
+	seenTime   time.Time
+	activeTime time.Time
+	pending    pendingEntries
+}
+
+// streamGroup is a consumer group, which shares the entries of a stream between its consumers
+// and tracks which ones they've acknowledged.
+type streamGroup struct {
+	name string
+	// lastID is the ID of the last entry delivered to any of the group's consumers.
+	lastID StreamID
+	// entriesRead is how many entries of the stream were added up to lastID, or -1 if that's
+	// unknown, such as after the group's last ID is set to an arbitrary one.
+	entriesRead int
+	pending     pendingEntries
+	consumers   map[string]*streamConsumer
+}
+
+// createGroup adds a group starting after lastID, returning false if there already is one
+// named name.
+func (s *stream) createGroup(name string, lastID StreamID, entriesRead int) bool {
+	if _, ok := s.groups[name]; ok {
+		return false
+	}
+	if s.groups == nil {
+		s.groups = make(map[string]*streamGroup)
+	}
+	s.groups[name] = &streamGroup{
+		name:        name,
+		lastID:      lastID,
+		entriesRead: entriesRead,
+		consumers:   make(map[string]*streamConsumer),
+	}
+	return true
+}
+
+// sortedGroups returns the stream's groups in order of name.
+func (s *stream) sortedGroups() []*streamGroup {
+	groups := make([]*streamGroup, 0, len(s.groups))
+	for _, group := range s.groups {
+		groups = append(groups, group)
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].name < groups[j].name })
+	return groups
+}
+
+// hasTombstonesAfter returns whether an entry with an ID of at least start may have been
+// deleted, which makes counting the entries after start unreliable.
+func (s *stream) hasTombstonesAfter(start StreamID) bool {
+	if s.length == 0 || s.maxDeletedID == (StreamID{}) || s.maxDeletedID.less(s.firstID()) {
+		return false
+	}
+	return !s.maxDeletedID.less(start)
+}
+
+// entriesAddedUpTo estimates how many entries were added to the stream up to and including the
+// one with id, returning false if it can't tell because entries may have been deleted.
+func (s *stream) entriesAddedUpTo(id StreamID) (int, bool) {
+	switch {
+	case s.entriesAdded == 0:
+		return 0, true
+	case s.lastID.less(id):
+		return 0, false
+	case s.length == 0 || id == s.lastID:
+		return s.entriesAdded, true
+	}
+	first := s.firstID()
+	if s.maxDeletedID == (StreamID{}) || s.maxDeletedID.less(first) {
+		// Only trimming has removed entries, so all the ones from the first are still there.
+		switch {
+		case id.less(first):
+			return s.entriesAdded - s.length, true
+		case id == first:
+			return s.entriesAdded - s.length + 1, true
+		}
+	}
+	return 0, false
+}
+
+// lag returns how many entries of s haven't been delivered to the group yet, or false if that's
+// unknown.
+func (g *streamGroup) lag(s *stream) (int, bool) {
+	if s.entriesAdded == 0 {
+		return 0, true
+	}
+	if g.entriesRead >= 0 && !s.hasTombstonesAfter(g.lastID) {
+		return s.entriesAdded - g.entriesRead, true
+	}
+	entriesRead, ok := s.entriesAddedUpTo(g.lastID)
+	if !ok {
+		return 0, false
+	}
+	return s.entriesAdded - entriesRead, true
+}
+
+// advance moves the group's last ID on to id, which has just been delivered to one of its
+// consumers.
+func (g *streamGroup) advance(s *stream, id StreamID) {
+	if !g.lastID.less(id) {
+		return
+	}
+	if g.entriesRead >= 0 && !s.hasTombstonesAfter(id) {
+		g.entriesRead++
+	} else if s.entriesAdded > 0 {
+		var ok bool
+		if g.entriesRead, ok = s.entriesAddedUpTo(id); !ok {
+			g.entriesRead = -1
+		}
+	}
+	g.lastID = id
+}
+
+// consumer returns the consumer named name, creating it if it doesn't exist, and records that
+// it was seen at now.
+func (g *streamGroup) consumer(name string, now time.Time) *streamConsumer {
+	consumer, _ := g.createConsumer(name, now)
+	consumer.seenTime = now
+	return consumer
+}
+
+// createConsumer returns the consumer named name, creating it and returning true if it doesn't
+// exist.
+func (g *streamGroup) createConsumer(name string, now time.Time) (*streamConsumer, bool) {
+	if consumer, ok := g.consumers[name]; ok {
+		return consumer, false
+	}
+	consumer := &streamConsumer{name: name, seenTime: now}
+	g.consumers[name] = consumer
+	return consumer, true
+}
+
+// deleteConsumer deletes the consumer named name along with its pending entries, returning how
+// many it had.
+func (g *streamGroup) deleteConsumer(name string) int {
+	consumer, ok := g.consumers[name]
+	if !ok {
+		return 0
+	}
+	for _, entry := range consumer.pending {
+		g.pending.remove(entry.id)
+	}
+	delete(g.consumers, name)
+	return len(consumer.pending)
+}
+
+// sortedConsumers returns the group's consumers in order of name.
+func (g *streamGroup) sortedConsumers() []*streamConsumer {
+	consumers := make([]*streamConsumer, 0, len(g.consumers))
+	for _, consumer := range g.consumers {
+		consumers = append(consumers, consumer)
+	}
+	sort.Slice(consumers, func(i, j int) bool { return consumers[i].name < consumers[j].name })
+	return consumers
+}
+
+// deliver records that the entry with id was delivered to consumer at now. If it was already
+// pending, which can happen when the group's last ID is moved back, it's handed over to
+// consumer as if it was delivered for the first time.
+func (g *streamGroup) deliver(id StreamID, consumer *streamConsumer, now time.Time) {
+	if entry, ok := g.pending.find(id); ok {
+		entry.consumer.pending.remove(id)
+		entry.consumer = consumer
+		entry.deliveryTime = now
+		entry.deliveryCount = 1
+		consumer.pending.insert(entry)
+		return
+	}
+	entry := &pendingEntry{id: id, consumer: consumer, deliveryTime: now, deliveryCount: 1}
+	g.pending.insert(entry)
+	consumer.pending.insert(entry)
+}
+
+// ack removes the entry with id from the pending entries lists, returning false if it wasn't
+// pending.
+func (g *streamGroup) ack(id StreamID) bool {
+	entry, ok := g.pending.find(id)
+	if !ok {
+		return false
+	}
+	g.pending.remove(id)
+	if entry.consumer != nil {
+		entry.consumer.pending.remove(id)
+	}
+	return true
+}
+
+// transfer hands the pending entry over to consumer.
+func (g *streamGroup) transfer(entry *pendingEntry, consumer *streamConsumer) {
+	if entry.consumer == consumer {
+		return
+	}
+	if entry.consumer != nil {
+		entry.consumer.pending.remove(entry.id)
+	}
+	entry.consumer = consumer
+	consumer.pending.insert(entry)
+}
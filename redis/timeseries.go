@@ -0,0 +1,311 @@
+package redis
+
+import (
+	"math"
+	"sort"
+)
+
+const (
+	errTSExists             CommandError = "ERR TSDB: key already exists"
+	errTSMissing            CommandError = "ERR TSDB: the key does not exist"
+	errTSOlderThanRetention CommandError = "ERR TSDB: Timestamp is older than retention"
+	errTSDuplicate          CommandError = "ERR TSDB: Error at upsert, update is not supported " +
+		"when DUPLICATE_POLICY is set to BLOCK mode"
+	errTSIncrTimestamp CommandError = "ERR TSDB: timestamp must be equal to or higher than " +
+		"the maximum existing timestamp"
+)
+
+// TSTimestampNow is the timestamp of a sample added with *, which is the current time.
+const TSTimestampNow int64 = -1
+
+// TSDuplicatePolicy is how a time series handles a sample added with the same timestamp as an
+// existing one.
+type TSDuplicatePolicy int
+
+const (
+	// TSDuplicateDefault is the policy of the time series itself, or TSDuplicateBlock for a new
+	// one.
+	TSDuplicateDefault TSDuplicatePolicy = iota
+	TSDuplicateBlock
+	TSDuplicateFirst
+	TSDuplicateLast
+	TSDuplicateMin
+	TSDuplicateMax
+	TSDuplicateSum
+)
+
+// TSLabel is a label of a time series, which TS.MRANGE filters series by.
+type TSLabel struct {
+	Name  string
+	Value string
+}
+
+// TSCreateOptions are the options of a new time series, from TS.CREATE, or from TS.ADD or
+// TS.INCRBY if they create it.
+type TSCreateOptions struct {
+	// Retention is how many milliseconds samples are kept for, relative to the timestamp of the
+	// latest sample, or 0 to keep them forever.
+	Retention       int64
+	DuplicatePolicy TSDuplicatePolicy
+	Labels          []TSLabel
+}
+
+// TSAggregator is the function that TSAggregation applies to each bucket of samples.
+type TSAggregator int
+
+const (
+	TSAggregatorAvg TSAggregator = iota
+	TSAggregatorSum
+	TSAggregatorMin
+	TSAggregatorMax
+	TSAggregatorRange
+	TSAggregatorCount
+	TSAggregatorFirst
+	TSAggregatorLast
+)
+
+// TSAggregation is the AGGREGATION of TS.RANGE or TS.CREATERULE, which groups samples into
+// buckets of BucketDuration milliseconds, and replaces each bucket with a single sample at its
+// start. Buckets start at Align, and every BucketDuration milliseconds before and after it.
+type TSAggregation struct {
+	Aggregator     TSAggregator
+	BucketDuration int64
+	Align          int64
+}
+
+// bucketStart returns the start of the bucket that timestamp is in.
+func (a TSAggregation) bucketStart(timestamp int64) int64 {
+	offset := (timestamp - a.Align) % a.BucketDuration
+	if offset < 0 {
+		offset += a.BucketDuration
+	}
+	return timestamp - offset
+}
+
+// aggregate returns the value of a bucket of samples, which mustn't be empty.
+func (a TSAggregation) aggregate(samples []tsSample) float64 {
+	switch a.Aggregator {
+	case TSAggregatorCount:
+		return float64(len(samples))
+	case TSAggregatorFirst:
+		return samples[0].value
+	case TSAggregatorLast:
+		return samples[len(samples)-1].value
+	}
+	sum, min, max := 0.0, math.Inf(1), math.Inf(-1)
+	for _, sample := range samples {
+		sum += sample.value
+		min = math.Min(min, sample.value)
+		max = math.Max(max, sample.value)
+	}
+	switch a.Aggregator {
+	case TSAggregatorAvg:
+		return sum / float64(len(samples))
+	case TSAggregatorSum:
+		return sum
+	case TSAggregatorMin:
+		return min
+	case TSAggregatorMax:
+		return max
+	}
+	return max - min
+}
+
+// apply returns samples aggregated into buckets, in order.
+func (a TSAggregation) apply(samples []tsSample) []tsSample {
+	var result []tsSample
+	for i := 0; i < len(samples); {
+		start := a.bucketStart(samples[i].timestamp)
+		j := i + 1
+		for j < len(samples) && samples[j].timestamp-start < a.BucketDuration {
+			j++
+		}
+		result = append(result, tsSample{timestamp: start, value: a.aggregate(samples[i:j])})
+		i = j
+	}
+	return result
+}
+
+type tsSample struct {
+	timestamp int64
+	value     float64
+}
+
+// timeSeries is the time series data type, like RedisTimeSeries's: samples in order of their
+// millisecond timestamps, with at most one sample per timestamp.
+type timeSeries struct {
+	samples         []tsSample
+	retention       int64
+	duplicatePolicy TSDuplicatePolicy
+	labels          []TSLabel
+	rules           []*tsRule
+	// source is the key of the time series that this one is a compaction of, or "" if it isn't
+	// one.
+	source string
+}
+
+// tsRule is a compaction rule of TS.CREATERULE, which aggregates a time series's samples into
+// another time series, destination, one bucket at a time. A bucket is compacted when a sample
+// is added to a later one, or again if a sample is added to it after that.
+type tsRule struct {
+	destination string
+	aggregation TSAggregation
+	// bucket is the start of the latest bucket that a sample has been added to, if started.
+	bucket  int64
+	started bool
+}
+
+func newTimeSeries(options TSCreateOptions) *timeSeries {
+	policy := options.DuplicatePolicy
+	if policy == TSDuplicateDefault {
+		policy = TSDuplicateBlock
+	}
+	return &timeSeries{
+		retention:       options.Retention,
+		duplicatePolicy: policy,
+		labels:          options.Labels,
+	}
+}
+
+// cutoff returns the timestamp of the oldest sample that the series keeps, which like
+// RedisTimeSeries is the retention before its latest sample, rather than before the current time.
+func (s *timeSeries) cutoff() int64 {
+	if s.retention == 0 || len(s.samples) == 0 {
+		return math.MinInt64
+	}
+	return s.samples[len(s.samples)-1].timestamp - s.retention
+}
+
+// search returns the index of the first sample at or after timestamp.
+func (s *timeSeries) search(timestamp int64) int {
+	return sort.Search(len(s.samples), func(i int) bool {
+		return s.samples[i].timestamp >= timestamp
+	})
+}
+
+// add adds a sample, resolving a sample with the same timestamp with policy, or the series's
+// own policy for TSDuplicateDefault.
+func (s *timeSeries) add(
+	timestamp int64,
+	value float64,
+	policy TSDuplicatePolicy,
+) error {
+	if timestamp < s.cutoff() {
+		return errTSOlderThanRetention
+	}
+	i := s.search(timestamp)
+	if i == len(s.samples) || s.samples[i].timestamp != timestamp {
+		s.samples = append(s.samples, tsSample{})
+		copy(s.samples[i+1:], s.samples[i:])
+		s.samples[i] = tsSample{timestamp: timestamp, value: value}
+		return nil
+	}
+
+	if policy == TSDuplicateDefault {
+		policy = s.duplicatePolicy
+	}
+	existing := &s.samples[i].value
+	switch policy {
+	case TSDuplicateBlock:
+		return errTSDuplicate
+	case TSDuplicateLast:
+		*existing = value
+	case TSDuplicateMin:
+		*existing = math.Min(*existing, value)
+	case TSDuplicateMax:
+		*existing = math.Max(*existing, value)
+	case TSDuplicateSum:
+		*existing += value
+	}
+	return nil
+}
+
+// trim removes the samples that are older than the retention.
+func (s *timeSeries) trim() {
+	if i := s.search(s.cutoff()); i > 0 {
+		s.samples = append([]tsSample(nil), s.samples[i:]...)
+	}
+}
+
+// between returns the samples from timestamp from to timestamp to inclusive, leaving out any
+// older than the retention.
+func (s *timeSeries) between(from, to int64) []tsSample {
+	if cutoff := s.cutoff(); from < cutoff {
+		from = cutoff
+	}
+	end := sort.Search(len(s.samples), func(i int) bool {
+		return s.samples[i].timestamp > to
+	})
+	start := s.search(from)
+	if start > end {
+		return nil
+	}
+	return s.samples[start:end]
+}
+
+// label returns the value of the label called name, or false if there isn't one.
+func (s *timeSeries) label(name string) (string, bool) {
+	for _, label := range s.labels {
+		if label.Name == name {
+			return label.Value, true
+		}
+	}
+	return "", false
+}
+
+// tsAdd adds a sample to series, then compacts it into the destinations of the series's rules,
+// before trimming the samples that are now older than the retention, which a bucket that has
+// just been completed may still need.
+func tsAdd(
+	tx *storeTx,
+	series *timeSeries,
+	timestamp int64,
+	value float64,
+	policy TSDuplicatePolicy,
+) error {
+	if err := series.add(timestamp, value, policy); err != nil {
+		return err
+	}
+	tsCompact(tx, series, timestamp)
+	series.trim()
+	return nil
+}
+
+// tsCompact compacts the bucket of each of series's rules that a sample at timestamp was just
+// added to or changed in, if it's complete. Like RedisTimeSeries, a complete bucket that starts
+// before the retention isn't compacted again, since some of its samples have been trimmed.
+func tsCompact(tx *storeTx, series *timeSeries, timestamp int64) {
+	for _, rule := range series.rules {
+		bucket := rule.aggregation.bucketStart(timestamp)
+		switch {
+		case !rule.started || bucket > rule.bucket:
+			if rule.started {
+				tsCompactBucket(tx, series, rule, rule.bucket)
+			}
+			rule.bucket = bucket
+			rule.started = true
+		case bucket < rule.bucket && bucket >= series.cutoff():
+			tsCompactBucket(tx, series, rule, bucket)
+		}
+	}
+}
+
+// tsCompactBucket replaces the sample at the start of a bucket in rule's destination with the
+// aggregate of series's samples in the bucket.
+func tsCompactBucket(tx *storeTx, series *timeSeries, rule *tsRule, bucket int64) {
+	value, ok, _ := tx.getTyped(rule.destination, ValueTypeTimeSeries)
+	if !ok {
+		return
+	}
+	start, end := series.search(bucket), series.search(bucket+rule.aggregation.BucketDuration)
+	samples := series.samples[start:end]
+	if len(samples) == 0 {
+		return
+	}
+	err := tsAdd(
+		tx, value.timeSeries(), bucket, rule.aggregation.aggregate(samples), TSDuplicateLast,
+	)
+	if err == nil {
+		tx.notify(notifyModule, "ts.add", rule.destination)
+	}
+}
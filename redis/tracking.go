@@ -0,0 +1,272 @@
+package redis
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// trackingChannel is the channel that a RESP2 client subscribes to in order to be sent the
+// invalidation messages of the clients that redirect them to it.
+const trackingChannel = "__redis__:invalidate"
+
+// trackingOptions are the options that a client turned CLIENT TRACKING on with.
+type trackingOptions struct {
+	// redirect is the client that invalidation messages are sent to instead, if any.
+	redirect *Client
+	// broadcast is true in broadcasting mode, where the client is sent invalidation messages for
+	// every key that starts with one of prefixes, rather than for the keys it read.
+	broadcast bool
+	prefixes  []string
+	// optIn only remembers the keys read by commands sent right after CLIENT CACHING YES, and
+	// optOut only those of commands that aren't sent right after CLIENT CACHING NO.
+	optIn  bool
+	optOut bool
+	// noLoop doesn't send the client invalidation messages for keys that it changed itself.
+	noLoop bool
+}
+
+func newTracking() *tracking {
+	return &tracking{
+		clients:  make(map[*Client]*trackingOptions),
+		keys:     make(map[string]map[*Client]struct{}),
+		prefixes: make(map[string]map[*Client]struct{}),
+	}
+}
+
+// tracking is the state of server-assisted client-side caching, after Redis's tracking table:
+// the clients with CLIENT TRACKING on, and which of them may have cached each key, so that they
+// can be sent invalidation messages when it changes.
+type tracking struct {
+	mu      sync.Mutex
+	clients map[*Client]*trackingOptions
+	// keys are the clients in the default mode that read each key since it last changed. Like
+	// Redis, clients aren't removed when they turn tracking off, only when the key changes.
+	keys map[string]map[*Client]struct{}
+	// prefixes are the clients in broadcasting mode that are interested in each prefix.
+	prefixes map[string]map[*Client]struct{}
+}
+
+const (
+	errTrackingBroadcastSwitch CommandError = "ERR You can't switch BCAST mode on/off before " +
+		"disabling tracking for this client, and then re-enabling it with a different mode."
+	errTrackingOptInSwitch CommandError = "ERR You can't switch OPTIN/OPTOUT mode before " +
+		"disabling tracking for this client, and then re-enabling it with a different mode."
+)
+
+// enable turns tracking on for client, or changes its options if it's already on. Prefixes of
+// broadcasting mode are added to those it already has, or if there are none, it's interested in
+// every key.
+func (t *tracking) enable(client *Client, options trackingOptions) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	current := t.clients[client]
+	if current != nil {
+		if current.broadcast != options.broadcast {
+			return errTrackingBroadcastSwitch
+		}
+		if (current.optIn && options.optOut) || (current.optOut && options.optIn) {
+			return errTrackingOptInSwitch
+		}
+	}
+	if options.broadcast {
+		var existing []string
+		if current != nil {
+			existing = current.prefixes
+		}
+		if len(options.prefixes) == 0 {
+			options.prefixes = []string{""}
+		}
+		if err := checkPrefixCollisions(existing, options.prefixes); err != nil {
+			return err
+		}
+		for _, prefix := range existing {
+			if !containsString(options.prefixes, prefix) {
+				options.prefixes = append(options.prefixes, prefix)
+			}
+		}
+		for _, prefix := range options.prefixes {
+			clients, ok := t.prefixes[prefix]
+			if !ok {
+				clients = make(map[*Client]struct{})
+				t.prefixes[prefix] = clients
+			}
+			clients[client] = struct{}{}
+		}
+	}
+	t.clients[client] = &options
+	client.setTracking(t)
+	return nil
+}
+
+// checkPrefixCollisions returns an error if any of the prefixes of broadcasting mode that a
+// client adds overlaps with another one, or with one that it has already, other than by being
+// the same: since each key is only sent to a client once per prefix, a key mustn't match two.
+func checkPrefixCollisions(existing []string, added []string) error {
+	for i, prefix := range added {
+		for _, other := range existing {
+			if prefix != other && prefixesOverlap(prefix, other) {
+				return CommandError(fmt.Sprintf(
+					"ERR Prefix '%s' overlaps with an existing prefix '%s'. "+
+						"Prefixes for a single client must not overlap.",
+					prefix,
+					other,
+				))
+			}
+		}
+		for _, other := range added[i+1:] {
+			if prefixesOverlap(prefix, other) {
+				return CommandError(fmt.Sprintf(
+					"ERR Prefix '%s' overlaps with another provided prefix '%s'. "+
+						"Prefixes for a single client must not overlap.",
+					prefix,
+					other,
+				))
+			}
+		}
+	}
+	return nil
+}
+
+func prefixesOverlap(a, b string) bool {
+	return strings.HasPrefix(a, b) || strings.HasPrefix(b, a)
+}
+
+func containsString(elements []string, s string) bool {
+	for _, element := range elements {
+		if element == s {
+			return true
+		}
+	}
+	return false
+}
+
+// disable turns tracking off for client.
+func (t *tracking) disable(client *Client) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	options, ok := t.clients[client]
+	if !ok {
+		return
+	}
+	for _, prefix := range options.prefixes {
+		clients := t.prefixes[prefix]
+		delete(clients, client)
+		if len(clients) == 0 {
+			delete(t.prefixes, prefix)
+		}
+	}
+	delete(t.clients, client)
+}
+
+// options returns a copy of client's tracking options, or false if tracking is off.
+func (t *tracking) options(client *Client) (trackingOptions, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	options, ok := t.clients[client]
+	if !ok {
+		return trackingOptions{}, false
+	}
+	return *options, true
+}
+
+// remember records that client read key, with caching true if it sent CLIENT CACHING just
+// before the command that read it, so that it's sent an invalidation message when key changes.
+func (t *tracking) remember(client *Client, caching bool, key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	options, ok := t.clients[client]
+	if !ok || options.broadcast || (options.optIn && !caching) || (options.optOut && caching) {
+		return
+	}
+	clients, ok := t.keys[key]
+	if !ok {
+		clients = make(map[*Client]struct{})
+		t.keys[key] = clients
+	}
+	clients[client] = struct{}{}
+}
+
+// invalidate sends invalidation messages for keys, which writer changed, to the clients that
+// may have cached them. Like Redis, clients in the default mode are sent a message for each key,
+// and must read it again to be sent another, and clients in broadcasting mode are sent a single
+// message with every key that starts with one of their prefixes.
+func (t *tracking) invalidate(keys []string, writer *Client) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(t.keys) == 0 && len(t.prefixes) == 0 {
+		return
+	}
+	seen := make(map[string]bool, len(keys))
+	broadcasts := make(map[*Client][]string)
+	var broadcastClients []*Client
+	for _, key := range keys {
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		for client := range t.keys[key] {
+			options, ok := t.clients[client]
+			if !ok || options.broadcast || (options.noLoop && client == writer) {
+				continue
+			}
+			t.send(client, options, []string{key})
+		}
+		delete(t.keys, key)
+
+		for prefix, clients := range t.prefixes {
+			if !strings.HasPrefix(key, prefix) {
+				continue
+			}
+			for client := range clients {
+				if t.clients[client].noLoop && client == writer {
+					continue
+				}
+				if _, ok := broadcasts[client]; !ok {
+					broadcastClients = append(broadcastClients, client)
+				}
+				broadcasts[client] = append(broadcasts[client], key)
+			}
+		}
+	}
+	for _, client := range broadcastClients {
+		t.send(client, t.clients[client], broadcasts[client])
+	}
+}
+
+// send sends client an invalidation message for keys: a push if the client it's sent to uses
+// RESP3, or a message of trackingChannel if it's a RESP2 client that client redirects to and
+// it's subscribed. A RESP2 client that doesn't redirect can't be sent anything.
+func (t *tracking) send(client *Client, options *trackingOptions, keys []string) {
+	// Invalidation messages aren't subject to the output buffer limit of publish/subscribe.
+	var limit OutputBufferLimit
+	target := client
+	if options.redirect != nil {
+		target = options.redirect
+		if target.isClosed() {
+			if client.protocol() == 3 {
+				message := pushArray(bulkString("tracking-redir-broken"), integer(target.ID()))
+				client.push(message, limit, time.Time{})
+			}
+			return
+		}
+	}
+	switch {
+	case target.protocol() == 3:
+		target.push(pushArray(bulkString("invalidate"), bulkStringArray(keys)), limit, time.Time{})
+	case options.redirect != nil && target.subscribed():
+		message := array(
+			bulkString("message"),
+			bulkString(trackingChannel),
+			bulkStringArray(keys),
+		)
+		target.push(message, limit, time.Time{})
+	}
+}
@@ -0,0 +1,108 @@
+package redis
+
+import "sync"
+
+func newWatches() *watches {
+	return &watches{
+		keys:    make(map[string]map[*Client]struct{}),
+		clients: make(map[*Client]*watchedKeys),
+	}
+}
+
+// watches are the keys that clients watch with WATCH, so that their transactions are discarded
+// if any of them change before EXEC.
+type watches struct {
+	mu sync.Mutex
+	// keys are the clients watching each key.
+	keys    map[string]map[*Client]struct{}
+	clients map[*Client]*watchedKeys
+}
+
+// watchedKeys are the keys that a client watches, and whether any of them changed since.
+type watchedKeys struct {
+	// expired holds whether each key had already expired, but not been deleted, when it was
+	// watched. Like Redis, deleting such a key doesn't count as a change.
+	expired map[string]bool
+	changed bool
+}
+
+// watch makes client watch key, which had already expired if expired is true.
+func (w *watches) watch(client *Client, key string, expired bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	watched, ok := w.clients[client]
+	if !ok {
+		watched = &watchedKeys{expired: make(map[string]bool)}
+		w.clients[client] = watched
+	}
+	if _, ok := watched.expired[key]; ok {
+		return
+	}
+	watched.expired[key] = expired
+	clients, ok := w.keys[key]
+	if !ok {
+		clients = make(map[*Client]struct{})
+		w.keys[key] = clients
+	}
+	clients[client] = struct{}{}
+	client.setWatches(w)
+}
+
+// touch records that keys changed, for the clients watching them. exists returns whether a key
+// exists after the change.
+func (w *watches) touch(keys []string, exists func(key string) bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.keys) == 0 {
+		return
+	}
+	for _, key := range keys {
+		for client := range w.keys[key] {
+			watched := w.clients[client]
+			if watched.expired[key] && !exists(key) {
+				// The key that had already expired was deleted, so it hasn't logically changed.
+				watched.expired[key] = false
+				continue
+			}
+			watched.changed = true
+		}
+	}
+}
+
+// unwatch stops client watching any keys. It returns the keys it watched, with whether each had
+// already expired when it was watched, and whether any of them changed since.
+func (w *watches) unwatch(client *Client) (expired map[string]bool, changed bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	watched, ok := w.clients[client]
+	if !ok {
+		return nil, false
+	}
+	for key := range watched.expired {
+		clients := w.keys[key]
+		delete(clients, client)
+		if len(clients) == 0 {
+			delete(w.keys, key)
+		}
+	}
+	delete(w.clients, client)
+	return watched.expired, watched.changed
+}
+
+// unwatch stops client watching any keys, returning true if any of them changed since it started
+// watching them, including by expiring by the time of tx, even if they haven't been deleted yet.
+func (tx *storeTx) unwatch(client *Client) bool {
+	keys, changed := client.unwatch()
+	if changed {
+		return true
+	}
+	for key, expired := range keys {
+		if value, ok := tx.store.entries[key]; ok && !expired && value.expiredAt(tx.now) {
+			return true
+		}
+	}
+	return false
+}
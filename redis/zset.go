@@ -0,0 +1,320 @@
+package redis
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+// zset is the sorted set data type: a map from members to their scores alongside a skiplist
+// that keeps them in order, like Redis's skiplist encoding.
+type zset struct {
+	scores map[string]float64
+	list   *skiplist
+}
+
+// ScoreMember is a sorted set member and its score.
+type ScoreMember struct {
+	Score  float64
+	Member string
+}
+
+func newZSet() *zset {
+	return &zset{
+		scores: make(map[string]float64),
+		list:   newSkiplist(),
+	}
+}
+
+func (z *zset) len() int {
+	return len(z.scores)
+}
+
+func (z *zset) score(member string) (float64, bool) {
+	score, ok := z.scores[member]
+	return score, ok
+}
+
+// add sets the score of member, returning true if it's new.
+func (z *zset) add(member string, score float64) bool {
+	old, exists := z.scores[member]
+	if exists {
+		if old == score {
+			return false
+		}
+		z.list.delete(old, member)
+	}
+	z.list.insert(score, member)
+	z.scores[member] = score
+	return !exists
+}
+
+// remove removes member, returning true if it was present.
+func (z *zset) remove(member string) bool {
+	score, ok := z.scores[member]
+	if !ok {
+		return false
+	}
+	z.list.delete(score, member)
+	delete(z.scores, member)
+	return true
+}
+
+// rank returns the 0-based rank of member in ascending order of score, or descending if reverse.
+func (z *zset) rank(member string, reverse bool) (int, bool) {
+	score, ok := z.scores[member]
+	if !ok {
+		return 0, false
+	}
+	rank := z.list.rank(score, member) - 1
+	if reverse {
+		rank = z.len() - 1 - rank
+	}
+	return rank, true
+}
+
+// entries returns the members of 0-based rank start to stop inclusive, which must be in range,
+// with their scores. They're in ascending order, or descending from stop if reverse.
+func (z *zset) entries(start, stop int, reverse bool) []ScoreMember {
+	if start > stop {
+		return nil
+	}
+	result := make([]ScoreMember, stop-start+1)
+	x := z.list.byRank(start + 1)
+	if reverse {
+		x = z.list.byRank(stop + 1)
+	}
+	for i := range result {
+		result[i] = ScoreMember{Score: x.score, Member: x.member}
+		if reverse {
+			x = x.backward
+		} else {
+			x = x.levels[0].forward
+		}
+	}
+	return result
+}
+
+// random returns a random member with its score. The sorted set must not be empty.
+func (z *zset) random() ScoreMember {
+	x := z.list.byRank(rand.Intn(z.len()) + 1)
+	return ScoreMember{Score: x.score, Member: x.member}
+}
+
+// ZRangeBy is what a ZRangeQuery selects members by.
+type ZRangeBy int
+
+const (
+	ZRangeByRank ZRangeBy = iota
+	ZRangeByScore
+	ZRangeByLex
+)
+
+// removeEvent returns the keyspace event of removing the members of a range selected by z, like
+// zremrangebyscore.
+func (z ZRangeBy) removeEvent() string {
+	switch z {
+	case ZRangeByScore:
+		return "zremrangebyscore"
+	case ZRangeByLex:
+		return "zremrangebylex"
+	}
+	return "zremrangebyrank"
+}
+
+// ZRangeQuery selects a range of a sorted set's members, like the arguments of ZRANGE.
+type ZRangeQuery struct {
+	By ZRangeBy
+	// Start and Stop are the ranks to select between, inclusive, when By is ZRangeByRank.
+	// Negative ranks count back from the last member, which is -1. When Reverse is true the
+	// ranks are in descending order.
+	Start, Stop int
+	// Scores is the range to select when By is ZRangeByScore.
+	Scores ScoreRange
+	// Lex is the range to select when By is ZRangeByLex.
+	Lex LexRange
+	// Reverse selects members in descending order rather than ascending.
+	Reverse bool
+	// Offset skips that many members of a ZRangeByScore or ZRangeByLex range, and Count limits
+	// it to that many members if it's not negative.
+	Offset, Count int
+}
+
+// NewZRangeQuery returns a ZRangeQuery that selects members by rank from start to stop, with no
+// limit.
+func NewZRangeQuery(start, stop int) ZRangeQuery {
+	return ZRangeQuery{By: ZRangeByRank, Start: start, Stop: stop, Count: -1}
+}
+
+// rankRange returns the 0-based ascending ranks of the first and last members that q selects,
+// with start > stop if it selects none.
+func (z *zset) rankRange(q ZRangeQuery) (start, stop int) {
+	length := z.len()
+	switch q.By {
+	case ZRangeByRank:
+		start, stop = q.Start, q.Stop
+		if start < 0 {
+			start += length
+		}
+		if stop < 0 {
+			stop += length
+		}
+		if start < 0 {
+			start = 0
+		}
+		if start > stop || start >= length {
+			return 0, -1
+		}
+		if stop >= length {
+			stop = length - 1
+		}
+		if q.Reverse {
+			start, stop = length-1-stop, length-1-start
+		}
+		return start, stop
+	case ZRangeByScore:
+		aboveMin := func(n *skiplistNode) bool { return q.Scores.aboveMin(n.score) }
+		belowMax := func(n *skiplistNode) bool { return q.Scores.belowMax(n.score) }
+		return z.nodeRange(aboveMin, belowMax)
+	case ZRangeByLex:
+		aboveMin := func(n *skiplistNode) bool { return q.Lex.aboveMin(n.member) }
+		belowMax := func(n *skiplistNode) bool { return q.Lex.belowMax(n.member) }
+		return z.nodeRange(aboveMin, belowMax)
+	}
+	panic(fmt.Sprintf("unknown redis.ZRangeBy: %d", q.By))
+}
+
+func (z *zset) nodeRange(aboveMin, belowMax func(*skiplistNode) bool) (start, stop int) {
+	first := z.list.first(aboveMin, belowMax)
+	if first == nil {
+		return 0, -1
+	}
+	last := z.list.last(aboveMin, belowMax)
+	return z.list.rank(first.score, first.member) - 1, z.list.rank(last.score, last.member) - 1
+}
+
+// query returns the members that q selects, with their scores, in q's order.
+func (z *zset) query(q ZRangeQuery) []ScoreMember {
+	start, stop := z.rankRange(q)
+	if q.By != ZRangeByRank {
+		if q.Offset < 0 {
+			return nil
+		}
+		if q.Reverse {
+			stop -= q.Offset
+			if q.Count >= 0 && stop-q.Count+1 > start {
+				start = stop - q.Count + 1
+			}
+		} else {
+			start += q.Offset
+			if q.Count >= 0 && start+q.Count-1 < stop {
+				stop = start + q.Count - 1
+			}
+		}
+	}
+	return z.entries(start, stop, q.Reverse)
+}
+
+// count returns the number of members that q selects, ignoring its offset and count, in O(log N)
+// time.
+func (z *zset) count(q ZRangeQuery) int {
+	start, stop := z.rankRange(q)
+	if start > stop {
+		return 0
+	}
+	return stop - start + 1
+}
+
+// ScoreRange is a range of sorted set scores, like the "(1 +inf" arguments of ZRANGE BYSCORE.
+type ScoreRange struct {
+	Min, Max                   float64
+	MinExclusive, MaxExclusive bool
+}
+
+func (r ScoreRange) aboveMin(score float64) bool {
+	if r.MinExclusive {
+		return score > r.Min
+	}
+	return score >= r.Min
+}
+
+func (r ScoreRange) belowMax(score float64) bool {
+	if r.MaxExclusive {
+		return score < r.Max
+	}
+	return score <= r.Max
+}
+
+// LexRange is a range of sorted set members, like the "[a (c" arguments of ZRANGE BYLEX.
+type LexRange struct {
+	Min, Max LexBound
+}
+
+// LexBound is one end of a LexRange. Infinity is -1 for "-", which is before every member, 1 for
+// "+", which is after every member, or 0 for a bound of Value.
+type LexBound struct {
+	Value     string
+	Exclusive bool
+	Infinity  int
+}
+
+func (r LexRange) aboveMin(member string) bool {
+	if r.Min.Infinity != 0 {
+		return r.Min.Infinity < 0
+	}
+	if r.Min.Exclusive {
+		return member > r.Min.Value
+	}
+	return member >= r.Min.Value
+}
+
+func (r LexRange) belowMax(member string) bool {
+	if r.Max.Infinity != 0 {
+		return r.Max.Infinity > 0
+	}
+	if r.Max.Exclusive {
+		return member < r.Max.Value
+	}
+	return member <= r.Max.Value
+}
+
+// formatScore formats a sorted set score like Redis does: as the shortest decimal that parses
+// back to it, in exponent form only if it's very large or very small.
+func formatScore(score float64) string {
+	switch {
+	case math.IsInf(score, 1):
+		return "inf"
+	case math.IsInf(score, -1):
+		return "-inf"
+	}
+
+	// Follow Redis's fpconv_dtoa, which decides on the form from the digits and the exponent of
+	// the last digit, k.
+	sign := ""
+	if score < 0 {
+		sign = "-"
+		score = -score
+	}
+	mantissa, exponent, _ := strings.Cut(strconv.FormatFloat(score, 'e', -1, 64), "e")
+	digits := strings.Replace(mantissa, ".", "", 1)
+	decimalExponent, _ := strconv.Atoi(exponent)
+	k := decimalExponent - (len(digits) - 1)
+	absExponent := decimalExponent
+	if absExponent < 0 {
+		absExponent = -absExponent
+	}
+
+	switch {
+	case k >= 0 && absExponent < len(digits)+7:
+		return sign + digits + strings.Repeat("0", k)
+	case k < 0 && (k > -7 || absExponent < 4):
+		return sign + strconv.FormatFloat(score, 'f', -1, 64)
+	}
+	exponentSign := "+"
+	if decimalExponent < 0 {
+		exponentSign = "-"
+	}
+	return sign + mantissa + "e" + exponentSign + strconv.Itoa(absExponent)
+}